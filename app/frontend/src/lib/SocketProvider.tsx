@@ -107,9 +107,11 @@ export const SocketProvider: React.FC<{ children: React.ReactNode }> = ({ childr
       console.error('[Socket] Reconnection failed after maximum attempts');
     });
 
-    // Listen for new notifications
-    newSocket.on('new_notification', (payload: NotificationPayload) => {
+    // Listen for new notifications. Messages flushed from the offline
+    // queue carry an ack callback; the server resends them until acked.
+    newSocket.on('new_notification', (payload: NotificationPayload, ack?: () => void) => {
       console.log('[Socket] Received notification:', payload);
+      ack?.();
 
       // Display toast notification based on type
       const title = payload.title || 'Notification';
@@ -139,14 +141,16 @@ export const SocketProvider: React.FC<{ children: React.ReactNode }> = ({ childr
     });
 
     // Listen for notification read confirmations
-    newSocket.on('notification_read', (data: { notificationId: string }) => {
+    newSocket.on('notification_read', (data: { notificationId: string }, ack?: () => void) => {
       console.log('[Socket] Notification marked as read:', data.notificationId);
+      ack?.();
       // Update UI to reflect read status
     });
 
     // Listen for broadcast messages
-    newSocket.on('broadcast', (payload: NotificationPayload) => {
+    newSocket.on('broadcast', (payload: NotificationPayload, ack?: () => void) => {
       console.log('[Socket] Received broadcast:', payload);
+      ack?.();
       info(payload.message, payload.title || 'Announcement');
     });
 
@@ -11,7 +11,13 @@ const TRACK_EVENT_MUTATION = gql`
 
 const IDENTIFY_USER_MUTATION = gql`
   mutation IdentifyUser($properties: JSON!) {
-    identifyUser(properties: $properties)
+    identifyUser(properties: $properties) {
+      enqueued
+      propertyIssues {
+        key
+        reason
+      }
+    }
   }
 `;
 
@@ -85,7 +91,7 @@ export const useAnalytics = () => {
       }
 
       try {
-        await identifyUserMutation({
+        const { data } = await identifyUserMutation({
           variables: {
             properties: {
               ...properties,
@@ -96,6 +102,11 @@ export const useAnalytics = () => {
           },
         });
 
+        const issues = data?.identifyUser?.propertyIssues ?? [];
+        if (issues.length > 0) {
+          console.warn('[Analytics] Properties dropped from identify:', issues);
+        }
+
         if (process.env.NODE_ENV === 'development') {
           console.log('[Analytics] User identified:', properties);
         }
@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
@@ -10,7 +11,11 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/99designs/gqlgen/graphql"
 	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"github.com/99designs/gqlgen/graphql/handler/lru"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
 	"github.com/99designs/gqlgen/graphql/playground"
 	"github.com/rs/cors"
 	"go.uber.org/zap"
@@ -32,7 +37,7 @@ func main() {
 	}
 
 	// Initialize logger
-	logger, err := initLogger(cfg.Logging.Level, cfg.Logging.Format)
+	logger, err := initLogger(cfg.Logging.Level, cfg.Logging.Format, cfg.Logging.Sampling.Zap())
 	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
@@ -51,40 +56,75 @@ func main() {
 		NotificationsService: cfg.Services.NotificationsService,
 		AnalyticsService:     cfg.Services.AnalyticsService,
 		FeatureFlagsService:  cfg.Services.FeatureFlagsService,
+		LoadBalancing:        cfg.Services.LoadBalancing,
 	}, logger)
 	if err != nil {
 		logger.Fatal("Failed to initialize gRPC clients", zap.Error(err))
 	}
-	defer grpcClients.Close()
 
 	logger.Info("✓ all gRPC clients initialized")
 
-	// Initialize dataloaders
-	loaders := dataloader.NewLoaders(dataloader.Clients{
+	// Cache the plans list so Plans queries don't hammer billing
+	if cfg.Cache.PlansTTLSec > 0 {
+		grpcClients.Billing = clients.NewCachedBillingClient(
+			grpcClients.Billing,
+			time.Duration(cfg.Cache.PlansTTLSec)*time.Second,
+			logger,
+		)
+	}
+
+	// Dataloaders are built per request from these clients
+	loaderClients := dataloader.Clients{
 		UserAuth: grpcClients.UserAuth,
 		Billing:  grpcClients.Billing,
-	}, logger)
+	}
 
 	// Initialize resolvers
 	resolver := resolvers.NewResolver(grpcClients, logger)
+	resolver.SetAdminToken(cfg.Auth.AdminAPIToken)
+	if cfg.Cache.FeatureFlagsTTLSec > 0 {
+		resolver.SetFeatureFlagCache(clients.NewFeatureFlagCache(
+			time.Duration(cfg.Cache.FeatureFlagsTTLSec) * time.Second,
+		))
+	}
 
 	// Create GraphQL server
-	srv := handler.NewDefaultServer(generated.NewExecutableSchema(generated.Config{
+	srv := newGraphQLServer(generated.NewExecutableSchema(generated.Config{
 		Resolvers: resolver,
-	}))
+	}), cfg.Server.Introspection)
+	if !cfg.Server.Introspection {
+		logger.Info("GraphQL introspection disabled")
+	}
+	srv.Use(middleware.NewAnonymousPolicy(cfg.Auth.AnonymousOperations))
 
 	// Initialize auth middleware
 	authMiddleware := middleware.NewAuthMiddleware(grpcClients.UserAuth, logger)
+	authMiddleware.SetCookieName(cfg.Auth.CookieName)
+	authMiddleware.SetCookieOrigins(cfg.Auth.CookieOrigins)
 
 	// Setup HTTP router
 	mux := http.NewServeMux()
 
-	// GraphQL endpoint with auth middleware and dataloaders
-	mux.Handle("/graphql", 
-		authMiddleware.Middleware(
-			dataloader.Middleware(loaders)(srv),
-		),
+	// GraphQL endpoint with client info, auth middleware, rate limiting and dataloaders
+	var apiHandler http.Handler = dataloader.Middleware(loaderClients, logger)(srv)
+	if cfg.RateLimit.Requests > 0 {
+		rateLimiter := middleware.NewRateLimiter(cfg.RateLimit.Requests, time.Duration(cfg.RateLimit.WindowSec)*time.Second)
+		apiHandler = rateLimiter.Middleware(apiHandler)
+		logger.Info("Rate limiting enabled",
+			zap.Int("requests", cfg.RateLimit.Requests),
+			zap.Int("window_seconds", cfg.RateLimit.WindowSec))
+	}
+	trustedProxies, err := middleware.ParseTrustedProxies(cfg.Server.TrustedProxies)
+	if err != nil {
+		logger.Fatal("Invalid TRUSTED_PROXIES", zap.Error(err))
+	}
+	var graphqlHandler http.Handler = middleware.ClientInfoMiddleware(trustedProxies)(
+		authMiddleware.Middleware(apiHandler),
 	)
+	if cfg.Server.MaxBodyBytes > 0 {
+		graphqlHandler = middleware.MaxBodySizeMiddleware(cfg.Server.MaxBodyBytes)(graphqlHandler)
+	}
+	mux.Handle("/graphql", graphqlHandler)
 
 	// GraphQL Playground (only in development)
 	if cfg.Server.Env == "development" {
@@ -103,6 +143,13 @@ func main() {
 		AllowedOrigins: []string{"*"}, // Configure this properly in production
 		AllowedMethods: []string{"GET", "POST", "OPTIONS"},
 		AllowedHeaders: []string{"Authorization", "Content-Type"},
+		ExposedHeaders: []string{
+			middleware.RateLimitLimitHeader,
+			middleware.RateLimitRemainingHeader,
+			middleware.RateLimitResetHeader,
+			middleware.ReissuedTokenHeader,
+			"Retry-After",
+		},
 		AllowCredentials: true,
 	})
 
@@ -110,9 +157,9 @@ func main() {
 	httpServer := &http.Server{
 		Addr:         fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
 		Handler:      corsHandler.Handler(mux),
-		ReadTimeout:  15 * time.Second,
-		WriteTimeout: 15 * time.Second,
-		IdleTimeout:  60 * time.Second,
+		ReadTimeout:  time.Duration(cfg.Server.ReadTimeoutSec) * time.Second,
+		WriteTimeout: time.Duration(cfg.Server.WriteTimeoutSec) * time.Second,
+		IdleTimeout:  time.Duration(cfg.Server.IdleTimeoutSec) * time.Second,
 	}
 
 	// Start server in goroutine
@@ -132,21 +179,63 @@ func main() {
 	<-quit
 
 	logger.Info("Shutting down server...")
+	shutdown(httpServer, grpcClients, time.Duration(cfg.Server.ShutdownTimeoutSec)*time.Second, logger)
+	logger.Info("Shutdown complete")
+}
 
-	// Graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
-	defer cancel()
+// shutdown stops the gateway in dependency order: stop accepting HTTP
+// connections, wait up to timeout (0 waits indefinitely) for in-flight
+// GraphQL operations, then close the gRPC connections those operations use
+func shutdown(httpServer *http.Server, grpcClients io.Closer, timeout time.Duration, logger *zap.Logger) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
 
 	if err := httpServer.Shutdown(ctx); err != nil {
-		logger.Error("Server forced to shutdown", zap.Error(err))
+		logger.Warn("In-flight requests did not finish in time, closing their connections",
+			zap.Duration("timeout", timeout),
+			zap.Error(err))
+		httpServer.Close()
 	}
-
 	logger.Info("✓ HTTP server stopped")
-	logger.Info("Shutdown complete")
+
+	if err := grpcClients.Close(); err != nil {
+		logger.Error("Failed to close gRPC clients", zap.Error(err))
+		return
+	}
+	logger.Info("✓ gRPC clients closed")
+}
+
+// newGraphQLServer mirrors handler.NewDefaultServer, but only installs the
+// introspection extension when introspection is enabled
+func newGraphQLServer(es graphql.ExecutableSchema, introspection bool) *handler.Server {
+	srv := handler.New(es)
+
+	srv.AddTransport(transport.Websocket{
+		KeepAlivePingInterval: 10 * time.Second,
+	})
+	srv.AddTransport(transport.Options{})
+	srv.AddTransport(transport.GET{})
+	srv.AddTransport(transport.POST{})
+	srv.AddTransport(transport.MultipartForm{})
+
+	srv.SetQueryCache(lru.New(1000))
+
+	if introspection {
+		srv.Use(extension.Introspection{})
+	}
+	srv.Use(extension.AutomaticPersistedQuery{
+		Cache: lru.New(100),
+	})
+
+	return srv
 }
 
-// initLogger initializes the logger
-func initLogger(level, format string) (*zap.Logger, error) {
+// initLogger initializes the logger. A nil sampling config disables sampling.
+func initLogger(level, format string, sampling *zap.SamplingConfig) (*zap.Logger, error) {
 	var zapLevel zapcore.Level
 	switch level {
 	case "debug":
@@ -163,6 +252,7 @@ func initLogger(level, format string) (*zap.Logger, error) {
 
 	config := zap.NewProductionConfig()
 	config.Level = zap.NewAtomicLevelAt(zapLevel)
+	config.Sampling = sampling
 
 	if format == "console" {
 		config.Encoding = "console"
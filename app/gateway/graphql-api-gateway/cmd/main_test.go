@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"go.uber.org/zap"
+)
+
+// introspectionSchema stands in for the generated schema. Like generated
+// code, it refuses __schema fields when the operation disables introspection.
+func introspectionSchema() graphql.ExecutableSchema {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `type Query { name: String! }`})
+
+	return &graphql.ExecutableSchemaMock{
+		SchemaFunc: func() *ast.Schema { return schema },
+		ComplexityFunc: func(typeName, fieldName string, childComplexity int, args map[string]interface{}) (int, bool) {
+			return 0, false
+		},
+		ExecFunc: func(ctx context.Context) graphql.ResponseHandler {
+			rc := graphql.GetOperationContext(ctx)
+			for _, sel := range rc.Operation.SelectionSet {
+				if field, ok := sel.(*ast.Field); ok && field.Name == "__schema" && rc.DisableIntrospection {
+					return graphql.OneShot(graphql.ErrorResponse(ctx, "introspection disabled"))
+				}
+			}
+			return graphql.OneShot(&graphql.Response{Data: []byte(`{"__schema":{"queryType":{"name":"Query"}}}`)})
+		},
+	}
+}
+
+func postIntrospectionQuery(t *testing.T, srv http.Handler) graphql.Response {
+	t.Helper()
+	body := `{"query":"{ __schema { queryType { name } } }"}`
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	var resp graphql.Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response %q: %v", rec.Body.String(), err)
+	}
+	return resp
+}
+
+func TestNewGraphQLServer_IntrospectionDisabled(t *testing.T) {
+	resp := postIntrospectionQuery(t, newGraphQLServer(introspectionSchema(), false))
+
+	if len(resp.Errors) == 0 {
+		t.Fatal("expected introspection query to be rejected")
+	}
+	if msg := resp.Errors[0].Message; msg != "introspection disabled" {
+		t.Errorf("expected introspection disabled error, got %q", msg)
+	}
+}
+
+func TestNewGraphQLServer_IntrospectionEnabled(t *testing.T) {
+	resp := postIntrospectionQuery(t, newGraphQLServer(introspectionSchema(), true))
+
+	if len(resp.Errors) != 0 {
+		t.Fatalf("expected introspection query to succeed, got %v", resp.Errors)
+	}
+}
+
+// closerFunc adapts a function to io.Closer, standing in for the gRPC clients
+type closerFunc func() error
+
+func (f closerFunc) Close() error { return f() }
+
+// startBlockingServer serves requests that block until release is closed, and
+// reports on started as each one arrives
+func startBlockingServer(t *testing.T, release <-chan struct{}, finished *atomic.Bool) (*http.Server, string, <-chan struct{}) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+
+	started := make(chan struct{}, 1)
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		finished.Store(true)
+		w.WriteHeader(http.StatusOK)
+	})}
+	go server.Serve(listener)
+
+	return server, "http://" + listener.Addr().String(), started
+}
+
+func TestShutdown_ClosesClientsAfterRequestsDrain(t *testing.T) {
+	release := make(chan struct{})
+	var finished atomic.Bool
+	server, url, started := startBlockingServer(t, release, &finished)
+
+	go http.Get(url)
+	<-started
+
+	closed := make(chan bool, 1)
+	clients := closerFunc(func() error {
+		closed <- finished.Load()
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		shutdown(server, clients, 5*time.Second, zap.NewNop())
+		close(done)
+	}()
+
+	select {
+	case <-closed:
+		t.Fatal("expected gRPC clients to stay open while a request is in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected shutdown to finish after the request drained")
+	}
+
+	if requestFinished := <-closed; !requestFinished {
+		t.Error("expected gRPC clients to close after the in-flight request finished")
+	}
+	if _, err := http.Get(url); err == nil {
+		t.Error("expected the server to stop accepting requests")
+	}
+}
+
+func TestShutdown_ClosesClientsAfterTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	var finished atomic.Bool
+	server, url, started := startBlockingServer(t, release, &finished)
+
+	go http.Get(url)
+	<-started
+
+	var closed atomic.Bool
+	clients := closerFunc(func() error {
+		closed.Store(true)
+		return nil
+	})
+
+	done := make(chan struct{})
+	go func() {
+		shutdown(server, clients, 50*time.Millisecond, zap.NewNop())
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected shutdown to give up on the stuck request after the timeout")
+	}
+	if !closed.Load() {
+		t.Error("expected gRPC clients to be closed after the timeout")
+	}
+}
@@ -5,6 +5,7 @@ go 1.21
 require (
 	github.com/99designs/gqlgen v0.17.43
 	github.com/graph-gophers/dataloader/v7 v7.1.0
+	github.com/haunted-saas/pkg v0.0.0
 	github.com/rs/cors v1.10.1
 	github.com/vektah/gqlparser/v2 v2.5.11
 	go.uber.org/zap v1.26.0
@@ -15,6 +16,7 @@ require (
 require (
 	github.com/agnivade/levenshtein v1.1.1 // indirect
 	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/google/uuid v1.3.1 // indirect
 	github.com/gorilla/websocket v1.5.1 // indirect
 	github.com/hashicorp/golang-lru/v2 v2.0.7 // indirect
 	github.com/mitchellh/mapstructure v1.5.0 // indirect
@@ -33,5 +35,6 @@ replace (
 	github.com/haunted-saas/feature-flags-service => ../../services/feature-flags-service
 	github.com/haunted-saas/llm-gateway-service => ../../services/llm-gateway-service
 	github.com/haunted-saas/notifications-service => ../../services/notifications-service
+	github.com/haunted-saas/pkg => ../../pkg
 	github.com/haunted-saas/user-auth-service => ../../services/user-auth-service
 )
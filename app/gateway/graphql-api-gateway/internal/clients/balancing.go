@@ -0,0 +1,85 @@
+package clients
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+// Load balancing policies supported for service connections
+const (
+	LBPolicyPickFirst  = "pick_first"
+	LBPolicyRoundRobin = "round_robin"
+)
+
+// staticScheme is the resolver scheme used for comma-separated address lists
+const staticScheme = "static"
+
+// dialConfig is the resolved dial target and options for one service
+type dialConfig struct {
+	target    string
+	policy    string
+	addresses []string // Replica addresses when a static list was configured
+	opts      []grpc.DialOption
+}
+
+// newDialConfig builds the dial target for a service address. The address is
+// a single host:port, any gRPC target such as "dns:///billing-service:50052",
+// or a comma-separated list of host:port replicas served by a static
+// resolver. Connections are balanced across the resolved addresses with
+// policy, which defaults to round_robin.
+func newDialConfig(name, address, policy string) (*dialConfig, error) {
+	if policy == "" {
+		policy = LBPolicyRoundRobin
+	}
+	if policy != LBPolicyPickFirst && policy != LBPolicyRoundRobin {
+		return nil, fmt.Errorf("unsupported load balancing policy %q for %s", policy, name)
+	}
+
+	cfg := &dialConfig{
+		target: address,
+		policy: policy,
+		opts: []grpc.DialOption{
+			grpc.WithDefaultServiceConfig(fmt.Sprintf(`{"loadBalancingConfig": [{"%s": {}}]}`, policy)),
+		},
+	}
+
+	if !strings.Contains(address, ",") {
+		return cfg, nil
+	}
+
+	for _, addr := range strings.Split(address, ",") {
+		if addr = strings.TrimSpace(addr); addr != "" {
+			cfg.addresses = append(cfg.addresses, addr)
+		}
+	}
+	if len(cfg.addresses) == 0 {
+		return nil, fmt.Errorf("no addresses configured for %s", name)
+	}
+
+	state := resolver.State{Addresses: make([]resolver.Address, len(cfg.addresses))}
+	for i, addr := range cfg.addresses {
+		state.Addresses[i] = resolver.Address{Addr: addr}
+	}
+
+	// Each connection gets its own resolver, so the scheme can be shared
+	r := manual.NewBuilderWithScheme(staticScheme)
+	r.InitialState(state)
+
+	cfg.target = fmt.Sprintf("%s:///%s", staticScheme, name)
+	cfg.opts = append(cfg.opts, grpc.WithResolvers(r))
+	return cfg, nil
+}
+
+// dialService connects to a service using its configured address and load
+// balancing policy
+func dialService(name, address, policy string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	cfg, err := newDialConfig(name, address, policy)
+	if err != nil {
+		return nil, err
+	}
+	return grpc.Dial(cfg.target, append(cfg.opts, opts...)...)
+}
@@ -0,0 +1,66 @@
+package clients
+
+import (
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+func TestNewDialConfig_TargetAndPolicy(t *testing.T) {
+	tests := []struct {
+		name           string
+		address        string
+		policy         string
+		expectedTarget string
+		expectedPolicy string
+		expectedAddrs  []string
+	}{
+		{name: "single address defaults to round robin", address: "billing-service:50052", expectedTarget: "billing-service:50052", expectedPolicy: LBPolicyRoundRobin},
+		{name: "dns target is passed through", address: "dns:///billing-service:50052", policy: LBPolicyRoundRobin, expectedTarget: "dns:///billing-service:50052", expectedPolicy: LBPolicyRoundRobin},
+		{name: "pick first is honored", address: "billing-service:50052", policy: LBPolicyPickFirst, expectedTarget: "billing-service:50052", expectedPolicy: LBPolicyPickFirst},
+		{
+			name:           "address list uses static resolver",
+			address:        "billing-1:50052, billing-2:50052,billing-3:50052",
+			expectedTarget: "static:///billing-service",
+			expectedPolicy: LBPolicyRoundRobin,
+			expectedAddrs:  []string{"billing-1:50052", "billing-2:50052", "billing-3:50052"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := newDialConfig("billing-service", tt.address, tt.policy)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if cfg.target != tt.expectedTarget {
+				t.Errorf("expected target %q, got %q", tt.expectedTarget, cfg.target)
+			}
+			if cfg.policy != tt.expectedPolicy {
+				t.Errorf("expected policy %q, got %q", tt.expectedPolicy, cfg.policy)
+			}
+			if len(cfg.addresses) != len(tt.expectedAddrs) {
+				t.Fatalf("expected addresses %v, got %v", tt.expectedAddrs, cfg.addresses)
+			}
+			for i, addr := range tt.expectedAddrs {
+				if cfg.addresses[i] != addr {
+					t.Errorf("expected address %q at %d, got %q", addr, i, cfg.addresses[i])
+				}
+			}
+
+			// The options must be accepted by grpc (dialing is lazy)
+			conn, err := grpc.Dial(cfg.target, append(cfg.opts, grpc.WithTransportCredentials(insecure.NewCredentials()))...)
+			if err != nil {
+				t.Fatalf("dial failed: %v", err)
+			}
+			conn.Close()
+		})
+	}
+}
+
+func TestNewDialConfig_RejectsUnknownPolicy(t *testing.T) {
+	if _, err := newDialConfig("billing-service", "billing-service:50052", "least_request"); err == nil {
+		t.Error("expected unsupported policy to be rejected")
+	}
+}
@@ -0,0 +1,110 @@
+package clients
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// FeatureFlagCache remembers recent IsFeatureEnabled results so a brief
+// feature-flags-service outage can be answered with the last known value
+// instead of failing the whole query. Entries are keyed by feature, user,
+// team and evaluation properties, so a result for one set of properties is
+// never served for another.
+type FeatureFlagCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	flags     map[featureFlagKey]cachedFeatureFlag
+	nextSweep time.Time
+}
+
+// featureFlagKey identifies one flag evaluation
+type featureFlagKey struct {
+	feature    string
+	userID     string
+	teamID     string
+	properties string // propertiesHash of the evaluation properties
+}
+
+// cachedFeatureFlag is a flag result with its expiry time
+type cachedFeatureFlag struct {
+	enabled   bool
+	expiresAt time.Time
+}
+
+// NewFeatureFlagCache creates a cache whose entries can be served for ttl
+// after they were fetched
+func NewFeatureFlagCache(ttl time.Duration) *FeatureFlagCache {
+	return &FeatureFlagCache{
+		ttl:   ttl,
+		now:   time.Now,
+		flags: make(map[featureFlagKey]cachedFeatureFlag),
+	}
+}
+
+// propertiesHash returns a stable hash of a properties JSON object. The JSON
+// is re-encoded first so key order and whitespace don't change the hash.
+func propertiesHash(propertiesJSON string) string {
+	normalized := []byte(propertiesJSON)
+	var properties interface{}
+	if err := json.Unmarshal(normalized, &properties); err == nil {
+		if encoded, err := json.Marshal(properties); err == nil {
+			normalized = encoded
+		}
+	}
+
+	sum := sha256.Sum256(normalized)
+	return hex.EncodeToString(sum[:])
+}
+
+func newFeatureFlagKey(feature, userID, teamID, propertiesJSON string) featureFlagKey {
+	return featureFlagKey{
+		feature:    feature,
+		userID:     userID,
+		teamID:     teamID,
+		properties: propertiesHash(propertiesJSON),
+	}
+}
+
+// Store records the latest result for a flag evaluation
+func (c *FeatureFlagCache) Store(feature, userID, teamID, propertiesJSON string, enabled bool) {
+	key := newFeatureFlagKey(feature, userID, teamID, propertiesJSON)
+	now := c.now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.flags[key] = cachedFeatureFlag{
+		enabled:   enabled,
+		expiresAt: now.Add(c.ttl),
+	}
+
+	// Drop expired entries once per TTL so per-user keys don't pile up
+	if now.After(c.nextSweep) {
+		for key, flag := range c.flags {
+			if !now.Before(flag.expiresAt) {
+				delete(c.flags, key)
+			}
+		}
+		c.nextSweep = now.Add(c.ttl)
+	}
+}
+
+// Lookup returns the last known result for a flag evaluation if it was
+// fetched within the TTL
+func (c *FeatureFlagCache) Lookup(feature, userID, teamID, propertiesJSON string) (enabled bool, ok bool) {
+	key := newFeatureFlagKey(feature, userID, teamID, propertiesJSON)
+
+	c.mu.Lock()
+	flag, found := c.flags[key]
+	c.mu.Unlock()
+
+	if !found || !c.now().Before(flag.expiresAt) {
+		return false, false
+	}
+	return flag.enabled, true
+}
@@ -0,0 +1,42 @@
+package clients
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFeatureFlagCache_KeysOnProperties(t *testing.T) {
+	cache := NewFeatureFlagCache(time.Minute)
+
+	cache.Store("new-dashboard", "user-1", "team-1", `{"plan":"pro","region":"eu"}`, true)
+	cache.Store("new-dashboard", "user-1", "team-1", `{"plan":"free","region":"eu"}`, false)
+
+	if enabled, ok := cache.Lookup("new-dashboard", "user-1", "team-1", `{"plan":"pro","region":"eu"}`); !ok || !enabled {
+		t.Errorf("pro properties: got %v, %v; want true, true", enabled, ok)
+	}
+	if enabled, ok := cache.Lookup("new-dashboard", "user-1", "team-1", `{"plan":"free","region":"eu"}`); !ok || enabled {
+		t.Errorf("free properties: got %v, %v; want false, true", enabled, ok)
+	}
+
+	// Key order and whitespace don't matter
+	if enabled, ok := cache.Lookup("new-dashboard", "user-1", "team-1", `{ "region": "eu", "plan": "pro" }`); !ok || !enabled {
+		t.Errorf("reordered pro properties: got %v, %v; want true, true", enabled, ok)
+	}
+
+	if _, ok := cache.Lookup("new-dashboard", "user-1", "team-1", `{}`); ok {
+		t.Error("expected no cached result for properties never evaluated")
+	}
+}
+
+func TestFeatureFlagCache_Expires(t *testing.T) {
+	cache := NewFeatureFlagCache(time.Minute)
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	cache.Store("new-dashboard", "user-1", "team-1", `{}`, true)
+
+	now = now.Add(time.Minute)
+	if _, ok := cache.Lookup("new-dashboard", "user-1", "team-1", `{}`); ok {
+		t.Error("expected the entry to expire after the TTL")
+	}
+}
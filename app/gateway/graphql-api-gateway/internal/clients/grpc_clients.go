@@ -36,6 +36,10 @@ type ServicesConfig struct {
 	NotificationsService string
 	AnalyticsService     string
 	FeatureFlagsService  string
+
+	// LoadBalancing maps a service name (e.g. "billing-service") to its
+	// load balancing policy; unset services use round_robin
+	LoadBalancing map[string]string
 }
 
 // NewGRPCClients initializes all gRPC clients
@@ -46,8 +50,10 @@ func NewGRPCClients(config ServicesConfig, logger *zap.Logger) (*GRPCClients, er
 
 	// Initialize User Auth Service client
 	logger.Info("connecting to user-auth-service", zap.String("address", config.UserAuthService))
-	userAuthConn, err := grpc.Dial(
+	userAuthConn, err := dialService(
+		"user-auth-service",
 		config.UserAuthService,
+		config.LoadBalancing["user-auth-service"],
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 	)
 	if err != nil {
@@ -59,8 +65,10 @@ func NewGRPCClients(config ServicesConfig, logger *zap.Logger) (*GRPCClients, er
 
 	// Initialize Billing Service client
 	logger.Info("connecting to billing-service", zap.String("address", config.BillingService))
-	billingConn, err := grpc.Dial(
+	billingConn, err := dialService(
+		"billing-service",
 		config.BillingService,
+		config.LoadBalancing["billing-service"],
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 	)
 	if err != nil {
@@ -72,8 +80,10 @@ func NewGRPCClients(config ServicesConfig, logger *zap.Logger) (*GRPCClients, er
 
 	// Initialize LLM Gateway Service client
 	logger.Info("connecting to llm-gateway-service", zap.String("address", config.LLMGatewayService))
-	llmConn, err := grpc.Dial(
+	llmConn, err := dialService(
+		"llm-gateway-service",
 		config.LLMGatewayService,
+		config.LoadBalancing["llm-gateway-service"],
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 	)
 	if err != nil {
@@ -85,8 +95,10 @@ func NewGRPCClients(config ServicesConfig, logger *zap.Logger) (*GRPCClients, er
 
 	// Initialize Notifications Service client
 	logger.Info("connecting to notifications-service", zap.String("address", config.NotificationsService))
-	notificationsConn, err := grpc.Dial(
+	notificationsConn, err := dialService(
+		"notifications-service",
 		config.NotificationsService,
+		config.LoadBalancing["notifications-service"],
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 	)
 	if err != nil {
@@ -98,8 +110,10 @@ func NewGRPCClients(config ServicesConfig, logger *zap.Logger) (*GRPCClients, er
 
 	// Initialize Analytics Service client
 	logger.Info("connecting to analytics-service", zap.String("address", config.AnalyticsService))
-	analyticsConn, err := grpc.Dial(
+	analyticsConn, err := dialService(
+		"analytics-service",
 		config.AnalyticsService,
+		config.LoadBalancing["analytics-service"],
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
 	)
 	if err != nil {
@@ -111,9 +125,12 @@ func NewGRPCClients(config ServicesConfig, logger *zap.Logger) (*GRPCClients, er
 
 	// Initialize Feature Flags Service client
 	logger.Info("connecting to feature-flags-service", zap.String("address", config.FeatureFlagsService))
-	featureFlagsConn, err := grpc.Dial(
+	featureFlagsConn, err := dialService(
+		"feature-flags-service",
 		config.FeatureFlagsService,
+		config.LoadBalancing["feature-flags-service"],
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(ClientMetadataInterceptor()), // IP/UA for Unleash strategies
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to feature-flags-service: %w", err)
@@ -0,0 +1,35 @@
+package clients
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/haunted-saas/graphql-api-gateway/internal/middleware"
+)
+
+// Outgoing metadata keys read by backend services.
+// gRPC reserves "user-agent" for its own value, so the browser's is sent as x-user-agent.
+const (
+	forwardedForKey = "x-forwarded-for"
+	realIPKey       = "x-real-ip"
+	userAgentKey    = "x-user-agent"
+)
+
+// ClientMetadataInterceptor copies the HTTP caller's IP and user agent into outgoing gRPC metadata
+func ClientMetadataInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		pairs := make([]string, 0, 6)
+		if ip := middleware.GetClientIP(ctx); ip != "" {
+			pairs = append(pairs, forwardedForKey, ip, realIPKey, ip)
+		}
+		if userAgent := middleware.GetUserAgent(ctx); userAgent != "" {
+			pairs = append(pairs, userAgentKey, userAgent)
+		}
+		if len(pairs) > 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, pairs...)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
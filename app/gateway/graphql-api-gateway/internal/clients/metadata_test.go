@@ -0,0 +1,66 @@
+package clients
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/haunted-saas/graphql-api-gateway/internal/middleware"
+)
+
+func TestClientMetadataInterceptor_AttachesClientInfo(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+	req.RemoteAddr = "10.0.0.1:51234"
+	req.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+	req.Header.Set("User-Agent", "Mozilla/5.0 (test)")
+
+	// Capture the context the HTTP middleware hands to resolvers
+	var ctx context.Context
+	trusted, err := middleware.ParseTrustedProxies([]string{"10.0.0.0/8"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	middleware.ClientInfoMiddleware(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	var outgoing metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+
+	err = ClientMetadataInterceptor()(ctx, "/featureflags.v1.FeatureFlagsService/IsFeatureEnabled", nil, nil, nil, invoker)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := outgoing.Get("x-forwarded-for"); len(got) != 1 || got[0] != "203.0.113.7" {
+		t.Errorf("unexpected x-forwarded-for: %v", got)
+	}
+	if got := outgoing.Get("x-real-ip"); len(got) != 1 || got[0] != "203.0.113.7" {
+		t.Errorf("unexpected x-real-ip: %v", got)
+	}
+	if got := outgoing.Get("x-user-agent"); len(got) != 1 || got[0] != "Mozilla/5.0 (test)" {
+		t.Errorf("unexpected x-user-agent: %v", got)
+	}
+}
+
+func TestClientMetadataInterceptor_NoClientInfo(t *testing.T) {
+	var outgoing metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		outgoing, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+
+	if err := ClientMetadataInterceptor()(context.Background(), "/test", nil, nil, nil, invoker); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(outgoing) != 0 {
+		t.Errorf("expected no metadata, got %v", outgoing)
+	}
+}
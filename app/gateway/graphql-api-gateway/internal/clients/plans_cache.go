@@ -0,0 +1,73 @@
+package clients
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	billingv1 "github.com/haunted-saas/billing-service/proto/billing/v1"
+)
+
+// CachedBillingClient wraps the billing client and caches ListPlans responses.
+// Plans change rarely, so entries are only invalidated by TTL expiry.
+type CachedBillingClient struct {
+	billingv1.BillingServiceClient
+
+	ttl    time.Duration
+	logger *zap.Logger
+	now    func() time.Time
+
+	mu    sync.Mutex
+	plans map[bool]*cachedPlans
+}
+
+// cachedPlans is a ListPlans response with its expiry time
+type cachedPlans struct {
+	resp      *billingv1.ListPlansResponse
+	expiresAt time.Time
+}
+
+// NewCachedBillingClient creates a billing client that caches ListPlans for ttl
+func NewCachedBillingClient(client billingv1.BillingServiceClient, ttl time.Duration, logger *zap.Logger) *CachedBillingClient {
+	return &CachedBillingClient{
+		BillingServiceClient: client,
+		ttl:                  ttl,
+		logger:               logger,
+		now:                  time.Now,
+		plans:                make(map[bool]*cachedPlans),
+	}
+}
+
+// ListPlans returns the cached plans list, fetching from billing on a miss
+func (c *CachedBillingClient) ListPlans(ctx context.Context, in *billingv1.ListPlansRequest, opts ...grpc.CallOption) (*billingv1.ListPlansResponse, error) {
+	activeOnly := in.GetActiveOnly()
+
+	c.mu.Lock()
+	entry, ok := c.plans[activeOnly]
+	c.mu.Unlock()
+
+	if ok && c.now().Before(entry.expiresAt) {
+		return entry.resp, nil
+	}
+
+	resp, err := c.BillingServiceClient.ListPlans(ctx, in, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.plans[activeOnly] = &cachedPlans{
+		resp:      resp,
+		expiresAt: c.now().Add(c.ttl),
+	}
+	c.mu.Unlock()
+
+	c.logger.Debug("plans cache refreshed",
+		zap.Bool("active_only", activeOnly),
+		zap.Int("count", len(resp.Plans)))
+
+	return resp, nil
+}
@@ -0,0 +1,56 @@
+package clients
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+
+	billingv1 "github.com/haunted-saas/billing-service/proto/billing/v1"
+)
+
+// countingBillingClient counts ListPlans calls made to the backend
+type countingBillingClient struct {
+	billingv1.BillingServiceClient
+	calls int
+}
+
+func (c *countingBillingClient) ListPlans(ctx context.Context, in *billingv1.ListPlansRequest, opts ...grpc.CallOption) (*billingv1.ListPlansResponse, error) {
+	c.calls++
+	return &billingv1.ListPlansResponse{
+		Plans: []*billingv1.Plan{{Id: "plan_pro", Name: "Pro"}},
+	}, nil
+}
+
+func TestCachedBillingClient_ListPlans(t *testing.T) {
+	backend := &countingBillingClient{}
+	cache := NewCachedBillingClient(backend, time.Minute, zap.NewNop())
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		resp, err := cache.ListPlans(context.Background(), &billingv1.ListPlansRequest{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(resp.Plans) != 1 {
+			t.Fatalf("expected 1 plan, got %d", len(resp.Plans))
+		}
+	}
+
+	if backend.calls != 1 {
+		t.Errorf("expected 1 backend call for two quick lookups, got %d", backend.calls)
+	}
+
+	// Expired entries are refetched
+	now = now.Add(2 * time.Minute)
+	if _, err := cache.ListPlans(context.Background(), &billingv1.ListPlansRequest{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if backend.calls != 2 {
+		t.Errorf("expected backend to be called again after TTL expiry, got %d calls", backend.calls)
+	}
+}
@@ -2,16 +2,22 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
 	"strconv"
+	"strings"
+
+	"github.com/haunted-saas/pkg/logsampling"
 )
 
 // Config holds the gateway configuration
 type Config struct {
-	Server   ServerConfig
-	Services ServicesConfig
-	Auth     AuthConfig
-	Logging  LoggingConfig
+	Server    ServerConfig
+	Services  ServicesConfig
+	Auth      AuthConfig
+	Cache     CacheConfig
+	RateLimit RateLimitConfig
+	Logging   LoggingConfig
 }
 
 // ServerConfig holds HTTP server configuration
@@ -19,9 +25,27 @@ type ServerConfig struct {
 	Port int
 	Host string
 	Env  string
+
+	// Introspection exposes the schema via __schema/__type queries.
+	// Defaults to on in development only.
+	Introspection bool
+
+	MaxBodyBytes    int64 // Requests to /graphql with a larger body are rejected with 413 (0 disables)
+	ReadTimeoutSec  int   // HTTP server timeouts; 0 means no timeout
+	WriteTimeoutSec int
+	IdleTimeoutSec  int
+
+	ShutdownTimeoutSec int // How long shutdown waits for in-flight requests before closing them (0 waits indefinitely)
+
+	// TrustedProxies lists the load balancers (CIDRs or IPs) whose
+	// X-Forwarded-For and X-Real-IP headers are believed. Empty uses the
+	// connection's remote address for every request.
+	TrustedProxies []string
 }
 
-// ServicesConfig holds gRPC service addresses
+// ServicesConfig holds gRPC service addresses. An address may be a
+// host:port, a gRPC target such as dns:///host:port, or a comma-separated
+// list of replicas.
 type ServicesConfig struct {
 	UserAuthService      string
 	BillingService       string
@@ -29,26 +53,89 @@ type ServicesConfig struct {
 	NotificationsService string
 	AnalyticsService     string
 	FeatureFlagsService  string
+
+	// LoadBalancing maps a service name to its load balancing policy
+	// ("round_robin" or "pick_first")
+	LoadBalancing map[string]string
 }
 
 // AuthConfig holds authentication configuration
 type AuthConfig struct {
-	JWTSecret string
+	JWTSecret  string
+	CookieName string // Session cookie accepted in place of a bearer token ("" disables)
+
+	// CookieOrigins lists the origins allowed to authenticate with the
+	// session cookie; a cookie sent from any other origin is ignored
+	CookieOrigins []string
+
+	// AnonymousOperations lists the root fields unauthenticated callers may
+	// select; any other operation is rejected before execution
+	AnonymousOperations []string
+
+	// AdminAPIToken is sent as x-admin-token on admin-only billing and
+	// feature-flags requests made for users with the admin role ("" leaves
+	// the services to reject them)
+	AdminAPIToken string
+}
+
+// CacheConfig holds response cache configuration
+type CacheConfig struct {
+	PlansTTLSec        int // 0 disables the plans cache
+	FeatureFlagsTTLSec int // How long a flag result can be served while feature-flags-service is unavailable (0 disables)
+}
+
+// RateLimitConfig holds per-caller rate limiting for /graphql. Callers are
+// identified by user ID when authenticated, otherwise by client IP.
+type RateLimitConfig struct {
+	Requests  int // Requests allowed per window (0 disables rate limiting)
+	WindowSec int
+}
+
+// defaultAnonymousOperations are the root fields anonymous callers may use
+// when ANONYMOUS_OPERATIONS isn't set: signing in and up, refreshing an
+// expired token, password resets, the public plan list, feature flags for
+// the landing page, and analytics events from visitors who aren't signed in
+var defaultAnonymousOperations = []string{
+	"register",
+	"login",
+	"logout",
+	"refreshToken",
+	"requestPasswordReset",
+	"resetPassword",
+	"plans",
+	"isFeatureEnabled",
+	"featureFlag",
+	"featureVariant",
+	"trackEvent",
 }
 
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
-	Level  string
-	Format string
+	Level    string
+	Format   string
+	Sampling logsampling.Config
 }
 
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
+	env := getEnv("ENV", "development")
+	lbPolicy := getEnv("GRPC_LB_POLICY", "round_robin")
+
 	cfg := &Config{
 		Server: ServerConfig{
-			Port: getEnvInt("PORT", 8080),
-			Host: getEnv("HOST", "0.0.0.0"),
-			Env:  getEnv("ENV", "development"),
+			Port:          getEnvInt("PORT", 8080),
+			Host:          getEnv("HOST", "0.0.0.0"),
+			Env:           env,
+			Introspection: getEnvBool("GRAPHQL_INTROSPECTION", env == "development"),
+
+			MaxBodyBytes:    int64(getEnvInt("MAX_REQUEST_BODY_BYTES", 1<<20)),
+			ReadTimeoutSec:  getEnvInt("HTTP_READ_TIMEOUT_SECONDS", 15),
+			WriteTimeoutSec: getEnvInt("HTTP_WRITE_TIMEOUT_SECONDS", 15),
+			IdleTimeoutSec:  getEnvInt("HTTP_IDLE_TIMEOUT_SECONDS", 60),
+
+			ShutdownTimeoutSec: getEnvInt("SHUTDOWN_TIMEOUT_SECONDS", 30),
+
+			TrustedProxies: getEnvList("TRUSTED_PROXIES", ""),
 		},
 		Services: ServicesConfig{
 			UserAuthService:      getEnv("USER_AUTH_SERVICE", "localhost:50051"),
@@ -57,13 +144,36 @@ func Load() (*Config, error) {
 			NotificationsService: getEnv("NOTIFICATIONS_SERVICE", "localhost:50054"),
 			AnalyticsService:     getEnv("ANALYTICS_SERVICE", "localhost:50055"),
 			FeatureFlagsService:  getEnv("FEATURE_FLAGS_SERVICE", "localhost:50056"),
+			LoadBalancing: map[string]string{
+				"user-auth-service":     getEnv("USER_AUTH_SERVICE_LB_POLICY", lbPolicy),
+				"billing-service":       getEnv("BILLING_SERVICE_LB_POLICY", lbPolicy),
+				"llm-gateway-service":   getEnv("LLM_GATEWAY_SERVICE_LB_POLICY", lbPolicy),
+				"notifications-service": getEnv("NOTIFICATIONS_SERVICE_LB_POLICY", lbPolicy),
+				"analytics-service":     getEnv("ANALYTICS_SERVICE_LB_POLICY", lbPolicy),
+				"feature-flags-service": getEnv("FEATURE_FLAGS_SERVICE_LB_POLICY", lbPolicy),
+			},
 		},
 		Auth: AuthConfig{
-			JWTSecret: getEnv("JWT_SECRET", ""),
+			JWTSecret:  getEnv("JWT_SECRET", ""),
+			CookieName: getEnv("AUTH_COOKIE_NAME", ""),
+
+			CookieOrigins:       getEnvList("AUTH_COOKIE_ORIGINS", ""),
+			AnonymousOperations: getEnvList("ANONYMOUS_OPERATIONS", strings.Join(defaultAnonymousOperations, ",")),
+
+			AdminAPIToken: getEnv("ADMIN_API_TOKEN", ""),
+		},
+		Cache: CacheConfig{
+			PlansTTLSec:        getEnvInt("PLANS_CACHE_TTL_SECONDS", 60),
+			FeatureFlagsTTLSec: getEnvInt("FEATURE_FLAGS_CACHE_TTL_SECONDS", 30),
+		},
+		RateLimit: RateLimitConfig{
+			Requests:  getEnvInt("RATE_LIMIT_REQUESTS", 0),
+			WindowSec: getEnvInt("RATE_LIMIT_WINDOW_SECONDS", 60),
 		},
 		Logging: LoggingConfig{
-			Level:  getEnv("LOG_LEVEL", "info"),
-			Format: getEnv("LOG_FORMAT", "json"),
+			Level:    getEnv("LOG_LEVEL", "info"),
+			Format:   getEnv("LOG_FORMAT", "json"),
+			Sampling: logsampling.FromEnv(),
 		},
 	}
 
@@ -81,6 +191,54 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("JWT_SECRET is required in production")
 	}
 
+	if c.Auth.CookieName != "" && len(c.Auth.CookieOrigins) == 0 {
+		return fmt.Errorf("AUTH_COOKIE_ORIGINS is required when AUTH_COOKIE_NAME is set")
+	}
+
+	if c.Server.MaxBodyBytes < 0 {
+		return fmt.Errorf("MAX_REQUEST_BODY_BYTES cannot be negative")
+	}
+
+	if c.Server.ReadTimeoutSec < 0 || c.Server.WriteTimeoutSec < 0 || c.Server.IdleTimeoutSec < 0 {
+		return fmt.Errorf("HTTP_READ_TIMEOUT_SECONDS, HTTP_WRITE_TIMEOUT_SECONDS and HTTP_IDLE_TIMEOUT_SECONDS cannot be negative")
+	}
+
+	if c.Server.ShutdownTimeoutSec < 0 {
+		return fmt.Errorf("SHUTDOWN_TIMEOUT_SECONDS cannot be negative")
+	}
+
+	for _, proxy := range c.Server.TrustedProxies {
+		if _, _, err := net.ParseCIDR(proxy); err != nil && net.ParseIP(proxy) == nil {
+			return fmt.Errorf("invalid TRUSTED_PROXIES entry %q (use a CIDR or IP)", proxy)
+		}
+	}
+
+	if c.Cache.PlansTTLSec < 0 {
+		return fmt.Errorf("PLANS_CACHE_TTL_SECONDS cannot be negative")
+	}
+
+	if c.Cache.FeatureFlagsTTLSec < 0 {
+		return fmt.Errorf("FEATURE_FLAGS_CACHE_TTL_SECONDS cannot be negative")
+	}
+
+	if c.RateLimit.Requests < 0 {
+		return fmt.Errorf("RATE_LIMIT_REQUESTS cannot be negative")
+	}
+
+	if c.RateLimit.Requests > 0 && c.RateLimit.WindowSec < 1 {
+		return fmt.Errorf("RATE_LIMIT_WINDOW_SECONDS must be at least 1")
+	}
+
+	for service, policy := range c.Services.LoadBalancing {
+		if policy != "round_robin" && policy != "pick_first" {
+			return fmt.Errorf("unsupported load balancing policy %q for %s (use round_robin or pick_first)", policy, service)
+		}
+	}
+
+	if err := c.Logging.Sampling.Validate(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -101,3 +259,30 @@ func getEnvInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+// getEnvList parses a comma-separated list, dropping empty entries. Unlike
+// getEnv, a variable set to "" yields an empty list rather than the default,
+// so ANONYMOUS_OPERATIONS= allows nothing.
+func getEnvList(key, defaultValue string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		value = defaultValue
+	}
+
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,155 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/haunted-saas/pkg/logsampling"
+)
+
+func TestLoad_SamplingFromEnv(t *testing.T) {
+	t.Setenv("LOG_SAMPLING_INITIAL", "25")
+	t.Setenv("LOG_SAMPLING_THEREAFTER", "200")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sampling := cfg.Logging.Sampling.Zap()
+	if sampling == nil || sampling.Initial != 25 || sampling.Thereafter != 200 {
+		t.Errorf("expected sampling from env, got %+v", sampling)
+	}
+}
+
+func TestValidate_InvalidSampling(t *testing.T) {
+	cfg := &Config{Logging: LoggingConfig{Sampling: logsampling.Config{Initial: -1}}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected negative LOG_SAMPLING_INITIAL to be rejected")
+	}
+
+	cfg = &Config{Logging: LoggingConfig{Sampling: logsampling.Config{Initial: 10, Thereafter: 0}}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected LOG_SAMPLING_THEREAFTER=0 to be rejected when sampling is enabled")
+	}
+}
+
+func TestLoad_IntrospectionDefaultsToDevelopmentOnly(t *testing.T) {
+	t.Setenv("ENV", "development")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Server.Introspection {
+		t.Error("expected introspection to be enabled in development")
+	}
+
+	t.Setenv("ENV", "production")
+	t.Setenv("JWT_SECRET", "secret")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.Server.Introspection {
+		t.Error("expected introspection to be disabled in production by default")
+	}
+
+	t.Setenv("GRAPHQL_INTROSPECTION", "true")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.Server.Introspection {
+		t.Error("expected GRAPHQL_INTROSPECTION to override the default")
+	}
+}
+
+func TestLoad_LoadBalancingFromEnv(t *testing.T) {
+	t.Setenv("GRPC_LB_POLICY", "pick_first")
+	t.Setenv("BILLING_SERVICE", "billing-1:50052,billing-2:50052")
+	t.Setenv("BILLING_SERVICE_LB_POLICY", "round_robin")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if cfg.Services.BillingService != "billing-1:50052,billing-2:50052" {
+		t.Errorf("expected billing replica list, got %q", cfg.Services.BillingService)
+	}
+	if got := cfg.Services.LoadBalancing["billing-service"]; got != "round_robin" {
+		t.Errorf("expected per-service policy round_robin, got %q", got)
+	}
+	if got := cfg.Services.LoadBalancing["user-auth-service"]; got != "pick_first" {
+		t.Errorf("expected default policy pick_first, got %q", got)
+	}
+}
+
+func TestValidate_UnknownLoadBalancingPolicy(t *testing.T) {
+	cfg := &Config{Services: ServicesConfig{LoadBalancing: map[string]string{"billing-service": "random"}}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected unknown load balancing policy to be rejected")
+	}
+}
+
+func TestValidate_CookieRequiresOrigins(t *testing.T) {
+	cfg := &Config{Auth: AuthConfig{CookieName: "haunted_session"}}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected cookie auth without AUTH_COOKIE_ORIGINS to be rejected")
+	}
+
+	cfg.Auth.CookieOrigins = []string{"https://app.example.com"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestValidate_NegativeHTTPLimits(t *testing.T) {
+	for _, server := range []ServerConfig{{MaxBodyBytes: -1}, {ReadTimeoutSec: -1}, {IdleTimeoutSec: -1}} {
+		cfg := &Config{Server: server}
+		if err := cfg.Validate(); err == nil {
+			t.Errorf("expected negative HTTP limits to be rejected: %+v", server)
+		}
+	}
+}
+
+func TestValidate_TrustedProxies(t *testing.T) {
+	cfg := &Config{Server: ServerConfig{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.10"}}}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	cfg.Server.TrustedProxies = []string{"load-balancer"}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected an invalid trusted proxy to be rejected")
+	}
+}
+
+func TestLoad_AnonymousOperationsFromEnv(t *testing.T) {
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Auth.AnonymousOperations) != len(defaultAnonymousOperations) {
+		t.Errorf("expected the default anonymous operations, got %v", cfg.Auth.AnonymousOperations)
+	}
+
+	t.Setenv("ANONYMOUS_OPERATIONS", "login, plans,")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := strings.Join(cfg.Auth.AnonymousOperations, ","); got != "login,plans" {
+		t.Errorf("expected login,plans, got %q", got)
+	}
+
+	// An empty value allows nothing anonymously
+	t.Setenv("ANONYMOUS_OPERATIONS", "")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(cfg.Auth.AnonymousOperations) != 0 {
+		t.Errorf("expected no anonymous operations, got %v", cfg.Auth.AnonymousOperations)
+	}
+}
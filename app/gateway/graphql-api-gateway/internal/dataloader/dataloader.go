@@ -8,6 +8,8 @@ import (
 
 	"github.com/graph-gophers/dataloader/v7"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	billingv1 "github.com/haunted-saas/billing-service/proto/billing/v1"
 	userauthv1 "github.com/haunted-saas/user-auth-service/proto/userauth/v1"
@@ -52,11 +54,13 @@ func NewLoaders(clients Clients, logger *zap.Logger) *Loaders {
 	}
 }
 
-// Middleware injects dataloaders into the request context
-func Middleware(loaders *Loaders) func(next http.Handler) http.Handler {
+// Middleware injects a fresh set of dataloaders into each request context.
+// Loaders cache every key they load, so sharing them across requests would
+// keep serving the first result for a user or plan indefinitely.
+func Middleware(clients Clients, logger *zap.Logger) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			ctx := context.WithValue(r.Context(), loadersKey, loaders)
+			ctx := context.WithValue(r.Context(), loadersKey, NewLoaders(clients, logger))
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -67,21 +71,35 @@ func For(ctx context.Context) *Loaders {
 	return ctx.Value(loadersKey).(*Loaders)
 }
 
-// userBatchFunc batches user lookups
-// Note: GetUser RPC doesn't exist in proto yet, so this is a placeholder
-// Users will be fetched directly in resolvers for now
+// userBatchFunc batches user lookups into a single GetUsers call
 func userBatchFunc(client userauthv1.UserAuthServiceClient, logger *zap.Logger) dataloader.BatchFunc[string, *userauthv1.User] {
 	return func(ctx context.Context, userIDs []string) []*dataloader.Result[*userauthv1.User] {
 		logger.Debug("batching user lookups", zap.Int("count", len(userIDs)))
 
 		results := make([]*dataloader.Result[*userauthv1.User], len(userIDs))
 
-		// TODO: Implement batch user lookup when GetUser RPC is added to proto
-		// For now, return errors - users will be fetched via ValidateToken in resolvers
+		usersResp, err := client.GetUsers(ctx, &userauthv1.GetUsersRequest{UserIds: userIDs})
+		if err != nil {
+			logger.Error("failed to fetch users", zap.Error(err))
+			for i := range results {
+				results[i] = &dataloader.Result[*userauthv1.User]{Error: err}
+			}
+			return results
+		}
+
+		// Unknown IDs are omitted, so match the response back to the keys
+		userMap := make(map[string]*userauthv1.User, len(usersResp.Users))
+		for _, user := range usersResp.Users {
+			userMap[user.Id] = user
+		}
+
 		for i, userID := range userIDs {
-			logger.Warn("user dataloader not implemented - GetUser RPC missing from proto", zap.String("user_id", userID))
-			results[i] = &dataloader.Result[*userauthv1.User]{
-				Error: errors.New("GetUser RPC not implemented"),
+			if user, ok := userMap[userID]; ok {
+				results[i] = &dataloader.Result[*userauthv1.User]{Data: user}
+			} else {
+				results[i] = &dataloader.Result[*userauthv1.User]{
+					Error: status.Error(codes.NotFound, "user not found"),
+				}
 			}
 		}
 
@@ -115,15 +133,14 @@ func subscriptionBatchFunc(client billingv1.BillingServiceClient, logger *zap.Lo
 	}
 }
 
-// planBatchFunc batches plan lookups
+// planBatchFunc batches plan lookups into a single GetPlansByIDs call
 func planBatchFunc(client billingv1.BillingServiceClient, logger *zap.Logger) dataloader.BatchFunc[string, *billingv1.Plan] {
 	return func(ctx context.Context, planIDs []string) []*dataloader.Result[*billingv1.Plan] {
 		logger.Debug("batching plan lookups", zap.Int("count", len(planIDs)))
 
 		results := make([]*dataloader.Result[*billingv1.Plan], len(planIDs))
 
-		// Fetch all plans once
-		plansResp, err := client.ListPlans(ctx, &billingv1.ListPlansRequest{})
+		plansResp, err := client.GetPlansByIDs(ctx, &billingv1.GetPlansByIDsRequest{PlanIds: planIDs})
 		if err != nil {
 			logger.Error("failed to fetch plans", zap.Error(err))
 			for i := range results {
@@ -132,13 +149,12 @@ func planBatchFunc(client billingv1.BillingServiceClient, logger *zap.Logger) da
 			return results
 		}
 
-		// Create a map for quick lookup
-		planMap := make(map[string]*billingv1.Plan)
+		// Unknown IDs are omitted, so match the response back to the keys
+		planMap := make(map[string]*billingv1.Plan, len(plansResp.Plans))
 		for _, plan := range plansResp.Plans {
 			planMap[plan.Id] = plan
 		}
 
-		// Match plans to requested IDs
 		for i, planID := range planIDs {
 			if plan, ok := planMap[planID]; ok {
 				results[i] = &dataloader.Result[*billingv1.Plan]{Data: plan}
@@ -0,0 +1,149 @@
+package dataloader
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	billingv1 "github.com/haunted-saas/billing-service/proto/billing/v1"
+	userauthv1 "github.com/haunted-saas/user-auth-service/proto/userauth/v1"
+)
+
+// plansByIDBillingClient serves GetPlansByIDs from a fixed catalog and
+// records each request. Any other RPC panics via the nil embedded client.
+type plansByIDBillingClient struct {
+	billingv1.BillingServiceClient
+	plans map[string]*billingv1.Plan
+
+	mu       sync.Mutex
+	requests [][]string
+}
+
+func (c *plansByIDBillingClient) GetPlansByIDs(ctx context.Context, in *billingv1.GetPlansByIDsRequest, opts ...grpc.CallOption) (*billingv1.GetPlansByIDsResponse, error) {
+	c.mu.Lock()
+	c.requests = append(c.requests, in.PlanIds)
+	c.mu.Unlock()
+
+	resp := &billingv1.GetPlansByIDsResponse{}
+	for _, id := range in.PlanIds {
+		if plan, ok := c.plans[id]; ok {
+			resp.Plans = append(resp.Plans, plan)
+		}
+	}
+	return resp, nil
+}
+
+func TestPlanByID_BatchesRequestedIDs(t *testing.T) {
+	client := &plansByIDBillingClient{plans: map[string]*billingv1.Plan{
+		"plan_free": {Id: "plan_free", Name: "Free"},
+		"plan_pro":  {Id: "plan_pro", Name: "Pro"},
+		"plan_team": {Id: "plan_team", Name: "Team"},
+	}}
+	loaders := NewLoaders(Clients{Billing: client}, zap.NewNop())
+
+	ctx := context.Background()
+	pro := loaders.PlanByID.Load(ctx, "plan_pro")
+	missing := loaders.PlanByID.Load(ctx, "plan_missing")
+	team := loaders.PlanByID.Load(ctx, "plan_team")
+
+	if plan, err := pro(); err != nil || plan.Name != "Pro" {
+		t.Errorf("expected plan_pro to load, got %v, %v", plan, err)
+	}
+	if plan, err := team(); err != nil || plan.Name != "Team" {
+		t.Errorf("expected plan_team to load, got %v, %v", plan, err)
+	}
+	if _, err := missing(); err == nil {
+		t.Error("expected an error for an unknown plan")
+	}
+
+	if len(client.requests) != 1 {
+		t.Fatalf("expected one GetPlansByIDs call, got %d", len(client.requests))
+	}
+	if got := len(client.requests[0]); got != 3 {
+		t.Errorf("expected only the 3 requested IDs to be fetched, got %v", client.requests[0])
+	}
+}
+
+// usersByIDUserAuthClient serves GetUsers from a fixed directory and records
+// each request. Any other RPC panics via the nil embedded client.
+type usersByIDUserAuthClient struct {
+	userauthv1.UserAuthServiceClient
+	users map[string]*userauthv1.User
+
+	mu       sync.Mutex
+	requests [][]string
+}
+
+func (c *usersByIDUserAuthClient) GetUsers(ctx context.Context, in *userauthv1.GetUsersRequest, opts ...grpc.CallOption) (*userauthv1.GetUsersResponse, error) {
+	c.mu.Lock()
+	c.requests = append(c.requests, in.UserIds)
+	c.mu.Unlock()
+
+	resp := &userauthv1.GetUsersResponse{}
+	for _, id := range in.UserIds {
+		if user, ok := c.users[id]; ok {
+			resp.Users = append(resp.Users, user)
+		}
+	}
+	return resp, nil
+}
+
+func TestUserByID_BatchesRequestedIDs(t *testing.T) {
+	client := &usersByIDUserAuthClient{users: map[string]*userauthv1.User{
+		"user-1": {Id: "user-1", Email: "one@example.com"},
+		"user-2": {Id: "user-2", Email: "two@example.com"},
+	}}
+	loaders := NewLoaders(Clients{UserAuth: client}, zap.NewNop())
+
+	ctx := context.Background()
+	first := loaders.UserByID.Load(ctx, "user-1")
+	missing := loaders.UserByID.Load(ctx, "user-missing")
+	second := loaders.UserByID.Load(ctx, "user-2")
+
+	if user, err := first(); err != nil || user.Email != "one@example.com" {
+		t.Errorf("expected user-1 to load, got %v, %v", user, err)
+	}
+	if user, err := second(); err != nil || user.Email != "two@example.com" {
+		t.Errorf("expected user-2 to load, got %v, %v", user, err)
+	}
+	if _, err := missing(); status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for an unknown user, got %v", err)
+	}
+
+	if len(client.requests) != 1 {
+		t.Fatalf("expected one GetUsers call, got %d", len(client.requests))
+	}
+	if got := len(client.requests[0]); got != 3 {
+		t.Errorf("expected only the 3 requested IDs to be fetched, got %v", client.requests[0])
+	}
+}
+
+func TestMiddleware_FreshLoadersPerRequest(t *testing.T) {
+	client := &usersByIDUserAuthClient{users: map[string]*userauthv1.User{
+		"user-1": {Id: "user-1", Name: "Before"},
+	}}
+
+	var names []string
+	handler := Middleware(Clients{UserAuth: client}, zap.NewNop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := For(r.Context()).UserByID.Load(r.Context(), "user-1")()
+		if err != nil {
+			t.Fatalf("failed to load user: %v", err)
+		}
+		names = append(names, user.Name)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/graphql", nil))
+	client.users["user-1"] = &userauthv1.User{Id: "user-1", Name: "After"}
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/graphql", nil))
+
+	if len(names) != 2 || names[1] != "After" {
+		t.Errorf("expected the second request to see the updated user, got %v", names)
+	}
+}
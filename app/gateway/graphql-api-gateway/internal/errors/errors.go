@@ -6,6 +6,27 @@ import (
 	"google.golang.org/grpc/status"
 )
 
+// GraphQL error codes set as extensions.code. Clients branch on these, so
+// their values must stay stable.
+const (
+	CodeBadRequest         = "BAD_REQUEST"
+	CodeNotFound           = "NOT_FOUND"
+	CodeAlreadyExists      = "ALREADY_EXISTS"
+	CodeForbidden          = "FORBIDDEN"
+	CodeUnauthenticated    = "UNAUTHENTICATED"
+	CodeInvalidCredentials = "INVALID_CREDENTIALS" // A password the caller supplied was wrong
+	CodeRateLimitExceeded  = "RATE_LIMIT_EXCEEDED" // ResourceExhausted: rate limit or quota hit, retry later
+	CodePreconditionFailed = "PRECONDITION_FAILED"
+	CodeAborted            = "ABORTED"
+	CodeOutOfRange         = "OUT_OF_RANGE"
+	CodeNotImplemented     = "NOT_IMPLEMENTED"
+	CodeServiceUnavailable = "SERVICE_UNAVAILABLE" // Unavailable: backend service down
+	CodeTimeout            = "TIMEOUT"             // DeadlineExceeded: backend took too long
+	CodeCanceled           = "CANCELED"
+	CodeDataLoss           = "DATA_LOSS"
+	CodeInternalError      = "INTERNAL_ERROR"
+)
+
 // ConvertGRPCError converts a gRPC error to a user-friendly GraphQL error
 func ConvertGRPCError(err error) error {
 	if err == nil {
@@ -18,7 +39,7 @@ func ConvertGRPCError(err error) error {
 		return &gqlerror.Error{
 			Message: "An unexpected error occurred",
 			Extensions: map[string]interface{}{
-				"code": "INTERNAL_ERROR",
+				"code": CodeInternalError,
 			},
 		}
 	}
@@ -32,7 +53,7 @@ func ConvertGRPCError(err error) error {
 		return &gqlerror.Error{
 			Message: st.Message(),
 			Extensions: map[string]interface{}{
-				"code": "BAD_REQUEST",
+				"code": CodeBadRequest,
 			},
 		}
 
@@ -40,7 +61,7 @@ func ConvertGRPCError(err error) error {
 		return &gqlerror.Error{
 			Message: st.Message(),
 			Extensions: map[string]interface{}{
-				"code": "NOT_FOUND",
+				"code": CodeNotFound,
 			},
 		}
 
@@ -48,7 +69,7 @@ func ConvertGRPCError(err error) error {
 		return &gqlerror.Error{
 			Message: st.Message(),
 			Extensions: map[string]interface{}{
-				"code": "ALREADY_EXISTS",
+				"code": CodeAlreadyExists,
 			},
 		}
 
@@ -56,7 +77,7 @@ func ConvertGRPCError(err error) error {
 		return &gqlerror.Error{
 			Message: "You don't have permission to perform this action",
 			Extensions: map[string]interface{}{
-				"code": "FORBIDDEN",
+				"code": CodeForbidden,
 			},
 		}
 
@@ -64,7 +85,7 @@ func ConvertGRPCError(err error) error {
 		return &gqlerror.Error{
 			Message: "Authentication required",
 			Extensions: map[string]interface{}{
-				"code": "UNAUTHENTICATED",
+				"code": CodeUnauthenticated,
 			},
 		}
 
@@ -72,7 +93,7 @@ func ConvertGRPCError(err error) error {
 		return &gqlerror.Error{
 			Message: "Rate limit exceeded. Please try again later",
 			Extensions: map[string]interface{}{
-				"code": "RATE_LIMIT_EXCEEDED",
+				"code": CodeRateLimitExceeded,
 			},
 		}
 
@@ -80,7 +101,7 @@ func ConvertGRPCError(err error) error {
 		return &gqlerror.Error{
 			Message: st.Message(),
 			Extensions: map[string]interface{}{
-				"code": "PRECONDITION_FAILED",
+				"code": CodePreconditionFailed,
 			},
 		}
 
@@ -88,7 +109,7 @@ func ConvertGRPCError(err error) error {
 		return &gqlerror.Error{
 			Message: "Operation was aborted. Please try again",
 			Extensions: map[string]interface{}{
-				"code": "ABORTED",
+				"code": CodeAborted,
 			},
 		}
 
@@ -96,7 +117,7 @@ func ConvertGRPCError(err error) error {
 		return &gqlerror.Error{
 			Message: st.Message(),
 			Extensions: map[string]interface{}{
-				"code": "OUT_OF_RANGE",
+				"code": CodeOutOfRange,
 			},
 		}
 
@@ -104,7 +125,7 @@ func ConvertGRPCError(err error) error {
 		return &gqlerror.Error{
 			Message: "This feature is not yet implemented",
 			Extensions: map[string]interface{}{
-				"code": "NOT_IMPLEMENTED",
+				"code": CodeNotImplemented,
 			},
 		}
 
@@ -112,7 +133,7 @@ func ConvertGRPCError(err error) error {
 		return &gqlerror.Error{
 			Message: "Service temporarily unavailable. Please try again later",
 			Extensions: map[string]interface{}{
-				"code": "SERVICE_UNAVAILABLE",
+				"code": CodeServiceUnavailable,
 			},
 		}
 
@@ -120,7 +141,7 @@ func ConvertGRPCError(err error) error {
 		return &gqlerror.Error{
 			Message: "Request timeout. Please try again",
 			Extensions: map[string]interface{}{
-				"code": "TIMEOUT",
+				"code": CodeTimeout,
 			},
 		}
 
@@ -128,7 +149,7 @@ func ConvertGRPCError(err error) error {
 		return &gqlerror.Error{
 			Message: "Request was canceled",
 			Extensions: map[string]interface{}{
-				"code": "CANCELED",
+				"code": CodeCanceled,
 			},
 		}
 
@@ -136,7 +157,7 @@ func ConvertGRPCError(err error) error {
 		return &gqlerror.Error{
 			Message: "Data loss or corruption detected",
 			Extensions: map[string]interface{}{
-				"code": "DATA_LOSS",
+				"code": CodeDataLoss,
 			},
 		}
 
@@ -147,7 +168,7 @@ func ConvertGRPCError(err error) error {
 		return &gqlerror.Error{
 			Message: "An internal error occurred. Please try again later",
 			Extensions: map[string]interface{}{
-				"code": "INTERNAL_ERROR",
+				"code": CodeInternalError,
 			},
 		}
 	}
@@ -158,7 +179,7 @@ func NewBadRequestError(message string) error {
 	return &gqlerror.Error{
 		Message: message,
 		Extensions: map[string]interface{}{
-			"code": "BAD_REQUEST",
+			"code": CodeBadRequest,
 		},
 	}
 }
@@ -168,7 +189,7 @@ func NewNotFoundError(resource string) error {
 	return &gqlerror.Error{
 		Message: resource + " not found",
 		Extensions: map[string]interface{}{
-			"code": "NOT_FOUND",
+			"code": CodeNotFound,
 		},
 	}
 }
@@ -178,7 +199,18 @@ func NewUnauthorizedError() error {
 	return &gqlerror.Error{
 		Message: "Authentication required",
 		Extensions: map[string]interface{}{
-			"code": "UNAUTHENTICATED",
+			"code": CodeUnauthenticated,
+		},
+	}
+}
+
+// NewInvalidCredentialsError creates an error for a wrong password from a
+// caller who is otherwise authenticated
+func NewInvalidCredentialsError(message string) error {
+	return &gqlerror.Error{
+		Message: message,
+		Extensions: map[string]interface{}{
+			"code": CodeInvalidCredentials,
 		},
 	}
 }
@@ -188,7 +220,7 @@ func NewForbiddenError() error {
 	return &gqlerror.Error{
 		Message: "You don't have permission to perform this action",
 		Extensions: map[string]interface{}{
-			"code": "FORBIDDEN",
+			"code": CodeForbidden,
 		},
 	}
 }
@@ -198,7 +230,7 @@ func NewInternalError() error {
 	return &gqlerror.Error{
 		Message: "An internal error occurred. Please try again later",
 		Extensions: map[string]interface{}{
-			"code": "INTERNAL_ERROR",
+			"code": CodeInternalError,
 		},
 	}
 }
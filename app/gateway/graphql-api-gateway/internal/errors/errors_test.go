@@ -0,0 +1,80 @@
+package errors
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestConvertGRPCError_Codes(t *testing.T) {
+	tests := []struct {
+		code     codes.Code
+		wantCode string
+	}{
+		{code: codes.InvalidArgument, wantCode: "BAD_REQUEST"},
+		{code: codes.NotFound, wantCode: "NOT_FOUND"},
+		{code: codes.AlreadyExists, wantCode: "ALREADY_EXISTS"},
+		{code: codes.PermissionDenied, wantCode: "FORBIDDEN"},
+		{code: codes.Unauthenticated, wantCode: "UNAUTHENTICATED"},
+		{code: codes.ResourceExhausted, wantCode: "RATE_LIMIT_EXCEEDED"},
+		{code: codes.FailedPrecondition, wantCode: "PRECONDITION_FAILED"},
+		{code: codes.Aborted, wantCode: "ABORTED"},
+		{code: codes.OutOfRange, wantCode: "OUT_OF_RANGE"},
+		{code: codes.Unimplemented, wantCode: "NOT_IMPLEMENTED"},
+		{code: codes.Unavailable, wantCode: "SERVICE_UNAVAILABLE"},
+		{code: codes.DeadlineExceeded, wantCode: "TIMEOUT"},
+		{code: codes.Canceled, wantCode: "CANCELED"},
+		{code: codes.DataLoss, wantCode: "DATA_LOSS"},
+		{code: codes.Internal, wantCode: "INTERNAL_ERROR"},
+		{code: codes.Unknown, wantCode: "INTERNAL_ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code.String(), func(t *testing.T) {
+			err := ConvertGRPCError(status.Error(tt.code, "backend detail"))
+
+			gqlErr, ok := err.(*gqlerror.Error)
+			if !ok {
+				t.Fatalf("expected a *gqlerror.Error, got %T", err)
+			}
+			if code := gqlErr.Extensions["code"]; code != tt.wantCode {
+				t.Errorf("expected code %s, got %v", tt.wantCode, code)
+			}
+		})
+	}
+}
+
+func TestConvertGRPCError_RetryableCodesHideBackendDetails(t *testing.T) {
+	for _, code := range []codes.Code{codes.ResourceExhausted, codes.DeadlineExceeded, codes.Unavailable} {
+		err := ConvertGRPCError(status.Error(code, "dial tcp 10.0.0.5:50053: connection refused"))
+
+		gqlErr := err.(*gqlerror.Error)
+		if gqlErr.Message == "dial tcp 10.0.0.5:50053: connection refused" {
+			t.Errorf("%s: expected a generic message, got the backend error", code)
+		}
+	}
+}
+
+func TestConvertGRPCError_NonGRPCError(t *testing.T) {
+	err := ConvertGRPCError(fmt.Errorf("boom"))
+
+	gqlErr, ok := err.(*gqlerror.Error)
+	if !ok {
+		t.Fatalf("expected a *gqlerror.Error, got %T", err)
+	}
+	if code := gqlErr.Extensions["code"]; code != CodeInternalError {
+		t.Errorf("expected code %s, got %v", CodeInternalError, code)
+	}
+}
+
+func TestConvertGRPCError_OK(t *testing.T) {
+	if err := ConvertGRPCError(nil); err != nil {
+		t.Errorf("expected nil for a nil error, got %v", err)
+	}
+	if err := ConvertGRPCError(status.Error(codes.OK, "")); err != nil {
+		t.Errorf("expected nil for codes.OK, got %v", err)
+	}
+}
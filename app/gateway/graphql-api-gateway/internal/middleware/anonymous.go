@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"context"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+)
+
+// AnonymousPolicy is a gqlgen extension that rejects an unauthenticated
+// operation before execution unless every root field it selects is
+// allowlisted, so resolvers don't each have to remember RequireAuth.
+// Introspection fields are always allowed; whether introspection is enabled
+// at all is decided separately. It relies on AuthMiddleware having run.
+type AnonymousPolicy struct {
+	allowed map[string]bool
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.OperationContextMutator
+} = (*AnonymousPolicy)(nil)
+
+// NewAnonymousPolicy creates a policy allowing the given root fields
+// (query, mutation or subscription) without authentication
+func NewAnonymousPolicy(operations []string) *AnonymousPolicy {
+	allowed := make(map[string]bool, len(operations))
+	for _, operation := range operations {
+		allowed[operation] = true
+	}
+	return &AnonymousPolicy{allowed: allowed}
+}
+
+// ExtensionName implements graphql.HandlerExtension
+func (p *AnonymousPolicy) ExtensionName() string {
+	return "AnonymousPolicy"
+}
+
+// Validate implements graphql.HandlerExtension
+func (p *AnonymousPolicy) Validate(schema graphql.ExecutableSchema) error {
+	return nil
+}
+
+// MutateOperationContext rejects the operation when the caller is
+// unauthenticated and selects a root field outside the allowlist
+func (p *AnonymousPolicy) MutateOperationContext(ctx context.Context, rc *graphql.OperationContext) *gqlerror.Error {
+	if IsAuthenticated(ctx) {
+		return nil
+	}
+
+	fields := graphql.CollectFields(rc, rc.Operation.SelectionSet, []string{rootTypeName(rc.Operation.Operation)})
+	for _, field := range fields {
+		if isIntrospectionField(field.Name) || p.allowed[field.Name] {
+			continue
+		}
+		err := authError(ctx)
+		err.Extensions["operation"] = field.Name
+		return err
+	}
+	return nil
+}
+
+// rootTypeName returns the schema's root type for an operation type
+func rootTypeName(operation ast.Operation) string {
+	switch operation {
+	case ast.Mutation:
+		return "Mutation"
+	case ast.Subscription:
+		return "Subscription"
+	default:
+		return "Query"
+	}
+}
+
+// isIntrospectionField reports whether a root field is __typename,
+// __schema or __type
+func isIntrospectionField(name string) bool {
+	return name == "__typename" || name == "__schema" || name == "__type"
+}
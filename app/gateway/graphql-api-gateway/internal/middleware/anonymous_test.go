@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler"
+	"github.com/99designs/gqlgen/graphql/handler/transport"
+	"github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+)
+
+// anonymousPolicyServer serves a small schema behind the policy. Execution
+// always succeeds, so any error comes from the policy.
+func anonymousPolicyServer(allowed ...string) http.Handler {
+	schema := gqlparser.MustLoadSchema(&ast.Source{Input: `
+		type Query { plans: String! me: String! }
+		type Mutation { login: String! logout: Boolean! }
+	`})
+
+	srv := handler.New(&graphql.ExecutableSchemaMock{
+		SchemaFunc: func() *ast.Schema { return schema },
+		ComplexityFunc: func(typeName, fieldName string, childComplexity int, args map[string]interface{}) (int, bool) {
+			return 0, false
+		},
+		ExecFunc: func(ctx context.Context) graphql.ResponseHandler {
+			return graphql.OneShot(&graphql.Response{Data: []byte(`{}`)})
+		},
+	})
+	srv.AddTransport(transport.POST{})
+	srv.Use(NewAnonymousPolicy(allowed))
+	return srv
+}
+
+func postOperation(t *testing.T, srv http.Handler, ctx context.Context, query string) graphql.Response {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"query": query})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body))).WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	rec := httptest.NewRecorder()
+	srv.ServeHTTP(rec, req)
+
+	var resp graphql.Response
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response %q: %v", rec.Body.String(), err)
+	}
+	return resp
+}
+
+func TestAnonymousPolicy_RejectsNonAllowlistedOperations(t *testing.T) {
+	srv := anonymousPolicyServer("plans", "login")
+	anonymous := unauthenticated(context.Background(), AuthFailureNoToken)
+
+	tests := []struct {
+		name  string
+		query string
+		field string
+	}{
+		{name: "query", query: `{ me }`, field: "me"},
+		{name: "mutation", query: `mutation { logout }`, field: "logout"},
+		{name: "mixed with an allowed field", query: `{ plans me }`, field: "me"},
+		{name: "inside a fragment", query: `query { ...f } fragment f on Query { me }`, field: "me"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := postOperation(t, srv, anonymous, tt.query)
+			if len(resp.Errors) != 1 {
+				t.Fatalf("expected one error, got %v", resp.Errors)
+			}
+			if code := resp.Errors[0].Extensions["code"]; code != "UNAUTHENTICATED" {
+				t.Errorf("expected UNAUTHENTICATED, got %v", code)
+			}
+			if operation := resp.Errors[0].Extensions["operation"]; operation != tt.field {
+				t.Errorf("expected the rejected operation to be %s, got %v", tt.field, operation)
+			}
+		})
+	}
+}
+
+func TestAnonymousPolicy_AllowsAllowlistedOperations(t *testing.T) {
+	srv := anonymousPolicyServer("plans", "login")
+	anonymous := unauthenticated(context.Background(), AuthFailureNoToken)
+
+	for _, query := range []string{`{ plans }`, `mutation { login }`, `{ __typename plans }`} {
+		if resp := postOperation(t, srv, anonymous, query); len(resp.Errors) != 0 {
+			t.Errorf("expected %s to be allowed anonymously, got %v", query, resp.Errors)
+		}
+	}
+}
+
+func TestAnonymousPolicy_SkippedWhenAuthenticated(t *testing.T) {
+	srv := anonymousPolicyServer("plans")
+	ctx := context.WithValue(context.Background(), IsAuthKey, true)
+
+	if resp := postOperation(t, srv, ctx, `{ me }`); len(resp.Errors) != 0 {
+		t.Errorf("expected authenticated callers to pass, got %v", resp.Errors)
+	}
+}
+
+func TestAnonymousPolicy_ExpiredToken(t *testing.T) {
+	srv := anonymousPolicyServer("plans")
+	expired := unauthenticated(context.Background(), AuthFailureExpiredToken)
+
+	resp := postOperation(t, srv, expired, `{ me }`)
+	if len(resp.Errors) != 1 || resp.Errors[0].Extensions["code"] != "TOKEN_EXPIRED" {
+		t.Errorf("expected TOKEN_EXPIRED so clients refresh, got %v", resp.Errors)
+	}
+}
@@ -2,8 +2,11 @@ package middleware
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/99designs/gqlgen/graphql"
 	"github.com/vektah/gqlparser/v2/gqlerror"
@@ -23,12 +26,26 @@ const (
 	RolesKey    contextKey = "roles"
 	TokenKey    contextKey = "token"
 	IsAuthKey   contextKey = "is_authenticated"
+	AuthFailKey contextKey = "auth_failure"
+)
+
+// ReissuedTokenHeader carries a replacement token when user-auth-service
+// reissued the caller's token after a role change
+const ReissuedTokenHeader = "X-Reissued-Token"
+
+// Reasons a request is unauthenticated, stored under AuthFailKey
+const (
+	AuthFailureNoToken      = "no_token"
+	AuthFailureInvalidToken = "invalid_token"
+	AuthFailureExpiredToken = "expired_token"
 )
 
 // AuthMiddleware handles authentication for GraphQL requests
 type AuthMiddleware struct {
 	userAuthClient userauthv1.UserAuthServiceClient
 	logger         *zap.Logger
+	cookieName     string // Session cookie checked when no Authorization header is sent ("" disables)
+	cookieOrigins  map[string]bool
 }
 
 // NewAuthMiddleware creates a new auth middleware
@@ -39,30 +56,113 @@ func NewAuthMiddleware(userAuthClient userauthv1.UserAuthServiceClient, logger *
 	}
 }
 
-// Middleware returns the HTTP middleware function
-func (m *AuthMiddleware) Middleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ctx := r.Context()
+// SetCookieName enables reading the token from the named cookie for browser clients
+func (m *AuthMiddleware) SetCookieName(name string) {
+	m.cookieName = name
+}
 
-		// Extract token from Authorization header
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
-			// No token - mark as unauthenticated and continue
-			ctx = context.WithValue(ctx, IsAuthKey, false)
-			next.ServeHTTP(w, r.WithContext(ctx))
-			return
-		}
+// SetCookieOrigins sets the origins allowed to authenticate with the session
+// cookie. Browsers attach the cookie to cross-site requests too, so a cookie
+// is only honoured when the request's Origin header is in this list.
+func (m *AuthMiddleware) SetCookieOrigins(origins []string) {
+	m.cookieOrigins = make(map[string]bool, len(origins))
+	for _, origin := range origins {
+		m.cookieOrigins[strings.TrimSuffix(origin, "/")] = true
+	}
+}
 
+// extractToken returns the request's token. The Authorization header takes
+// precedence; the session cookie is only consulted when no header is sent,
+// and only from an allowed origin. present reports whether the request
+// carried credentials at all, so a malformed header or a cookie sent from
+// another origin is an invalid token rather than a missing one.
+func (m *AuthMiddleware) extractToken(r *http.Request) (token string, fromCookie, present bool) {
+	if authHeader := r.Header.Get("Authorization"); authHeader != "" {
 		// Parse Bearer token
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
 			m.logger.Warn("invalid authorization header format")
-			ctx = context.WithValue(ctx, IsAuthKey, false)
-			next.ServeHTTP(w, r.WithContext(ctx))
-			return
+			return "", false, true
 		}
+		return parts[1], false, true
+	}
 
-		token := parts[1]
+	if m.cookieName == "" {
+		return "", false, false
+	}
+	cookie, err := r.Cookie(m.cookieName)
+	if err != nil || cookie.Value == "" {
+		return "", false, false
+	}
+	if origin := r.Header.Get("Origin"); !m.cookieOrigins[origin] {
+		m.logger.Warn("session cookie sent from a disallowed origin", zap.String("origin", origin))
+		return "", false, true
+	}
+	return cookie.Value, true, true
+}
+
+// handBackReissuedToken returns a reissued token to the client: always in
+// ReissuedTokenHeader, and by replacing the session cookie when that's
+// where the old token came from, so browser clients pick it up unaided
+func (m *AuthMiddleware) handBackReissuedToken(w http.ResponseWriter, token string, fromCookie bool) {
+	w.Header().Set(ReissuedTokenHeader, token)
+	if !fromCookie {
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     m.cookieName,
+		Value:    token,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   true,
+		SameSite: http.SameSiteStrictMode,
+	})
+}
+
+// tokenExpired reports whether the token's exp claim is in the past. The
+// signature isn't checked; this only classifies a token user-auth-service
+// already rejected, so clients know to refresh rather than sign in again.
+func tokenExpired(token string) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return false
+	}
+	return time.Unix(claims.Exp, 0).Before(time.Now())
+}
+
+// unauthenticated marks the request as unauthenticated for the given reason
+func unauthenticated(ctx context.Context, reason string) context.Context {
+	ctx = context.WithValue(ctx, IsAuthKey, false)
+	return context.WithValue(ctx, AuthFailKey, reason)
+}
+
+// Middleware returns the HTTP middleware function
+func (m *AuthMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+
+		token, fromCookie, present := m.extractToken(r)
+		if token == "" {
+			// No usable token - mark as unauthenticated and continue
+			reason := AuthFailureNoToken
+			if present {
+				reason = AuthFailureInvalidToken
+			}
+			next.ServeHTTP(w, r.WithContext(unauthenticated(ctx, reason)))
+			return
+		}
 
 		// Validate token with user-auth-service
 		resp, err := m.userAuthClient.ValidateToken(ctx, &userauthv1.ValidateTokenRequest{
@@ -70,24 +170,33 @@ func (m *AuthMiddleware) Middleware(next http.Handler) http.Handler {
 		})
 
 		if err != nil {
+			reason := AuthFailureInvalidToken
 			st, ok := status.FromError(err)
 			if ok && st.Code() == codes.Unauthenticated {
 				m.logger.Debug("invalid token", zap.Error(err))
+				if tokenExpired(token) {
+					reason = AuthFailureExpiredToken
+				}
 			} else {
 				m.logger.Error("failed to validate token", zap.Error(err))
 			}
-			ctx = context.WithValue(ctx, IsAuthKey, false)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, r.WithContext(unauthenticated(ctx, reason)))
 			return
 		}
 
 		if !resp.Valid {
 			m.logger.Debug("token validation failed")
-			ctx = context.WithValue(ctx, IsAuthKey, false)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, r.WithContext(unauthenticated(ctx, AuthFailureInvalidToken)))
 			return
 		}
 
+		// A reissued token carries the user's new roles; use it for the
+		// rest of the request and hand it back so the client swaps it in
+		if resp.ReissuedToken != "" {
+			token = resp.ReissuedToken
+			m.handBackReissuedToken(w, token, fromCookie)
+		}
+
 		// Token is valid - inject user information into context
 		ctx = context.WithValue(ctx, IsAuthKey, true)
 		ctx = context.WithValue(ctx, UserIDKey, resp.UserId)
@@ -106,31 +215,46 @@ func (m *AuthMiddleware) Middleware(next http.Handler) http.Handler {
 
 // GraphQLAuthDirective enforces authentication on GraphQL operations
 func GraphQLAuthDirective(ctx context.Context, obj interface{}, next graphql.Resolver) (interface{}, error) {
-	isAuth, ok := ctx.Value(IsAuthKey).(bool)
-	if !ok || !isAuth {
-		return nil, &gqlerror.Error{
-			Message: "Unauthorized: authentication required",
-			Extensions: map[string]interface{}{
-				"code": "UNAUTHENTICATED",
-			},
-		}
+	if err := RequireAuth(ctx); err != nil {
+		return nil, err
 	}
 
 	return next(ctx)
 }
 
-// RequireAuth is a helper that can be called in resolvers to enforce authentication
+// RequireAuth is a helper that can be called in resolvers to enforce
+// authentication. An expired token yields TOKEN_EXPIRED so clients know to
+// refresh; any other failure is UNAUTHENTICATED.
 func RequireAuth(ctx context.Context) error {
-	isAuth, ok := ctx.Value(IsAuthKey).(bool)
-	if !ok || !isAuth {
+	if IsAuthenticated(ctx) {
+		return nil
+	}
+	return authError(ctx)
+}
+
+// authError describes why an unauthenticated request was refused
+func authError(ctx context.Context) *gqlerror.Error {
+	if GetAuthFailure(ctx) == AuthFailureExpiredToken {
 		return &gqlerror.Error{
-			Message: "Unauthorized: authentication required",
+			Message: "Unauthorized: token has expired",
 			Extensions: map[string]interface{}{
-				"code": "UNAUTHENTICATED",
+				"code": "TOKEN_EXPIRED",
 			},
 		}
 	}
-	return nil
+	return &gqlerror.Error{
+		Message: "Unauthorized: authentication required",
+		Extensions: map[string]interface{}{
+			"code": "UNAUTHENTICATED",
+		},
+	}
+}
+
+// GetAuthFailure returns why the request is unauthenticated, or "" if it
+// is authenticated or never passed through the auth middleware
+func GetAuthFailure(ctx context.Context) string {
+	reason, _ := ctx.Value(AuthFailKey).(string)
+	return reason
 }
 
 // GetUserID extracts user ID from context
@@ -0,0 +1,208 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	userauthv1 "github.com/haunted-saas/user-auth-service/proto/userauth/v1"
+)
+
+// fakeUserAuthClient accepts a single known token
+type fakeUserAuthClient struct {
+	userauthv1.UserAuthServiceClient
+	validToken string
+	reissued   string // Returned as ReissuedToken when set
+	seenTokens []string
+}
+
+func (c *fakeUserAuthClient) ValidateToken(ctx context.Context, req *userauthv1.ValidateTokenRequest, opts ...grpc.CallOption) (*userauthv1.ValidateTokenResponse, error) {
+	c.seenTokens = append(c.seenTokens, req.Token)
+	if req.Token != c.validToken {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+	return &userauthv1.ValidateTokenResponse{
+		Valid:  true,
+		UserId: "user-1",
+		TeamId: "team-1",
+		Roles:  []string{"member"},
+
+		ReissuedToken: c.reissued,
+	}, nil
+}
+
+// serve runs the middleware and returns the context seen by the next handler
+func serve(t *testing.T, m *AuthMiddleware, r *http.Request) context.Context {
+	t.Helper()
+	ctx, _ := serveRecorded(t, m, r)
+	return ctx
+}
+
+// serveRecorded is serve that also returns the recorded response
+func serveRecorded(t *testing.T, m *AuthMiddleware, r *http.Request) (context.Context, *httptest.ResponseRecorder) {
+	t.Helper()
+	var got context.Context
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Context()
+	})
+	rec := httptest.NewRecorder()
+	m.Middleware(next).ServeHTTP(rec, r)
+	if got == nil {
+		t.Fatal("next handler was not called")
+	}
+	return got, rec
+}
+
+func TestAuthMiddleware_CookieAuthenticatesUser(t *testing.T) {
+	client := &fakeUserAuthClient{validToken: "cookie-token"}
+	m := NewAuthMiddleware(client, zap.NewNop())
+	m.SetCookieName("haunted_session")
+	m.SetCookieOrigins([]string{"https://app.example.com"})
+
+	r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	r.AddCookie(&http.Cookie{Name: "haunted_session", Value: "cookie-token"})
+
+	ctx := serve(t, m, r)
+	if !IsAuthenticated(ctx) {
+		t.Fatal("expected request to be authenticated via cookie")
+	}
+	if userID, err := GetUserID(ctx); err != nil || userID != "user-1" {
+		t.Errorf("expected user-1 in context, got %q (err %v)", userID, err)
+	}
+	if teamID := GetTeamID(ctx); teamID != "team-1" {
+		t.Errorf("expected team-1 in context, got %q", teamID)
+	}
+	if token := GetToken(ctx); token != "cookie-token" {
+		t.Errorf("expected cookie token in context, got %q", token)
+	}
+}
+
+func TestAuthMiddleware_BearerTakesPrecedenceOverCookie(t *testing.T) {
+	client := &fakeUserAuthClient{validToken: "bearer-token"}
+	m := NewAuthMiddleware(client, zap.NewNop())
+	m.SetCookieName("haunted_session")
+
+	r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+	r.Header.Set("Authorization", "Bearer bearer-token")
+	r.AddCookie(&http.Cookie{Name: "haunted_session", Value: "cookie-token"})
+
+	ctx := serve(t, m, r)
+	if !IsAuthenticated(ctx) {
+		t.Fatal("expected request to be authenticated via bearer token")
+	}
+	if len(client.seenTokens) != 1 || client.seenTokens[0] != "bearer-token" {
+		t.Errorf("expected only the bearer token to be validated, got %v", client.seenTokens)
+	}
+}
+
+func TestAuthMiddleware_CookieRejectedFromOtherOrigins(t *testing.T) {
+	for _, origin := range []string{"https://evil.example.net", ""} {
+		client := &fakeUserAuthClient{validToken: "cookie-token"}
+		m := NewAuthMiddleware(client, zap.NewNop())
+		m.SetCookieName("haunted_session")
+		m.SetCookieOrigins([]string{"https://app.example.com/"})
+
+		r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+		if origin != "" {
+			r.Header.Set("Origin", origin)
+		}
+		r.AddCookie(&http.Cookie{Name: "haunted_session", Value: "cookie-token"})
+
+		ctx := serve(t, m, r)
+		if IsAuthenticated(ctx) {
+			t.Errorf("expected cookie from origin %q to be rejected", origin)
+		}
+		if reason := GetAuthFailure(ctx); reason != AuthFailureInvalidToken {
+			t.Errorf("expected %s for origin %q, got %q", AuthFailureInvalidToken, origin, reason)
+		}
+		if len(client.seenTokens) != 0 {
+			t.Errorf("expected no token validation for origin %q, got %v", origin, client.seenTokens)
+		}
+	}
+}
+
+func TestAuthMiddleware_CookieIgnoredWhenDisabled(t *testing.T) {
+	client := &fakeUserAuthClient{validToken: "cookie-token"}
+	m := NewAuthMiddleware(client, zap.NewNop())
+
+	r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+	r.AddCookie(&http.Cookie{Name: "haunted_session", Value: "cookie-token"})
+
+	ctx := serve(t, m, r)
+	if IsAuthenticated(ctx) {
+		t.Error("expected cookie to be ignored when no cookie name is configured")
+	}
+	if len(client.seenTokens) != 0 {
+		t.Errorf("expected no token validation, got %v", client.seenTokens)
+	}
+}
+
+func TestAuthMiddleware_ReissuedTokenReturnedInHeader(t *testing.T) {
+	client := &fakeUserAuthClient{validToken: "old-token", reissued: "new-token"}
+	m := NewAuthMiddleware(client, zap.NewNop())
+	m.SetCookieName("haunted_session")
+
+	r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+	r.Header.Set("Authorization", "Bearer old-token")
+
+	ctx, rec := serveRecorded(t, m, r)
+	if token := GetToken(ctx); token != "new-token" {
+		t.Errorf("expected reissued token in context, got %q", token)
+	}
+	if got := rec.Header().Get(ReissuedTokenHeader); got != "new-token" {
+		t.Errorf("expected %s to be new-token, got %q", ReissuedTokenHeader, got)
+	}
+	if cookies := rec.Result().Cookies(); len(cookies) != 0 {
+		t.Errorf("expected no cookie for a bearer token, got %v", cookies)
+	}
+}
+
+func TestAuthMiddleware_ReissuedTokenReplacesCookie(t *testing.T) {
+	client := &fakeUserAuthClient{validToken: "old-token", reissued: "new-token"}
+	m := NewAuthMiddleware(client, zap.NewNop())
+	m.SetCookieName("haunted_session")
+	m.SetCookieOrigins([]string{"https://app.example.com"})
+
+	r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	r.AddCookie(&http.Cookie{Name: "haunted_session", Value: "old-token"})
+
+	_, rec := serveRecorded(t, m, r)
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 {
+		t.Fatalf("expected one cookie, got %v", cookies)
+	}
+	c := cookies[0]
+	if c.Name != "haunted_session" || c.Value != "new-token" {
+		t.Errorf("expected haunted_session=new-token, got %s=%s", c.Name, c.Value)
+	}
+	if !c.HttpOnly || !c.Secure || c.SameSite != http.SameSiteStrictMode {
+		t.Errorf("expected a Secure, HttpOnly, SameSite=Strict cookie, got %+v", c)
+	}
+}
+
+func TestAuthMiddleware_NoReissueLeavesResponseAlone(t *testing.T) {
+	client := &fakeUserAuthClient{validToken: "cookie-token"}
+	m := NewAuthMiddleware(client, zap.NewNop())
+	m.SetCookieName("haunted_session")
+	m.SetCookieOrigins([]string{"https://app.example.com"})
+
+	r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+	r.Header.Set("Origin", "https://app.example.com")
+	r.AddCookie(&http.Cookie{Name: "haunted_session", Value: "cookie-token"})
+
+	_, rec := serveRecorded(t, m, r)
+	if got := rec.Header().Get(ReissuedTokenHeader); got != "" {
+		t.Errorf("expected no %s, got %q", ReissuedTokenHeader, got)
+	}
+	if cookies := rec.Result().Cookies(); len(cookies) != 0 {
+		t.Errorf("expected no cookie, got %v", cookies)
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMaxBodySizeMiddleware(t *testing.T) {
+	var received string
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received = string(body)
+	})
+	handler := MaxBodySizeMiddleware(64)(next)
+
+	tests := []struct {
+		name           string
+		body           string
+		chunked        bool
+		expectedStatus int
+	}{
+		{name: "within limit", body: `{"query":"{ me { id } }"}`, expectedStatus: http.StatusOK},
+		{name: "over limit", body: `{"query":"` + strings.Repeat("a", 100) + `"}`, expectedStatus: http.StatusRequestEntityTooLarge},
+		{name: "over limit without content length", body: `{"query":"` + strings.Repeat("a", 100) + `"}`, chunked: true, expectedStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			received = ""
+			req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(tt.body))
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.expectedStatus {
+				t.Fatalf("expected status %d, got %d", tt.expectedStatus, rec.Code)
+			}
+			if tt.expectedStatus == http.StatusOK && received != tt.body {
+				t.Errorf("expected handler to receive the full body, got %q", received)
+			}
+			if tt.expectedStatus != http.StatusOK {
+				if received != "" {
+					t.Error("expected oversized body not to reach the handler")
+				}
+				if !strings.Contains(rec.Body.String(), "PAYLOAD_TOO_LARGE") {
+					t.Errorf("expected PAYLOAD_TOO_LARGE error, got %q", rec.Body.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientInfoMiddleware_ClientIP(t *testing.T) {
+	trusted, err := ParseTrustedProxies([]string{"10.0.0.0/8", "192.168.1.10"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  string
+		realIP     string
+		expected   string
+	}{
+		{name: "direct client", remoteAddr: "203.0.113.7:5000", expected: "203.0.113.7"},
+		{name: "untrusted remote ignores forwarded headers", remoteAddr: "203.0.113.7:5000", forwarded: "198.51.100.1", realIP: "198.51.100.2", expected: "203.0.113.7"},
+		{name: "trusted proxy forwards client", remoteAddr: "10.0.0.1:5000", forwarded: "203.0.113.7", expected: "203.0.113.7"},
+		{name: "spoofed leftmost hop is skipped", remoteAddr: "10.0.0.1:5000", forwarded: "198.51.100.1, 203.0.113.7", expected: "203.0.113.7"},
+		{name: "trusted hops are skipped", remoteAddr: "10.0.0.1:5000", forwarded: "203.0.113.7, 192.168.1.10, 10.0.0.2", expected: "203.0.113.7"},
+		{name: "all hops trusted", remoteAddr: "10.0.0.1:5000", forwarded: "10.0.0.3, 10.0.0.2", expected: "10.0.0.3"},
+		{name: "real ip from trusted proxy", remoteAddr: "192.168.1.10:5000", realIP: "203.0.113.7", expected: "203.0.113.7"},
+		{name: "trusted proxy without headers", remoteAddr: "10.0.0.1:5000", expected: "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			handler := ClientInfoMiddleware(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = GetClientIP(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+			req.RemoteAddr = tt.remoteAddr
+			if tt.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tt.forwarded)
+			}
+			if tt.realIP != "" {
+				req.Header.Set("X-Real-IP", tt.realIP)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got != tt.expected {
+				t.Errorf("expected client IP %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseTrustedProxies_RejectsInvalid(t *testing.T) {
+	for _, proxy := range []string{"load-balancer", "10.0.0.0/33", "10.0.0"} {
+		if _, err := ParseTrustedProxies([]string{proxy}); err == nil {
+			t.Errorf("expected %q to be rejected", proxy)
+		}
+	}
+}
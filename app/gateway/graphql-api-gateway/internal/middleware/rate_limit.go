@@ -0,0 +1,126 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Rate limit response headers
+const (
+	RateLimitLimitHeader     = "X-RateLimit-Limit"
+	RateLimitRemainingHeader = "X-RateLimit-Remaining"
+	RateLimitResetHeader     = "X-RateLimit-Reset"
+)
+
+// rateLimitedResponse matches the error the gateway returns for a
+// ResourceExhausted status from a backend service
+const rateLimitedResponse = `{"errors":[{"message":"Rate limit exceeded. Please try again later","extensions":{"code":"RATE_LIMIT_EXCEEDED"}}]}`
+
+// rateLimitWindow counts one key's requests in the current window
+type rateLimitWindow struct {
+	start time.Time
+	count int
+}
+
+// RateLimiter allows each key a fixed number of requests per window.
+// Authenticated requests are keyed by user ID and anonymous ones by client
+// IP, so it must run inside AuthMiddleware and ClientInfoMiddleware.
+type RateLimiter struct {
+	limit  int
+	window time.Duration
+	now    func() time.Time
+
+	mu        sync.Mutex
+	windows   map[string]*rateLimitWindow
+	lastSweep time.Time
+}
+
+// NewRateLimiter creates a limiter allowing limit requests per key every window
+func NewRateLimiter(limit int, window time.Duration) *RateLimiter {
+	return &RateLimiter{
+		limit:   limit,
+		window:  window,
+		now:     time.Now,
+		windows: make(map[string]*rateLimitWindow),
+	}
+}
+
+// Middleware enforces the limit and reports the caller's quota on every
+// response: X-RateLimit-Limit, X-RateLimit-Remaining, and X-RateLimit-Reset
+// (Unix seconds when the window resets). Rejected requests get 429.
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		allowed, remaining, reset := l.take(rateLimitKey(r))
+
+		header := w.Header()
+		header.Set(RateLimitLimitHeader, strconv.Itoa(l.limit))
+		header.Set(RateLimitRemainingHeader, strconv.Itoa(remaining))
+		header.Set(RateLimitResetHeader, strconv.FormatInt(reset.Unix(), 10))
+
+		if !allowed {
+			retryAfter := int(reset.Sub(l.now()).Seconds() + 0.5)
+			if retryAfter < 1 {
+				retryAfter = 1
+			}
+			header.Set("Retry-After", strconv.Itoa(retryAfter))
+			header.Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(rateLimitedResponse))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take counts a request against key and reports whether it is allowed, how
+// many requests remain in the window, and when the window resets
+func (l *RateLimiter) take(key string) (allowed bool, remaining int, reset time.Time) {
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	current, ok := l.windows[key]
+	if !ok || !now.Before(current.start.Add(l.window)) {
+		current = &rateLimitWindow{start: now}
+		l.windows[key] = current
+	}
+	reset = current.start.Add(l.window)
+
+	if current.count >= l.limit {
+		return false, 0, reset
+	}
+	current.count++
+	return true, l.limit - current.count, reset
+}
+
+// sweep drops expired windows at most once per window so idle keys don't
+// accumulate
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.window {
+		return
+	}
+	for key, w := range l.windows {
+		if !now.Before(w.start.Add(l.window)) {
+			delete(l.windows, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// rateLimitKey identifies the caller: the user when authenticated, otherwise
+// the client IP
+func rateLimitKey(r *http.Request) string {
+	if userID, err := GetUserID(r.Context()); err == nil {
+		return "user:" + userID
+	}
+	if ip := GetClientIP(r.Context()); ip != "" {
+		return "ip:" + ip
+	}
+	return "ip:" + remoteIP(r)
+}
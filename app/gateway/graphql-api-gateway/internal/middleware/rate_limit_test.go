@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestRateLimiter returns a limiter on a controllable clock
+func newTestRateLimiter(limit int, window time.Duration) (*RateLimiter, *time.Time) {
+	now := time.Unix(1700000000, 0)
+	limiter := NewRateLimiter(limit, window)
+	limiter.now = func() time.Time { return now }
+	return limiter, &now
+}
+
+// rateLimitRequest builds a request as it looks after ClientInfoMiddleware
+// and AuthMiddleware have run
+func rateLimitRequest(ip, userID string) *http.Request {
+	r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+	ctx := context.WithValue(r.Context(), ClientIPKey, ip)
+	if userID != "" {
+		ctx = context.WithValue(ctx, IsAuthKey, true)
+		ctx = context.WithValue(ctx, UserIDKey, userID)
+	}
+	return r.WithContext(ctx)
+}
+
+func TestRateLimiter_HeadersDecrement(t *testing.T) {
+	limiter, now := newTestRateLimiter(3, time.Minute)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	wantReset := strconv.FormatInt(now.Add(time.Minute).Unix(), 10)
+
+	for i, wantRemaining := range []string{"2", "1", "0"} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, rateLimitRequest("10.0.0.1", ""))
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected status 200, got %d", i+1, rec.Code)
+		}
+		if got := rec.Header().Get(RateLimitLimitHeader); got != "3" {
+			t.Errorf("request %d: expected limit 3, got %q", i+1, got)
+		}
+		if got := rec.Header().Get(RateLimitRemainingHeader); got != wantRemaining {
+			t.Errorf("request %d: expected remaining %s, got %q", i+1, wantRemaining, got)
+		}
+		if got := rec.Header().Get(RateLimitResetHeader); got != wantReset {
+			t.Errorf("request %d: expected reset %s, got %q", i+1, wantReset, got)
+		}
+	}
+
+	// Over the limit
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, rateLimitRequest("10.0.0.1", ""))
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status 429, got %d", rec.Code)
+	}
+	if got := rec.Header().Get(RateLimitRemainingHeader); got != "0" {
+		t.Errorf("expected remaining 0, got %q", got)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("expected Retry-After 60, got %q", got)
+	}
+	if !strings.Contains(rec.Body.String(), "RATE_LIMIT_EXCEEDED") {
+		t.Errorf("expected a RATE_LIMIT_EXCEEDED error, got %s", rec.Body.String())
+	}
+
+	// A new window restores the quota
+	*now = now.Add(time.Minute)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, rateLimitRequest("10.0.0.1", ""))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200 after the window reset, got %d", rec.Code)
+	}
+	if got := rec.Header().Get(RateLimitRemainingHeader); got != "2" {
+		t.Errorf("expected remaining 2 after the window reset, got %q", got)
+	}
+}
+
+func TestRateLimiter_SeparateQuotaPerKey(t *testing.T) {
+	limiter, _ := newTestRateLimiter(2, time.Minute)
+	handler := limiter.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	remaining := func(r *http.Request) string {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, r)
+		return rec.Header().Get(RateLimitRemainingHeader)
+	}
+
+	if got := remaining(rateLimitRequest("10.0.0.1", "")); got != "1" {
+		t.Errorf("expected ip 10.0.0.1 to have 1 remaining, got %q", got)
+	}
+	if got := remaining(rateLimitRequest("10.0.0.2", "")); got != "1" {
+		t.Errorf("expected ip 10.0.0.2 to have its own quota, got %q", got)
+	}
+
+	// Authenticated requests are counted per user, whatever their IP
+	if got := remaining(rateLimitRequest("10.0.0.1", "user-1")); got != "1" {
+		t.Errorf("expected user-1 to have its own quota, got %q", got)
+	}
+	if got := remaining(rateLimitRequest("10.0.0.3", "user-1")); got != "0" {
+		t.Errorf("expected user-1 to share its quota across IPs, got %q", got)
+	}
+}
@@ -0,0 +1,117 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/haunted-saas/graphql-api-gateway/internal/clients"
+	"github.com/haunted-saas/graphql-api-gateway/internal/generated"
+	"github.com/haunted-saas/graphql-api-gateway/internal/middleware"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	userauthv1 "github.com/haunted-saas/user-auth-service/proto/userauth/v1"
+)
+
+// rejectingUserAuthClient rejects every token, as user-auth-service does for
+// expired or forged tokens
+type rejectingUserAuthClient struct {
+	userauthv1.UserAuthServiceClient
+}
+
+func (c *rejectingUserAuthClient) ValidateToken(ctx context.Context, req *userauthv1.ValidateTokenRequest, opts ...grpc.CallOption) (*userauthv1.ValidateTokenResponse, error) {
+	return nil, status.Error(codes.Unauthenticated, "invalid token")
+}
+
+// recordingUserAuthClient records the Login request it receives
+type recordingUserAuthClient struct {
+	userauthv1.UserAuthServiceClient
+	login *userauthv1.LoginRequest
+}
+
+func (c *recordingUserAuthClient) Login(ctx context.Context, req *userauthv1.LoginRequest, opts ...grpc.CallOption) (*userauthv1.LoginResponse, error) {
+	c.login = req
+	return &userauthv1.LoginResponse{AccessToken: "access", ExpiresAt: timestamppb.Now()}, nil
+}
+
+// unsignedToken builds a JWT-shaped token with the given exp claim
+func unsignedToken(exp time.Time) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"RS256","typ":"JWT"}`)) + "." +
+		encode([]byte(fmt.Sprintf(`{"sub":"user-1","exp":%d}`, exp.Unix()))) + ".signature"
+}
+
+// authenticatedContext runs the auth middleware and returns the request
+// context it hands to the GraphQL handler
+func authenticatedContext(t *testing.T, authorization string) context.Context {
+	t.Helper()
+	m := middleware.NewAuthMiddleware(&rejectingUserAuthClient{}, zap.NewNop())
+
+	r := httptest.NewRequest(http.MethodPost, "/graphql", nil)
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+
+	var ctx context.Context
+	m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx = r.Context()
+	})).ServeHTTP(httptest.NewRecorder(), r)
+	return ctx
+}
+
+func TestQueryResolver_DistinguishesMissingAndExpiredTokens(t *testing.T) {
+	query := NewResolver(&clients.GRPCClients{}, zap.NewNop()).Query()
+
+	tests := []struct {
+		name          string
+		authorization string
+		expectedCode  string
+	}{
+		{name: "no token", expectedCode: "UNAUTHENTICATED"},
+		{name: "expired token", authorization: "Bearer " + unsignedToken(time.Now().Add(-time.Hour)), expectedCode: "TOKEN_EXPIRED"},
+		{name: "rejected unexpired token", authorization: "Bearer " + unsignedToken(time.Now().Add(time.Hour)), expectedCode: "UNAUTHENTICATED"},
+		{name: "malformed header", authorization: "Token abc", expectedCode: "UNAUTHENTICATED"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := authenticatedContext(t, tt.authorization)
+
+			_, err := query.MyPermissions(ctx)
+
+			gqlErr, ok := err.(*gqlerror.Error)
+			if !ok {
+				t.Fatalf("expected a GraphQL error, got %v", err)
+			}
+			if code := gqlErr.Extensions["code"]; code != tt.expectedCode {
+				t.Errorf("expected code %s, got %v", tt.expectedCode, code)
+			}
+		})
+	}
+}
+
+func TestMutationResolver_LoginForwardsClientIP(t *testing.T) {
+	backend := &recordingUserAuthClient{}
+	mutation := NewResolver(&clients.GRPCClients{UserAuth: backend}, zap.NewNop()).Mutation()
+
+	ctx := context.WithValue(context.Background(), middleware.ClientIPKey, "203.0.113.7")
+	if _, err := mutation.Login(ctx, generated.LoginInput{Email: "user@example.com", Password: "password"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if backend.login == nil {
+		t.Fatal("expected Login to be called")
+	}
+	if backend.login.IpAddress != "203.0.113.7" {
+		t.Errorf("expected IP 203.0.113.7 to be forwarded, got %q", backend.login.IpAddress)
+	}
+}
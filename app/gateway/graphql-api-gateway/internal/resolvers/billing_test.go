@@ -0,0 +1,168 @@
+package resolvers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/haunted-saas/graphql-api-gateway/internal/clients"
+	"github.com/haunted-saas/graphql-api-gateway/internal/middleware"
+	"github.com/haunted-saas/pkg/admintoken"
+	"github.com/haunted-saas/pkg/caller"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	billingv1 "github.com/haunted-saas/billing-service/proto/billing/v1"
+)
+
+// stubBillingClient answers GetSubscription and GetCheckoutStatus with fixed
+// responses and records the checkout requests it receives
+type stubBillingClient struct {
+	billingv1.BillingServiceClient
+	subscription *billingv1.Subscription
+
+	checkout         *billingv1.CreateCheckoutSessionRequest
+	checkoutMetadata metadata.MD
+
+	checkoutStatus         *billingv1.GetCheckoutStatusResponse
+	checkoutStatusRequest  *billingv1.GetCheckoutStatusRequest
+	checkoutStatusMetadata metadata.MD
+}
+
+func (c *stubBillingClient) GetSubscription(ctx context.Context, in *billingv1.GetSubscriptionRequest, opts ...grpc.CallOption) (*billingv1.GetSubscriptionResponse, error) {
+	return &billingv1.GetSubscriptionResponse{Subscription: c.subscription}, nil
+}
+
+func (c *stubBillingClient) CreateCheckoutSession(ctx context.Context, in *billingv1.CreateCheckoutSessionRequest, opts ...grpc.CallOption) (*billingv1.CreateCheckoutSessionResponse, error) {
+	c.checkout = in
+	c.checkoutMetadata, _ = metadata.FromOutgoingContext(ctx)
+	return &billingv1.CreateCheckoutSessionResponse{SessionId: "cs_123", CheckoutUrl: "https://checkout.stripe.com/cs_123"}, nil
+}
+
+func (c *stubBillingClient) GetCheckoutStatus(ctx context.Context, in *billingv1.GetCheckoutStatusRequest, opts ...grpc.CallOption) (*billingv1.GetCheckoutStatusResponse, error) {
+	c.checkoutStatusRequest = in
+	c.checkoutStatusMetadata, _ = metadata.FromOutgoingContext(ctx)
+	return c.checkoutStatus, nil
+}
+
+func TestQueryResolver_MySubscription_ReturnsEntitlements(t *testing.T) {
+	backend := &stubBillingClient{subscription: &billingv1.Subscription{
+		Id:     "sub_123",
+		TeamId: "user-123",
+		PlanId: "plan_pro",
+		Status: "active",
+		Plan: &billingv1.Plan{Id: "plan_pro", Name: "Pro", Features: map[string]string{
+			"max_seats":  "10",
+			"api_access": "true",
+		}},
+	}}
+	resolver := NewResolver(&clients.GRPCClients{Billing: backend}, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "user-123")
+	sub, err := resolver.Query().MySubscription(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if sub == nil {
+		t.Fatal("expected a subscription")
+	}
+	if sub.ID != "sub_123" || sub.Plan == nil || sub.Plan.ID != "plan_pro" {
+		t.Errorf("unexpected subscription: %+v", sub)
+	}
+
+	expected := [][2]string{{"api_access", "true"}, {"max_seats", "10"}}
+	if len(sub.Entitlements) != len(expected) {
+		t.Fatalf("expected %d entitlements, got %d", len(expected), len(sub.Entitlements))
+	}
+	for i, e := range expected {
+		if sub.Entitlements[i].Key != e[0] || sub.Entitlements[i].Value != e[1] {
+			t.Errorf("entitlement %d: expected %s=%s, got %s=%s", i, e[0], e[1], sub.Entitlements[i].Key, sub.Entitlements[i].Value)
+		}
+	}
+}
+
+func TestMutationResolver_CreateTrialCheckout(t *testing.T) {
+	backend := &stubBillingClient{}
+	resolver := NewResolver(&clients.GRPCClients{Billing: backend}, zap.NewNop())
+	resolver.SetAdminToken("secret")
+
+	ctx := context.WithValue(context.Background(), middleware.IsAuthKey, true)
+	ctx = context.WithValue(ctx, middleware.UserIDKey, "sales-1")
+	ctx = context.WithValue(ctx, middleware.RolesKey, []string{"admin"})
+
+	payload, err := resolver.Mutation().CreateTrialCheckout(ctx, "team-123", "plan_pro", 45, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if payload.SessionID != "cs_123" {
+		t.Errorf("unexpected payload: %+v", payload)
+	}
+
+	if backend.checkout == nil {
+		t.Fatal("expected CreateCheckoutSession to be called")
+	}
+	if backend.checkout.TeamId != "team-123" || backend.checkout.GetTrialDaysOverride() != 45 {
+		t.Errorf("unexpected checkout request: %+v", backend.checkout)
+	}
+	if tokens := backend.checkoutMetadata.Get(admintoken.MetadataKey); len(tokens) != 1 || tokens[0] != "secret" {
+		t.Errorf("expected the admin token to be sent, got %v", tokens)
+	}
+}
+
+func TestMutationResolver_CreateTrialCheckout_RequiresAdmin(t *testing.T) {
+	backend := &stubBillingClient{}
+	resolver := NewResolver(&clients.GRPCClients{Billing: backend}, zap.NewNop())
+	resolver.SetAdminToken("secret")
+
+	ctx := context.WithValue(context.Background(), middleware.IsAuthKey, true)
+	ctx = context.WithValue(ctx, middleware.UserIDKey, "user-1")
+	ctx = context.WithValue(ctx, middleware.RolesKey, []string{"member"})
+
+	if _, err := resolver.Mutation().CreateTrialCheckout(ctx, "team-123", "plan_pro", 45, nil); err == nil {
+		t.Fatal("expected non-admins to be rejected")
+	}
+	if backend.checkout != nil {
+		t.Error("expected billing not to be called for a non-admin")
+	}
+}
+
+func TestQueryResolver_CheckoutStatus_SendsCallerTeam(t *testing.T) {
+	backend := &stubBillingClient{checkoutStatus: &billingv1.GetCheckoutStatusResponse{
+		SessionId:     "cs_123",
+		Status:        "complete",
+		PaymentStatus: "paid",
+		Provisioned:   true,
+		Subscription:  &billingv1.Subscription{Id: "sub_123", TeamId: "user-123", PlanId: "plan_pro", Status: "active"},
+	}}
+	resolver := NewResolver(&clients.GRPCClients{Billing: backend}, zap.NewNop())
+
+	ctx := context.WithValue(context.Background(), middleware.IsAuthKey, true)
+	ctx = context.WithValue(ctx, middleware.UserIDKey, "user-123")
+
+	checkout, err := resolver.Query().CheckoutStatus(ctx, "cs_123")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if checkout.Status != "complete" || !checkout.Provisioned || checkout.Subscription == nil || checkout.Subscription.ID != "sub_123" {
+		t.Errorf("unexpected checkout status: %+v", checkout)
+	}
+
+	if backend.checkoutStatusRequest == nil || backend.checkoutStatusRequest.SessionId != "cs_123" {
+		t.Fatalf("unexpected checkout status request: %+v", backend.checkoutStatusRequest)
+	}
+	if teams := backend.checkoutStatusMetadata.Get(caller.TeamMetadataKey); len(teams) != 1 || teams[0] != "user-123" {
+		t.Errorf("expected the caller's team to be sent, got %v", teams)
+	}
+}
+
+func TestQueryResolver_CheckoutStatus_RequiresAuth(t *testing.T) {
+	backend := &stubBillingClient{}
+	resolver := NewResolver(&clients.GRPCClients{Billing: backend}, zap.NewNop())
+
+	if _, err := resolver.Query().CheckoutStatus(context.Background(), "cs_123"); err == nil {
+		t.Fatal("expected unauthenticated callers to be rejected")
+	}
+	if backend.checkoutStatusRequest != nil {
+		t.Error("expected billing not to be called for an unauthenticated caller")
+	}
+}
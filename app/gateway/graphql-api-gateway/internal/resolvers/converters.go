@@ -1,10 +1,14 @@
 package resolvers
 
 import (
+	"sort"
 	"time"
 
 	"github.com/haunted-saas/graphql-api-gateway/internal/generated"
+	"github.com/haunted-saas/pkg/permission"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
+	analyticsv1 "github.com/haunted-saas/analytics-service/proto/analytics/v1"
 	billingv1 "github.com/haunted-saas/billing-service/proto/billing/v1"
 	featureflagsv1 "github.com/haunted-saas/feature-flags-service/proto/featureflags/v1"
 	llmv1 "github.com/haunted-saas/llm-gateway-service/proto/llm/v1"
@@ -25,16 +29,22 @@ func convertUser(u *userauthv1.User) *generated.User {
 		roles[i] = convertRole(role)
 	}
 
-	// Extract permission names from roles
+	// Extract permission names from roles, dropping any a role denies
 	permissionSet := make(map[string]bool)
+	var denied []string
 	for _, role := range u.Roles {
 		for _, perm := range role.Permissions {
 			permissionSet[perm.Name] = true
 		}
+		for _, perm := range role.DeniedPermissions {
+			denied = append(denied, perm.Name)
+		}
 	}
 	permissions := make([]string, 0, len(permissionSet))
 	for perm := range permissionSet {
-		permissions = append(permissions, perm)
+		if !permission.AnyCovers(denied, perm) {
+			permissions = append(permissions, perm)
+		}
 	}
 
 	return &generated.User{
@@ -103,29 +113,41 @@ func convertSubscription(s *billingv1.Subscription) *generated.Subscription {
 		return nil
 	}
 
-	// TODO: Fix this once gqlgen regenerates with correct schema
-	// The generated.Subscription type doesn't match the GraphQL schema yet
-	return nil
-	
-	/* Commented out until gqlgen regenerates properly
-	cancelAtPeriodEnd := false
-	if s.CancelAt != nil {
-		cancelAtPeriodEnd = true
+	return &generated.Subscription{
+		ID:                   s.Id,
+		UserID:               s.TeamId,
+		PlanID:               s.PlanId,
+		Status:               s.Status,
+		CurrentPeriodStart:   s.CurrentPeriodStart.AsTime(),
+		CurrentPeriodEnd:     s.CurrentPeriodEnd.AsTime(),
+		CancelAtPeriodEnd:    s.CancelAt != nil,
+		StripeSubscriptionID: s.StripeSubscriptionId,
+		CreatedAt:            s.CreatedAt.AsTime(),
+		UpdatedAt:            s.UpdatedAt.AsTime(),
+		Entitlements:         convertEntitlements(s.Plan),
+		Plan:                 convertPlan(s.Plan),
 	}
+}
 
-	return &generated.Subscription{
-		ID:                    s.Id,
-		UserID:                s.TeamId,
-		PlanID:                s.PlanId,
-		Status:                s.Status,
-		CurrentPeriodStart:    s.CurrentPeriodStart.AsTime(),
-		CurrentPeriodEnd:      s.CurrentPeriodEnd.AsTime(),
-		CancelAtPeriodEnd:     cancelAtPeriodEnd,
-		StripeSubscriptionID:  s.StripeSubscriptionId,
-		CreatedAt:             s.CreatedAt.AsTime(),
-		UpdatedAt:             s.UpdatedAt.AsTime(),
-	}
-	*/
+// convertEntitlements maps a plan's features to entitlements sorted by key.
+// billing-service preloads the plan on subscriptions; without one there are
+// no entitlements.
+func convertEntitlements(p *billingv1.Plan) []*generated.Entitlement {
+	if p == nil {
+		return []*generated.Entitlement{}
+	}
+
+	keys := make([]string, 0, len(p.Features))
+	for key := range p.Features {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	entitlements := make([]*generated.Entitlement, len(keys))
+	for i, key := range keys {
+		entitlements[i] = &generated.Entitlement{Key: key, Value: p.Features[key]}
+	}
+	return entitlements
 }
 
 // ============================================================================
@@ -181,6 +203,56 @@ func convertPromptMetadata(resp *llmv1.GetPromptMetadataResponse) *generated.Pro
 	}
 }
 
+// ============================================================================
+// ANALYTICS CONVERTERS
+// ============================================================================
+
+func convertAnalyticsProperties(props map[string]interface{}) map[string]*analyticsv1.PropertyValue {
+	properties := make(map[string]*analyticsv1.PropertyValue, len(props))
+	for key, val := range props {
+		properties[key] = convertAnalyticsPropertyValue(val)
+	}
+	return properties
+}
+
+// convertAnalyticsPropertyValue keeps dates typed: time.Time values and
+// strings that parse as RFC3339 are sent as timestamps rather than strings
+func convertAnalyticsPropertyValue(val interface{}) *analyticsv1.PropertyValue {
+	pv := &analyticsv1.PropertyValue{}
+	switch v := val.(type) {
+	case time.Time:
+		pv.Value = &analyticsv1.PropertyValue_TimestampValue{TimestampValue: timestamppb.New(v)}
+	case string:
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			pv.Value = &analyticsv1.PropertyValue_TimestampValue{TimestampValue: timestamppb.New(t)}
+		} else {
+			pv.Value = &analyticsv1.PropertyValue_StringValue{StringValue: v}
+		}
+	case float64:
+		pv.Value = &analyticsv1.PropertyValue_NumberValue{NumberValue: v}
+	case bool:
+		pv.Value = &analyticsv1.PropertyValue_BoolValue{BoolValue: v}
+	}
+	return pv
+}
+
+// convertIdentifyUserResponse reports whether the identify was sent and
+// which properties analytics-service dropped
+func convertIdentifyUserResponse(resp *analyticsv1.IdentifyUserResponse) *generated.IdentifyUserResult {
+	issues := make([]*generated.PropertyIssue, 0, len(resp.PropertyIssues))
+	for _, issue := range resp.PropertyIssues {
+		issues = append(issues, &generated.PropertyIssue{
+			Key:    issue.Key,
+			Reason: issue.Reason,
+		})
+	}
+
+	return &generated.IdentifyUserResult{
+		Enqueued:       resp.Enqueued,
+		PropertyIssues: issues,
+	}
+}
+
 // ============================================================================
 // HELPER FUNCTIONS
 // ============================================================================
@@ -0,0 +1,98 @@
+package resolvers
+
+import (
+	"testing"
+	"time"
+
+	analyticsv1 "github.com/haunted-saas/analytics-service/proto/analytics/v1"
+	billingv1 "github.com/haunted-saas/billing-service/proto/billing/v1"
+)
+
+func TestConvertAnalyticsPropertyValue_Timestamps(t *testing.T) {
+	trialEnds := time.Date(2024, 6, 15, 9, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected time.Time
+	}{
+		{name: "time value", value: trialEnds, expected: trialEnds},
+		{name: "RFC3339 string", value: "2024-06-15T09:30:00Z", expected: trialEnds},
+		{name: "RFC3339 string with offset", value: "2024-06-15T11:30:00+02:00", expected: trialEnds},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pv := convertAnalyticsPropertyValue(tt.value)
+
+			ts, ok := pv.Value.(*analyticsv1.PropertyValue_TimestampValue)
+			if !ok {
+				t.Fatalf("expected timestamp value, got %T", pv.Value)
+			}
+			if got := ts.TimestampValue.AsTime(); !got.Equal(tt.expected) {
+				t.Errorf("expected %s, got %s", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestConvertAnalyticsPropertyValue_NonDateStringStaysString(t *testing.T) {
+	for _, value := range []string{"/dashboard", "2024-06-15", "June 15"} {
+		pv := convertAnalyticsPropertyValue(value)
+
+		s, ok := pv.Value.(*analyticsv1.PropertyValue_StringValue)
+		if !ok {
+			t.Fatalf("%q: expected string value, got %T", value, pv.Value)
+		}
+		if s.StringValue != value {
+			t.Errorf("expected %q, got %q", value, s.StringValue)
+		}
+	}
+}
+
+func TestConvertIdentifyUserResponse_MapsPropertyIssues(t *testing.T) {
+	result := convertIdentifyUserResponse(&analyticsv1.IdentifyUserResponse{
+		Success:  true,
+		Enqueued: true,
+		PropertyIssues: []*analyticsv1.PropertyIssue{
+			{Key: "distinct_id", Reason: `"distinct_id" is a reserved property key`},
+		},
+	})
+
+	if !result.Enqueued {
+		t.Error("expected enqueued to be true")
+	}
+	if len(result.PropertyIssues) != 1 || result.PropertyIssues[0].Key != "distinct_id" {
+		t.Errorf("unexpected property issues: %+v", result.PropertyIssues)
+	}
+
+	// No issues is an empty list, never null
+	result = convertIdentifyUserResponse(&analyticsv1.IdentifyUserResponse{Success: true, Enqueued: true})
+	if result.PropertyIssues == nil || len(result.PropertyIssues) != 0 {
+		t.Errorf("expected an empty issue list, got %#v", result.PropertyIssues)
+	}
+}
+
+func TestConvertEntitlements_MapsPlanFeatures(t *testing.T) {
+	plan := &billingv1.Plan{Id: "plan_pro", Features: map[string]string{
+		"max_seats":  "10",
+		"api_access": "true",
+		"storage_gb": "100",
+	}}
+
+	entitlements := convertEntitlements(plan)
+
+	expected := [][2]string{{"api_access", "true"}, {"max_seats", "10"}, {"storage_gb", "100"}}
+	if len(entitlements) != len(expected) {
+		t.Fatalf("expected %d entitlements, got %d", len(expected), len(entitlements))
+	}
+	for i, e := range expected {
+		if entitlements[i].Key != e[0] || entitlements[i].Value != e[1] {
+			t.Errorf("entitlement %d: expected %s=%s, got %s=%s", i, e[0], e[1], entitlements[i].Key, entitlements[i].Value)
+		}
+	}
+
+	if got := convertEntitlements(nil); got == nil || len(got) != 0 {
+		t.Errorf("expected no entitlements without a plan, got %v", got)
+	}
+}
@@ -0,0 +1,67 @@
+package resolvers
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/haunted-saas/graphql-api-gateway/internal/errors"
+	"github.com/haunted-saas/graphql-api-gateway/internal/generated"
+	"github.com/haunted-saas/graphql-api-gateway/internal/middleware"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	featureflagsv1 "github.com/haunted-saas/feature-flags-service/proto/featureflags/v1"
+)
+
+// featureFlagStatus evaluates a flag for the current user and team. When
+// feature-flags-service is briefly unreachable the last known result is
+// served from the flag cache and marked stale.
+func (r *Resolver) featureFlagStatus(ctx context.Context, featureName string, properties map[string]interface{}) (*generated.FeatureFlagStatus, error) {
+	userID, _ := middleware.GetUserID(ctx)
+	teamID := middleware.GetTeamID(ctx)
+
+	propertiesJSON := "{}"
+	if properties != nil {
+		jsonBytes, err := json.Marshal(properties)
+		if err != nil {
+			return nil, errors.NewBadRequestError("invalid properties")
+		}
+		propertiesJSON = string(jsonBytes)
+	}
+
+	resp, err := r.clients.FeatureFlags.IsFeatureEnabled(ctx, &featureflagsv1.IsFeatureEnabledRequest{
+		FeatureName:    featureName,
+		UserId:         userID,
+		TeamId:         teamID,
+		PropertiesJson: propertiesJSON,
+	})
+	if err != nil {
+		if r.flagCache != nil && isTransientError(err) {
+			if enabled, ok := r.flagCache.Lookup(featureName, userID, teamID, propertiesJSON); ok {
+				r.logger.Warn("feature-flags-service unavailable, serving cached flag",
+					zap.String("feature", featureName),
+					zap.Error(err))
+				return &generated.FeatureFlagStatus{Enabled: enabled, Stale: true}, nil
+			}
+		}
+		return nil, errors.ConvertGRPCError(err)
+	}
+
+	if r.flagCache != nil {
+		r.flagCache.Store(featureName, userID, teamID, propertiesJSON, resp.Enabled)
+	}
+
+	return &generated.FeatureFlagStatus{Enabled: resp.Enabled}, nil
+}
+
+// isTransientError reports whether a backend error is likely a brief outage
+// rather than a problem with the request
+func isTransientError(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
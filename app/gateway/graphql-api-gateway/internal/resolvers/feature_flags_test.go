@@ -0,0 +1,80 @@
+package resolvers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/haunted-saas/graphql-api-gateway/internal/clients"
+	"github.com/haunted-saas/graphql-api-gateway/internal/middleware"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	featureflagsv1 "github.com/haunted-saas/feature-flags-service/proto/featureflags/v1"
+)
+
+// stubFeatureFlagsClient answers IsFeatureEnabled with a fixed result
+type stubFeatureFlagsClient struct {
+	featureflagsv1.FeatureFlagsServiceClient
+	enabled bool
+	err     error
+}
+
+func (c *stubFeatureFlagsClient) IsFeatureEnabled(ctx context.Context, in *featureflagsv1.IsFeatureEnabledRequest, opts ...grpc.CallOption) (*featureflagsv1.IsFeatureEnabledResponse, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	return &featureflagsv1.IsFeatureEnabledResponse{Enabled: c.enabled}, nil
+}
+
+func TestQueryResolver_FeatureFlag_ServesCachedValueWhenUnavailable(t *testing.T) {
+	backend := &stubFeatureFlagsClient{enabled: true}
+	resolver := NewResolver(&clients.GRPCClients{FeatureFlags: backend}, zap.NewNop())
+	resolver.SetFeatureFlagCache(clients.NewFeatureFlagCache(time.Minute))
+	query := resolver.Query()
+
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "user-123")
+	ctx = context.WithValue(ctx, middleware.TeamIDKey, "team-456")
+
+	status1, err := query.FeatureFlag(ctx, "new-dashboard", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !status1.Enabled || status1.Stale {
+		t.Fatalf("expected a fresh enabled flag, got %+v", status1)
+	}
+
+	// A backend blip serves the cached value, marked stale
+	backend.err = status.Error(codes.Unavailable, "connection refused")
+	cached, err := query.FeatureFlag(ctx, "new-dashboard", nil)
+	if err != nil {
+		t.Fatalf("expected cached value during outage, got error: %v", err)
+	}
+	if !cached.Enabled || !cached.Stale {
+		t.Errorf("expected a stale enabled flag, got %+v", cached)
+	}
+
+	enabled, err := query.IsFeatureEnabled(ctx, "new-dashboard", nil)
+	if err != nil || !enabled {
+		t.Errorf("expected isFeatureEnabled to serve the cached value, got %v, %v", enabled, err)
+	}
+
+	// Flags never fetched for this user still fail
+	otherUser := context.WithValue(ctx, middleware.UserIDKey, "user-789")
+	if _, err := query.FeatureFlag(otherUser, "new-dashboard", nil); err == nil {
+		t.Error("expected an error for a flag with no cached value")
+	}
+
+	// Nor do flags fetched with different properties
+	if _, err := query.FeatureFlag(ctx, "new-dashboard", map[string]interface{}{"plan": "free"}); err == nil {
+		t.Error("expected an error for properties with no cached value")
+	}
+
+	// Errors that aren't outages are not masked by the cache
+	backend.err = status.Error(codes.InvalidArgument, "invalid properties")
+	if _, err := query.FeatureFlag(ctx, "new-dashboard", nil); err == nil {
+		t.Error("expected non-transient errors to be returned")
+	}
+}
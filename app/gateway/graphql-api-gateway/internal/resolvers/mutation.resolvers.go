@@ -3,15 +3,18 @@ package resolvers
 import (
 	"context"
 	"encoding/json"
-	"time"
 
+	"github.com/haunted-saas/graphql-api-gateway/internal/dataloader"
 	"github.com/haunted-saas/graphql-api-gateway/internal/errors"
 	"github.com/haunted-saas/graphql-api-gateway/internal/generated"
 	"github.com/haunted-saas/graphql-api-gateway/internal/middleware"
 	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	analyticsv1 "github.com/haunted-saas/analytics-service/proto/analytics/v1"
 	billingv1 "github.com/haunted-saas/billing-service/proto/billing/v1"
+	featureflagsv1 "github.com/haunted-saas/feature-flags-service/proto/featureflags/v1"
 	llmv1 "github.com/haunted-saas/llm-gateway-service/proto/llm/v1"
 	notificationsv1 "github.com/haunted-saas/notifications-service/proto/notifications/v1"
 	userauthv1 "github.com/haunted-saas/user-auth-service/proto/userauth/v1"
@@ -40,8 +43,10 @@ func (r *mutationResolver) Register(ctx context.Context, input generated.Registe
 
 	// RegisterResponse only returns User, need to login to get token
 	loginResp, err := r.clients.UserAuth.Login(ctx, &userauthv1.LoginRequest{
-		Email:    input.Email,
-		Password: input.Password,
+		Email:      input.Email,
+		Password:   input.Password,
+		IpAddress:  middleware.GetClientIP(ctx),
+		ClientType: stringPtrToString(input.ClientType),
 	})
 	if err != nil {
 		return nil, errors.ConvertGRPCError(err)
@@ -57,8 +62,10 @@ func (r *mutationResolver) Register(ctx context.Context, input generated.Registe
 
 func (r *mutationResolver) Login(ctx context.Context, input generated.LoginInput) (*generated.AuthPayload, error) {
 	resp, err := r.clients.UserAuth.Login(ctx, &userauthv1.LoginRequest{
-		Email:    input.Email,
-		Password: input.Password,
+		Email:      input.Email,
+		Password:   input.Password,
+		IpAddress:  middleware.GetClientIP(ctx),
+		ClientType: stringPtrToString(input.ClientType),
 	})
 	if err != nil {
 		return nil, errors.ConvertGRPCError(err)
@@ -86,6 +93,22 @@ func (r *mutationResolver) Logout(ctx context.Context) (bool, error) {
 	return true, nil
 }
 
+func (r *mutationResolver) RefreshToken(ctx context.Context, refreshToken string) (*generated.AuthPayload, error) {
+	resp, err := r.clients.UserAuth.RefreshToken(ctx, &userauthv1.RefreshTokenRequest{
+		RefreshToken: refreshToken,
+	})
+	if err != nil {
+		return nil, errors.ConvertGRPCError(err)
+	}
+
+	return &generated.AuthPayload{
+		Token:        resp.AccessToken,
+		RefreshToken: resp.RefreshToken,
+		User:         convertUser(resp.User),
+		ExpiresAt:    resp.ExpiresAt.AsTime(),
+	}, nil
+}
+
 func (r *mutationResolver) RequestPasswordReset(ctx context.Context, email string) (bool, error) {
 	_, err := r.clients.UserAuth.RequestPasswordReset(ctx, &userauthv1.PasswordResetRequest{
 		Email: email,
@@ -111,13 +134,50 @@ func (r *mutationResolver) ResetPassword(ctx context.Context, token string, newP
 }
 
 func (r *mutationResolver) ChangePassword(ctx context.Context, currentPassword string, newPassword string) (bool, error) {
-	// TODO: ChangePassword RPC not implemented in user-auth-service proto yet
-	return false, errors.NewBadRequestError("ChangePassword not implemented yet")
+	if err := middleware.RequireAuth(ctx); err != nil {
+		return false, err
+	}
+
+	_, err := r.clients.UserAuth.ChangePassword(ctx, &userauthv1.ChangePasswordRequest{
+		SessionToken:    middleware.GetToken(ctx),
+		CurrentPassword: currentPassword,
+		NewPassword:     newPassword,
+	})
+	if err != nil {
+		// The auth middleware already accepted the token, so an
+		// Unauthenticated reply means the current password was wrong
+		if st, ok := status.FromError(err); ok && st.Code() == codes.Unauthenticated {
+			return false, errors.NewInvalidCredentialsError("Current password is incorrect")
+		}
+		return false, errors.ConvertGRPCError(err)
+	}
+
+	return true, nil
 }
 
 func (r *mutationResolver) UpdateProfile(ctx context.Context, input generated.UpdateProfileInput) (*generated.User, error) {
-	// TODO: UpdateUser RPC not implemented in user-auth-service proto yet
-	return nil, errors.NewBadRequestError("UpdateProfile not implemented yet")
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Email changes wait until re-verification is supported
+	if input.Email != nil {
+		return nil, errors.NewBadRequestError("email changes are not supported yet")
+	}
+	if input.Name == nil {
+		return nil, errors.NewBadRequestError("name is required")
+	}
+
+	resp, err := r.clients.UserAuth.UpdateUser(ctx, &userauthv1.UpdateUserRequest{
+		UserId: userID,
+		Name:   *input.Name,
+	})
+	if err != nil {
+		return nil, errors.ConvertGRPCError(err)
+	}
+
+	return convertUser(resp), nil
 }
 
 // ============================================================================
@@ -137,12 +197,7 @@ func (r *mutationResolver) AssignRole(ctx context.Context, userID string, roleID
 		return nil, errors.ConvertGRPCError(err)
 	}
 
-	// TODO: GetUser RPC not implemented - return placeholder
-	return &generated.User{
-		ID:    userID,
-		Email: "user@example.com",
-		Name:  stringToPtr("User"),
-	}, nil
+	return r.reloadUser(ctx, userID)
 }
 
 func (r *mutationResolver) RemoveRole(ctx context.Context, userID string, roleID string) (*generated.User, error) {
@@ -158,12 +213,21 @@ func (r *mutationResolver) RemoveRole(ctx context.Context, userID string, roleID
 		return nil, errors.ConvertGRPCError(err)
 	}
 
-	// TODO: GetUser RPC not implemented - return placeholder
-	return &generated.User{
-		ID:    userID,
-		Email: "user@example.com",
-		Name:  stringToPtr("User"),
-	}, nil
+	return r.reloadUser(ctx, userID)
+}
+
+// reloadUser loads a user through the request's dataloader after a change
+// to them, dropping any copy the loader cached before the change
+func (r *mutationResolver) reloadUser(ctx context.Context, userID string) (*generated.User, error) {
+	loader := dataloader.For(ctx).UserByID
+	loader.Clear(ctx, userID)
+
+	u, err := loader.Load(ctx, userID)()
+	if err != nil {
+		r.logger.Error("failed to load user", zap.String("user_id", userID), zap.Error(err))
+		return nil, errors.ConvertGRPCError(err)
+	}
+	return convertUser(u), nil
 }
 
 func (r *mutationResolver) CreateRole(ctx context.Context, input generated.CreateRoleInput) (*generated.Role, error) {
@@ -171,10 +235,13 @@ func (r *mutationResolver) CreateRole(ctx context.Context, input generated.Creat
 		return nil, err
 	}
 
+	actorID, _ := middleware.GetUserID(ctx)
+
 	resp, err := r.clients.UserAuth.CreateRole(ctx, &userauthv1.CreateRoleRequest{
 		Name:          input.Name,
 		Description:   stringPtrToString(input.Description),
 		PermissionIds: input.Permissions,
+		ActorUserId:   actorID,
 	})
 	if err != nil {
 		return nil, errors.ConvertGRPCError(err)
@@ -211,6 +278,30 @@ func (r *mutationResolver) CreateSubscriptionCheckout(ctx context.Context, planI
 	}, nil
 }
 
+func (r *mutationResolver) CreateTrialCheckout(ctx context.Context, teamID string, planID string, trialDays int, customerEmail *string) (*generated.CheckoutPayload, error) {
+	if err := middleware.RequireRole(ctx, "admin"); err != nil {
+		return nil, err
+	}
+
+	trialDaysOverride := int32(trialDays)
+	resp, err := r.clients.Billing.CreateCheckoutSession(r.withAdminToken(ctx), &billingv1.CreateCheckoutSessionRequest{
+		TeamId:            teamID,
+		PlanId:            planID,
+		SuccessUrl:        "http://localhost:3000/success",
+		CancelUrl:         "http://localhost:3000/cancel",
+		CustomerEmail:     stringPtrToString(customerEmail),
+		TrialDaysOverride: &trialDaysOverride,
+	})
+	if err != nil {
+		return nil, errors.ConvertGRPCError(err)
+	}
+
+	return &generated.CheckoutPayload{
+		SessionID: resp.SessionId,
+		URL:       resp.CheckoutUrl,
+	}, nil
+}
+
 func (r *mutationResolver) CancelSubscription(ctx context.Context) (*generated.Subscription, error) {
 	userID, err := middleware.GetUserID(ctx)
 	if err != nil {
@@ -247,6 +338,30 @@ func (r *mutationResolver) UpdateSubscription(ctx context.Context, planID string
 	return convertSubscription(resp.Subscription), nil
 }
 
+// ============================================================================
+// FEATURE FLAGS MUTATIONS
+// ============================================================================
+
+func (r *mutationResolver) SetFeatureEnabled(ctx context.Context, featureName string, enabled bool, persist *bool) (bool, error) {
+	if err := middleware.RequireRole(ctx, "admin"); err != nil {
+		return false, err
+	}
+
+	actorID, _ := middleware.GetUserID(ctx)
+
+	_, err := r.clients.FeatureFlags.SetFeatureEnabled(r.withAdminToken(ctx), &featureflagsv1.SetFeatureEnabledRequest{
+		FeatureName:       featureName,
+		Enabled:           enabled,
+		Persist:           persist != nil && *persist,
+		RequestedByUserId: actorID,
+	})
+	if err != nil {
+		return false, errors.ConvertGRPCError(err)
+	}
+
+	return true, nil
+}
+
 // ============================================================================
 // LLM GATEWAY MUTATIONS
 // ============================================================================
@@ -332,27 +447,20 @@ func (r *mutationResolver) TrackEvent(ctx context.Context, input generated.Track
 	userID, _ := middleware.GetUserID(ctx)
 
 	// Convert properties map to proto PropertyValue map
-	properties := make(map[string]*analyticsv1.PropertyValue)
-	if input.Properties != nil {
-		for key, val := range input.Properties {
-			pv := &analyticsv1.PropertyValue{}
-			switch v := val.(type) {
-			case string:
-				pv.Value = &analyticsv1.PropertyValue_StringValue{StringValue: v}
-			case float64:
-				pv.Value = &analyticsv1.PropertyValue_NumberValue{NumberValue: v}
-			case bool:
-				pv.Value = &analyticsv1.PropertyValue_BoolValue{BoolValue: v}
-			}
-			properties[key] = pv
-		}
+	properties := convertAnalyticsProperties(input.Properties)
+
+	// Leave the timestamp unset so analytics-service stamps the event at ingest,
+	// unless the client supplied one explicitly (backfills)
+	var timestamp int64
+	if input.Timestamp != nil {
+		timestamp = input.Timestamp.Unix()
 	}
 
 	_, err := r.clients.Analytics.TrackEvent(ctx, &analyticsv1.TrackEventRequest{
 		EventName:  input.EventName,
 		UserId:     userID,
 		Properties: properties, // Fixed: use map not JSON
-		Timestamp:  time.Now().Unix(), // Fixed: int64 not timestamppb
+		Timestamp:  timestamp,  // Fixed: int64 not timestamppb
 	})
 	if err != nil {
 		return false, errors.ConvertGRPCError(err)
@@ -361,38 +469,24 @@ func (r *mutationResolver) TrackEvent(ctx context.Context, input generated.Track
 	return true, nil
 }
 
-func (r *mutationResolver) IdentifyUser(ctx context.Context, properties map[string]interface{}) (bool, error) {
+func (r *mutationResolver) IdentifyUser(ctx context.Context, properties map[string]interface{}) (*generated.IdentifyUserResult, error) {
 	userID, err := middleware.GetUserID(ctx)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
 	// Convert properties map to proto PropertyValue map
-	protoProps := make(map[string]*analyticsv1.PropertyValue)
-	if properties != nil {
-		for key, val := range properties {
-			pv := &analyticsv1.PropertyValue{}
-			switch v := val.(type) {
-			case string:
-				pv.Value = &analyticsv1.PropertyValue_StringValue{StringValue: v}
-			case float64:
-				pv.Value = &analyticsv1.PropertyValue_NumberValue{NumberValue: v}
-			case bool:
-				pv.Value = &analyticsv1.PropertyValue_BoolValue{BoolValue: v}
-			}
-			protoProps[key] = pv
-		}
-	}
+	protoProps := convertAnalyticsProperties(properties)
 
-	_, err = r.clients.Analytics.IdentifyUser(ctx, &analyticsv1.IdentifyUserRequest{
+	resp, err := r.clients.Analytics.IdentifyUser(ctx, &analyticsv1.IdentifyUserRequest{
 		UserId:     userID,
 		Properties: protoProps, // Fixed: use map not JSON
 	})
 	if err != nil {
-		return false, errors.ConvertGRPCError(err)
+		return nil, errors.ConvertGRPCError(err)
 	}
 
-	return true, nil
+	return convertIdentifyUserResponse(resp), nil
 }
 
 // ============================================================================
@@ -5,9 +5,11 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/haunted-saas/graphql-api-gateway/internal/dataloader"
 	"github.com/haunted-saas/graphql-api-gateway/internal/errors"
 	"github.com/haunted-saas/graphql-api-gateway/internal/generated"
 	"github.com/haunted-saas/graphql-api-gateway/internal/middleware"
+	"github.com/haunted-saas/pkg/caller"
 	"go.uber.org/zap"
 
 	billingv1 "github.com/haunted-saas/billing-service/proto/billing/v1"
@@ -34,27 +36,84 @@ type queryResolver struct{ *Resolver }
 // ============================================================================
 
 func (r *queryResolver) Me(ctx context.Context) (*generated.User, error) {
-	// GetUser RPC doesn't exist - use ValidateToken as workaround
-	token := middleware.GetToken(ctx)
-	resp, err := r.clients.UserAuth.ValidateToken(ctx, &userauthv1.ValidateTokenRequest{
-		Token: token,
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := dataloader.For(ctx).UserByID.Load(ctx, userID)()
+	if err != nil {
+		r.logger.Error("failed to load user", zap.String("user_id", userID), zap.Error(err))
+		return nil, errors.ConvertGRPCError(err)
+	}
+
+	user := convertUser(u)
+
+	// Effective permissions include wildcard expansion, which the role
+	// list on the user doesn't
+	permsResp, err := r.clients.UserAuth.GetUserPermissions(ctx, &userauthv1.GetUserPermissionsRequest{
+		UserId: userID,
 	})
 	if err != nil {
-		r.logger.Error("failed to validate token", zap.Error(err))
+		r.logger.Error("failed to get user permissions", zap.String("user_id", userID), zap.Error(err))
 		return nil, errors.ConvertGRPCError(err)
 	}
+	user.Permissions = permsResp.Permissions
 
-	return convertUser(resp.User), nil
+	return user, nil
 }
 
 func (r *queryResolver) User(ctx context.Context, id string) (*generated.User, error) {
-	// GetUser RPC doesn't exist in proto yet
-	return nil, errors.NewBadRequestError("User query not implemented - GetUser RPC missing")
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Users may look themselves up; anyone else needs admin
+	if id != userID {
+		if err := middleware.RequireRole(ctx, "admin"); err != nil {
+			return nil, err
+		}
+	}
+
+	u, err := dataloader.For(ctx).UserByID.Load(ctx, id)()
+	if err != nil {
+		r.logger.Error("failed to load user", zap.String("user_id", id), zap.Error(err))
+		return nil, errors.ConvertGRPCError(err)
+	}
+
+	return convertUser(u), nil
 }
 
 func (r *queryResolver) Users(ctx context.Context, limit *int, offset *int) (*generated.UserConnection, error) {
-	// ListUsers RPC doesn't exist in proto yet
-	return nil, errors.NewBadRequestError("Users query not implemented - ListUsers RPC missing")
+	if err := middleware.RequireRole(ctx, "admin"); err != nil {
+		return nil, err
+	}
+
+	requestingUserID, _ := middleware.GetUserID(ctx)
+
+	req := &userauthv1.ListUsersRequest{RequestingUserId: requestingUserID}
+	if limit != nil {
+		req.Limit = int32(*limit)
+	}
+	if offset != nil {
+		req.Offset = int32(*offset)
+	}
+
+	resp, err := r.clients.UserAuth.ListUsers(ctx, req)
+	if err != nil {
+		return nil, errors.ConvertGRPCError(err)
+	}
+
+	users := make([]*generated.User, len(resp.Users))
+	for i, u := range resp.Users {
+		users[i] = convertUser(u)
+	}
+
+	return &generated.UserConnection{
+		Nodes:      users,
+		TotalCount: int(resp.TotalCount),
+	}, nil
 }
 
 func (r *queryResolver) MyPermissions(ctx context.Context) ([]string, error) {
@@ -73,6 +132,28 @@ func (r *queryResolver) MyPermissions(ctx context.Context) ([]string, error) {
 	return resp.Permissions, nil
 }
 
+func (r *queryResolver) UserRoles(ctx context.Context, userID string) ([]*generated.Role, error) {
+	requestingUserID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.clients.UserAuth.GetUserRoles(ctx, &userauthv1.GetUserRolesRequest{
+		UserId:           userID,
+		RequestingUserId: requestingUserID,
+	})
+	if err != nil {
+		return nil, errors.ConvertGRPCError(err)
+	}
+
+	roles := make([]*generated.Role, len(resp.Roles))
+	for i, role := range resp.Roles {
+		roles[i] = convertRole(role)
+	}
+
+	return roles, nil
+}
+
 func (r *queryResolver) Roles(ctx context.Context) ([]*generated.Role, error) {
 	// ListRoles RPC doesn't exist in proto yet
 	return nil, errors.NewBadRequestError("Roles query not implemented - ListRoles RPC missing")
@@ -150,34 +231,45 @@ func (r *queryResolver) BillingPortalURL(ctx context.Context) (string, error) {
 	return resp.PortalUrl, nil // Fixed: field is portal_url
 }
 
+func (r *queryResolver) CheckoutStatus(ctx context.Context, sessionID string) (*generated.CheckoutStatus, error) {
+	userID, err := middleware.GetUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Billing checks the session against the caller's team, which is the
+	// user's ID like the TeamId the checkout was created with
+	resp, err := r.clients.Billing.GetCheckoutStatus(caller.WithTeam(ctx, userID), &billingv1.GetCheckoutStatusRequest{
+		SessionId: sessionID,
+	})
+	if err != nil {
+		return nil, errors.ConvertGRPCError(err)
+	}
+
+	return &generated.CheckoutStatus{
+		SessionID:     resp.SessionId,
+		Status:        resp.Status,
+		PaymentStatus: resp.PaymentStatus,
+		Provisioned:   resp.Provisioned,
+		Subscription:  convertSubscription(resp.Subscription),
+	}, nil
+}
+
 // ============================================================================
 // FEATURE FLAGS QUERIES
 // ============================================================================
 
 func (r *queryResolver) IsFeatureEnabled(ctx context.Context, featureName string, properties map[string]interface{}) (bool, error) {
-	userID, _ := middleware.GetUserID(ctx)
-	teamID := middleware.GetTeamID(ctx)
-
-	propertiesJSON := "{}"
-	if properties != nil {
-		jsonBytes, err := json.Marshal(properties)
-		if err != nil {
-			return false, errors.NewBadRequestError("invalid properties")
-		}
-		propertiesJSON = string(jsonBytes)
-	}
-
-	resp, err := r.clients.FeatureFlags.IsFeatureEnabled(ctx, &featureflagsv1.IsFeatureEnabledRequest{
-		FeatureName:    featureName,
-		UserId:         userID,
-		TeamId:         teamID,
-		PropertiesJson: propertiesJSON,
-	})
+	flagStatus, err := r.featureFlagStatus(ctx, featureName, properties)
 	if err != nil {
-		return false, errors.ConvertGRPCError(err)
+		return false, err
 	}
 
-	return resp.Enabled, nil
+	return flagStatus.Enabled, nil
+}
+
+func (r *queryResolver) FeatureFlag(ctx context.Context, featureName string, properties map[string]interface{}) (*generated.FeatureFlagStatus, error) {
+	return r.featureFlagStatus(ctx, featureName, properties)
 }
 
 func (r *queryResolver) FeatureVariant(ctx context.Context, featureName string, properties map[string]interface{}) (*generated.FeatureVariant, error) {
@@ -217,12 +309,20 @@ func (r *queryResolver) FeatureVariant(ctx context.Context, featureName string,
 	}, nil
 }
 
-func (r *queryResolver) AvailableFeatures(ctx context.Context) ([]*generated.Feature, error) {
+func (r *queryResolver) AvailableFeatures(ctx context.Context, nameContains *string, limit *int, offset *int) ([]*generated.Feature, error) {
 	if err := middleware.RequireRole(ctx, "admin"); err != nil {
 		return nil, err
 	}
 
-	resp, err := r.clients.FeatureFlags.ListFeatures(ctx, &featureflagsv1.ListFeaturesRequest{})
+	req := &featureflagsv1.ListFeaturesRequest{NameContains: stringPtrToString(nameContains)}
+	if limit != nil {
+		req.Limit = int32(*limit)
+	}
+	if offset != nil {
+		req.Offset = int32(*offset)
+	}
+
+	resp, err := r.clients.FeatureFlags.ListFeatures(ctx, req)
 	if err != nil {
 		return nil, errors.ConvertGRPCError(err)
 	}
@@ -1,7 +1,10 @@
 package resolvers
 
 import (
+	"context"
+
 	"github.com/haunted-saas/graphql-api-gateway/internal/clients"
+	"github.com/haunted-saas/pkg/admintoken"
 	"go.uber.org/zap"
 )
 
@@ -9,8 +12,11 @@ import (
 
 // Resolver is the root resolver
 type Resolver struct {
-	clients *clients.GRPCClients
-	logger  *zap.Logger
+	clients   *clients.GRPCClients
+	flagCache *clients.FeatureFlagCache // Optional; nil disables stale flag fallback
+	logger    *zap.Logger
+
+	adminToken string
 }
 
 // NewResolver creates a new resolver
@@ -20,3 +26,20 @@ func NewResolver(clients *clients.GRPCClients, logger *zap.Logger) *Resolver {
 		logger:  logger,
 	}
 }
+
+// SetFeatureFlagCache serves the last known feature flag result when
+// feature-flags-service is briefly unavailable
+func (r *Resolver) SetFeatureFlagCache(cache *clients.FeatureFlagCache) {
+	r.flagCache = cache
+}
+
+// SetAdminToken sets the token sent to backends on admin-only requests.
+// Resolvers check the admin role before attaching it.
+func (r *Resolver) SetAdminToken(token string) {
+	r.adminToken = token
+}
+
+// withAdminToken attaches the admin token to calls made with ctx
+func (r *Resolver) withAdminToken(ctx context.Context) context.Context {
+	return admintoken.WithToken(ctx, r.adminToken)
+}
@@ -0,0 +1,37 @@
+// Package admintoken checks the shared token that admin-only RPCs and
+// request fields require. Callers such as the gateway send it in the
+// x-admin-token metadata after enforcing the admin role themselves.
+package admintoken
+
+import (
+	"context"
+	"crypto/subtle"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// MetadataKey carries the admin token on admin requests
+const MetadataKey = "x-admin-token"
+
+// Check verifies the admin token in the incoming metadata against token.
+// An empty token means admin access isn't configured and every caller is
+// denied (PermissionDenied); a missing or wrong token is Unauthenticated.
+func Check(ctx context.Context, token string) error {
+	if token == "" {
+		return status.Error(codes.PermissionDenied, "admin access is disabled: ADMIN_API_TOKEN is not configured")
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	tokens := md.Get(MetadataKey)
+	if len(tokens) == 0 || subtle.ConstantTimeCompare([]byte(tokens[0]), []byte(token)) != 1 {
+		return status.Error(codes.Unauthenticated, "invalid or missing admin token")
+	}
+	return nil
+}
+
+// WithToken attaches token to the outgoing metadata of calls made with ctx
+func WithToken(ctx context.Context, token string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, MetadataKey, token)
+}
@@ -0,0 +1,43 @@
+package admintoken
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		token    string
+		sent     []string
+		expected codes.Code
+	}{
+		{name: "valid token", token: "secret", sent: []string{"secret"}, expected: codes.OK},
+		{name: "missing token", token: "secret", expected: codes.Unauthenticated},
+		{name: "wrong token", token: "secret", sent: []string{"guess"}, expected: codes.Unauthenticated},
+		{name: "not configured", sent: []string{""}, expected: codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			if tt.sent != nil {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(MetadataKey, tt.sent[0]))
+			}
+			if code := status.Code(Check(ctx, tt.token)); code != tt.expected {
+				t.Errorf("expected %v, got %v", tt.expected, code)
+			}
+		})
+	}
+}
+
+func TestWithToken(t *testing.T) {
+	md, _ := metadata.FromOutgoingContext(WithToken(context.Background(), "secret"))
+	if tokens := md.Get(MetadataKey); len(tokens) != 1 || tokens[0] != "secret" {
+		t.Errorf("expected the token in outgoing metadata, got %v", tokens)
+	}
+}
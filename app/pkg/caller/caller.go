@@ -0,0 +1,32 @@
+// Package caller carries the authenticated caller's team from the gateway to
+// backend services. The gateway has already verified the caller, so services
+// read the team from metadata rather than trusting a request field.
+package caller
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// TeamMetadataKey carries the caller's team on requests from the gateway
+const TeamMetadataKey = "x-caller-team-id"
+
+// WithTeam attaches the caller's team to the outgoing metadata of calls
+// made with ctx
+func WithTeam(ctx context.Context, teamID string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, TeamMetadataKey, teamID)
+}
+
+// Team returns the caller's team from the incoming metadata. A request
+// without one is Unauthenticated.
+func Team(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	teams := md.Get(TeamMetadataKey)
+	if len(teams) == 0 || teams[0] == "" {
+		return "", status.Error(codes.Unauthenticated, "caller team is required")
+	}
+	return teams[0], nil
+}
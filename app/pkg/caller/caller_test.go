@@ -0,0 +1,41 @@
+package caller
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestTeam(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(TeamMetadataKey, "team-1"))
+	teamID, err := Team(ctx)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if teamID != "team-1" {
+		t.Errorf("expected team-1, got %q", teamID)
+	}
+}
+
+func TestTeam_Missing(t *testing.T) {
+	for name, ctx := range map[string]context.Context{
+		"no metadata": context.Background(),
+		"empty team":  metadata.NewIncomingContext(context.Background(), metadata.Pairs(TeamMetadataKey, "")),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := Team(ctx); status.Code(err) != codes.Unauthenticated {
+				t.Errorf("expected Unauthenticated, got %v", err)
+			}
+		})
+	}
+}
+
+func TestWithTeam(t *testing.T) {
+	md, _ := metadata.FromOutgoingContext(WithTeam(context.Background(), "team-1"))
+	if teams := md.Get(TeamMetadataKey); len(teams) != 1 || teams[0] != "team-1" {
+		t.Errorf("expected the team in outgoing metadata, got %v", teams)
+	}
+}
@@ -0,0 +1,31 @@
+// Package deadline gives gRPC requests a deadline when the caller didn't set
+// one, so a handler can't run unbounded.
+package deadline
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor applies defaultTimeout to requests that arrive
+// without a deadline and rejects requests whose deadline has already passed.
+// A defaultTimeout of 0 leaves requests without a deadline alone.
+func UnaryServerInterceptor(defaultTimeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if deadline, ok := ctx.Deadline(); ok {
+			if !time.Now().Before(deadline) {
+				return nil, status.Error(codes.DeadlineExceeded, "request deadline already exceeded")
+			}
+		} else if defaultTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, defaultTimeout)
+			defer cancel()
+		}
+
+		return handler(ctx, req)
+	}
+}
@@ -0,0 +1,88 @@
+package deadline
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var testInfo = &grpc.UnaryServerInfo{FullMethod: "/test.v1.TestService/Test"}
+
+func TestUnaryServerInterceptor_AppliesDefaultDeadline(t *testing.T) {
+	var deadline time.Time
+	var hasDeadline bool
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		deadline, hasDeadline = ctx.Deadline()
+		return nil, nil
+	}
+
+	start := time.Now()
+	if _, err := UnaryServerInterceptor(5*time.Second)(context.Background(), nil, testInfo, handler); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	end := time.Now()
+
+	if !hasDeadline {
+		t.Fatal("expected the default deadline to be applied")
+	}
+	if deadline.Before(start.Add(5*time.Second)) || deadline.After(end.Add(5*time.Second)) {
+		t.Errorf("deadline %v is not 5s after the call", deadline)
+	}
+}
+
+func TestUnaryServerInterceptor_NoDefault(t *testing.T) {
+	var hasDeadline bool
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		_, hasDeadline = ctx.Deadline()
+		return nil, nil
+	}
+
+	if _, err := UnaryServerInterceptor(0)(context.Background(), nil, testInfo, handler); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if hasDeadline {
+		t.Error("expected no deadline with a 0 default")
+	}
+}
+
+func TestUnaryServerInterceptor_KeepsClientDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	want, _ := ctx.Deadline()
+
+	var got time.Time
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got, _ = ctx.Deadline()
+		return nil, nil
+	}
+
+	if _, err := UnaryServerInterceptor(5*time.Second)(ctx, nil, testInfo, handler); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !got.Equal(want) {
+		t.Errorf("expected client deadline %v to be kept, got %v", want, got)
+	}
+}
+
+func TestUnaryServerInterceptor_RejectsExpiredDeadline(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	called := false
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		called = true
+		return nil, nil
+	}
+
+	_, err := UnaryServerInterceptor(5*time.Second)(ctx, nil, testInfo, handler)
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+	if called {
+		t.Error("expected the handler not to run")
+	}
+}
@@ -0,0 +1,22 @@
+module github.com/haunted-saas/pkg
+
+go 1.21
+
+require (
+	go.uber.org/zap v1.26.0
+	go.uber.org/zap v1.26.0
+	google.golang.org/grpc v1.60.1
+	gorm.io/gorm v1.25.5
+)
+
+require (
+	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
+	go.uber.org/multierr v1.10.0 // indirect
+	golang.org/x/net v0.20.0 // indirect
+	golang.org/x/sys v0.16.0 // indirect
+	golang.org/x/text v0.14.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20231212172506-995d672761c0 // indirect
+	google.golang.org/protobuf v1.32.0 // indirect
+)
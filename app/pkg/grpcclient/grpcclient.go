@@ -0,0 +1,38 @@
+// Package grpcclient connects to the internal services a service calls on
+// the side of its own work, such as notifications-service and
+// analytics-service. Those calls are best effort, so each one is bounded by
+// the connection's timeout and a slow peer can't hold up the caller.
+package grpcclient
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// Conn is a connection to an internal service with a per-call timeout
+type Conn struct {
+	*grpc.ClientConn
+	Timeout time.Duration // Bound on each call (0 leaves calls unbounded)
+}
+
+// Dial connects to service at address. service names the peer in errors.
+func Dial(service, address string, timeout time.Duration) (*Conn, error) {
+	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", service, err)
+	}
+	return &Conn{ClientConn: conn, Timeout: timeout}, nil
+}
+
+// Bound returns ctx limited to the connection's timeout. Callers must call
+// the returned cancel once the call completes.
+func (c *Conn) Bound(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.Timeout)
+}
@@ -0,0 +1,49 @@
+package grpcclient
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestConn_Bound(t *testing.T) {
+	conn := &Conn{Timeout: time.Second}
+
+	start := time.Now()
+	ctx, cancel := conn.Bound(context.Background())
+	defer cancel()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		t.Fatal("expected the call to be bounded")
+	}
+	if deadline.Before(start.Add(time.Second)) || deadline.After(time.Now().Add(time.Second)) {
+		t.Errorf("deadline %v is not the connection's timeout away", deadline)
+	}
+}
+
+func TestConn_BoundWithoutTimeout(t *testing.T) {
+	conn := &Conn{}
+
+	ctx, cancel := conn.Bound(context.Background())
+	if _, ok := ctx.Deadline(); ok {
+		t.Error("expected no deadline without a timeout")
+	}
+
+	cancel()
+	if ctx.Err() == nil {
+		t.Error("expected cancel to end the context")
+	}
+}
+
+func TestDial(t *testing.T) {
+	conn, err := Dial("notifications-service", "localhost:50051", time.Second)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer conn.Close()
+
+	if conn.Timeout != time.Second {
+		t.Errorf("expected a 1s timeout, got %v", conn.Timeout)
+	}
+}
@@ -0,0 +1,59 @@
+// Package logsampling reads the zap log sampling policy services configure
+// with LOG_SAMPLING_INITIAL and LOG_SAMPLING_THEREAFTER.
+package logsampling
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// Config keeps the first Initial identical entries per second and every
+// Thereafter-th after that. An Initial of 0 disables sampling.
+type Config struct {
+	Initial    int
+	Thereafter int
+}
+
+// FromEnv reads LOG_SAMPLING_INITIAL (default 0, sampling off) and
+// LOG_SAMPLING_THEREAFTER (default 100). Unparseable values use the default.
+func FromEnv() Config {
+	return Config{
+		Initial:    getEnvInt("LOG_SAMPLING_INITIAL", 0),
+		Thereafter: getEnvInt("LOG_SAMPLING_THEREAFTER", 100),
+	}
+}
+
+// Validate rejects negative values, and a Thereafter of 0 while sampling is
+// enabled: zap would then drop every entry after the first Initial.
+func (c Config) Validate() error {
+	if c.Initial < 0 || c.Thereafter < 0 {
+		return fmt.Errorf("LOG_SAMPLING_INITIAL and LOG_SAMPLING_THEREAFTER cannot be negative")
+	}
+	if c.Initial > 0 && c.Thereafter == 0 {
+		return fmt.Errorf("LOG_SAMPLING_THEREAFTER must be at least 1 when LOG_SAMPLING_INITIAL is set")
+	}
+	return nil
+}
+
+// Zap returns the zap sampling policy, or nil when sampling is disabled
+func (c Config) Zap() *zap.SamplingConfig {
+	if c.Initial <= 0 {
+		return nil
+	}
+	return &zap.SamplingConfig{
+		Initial:    c.Initial,
+		Thereafter: c.Thereafter,
+	}
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,55 @@
+package logsampling
+
+import "testing"
+
+func TestConfig_Zap(t *testing.T) {
+	if got := (Config{}).Zap(); got != nil {
+		t.Errorf("expected sampling to be off by default, got %+v", got)
+	}
+
+	got := Config{Initial: 10, Thereafter: 50}.Zap()
+	if got == nil {
+		t.Fatal("expected a sampling config when Initial is set")
+	}
+	if got.Initial != 10 || got.Thereafter != 50 {
+		t.Errorf("expected initial=10 thereafter=50, got initial=%d thereafter=%d", got.Initial, got.Thereafter)
+	}
+}
+
+func TestFromEnv(t *testing.T) {
+	t.Setenv("LOG_SAMPLING_INITIAL", "")
+	t.Setenv("LOG_SAMPLING_THEREAFTER", "")
+	if got := FromEnv(); got != (Config{Initial: 0, Thereafter: 100}) {
+		t.Errorf("unexpected defaults: %+v", got)
+	}
+
+	t.Setenv("LOG_SAMPLING_INITIAL", "25")
+	t.Setenv("LOG_SAMPLING_THEREAFTER", "200")
+	if got := FromEnv(); got != (Config{Initial: 25, Thereafter: 200}) {
+		t.Errorf("expected sampling from env, got %+v", got)
+	}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  Config
+		wantErr bool
+	}{
+		{"disabled", Config{Initial: 0, Thereafter: 100}, false},
+		{"disabled with zero thereafter", Config{Initial: 0, Thereafter: 0}, false},
+		{"enabled", Config{Initial: 10, Thereafter: 1}, false},
+		{"negative initial", Config{Initial: -1, Thereafter: 100}, true},
+		{"negative thereafter", Config{Initial: 10, Thereafter: -1}, true},
+		{"enabled with zero thereafter", Config{Initial: 10, Thereafter: 0}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.config.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+// Package pagination is the paging convention shared by list RPCs. A request
+// carries a limit and either an offset or an opaque cursor from a previous
+// response; a response carries the total match count and the cursor for the
+// next page, empty on the last page.
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+// Errors returned when normalizing a request
+var (
+	ErrNegativeLimit  = errors.New("limit cannot be negative")
+	ErrNegativeOffset = errors.New("offset cannot be negative")
+	ErrInvalidCursor  = errors.New("invalid cursor")
+)
+
+// Limits bounds the page size for one RPC
+type Limits struct {
+	Default int // Used when the request leaves limit unset
+	Max     int // Larger limits are clamped to this
+}
+
+// DefaultLimits applies to RPCs without their own bounds
+var DefaultLimits = Limits{Default: 20, Max: 100}
+
+// ClampLimit applies the default to an unset limit and caps it at the maximum
+func (l Limits) ClampLimit(limit int) int {
+	if limit <= 0 {
+		limit = l.Default
+	}
+	if l.Max > 0 && limit > l.Max {
+		limit = l.Max
+	}
+	return limit
+}
+
+// Request is the paging part of a list request. Cursor takes precedence
+// over Offset when both are set.
+type Request struct {
+	Limit  int
+	Offset int
+	Cursor string
+}
+
+// Page is a validated request ready to apply to a query. A zero Limit
+// applies no limit.
+type Page struct {
+	Limit  int
+	Offset int
+}
+
+// Response is the paging part of a list response
+type Response struct {
+	Total      int64
+	NextCursor string
+}
+
+// Normalize validates the request, decodes its cursor and clamps its limit
+func Normalize(req Request, limits Limits) (Page, error) {
+	if req.Limit < 0 {
+		return Page{}, ErrNegativeLimit
+	}
+	if req.Offset < 0 {
+		return Page{}, ErrNegativeOffset
+	}
+
+	offset := req.Offset
+	if req.Cursor != "" {
+		var err error
+		if offset, err = DecodeCursor(req.Cursor); err != nil {
+			return Page{}, err
+		}
+	}
+
+	return Page{Limit: limits.ClampLimit(req.Limit), Offset: offset}, nil
+}
+
+// Apply adds the page's LIMIT and OFFSET to a query. Callers count the total
+// on the unpaged query first.
+func (p Page) Apply(query *gorm.DB) *gorm.DB {
+	if p.Limit > 0 {
+		query = query.Limit(p.Limit)
+	}
+	if p.Offset > 0 {
+		query = query.Offset(p.Offset)
+	}
+	return query
+}
+
+// Result builds the response for a page that returned count rows out of total
+func (p Page) Result(total int64, count int) Response {
+	resp := Response{Total: total}
+	if next := p.Offset + count; count > 0 && int64(next) < total {
+		resp.NextCursor = EncodeCursor(next)
+	}
+	return resp
+}
+
+// cursor is the decoded form of an opaque page cursor
+type cursor struct {
+	Offset int `json:"o"`
+}
+
+// EncodeCursor returns the opaque cursor for the page starting at offset
+func EncodeCursor(offset int) string {
+	data, _ := json.Marshal(cursor{Offset: offset})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor returns the offset encoded in a cursor from EncodeCursor
+func DecodeCursor(s string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Offset < 0 {
+		return 0, ErrInvalidCursor
+	}
+	return c.Offset, nil
+}
@@ -0,0 +1,97 @@
+package pagination
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLimits_ClampLimit(t *testing.T) {
+	limits := Limits{Default: 20, Max: 100}
+
+	tests := []struct {
+		name  string
+		limit int
+		want  int
+	}{
+		{name: "unset uses default", limit: 0, want: 20},
+		{name: "negative uses default", limit: -5, want: 20},
+		{name: "within bounds", limit: 50, want: 50},
+		{name: "at max", limit: 100, want: 100},
+		{name: "over max is clamped", limit: 1000, want: 100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := limits.ClampLimit(tt.limit); got != tt.want {
+				t.Errorf("ClampLimit(%d) = %d, want %d", tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursor_RoundTrip(t *testing.T) {
+	for _, offset := range []int{0, 1, 50, 123456} {
+		got, err := DecodeCursor(EncodeCursor(offset))
+		if err != nil {
+			t.Fatalf("DecodeCursor(EncodeCursor(%d)) error: %v", offset, err)
+		}
+		if got != offset {
+			t.Errorf("DecodeCursor(EncodeCursor(%d)) = %d", offset, got)
+		}
+	}
+}
+
+func TestDecodeCursor_Invalid(t *testing.T) {
+	for _, c := range []string{"not base64!", "bm90IGpzb24", EncodeCursor(-1)} {
+		if _, err := DecodeCursor(c); !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("DecodeCursor(%q) error = %v, want ErrInvalidCursor", c, err)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	limits := Limits{Default: 20, Max: 100}
+
+	page, err := Normalize(Request{Limit: 500, Offset: 10}, limits)
+	if err != nil {
+		t.Fatalf("Normalize error: %v", err)
+	}
+	if page != (Page{Limit: 100, Offset: 10}) {
+		t.Errorf("Normalize = %+v, want limit 100 offset 10", page)
+	}
+
+	// The cursor takes precedence over the offset
+	page, err = Normalize(Request{Offset: 10, Cursor: EncodeCursor(40)}, limits)
+	if err != nil {
+		t.Fatalf("Normalize error: %v", err)
+	}
+	if page != (Page{Limit: 20, Offset: 40}) {
+		t.Errorf("Normalize = %+v, want limit 20 offset 40", page)
+	}
+
+	if _, err := Normalize(Request{Limit: -1}, limits); !errors.Is(err, ErrNegativeLimit) {
+		t.Errorf("negative limit error = %v", err)
+	}
+	if _, err := Normalize(Request{Offset: -1}, limits); !errors.Is(err, ErrNegativeOffset) {
+		t.Errorf("negative offset error = %v", err)
+	}
+	if _, err := Normalize(Request{Cursor: "%%%"}, limits); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("bad cursor error = %v", err)
+	}
+}
+
+func TestPage_Result(t *testing.T) {
+	page := Page{Limit: 20, Offset: 40}
+
+	resp := page.Result(100, 20)
+	if resp.Total != 100 {
+		t.Errorf("Total = %d, want 100", resp.Total)
+	}
+	if offset, err := DecodeCursor(resp.NextCursor); err != nil || offset != 60 {
+		t.Errorf("NextCursor decodes to %d (%v), want 60", offset, err)
+	}
+
+	if last := page.Result(55, 15); last.NextCursor != "" {
+		t.Errorf("last page NextCursor = %q, want empty", last.NextCursor)
+	}
+}
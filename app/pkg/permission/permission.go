@@ -0,0 +1,31 @@
+// Package permission matches "resource:action" permission names against
+// grants and denies. user-auth-service enforces permissions with it and the
+// gateway uses it to present them, so both sides agree on what a wildcard
+// covers.
+package permission
+
+import "strings"
+
+// IsWildcard reports whether a permission covers everything ("*") or every
+// action on a resource ("users:*")
+func IsWildcard(permission string) bool {
+	return permission == "*" || strings.HasSuffix(permission, ":*")
+}
+
+// Covers reports whether pattern, an exact or wildcard permission, covers name
+func Covers(pattern, name string) bool {
+	if pattern == name || pattern == "*" {
+		return true
+	}
+	return strings.HasSuffix(pattern, ":*") && strings.HasPrefix(name, strings.TrimSuffix(pattern, "*"))
+}
+
+// AnyCovers reports whether any of patterns covers name
+func AnyCovers(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if Covers(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
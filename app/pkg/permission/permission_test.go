@@ -0,0 +1,51 @@
+package permission
+
+import "testing"
+
+func TestCovers(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{pattern: "users:read", name: "users:read", want: true},
+		{pattern: "users:read", name: "users:write", want: false},
+		{pattern: "*", name: "billing:refund", want: true},
+		{pattern: "users:*", name: "users:delete", want: true},
+		{pattern: "users:*", name: "usersettings:read", want: false},
+		{pattern: "users:*", name: "billing:read", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := Covers(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Covers(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAnyCovers(t *testing.T) {
+	patterns := []string{"users:read", "billing:*"}
+
+	if !AnyCovers(patterns, "billing:refund") {
+		t.Error("expected billing:* to cover billing:refund")
+	}
+	if AnyCovers(patterns, "users:write") {
+		t.Error("expected users:write to be uncovered")
+	}
+	if AnyCovers(nil, "users:read") {
+		t.Error("expected no patterns to cover nothing")
+	}
+}
+
+func TestIsWildcard(t *testing.T) {
+	for _, perm := range []string{"*", "users:*"} {
+		if !IsWildcard(perm) {
+			t.Errorf("expected %q to be a wildcard", perm)
+		}
+	}
+	for _, perm := range []string{"users:read", "users*"} {
+		if IsWildcard(perm) {
+			t.Errorf("expected %q not to be a wildcard", perm)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+// Package shutdown bounds how long a service waits for in-flight requests
+// when it stops.
+package shutdown
+
+import "time"
+
+// GRPCServer is the part of *grpc.Server needed to drain it
+type GRPCServer interface {
+	GracefulStop()
+	Stop()
+}
+
+// DrainGRPC stops server gracefully, waiting for in-flight RPCs to finish,
+// and falls back to Stop once timeout elapses so a hung handler can't block
+// shutdown. It reports whether the drain completed before the timeout. A
+// timeout of 0 waits indefinitely.
+func DrainGRPC(server GRPCServer, timeout time.Duration) bool {
+	if timeout <= 0 {
+		server.GracefulStop()
+		return true
+	}
+
+	done := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		// Stop cancels in-flight RPCs and makes GracefulStop return
+		server.Stop()
+		<-done
+		return false
+	}
+}
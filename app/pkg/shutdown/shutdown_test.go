@@ -0,0 +1,87 @@
+package shutdown
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeServer's GracefulStop blocks until release is closed or Stop is called,
+// like a gRPC server with an in-flight request
+type fakeServer struct {
+	release chan struct{}
+
+	mu      sync.Mutex
+	stopped bool
+	once    sync.Once
+}
+
+func newFakeServer() *fakeServer {
+	return &fakeServer{release: make(chan struct{})}
+}
+
+func (s *fakeServer) GracefulStop() {
+	<-s.release
+}
+
+func (s *fakeServer) Stop() {
+	s.mu.Lock()
+	s.stopped = true
+	s.mu.Unlock()
+	s.finish()
+}
+
+func (s *fakeServer) finish() {
+	s.once.Do(func() { close(s.release) })
+}
+
+func (s *fakeServer) wasStopped() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stopped
+}
+
+func TestDrainGRPC_CompletesBeforeTimeout(t *testing.T) {
+	server := newFakeServer()
+	server.finish()
+
+	if !DrainGRPC(server, time.Second) {
+		t.Error("expected the drain to complete gracefully")
+	}
+	if server.wasStopped() {
+		t.Error("expected Stop not to be called")
+	}
+}
+
+func TestDrainGRPC_HungRequestForcesStop(t *testing.T) {
+	server := newFakeServer()
+
+	start := time.Now()
+	graceful := DrainGRPC(server, 50*time.Millisecond)
+	elapsed := time.Since(start)
+
+	if graceful {
+		t.Error("expected the drain to time out")
+	}
+	if !server.wasStopped() {
+		t.Error("expected Stop to be called after the timeout")
+	}
+	if elapsed > time.Second {
+		t.Errorf("expected shutdown shortly after the timeout, took %v", elapsed)
+	}
+}
+
+func TestDrainGRPC_ZeroTimeoutWaits(t *testing.T) {
+	server := newFakeServer()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		server.finish()
+	}()
+
+	if !DrainGRPC(server, 0) {
+		t.Error("expected a zero timeout to wait for the drain")
+	}
+	if server.wasStopped() {
+		t.Error("expected Stop not to be called")
+	}
+}
@@ -13,6 +13,8 @@ import (
 	"github.com/haunted-saas/analytics-service/internal"
 	"github.com/haunted-saas/analytics-service/internal/config"
 	pb "github.com/haunted-saas/analytics-service/proto/analytics/v1"
+	"github.com/haunted-saas/pkg/deadline"
+	"github.com/haunted-saas/pkg/shutdown"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc"
@@ -29,7 +31,7 @@ func main() {
 	}
 
 	// Initialize logger
-	logger, err := initLogger(cfg.Logging.Level, cfg.Logging.Format)
+	logger, err := initLogger(cfg.Logging.Level, cfg.Logging.Format, cfg.Logging.Sampling.Zap())
 	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
@@ -45,14 +47,21 @@ func main() {
 	queue := internal.NewBatchQueue(cfg.Analytics.BatchSize)
 	logger.Info("✓ Batch queue initialized", zap.Int("max_size", cfg.Analytics.BatchSize))
 
-	// Initialize external provider (Mixpanel)
-	provider := internal.NewMixpanelProvider(cfg.Analytics.MixpanelAPIKey, cfg.Analytics.TestMode, logger)
+	// Initialize external provider
+	provider, err := internal.NewProvider(cfg.Analytics, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize analytics provider", zap.Error(err))
+	}
 	logger.Info("✓ Analytics provider initialized", zap.String("provider", provider.GetName()))
 
 	if cfg.Analytics.TestMode {
 		logger.Warn("⚠️  TEST MODE ENABLED - Events will not be sent to external provider")
 	}
 
+	// Track delivery health per provider
+	providerHealth := internal.NewProviderHealthTracker(cfg.Analytics.UnhealthyAfter)
+	trackedProvider := internal.WithHealthTracking(provider, providerHealth)
+
 	// Initialize retry config
 	retryConfig := &internal.RetryConfig{
 		MaxAttempts:   cfg.Analytics.MaxRetryAttempts,
@@ -63,7 +72,14 @@ func main() {
 
 	// Initialize batch worker
 	flushInterval := time.Duration(cfg.Analytics.FlushIntervalSec) * time.Second
-	worker := internal.NewBatchWorker(queue, provider, flushInterval, retryConfig, logger)
+	worker := internal.NewBatchWorker(queue, trackedProvider, flushInterval, retryConfig, logger)
+	worker.SetShutdownTimeout(time.Duration(cfg.Analytics.ShutdownFlushSec) * time.Second)
+	worker.SetMaxPausedEvents(cfg.Analytics.MaxPausedEvents)
+	worker.SetRedactor(internal.NewPropertyRedactor(
+		cfg.Analytics.RedactProperties,
+		cfg.Analytics.HashProperties,
+		cfg.Analytics.PropertyHashKey,
+	))
 	
 	// Start batch worker (concurrent goroutine)
 	worker.Start()
@@ -71,11 +87,17 @@ func main() {
 
 	// Initialize gRPC server
 	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(loggingInterceptor(logger)),
+		grpc.ChainUnaryInterceptor(
+			loggingInterceptor(logger),
+			deadline.UnaryServerInterceptor(cfg.Server.DefaultDeadline),
+		),
 	)
 
 	// Register analytics service
-	analyticsService := internal.NewAnalyticsServer(queue, logger)
+	analyticsService := internal.NewAnalyticsServer(queue, worker, logger)
+	analyticsService.SetProviderHealth(providerHealth)
+	analyticsService.SetAdminToken(cfg.Analytics.AdminAPIToken)
+	analyticsService.SetFlushNowMinInterval(time.Duration(cfg.Analytics.FlushNowMinSec) * time.Second)
 	pb.RegisterAnalyticsServiceServer(grpcServer, analyticsService)
 
 	// Register health check
@@ -83,6 +105,20 @@ func main() {
 	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
 	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
 
+	// Reflect provider delivery health on the service-specific status.
+	// The overall "" status stays SERVING since events are still accepted.
+	const serviceName = "analytics.v1.AnalyticsService"
+	healthServer.SetServingStatus(serviceName, grpc_health_v1.HealthCheckResponse_SERVING)
+	providerHealth.OnChange(func(healthy bool) {
+		if healthy {
+			logger.Info("analytics providers healthy again")
+			healthServer.SetServingStatus(serviceName, grpc_health_v1.HealthCheckResponse_SERVING)
+			return
+		}
+		logger.Error("analytics provider unhealthy", zap.Any("providers", providerHealth.Snapshot()))
+		healthServer.SetServingStatus(serviceName, grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	})
+
 	// Register reflection for development
 	reflection.Register(grpcServer)
 
@@ -110,18 +146,21 @@ func main() {
 	logger.Info("Shutting down gracefully...")
 
 	// Stop accepting new gRPC requests
-	grpcServer.GracefulStop()
+	if !shutdown.DrainGRPC(grpcServer, cfg.Server.DrainTimeout) {
+		logger.Warn("In-flight requests did not finish in time, forced gRPC server stop",
+			zap.Duration("timeout", cfg.Server.DrainTimeout))
+	}
 	logger.Info("✓ gRPC server stopped")
 
-	// Stop batch worker (triggers final flush)
+	// Stop batch worker (triggers final flush, bounded by SHUTDOWN_FLUSH_TIMEOUT_SECONDS)
 	worker.Stop()
-	logger.Info("✓ Batch worker stopped (final flush completed)")
+	logger.Info("✓ Batch worker stopped")
 
 	logger.Info("Shutdown complete")
 }
 
-// initLogger initializes the logger
-func initLogger(level, format string) (*zap.Logger, error) {
+// initLogger initializes the logger. A nil sampling config disables sampling.
+func initLogger(level, format string, sampling *zap.SamplingConfig) (*zap.Logger, error) {
 	var zapLevel zapcore.Level
 	switch level {
 	case "debug":
@@ -138,6 +177,7 @@ func initLogger(level, format string) (*zap.Logger, error) {
 
 	config := zap.NewProductionConfig()
 	config.Level = zap.NewAtomicLevelAt(zapLevel)
+	config.Sampling = sampling
 
 	if format == "console" {
 		config.Encoding = "console"
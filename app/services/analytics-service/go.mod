@@ -4,6 +4,7 @@ go 1.21
 
 require (
 	github.com/google/uuid v1.5.0
+	github.com/haunted-saas/pkg v0.0.0
 	go.uber.org/zap v1.26.0
 	google.golang.org/grpc v1.60.1
 	google.golang.org/protobuf v1.32.0
@@ -17,3 +18,5 @@ require (
 	golang.org/x/text v0.14.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20231212172506-995d672761c0 // indirect
 )
+
+replace github.com/haunted-saas/pkg => ../../pkg
@@ -3,6 +3,7 @@ package internal
 import (
 	"context"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"go.uber.org/zap"
@@ -24,8 +25,27 @@ type BatchWorker struct {
 	logger       *zap.Logger
 	stopChan     chan struct{}
 	doneChan     chan struct{}
+
+	// Bounds the final flush on Stop (0 waits indefinitely)
+	shutdownTimeout time.Duration
+	// Events taken off the queue but not yet delivered
+	inFlight atomic.Int64
+
+	// Maintenance pause: events keep queueing but are not flushed
+	paused          atomic.Bool
+	resumeChan      chan struct{}
+	maxPausedEvents int
+
+	// On-demand flushes requested by FlushNow, served by the worker loop
+	flushNowChan chan chan FlushResult
+
+	// Drops or hashes PII properties before events leave the service (nil disables)
+	redactor *PropertyRedactor
 }
 
+// defaultMaxPausedEvents bounds the queue while the worker is paused
+const defaultMaxPausedEvents = 10000
+
 // NewBatchWorker creates a new batch worker
 func NewBatchWorker(
 	queue *BatchQueue,
@@ -42,7 +62,92 @@ func NewBatchWorker(
 		logger:        logger,
 		stopChan:      make(chan struct{}),
 		doneChan:      make(chan struct{}),
+
+		resumeChan:      make(chan struct{}, 1),
+		maxPausedEvents: defaultMaxPausedEvents,
+		flushNowChan:    make(chan chan FlushResult),
+	}
+}
+
+// FlushResult reports the outcome of an on-demand flush
+type FlushResult struct {
+	Flushed int   // Events delivered to the provider
+	Err     error // Last send error, if any batch failed
+}
+
+// FlushNow asks the worker to flush the current queue immediately, even while
+// paused, and waits for the result or for ctx to be done
+func (w *BatchWorker) FlushNow(ctx context.Context) (FlushResult, error) {
+	reply := make(chan FlushResult, 1)
+
+	select {
+	case w.flushNowChan <- reply:
+	case <-w.doneChan:
+		return FlushResult{}, fmt.Errorf("batch worker is stopped")
+	case <-ctx.Done():
+		return FlushResult{}, ctx.Err()
+	}
+
+	select {
+	case result := <-reply:
+		return result, nil
+	case <-ctx.Done():
+		return FlushResult{}, ctx.Err()
+	}
+}
+
+// SetShutdownTimeout bounds how long Stop waits for the final flush (0 waits indefinitely)
+func (w *BatchWorker) SetShutdownTimeout(timeout time.Duration) {
+	w.shutdownTimeout = timeout
+}
+
+// SetMaxPausedEvents bounds how many events may accumulate while paused.
+// Past this limit the worker flushes anyway to bound memory.
+func (w *BatchWorker) SetMaxPausedEvents(max int) {
+	w.maxPausedEvents = max
+}
+
+// SetRedactor sets the redactor applied to every batch before it is sent
+func (w *BatchWorker) SetRedactor(redactor *PropertyRedactor) {
+	w.redactor = redactor
+}
+
+// Pause stops flushing while events keep queueing (e.g. during provider maintenance)
+func (w *BatchWorker) Pause() {
+	if !w.paused.Swap(true) {
+		w.logger.Info("batch worker paused", zap.Int("queued_events", w.queue.Size()))
+	}
+}
+
+// Resume restarts flushing and drains events buffered while paused
+func (w *BatchWorker) Resume() {
+	if w.paused.Swap(false) {
+		w.logger.Info("batch worker resumed", zap.Int("queued_events", w.queue.Size()))
+		select {
+		case w.resumeChan <- struct{}{}:
+		default:
+			// Drain already pending
+		}
+	}
+}
+
+// IsPaused reports whether flushing is paused
+func (w *BatchWorker) IsPaused() bool {
+	return w.paused.Load()
+}
+
+// holdFlush reports whether a flush should be skipped because the worker is paused
+func (w *BatchWorker) holdFlush() bool {
+	if !w.paused.Load() {
+		return false
 	}
+	if queued := w.queue.Size(); w.maxPausedEvents > 0 && queued >= w.maxPausedEvents {
+		w.logger.Warn("paused buffer full, flushing to bound memory",
+			zap.Int("queued_events", queued),
+			zap.Int("max_paused_events", w.maxPausedEvents))
+		return false
+	}
+	return true
 }
 
 // Start starts the batch worker
@@ -64,21 +169,43 @@ func (w *BatchWorker) run() {
 	for {
 		select {
 		case <-w.queue.FlushChannel():
-			// Batch size reached - flush immediately
+			// Batch size reached - flush immediately (unless paused)
+			if w.holdFlush() {
+				continue
+			}
 			w.logger.Debug("batch size reached, flushing")
 			w.flush()
 			w.resetTimer()
 
 		case <-w.flushTimer.C:
-			// Timer expired - flush if we have events
-			w.logger.Debug("flush timer expired")
+			// Timer expired - flush if we have events (unless paused)
+			if !w.holdFlush() {
+				w.logger.Debug("flush timer expired")
+				w.flush()
+			}
+			w.resetTimer()
+
+		case reply := <-w.flushNowChan:
+			// Admin-requested flush - runs regardless of pause
+			w.logger.Info("on-demand flush requested")
+			reply <- w.flushWithContext(context.Background())
+			w.resetTimer()
+
+		case <-w.resumeChan:
+			// Resumed - drain everything buffered while paused
 			w.flush()
 			w.resetTimer()
 
 		case <-w.stopChan:
-			// Shutdown requested - final flush
+			// Shutdown requested - final flush, bounded by the shutdown timeout
 			w.logger.Info("batch worker stopping, performing final flush")
-			w.flush()
+			ctx := context.Background()
+			if w.shutdownTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, w.shutdownTimeout)
+				defer cancel()
+			}
+			w.flushWithContext(ctx)
 			return
 		}
 	}
@@ -86,18 +213,54 @@ func (w *BatchWorker) run() {
 
 // flush processes the current batch
 func (w *BatchWorker) flush() {
-	batch := w.queue.GetBatch()
-	if len(batch) == 0 {
+	w.flushWithContext(context.Background())
+}
+
+// flushWithContext sends queued events in batch-sized chunks, giving up when ctx is done.
+// Chunking keeps requests within provider limits when a backlog built up (e.g. while paused).
+func (w *BatchWorker) flushWithContext(ctx context.Context) FlushResult {
+	var result FlushResult
+
+	// Only drain what is queued now so sustained traffic can't keep us here forever
+	remaining := w.queue.Size()
+	if remaining == 0 {
 		w.logger.Debug("no events to flush")
-		return
+		return result
+	}
+
+	for remaining > 0 && ctx.Err() == nil {
+		batch := w.queue.GetBatchUpTo(w.queue.maxSize)
+		if len(batch) == 0 {
+			break
+		}
+		remaining -= len(batch)
+		if err := w.sendBatch(ctx, batch); err != nil {
+			result.Err = err
+		} else {
+			result.Flushed += len(batch)
+		}
 	}
 
+	return result
+}
+
+// sendBatch delivers a batch taken off the queue, logging the outcome
+func (w *BatchWorker) sendBatch(ctx context.Context, batch []Event) error {
+	w.inFlight.Store(int64(len(batch)))
+	defer w.inFlight.Store(0)
+
 	w.logger.Info("flushing batch",
 		zap.Int("event_count", len(batch)),
 		zap.String("provider", w.provider.GetName()))
 
+	// Redact once up front so retries resend the same payload
+	if w.redactor.Enabled() {
+		batch = w.redactor.Redact(batch)
+	}
+
 	// Send batch with retry logic
-	if err := w.sendBatchWithRetry(context.Background(), batch); err != nil {
+	err := w.sendBatchWithRetry(ctx, batch)
+	if err != nil {
 		w.logger.Error("failed to flush batch after retries",
 			zap.Int("event_count", len(batch)),
 			zap.Error(err))
@@ -106,6 +269,12 @@ func (w *BatchWorker) flush() {
 		w.logger.Info("batch flushed successfully",
 			zap.Int("event_count", len(batch)))
 	}
+	return err
+}
+
+// UnflushedCount returns the number of events queued or in flight
+func (w *BatchWorker) UnflushedCount() int {
+	return w.queue.Size() + int(w.inFlight.Load())
 }
 
 // sendBatchWithRetry sends a batch with exponential backoff retry
@@ -163,10 +332,25 @@ func (w *BatchWorker) resetTimer() {
 	w.flushTimer.Reset(w.flushInterval)
 }
 
-// Stop stops the batch worker gracefully
+// Stop stops the batch worker gracefully.
+// If the final flush exceeds the shutdown timeout, Stop returns without waiting for it.
 func (w *BatchWorker) Stop() {
 	w.logger.Info("stopping batch worker")
 	close(w.stopChan)
-	<-w.doneChan
-	w.logger.Info("batch worker stopped")
+
+	if w.shutdownTimeout <= 0 {
+		<-w.doneChan
+		w.logger.Info("batch worker stopped")
+		return
+	}
+
+	select {
+	case <-w.doneChan:
+		w.logger.Info("batch worker stopped")
+	case <-time.After(w.shutdownTimeout):
+		// A hung provider ignored cancellation - abandon the flush rather than block shutdown
+		w.logger.Error("final flush timed out, abandoning unflushed events",
+			zap.Duration("timeout", w.shutdownTimeout),
+			zap.Int("unflushed_events", w.UnflushedCount()))
+	}
 }
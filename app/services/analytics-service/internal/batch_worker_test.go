@@ -0,0 +1,189 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// hangingProvider blocks until released, ignoring context cancellation
+type hangingProvider struct {
+	release chan struct{}
+}
+
+func (p *hangingProvider) SendBatch(ctx context.Context, events []Event) error {
+	<-p.release
+	return nil
+}
+
+func (p *hangingProvider) GetName() string {
+	return "hanging"
+}
+
+func TestBatchWorker_StopDoesNotBlockOnHangingProvider(t *testing.T) {
+	provider := &hangingProvider{release: make(chan struct{})}
+	defer close(provider.release)
+
+	queue := NewBatchQueue(100)
+	worker := NewBatchWorker(queue, provider, time.Hour, DefaultRetryConfig(), zap.NewNop())
+	worker.SetShutdownTimeout(50 * time.Millisecond)
+	worker.Start()
+
+	queue.Add(Event{ID: "evt-1", EventName: "signup"})
+	queue.Add(Event{ID: "evt-2", EventName: "login"})
+
+	start := time.Now()
+	worker.Stop()
+	elapsed := time.Since(start)
+
+	if elapsed > time.Second {
+		t.Fatalf("Stop blocked for %s despite a 50ms shutdown timeout", elapsed)
+	}
+	if got := worker.UnflushedCount(); got != 2 {
+		t.Errorf("expected 2 unflushed events, got %d", got)
+	}
+}
+
+// countingProvider records how many events it received
+type countingProvider struct {
+	events chan int
+}
+
+func (p *countingProvider) SendBatch(ctx context.Context, events []Event) error {
+	p.events <- len(events)
+	return nil
+}
+
+func (p *countingProvider) GetName() string {
+	return "counting"
+}
+
+func TestBatchWorker_StopFlushesRemainingEvents(t *testing.T) {
+	provider := &countingProvider{events: make(chan int, 1)}
+
+	queue := NewBatchQueue(100)
+	worker := NewBatchWorker(queue, provider, time.Hour, DefaultRetryConfig(), zap.NewNop())
+	worker.SetShutdownTimeout(time.Second)
+	worker.Start()
+
+	queue.Add(Event{ID: "evt-1", EventName: "signup"})
+	worker.Stop()
+
+	select {
+	case n := <-provider.events:
+		if n != 1 {
+			t.Errorf("expected 1 event flushed, got %d", n)
+		}
+	default:
+		t.Fatal("expected final flush to send remaining events")
+	}
+	if got := worker.UnflushedCount(); got != 0 {
+		t.Errorf("expected no unflushed events, got %d", got)
+	}
+}
+
+func TestBatchWorker_PauseAccumulatesAndResumeDrains(t *testing.T) {
+	provider := &countingProvider{events: make(chan int, 10)}
+
+	queue := NewBatchQueue(2)
+	worker := NewBatchWorker(queue, provider, 10*time.Millisecond, DefaultRetryConfig(), zap.NewNop())
+	worker.Start()
+	defer worker.Stop()
+
+	worker.Pause()
+	for i := 0; i < 5; i++ {
+		queue.Add(Event{ID: fmt.Sprintf("evt-%d", i), EventName: "page_view"})
+	}
+
+	// Several flush intervals pass without anything being sent
+	time.Sleep(50 * time.Millisecond)
+	select {
+	case n := <-provider.events:
+		t.Fatalf("expected no flush while paused, got batch of %d", n)
+	default:
+	}
+	if got := queue.Size(); got != 5 {
+		t.Fatalf("expected 5 queued events while paused, got %d", got)
+	}
+
+	worker.Resume()
+
+	// Drained in batch-sized chunks: 2 + 2 + 1
+	total := 0
+	deadline := time.After(time.Second)
+	for total < 5 {
+		select {
+		case n := <-provider.events:
+			if n > 2 {
+				t.Errorf("expected drain batches of at most 2, got %d", n)
+			}
+			total += n
+		case <-deadline:
+			t.Fatalf("expected 5 events flushed after resume, got %d", total)
+		}
+	}
+}
+
+func TestBatchWorker_PausedBufferLimitForcesFlush(t *testing.T) {
+	provider := &countingProvider{events: make(chan int, 10)}
+
+	queue := NewBatchQueue(2)
+	worker := NewBatchWorker(queue, provider, time.Hour, DefaultRetryConfig(), zap.NewNop())
+	worker.SetMaxPausedEvents(4)
+	worker.Start()
+	defer worker.Stop()
+
+	worker.Pause()
+	for i := 0; i < 4; i++ {
+		queue.Add(Event{ID: fmt.Sprintf("evt-%d", i), EventName: "page_view"})
+	}
+
+	select {
+	case <-provider.events:
+	case <-time.After(time.Second):
+		t.Fatal("expected a flush once the paused buffer limit was reached")
+	}
+}
+
+func TestBatchWorker_FlushNowDrainsQueue(t *testing.T) {
+	provider := &countingProvider{events: make(chan int, 10)}
+
+	queue := NewBatchQueue(2)
+	worker := NewBatchWorker(queue, provider, time.Hour, DefaultRetryConfig(), zap.NewNop())
+	worker.Start()
+	defer worker.Stop()
+
+	// Paused so the size trigger doesn't flush before FlushNow does
+	worker.Pause()
+	for i := 0; i < 5; i++ {
+		queue.Add(Event{ID: fmt.Sprintf("evt-%d", i), EventName: "page_view"})
+	}
+
+	result, err := worker.FlushNow(context.Background())
+	if err != nil {
+		t.Fatalf("FlushNow failed: %v", err)
+	}
+	if result.Err != nil {
+		t.Errorf("expected no send error, got %v", result.Err)
+	}
+	if result.Flushed != 5 {
+		t.Errorf("expected 5 events flushed, got %d", result.Flushed)
+	}
+	if got := queue.Size(); got != 0 {
+		t.Errorf("expected empty queue after FlushNow, got %d", got)
+	}
+}
+
+func TestBatchWorker_FlushNowAfterStop(t *testing.T) {
+	queue := NewBatchQueue(10)
+	worker := NewBatchWorker(queue, &countingProvider{events: make(chan int, 1)}, time.Hour, DefaultRetryConfig(), zap.NewNop())
+	worker.Start()
+	worker.Stop()
+
+	if _, err := worker.FlushNow(context.Background()); err == nil {
+		t.Fatal("expected an error when the worker is stopped")
+	}
+}
@@ -4,6 +4,17 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haunted-saas/pkg/logsampling"
+)
+
+// Supported values for ANALYTICS_PROVIDER
+const (
+	ProviderMixpanel = "mixpanel"
+	ProviderSegment  = "segment"
+	ProviderNoop     = "noop"
 )
 
 // Config holds the service configuration
@@ -15,46 +26,75 @@ type Config struct {
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
-	GRPCPort int
-	Host     string
+	GRPCPort        int
+	Host            string
+	DefaultDeadline time.Duration // Applied to requests that arrive without a deadline (0 disables)
+	DrainTimeout    time.Duration // Bounds the wait for in-flight RPCs on shutdown (0 waits indefinitely)
 }
 
 // AnalyticsConfig holds analytics configuration
 type AnalyticsConfig struct {
+	Provider          string // One of the Provider* constants
 	MixpanelAPIKey    string
+	SegmentWriteKey   string
 	BatchSize         int
 	FlushIntervalSec  int
 	TestMode          bool
 	MaxRetryAttempts  int
 	InitialRetryDelay int
 	MaxRetryDelay     int
+	ShutdownFlushSec  int    // Bound on the final flush at shutdown (0 waits indefinitely)
+	MaxPausedEvents   int    // Queue bound while the worker is paused for maintenance
+	UnhealthyAfter    int    // Consecutive send failures before a provider is unhealthy
+	AdminAPIToken     string // Shared secret for admin RPCs such as FlushNow (empty disables them)
+	FlushNowMinSec    int    // Minimum seconds between FlushNow calls
+
+	// Property keys stripped from events before they reach the provider.
+	// Hashed keys keep events joinable without exposing the raw value.
+	RedactProperties []string
+	HashProperties   []string
+	PropertyHashKey  string // HMAC key for HashProperties
 }
 
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
-	Level  string
-	Format string
+	Level    string
+	Format   string
+	Sampling logsampling.Config
 }
 
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	cfg := &Config{
 		Server: ServerConfig{
-			GRPCPort: getEnvInt("GRPC_PORT", 50054),
-			Host:     getEnv("HOST", "0.0.0.0"),
+			GRPCPort:        getEnvInt("GRPC_PORT", 50054),
+			Host:            getEnv("HOST", "0.0.0.0"),
+			DefaultDeadline: time.Duration(getEnvInt("DEFAULT_REQUEST_DEADLINE_SECONDS", 30)) * time.Second,
+			DrainTimeout:    time.Duration(getEnvInt("GRPC_DRAIN_TIMEOUT_SECONDS", 15)) * time.Second,
 		},
 		Analytics: AnalyticsConfig{
+			Provider:          strings.ToLower(strings.TrimSpace(getEnv("ANALYTICS_PROVIDER", ProviderMixpanel))),
 			MixpanelAPIKey:    getEnv("MIXPANEL_API_KEY", ""),
+			SegmentWriteKey:   getEnv("SEGMENT_WRITE_KEY", ""),
 			BatchSize:         getEnvInt("BATCH_SIZE", 50),
 			FlushIntervalSec:  getEnvInt("FLUSH_INTERVAL_SECONDS", 10),
 			TestMode:          getEnvBool("TEST_MODE", false),
 			MaxRetryAttempts:  getEnvInt("MAX_RETRY_ATTEMPTS", 5),
 			InitialRetryDelay: getEnvInt("INITIAL_RETRY_DELAY_MS", 1000),
 			MaxRetryDelay:     getEnvInt("MAX_RETRY_DELAY_MS", 30000),
+			ShutdownFlushSec:  getEnvInt("SHUTDOWN_FLUSH_TIMEOUT_SECONDS", 10),
+			MaxPausedEvents:   getEnvInt("MAX_PAUSED_EVENTS", 10000),
+			UnhealthyAfter:    getEnvInt("PROVIDER_UNHEALTHY_AFTER_FAILURES", 3),
+			AdminAPIToken:     getEnv("ADMIN_API_TOKEN", ""),
+			FlushNowMinSec:    getEnvInt("FLUSH_NOW_MIN_INTERVAL_SECONDS", 10),
+			RedactProperties:  getEnvList("REDACT_PROPERTIES"),
+			HashProperties:    getEnvList("HASH_PROPERTIES"),
+			PropertyHashKey:   getEnv("PROPERTY_HASH_KEY", ""),
 		},
 		Logging: LoggingConfig{
-			Level:  getEnv("LOG_LEVEL", "info"),
-			Format: getEnv("LOG_FORMAT", "json"),
+			Level:    getEnv("LOG_LEVEL", "info"),
+			Format:   getEnv("LOG_FORMAT", "json"),
+			Sampling: logsampling.FromEnv(),
 		},
 	}
 
@@ -68,9 +108,20 @@ func Load() (*Config, error) {
 
 // Validate validates the configuration
 func (c *Config) Validate() error {
-	// Validate Mixpanel API key (unless in test mode)
-	if !c.Analytics.TestMode && c.Analytics.MixpanelAPIKey == "" {
-		return fmt.Errorf("MIXPANEL_API_KEY is required (or enable TEST_MODE)")
+	// Validate the provider and its credentials (unless in test mode)
+	switch c.Analytics.Provider {
+	case ProviderMixpanel:
+		if !c.Analytics.TestMode && c.Analytics.MixpanelAPIKey == "" {
+			return fmt.Errorf("MIXPANEL_API_KEY is required (or enable TEST_MODE)")
+		}
+	case ProviderSegment:
+		if !c.Analytics.TestMode && c.Analytics.SegmentWriteKey == "" {
+			return fmt.Errorf("SEGMENT_WRITE_KEY is required (or enable TEST_MODE)")
+		}
+	case ProviderNoop:
+	default:
+		return fmt.Errorf("ANALYTICS_PROVIDER must be one of %s, %s, %s (got %q)",
+			ProviderMixpanel, ProviderSegment, ProviderNoop, c.Analytics.Provider)
 	}
 
 	// Validate batch size
@@ -83,6 +134,50 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("FLUSH_INTERVAL_SECONDS must be between 1 and 300")
 	}
 
+	// Validate shutdown flush timeout
+	if c.Analytics.ShutdownFlushSec < 0 {
+		return fmt.Errorf("SHUTDOWN_FLUSH_TIMEOUT_SECONDS cannot be negative")
+	}
+
+	// Validate paused buffer size
+	if c.Analytics.MaxPausedEvents < c.Analytics.BatchSize {
+		return fmt.Errorf("MAX_PAUSED_EVENTS must be at least BATCH_SIZE")
+	}
+
+	// Validate provider health threshold
+	if c.Analytics.UnhealthyAfter < 1 {
+		return fmt.Errorf("PROVIDER_UNHEALTHY_AFTER_FAILURES must be at least 1")
+	}
+
+	// Validate FlushNow rate limit
+	if c.Analytics.FlushNowMinSec < 0 {
+		return fmt.Errorf("FLUSH_NOW_MIN_INTERVAL_SECONDS cannot be negative")
+	}
+
+	// Validate property redaction
+	if len(c.Analytics.HashProperties) > 0 && c.Analytics.PropertyHashKey == "" {
+		return fmt.Errorf("PROPERTY_HASH_KEY is required when HASH_PROPERTIES is set")
+	}
+	for _, dropped := range c.Analytics.RedactProperties {
+		for _, hashed := range c.Analytics.HashProperties {
+			if strings.EqualFold(dropped, hashed) {
+				return fmt.Errorf("property %q cannot be in both REDACT_PROPERTIES and HASH_PROPERTIES", dropped)
+			}
+		}
+	}
+
+	if err := c.Logging.Sampling.Validate(); err != nil {
+		return err
+	}
+
+	if c.Server.DefaultDeadline < 0 {
+		return fmt.Errorf("DEFAULT_REQUEST_DEADLINE_SECONDS cannot be negative")
+	}
+
+	if c.Server.DrainTimeout < 0 {
+		return fmt.Errorf("GRPC_DRAIN_TIMEOUT_SECONDS cannot be negative")
+	}
+
 	return nil
 }
 
@@ -112,3 +207,13 @@ func getEnvBool(key string, defaultValue bool) bool {
 	}
 	return defaultValue
 }
+
+func getEnvList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
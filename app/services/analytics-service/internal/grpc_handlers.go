@@ -2,10 +2,15 @@ package internal
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	pb "github.com/haunted-saas/analytics-service/proto/analytics/v1"
+	"github.com/haunted-saas/pkg/admintoken"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
@@ -15,17 +20,48 @@ import (
 type AnalyticsServer struct {
 	pb.UnimplementedAnalyticsServiceServer
 	queue  *BatchQueue
+	worker *BatchWorker
+	health *ProviderHealthTracker
 	logger *zap.Logger
+	now    func() time.Time // Server clock used to stamp events at ingest
+
+	// FlushNow guard: shared admin token and minimum spacing between calls
+	adminToken          string
+	flushNowMinInterval time.Duration
+	flushNowMu          sync.Mutex
+	lastFlushNow        time.Time
 }
 
+// defaultFlushNowMinInterval is the minimum time between FlushNow calls
+const defaultFlushNowMinInterval = 10 * time.Second
+
 // NewAnalyticsServer creates a new analytics server
-func NewAnalyticsServer(queue *BatchQueue, logger *zap.Logger) *AnalyticsServer {
+func NewAnalyticsServer(queue *BatchQueue, worker *BatchWorker, logger *zap.Logger) *AnalyticsServer {
 	return &AnalyticsServer{
 		queue:  queue,
+		worker: worker,
 		logger: logger,
+		now:    time.Now,
+
+		flushNowMinInterval: defaultFlushNowMinInterval,
 	}
 }
 
+// SetAdminToken sets the token admin RPCs must present (empty disables them)
+func (s *AnalyticsServer) SetAdminToken(token string) {
+	s.adminToken = token
+}
+
+// SetFlushNowMinInterval sets the minimum time between FlushNow calls
+func (s *AnalyticsServer) SetFlushNowMinInterval(interval time.Duration) {
+	s.flushNowMinInterval = interval
+}
+
+// SetProviderHealth attaches the provider health tracker reported by GetProviderHealth
+func (s *AnalyticsServer) SetProviderHealth(health *ProviderHealthTracker) {
+	s.health = health
+}
+
 // TrackEvent tracks an analytics event (NON-BLOCKING)
 func (s *AnalyticsServer) TrackEvent(ctx context.Context, req *pb.TrackEventRequest) (*pb.TrackEventResponse, error) {
 	// Validate request
@@ -42,18 +78,19 @@ func (s *AnalyticsServer) TrackEvent(ctx context.Context, req *pb.TrackEventRequ
 		properties[key] = convertPropertyValue(propValue)
 	}
 
-	// Create event
+	// Create event, stamped at ingest by default
+	ingestedAt := s.now()
 	event := Event{
 		ID:         eventID,
 		EventName:  req.EventName,
 		UserID:     req.UserId,
 		Properties: properties,
-		Timestamp:  time.Now(),
-		CreatedAt:  time.Now(),
+		Timestamp:  ingestedAt,
+		CreatedAt:  ingestedAt,
 	}
 
-	// Override timestamp if provided
-	if req.Timestamp > 0 {
+	// Keep an explicit client timestamp (backfills) unless server time was requested
+	if req.Timestamp > 0 && !req.UseServerTimestamp {
 		event.Timestamp = time.Unix(req.Timestamp, 0)
 	}
 
@@ -74,27 +111,54 @@ func (s *AnalyticsServer) TrackEvent(ctx context.Context, req *pb.TrackEventRequ
 	}, nil
 }
 
-// IdentifyUser identifies a user (NON-BLOCKING)
+// IdentifyUser identifies a user (NON-BLOCKING). Reserved or empty property
+// keys are dropped and reported in property_issues; the rest are still sent
+// unless nothing valid remains.
 func (s *AnalyticsServer) IdentifyUser(ctx context.Context, req *pb.IdentifyUserRequest) (*pb.IdentifyUserResponse, error) {
 	// Validate request
 	if req.UserId == "" {
 		return nil, status.Error(codes.InvalidArgument, "user_id is required")
 	}
 
-	// Convert properties
+	// Convert properties, dropping the ones providers would misinterpret
 	properties := make(map[string]interface{})
+	issues := identifyPropertyIssues(req.Properties)
 	for key, propValue := range req.Properties {
+		if _, rejected := issues[key]; rejected {
+			continue
+		}
 		properties[key] = convertPropertyValue(propValue)
 	}
 
+	propertyIssues := make([]*pb.PropertyIssue, 0, len(issues))
+	for key, reason := range issues {
+		propertyIssues = append(propertyIssues, &pb.PropertyIssue{Key: key, Reason: reason})
+	}
+	sort.Slice(propertyIssues, func(i, j int) bool { return propertyIssues[i].Key < propertyIssues[j].Key })
+
+	if len(issues) > 0 {
+		s.logger.Warn("identify properties rejected",
+			zap.String("user_id", req.UserId),
+			zap.Int("rejected_count", len(issues)))
+	}
+
+	// Nothing left to merge into the profile
+	if len(req.Properties) > 0 && len(properties) == 0 {
+		return &pb.IdentifyUserResponse{
+			Success:        true,
+			Enqueued:       false,
+			PropertyIssues: propertyIssues,
+		}, nil
+	}
+
 	// Create identify event (special event type)
 	event := Event{
 		ID:         uuid.New().String(),
 		EventName:  "$identify",
 		UserID:     req.UserId,
 		Properties: properties,
-		Timestamp:  time.Now(),
-		CreatedAt:  time.Now(),
+		Timestamp:  s.now(),
+		CreatedAt:  s.now(),
 	}
 
 	// Add to queue (NON-BLOCKING)
@@ -106,10 +170,40 @@ func (s *AnalyticsServer) IdentifyUser(ctx context.Context, req *pb.IdentifyUser
 
 	// Return immediately (non-blocking)
 	return &pb.IdentifyUserResponse{
-		Success: true,
+		Success:        true,
+		Enqueued:       true,
+		PropertyIssues: propertyIssues,
 	}, nil
 }
 
+// reservedPropertyKeys are set by the providers themselves. A trait with one
+// of these keys would overwrite them, e.g. distinct_id would merge the
+// profile into another user's.
+var reservedPropertyKeys = map[string]bool{
+	"distinct_id": true,
+	"time":        true,
+	"$insert_id":  true,
+	"token":       true,
+	"user_id":     true,
+	"userId":      true,
+}
+
+// identifyPropertyIssues returns the reason each invalid property is rejected, by key
+func identifyPropertyIssues(properties map[string]*pb.PropertyValue) map[string]string {
+	issues := make(map[string]string)
+	for key, value := range properties {
+		switch {
+		case strings.TrimSpace(key) == "":
+			issues[key] = "property key is empty"
+		case reservedPropertyKeys[key]:
+			issues[key] = fmt.Sprintf("%q is a reserved property key", key)
+		case value == nil || value.Value == nil:
+			issues[key] = "property value is not set"
+		}
+	}
+	return issues
+}
+
 // GetEventCount returns event counts (placeholder for future implementation)
 func (s *AnalyticsServer) GetEventCount(ctx context.Context, req *pb.GetEventCountRequest) (*pb.GetEventCountResponse, error) {
 	// TODO: Implement querying from external provider or local database
@@ -130,11 +224,130 @@ func (s *AnalyticsServer) GetUserCount(ctx context.Context, req *pb.GetUserCount
 
 // HealthCheck returns service health status
 func (s *AnalyticsServer) HealthCheck(ctx context.Context, req *pb.HealthCheckRequest) (*pb.HealthCheckResponse, error) {
+	// Events are still accepted while a provider is failing, so report degraded rather than down
+	status := "healthy"
+	if s.health != nil && !s.health.IsHealthy() {
+		status = "degraded"
+	}
+
 	return &pb.HealthCheckResponse{
-		Status: "healthy",
+		Status: status,
 	}, nil
 }
 
+// GetProviderHealth reports delivery health per external provider
+func (s *AnalyticsServer) GetProviderHealth(ctx context.Context, req *pb.GetProviderHealthRequest) (*pb.GetProviderHealthResponse, error) {
+	if s.health == nil {
+		return &pb.GetProviderHealthResponse{Healthy: true}, nil
+	}
+
+	statuses := s.health.Snapshot()
+	providers := make([]*pb.ProviderHealth, len(statuses))
+	for i, st := range statuses {
+		providers[i] = &pb.ProviderHealth{
+			Name:                st.Name,
+			Healthy:             st.Healthy,
+			LastSuccessAt:       unixOrZero(st.LastSuccess),
+			LastFailureAt:       unixOrZero(st.LastFailure),
+			LastError:           st.LastError,
+			ConsecutiveFailures: int32(st.ConsecutiveFailures),
+			ErrorRate:           st.ErrorRate,
+		}
+	}
+
+	return &pb.GetProviderHealthResponse{
+		Healthy:   s.health.IsHealthy(),
+		Providers: providers,
+	}, nil
+}
+
+// unixOrZero converts a time to a Unix timestamp, keeping the zero time as 0
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// SetWorkerPaused pauses or resumes flushing while events keep queueing.
+// Requires the admin token.
+func (s *AnalyticsServer) SetWorkerPaused(ctx context.Context, req *pb.SetWorkerPausedRequest) (*pb.SetWorkerPausedResponse, error) {
+	if err := admintoken.Check(ctx, s.adminToken); err != nil {
+		return nil, err
+	}
+	if req.RequestedByUserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "requested_by_user_id is required")
+	}
+	if s.worker == nil {
+		return nil, status.Error(codes.FailedPrecondition, "batch worker not configured")
+	}
+
+	if req.Paused {
+		s.worker.Pause()
+	} else {
+		s.worker.Resume()
+	}
+
+	// Audit log
+	s.logger.Info("audit event",
+		zap.String("event_type", "analytics.worker.paused_changed"),
+		zap.Bool("paused", req.Paused),
+		zap.String("requested_by_user_id", req.RequestedByUserId))
+
+	return &pb.SetWorkerPausedResponse{
+		Paused:       s.worker.IsPaused(),
+		QueuedEvents: int32(s.queue.Size()),
+	}, nil
+}
+
+// FlushNow forces an immediate flush of queued events, e.g. before a deploy.
+// Requires the admin token and is limited to one call per flushNowMinInterval.
+func (s *AnalyticsServer) FlushNow(ctx context.Context, req *pb.FlushNowRequest) (*pb.FlushNowResponse, error) {
+	if err := admintoken.Check(ctx, s.adminToken); err != nil {
+		return nil, err
+	}
+	if req.RequestedByUserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "requested_by_user_id is required")
+	}
+	if s.worker == nil {
+		return nil, status.Error(codes.FailedPrecondition, "batch worker not configured")
+	}
+
+	s.flushNowMu.Lock()
+	now := s.now()
+	if !s.lastFlushNow.IsZero() && now.Sub(s.lastFlushNow) < s.flushNowMinInterval {
+		s.flushNowMu.Unlock()
+		return nil, status.Errorf(codes.ResourceExhausted, "FlushNow was called less than %s ago", s.flushNowMinInterval)
+	}
+	s.lastFlushNow = now
+	s.flushNowMu.Unlock()
+
+	result, err := s.worker.FlushNow(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Unavailable, "flush not performed: %v", err)
+	}
+
+	// Audit log
+	fields := []zap.Field{
+		zap.String("event_type", "analytics.worker.flushed"),
+		zap.Int("flushed_events", result.Flushed),
+		zap.String("requested_by_user_id", req.RequestedByUserId),
+	}
+	if result.Err != nil {
+		fields = append(fields, zap.Error(result.Err))
+	}
+	s.logger.Info("audit event", fields...)
+
+	resp := &pb.FlushNowResponse{
+		FlushedEvents:   int32(result.Flushed),
+		RemainingEvents: int32(s.queue.Size()),
+	}
+	if result.Err != nil {
+		resp.Error = result.Err.Error()
+	}
+	return resp, nil
+}
+
 // convertPropertyValue converts a proto PropertyValue to interface{}
 func convertPropertyValue(pv *pb.PropertyValue) interface{} {
 	if pv == nil {
@@ -148,6 +361,8 @@ func convertPropertyValue(pv *pb.PropertyValue) interface{} {
 		return v.NumberValue
 	case *pb.PropertyValue_BoolValue:
 		return v.BoolValue
+	case *pb.PropertyValue_TimestampValue:
+		return v.TimestampValue.AsTime()
 	default:
 		return nil
 	}
@@ -0,0 +1,272 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	pb "github.com/haunted-saas/analytics-service/proto/analytics/v1"
+	"github.com/haunted-saas/pkg/admintoken"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newTestAnalyticsServer(now time.Time) (*AnalyticsServer, *BatchQueue) {
+	queue := NewBatchQueue(100)
+	server := NewAnalyticsServer(queue, nil, zap.NewNop())
+	server.now = func() time.Time { return now }
+	return server, queue
+}
+
+func TestTrackEvent_ZeroTimestampStampedServerSide(t *testing.T) {
+	ingestedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	server, queue := newTestAnalyticsServer(ingestedAt)
+
+	_, err := server.TrackEvent(context.Background(), &pb.TrackEventRequest{
+		EventName: "signup",
+		UserId:    "user-1",
+	})
+	if err != nil {
+		t.Fatalf("TrackEvent failed: %v", err)
+	}
+
+	batch := queue.GetBatch()
+	if len(batch) != 1 {
+		t.Fatalf("expected 1 queued event, got %d", len(batch))
+	}
+	if !batch[0].Timestamp.Equal(ingestedAt) {
+		t.Errorf("expected server timestamp %s, got %s", ingestedAt, batch[0].Timestamp)
+	}
+}
+
+func TestTrackEvent_TimestampHandling(t *testing.T) {
+	ingestedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	backfill := time.Date(2023, 1, 15, 8, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		req      *pb.TrackEventRequest
+		expected time.Time
+	}{
+		{
+			name:     "explicit timestamp kept for backfills",
+			req:      &pb.TrackEventRequest{EventName: "purchase", Timestamp: backfill.Unix()},
+			expected: backfill,
+		},
+		{
+			name:     "server timestamp flag overrides client timestamp",
+			req:      &pb.TrackEventRequest{EventName: "purchase", Timestamp: backfill.Unix(), UseServerTimestamp: true},
+			expected: ingestedAt,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, queue := newTestAnalyticsServer(ingestedAt)
+
+			if _, err := server.TrackEvent(context.Background(), tt.req); err != nil {
+				t.Fatalf("TrackEvent failed: %v", err)
+			}
+
+			batch := queue.GetBatch()
+			if len(batch) != 1 {
+				t.Fatalf("expected 1 queued event, got %d", len(batch))
+			}
+			if !batch[0].Timestamp.Equal(tt.expected) {
+				t.Errorf("expected timestamp %s, got %s", tt.expected, batch[0].Timestamp)
+			}
+			if !batch[0].CreatedAt.Equal(ingestedAt) {
+				t.Errorf("expected created_at %s, got %s", ingestedAt, batch[0].CreatedAt)
+			}
+		})
+	}
+}
+
+func TestTrackEvent_TimestampPropertyConvertedToTime(t *testing.T) {
+	ingestedAt := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	trialEnds := time.Date(2024, 6, 15, 9, 30, 0, 0, time.UTC)
+	server, queue := newTestAnalyticsServer(ingestedAt)
+
+	_, err := server.TrackEvent(context.Background(), &pb.TrackEventRequest{
+		EventName: "trial_started",
+		Properties: map[string]*pb.PropertyValue{
+			"trial_ends_at": {Value: &pb.PropertyValue_TimestampValue{TimestampValue: timestamppb.New(trialEnds)}},
+		},
+	})
+	if err != nil {
+		t.Fatalf("TrackEvent failed: %v", err)
+	}
+
+	batch := queue.GetBatch()
+	if len(batch) != 1 {
+		t.Fatalf("expected 1 queued event, got %d", len(batch))
+	}
+	got, ok := batch[0].Properties["trial_ends_at"].(time.Time)
+	if !ok {
+		t.Fatalf("expected time.Time property, got %T", batch[0].Properties["trial_ends_at"])
+	}
+	if !got.Equal(trialEnds) {
+		t.Errorf("expected %s, got %s", trialEnds, got)
+	}
+	if formatted := mixpanelPropertyValue(got); formatted != "2024-06-15T09:30:00" {
+		t.Errorf("expected Mixpanel date format, got %v", formatted)
+	}
+}
+
+func adminContext(token string) context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(admintoken.MetadataKey, token))
+}
+
+func TestFlushNow_RequiresAdminToken(t *testing.T) {
+	server, _ := newTestAnalyticsServer(time.Now())
+	req := &pb.FlushNowRequest{RequestedByUserId: "admin-1"}
+
+	if _, err := server.FlushNow(adminContext("secret"), req); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied without a configured token, got %v", err)
+	}
+
+	server.SetAdminToken("secret")
+	if _, err := server.FlushNow(context.Background(), req); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated without metadata, got %v", err)
+	}
+	if _, err := server.FlushNow(adminContext("wrong"), req); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated for a wrong token, got %v", err)
+	}
+}
+
+func TestSetWorkerPaused_RequiresAdminToken(t *testing.T) {
+	queue := NewBatchQueue(100)
+	worker := NewBatchWorker(queue, &countingProvider{events: make(chan int, 10)}, time.Hour, DefaultRetryConfig(), zap.NewNop())
+	server := NewAnalyticsServer(queue, worker, zap.NewNop())
+	req := &pb.SetWorkerPausedRequest{Paused: true, RequestedByUserId: "admin-1"}
+
+	if _, err := server.SetWorkerPaused(adminContext("secret"), req); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("expected PermissionDenied without a configured token, got %v", err)
+	}
+
+	server.SetAdminToken("secret")
+	if _, err := server.SetWorkerPaused(context.Background(), req); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated without metadata, got %v", err)
+	}
+	if _, err := server.SetWorkerPaused(adminContext("wrong"), req); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("expected Unauthenticated for a wrong token, got %v", err)
+	}
+	if worker.IsPaused() {
+		t.Fatal("expected the worker to stay running after rejected calls")
+	}
+
+	resp, err := server.SetWorkerPaused(adminContext("secret"), req)
+	if err != nil {
+		t.Fatalf("SetWorkerPaused failed: %v", err)
+	}
+	if !resp.Paused || !worker.IsPaused() {
+		t.Error("expected the worker to be paused")
+	}
+}
+
+func TestFlushNow_DrainsQueueAndRateLimits(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	provider := &countingProvider{events: make(chan int, 10)}
+
+	queue := NewBatchQueue(100)
+	worker := NewBatchWorker(queue, provider, time.Hour, DefaultRetryConfig(), zap.NewNop())
+	worker.Start()
+	defer worker.Stop()
+
+	server := NewAnalyticsServer(queue, worker, zap.NewNop())
+	server.now = func() time.Time { return now }
+	server.SetAdminToken("secret")
+	server.SetFlushNowMinInterval(time.Minute)
+
+	for i := 0; i < 3; i++ {
+		queue.Add(Event{ID: fmt.Sprintf("evt-%d", i), EventName: "page_view"})
+	}
+
+	req := &pb.FlushNowRequest{RequestedByUserId: "admin-1"}
+	resp, err := server.FlushNow(adminContext("secret"), req)
+	if err != nil {
+		t.Fatalf("FlushNow failed: %v", err)
+	}
+	if resp.FlushedEvents != 3 || resp.RemainingEvents != 0 || resp.Error != "" {
+		t.Errorf("unexpected response: flushed=%d remaining=%d error=%q",
+			resp.FlushedEvents, resp.RemainingEvents, resp.Error)
+	}
+
+	now = now.Add(30 * time.Second)
+	if _, err := server.FlushNow(adminContext("secret"), req); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expected ResourceExhausted within the min interval, got %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	if _, err := server.FlushNow(adminContext("secret"), req); err != nil {
+		t.Errorf("expected FlushNow to succeed after the min interval, got %v", err)
+	}
+}
+
+func stringProperty(value string) *pb.PropertyValue {
+	return &pb.PropertyValue{Value: &pb.PropertyValue_StringValue{StringValue: value}}
+}
+
+func TestIdentifyUser_ReservedKeysFlagged(t *testing.T) {
+	server, queue := newTestAnalyticsServer(time.Now())
+
+	resp, err := server.IdentifyUser(context.Background(), &pb.IdentifyUserRequest{
+		UserId: "user-1",
+		Properties: map[string]*pb.PropertyValue{
+			"plan":        stringProperty("pro"),
+			"distinct_id": stringProperty("user-2"),
+			"time":        stringProperty("yesterday"),
+			"empty":       {},
+		},
+	})
+	if err != nil {
+		t.Fatalf("IdentifyUser failed: %v", err)
+	}
+	if !resp.Success || !resp.Enqueued {
+		t.Errorf("expected the identify to be enqueued, got success=%v enqueued=%v", resp.Success, resp.Enqueued)
+	}
+
+	var flagged []string
+	for _, issue := range resp.PropertyIssues {
+		flagged = append(flagged, issue.Key)
+		if issue.Reason == "" {
+			t.Errorf("expected a reason for %q", issue.Key)
+		}
+	}
+	if fmt.Sprint(flagged) != "[distinct_id empty time]" {
+		t.Errorf("expected distinct_id, empty and time to be flagged, got %v", flagged)
+	}
+
+	batch := queue.GetBatch()
+	if len(batch) != 1 {
+		t.Fatalf("expected 1 queued event, got %d", len(batch))
+	}
+	if len(batch[0].Properties) != 1 || batch[0].Properties["plan"] != "pro" {
+		t.Errorf("expected only the plan property to be sent, got %v", batch[0].Properties)
+	}
+}
+
+func TestIdentifyUser_NothingValidNotEnqueued(t *testing.T) {
+	server, queue := newTestAnalyticsServer(time.Now())
+
+	resp, err := server.IdentifyUser(context.Background(), &pb.IdentifyUserRequest{
+		UserId:     "user-1",
+		Properties: map[string]*pb.PropertyValue{"distinct_id": stringProperty("user-2")},
+	})
+	if err != nil {
+		t.Fatalf("IdentifyUser failed: %v", err)
+	}
+	if resp.Enqueued {
+		t.Error("expected no identify to be enqueued when every property is rejected")
+	}
+	if len(resp.PropertyIssues) != 1 {
+		t.Errorf("expected 1 property issue, got %d", len(resp.PropertyIssues))
+	}
+	if queue.Size() != 0 {
+		t.Errorf("expected an empty queue, got %d events", queue.Size())
+	}
+}
@@ -11,6 +11,9 @@ import (
 	"go.uber.org/zap"
 )
 
+// mixpanelDateFormat is the only date format Mixpanel recognizes as a date property
+const mixpanelDateFormat = "2006-01-02T15:04:05"
+
 // MixpanelProvider implements the ExternalProvider interface for Mixpanel
 type MixpanelProvider struct {
 	apiKey     string
@@ -58,7 +61,7 @@ func (p *MixpanelProvider) SendBatch(ctx context.Context, events []Event) error
 		// Merge custom properties
 		if event.Properties != nil {
 			for k, v := range event.Properties {
-				mixpanelEvents[i]["properties"].(map[string]interface{})[k] = v
+				mixpanelEvents[i]["properties"].(map[string]interface{})[k] = mixpanelPropertyValue(v)
 			}
 		}
 	}
@@ -103,3 +106,11 @@ func (p *MixpanelProvider) SendBatch(ctx context.Context, events []Event) error
 func (p *MixpanelProvider) GetName() string {
 	return "mixpanel"
 }
+
+// mixpanelPropertyValue formats dates the way Mixpanel expects, in UTC
+func mixpanelPropertyValue(v interface{}) interface{} {
+	if t, ok := v.(time.Time); ok {
+		return t.UTC().Format(mixpanelDateFormat)
+	}
+	return v
+}
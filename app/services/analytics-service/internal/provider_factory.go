@@ -0,0 +1,46 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/haunted-saas/analytics-service/internal/config"
+	"go.uber.org/zap"
+)
+
+// NewProvider returns the external provider selected by cfg.Provider, so
+// switching providers is a configuration change
+func NewProvider(cfg config.AnalyticsConfig, logger *zap.Logger) (ExternalProvider, error) {
+	switch cfg.Provider {
+	case config.ProviderMixpanel:
+		return NewMixpanelProvider(cfg.MixpanelAPIKey, cfg.TestMode, logger), nil
+	case config.ProviderSegment:
+		return NewSegmentProvider(cfg.SegmentWriteKey, cfg.TestMode, logger), nil
+	case config.ProviderNoop:
+		return NewNoopProvider(logger), nil
+	default:
+		return nil, fmt.Errorf("unknown analytics provider %q", cfg.Provider)
+	}
+}
+
+// NoopProvider discards every batch. Useful where events should be accepted
+// but not delivered anywhere, e.g. local development or staging.
+type NoopProvider struct {
+	logger *zap.Logger
+}
+
+// NewNoopProvider creates a provider that drops events
+func NewNoopProvider(logger *zap.Logger) *NoopProvider {
+	return &NoopProvider{logger: logger}
+}
+
+// SendBatch discards the batch
+func (p *NoopProvider) SendBatch(ctx context.Context, events []Event) error {
+	p.logger.Debug("noop provider dropped batch", zap.Int("event_count", len(events)))
+	return nil
+}
+
+// GetName returns the provider name
+func (p *NoopProvider) GetName() string {
+	return config.ProviderNoop
+}
@@ -0,0 +1,45 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/haunted-saas/analytics-service/internal/config"
+	"go.uber.org/zap"
+)
+
+func TestNewProvider_SelectsConfiguredProvider(t *testing.T) {
+	tests := []struct {
+		provider string
+		wantName string
+	}{
+		{provider: config.ProviderMixpanel, wantName: "mixpanel"},
+		{provider: config.ProviderSegment, wantName: "segment"},
+		{provider: config.ProviderNoop, wantName: "noop"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.provider, func(t *testing.T) {
+			provider, err := NewProvider(config.AnalyticsConfig{
+				Provider:        tt.provider,
+				MixpanelAPIKey:  "mixpanel-key",
+				SegmentWriteKey: "segment-key",
+			}, zap.NewNop())
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if provider.GetName() != tt.wantName {
+				t.Errorf("expected %s provider, got %s", tt.wantName, provider.GetName())
+			}
+		})
+	}
+}
+
+func TestNewProvider_UnknownProvider(t *testing.T) {
+	provider, err := NewProvider(config.AnalyticsConfig{Provider: "heap"}, zap.NewNop())
+	if err == nil {
+		t.Fatal("expected an error for an unknown provider")
+	}
+	if provider != nil {
+		t.Errorf("expected no provider, got %s", provider.GetName())
+	}
+}
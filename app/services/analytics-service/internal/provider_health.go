@@ -0,0 +1,182 @@
+package internal
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Provider health defaults
+const (
+	defaultUnhealthyThreshold = 3  // Consecutive failures before a provider is unhealthy
+	healthWindowSize          = 20 // Recent sends used to compute the error rate
+)
+
+// ProviderStatus is a point-in-time view of a provider's delivery health
+type ProviderStatus struct {
+	Name                string
+	Healthy             bool
+	LastSuccess         time.Time
+	LastFailure         time.Time
+	LastError           string
+	ConsecutiveFailures int
+	ErrorRate           float64 // Failures over the last healthWindowSize sends
+}
+
+// providerRecord tracks outcomes for a single provider
+type providerRecord struct {
+	status  ProviderStatus
+	outcome []bool // Ring buffer of recent results (true = failure)
+	next    int
+}
+
+// ProviderHealthTracker records send outcomes per provider
+type ProviderHealthTracker struct {
+	mu        sync.RWMutex
+	providers map[string]*providerRecord
+	threshold int
+	now       func() time.Time
+
+	// Called when overall health flips (optional)
+	onChange func(healthy bool)
+}
+
+// NewProviderHealthTracker creates a tracker that marks a provider unhealthy
+// after threshold consecutive failures
+func NewProviderHealthTracker(threshold int) *ProviderHealthTracker {
+	if threshold <= 0 {
+		threshold = defaultUnhealthyThreshold
+	}
+	return &ProviderHealthTracker{
+		providers: make(map[string]*providerRecord),
+		threshold: threshold,
+		now:       time.Now,
+	}
+}
+
+// OnChange registers a callback invoked when overall health flips
+func (t *ProviderHealthTracker) OnChange(fn func(healthy bool)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.onChange = fn
+}
+
+// RecordSuccess records a successful send
+func (t *ProviderHealthTracker) RecordSuccess(name string) {
+	t.record(name, nil)
+}
+
+// RecordFailure records a failed send
+func (t *ProviderHealthTracker) RecordFailure(name string, err error) {
+	t.record(name, err)
+}
+
+func (t *ProviderHealthTracker) record(name string, err error) {
+	t.mu.Lock()
+	wasHealthy := t.healthyLocked()
+
+	rec := t.recordFor(name)
+	failed := err != nil
+	if len(rec.outcome) < healthWindowSize {
+		rec.outcome = append(rec.outcome, failed)
+	} else {
+		rec.outcome[rec.next] = failed
+		rec.next = (rec.next + 1) % healthWindowSize
+	}
+
+	if failed {
+		rec.status.LastFailure = t.now()
+		rec.status.LastError = err.Error()
+		rec.status.ConsecutiveFailures++
+	} else {
+		rec.status.LastSuccess = t.now()
+		rec.status.ConsecutiveFailures = 0
+	}
+	rec.status.Healthy = rec.status.ConsecutiveFailures < t.threshold
+
+	failures := 0
+	for _, f := range rec.outcome {
+		if f {
+			failures++
+		}
+	}
+	rec.status.ErrorRate = float64(failures) / float64(len(rec.outcome))
+
+	isHealthy := t.healthyLocked()
+	onChange := t.onChange
+	t.mu.Unlock()
+
+	if onChange != nil && wasHealthy != isHealthy {
+		onChange(isHealthy)
+	}
+}
+
+// recordFor returns the record for a provider, creating it if needed. Caller holds mu.
+func (t *ProviderHealthTracker) recordFor(name string) *providerRecord {
+	rec, ok := t.providers[name]
+	if !ok {
+		rec = &providerRecord{status: ProviderStatus{Name: name, Healthy: true}}
+		t.providers[name] = rec
+	}
+	return rec
+}
+
+// healthyLocked reports whether every provider is healthy. Caller holds mu.
+func (t *ProviderHealthTracker) healthyLocked() bool {
+	for _, rec := range t.providers {
+		if !rec.status.Healthy {
+			return false
+		}
+	}
+	return true
+}
+
+// IsHealthy reports whether every tracked provider is healthy
+func (t *ProviderHealthTracker) IsHealthy() bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.healthyLocked()
+}
+
+// Snapshot returns the status of every tracked provider, sorted by name
+func (t *ProviderHealthTracker) Snapshot() []ProviderStatus {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	statuses := make([]ProviderStatus, 0, len(t.providers))
+	for _, rec := range t.providers {
+		statuses = append(statuses, rec.status)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Name < statuses[j].Name
+	})
+	return statuses
+}
+
+// healthTrackingProvider records the outcome of every send on the wrapped provider
+type healthTrackingProvider struct {
+	ExternalProvider
+	tracker *ProviderHealthTracker
+}
+
+// WithHealthTracking wraps a provider so its sends are recorded by tracker
+func WithHealthTracking(provider ExternalProvider, tracker *ProviderHealthTracker) ExternalProvider {
+	// Register up front so the provider is reported before its first send
+	tracker.mu.Lock()
+	tracker.recordFor(provider.GetName())
+	tracker.mu.Unlock()
+
+	return &healthTrackingProvider{ExternalProvider: provider, tracker: tracker}
+}
+
+// SendBatch sends the batch and records the outcome
+func (p *healthTrackingProvider) SendBatch(ctx context.Context, events []Event) error {
+	err := p.ExternalProvider.SendBatch(ctx, events)
+	if err != nil {
+		p.tracker.RecordFailure(p.GetName(), err)
+	} else {
+		p.tracker.RecordSuccess(p.GetName())
+	}
+	return err
+}
@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// failingProvider fails every send while fail is set
+type failingProvider struct {
+	fail bool
+}
+
+func (p *failingProvider) SendBatch(ctx context.Context, events []Event) error {
+	if p.fail {
+		return errors.New("mixpanel API returned status 503")
+	}
+	return nil
+}
+
+func (p *failingProvider) GetName() string {
+	return "mixpanel"
+}
+
+func TestProviderHealth_RepeatedFailuresFlipStatus(t *testing.T) {
+	tracker := NewProviderHealthTracker(3)
+
+	var changes []bool
+	tracker.OnChange(func(healthy bool) {
+		changes = append(changes, healthy)
+	})
+
+	backend := &failingProvider{fail: true}
+	provider := WithHealthTracking(backend, tracker)
+	events := []Event{{ID: "evt-1", EventName: "signup"}}
+
+	if !tracker.IsHealthy() {
+		t.Fatal("expected provider to start healthy")
+	}
+
+	// Two failures stay below the threshold
+	provider.SendBatch(context.Background(), events)
+	provider.SendBatch(context.Background(), events)
+	if !tracker.IsHealthy() {
+		t.Fatal("expected provider to stay healthy below the threshold")
+	}
+
+	// Third consecutive failure flips it
+	provider.SendBatch(context.Background(), events)
+	if tracker.IsHealthy() {
+		t.Fatal("expected provider to be unhealthy after 3 consecutive failures")
+	}
+
+	status := tracker.Snapshot()[0]
+	if status.Name != "mixpanel" || status.ConsecutiveFailures != 3 || status.ErrorRate != 1 {
+		t.Errorf("unexpected status: %+v", status)
+	}
+	if status.LastError == "" || !status.LastSuccess.IsZero() {
+		t.Errorf("unexpected error details: %+v", status)
+	}
+
+	// A success recovers it and lowers the error rate
+	backend.fail = false
+	provider.SendBatch(context.Background(), events)
+	if !tracker.IsHealthy() {
+		t.Fatal("expected provider to recover after a success")
+	}
+
+	status = tracker.Snapshot()[0]
+	if status.ErrorRate != 0.75 || status.LastSuccess.IsZero() {
+		t.Errorf("unexpected status after recovery: %+v", status)
+	}
+
+	if len(changes) != 2 || changes[0] != false || changes[1] != true {
+		t.Errorf("expected health to flip unhealthy then healthy, got %v", changes)
+	}
+}
@@ -0,0 +1,79 @@
+package internal
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// PropertyRedactor strips PII from event properties before they reach a
+// provider. Dropped keys are removed; hashed keys are replaced with an
+// HMAC-SHA256 of the value so events can still be joined on them without
+// the provider seeing the raw value. Keys match case-insensitively.
+type PropertyRedactor struct {
+	drop    map[string]bool
+	hash    map[string]bool
+	hashKey []byte
+}
+
+// NewPropertyRedactor creates a redactor dropping the drop keys and hashing
+// the hash keys with hashKey
+func NewPropertyRedactor(drop, hash []string, hashKey string) *PropertyRedactor {
+	r := &PropertyRedactor{
+		drop:    make(map[string]bool, len(drop)),
+		hash:    make(map[string]bool, len(hash)),
+		hashKey: []byte(hashKey),
+	}
+	for _, key := range drop {
+		r.drop[strings.ToLower(key)] = true
+	}
+	for _, key := range hash {
+		r.hash[strings.ToLower(key)] = true
+	}
+	return r
+}
+
+// Enabled reports whether any keys are configured
+func (r *PropertyRedactor) Enabled() bool {
+	return r != nil && (len(r.drop) > 0 || len(r.hash) > 0)
+}
+
+// Redact returns copies of events with configured properties dropped or
+// hashed. The input events are left untouched.
+func (r *PropertyRedactor) Redact(events []Event) []Event {
+	if !r.Enabled() {
+		return events
+	}
+
+	redacted := make([]Event, len(events))
+	for i, event := range events {
+		redacted[i] = event
+		if len(event.Properties) == 0 {
+			continue
+		}
+
+		properties := make(map[string]interface{}, len(event.Properties))
+		for key, value := range event.Properties {
+			normalized := strings.ToLower(key)
+			switch {
+			case r.drop[normalized]:
+				continue
+			case r.hash[normalized]:
+				properties[key] = r.hashValue(value)
+			default:
+				properties[key] = value
+			}
+		}
+		redacted[i].Properties = properties
+	}
+	return redacted
+}
+
+// hashValue returns the hex HMAC-SHA256 of a property value's string form
+func (r *PropertyRedactor) hashValue(value interface{}) string {
+	mac := hmac.New(sha256.New, r.hashKey)
+	mac.Write([]byte(fmt.Sprint(value)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
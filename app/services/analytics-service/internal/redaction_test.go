@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestPropertyRedactor_DropsAndHashes(t *testing.T) {
+	redactor := NewPropertyRedactor([]string{"phone"}, []string{"email"}, "secret")
+	events := []Event{
+		{ID: "evt-1", Properties: map[string]interface{}{"email": "a@example.com", "Phone": "555-0100", "plan": "pro"}},
+		{ID: "evt-2", Properties: map[string]interface{}{"Email": "a@example.com"}},
+	}
+
+	redacted := redactor.Redact(events)
+
+	first := redacted[0].Properties
+	if _, ok := first["Phone"]; ok {
+		t.Error("expected Phone to be dropped regardless of case")
+	}
+	if first["plan"] != "pro" {
+		t.Errorf("expected unconfigured properties to pass through, got %v", first["plan"])
+	}
+	hashed, ok := first["email"].(string)
+	if !ok || hashed == "" || hashed == "a@example.com" {
+		t.Fatalf("expected email to be hashed, got %v", first["email"])
+	}
+	if redacted[1].Properties["Email"] != hashed {
+		t.Errorf("expected the same value to hash identically so events stay joinable, got %v and %v",
+			hashed, redacted[1].Properties["Email"])
+	}
+	if other := NewPropertyRedactor(nil, []string{"email"}, "other").Redact(events[:1]); other[0].Properties["email"] == hashed {
+		t.Error("expected a different hash key to produce a different hash")
+	}
+
+	if events[0].Properties["email"] != "a@example.com" || events[0].Properties["Phone"] != "555-0100" {
+		t.Errorf("expected the input events to be left untouched, got %v", events[0].Properties)
+	}
+}
+
+func TestBatchWorker_RedactsOutgoingPayload(t *testing.T) {
+	payloads := make(chan map[string]interface{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		payloads <- payload
+	}))
+	defer server.Close()
+
+	provider := NewMixpanelProvider("key", false, zap.NewNop())
+	provider.apiURL = server.URL
+
+	redactor := NewPropertyRedactor([]string{"phone"}, []string{"email"}, "secret")
+	wantHash := redactor.hashValue("a@example.com")
+
+	queue := NewBatchQueue(10)
+	worker := NewBatchWorker(queue, provider, time.Hour, DefaultRetryConfig(), zap.NewNop())
+	worker.SetRedactor(redactor)
+	worker.Start()
+	defer worker.Stop()
+
+	queue.Add(Event{
+		ID:         "evt-1",
+		UserID:     "user-1",
+		EventName:  "signup",
+		Timestamp:  time.Now(),
+		Properties: map[string]interface{}{"email": "a@example.com", "phone": "555-0100", "plan": "pro"},
+	})
+
+	if result, err := worker.FlushNow(context.Background()); err != nil || result.Err != nil {
+		t.Fatalf("FlushNow failed: %v, %v", err, result.Err)
+	}
+
+	payload := <-payloads
+	properties := payload["events"].([]interface{})[0].(map[string]interface{})["properties"].(map[string]interface{})
+	if _, ok := properties["phone"]; ok {
+		t.Error("expected phone to be dropped from the outgoing payload")
+	}
+	if properties["email"] != wantHash {
+		t.Errorf("expected email to be sent as %s, got %v", wantHash, properties["email"])
+	}
+	if properties["plan"] != "pro" {
+		t.Errorf("expected plan to be sent unchanged, got %v", properties["plan"])
+	}
+}
@@ -85,6 +85,25 @@ func (q *BatchQueue) GetBatch() []Event {
 	return batch
 }
 
+// GetBatchUpTo returns and removes at most n of the oldest events
+func (q *BatchQueue) GetBatchUpTo(n int) []Event {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.events) == 0 || n <= 0 {
+		return nil
+	}
+	if n > len(q.events) {
+		n = len(q.events)
+	}
+
+	batch := make([]Event, n)
+	copy(batch, q.events[:n])
+	q.events = append(q.events[:0], q.events[n:]...)
+
+	return batch
+}
+
 // Size returns the current queue size
 func (q *BatchQueue) Size() int {
 	q.mu.Lock()
@@ -15,6 +15,8 @@ import (
 	"github.com/haunted-saas/billing-service/internal/config"
 	"github.com/haunted-saas/billing-service/internal/db"
 	pb "github.com/haunted-saas/billing-service/proto/billing/v1"
+	"github.com/haunted-saas/pkg/deadline"
+	"github.com/haunted-saas/pkg/shutdown"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health"
@@ -76,13 +78,29 @@ func main() {
 
 	// Initialize gRPC server
 	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(loggingInterceptor(zapLogger)),
+		grpc.ChainUnaryInterceptor(
+			loggingInterceptor(zapLogger),
+			deadline.UnaryServerInterceptor(cfg.Server.DefaultDeadline),
+		),
 	)
 
 	// Register billing service
 	billingService := internal.NewBillingServiceServer(stripeClient, store, zapLogger)
 	pb.RegisterBillingServiceServer(grpcServer, billingService)
 
+	// Start subscription reconciler (catches missed webhooks)
+	reconciler := internal.NewSubscriptionReconciler(
+		stripeClient,
+		store,
+		time.Duration(cfg.Reconcile.IntervalMinutes)*time.Minute,
+		zapLogger,
+	)
+	billingService.SetReconciler(reconciler)
+	billingService.SetTrialsWithoutCard(cfg.Trials.AllowWithoutCard)
+	billingService.SetTrialOverrideMax(int32(cfg.Trials.OverrideMaxDays))
+	billingService.SetAdminToken(cfg.AdminAPIToken)
+	reconciler.Start()
+
 	// Register health check
 	healthServer := health.NewServer()
 	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
@@ -105,7 +123,30 @@ func main() {
 	}()
 
 	// Initialize webhook handler
-	webhookHandler := internal.NewWebhookHandler(stripeClient, store, cfg.Stripe.WebhookSecret, zapLogger)
+	webhookHandler := internal.NewWebhookHandler(stripeClient, store, cfg.Stripe.WebhookSecrets, zapLogger)
+	if cfg.Notifications.Address != "" {
+		notificationsClient, err := internal.NewNotificationsClient(
+			cfg.Notifications.Address,
+			time.Duration(cfg.Notifications.TimeoutSec)*time.Second,
+		)
+		if err != nil {
+			zapLogger.Warn("Notifications disabled", zap.Error(err))
+		} else {
+			defer notificationsClient.Close()
+			webhookHandler.SetNotifier(notificationsClient)
+		}
+	}
+
+	// Start webhook retrier (re-processes events that failed transiently)
+	webhookRetrier := internal.NewWebhookRetrier(
+		webhookHandler,
+		store,
+		time.Duration(cfg.WebhookRetry.IntervalSeconds)*time.Second,
+		cfg.WebhookRetry.MaxRetries,
+		time.Duration(cfg.WebhookRetry.BackoffSeconds)*time.Second,
+		zapLogger,
+	)
+	webhookRetrier.Start()
 
 	// Start HTTP server for webhooks
 	httpMux := http.NewServeMux()
@@ -144,16 +185,30 @@ func main() {
 		zapLogger.Error("HTTP server shutdown error", zap.Error(err))
 	}
 
-	grpcServer.GracefulStop()
+	if !shutdown.DrainGRPC(grpcServer, cfg.Server.DrainTimeout) {
+		zapLogger.Warn("In-flight requests did not finish in time, forced gRPC server stop",
+			zap.Duration("timeout", cfg.Server.DrainTimeout))
+	}
+	webhookRetrier.Stop()
+	reconciler.Stop()
 	zapLogger.Info("Servers stopped")
 }
 
 func runMigrations(database *gorm.DB) error {
-	return database.AutoMigrate(
+	if err := database.AutoMigrate(
 		&db.Plan{},
 		&db.Subscription{},
 		&db.WebhookEvent{},
-	)
+		&db.TeamTrial{},
+	); err != nil {
+		return err
+	}
+
+	// AutoMigrate can't express a partial expression index; see
+	// migrations/006_unique_active_plan_name.sql
+	return database.Exec(
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_plans_active_name ON plans (LOWER(name)) WHERE is_active = true",
+	).Error
 }
 
 func loggingInterceptor(logger *zap.Logger) grpc.UnaryServerInterceptor {
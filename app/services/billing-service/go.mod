@@ -4,10 +4,12 @@ go 1.21
 
 require (
 	github.com/google/uuid v1.5.0
+	github.com/haunted-saas/notifications-service v0.0.0
+	github.com/haunted-saas/pkg v0.0.0
 	github.com/redis/go-redis/v9 v9.3.0
 	github.com/spf13/viper v1.18.2
-	github.com/stripe/stripe-go/v76 v76.16.0
 	github.com/stretchr/testify v1.8.4
+	github.com/stripe/stripe-go/v76 v76.16.0
 	go.uber.org/zap v1.26.0
 	google.golang.org/grpc v1.60.1
 	google.golang.org/protobuf v1.32.0
@@ -41,11 +43,17 @@ require (
 	github.com/stretchr/objx v0.5.0 // indirect
 	github.com/subosito/gotenv v1.6.0 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
-	golang.org/x/net v0.19.0 // indirect
+	golang.org/x/crypto v0.18.0 // indirect
+	golang.org/x/net v0.20.0 // indirect
 	golang.org/x/sync v0.5.0 // indirect
-	golang.org/x/sys v0.15.0 // indirect
+	golang.org/x/sys v0.16.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20231212172506-995d672761c0 // indirect
 	gopkg.in/ini.v1 v1.67.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
+
+replace (
+	github.com/haunted-saas/notifications-service => ../notifications-service
+	github.com/haunted-saas/pkg => ../../pkg
+)
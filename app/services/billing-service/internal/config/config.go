@@ -4,22 +4,31 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/spf13/viper"
 )
 
 // Config holds all configuration for the billing service
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Stripe   StripeConfig
+	Server        ServerConfig
+	Database      DatabaseConfig
+	Stripe        StripeConfig
+	Reconcile     ReconcileConfig
+	WebhookRetry  WebhookRetryConfig
+	Notifications NotificationsConfig
+	Trials        TrialsConfig
+	AdminAPIToken string // Required by admin-only request fields (empty disables them)
 }
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
-	GRPCPort int
-	HTTPPort int
-	Host     string
+	GRPCPort        int
+	HTTPPort        int
+	Host            string
+	DefaultDeadline time.Duration // Applied to requests that arrive without a deadline (0 disables)
+	DrainTimeout    time.Duration // Bounds the wait for in-flight RPCs on shutdown (0 waits indefinitely)
 }
 
 // DatabaseConfig holds database configuration
@@ -30,9 +39,33 @@ type DatabaseConfig struct {
 
 // StripeConfig holds Stripe configuration
 type StripeConfig struct {
-	APIKey        string
-	WebhookSecret string
-	APIVersion    string
+	APIKey         string
+	WebhookSecrets []string
+	APIVersion     string
+}
+
+// ReconcileConfig holds subscription reconciliation configuration
+type ReconcileConfig struct {
+	IntervalMinutes int // 0 disables the background job
+}
+
+// WebhookRetryConfig holds configuration for re-processing failed webhook events
+type WebhookRetryConfig struct {
+	IntervalSeconds int // 0 disables the background job
+	MaxRetries      int
+	BackoffSeconds  int // Delay after the first failed retry, doubled after each one
+}
+
+// NotificationsConfig holds the optional notifications-service connection
+type NotificationsConfig struct {
+	Address    string // Empty disables real-time billing events
+	TimeoutSec int
+}
+
+// TrialsConfig holds trial configuration
+type TrialsConfig struct {
+	AllowWithoutCard bool // Enables StartTrial, which skips Checkout and card collection
+	OverrideMaxDays  int  // Longest trial an admin can grant with trial_days_override
 }
 
 // Load loads configuration from environment variables
@@ -41,19 +74,38 @@ func Load() (*Config, error) {
 
 	config := &Config{
 		Server: ServerConfig{
-			GRPCPort: getEnvAsInt("GRPC_PORT", 50052),
-			HTTPPort: getEnvAsInt("HTTP_PORT", 8080),
-			Host:     getEnv("HOST", "0.0.0.0"),
+			GRPCPort:        getEnvAsInt("GRPC_PORT", 50052),
+			HTTPPort:        getEnvAsInt("HTTP_PORT", 8080),
+			Host:            getEnv("HOST", "0.0.0.0"),
+			DefaultDeadline: time.Duration(getEnvAsInt("DEFAULT_REQUEST_DEADLINE_SECONDS", 30)) * time.Second,
+			DrainTimeout:    time.Duration(getEnvAsInt("GRPC_DRAIN_TIMEOUT_SECONDS", 15)) * time.Second,
 		},
 		Database: DatabaseConfig{
 			URL:            getEnv("DATABASE_URL", ""),
 			MaxConnections: getEnvAsInt("DB_MAX_CONNECTIONS", 25),
 		},
 		Stripe: StripeConfig{
-			APIKey:        getEnv("STRIPE_API_KEY", ""),
-			WebhookSecret: getEnv("STRIPE_WEBHOOK_SECRET", ""),
-			APIVersion:    getEnv("STRIPE_API_VERSION", "2023-10-16"),
+			APIKey:         getEnv("STRIPE_API_KEY", ""),
+			WebhookSecrets: getWebhookSecrets(),
+			APIVersion:     getEnv("STRIPE_API_VERSION", "2023-10-16"),
+		},
+		Reconcile: ReconcileConfig{
+			IntervalMinutes: getEnvAsInt("RECONCILE_INTERVAL_MINUTES", 60),
+		},
+		WebhookRetry: WebhookRetryConfig{
+			IntervalSeconds: getEnvAsInt("WEBHOOK_RETRY_INTERVAL_SECONDS", 60),
+			MaxRetries:      getEnvAsInt("WEBHOOK_RETRY_MAX_ATTEMPTS", 5),
+			BackoffSeconds:  getEnvAsInt("WEBHOOK_RETRY_BACKOFF_SECONDS", 60),
+		},
+		Notifications: NotificationsConfig{
+			Address:    getEnv("NOTIFICATIONS_SERVICE", ""),
+			TimeoutSec: getEnvAsInt("NOTIFICATIONS_TIMEOUT_SECONDS", 2),
 		},
+		Trials: TrialsConfig{
+			AllowWithoutCard: getEnvAsBool("ALLOW_TRIAL_WITHOUT_CARD", false),
+			OverrideMaxDays:  getEnvAsInt("TRIAL_DAYS_OVERRIDE_MAX", 90),
+		},
+		AdminAPIToken: getEnv("ADMIN_API_TOKEN", ""),
 	}
 
 	// Validate required configuration
@@ -65,13 +117,64 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("STRIPE_API_KEY is required")
 	}
 
-	if config.Stripe.WebhookSecret == "" {
-		return nil, fmt.Errorf("STRIPE_WEBHOOK_SECRET is required")
+	if len(config.Stripe.WebhookSecrets) == 0 {
+		return nil, fmt.Errorf("STRIPE_WEBHOOK_SECRET or STRIPE_WEBHOOK_SECRETS is required")
+	}
+
+	if config.Reconcile.IntervalMinutes < 0 {
+		return nil, fmt.Errorf("RECONCILE_INTERVAL_MINUTES cannot be negative")
+	}
+
+	if config.WebhookRetry.IntervalSeconds < 0 {
+		return nil, fmt.Errorf("WEBHOOK_RETRY_INTERVAL_SECONDS cannot be negative")
+	}
+
+	if config.WebhookRetry.MaxRetries < 0 {
+		return nil, fmt.Errorf("WEBHOOK_RETRY_MAX_ATTEMPTS cannot be negative")
+	}
+
+	if config.WebhookRetry.BackoffSeconds < 0 {
+		return nil, fmt.Errorf("WEBHOOK_RETRY_BACKOFF_SECONDS cannot be negative")
+	}
+
+	if config.Notifications.Address != "" && config.Notifications.TimeoutSec < 1 {
+		return nil, fmt.Errorf("NOTIFICATIONS_TIMEOUT_SECONDS must be at least 1")
+	}
+
+	if config.Trials.OverrideMaxDays < 0 || config.Trials.OverrideMaxDays > 730 {
+		return nil, fmt.Errorf("TRIAL_DAYS_OVERRIDE_MAX must be between 0 and 730")
+	}
+
+	if config.Server.DefaultDeadline < 0 {
+		return nil, fmt.Errorf("DEFAULT_REQUEST_DEADLINE_SECONDS cannot be negative")
+	}
+
+	if config.Server.DrainTimeout < 0 {
+		return nil, fmt.Errorf("GRPC_DRAIN_TIMEOUT_SECONDS cannot be negative")
 	}
 
 	return config, nil
 }
 
+// getWebhookSecrets combines STRIPE_WEBHOOK_SECRET with the comma-separated
+// STRIPE_WEBHOOK_SECRETS list (one secret per regional endpoint)
+func getWebhookSecrets() []string {
+	var secrets []string
+	seen := make(map[string]bool)
+
+	candidates := append([]string{getEnv("STRIPE_WEBHOOK_SECRET", "")}, strings.Split(getEnv("STRIPE_WEBHOOK_SECRETS", ""), ",")...)
+	for _, secret := range candidates {
+		secret = strings.TrimSpace(secret)
+		if secret == "" || seen[secret] {
+			continue
+		}
+		seen[secret] = true
+		secrets = append(secrets, secret)
+	}
+
+	return secrets
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -90,3 +193,15 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return value
 }
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
@@ -0,0 +1,34 @@
+package internal
+
+import "strings"
+
+// maxTrialDays is the longest trial period Stripe accepts on a subscription
+const maxTrialDays = 730
+
+// supportedCurrencies lists the ISO-4217 codes accepted for plan prices
+var supportedCurrencies = map[string]bool{
+	"aed": true, "afn": true, "all": true, "amd": true, "ang": true, "aoa": true, "ars": true, "aud": true,
+	"awg": true, "azn": true, "bam": true, "bbd": true, "bdt": true, "bgn": true, "bhd": true, "bif": true,
+	"bmd": true, "bnd": true, "bob": true, "brl": true, "bsd": true, "bwp": true, "byn": true, "bzd": true,
+	"cad": true, "cdf": true, "chf": true, "clp": true, "cny": true, "cop": true, "crc": true, "cve": true,
+	"czk": true, "djf": true, "dkk": true, "dop": true, "dzd": true, "egp": true, "etb": true, "eur": true,
+	"fjd": true, "fkp": true, "gbp": true, "gel": true, "gip": true, "gmd": true, "gnf": true, "gtq": true,
+	"gyd": true, "hkd": true, "hnl": true, "htg": true, "huf": true, "idr": true, "ils": true, "inr": true,
+	"isk": true, "jmd": true, "jod": true, "jpy": true, "kes": true, "kgs": true, "khr": true, "kmf": true,
+	"krw": true, "kwd": true, "kyd": true, "kzt": true, "lak": true, "lbp": true, "lkr": true, "lrd": true,
+	"lsl": true, "mad": true, "mdl": true, "mga": true, "mkd": true, "mmk": true, "mnt": true, "mop": true,
+	"mur": true, "mvr": true, "mwk": true, "mxn": true, "myr": true, "mzn": true, "nad": true, "ngn": true,
+	"nio": true, "nok": true, "npr": true, "nzd": true, "omr": true, "pab": true, "pen": true, "pgk": true,
+	"php": true, "pkr": true, "pln": true, "pyg": true, "qar": true, "ron": true, "rsd": true, "rub": true,
+	"rwf": true, "sar": true, "sbd": true, "scr": true, "sek": true, "sgd": true, "shp": true, "sle": true,
+	"sos": true, "srd": true, "std": true, "szl": true, "thb": true, "tjs": true, "tnd": true, "top": true,
+	"try": true, "ttd": true, "twd": true, "tzs": true, "uah": true, "ugx": true, "usd": true, "uyu": true,
+	"uzs": true, "vnd": true, "vuv": true, "wst": true, "xaf": true, "xcd": true, "xof": true, "xpf": true,
+	"yer": true, "zar": true, "zmw": true,
+}
+
+// normalizeCurrency lowercases a currency code and reports whether it is supported
+func normalizeCurrency(currency string) (string, bool) {
+	code := strings.ToLower(strings.TrimSpace(currency))
+	return code, supportedCurrencies[code]
+}
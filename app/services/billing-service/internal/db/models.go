@@ -39,6 +39,8 @@ type Subscription struct {
 	CancelAt             *time.Time `json:"cancel_at,omitempty"`
 	CanceledAt           *time.Time `json:"canceled_at,omitempty"`
 	TrialEnd             *time.Time `json:"trial_end,omitempty"`
+	DisputeID            *string    `json:"dispute_id,omitempty"`
+	DisputedAt           *time.Time `json:"disputed_at,omitempty"`
 	CreatedAt            time.Time  `gorm:"not null;default:now()" json:"created_at"`
 	UpdatedAt            time.Time  `gorm:"not null;default:now()" json:"updated_at"`
 	
@@ -55,11 +57,30 @@ func (s *Subscription) IsActive() bool {
 	return s.Status == "active" || s.Status == "trialing"
 }
 
+// IsDisputed checks if a payment on the subscription has been disputed
+func (s *Subscription) IsDisputed() bool {
+	return s.DisputedAt != nil
+}
+
 // IsCanceled checks if the subscription is canceled
 func (s *Subscription) IsCanceled() bool {
 	return s.Status == "canceled"
 }
 
+// TeamTrial records that a team has used its free trial. It outlives the
+// subscription row so a team can't trial again after canceling.
+type TeamTrial struct {
+	TeamID               string    `gorm:"type:uuid;primaryKey" json:"team_id"`
+	PlanID               string    `gorm:"type:uuid;not null" json:"plan_id"`
+	StripeSubscriptionID *string   `json:"stripe_subscription_id,omitempty"`
+	StartedAt            time.Time `gorm:"not null;default:now()" json:"started_at"`
+}
+
+// TableName specifies the table name for GORM
+func (TeamTrial) TableName() string {
+	return "team_trials"
+}
+
 // WebhookEvent represents a processed webhook event for idempotency
 type WebhookEvent struct {
 	ID              string    `gorm:"type:uuid;primaryKey;default:gen_random_uuid()" json:"id"`
@@ -69,6 +90,9 @@ type WebhookEvent struct {
 	ProcessingError *string   `json:"processing_error,omitempty"`
 	ReceivedAt      time.Time `gorm:"not null;default:now()" json:"received_at"`
 	ProcessedAt     *time.Time `json:"processed_at,omitempty"`
+	Payload         []byte     `gorm:"type:jsonb" json:"-"` // Verified request body, kept for retries
+	RetryCount      int        `gorm:"not null;default:0" json:"retry_count"`
+	NextRetryAt     *time.Time `json:"next_retry_at,omitempty"`
 }
 
 // TableName specifies the table name for GORM
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/haunted-saas/pkg/pagination"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // Store handles all database operations
@@ -45,6 +47,19 @@ func (s *Store) GetPlanByStripePriceID(ctx context.Context, stripePriceID string
 	return &plan, nil
 }
 
+// GetActivePlanByName retrieves the active plan with the given name,
+// compared case-insensitively. Archived plans are ignored.
+func (s *Store) GetActivePlanByName(ctx context.Context, name string) (*Plan, error) {
+	var plan Plan
+	err := s.db.WithContext(ctx).
+		Where("is_active = ? AND LOWER(name) = LOWER(?)", true, name).
+		First(&plan).Error
+	if err != nil {
+		return nil, err
+	}
+	return &plan, nil
+}
+
 // ListPlans retrieves all plans, optionally filtering by active status
 func (s *Store) ListPlans(ctx context.Context, activeOnly bool) ([]Plan, error) {
 	var plans []Plan
@@ -58,6 +73,18 @@ func (s *Store) ListPlans(ctx context.Context, activeOnly bool) ([]Plan, error)
 	return plans, err
 }
 
+// GetPlansByIDs retrieves the plans with the given IDs in a single query.
+// IDs that don't exist are skipped, so fewer plans may be returned.
+func (s *Store) GetPlansByIDs(ctx context.Context, planIDs []string) ([]Plan, error) {
+	if len(planIDs) == 0 {
+		return nil, nil
+	}
+
+	var plans []Plan
+	err := s.db.WithContext(ctx).Where("id IN ?", planIDs).Find(&plans).Error
+	return plans, err
+}
+
 // UpdatePlan updates a plan
 func (s *Store) UpdatePlan(ctx context.Context, plan *Plan) error {
 	return s.db.WithContext(ctx).Save(plan).Error
@@ -105,6 +132,20 @@ func (s *Store) GetSubscriptionByStripeID(ctx context.Context, stripeSubID strin
 	return &subscription, nil
 }
 
+// GetSubscriptionByStripeCustomerID retrieves a subscription by Stripe customer ID
+func (s *Store) GetSubscriptionByStripeCustomerID(ctx context.Context, stripeCustomerID string) (*Subscription, error) {
+	var subscription Subscription
+	err := s.db.WithContext(ctx).
+		Preload("Plan").
+		Where("stripe_customer_id = ?", stripeCustomerID).
+		First(&subscription).Error
+	
+	if err != nil {
+		return nil, err
+	}
+	return &subscription, nil
+}
+
 // UpdateSubscription updates a subscription
 func (s *Store) UpdateSubscription(ctx context.Context, subscription *Subscription) error {
 	return s.db.WithContext(ctx).Save(subscription).Error
@@ -117,11 +158,61 @@ func (s *Store) UpdateSubscriptionStatus(ctx context.Context, subscriptionID, st
 		Update("status", status).Error
 }
 
+// UpdateSubscriptionPeriod updates only the status and billing period of a
+// subscription, leaving columns other writers may be changing alone
+func (s *Store) UpdateSubscriptionPeriod(ctx context.Context, subscriptionID, status string, periodStart, periodEnd time.Time) error {
+	return s.db.WithContext(ctx).Model(&Subscription{}).
+		Where("id = ?", subscriptionID).
+		Updates(map[string]interface{}{
+			"status":               status,
+			"current_period_start": periodStart,
+			"current_period_end":   periodEnd,
+		}).Error
+}
+
 // DeleteSubscription deletes a subscription (soft delete)
 func (s *Store) DeleteSubscription(ctx context.Context, subscriptionID string) error {
 	return s.db.WithContext(ctx).Delete(&Subscription{}, "id = ?", subscriptionID).Error
 }
 
+// ListSubscriptionsToReconcile retrieves subscriptions that can still change in
+// Stripe, i.e. everything not in a terminal state
+func (s *Store) ListSubscriptionsToReconcile(ctx context.Context) ([]Subscription, error) {
+	var subscriptions []Subscription
+	err := s.db.WithContext(ctx).
+		Where("status NOT IN ?", []string{"canceled", "incomplete_expired"}).
+		Order("updated_at ASC").
+		Find(&subscriptions).Error
+	return subscriptions, err
+}
+
+// Trial Operations
+
+// CreateTeamTrial claims the team's trial. It fails if the team already has one.
+func (s *Store) CreateTeamTrial(ctx context.Context, trial *TeamTrial) error {
+	return s.db.WithContext(ctx).Create(trial).Error
+}
+
+// GetTeamTrial retrieves the trial a team has used
+func (s *Store) GetTeamTrial(ctx context.Context, teamID string) (*TeamTrial, error) {
+	var trial TeamTrial
+	err := s.db.WithContext(ctx).Where("team_id = ?", teamID).First(&trial).Error
+	if err != nil {
+		return nil, err
+	}
+	return &trial, nil
+}
+
+// UpdateTeamTrial updates a team trial record
+func (s *Store) UpdateTeamTrial(ctx context.Context, trial *TeamTrial) error {
+	return s.db.WithContext(ctx).Save(trial).Error
+}
+
+// DeleteTeamTrial releases a trial claim whose Stripe subscription was never created
+func (s *Store) DeleteTeamTrial(ctx context.Context, teamID string) error {
+	return s.db.WithContext(ctx).Where("team_id = ?", teamID).Delete(&TeamTrial{}).Error
+}
+
 // Webhook Event Operations (for idempotency)
 
 // CreateWebhookEvent creates a webhook event record
@@ -170,6 +261,118 @@ func (s *Store) IsWebhookEventProcessed(ctx context.Context, stripeEventID strin
 	return count > 0, nil
 }
 
+// ClaimWebhookEventsToRetry claims failed webhook events that still have
+// attempts left and are due for a retry, oldest first. The rows are locked
+// with SKIP LOCKED and their next_retry_at pushed to claimUntil before the
+// transaction commits, so another replica's retrier skips them while this
+// one works through them. RecordWebhookEventRetry replaces the claim with
+// the real schedule, and a claim left by a crashed replica expires.
+func (s *Store) ClaimWebhookEventsToRetry(ctx context.Context, maxRetries int, now, claimUntil time.Time, limit int) ([]WebhookEvent, error) {
+	var events []WebhookEvent
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := webhookEventsToRetryQuery(tx, maxRetries, now, limit).Find(&events).Error; err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+
+		ids := make([]string, len(events))
+		for i := range events {
+			ids[i] = events[i].ID
+		}
+		return tx.Model(&WebhookEvent{}).
+			Where("id IN ?", ids).
+			Update("next_retry_at", claimUntil).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// webhookEventsToRetryQuery selects and locks the events due for a retry,
+// skipping rows another transaction has locked
+func webhookEventsToRetryQuery(tx *gorm.DB, maxRetries int, now time.Time, limit int) *gorm.DB {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("processing_error IS NOT NULL AND processing_error <> ''").
+		Where("payload IS NOT NULL").
+		Where("retry_count < ?", maxRetries).
+		Where("(next_retry_at IS NULL OR next_retry_at <= ?)", now).
+		Order("received_at ASC").
+		Limit(limit)
+}
+
+// RecordWebhookEventRetry records the outcome of a retry. A nil
+// processingError clears the stored error, marking the event successful.
+func (s *Store) RecordWebhookEventRetry(ctx context.Context, stripeEventID string, processingError *string, nextRetryAt *time.Time) error {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"retry_count":      gorm.Expr("retry_count + 1"),
+		"processing_error": processingError,
+		"next_retry_at":    nextRetryAt,
+		"processed_at":     &now,
+	}
+	
+	return s.db.WithContext(ctx).Model(&WebhookEvent{}).
+		Where("stripe_event_id = ?", stripeEventID).
+		Updates(updates).Error
+}
+
+// WebhookEventFilter narrows ListWebhookEvents. Zero values are ignored.
+type WebhookEventFilter struct {
+	EventType      string
+	Processed      *bool
+	HasError       *bool
+	ReceivedAfter  time.Time
+	ReceivedBefore time.Time
+	Page           pagination.Page
+}
+
+// ListWebhookEvents retrieves webhook events matching the filter, newest first,
+// along with the total number of matching events before pagination
+func (s *Store) ListWebhookEvents(ctx context.Context, filter WebhookEventFilter) ([]WebhookEvent, int64, error) {
+	var total int64
+	err := applyWebhookEventFilter(s.db.WithContext(ctx).Model(&WebhookEvent{}), filter).
+		Count(&total).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	
+	query := filter.Page.Apply(applyWebhookEventFilter(s.db.WithContext(ctx), filter).Order("received_at DESC"))
+	
+	var events []WebhookEvent
+	err = query.Find(&events).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	
+	return events, total, nil
+}
+
+func applyWebhookEventFilter(query *gorm.DB, filter WebhookEventFilter) *gorm.DB {
+	if filter.EventType != "" {
+		query = query.Where("event_type = ?", filter.EventType)
+	}
+	if filter.Processed != nil {
+		query = query.Where("processed = ?", *filter.Processed)
+	}
+	if filter.HasError != nil {
+		if *filter.HasError {
+			query = query.Where("processing_error IS NOT NULL AND processing_error <> ''")
+		} else {
+			query = query.Where("(processing_error IS NULL OR processing_error = '')")
+		}
+	}
+	if !filter.ReceivedAfter.IsZero() {
+		query = query.Where("received_at >= ?", filter.ReceivedAfter)
+	}
+	if !filter.ReceivedBefore.IsZero() {
+		query = query.Where("received_at < ?", filter.ReceivedBefore)
+	}
+	return query
+}
+
 // Transaction support
 
 // WithTransaction executes a function within a database transaction
@@ -0,0 +1,185 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/haunted-saas/pkg/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// newDryRunDB builds SQL without connecting to a database
+func newDryRunDB(t *testing.T) *gorm.DB {
+	gdb, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost dbname=billing_test"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+	})
+	require.NoError(t, err)
+	return gdb
+}
+
+func TestApplyWebhookEventFilter(t *testing.T) {
+	yes, no := true, false
+	after := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	before := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		filter    WebhookEventFilter
+		wantWhere []string
+		wantVars  []interface{}
+	}{
+		{
+			name:   "no filters",
+			filter: WebhookEventFilter{},
+		},
+		{
+			name:      "event type",
+			filter:    WebhookEventFilter{EventType: "checkout.session.completed"},
+			wantWhere: []string{"event_type = $1"},
+			wantVars:  []interface{}{"checkout.session.completed"},
+		},
+		{
+			name:      "unprocessed",
+			filter:    WebhookEventFilter{Processed: &no},
+			wantWhere: []string{"processed = $1"},
+			wantVars:  []interface{}{false},
+		},
+		{
+			name:      "has error",
+			filter:    WebhookEventFilter{HasError: &yes},
+			wantWhere: []string{"processing_error IS NOT NULL AND processing_error <> ''"},
+		},
+		{
+			name:      "no error",
+			filter:    WebhookEventFilter{HasError: &no},
+			wantWhere: []string{"(processing_error IS NULL OR processing_error = '')"},
+		},
+		{
+			name:      "time range",
+			filter:    WebhookEventFilter{ReceivedAfter: after, ReceivedBefore: before},
+			wantWhere: []string{"received_at >= $1", "received_at < $2"},
+			wantVars:  []interface{}{after, before},
+		},
+		{
+			name: "combined",
+			filter: WebhookEventFilter{
+				EventType: "invoice.paid",
+				Processed: &yes,
+				HasError:  &yes,
+			},
+			wantWhere: []string{"event_type = $1", "processed = $2", "processing_error IS NOT NULL"},
+			wantVars:  []interface{}{"invoice.paid", true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []WebhookEvent
+			stmt := applyWebhookEventFilter(newDryRunDB(t), tt.filter).Find(&events).Statement
+			sql := stmt.SQL.String()
+
+			if len(tt.wantWhere) == 0 {
+				assert.NotContains(t, sql, "WHERE")
+			}
+			for _, where := range tt.wantWhere {
+				assert.Contains(t, sql, where)
+			}
+			if tt.wantVars == nil {
+				assert.Empty(t, stmt.Vars)
+			} else {
+				assert.Equal(t, tt.wantVars, stmt.Vars)
+			}
+		})
+	}
+}
+
+func TestListWebhookEvents_OrdersAndPaginates(t *testing.T) {
+	gdb := newDryRunDB(t)
+
+	var captured []string
+	err := gdb.Callback().Query().After("gorm:query").Register("test:capture", func(tx *gorm.DB) {
+		captured = append(captured, tx.Statement.SQL.String())
+	})
+	require.NoError(t, err)
+
+	store := NewStore(gdb)
+	_, _, err = store.ListWebhookEvents(context.Background(), WebhookEventFilter{
+		EventType: "customer.subscription.updated",
+		Page:      pagination.Page{Limit: 25, Offset: 50},
+	})
+	require.NoError(t, err)
+
+	require.Len(t, captured, 2)
+	assert.Contains(t, captured[0], "count(*)")
+	assert.NotContains(t, captured[0], "LIMIT")
+	assert.Contains(t, captured[1], "ORDER BY received_at DESC")
+	assert.Contains(t, captured[1], "LIMIT 25 OFFSET 50")
+}
+
+func TestGetPlansByIDs_SingleInQuery(t *testing.T) {
+	gdb := newDryRunDB(t)
+
+	var captured []*gorm.Statement
+	err := gdb.Callback().Query().After("gorm:query").Register("test:capture", func(tx *gorm.DB) {
+		captured = append(captured, tx.Statement)
+	})
+	require.NoError(t, err)
+
+	store := NewStore(gdb)
+	_, err = store.GetPlansByIDs(context.Background(), []string{"plan-1", "plan-2", "plan-3"})
+	require.NoError(t, err)
+
+	require.Len(t, captured, 1)
+	assert.Contains(t, captured[0].SQL.String(), `FROM "plans" WHERE id IN ($1,$2,$3)`)
+	assert.Equal(t, []interface{}{"plan-1", "plan-2", "plan-3"}, captured[0].Vars)
+
+	// No IDs means no query
+	plans, err := store.GetPlansByIDs(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Empty(t, plans)
+	assert.Len(t, captured, 1)
+}
+
+func TestUpdateSubscriptionPeriod_OnlyWritesReconciledColumns(t *testing.T) {
+	gdb := newDryRunDB(t)
+
+	var captured []string
+	err := gdb.Callback().Update().After("gorm:update").Register("test:capture", func(tx *gorm.DB) {
+		captured = append(captured, tx.Statement.SQL.String())
+	})
+	require.NoError(t, err)
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	// Updates otherwise open a transaction, which needs a connection
+	store := NewStore(gdb.Session(&gorm.Session{SkipDefaultTransaction: true}))
+	require.NoError(t, store.UpdateSubscriptionPeriod(context.Background(), "sub-1", "past_due", start, end))
+
+	require.Len(t, captured, 1)
+	sql := captured[0]
+	assert.Contains(t, sql, `UPDATE "subscriptions" SET`)
+	assert.Contains(t, sql, `"status"=`)
+	assert.Contains(t, sql, `"current_period_start"=`)
+	assert.Contains(t, sql, `"current_period_end"=`)
+	assert.NotContains(t, sql, `"plan_id"`)
+	assert.NotContains(t, sql, `"cancel_at"`)
+	assert.Contains(t, sql, "WHERE id = ")
+}
+
+func TestWebhookEventsToRetryQuery_SkipsLockedRows(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var events []WebhookEvent
+	stmt := webhookEventsToRetryQuery(newDryRunDB(t), 3, now, 100).Find(&events).Statement
+	sql := stmt.SQL.String()
+
+	assert.Contains(t, sql, "retry_count < $1")
+	assert.Contains(t, sql, "next_retry_at <= $2")
+	assert.Contains(t, sql, "ORDER BY received_at ASC LIMIT 100 FOR UPDATE SKIP LOCKED")
+	assert.Equal(t, []interface{}{3, now}, stmt.Vars)
+}
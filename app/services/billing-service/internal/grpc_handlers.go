@@ -2,9 +2,14 @@ package internal
 
 import (
 	"context"
+	"errors"
+	"strconv"
 	"time"
 
 	"github.com/haunted-saas/billing-service/internal/db"
+	"github.com/haunted-saas/pkg/admintoken"
+	"github.com/haunted-saas/pkg/caller"
+	"github.com/haunted-saas/pkg/pagination"
 	pb "github.com/haunted-saas/billing-service/proto/billing/v1"
 	"github.com/stripe/stripe-go/v76"
 	"go.uber.org/zap"
@@ -14,23 +19,111 @@ import (
 	"gorm.io/gorm"
 )
 
+// Page size bounds for ListWebhookEvents
+var webhookEventsLimits = pagination.Limits{Default: 50, Max: 500}
+
+// maxPlansByIDs bounds how many plans GetPlansByIDs returns per call
+const maxPlansByIDs = 100
+
+// maxCancelAtHorizon bounds how far ahead a cancellation can be scheduled
+const maxCancelAtHorizon = 2 * 365 * 24 * time.Hour
+
+// Stripe proration behaviors accepted when changing plans
+const (
+	ProrationCreateProrations = "create_prorations" // Credit/charge the difference on the next invoice
+	ProrationNone             = "none"              // Switch plans without prorating
+	ProrationAlwaysInvoice    = "always_invoice"    // Invoice the prorated difference immediately
+)
+
+// defaultTrialOverrideMaxDays bounds trial_days_override unless configured
+const defaultTrialOverrideMaxDays = 90
+
+// billingStripeClient is the subset of StripeClient the billing service needs
+type billingStripeClient interface {
+	reconcileStripeClient
+	CreateProduct(ctx context.Context, name string, metadata map[string]string) (*stripe.Product, error)
+	UpdateProduct(productID, name string, metadata map[string]string) (*stripe.Product, error)
+	CreatePrice(ctx context.Context, productID string, amountCents int64, currency, interval string) (*stripe.Price, error)
+	CreateCustomer(ctx context.Context, email, teamID string, metadata map[string]string) (*stripe.Customer, error)
+	CreateCheckoutSession(ctx context.Context, priceID, customerID, successURL, cancelURL string, metadata map[string]string, trialDays int32) (*stripe.CheckoutSession, error)
+	GetCheckoutSession(ctx context.Context, sessionID string) (*stripe.CheckoutSession, error)
+	CreateTrialSubscription(ctx context.Context, customerID, priceID string, trialDays int32, metadata map[string]string) (*stripe.Subscription, error)
+	CancelSubscription(subscriptionID string, cancelAtPeriodEnd bool) (*stripe.Subscription, error)
+	ScheduleCancellation(ctx context.Context, subscriptionID string, cancelAt time.Time) (*stripe.Subscription, error)
+	UpdateSubscription(subscriptionID, newPriceID string, prorationBehavior string) (*stripe.Subscription, error)
+	CreateCustomerPortalSession(customerID, returnURL string) (*stripe.BillingPortalSession, error)
+	GetUpcomingInvoice(customerID string) (*stripe.Invoice, error)
+	ListInvoices(customerID string, limit int64, createdAfter, createdBefore time.Time) ([]*stripe.Invoice, error)
+}
+
+// billingStore is the subset of db.Store the billing service needs
+type billingStore interface {
+	reconcileStore
+	CreatePlan(ctx context.Context, plan *db.Plan) error
+	GetPlanByID(ctx context.Context, planID string) (*db.Plan, error)
+	GetActivePlanByName(ctx context.Context, name string) (*db.Plan, error)
+	ListPlans(ctx context.Context, activeOnly bool) ([]db.Plan, error)
+	GetPlansByIDs(ctx context.Context, planIDs []string) ([]db.Plan, error)
+	UpdatePlan(ctx context.Context, plan *db.Plan) error
+	DeactivatePlan(ctx context.Context, planID string) error
+	CreateSubscription(ctx context.Context, subscription *db.Subscription) error
+	GetSubscriptionByStripeID(ctx context.Context, stripeSubID string) (*db.Subscription, error)
+	UpdateSubscription(ctx context.Context, subscription *db.Subscription) error
+	GetTeamTrial(ctx context.Context, teamID string) (*db.TeamTrial, error)
+	CreateTeamTrial(ctx context.Context, trial *db.TeamTrial) error
+	UpdateTeamTrial(ctx context.Context, trial *db.TeamTrial) error
+	DeleteTeamTrial(ctx context.Context, teamID string) error
+	ListWebhookEvents(ctx context.Context, filter db.WebhookEventFilter) ([]db.WebhookEvent, int64, error)
+}
+
 // BillingServiceServer implements the gRPC billing service
 type BillingServiceServer struct {
 	pb.UnimplementedBillingServiceServer
-	stripeClient *StripeClient
-	store        *db.Store
+	stripeClient billingStripeClient
+	store        billingStore
+	reconciler   *SubscriptionReconciler
 	logger       *zap.Logger
+	
+	trialsWithoutCard bool
+	adminToken        string
+	trialOverrideMax  int32
 }
 
 // NewBillingServiceServer creates a new billing service server
-func NewBillingServiceServer(stripeClient *StripeClient, store *db.Store, logger *zap.Logger) *BillingServiceServer {
+func NewBillingServiceServer(stripeClient billingStripeClient, store billingStore, logger *zap.Logger) *BillingServiceServer {
 	return &BillingServiceServer{
 		stripeClient: stripeClient,
 		store:        store,
+		reconciler:   NewSubscriptionReconciler(stripeClient, store, 0, logger),
 		logger:       logger,
+
+		trialOverrideMax: defaultTrialOverrideMaxDays,
 	}
 }
 
+// SetReconciler shares the background reconciler with the ReconcileSubscription RPC
+func (s *BillingServiceServer) SetReconciler(reconciler *SubscriptionReconciler) {
+	s.reconciler = reconciler
+}
+
+// SetTrialsWithoutCard enables the StartTrial RPC, which starts trials
+// without collecting a card
+func (s *BillingServiceServer) SetTrialsWithoutCard(enabled bool) {
+	s.trialsWithoutCard = enabled
+}
+
+// SetAdminToken sets the token admin-only request fields must present
+// (empty disables them)
+func (s *BillingServiceServer) SetAdminToken(token string) {
+	s.adminToken = token
+}
+
+// SetTrialOverrideMax sets the longest trial an admin can grant through
+// CreateCheckoutSession's trial_days_override
+func (s *BillingServiceServer) SetTrialOverrideMax(days int32) {
+	s.trialOverrideMax = days
+}
+
 // Plan Management
 
 // CreatePlan creates a new subscription plan
@@ -50,14 +143,26 @@ func (s *BillingServiceServer) CreatePlan(ctx context.Context, req *pb.CreatePla
 	if req.BillingInterval != "month" && req.BillingInterval != "year" {
 		return nil, status.Error(codes.InvalidArgument, "billing interval must be 'month' or 'year'")
 	}
+	if req.TrialDays < 0 || req.TrialDays > maxTrialDays {
+		return nil, status.Errorf(codes.InvalidArgument, "trial days must be between 0 and %d", maxTrialDays)
+	}
+	
+	currency := "usd"
+	if req.Currency != "" {
+		code, ok := normalizeCurrency(req.Currency)
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "unsupported currency: %s", req.Currency)
+		}
+		currency = code
+	}
 	
-	currency := req.Currency
-	if currency == "" {
-		currency = "usd"
+	// Check the name before creating anything in Stripe
+	if err := s.checkPlanNameAvailable(ctx, req.Name, ""); err != nil {
+		return nil, err
 	}
 	
 	// Create Stripe product
-	stripeProduct, err := s.stripeClient.CreateProduct(req.Name, map[string]string{
+	stripeProduct, err := s.stripeClient.CreateProduct(ctx, req.Name, map[string]string{
 		"created_by": req.CreatedByUserId,
 	})
 	if err != nil {
@@ -66,7 +171,7 @@ func (s *BillingServiceServer) CreatePlan(ctx context.Context, req *pb.CreatePla
 	}
 	
 	// Create Stripe price
-	stripePrice, err := s.stripeClient.CreatePrice(
+	stripePrice, err := s.stripeClient.CreatePrice(ctx, 
 		stripeProduct.ID,
 		req.PriceCents,
 		currency,
@@ -95,6 +200,14 @@ func (s *BillingServiceServer) CreatePlan(ctx context.Context, req *pb.CreatePla
 	}
 	
 	if err := s.store.CreatePlan(ctx, plan); err != nil {
+		// A concurrent create may have taken the name; the unique index on
+		// active plan names rejects the second insert
+		if nameErr := s.checkPlanNameAvailable(ctx, req.Name, ""); status.Code(nameErr) == codes.AlreadyExists {
+			s.logger.Warn("plan name taken during creation; Stripe product left unused",
+				zap.String("name", req.Name),
+				zap.String("stripe_product_id", stripeProduct.ID))
+			return nil, nameErr
+		}
 		s.logger.Error("failed to create plan in database", zap.Error(err))
 		return nil, status.Errorf(codes.Internal, "failed to save plan: %v", err)
 	}
@@ -146,6 +259,43 @@ func (s *BillingServiceServer) ListPlans(ctx context.Context, req *pb.ListPlansR
 	}, nil
 }
 
+// GetPlansByIDs retrieves several plans by ID in one call. Unknown IDs are
+// omitted from the response.
+func (s *BillingServiceServer) GetPlansByIDs(ctx context.Context, req *pb.GetPlansByIDsRequest) (*pb.GetPlansByIDsResponse, error) {
+	seen := make(map[string]bool, len(req.PlanIds))
+	planIDs := make([]string, 0, len(req.PlanIds))
+	for _, planID := range req.PlanIds {
+		if planID == "" {
+			return nil, status.Error(codes.InvalidArgument, "plan_ids cannot contain an empty ID")
+		}
+		if !seen[planID] {
+			seen[planID] = true
+			planIDs = append(planIDs, planID)
+		}
+	}
+	if len(planIDs) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "plan_ids is required")
+	}
+	if len(planIDs) > maxPlansByIDs {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d plan_ids can be requested at once", maxPlansByIDs)
+	}
+	
+	plans, err := s.store.GetPlansByIDs(ctx, planIDs)
+	if err != nil {
+		s.logger.Error("failed to get plans", zap.Int("count", len(planIDs)), zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to get plans: %v", err)
+	}
+	
+	pbPlans := make([]*pb.Plan, len(plans))
+	for i := range plans {
+		pbPlans[i] = dbPlanToProto(&plans[i])
+	}
+	
+	return &pb.GetPlansByIDsResponse{
+		Plans: pbPlans,
+	}, nil
+}
+
 // UpdatePlan updates a plan
 func (s *BillingServiceServer) UpdatePlan(ctx context.Context, req *pb.UpdatePlanRequest) (*pb.UpdatePlanResponse, error) {
 	if req.PlanId == "" {
@@ -162,6 +312,11 @@ func (s *BillingServiceServer) UpdatePlan(ctx context.Context, req *pb.UpdatePla
 	
 	// Update fields
 	if req.Name != "" {
+		if plan.IsActive {
+			if err := s.checkPlanNameAvailable(ctx, req.Name, plan.ID); err != nil {
+				return nil, err
+			}
+		}
 		plan.Name = req.Name
 		// Update Stripe product name
 		if _, err := s.stripeClient.UpdateProduct(plan.StripeProductID, req.Name, nil); err != nil {
@@ -183,6 +338,23 @@ func (s *BillingServiceServer) UpdatePlan(ctx context.Context, req *pb.UpdatePla
 	}, nil
 }
 
+// checkPlanNameAvailable returns AlreadyExists when another active plan,
+// other than excludePlanID, already uses the name. Archived plans don't count.
+func (s *BillingServiceServer) checkPlanNameAvailable(ctx context.Context, name, excludePlanID string) error {
+	existing, err := s.store.GetActivePlanByName(ctx, name)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		s.logger.Error("failed to check plan name", zap.Error(err))
+		return status.Errorf(codes.Internal, "failed to check plan name: %v", err)
+	}
+	if existing.ID == excludePlanID {
+		return nil
+	}
+	return status.Errorf(codes.AlreadyExists, "an active plan named %q already exists", existing.Name)
+}
+
 // DeactivatePlan deactivates a plan
 func (s *BillingServiceServer) DeactivatePlan(ctx context.Context, req *pb.DeactivatePlanRequest) (*pb.DeactivatePlanResponse, error) {
 	if req.PlanId == "" {
@@ -225,6 +397,14 @@ func (s *BillingServiceServer) CreateCheckoutSession(ctx context.Context, req *p
 	if req.CancelUrl == "" {
 		return nil, status.Error(codes.InvalidArgument, "cancel_url is required")
 	}
+	if req.TrialDaysOverride != nil {
+		if err := admintoken.Check(ctx, s.adminToken); err != nil {
+			return nil, err
+		}
+		if req.GetTrialDaysOverride() < 0 || req.GetTrialDaysOverride() > s.trialOverrideMax {
+			return nil, status.Errorf(codes.InvalidArgument, "trial_days_override must be between 0 and %d", s.trialOverrideMax)
+		}
+	}
 	
 	// Get plan
 	plan, err := s.store.GetPlanByID(ctx, req.PlanId)
@@ -250,7 +430,7 @@ func (s *BillingServiceServer) CreateCheckoutSession(ctx context.Context, req *p
 	if existingSub != nil {
 		customerID = existingSub.StripeCustomerID
 	} else if req.CustomerEmail != "" {
-		customer, err := s.stripeClient.CreateCustomer(req.CustomerEmail, req.TeamId, nil)
+		customer, err := s.stripeClient.CreateCustomer(ctx, req.CustomerEmail, req.TeamId, nil)
 		if err != nil {
 			s.logger.Error("failed to create Stripe customer", zap.Error(err))
 			return nil, status.Errorf(codes.Internal, "failed to create customer: %v", err)
@@ -258,17 +438,49 @@ func (s *BillingServiceServer) CreateCheckoutSession(ctx context.Context, req *p
 		customerID = customer.ID
 	}
 	
+	// Sales can grant a custom trial in place of the plan's
+	trialDays := plan.TrialDays
+	checkoutMetadata := map[string]string{
+		"team_id": req.TeamId,
+		"plan_id": req.PlanId,
+	}
+	if req.TrialDaysOverride != nil {
+		trialDays = req.GetTrialDaysOverride()
+		checkoutMetadata["trial_days_override"] = strconv.Itoa(int(trialDays))
+		checkoutMetadata["plan_trial_days"] = strconv.Itoa(int(plan.TrialDays))
+		s.logger.Info("overriding plan trial for checkout",
+			zap.String("team_id", req.TeamId),
+			zap.String("plan_id", req.PlanId),
+			zap.Int32("plan_trial_days", plan.TrialDays),
+			zap.Int32("trial_days", trialDays))
+	}
+	
+	// One trial per team: a team that already trialed checks out without
+	// one, and an override can't grant it a second
+	if trialDays > 0 {
+		used, err := s.teamTrialUsed(ctx, req.TeamId)
+		if err != nil {
+			return nil, err
+		}
+		if used && req.TrialDaysOverride != nil {
+			return nil, status.Error(codes.AlreadyExists, "team has already used its trial")
+		}
+		if used {
+			s.logger.Info("team has already used its trial, checking out without one",
+				zap.String("team_id", req.TeamId),
+				zap.String("plan_id", req.PlanId))
+			trialDays = 0
+		}
+	}
+	
 	// Create checkout session
-	session, err := s.stripeClient.CreateCheckoutSession(
+	session, err := s.stripeClient.CreateCheckoutSession(ctx, 
 		plan.StripePriceID,
 		customerID,
 		req.SuccessUrl,
 		req.CancelUrl,
-		map[string]string{
-			"team_id": req.TeamId,
-			"plan_id": req.PlanId,
-		},
-		plan.TrialDays,
+		checkoutMetadata,
+		trialDays,
 	)
 	if err != nil {
 		s.logger.Error("failed to create checkout session", zap.Error(err))
@@ -285,6 +497,190 @@ func (s *BillingServiceServer) CreateCheckoutSession(ctx context.Context, req *p
 	}, nil
 }
 
+// teamTrialUsed reports whether the team has started a trial before, through
+// StartTrial or a checkout. Trials are recorded in team_trials either way.
+func (s *BillingServiceServer) teamTrialUsed(ctx context.Context, teamID string) (bool, error) {
+	if _, err := s.store.GetTeamTrial(ctx, teamID); err == nil {
+		return true, nil
+	} else if err != gorm.ErrRecordNotFound {
+		return false, status.Errorf(codes.Internal, "failed to check trial history: %v", err)
+	}
+	return false, nil
+}
+
+// StartTrial starts a trial subscription directly in Stripe, skipping
+// Checkout so no card is collected. Each team gets one trial, ever.
+func (s *BillingServiceServer) StartTrial(ctx context.Context, req *pb.StartTrialRequest) (*pb.StartTrialResponse, error) {
+	s.logger.Info("starting trial",
+		zap.String("team_id", req.TeamId),
+		zap.String("plan_id", req.PlanId))
+	
+	if !s.trialsWithoutCard {
+		return nil, status.Error(codes.FailedPrecondition, "trials without a card are disabled; use CreateCheckoutSession")
+	}
+	if req.TeamId == "" {
+		return nil, status.Error(codes.InvalidArgument, "team_id is required")
+	}
+	if req.PlanId == "" {
+		return nil, status.Error(codes.InvalidArgument, "plan_id is required")
+	}
+	
+	plan, err := s.store.GetPlanByID(ctx, req.PlanId)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, status.Error(codes.NotFound, "plan not found")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to get plan: %v", err)
+	}
+	if !plan.IsActive {
+		return nil, status.Error(codes.FailedPrecondition, "plan is not active")
+	}
+	if plan.TrialDays <= 0 {
+		return nil, status.Error(codes.FailedPrecondition, "plan does not offer a trial")
+	}
+	
+	// One trial per team, even after the trial's subscription is gone
+	if used, err := s.teamTrialUsed(ctx, req.TeamId); err != nil {
+		return nil, err
+	} else if used {
+		return nil, status.Error(codes.AlreadyExists, "team has already used its trial")
+	}
+	
+	existingSub, err := s.store.GetSubscriptionByTeamID(ctx, req.TeamId)
+	if err == nil && existingSub != nil && existingSub.IsActive() {
+		return nil, status.Error(codes.AlreadyExists, "team already has an active subscription")
+	}
+	
+	var customerID string
+	if existingSub != nil {
+		customerID = existingSub.StripeCustomerID
+	} else if req.CustomerEmail != "" {
+		customer, err := s.stripeClient.CreateCustomer(ctx, req.CustomerEmail, req.TeamId, nil)
+		if err != nil {
+			s.logger.Error("failed to create Stripe customer", zap.Error(err))
+			return nil, status.Errorf(codes.Internal, "failed to create customer: %v", err)
+		}
+		customerID = customer.ID
+	} else {
+		return nil, status.Error(codes.InvalidArgument, "customer_email is required")
+	}
+	
+	// Claim the trial before calling Stripe so concurrent requests can't both start one
+	trial := &db.TeamTrial{
+		TeamID:    req.TeamId,
+		PlanID:    plan.ID,
+		StartedAt: time.Now(),
+	}
+	if err := s.store.CreateTeamTrial(ctx, trial); err != nil {
+		if _, getErr := s.store.GetTeamTrial(ctx, req.TeamId); getErr == nil {
+			return nil, status.Error(codes.AlreadyExists, "team has already used its trial")
+		}
+		return nil, status.Errorf(codes.Internal, "failed to record trial: %v", err)
+	}
+	
+	stripeSub, err := s.stripeClient.CreateTrialSubscription(ctx, customerID, plan.StripePriceID, plan.TrialDays, map[string]string{
+		"team_id": req.TeamId,
+		"plan_id": plan.ID,
+	})
+	if err != nil {
+		s.logger.Error("failed to create trial subscription", zap.Error(err))
+		if releaseErr := s.store.DeleteTeamTrial(ctx, req.TeamId); releaseErr != nil {
+			s.logger.Error("failed to release trial claim",
+				zap.String("team_id", req.TeamId),
+				zap.Error(releaseErr))
+		}
+		return nil, status.Errorf(codes.Internal, "failed to create trial subscription: %v", err)
+	}
+	
+	trial.StripeSubscriptionID = &stripeSub.ID
+	if err := s.store.UpdateTeamTrial(ctx, trial); err != nil {
+		s.logger.Warn("failed to record trial subscription ID",
+			zap.String("team_id", req.TeamId),
+			zap.Error(err))
+	}
+	
+	subscription := &db.Subscription{
+		TeamID:               req.TeamId,
+		PlanID:               plan.ID,
+		Status:               string(stripeSub.Status),
+		StripeSubscriptionID: stripeSub.ID,
+		StripeCustomerID:     customerID,
+		CurrentPeriodStart:   time.Unix(stripeSub.CurrentPeriodStart, 0),
+		CurrentPeriodEnd:     time.Unix(stripeSub.CurrentPeriodEnd, 0),
+	}
+	if stripeSub.TrialEnd > 0 {
+		trialEnd := time.Unix(stripeSub.TrialEnd, 0)
+		subscription.TrialEnd = &trialEnd
+	}
+	
+	if existingSub != nil {
+		subscription.ID = existingSub.ID
+		err = s.store.UpdateSubscription(ctx, subscription)
+	} else {
+		err = s.store.CreateSubscription(ctx, subscription)
+	}
+	if err != nil {
+		s.logger.Error("failed to store trial subscription",
+			zap.String("team_id", req.TeamId),
+			zap.String("stripe_subscription_id", stripeSub.ID),
+			zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to store subscription: %v", err)
+	}
+	subscription.Plan = *plan
+	
+	s.logger.Info("trial started",
+		zap.String("team_id", req.TeamId),
+		zap.String("stripe_subscription_id", stripeSub.ID),
+		zap.Int32("trial_days", plan.TrialDays))
+	
+	return &pb.StartTrialResponse{
+		Subscription: dbSubscriptionToProto(subscription),
+	}, nil
+}
+
+// GetCheckoutStatus reports the state of a Checkout session so the frontend
+// can confirm completion without waiting for the webhook. The session must
+// belong to the caller's team, which the gateway sends in metadata.
+func (s *BillingServiceServer) GetCheckoutStatus(ctx context.Context, req *pb.GetCheckoutStatusRequest) (*pb.GetCheckoutStatusResponse, error) {
+	if req.SessionId == "" {
+		return nil, status.Error(codes.InvalidArgument, "session_id is required")
+	}
+	teamID, err := caller.Team(ctx)
+	if err != nil {
+		return nil, err
+	}
+	
+	session, err := s.stripeClient.GetCheckoutSession(ctx, req.SessionId)
+	if err != nil {
+		var stripeErr *stripe.Error
+		if errors.As(err, &stripeErr) && stripeErr.Code == stripe.ErrorCodeResourceMissing {
+			return nil, status.Error(codes.NotFound, "checkout session not found")
+		}
+		s.logger.Error("failed to get checkout session", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to get checkout session: %v", err)
+	}
+	
+	// Don't leak another team's checkout state
+	if session.Metadata["team_id"] != teamID {
+		s.logger.Warn("checkout session team mismatch",
+			zap.String("session_id", req.SessionId),
+			zap.String("team_id", teamID))
+		return nil, status.Error(codes.PermissionDenied, "checkout session does not belong to team")
+	}
+	
+	// Once complete, return the subscription if the webhook has provisioned it
+	var subscription *db.Subscription
+	if session.Status == stripe.CheckoutSessionStatusComplete && session.Subscription != nil {
+		subscription, err = s.store.GetSubscriptionByStripeID(ctx, session.Subscription.ID)
+		if err != nil && err != gorm.ErrRecordNotFound {
+			s.logger.Error("failed to get subscription", zap.Error(err))
+			return nil, status.Errorf(codes.Internal, "failed to get subscription: %v", err)
+		}
+	}
+	
+	return checkoutStatusToProto(session, subscription), nil
+}
+
 // GetSubscription retrieves a subscription by team ID
 func (s *BillingServiceServer) GetSubscription(ctx context.Context, req *pb.GetSubscriptionRequest) (*pb.GetSubscriptionResponse, error) {
 	if req.TeamId == "" {
@@ -300,9 +696,24 @@ func (s *BillingServiceServer) GetSubscription(ctx context.Context, req *pb.GetS
 		return nil, status.Errorf(codes.Internal, "failed to get subscription: %v", err)
 	}
 	
-	return &pb.GetSubscriptionResponse{
+	resp := &pb.GetSubscriptionResponse{
 		Subscription: dbSubscriptionToProto(subscription),
-	}, nil
+	}
+	
+	// The subscription is still returned if the invoice can't be fetched
+	if req.IncludeUpcomingInvoice {
+		invoice, err := s.stripeClient.GetUpcomingInvoice(subscription.StripeCustomerID)
+		if err != nil {
+			s.logger.Warn("failed to get upcoming invoice for subscription",
+				zap.String("team_id", req.TeamId),
+				zap.Error(err))
+			resp.UpcomingInvoiceError = err.Error()
+		} else {
+			resp.UpcomingInvoice = stripeInvoiceToProto(invoice)
+		}
+	}
+	
+	return resp, nil
 }
 
 // CancelSubscription cancels a subscription
@@ -315,6 +726,21 @@ func (s *BillingServiceServer) CancelSubscription(ctx context.Context, req *pb.C
 		return nil, status.Error(codes.InvalidArgument, "team_id is required")
 	}
 	
+	var scheduledAt time.Time
+	if req.CancelAt != nil {
+		if req.Immediate {
+			return nil, status.Error(codes.InvalidArgument, "cancel_at cannot be combined with immediate")
+		}
+		scheduledAt = req.CancelAt.AsTime()
+		now := time.Now()
+		if !scheduledAt.After(now) {
+			return nil, status.Error(codes.InvalidArgument, "cancel_at must be in the future")
+		}
+		if scheduledAt.After(now.Add(maxCancelAtHorizon)) {
+			return nil, status.Errorf(codes.InvalidArgument, "cancel_at must be within %d days", int(maxCancelAtHorizon.Hours()/24))
+		}
+	}
+	
 	// Get subscription
 	subscription, err := s.store.GetSubscriptionByTeamID(ctx, req.TeamId)
 	if err != nil {
@@ -325,8 +751,13 @@ func (s *BillingServiceServer) CancelSubscription(ctx context.Context, req *pb.C
 	}
 	
 	// Cancel in Stripe
-	cancelAtPeriodEnd := !req.Immediate
-	stripeSub, err := s.stripeClient.CancelSubscription(subscription.StripeSubscriptionID, cancelAtPeriodEnd)
+	var stripeSub *stripe.Subscription
+	if !scheduledAt.IsZero() {
+		stripeSub, err = s.stripeClient.ScheduleCancellation(ctx, subscription.StripeSubscriptionID, scheduledAt)
+	} else {
+		cancelAtPeriodEnd := !req.Immediate
+		stripeSub, err = s.stripeClient.CancelSubscription(subscription.StripeSubscriptionID, cancelAtPeriodEnd)
+	}
 	if err != nil {
 		s.logger.Error("failed to cancel Stripe subscription", zap.Error(err))
 		return nil, status.Errorf(codes.Internal, "failed to cancel subscription: %v", err)
@@ -351,6 +782,8 @@ func (s *BillingServiceServer) CancelSubscription(ctx context.Context, req *pb.C
 	cancellationDate := subscription.CurrentPeriodEnd.Format("2006-01-02")
 	if req.Immediate {
 		cancellationDate = time.Now().Format("2006-01-02")
+	} else if subscription.CancelAt != nil {
+		cancellationDate = subscription.CancelAt.Format("2006-01-02")
 	}
 	
 	s.logger.Info("subscription canceled",
@@ -376,6 +809,17 @@ func (s *BillingServiceServer) UpdateSubscription(ctx context.Context, req *pb.U
 		return nil, status.Error(codes.InvalidArgument, "new_plan_id is required")
 	}
 	
+	prorationBehavior := ProrationCreateProrations
+	if req.ProrationBehavior != "" {
+		prorationBehavior = req.ProrationBehavior
+	}
+	switch prorationBehavior {
+	case ProrationCreateProrations, ProrationNone, ProrationAlwaysInvoice:
+	default:
+		return nil, status.Errorf(codes.InvalidArgument,
+			"proration_behavior must be '%s', '%s' or '%s'", ProrationCreateProrations, ProrationNone, ProrationAlwaysInvoice)
+	}
+	
 	// Get current subscription
 	subscription, err := s.store.GetSubscriptionByTeamID(ctx, req.TeamId)
 	if err != nil {
@@ -398,11 +842,11 @@ func (s *BillingServiceServer) UpdateSubscription(ctx context.Context, req *pb.U
 		return nil, status.Error(codes.FailedPrecondition, "plan is not active")
 	}
 	
-	// Update subscription in Stripe with proration
+	// Update subscription in Stripe with the requested proration
 	stripeSub, err := s.stripeClient.UpdateSubscription(
 		subscription.StripeSubscriptionID,
 		newPlan.StripePriceID,
-		"create_prorations",
+		prorationBehavior,
 	)
 	if err != nil {
 		s.logger.Error("failed to update Stripe subscription", zap.Error(err))
@@ -504,6 +948,17 @@ func (s *BillingServiceServer) ListInvoices(ctx context.Context, req *pb.ListInv
 		limit = 10
 	}
 	
+	var startDate, endDate time.Time
+	if req.StartDate != nil {
+		startDate = req.StartDate.AsTime()
+	}
+	if req.EndDate != nil {
+		endDate = req.EndDate.AsTime()
+	}
+	if !startDate.IsZero() && !endDate.IsZero() && !startDate.Before(endDate) {
+		return nil, status.Error(codes.InvalidArgument, "start_date must be before end_date")
+	}
+	
 	subscription, err := s.store.GetSubscriptionByTeamID(ctx, req.TeamId)
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
@@ -512,7 +967,7 @@ func (s *BillingServiceServer) ListInvoices(ctx context.Context, req *pb.ListInv
 		return nil, status.Errorf(codes.Internal, "failed to get subscription: %v", err)
 	}
 	
-	invoices, err := s.stripeClient.ListInvoices(subscription.StripeCustomerID, int64(limit))
+	invoices, err := s.stripeClient.ListInvoices(subscription.StripeCustomerID, int64(limit), startDate, endDate)
 	if err != nil {
 		s.logger.Error("failed to list invoices", zap.Error(err))
 		return nil, status.Errorf(codes.Internal, "failed to list invoices: %v", err)
@@ -528,6 +983,80 @@ func (s *BillingServiceServer) ListInvoices(ctx context.Context, req *pb.ListInv
 	}, nil
 }
 
+// ReconcileSubscription syncs a team's subscription from its live Stripe state
+func (s *BillingServiceServer) ReconcileSubscription(ctx context.Context, req *pb.ReconcileSubscriptionRequest) (*pb.ReconcileSubscriptionResponse, error) {
+	if req.TeamId == "" {
+		return nil, status.Error(codes.InvalidArgument, "team_id is required")
+	}
+	
+	result, err := s.reconciler.ReconcileTeam(ctx, req.TeamId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, status.Error(codes.NotFound, "subscription not found")
+		}
+		s.logger.Error("failed to reconcile subscription", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to reconcile subscription: %v", err)
+	}
+	
+	return &pb.ReconcileSubscriptionResponse{
+		Subscription: dbSubscriptionToProto(result.Subscription),
+		Diverged:     result.Diverged,
+		Changes:      result.Changes,
+	}, nil
+}
+
+// ListWebhookEvents lists stored webhook events for debugging missed
+// provisioning. Events carry raw payloads, so callers must present the
+// admin token.
+func (s *BillingServiceServer) ListWebhookEvents(ctx context.Context, req *pb.ListWebhookEventsRequest) (*pb.ListWebhookEventsResponse, error) {
+	if err := admintoken.Check(ctx, s.adminToken); err != nil {
+		return nil, err
+	}
+	
+	page, err := pagination.Normalize(pagination.Request{
+		Limit:  int(req.Limit),
+		Offset: int(req.Offset),
+		Cursor: req.Cursor,
+	}, webhookEventsLimits)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	
+	filter := db.WebhookEventFilter{
+		EventType: req.EventType,
+		Processed: req.Processed,
+		HasError:  req.HasError,
+		Page:      page,
+	}
+	if req.ReceivedAfter != nil {
+		filter.ReceivedAfter = req.ReceivedAfter.AsTime()
+	}
+	if req.ReceivedBefore != nil {
+		filter.ReceivedBefore = req.ReceivedBefore.AsTime()
+	}
+	if !filter.ReceivedAfter.IsZero() && !filter.ReceivedBefore.IsZero() && !filter.ReceivedAfter.Before(filter.ReceivedBefore) {
+		return nil, status.Error(codes.InvalidArgument, "received_after must be before received_before")
+	}
+	
+	events, total, err := s.store.ListWebhookEvents(ctx, filter)
+	if err != nil {
+		s.logger.Error("failed to list webhook events", zap.Error(err))
+		return nil, status.Errorf(codes.Internal, "failed to list webhook events: %v", err)
+	}
+	
+	pbEvents := make([]*pb.WebhookEvent, len(events))
+	for i, event := range events {
+		pbEvents[i] = dbWebhookEventToProto(&event)
+	}
+	
+	result := page.Result(total, len(events))
+	return &pb.ListWebhookEventsResponse{
+		Events:     pbEvents,
+		TotalCount: result.Total,
+		NextCursor: result.NextCursor,
+	}, nil
+}
+
 // Helper functions to convert between database and proto models
 
 func dbPlanToProto(plan *db.Plan) *pb.Plan {
@@ -577,6 +1106,21 @@ func dbSubscriptionToProto(sub *db.Subscription) *pb.Subscription {
 	return pbSub
 }
 
+func checkoutStatusToProto(session *stripe.CheckoutSession, sub *db.Subscription) *pb.GetCheckoutStatusResponse {
+	resp := &pb.GetCheckoutStatusResponse{
+		SessionId:     session.ID,
+		Status:        string(session.Status),
+		PaymentStatus: string(session.PaymentStatus),
+	}
+	
+	if session.Status == stripe.CheckoutSessionStatusComplete && sub != nil {
+		resp.Provisioned = true
+		resp.Subscription = dbSubscriptionToProto(sub)
+	}
+	
+	return resp
+}
+
 func stripeInvoiceToProto(inv *stripe.Invoice) *pb.Invoice {
 	pbInv := &pb.Invoice{
 		Id:         inv.ID,
@@ -599,3 +1143,22 @@ func stripeInvoiceToProto(inv *stripe.Invoice) *pb.Invoice {
 	
 	return pbInv
 }
+
+func dbWebhookEventToProto(event *db.WebhookEvent) *pb.WebhookEvent {
+	pbEvent := &pb.WebhookEvent{
+		Id:            event.ID,
+		StripeEventId: event.StripeEventID,
+		EventType:     event.EventType,
+		Processed:     event.Processed,
+		ReceivedAt:    timestamppb.New(event.ReceivedAt),
+	}
+	
+	if event.ProcessingError != nil {
+		pbEvent.ProcessingError = *event.ProcessingError
+	}
+	if event.ProcessedAt != nil {
+		pbEvent.ProcessedAt = timestamppb.New(*event.ProcessedAt)
+	}
+	
+	return pbEvent
+}
@@ -2,16 +2,22 @@ package internal
 
 import (
 	"context"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/haunted-saas/billing-service/internal/db"
 	pb "github.com/haunted-saas/billing-service/proto/billing/v1"
+	"github.com/haunted-saas/pkg/admintoken"
+	"github.com/haunted-saas/pkg/caller"
 	"github.com/stripe/stripe-go/v76"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	"gorm.io/gorm"
 )
 
@@ -38,6 +44,9 @@ func TestBillingService_CreatePlan(t *testing.T) {
 				TrialDays: 14,
 			},
 			setupMocks: func(sc *MockStripeClient, store *MockStore) {
+				// No active plan has the name yet
+				store.On("GetActivePlanByName", mock.Anything, "Pro Plan").Return(nil, gorm.ErrRecordNotFound)
+
 				// Mock Stripe product creation
 				sc.On("CreateProduct", "Pro Plan", mock.Anything).Return(&stripe.Product{
 					ID:   "prod_test_123",
@@ -94,16 +103,14 @@ func TestBillingService_CreatePlan(t *testing.T) {
 
 			tt.setupMocks(mockStripe, mockStore)
 
-			// Note: This demonstrates test structure
-			// Full implementation would require proper mocking
+			server := NewBillingServiceServer(mockStripe, mockStore, logger)
+			resp, err := server.CreatePlan(context.Background(), tt.request)
 
 			// Assertions
-			if tt.expectedError != codes.OK {
-				// Expect error
-				assert.NotEqual(t, codes.OK, tt.expectedError)
-			} else {
-				// Expect success
-				assert.True(t, tt.expectedResult)
+			assert.Equal(t, tt.expectedError, status.Code(err))
+			if tt.expectedResult {
+				assert.NotNil(t, resp)
+				assert.Equal(t, "price_test_123", resp.Plan.StripePriceId)
 			}
 
 			mockStripe.AssertExpectations(t)
@@ -112,6 +119,115 @@ func TestBillingService_CreatePlan(t *testing.T) {
 	}
 }
 
+// Test CreatePlan rejects the name of an existing active plan
+func TestBillingService_CreatePlan_DuplicateName(t *testing.T) {
+	mockStripe := new(MockStripeClient)
+	mockStore := new(MockStore)
+	logger, _ := zap.NewDevelopment()
+
+	mockStore.On("GetActivePlanByName", mock.Anything, "pro plan").Return(&db.Plan{
+		ID:       "plan_123",
+		Name:     "Pro Plan",
+		IsActive: true,
+	}, nil)
+
+	server := NewBillingServiceServer(mockStripe, mockStore, logger)
+
+	_, err := server.CreatePlan(context.Background(), &pb.CreatePlanRequest{
+		Name:            "pro plan",
+		PriceCents:      2999,
+		BillingInterval: "month",
+	})
+
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	mockStripe.AssertNotCalled(t, "CreateProduct", mock.Anything, mock.Anything)
+	mockStore.AssertNotCalled(t, "CreatePlan", mock.Anything, mock.Anything)
+	mockStore.AssertExpectations(t)
+}
+
+// Test CreatePlan reuses the name of an archived plan
+func TestBillingService_CreatePlan_ReusesArchivedName(t *testing.T) {
+	mockStripe := new(MockStripeClient)
+	mockStore := new(MockStore)
+	logger, _ := zap.NewDevelopment()
+
+	// Archived plans aren't returned by the active-name lookup
+	mockStore.On("GetActivePlanByName", mock.Anything, "Pro Plan").Return(nil, gorm.ErrRecordNotFound)
+	mockStripe.On("CreateProduct", "Pro Plan", mock.Anything).Return(&stripe.Product{ID: "prod_test_456"}, nil)
+	mockStripe.On("CreatePrice", "prod_test_456", int64(2999), "usd", "month").Return(&stripe.Price{ID: "price_test_456"}, nil)
+	mockStore.On("CreatePlan", mock.Anything, mock.AnythingOfType("*db.Plan")).Return(nil)
+
+	server := NewBillingServiceServer(mockStripe, mockStore, logger)
+
+	resp, err := server.CreatePlan(context.Background(), &pb.CreatePlanRequest{
+		Name:            "Pro Plan",
+		PriceCents:      2999,
+		BillingInterval: "month",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Pro Plan", resp.Plan.Name)
+	mockStripe.AssertExpectations(t)
+	mockStore.AssertExpectations(t)
+}
+
+// Test CreatePlan currency and trial validation
+func TestBillingService_CreatePlan_Validation(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	server := NewBillingServiceServer(nil, nil, logger)
+
+	tests := []struct {
+		name    string
+		request *pb.CreatePlanRequest
+	}{
+		{
+			name: "unknown currency",
+			request: &pb.CreatePlanRequest{
+				Name:            "Pro Plan",
+				PriceCents:      2999,
+				Currency:        "xyz",
+				BillingInterval: "month",
+			},
+		},
+		{
+			name: "negative trial days",
+			request: &pb.CreatePlanRequest{
+				Name:            "Pro Plan",
+				PriceCents:      2999,
+				Currency:        "usd",
+				BillingInterval: "month",
+				TrialDays:       -1,
+			},
+		},
+		{
+			name: "trial days above cap",
+			request: &pb.CreatePlanRequest{
+				Name:            "Pro Plan",
+				PriceCents:      2999,
+				BillingInterval: "month",
+				TrialDays:       maxTrialDays + 1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := server.CreatePlan(context.Background(), tt.request)
+			st, _ := status.FromError(err)
+			assert.Equal(t, codes.InvalidArgument, st.Code())
+		})
+	}
+}
+
+func TestNormalizeCurrency(t *testing.T) {
+	code, ok := normalizeCurrency(" EUR ")
+	assert.True(t, ok)
+	assert.Equal(t, "eur", code)
+
+	_, ok = normalizeCurrency("dollars")
+	assert.False(t, ok)
+}
+
 // Test GetSubscription
 func TestBillingService_GetSubscription(t *testing.T) {
 	tests := []struct {
@@ -186,3 +302,755 @@ func TestBillingService_GetSubscription(t *testing.T) {
 		})
 	}
 }
+
+// Test GetSubscription embeds the upcoming invoice when asked
+func TestBillingService_GetSubscription_IncludeUpcomingInvoice(t *testing.T) {
+	subscription := &db.Subscription{
+		ID:                   "sub_123",
+		TeamID:               "team_123",
+		PlanID:               "plan_123",
+		Status:               "active",
+		StripeCustomerID:     "cus_123",
+		StripeSubscriptionID: "sub_stripe_123",
+	}
+
+	tests := []struct {
+		name          string
+		include       bool
+		setupMocks    func(*MockStripeClient)
+		expectInvoice bool
+		expectError   bool
+	}{
+		{
+			name:    "invoice embedded",
+			include: true,
+			setupMocks: func(sc *MockStripeClient) {
+				sc.On("GetUpcomingInvoice", "cus_123").Return(&stripe.Invoice{
+					ID:        "in_upcoming",
+					AmountDue: 2900,
+					Currency:  stripe.CurrencyUSD,
+					Status:    stripe.InvoiceStatusDraft,
+				}, nil)
+			},
+			expectInvoice: true,
+		},
+		{
+			name:    "invoice fetch fails",
+			include: true,
+			setupMocks: func(sc *MockStripeClient) {
+				sc.On("GetUpcomingInvoice", "cus_123").Return(nil, errors.New("stripe unavailable"))
+			},
+			expectError: true,
+		},
+		{
+			name:       "invoice not requested",
+			include:    false,
+			setupMocks: func(sc *MockStripeClient) {},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStripe := new(MockStripeClient)
+			mockStore := new(MockStore)
+			logger, _ := zap.NewDevelopment()
+
+			mockStore.On("GetSubscriptionByTeamID", mock.Anything, "team_123").Return(subscription, nil)
+			tt.setupMocks(mockStripe)
+
+			server := NewBillingServiceServer(mockStripe, mockStore, logger)
+
+			resp, err := server.GetSubscription(context.Background(), &pb.GetSubscriptionRequest{
+				TeamId:                 "team_123",
+				IncludeUpcomingInvoice: tt.include,
+			})
+
+			assert.NoError(t, err)
+			assert.NotNil(t, resp.Subscription)
+			if tt.expectInvoice {
+				assert.NotNil(t, resp.UpcomingInvoice)
+				assert.Equal(t, "in_upcoming", resp.UpcomingInvoice.Id)
+				assert.Equal(t, int64(2900), resp.UpcomingInvoice.AmountDue)
+			} else {
+				assert.Nil(t, resp.UpcomingInvoice)
+			}
+			if tt.expectError {
+				assert.Contains(t, resp.UpcomingInvoiceError, "stripe unavailable")
+			} else {
+				assert.Empty(t, resp.UpcomingInvoiceError)
+			}
+
+			mockStripe.AssertExpectations(t)
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+// Test CancelSubscription scheduling cancellation for a specific date
+func TestBillingService_CancelSubscription_ScheduledCancelAt(t *testing.T) {
+	cancelAt := time.Now().Add(30 * 24 * time.Hour).Truncate(time.Second)
+
+	mockStripe := new(MockStripeClient)
+	mockStore := new(MockStore)
+	logger, _ := zap.NewDevelopment()
+
+	mockStore.On("GetSubscriptionByTeamID", mock.Anything, "team_123").Return(&db.Subscription{
+		ID:                   "sub_123",
+		TeamID:               "team_123",
+		Status:               "active",
+		StripeSubscriptionID: "sub_stripe_123",
+		CurrentPeriodEnd:     time.Now().Add(10 * 24 * time.Hour),
+	}, nil)
+	mockStripe.On("ScheduleCancellation", "sub_stripe_123", mock.MatchedBy(func(at time.Time) bool {
+		return at.Equal(cancelAt)
+	})).Return(&stripe.Subscription{
+		ID:       "sub_stripe_123",
+		Status:   stripe.SubscriptionStatusActive,
+		CancelAt: cancelAt.Unix(),
+	}, nil)
+	mockStore.On("UpdateSubscription", mock.Anything, mock.MatchedBy(func(sub *db.Subscription) bool {
+		return sub.CancelAt != nil && sub.CancelAt.Equal(cancelAt) && sub.Status == "active"
+	})).Return(nil)
+
+	server := NewBillingServiceServer(mockStripe, mockStore, logger)
+
+	resp, err := server.CancelSubscription(context.Background(), &pb.CancelSubscriptionRequest{
+		TeamId:   "team_123",
+		CancelAt: timestamppb.New(cancelAt),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, cancelAt.Format("2006-01-02"), resp.CancellationDate)
+	assert.True(t, resp.Subscription.CancelAt.AsTime().Equal(cancelAt))
+	mockStripe.AssertNotCalled(t, "CancelSubscription", mock.Anything, mock.Anything)
+	mockStripe.AssertExpectations(t)
+	mockStore.AssertExpectations(t)
+}
+
+// Test CancelSubscription rejects cancel_at dates outside the allowed window
+func TestBillingService_CancelSubscription_CancelAtValidation(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	server := NewBillingServiceServer(nil, nil, logger)
+
+	tests := []struct {
+		name      string
+		cancelAt  time.Time
+		immediate bool
+	}{
+		{name: "in the past", cancelAt: time.Now().Add(-time.Hour)},
+		{name: "beyond the horizon", cancelAt: time.Now().Add(maxCancelAtHorizon + 24*time.Hour)},
+		{name: "combined with immediate", cancelAt: time.Now().Add(24 * time.Hour), immediate: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := server.CancelSubscription(context.Background(), &pb.CancelSubscriptionRequest{
+				TeamId:    "team_123",
+				CancelAt:  timestamppb.New(tt.cancelAt),
+				Immediate: tt.immediate,
+			})
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
+
+// Test GetCheckoutStatus response building
+func TestBillingService_GetCheckoutStatus(t *testing.T) {
+	provisioned := &db.Subscription{
+		ID:                   "sub_123",
+		TeamID:               "team_123",
+		PlanID:               "plan_123",
+		Status:               "active",
+		StripeSubscriptionID: "sub_stripe_123",
+	}
+
+	tests := []struct {
+		name                string
+		session             *stripe.CheckoutSession
+		subscription        *db.Subscription
+		expectedStatus      string
+		expectedProvisioned bool
+	}{
+		{
+			name: "completed session with provisioned subscription",
+			session: &stripe.CheckoutSession{
+				ID:            "cs_test_complete",
+				Status:        stripe.CheckoutSessionStatusComplete,
+				PaymentStatus: stripe.CheckoutSessionPaymentStatusPaid,
+				Metadata:      map[string]string{"team_id": "team_123"},
+				Subscription:  &stripe.Subscription{ID: "sub_stripe_123"},
+			},
+			subscription:        provisioned,
+			expectedStatus:      "complete",
+			expectedProvisioned: true,
+		},
+		{
+			name: "completed session awaiting webhook",
+			session: &stripe.CheckoutSession{
+				ID:            "cs_test_pending",
+				Status:        stripe.CheckoutSessionStatusComplete,
+				PaymentStatus: stripe.CheckoutSessionPaymentStatusPaid,
+				Metadata:      map[string]string{"team_id": "team_123"},
+				Subscription:  &stripe.Subscription{ID: "sub_stripe_456"},
+			},
+			subscription:        nil,
+			expectedStatus:      "complete",
+			expectedProvisioned: false,
+		},
+		{
+			name: "open session",
+			session: &stripe.CheckoutSession{
+				ID:            "cs_test_open",
+				Status:        stripe.CheckoutSessionStatusOpen,
+				PaymentStatus: stripe.CheckoutSessionPaymentStatusUnpaid,
+				Metadata:      map[string]string{"team_id": "team_123"},
+			},
+			subscription:        provisioned, // Ignored until the session completes
+			expectedStatus:      "open",
+			expectedProvisioned: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := checkoutStatusToProto(tt.session, tt.subscription)
+
+			assert.Equal(t, tt.session.ID, resp.SessionId)
+			assert.Equal(t, tt.expectedStatus, resp.Status)
+			assert.Equal(t, tt.expectedProvisioned, resp.Provisioned)
+			if tt.expectedProvisioned {
+				assert.NotNil(t, resp.Subscription)
+				assert.Equal(t, "team_123", resp.Subscription.TeamId)
+			} else {
+				assert.Nil(t, resp.Subscription)
+			}
+		})
+	}
+}
+
+// Test GetCheckoutStatus checks the session against the caller's team
+func TestBillingService_GetCheckoutStatus_Handler(t *testing.T) {
+	tests := []struct {
+		name                string
+		callerTeam          string
+		session             *stripe.CheckoutSession
+		setupMocks          func(*MockStore)
+		expectedError       codes.Code
+		expectedStatus      string
+		expectedProvisioned bool
+	}{
+		{
+			name:       "completed session",
+			callerTeam: "team_123",
+			session: &stripe.CheckoutSession{
+				ID:            "cs_test_123",
+				Status:        stripe.CheckoutSessionStatusComplete,
+				PaymentStatus: stripe.CheckoutSessionPaymentStatusPaid,
+				Metadata:      map[string]string{"team_id": "team_123"},
+				Subscription:  &stripe.Subscription{ID: "sub_stripe_123"},
+			},
+			setupMocks: func(store *MockStore) {
+				store.On("GetSubscriptionByStripeID", mock.Anything, "sub_stripe_123").Return(&db.Subscription{
+					ID:     "sub_123",
+					TeamID: "team_123",
+					Status: "active",
+				}, nil)
+			},
+			expectedError:       codes.OK,
+			expectedStatus:      "complete",
+			expectedProvisioned: true,
+		},
+		{
+			name:       "open session",
+			callerTeam: "team_123",
+			session: &stripe.CheckoutSession{
+				ID:            "cs_test_123",
+				Status:        stripe.CheckoutSessionStatusOpen,
+				PaymentStatus: stripe.CheckoutSessionPaymentStatusUnpaid,
+				Metadata:      map[string]string{"team_id": "team_123"},
+			},
+			expectedError:  codes.OK,
+			expectedStatus: "open",
+		},
+		{
+			name:       "another team's session",
+			callerTeam: "team_456",
+			session: &stripe.CheckoutSession{
+				ID:       "cs_test_123",
+				Status:   stripe.CheckoutSessionStatusOpen,
+				Metadata: map[string]string{"team_id": "team_123"},
+			},
+			expectedError: codes.PermissionDenied,
+		},
+		{
+			name:          "no caller team",
+			expectedError: codes.Unauthenticated,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStripe := new(MockStripeClient)
+			mockStore := new(MockStore)
+			logger, _ := zap.NewDevelopment()
+
+			if tt.session != nil {
+				mockStripe.On("GetCheckoutSession", "cs_test_123").Return(tt.session, nil)
+			}
+			if tt.setupMocks != nil {
+				tt.setupMocks(mockStore)
+			}
+
+			ctx := context.Background()
+			if tt.callerTeam != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(caller.TeamMetadataKey, tt.callerTeam))
+			}
+
+			server := NewBillingServiceServer(mockStripe, mockStore, logger)
+			resp, err := server.GetCheckoutStatus(ctx, &pb.GetCheckoutStatusRequest{SessionId: "cs_test_123"})
+
+			if tt.expectedError != codes.OK {
+				st, _ := status.FromError(err)
+				assert.Equal(t, tt.expectedError, st.Code())
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, resp.Status)
+			assert.Equal(t, tt.expectedProvisioned, resp.Provisioned)
+			mockStripe.AssertExpectations(t)
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+// Test GetCheckoutStatus input validation
+func TestBillingService_GetCheckoutStatus_Validation(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	server := NewBillingServiceServer(nil, nil, logger)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(caller.TeamMetadataKey, "team_123"))
+	_, err := server.GetCheckoutStatus(ctx, &pb.GetCheckoutStatusRequest{})
+	st, _ := status.FromError(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+}
+
+// Test StartTrial creates a card-less trial and claims the team's trial
+func TestBillingService_StartTrial(t *testing.T) {
+	trialEnd := time.Now().Add(14 * 24 * time.Hour).Truncate(time.Second)
+
+	mockStripe := new(MockStripeClient)
+	mockStore := new(MockStore)
+	logger, _ := zap.NewDevelopment()
+
+	mockStore.On("GetPlanByID", mock.Anything, "plan_123").Return(&db.Plan{
+		ID:            "plan_123",
+		Name:          "Pro Plan",
+		IsActive:      true,
+		StripePriceID: "price_test_123",
+		TrialDays:     14,
+	}, nil)
+	mockStore.On("GetTeamTrial", mock.Anything, "team_123").Return(nil, gorm.ErrRecordNotFound)
+	mockStore.On("GetSubscriptionByTeamID", mock.Anything, "team_123").Return(nil, gorm.ErrRecordNotFound)
+	mockStripe.On("CreateCustomer", "owner@example.com", "team_123", mock.Anything).Return(&stripe.Customer{ID: "cus_test_123"}, nil)
+	mockStore.On("CreateTeamTrial", mock.Anything, mock.MatchedBy(func(trial *db.TeamTrial) bool {
+		return trial.TeamID == "team_123" && trial.PlanID == "plan_123"
+	})).Return(nil)
+	mockStripe.On("CreateTrialSubscription", "cus_test_123", "price_test_123", int32(14), mock.Anything).Return(&stripe.Subscription{
+		ID:       "sub_stripe_123",
+		Status:   stripe.SubscriptionStatusTrialing,
+		TrialEnd: trialEnd.Unix(),
+	}, nil)
+	mockStore.On("UpdateTeamTrial", mock.Anything, mock.AnythingOfType("*db.TeamTrial")).Return(nil)
+	mockStore.On("CreateSubscription", mock.Anything, mock.MatchedBy(func(sub *db.Subscription) bool {
+		return sub.Status == "trialing" && sub.StripeCustomerID == "cus_test_123"
+	})).Return(nil)
+
+	server := NewBillingServiceServer(mockStripe, mockStore, logger)
+	server.SetTrialsWithoutCard(true)
+
+	resp, err := server.StartTrial(context.Background(), &pb.StartTrialRequest{
+		TeamId:        "team_123",
+		PlanId:        "plan_123",
+		CustomerEmail: "owner@example.com",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "trialing", resp.Subscription.Status)
+	assert.True(t, resp.Subscription.TrialEnd.AsTime().Equal(trialEnd))
+	mockStripe.AssertExpectations(t)
+	mockStore.AssertExpectations(t)
+}
+
+// Test StartTrial rejects a second trial for the same team
+func TestBillingService_StartTrial_SecondTrialRejected(t *testing.T) {
+	mockStripe := new(MockStripeClient)
+	mockStore := new(MockStore)
+	logger, _ := zap.NewDevelopment()
+
+	mockStore.On("GetPlanByID", mock.Anything, "plan_123").Return(&db.Plan{
+		ID:            "plan_123",
+		IsActive:      true,
+		StripePriceID: "price_test_123",
+		TrialDays:     14,
+	}, nil)
+	mockStore.On("GetTeamTrial", mock.Anything, "team_123").Return(&db.TeamTrial{
+		TeamID:    "team_123",
+		PlanID:    "plan_123",
+		StartedAt: time.Now().Add(-60 * 24 * time.Hour),
+	}, nil)
+
+	server := NewBillingServiceServer(mockStripe, mockStore, logger)
+	server.SetTrialsWithoutCard(true)
+
+	_, err := server.StartTrial(context.Background(), &pb.StartTrialRequest{
+		TeamId:        "team_123",
+		PlanId:        "plan_123",
+		CustomerEmail: "owner@example.com",
+	})
+
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	mockStripe.AssertNotCalled(t, "CreateTrialSubscription", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	mockStore.AssertNotCalled(t, "CreateTeamTrial", mock.Anything, mock.Anything)
+	mockStore.AssertExpectations(t)
+}
+
+// Test StartTrial is off unless configured
+func TestBillingService_StartTrial_Disabled(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	server := NewBillingServiceServer(nil, nil, logger)
+
+	_, err := server.StartTrial(context.Background(), &pb.StartTrialRequest{TeamId: "team_123", PlanId: "plan_123"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+// Test CreateCheckoutSession uses an admin's trial_days_override in place of the plan's trial
+func TestBillingService_CreateCheckoutSession_TrialOverride(t *testing.T) {
+	mockStripe := new(MockStripeClient)
+	mockStore := new(MockStore)
+	logger, _ := zap.NewDevelopment()
+
+	mockStore.On("GetPlanByID", mock.Anything, "plan_123").Return(&db.Plan{
+		ID:            "plan_123",
+		IsActive:      true,
+		StripePriceID: "price_test_123",
+		TrialDays:     14,
+	}, nil)
+	mockStore.On("GetSubscriptionByTeamID", mock.Anything, "team_123").Return(nil, gorm.ErrRecordNotFound)
+	mockStore.On("GetTeamTrial", mock.Anything, "team_123").Return(nil, gorm.ErrRecordNotFound)
+	mockStripe.On("CreateCheckoutSession", "price_test_123", "", "https://app.example.com/success", "https://app.example.com/cancel",
+		map[string]string{
+			"team_id":             "team_123",
+			"plan_id":             "plan_123",
+			"trial_days_override": "45",
+			"plan_trial_days":     "14",
+		},
+		int32(45),
+	).Return(&stripe.CheckoutSession{ID: "cs_test_123", URL: "https://checkout.stripe.com/cs_test_123"}, nil)
+
+	server := NewBillingServiceServer(mockStripe, mockStore, logger)
+	server.SetAdminToken("secret")
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(admintoken.MetadataKey, "secret"))
+	override := int32(45)
+	resp, err := server.CreateCheckoutSession(ctx, &pb.CreateCheckoutSessionRequest{
+		TeamId:            "team_123",
+		PlanId:            "plan_123",
+		SuccessUrl:        "https://app.example.com/success",
+		CancelUrl:         "https://app.example.com/cancel",
+		TrialDaysOverride: &override,
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "cs_test_123", resp.SessionId)
+	mockStripe.AssertExpectations(t)
+	mockStore.AssertExpectations(t)
+}
+
+// Test CreateCheckoutSession rejects trial_days_override from non-admins and beyond the max
+// Test that a team that already trialed checks out without a trial, and
+// that an override can't grant it another
+func TestBillingService_CreateCheckoutSession_TrialAlreadyUsed(t *testing.T) {
+	newServer := func() (*BillingServiceServer, *MockStripeClient, *MockStore) {
+		mockStripe := new(MockStripeClient)
+		mockStore := new(MockStore)
+		mockStore.On("GetPlanByID", mock.Anything, "plan_123").Return(&db.Plan{
+			ID:            "plan_123",
+			IsActive:      true,
+			StripePriceID: "price_test_123",
+			TrialDays:     14,
+		}, nil)
+		mockStore.On("GetSubscriptionByTeamID", mock.Anything, "team_123").Return(nil, gorm.ErrRecordNotFound)
+		mockStore.On("GetTeamTrial", mock.Anything, "team_123").Return(&db.TeamTrial{TeamID: "team_123"}, nil)
+
+		server := NewBillingServiceServer(mockStripe, mockStore, zap.NewNop())
+		server.SetAdminToken("secret")
+		return server, mockStripe, mockStore
+	}
+	req := &pb.CreateCheckoutSessionRequest{
+		TeamId:     "team_123",
+		PlanId:     "plan_123",
+		SuccessUrl: "https://app.example.com/success",
+		CancelUrl:  "https://app.example.com/cancel",
+	}
+
+	server, mockStripe, _ := newServer()
+	mockStripe.On("CreateCheckoutSession", "price_test_123", "", req.SuccessUrl, req.CancelUrl, mock.Anything, int32(0)).
+		Return(&stripe.CheckoutSession{ID: "cs_test_123"}, nil)
+	_, err := server.CreateCheckoutSession(context.Background(), req)
+	assert.NoError(t, err)
+	mockStripe.AssertExpectations(t)
+
+	server, mockStripe, _ = newServer()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(admintoken.MetadataKey, "secret"))
+	override := int32(45)
+	req.TrialDaysOverride = &override
+	_, err = server.CreateCheckoutSession(ctx, req)
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	mockStripe.AssertNotCalled(t, "CreateCheckoutSession", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBillingService_CreateCheckoutSession_TrialOverrideRejected(t *testing.T) {
+	tests := []struct {
+		name          string
+		ctx           context.Context
+		override      int32
+		expectedError codes.Code
+	}{
+		{
+			name:          "no admin token",
+			ctx:           context.Background(),
+			override:      45,
+			expectedError: codes.Unauthenticated,
+		},
+		{
+			name:          "wrong admin token",
+			ctx:           metadata.NewIncomingContext(context.Background(), metadata.Pairs(admintoken.MetadataKey, "guess")),
+			override:      45,
+			expectedError: codes.Unauthenticated,
+		},
+		{
+			name:          "beyond the max",
+			ctx:           metadata.NewIncomingContext(context.Background(), metadata.Pairs(admintoken.MetadataKey, "secret")),
+			override:      defaultTrialOverrideMaxDays + 1,
+			expectedError: codes.InvalidArgument,
+		},
+		{
+			name:          "negative",
+			ctx:           metadata.NewIncomingContext(context.Background(), metadata.Pairs(admintoken.MetadataKey, "secret")),
+			override:      -1,
+			expectedError: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStripe := new(MockStripeClient)
+			mockStore := new(MockStore)
+			logger, _ := zap.NewDevelopment()
+
+			server := NewBillingServiceServer(mockStripe, mockStore, logger)
+			server.SetAdminToken("secret")
+
+			override := tt.override
+			_, err := server.CreateCheckoutSession(tt.ctx, &pb.CreateCheckoutSessionRequest{
+				TeamId:            "team_123",
+				PlanId:            "plan_123",
+				SuccessUrl:        "https://app.example.com/success",
+				CancelUrl:         "https://app.example.com/cancel",
+				TrialDaysOverride: &override,
+			})
+
+			assert.Equal(t, tt.expectedError, status.Code(err))
+			mockStripe.AssertNotCalled(t, "CreateCheckoutSession", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			mockStore.AssertNotCalled(t, "GetPlanByID", mock.Anything, mock.Anything)
+		})
+	}
+}
+
+// Test UpdateSubscription passes the requested proration behavior to Stripe
+func TestBillingService_UpdateSubscription_ProrationBehavior(t *testing.T) {
+	tests := []struct {
+		name              string
+		prorationBehavior string
+		expected          string
+	}{
+		{name: "defaults to create_prorations", prorationBehavior: "", expected: "create_prorations"},
+		{name: "none", prorationBehavior: "none", expected: "none"},
+		{name: "always_invoice", prorationBehavior: "always_invoice", expected: "always_invoice"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStripe := new(MockStripeClient)
+			mockStore := new(MockStore)
+			logger, _ := zap.NewDevelopment()
+
+			mockStore.On("GetSubscriptionByTeamID", mock.Anything, "team_123").Return(&db.Subscription{
+				ID:                   "sub_123",
+				TeamID:               "team_123",
+				Status:               "active",
+				StripeCustomerID:     "cus_test_123",
+				StripeSubscriptionID: "sub_stripe_123",
+			}, nil)
+			mockStore.On("GetPlanByID", mock.Anything, "plan_pro").Return(&db.Plan{
+				ID:            "plan_pro",
+				IsActive:      true,
+				PriceCents:    4900,
+				StripePriceID: "price_pro",
+			}, nil)
+			mockStripe.On("UpdateSubscription", "sub_stripe_123", "price_pro", tt.expected).Return(&stripe.Subscription{
+				ID:     "sub_stripe_123",
+				Status: stripe.SubscriptionStatusActive,
+			}, nil)
+			mockStore.On("UpdateSubscription", mock.Anything, mock.MatchedBy(func(sub *db.Subscription) bool {
+				return sub.PlanID == "plan_pro"
+			})).Return(nil)
+			mockStripe.On("GetUpcomingInvoice", "cus_test_123").Return(&stripe.Invoice{AmountDue: 1200}, nil)
+
+			server := NewBillingServiceServer(mockStripe, mockStore, logger)
+
+			resp, err := server.UpdateSubscription(context.Background(), &pb.UpdateSubscriptionRequest{
+				TeamId:            "team_123",
+				NewPlanId:         "plan_pro",
+				ProrationBehavior: tt.prorationBehavior,
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1200), resp.ProrationAmountCents)
+			mockStripe.AssertExpectations(t)
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
+
+// Test UpdateSubscription rejects unknown proration behaviors
+func TestBillingService_UpdateSubscription_InvalidProrationBehavior(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	server := NewBillingServiceServer(nil, nil, logger)
+
+	_, err := server.UpdateSubscription(context.Background(), &pb.UpdateSubscriptionRequest{
+		TeamId:            "team_123",
+		NewPlanId:         "plan_pro",
+		ProrationBehavior: "immediately",
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// Test ListInvoices forwards the requested date range to Stripe
+func TestBillingService_ListInvoices_DateRange(t *testing.T) {
+	startDate := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	endDate := time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	mockStripe := new(MockStripeClient)
+	mockStore := new(MockStore)
+	logger, _ := zap.NewDevelopment()
+
+	mockStore.On("GetSubscriptionByTeamID", mock.Anything, "team_123").Return(&db.Subscription{
+		ID:               "sub_123",
+		TeamID:           "team_123",
+		StripeCustomerID: "cus_test_123",
+	}, nil)
+	mockStripe.On("ListInvoices", "cus_test_123", int64(10), mock.MatchedBy(func(at time.Time) bool {
+		return at.Equal(startDate)
+	}), mock.MatchedBy(func(at time.Time) bool {
+		return at.Equal(endDate)
+	})).Return([]*stripe.Invoice{{ID: "in_march", AmountDue: 4900}}, nil)
+
+	server := NewBillingServiceServer(mockStripe, mockStore, logger)
+
+	resp, err := server.ListInvoices(context.Background(), &pb.ListInvoicesRequest{
+		TeamId:    "team_123",
+		StartDate: timestamppb.New(startDate),
+		EndDate:   timestamppb.New(endDate),
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, resp.Invoices, 1)
+	mockStripe.AssertExpectations(t)
+	mockStore.AssertExpectations(t)
+}
+
+// Test ListInvoices rejects an empty or inverted date range
+func TestBillingService_ListInvoices_InvalidDateRange(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	server := NewBillingServiceServer(nil, nil, logger)
+
+	day := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		startDate time.Time
+		endDate   time.Time
+	}{
+		{name: "end before start", startDate: day, endDate: day.Add(-24 * time.Hour)},
+		{name: "empty range", startDate: day, endDate: day},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := server.ListInvoices(context.Background(), &pb.ListInvoicesRequest{
+				TeamId:    "team_123",
+				StartDate: timestamppb.New(tt.startDate),
+				EndDate:   timestamppb.New(tt.endDate),
+			})
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
+
+func TestBillingService_ListWebhookEvents_RequiresAdminToken(t *testing.T) {
+	tests := []struct {
+		name          string
+		adminToken    string
+		ctx           context.Context
+		expectedError codes.Code
+	}{
+		{
+			name:          "admin access not configured",
+			adminToken:    "",
+			ctx:           metadata.NewIncomingContext(context.Background(), metadata.Pairs(admintoken.MetadataKey, "secret")),
+			expectedError: codes.PermissionDenied,
+		},
+		{
+			name:          "no admin token",
+			adminToken:    "secret",
+			ctx:           context.Background(),
+			expectedError: codes.Unauthenticated,
+		},
+		{
+			name:          "wrong admin token",
+			adminToken:    "secret",
+			ctx:           metadata.NewIncomingContext(context.Background(), metadata.Pairs(admintoken.MetadataKey, "guess")),
+			expectedError: codes.Unauthenticated,
+		},
+		{
+			name:          "valid admin token",
+			adminToken:    "secret",
+			ctx:           metadata.NewIncomingContext(context.Background(), metadata.Pairs(admintoken.MetadataKey, "secret")),
+			expectedError: codes.OK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockStore := new(MockStore)
+			logger, _ := zap.NewDevelopment()
+
+			server := NewBillingServiceServer(new(MockStripeClient), mockStore, logger)
+			server.SetAdminToken(tt.adminToken)
+
+			if tt.expectedError == codes.OK {
+				mockStore.On("ListWebhookEvents", mock.Anything, mock.Anything).Return([]db.WebhookEvent{}, int64(0), nil)
+			}
+
+			_, err := server.ListWebhookEvents(tt.ctx, &pb.ListWebhookEventsRequest{})
+
+			assert.Equal(t, tt.expectedError, status.Code(err))
+			if tt.expectedError != codes.OK {
+				mockStore.AssertNotCalled(t, "ListWebhookEvents", mock.Anything, mock.Anything)
+			}
+			mockStore.AssertExpectations(t)
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	notificationsv1 "github.com/haunted-saas/notifications-service/proto/notifications/v1"
+	"github.com/haunted-saas/pkg/grpcclient"
+)
+
+// Real-time billing events sent to teams
+const (
+	EventSubscriptionActivated = "billing.subscription_activated"
+	EventSubscriptionDisputed  = "billing.subscription_disputed"
+)
+
+// TeamNotifier sends real-time events to a team's connected members
+type TeamNotifier interface {
+	NotifyTeam(ctx context.Context, teamID, eventType string, payload interface{}) error
+}
+
+// NotificationsClient sends team events through notifications-service
+type NotificationsClient struct {
+	conn   *grpcclient.Conn
+	client notificationsv1.NotificationsServiceClient
+}
+
+// NewNotificationsClient connects to notifications-service
+func NewNotificationsClient(address string, timeout time.Duration) (*NotificationsClient, error) {
+	conn, err := grpcclient.Dial("notifications-service", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NotificationsClient{
+		conn:   conn,
+		client: notificationsv1.NewNotificationsServiceClient(conn),
+	}, nil
+}
+
+// NotifyTeam broadcasts an event to the team's room
+func (c *NotificationsClient) NotifyTeam(ctx context.Context, teamID, eventType string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification payload: %w", err)
+	}
+
+	ctx, cancel := c.conn.Bound(ctx)
+	defer cancel()
+
+	_, err = c.client.BroadcastToRoom(ctx, &notificationsv1.BroadcastToRoomRequest{
+		RoomId:      "team_" + teamID,
+		EventType:   eventType,
+		PayloadJson: string(payloadJSON),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to broadcast %s to team %s: %w", eventType, teamID, err)
+	}
+	return nil
+}
+
+// Close closes the connection to notifications-service
+func (c *NotificationsClient) Close() error {
+	return c.conn.Close()
+}
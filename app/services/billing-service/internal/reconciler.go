@@ -0,0 +1,194 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/haunted-saas/billing-service/internal/db"
+	"github.com/stripe/stripe-go/v76"
+	"go.uber.org/zap"
+)
+
+// reconcileStripeClient is the subset of StripeClient the reconciler needs
+type reconcileStripeClient interface {
+	GetSubscription(ctx context.Context, subscriptionID string) (*stripe.Subscription, error)
+}
+
+// reconcileStore is the subset of db.Store the reconciler needs
+type reconcileStore interface {
+	GetSubscriptionByTeamID(ctx context.Context, teamID string) (*db.Subscription, error)
+	ListSubscriptionsToReconcile(ctx context.Context) ([]db.Subscription, error)
+	UpdateSubscriptionPeriod(ctx context.Context, subscriptionID, status string, periodStart, periodEnd time.Time) error
+}
+
+// ReconcileResult describes the outcome of reconciling one subscription
+type ReconcileResult struct {
+	Subscription *db.Subscription
+	Diverged     bool
+	Changes      []string
+}
+
+// SubscriptionReconciler periodically syncs local subscription state from
+// Stripe so that missed webhooks don't leave our records stale
+type SubscriptionReconciler struct {
+	stripeClient reconcileStripeClient
+	store        reconcileStore
+	interval     time.Duration
+	logger       *zap.Logger
+
+	stopOnce sync.Once
+	stopChan chan struct{}
+	doneChan chan struct{}
+}
+
+// NewSubscriptionReconciler creates a new reconciler. An interval of zero
+// disables the background job; ReconcileTeam still works on demand.
+func NewSubscriptionReconciler(stripeClient reconcileStripeClient, store reconcileStore, interval time.Duration, logger *zap.Logger) *SubscriptionReconciler {
+	return &SubscriptionReconciler{
+		stripeClient: stripeClient,
+		store:        store,
+		interval:     interval,
+		logger:       logger,
+		stopChan:     make(chan struct{}),
+		doneChan:     make(chan struct{}),
+	}
+}
+
+// Start begins the background reconciliation loop
+func (r *SubscriptionReconciler) Start() {
+	if r.interval <= 0 {
+		close(r.doneChan)
+		r.logger.Info("subscription reconciler disabled")
+		return
+	}
+
+	go r.run()
+	r.logger.Info("subscription reconciler started", zap.Duration("interval", r.interval))
+}
+
+// Stop stops the background loop and waits for an in-progress pass to finish
+func (r *SubscriptionReconciler) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopChan)
+	})
+	<-r.doneChan
+}
+
+func (r *SubscriptionReconciler) run() {
+	defer close(r.doneChan)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				select {
+				case <-r.stopChan:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+			r.ReconcileAll(ctx)
+			cancel()
+
+		case <-r.stopChan:
+			return
+		}
+	}
+}
+
+// ReconcileAll reconciles every non-terminal subscription and returns how many diverged
+func (r *SubscriptionReconciler) ReconcileAll(ctx context.Context) int {
+	subscriptions, err := r.store.ListSubscriptionsToReconcile(ctx)
+	if err != nil {
+		r.logger.Error("failed to list subscriptions to reconcile", zap.Error(err))
+		return 0
+	}
+
+	diverged := 0
+	for i := range subscriptions {
+		if ctx.Err() != nil {
+			break
+		}
+
+		result, err := r.reconcile(ctx, &subscriptions[i])
+		if err != nil {
+			r.logger.Error("failed to reconcile subscription",
+				zap.String("team_id", subscriptions[i].TeamID),
+				zap.String("subscription_id", subscriptions[i].StripeSubscriptionID),
+				zap.Error(err))
+			continue
+		}
+		if result.Diverged {
+			diverged++
+		}
+	}
+
+	r.logger.Info("subscription reconciliation completed",
+		zap.Int("checked", len(subscriptions)),
+		zap.Int("diverged", diverged))
+
+	return diverged
+}
+
+// ReconcileTeam reconciles the subscription belonging to a team
+func (r *SubscriptionReconciler) ReconcileTeam(ctx context.Context, teamID string) (*ReconcileResult, error) {
+	subscription, err := r.store.GetSubscriptionByTeamID(ctx, teamID)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.reconcile(ctx, subscription)
+}
+
+// reconcile fetches the live subscription from Stripe and updates the local
+// record if its status or billing period differs
+func (r *SubscriptionReconciler) reconcile(ctx context.Context, subscription *db.Subscription) (*ReconcileResult, error) {
+	stripeSub, err := r.stripeClient.GetSubscription(ctx, subscription.StripeSubscriptionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subscription from Stripe: %w", err)
+	}
+
+	result := &ReconcileResult{Subscription: subscription}
+
+	if status := string(stripeSub.Status); subscription.Status != status {
+		result.Changes = append(result.Changes, fmt.Sprintf("status: %s -> %s", subscription.Status, status))
+		subscription.Status = status
+	}
+
+	if start := time.Unix(stripeSub.CurrentPeriodStart, 0); !subscription.CurrentPeriodStart.Equal(start) {
+		result.Changes = append(result.Changes, fmt.Sprintf("current_period_start: %s -> %s",
+			subscription.CurrentPeriodStart.UTC().Format(time.RFC3339), start.UTC().Format(time.RFC3339)))
+		subscription.CurrentPeriodStart = start
+	}
+
+	if end := time.Unix(stripeSub.CurrentPeriodEnd, 0); !subscription.CurrentPeriodEnd.Equal(end) {
+		result.Changes = append(result.Changes, fmt.Sprintf("current_period_end: %s -> %s",
+			subscription.CurrentPeriodEnd.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339)))
+		subscription.CurrentPeriodEnd = end
+	}
+
+	if len(result.Changes) == 0 {
+		return result, nil
+	}
+
+	result.Diverged = true
+	r.logger.Warn("subscription diverged from Stripe",
+		zap.String("team_id", subscription.TeamID),
+		zap.String("subscription_id", subscription.StripeSubscriptionID),
+		zap.Strings("changes", result.Changes))
+
+	// Only write the reconciled columns so a concurrent webhook or RPC
+	// changing the plan or cancellation isn't overwritten with stale values
+	if err := r.store.UpdateSubscriptionPeriod(ctx, subscription.ID, subscription.Status,
+		subscription.CurrentPeriodStart, subscription.CurrentPeriodEnd); err != nil {
+		return nil, fmt.Errorf("failed to update subscription: %w", err)
+	}
+
+	return result, nil
+}
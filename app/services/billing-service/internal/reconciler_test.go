@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/haunted-saas/billing-service/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/stripe/stripe-go/v76"
+	"go.uber.org/zap"
+)
+
+func TestSubscriptionReconciler_CorrectsDivergentStatus(t *testing.T) {
+	periodStart := time.Unix(1700000000, 0)
+	periodEnd := time.Unix(1702592000, 0)
+
+	local := &db.Subscription{
+		ID:                   "sub_local_1",
+		TeamID:               "team_123",
+		Status:               "active",
+		StripeSubscriptionID: "sub_stripe_1",
+		CurrentPeriodStart:   periodStart,
+		CurrentPeriodEnd:     periodEnd,
+	}
+
+	mockStore := new(MockStore)
+	mockStripe := new(MockStripeClient)
+
+	mockStore.On("GetSubscriptionByTeamID", mock.Anything, "team_123").Return(local, nil)
+	mockStripe.On("GetSubscription", "sub_stripe_1").Return(&stripe.Subscription{
+		ID:                 "sub_stripe_1",
+		Status:             stripe.SubscriptionStatusPastDue,
+		CurrentPeriodStart: periodStart.Unix(),
+		CurrentPeriodEnd:   periodEnd.Unix(),
+	}, nil)
+	mockStore.On("UpdateSubscriptionPeriod", mock.Anything, "sub_local_1", "past_due", periodStart, periodEnd).Return(nil)
+
+	reconciler := NewSubscriptionReconciler(mockStripe, mockStore, 0, zap.NewNop())
+
+	result, err := reconciler.ReconcileTeam(context.Background(), "team_123")
+	require.NoError(t, err)
+
+	assert.True(t, result.Diverged)
+	assert.Equal(t, []string{"status: active -> past_due"}, result.Changes)
+	assert.Equal(t, "past_due", local.Status)
+	mockStore.AssertExpectations(t)
+	mockStripe.AssertExpectations(t)
+}
+
+func TestSubscriptionReconciler_InSyncIsNotUpdated(t *testing.T) {
+	periodStart := time.Unix(1700000000, 0)
+	periodEnd := time.Unix(1702592000, 0)
+
+	mockStore := new(MockStore)
+	mockStripe := new(MockStripeClient)
+
+	mockStore.On("ListSubscriptionsToReconcile", mock.Anything).Return([]db.Subscription{{
+		TeamID:               "team_123",
+		Status:               "active",
+		StripeSubscriptionID: "sub_stripe_1",
+		CurrentPeriodStart:   periodStart,
+		CurrentPeriodEnd:     periodEnd,
+	}}, nil)
+	mockStripe.On("GetSubscription", "sub_stripe_1").Return(&stripe.Subscription{
+		ID:                 "sub_stripe_1",
+		Status:             stripe.SubscriptionStatusActive,
+		CurrentPeriodStart: periodStart.Unix(),
+		CurrentPeriodEnd:   periodEnd.Unix(),
+	}, nil)
+
+	reconciler := NewSubscriptionReconciler(mockStripe, mockStore, 0, zap.NewNop())
+
+	assert.Equal(t, 0, reconciler.ReconcileAll(context.Background()))
+	mockStore.AssertNotCalled(t, "UpdateSubscriptionPeriod", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
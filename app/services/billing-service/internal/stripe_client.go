@@ -1,11 +1,17 @@
 package internal
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"time"
 
+	"github.com/google/uuid"
 	"github.com/stripe/stripe-go/v76"
 	portalsession "github.com/stripe/stripe-go/v76/billingportal/session"
 	checkoutsession "github.com/stripe/stripe-go/v76/checkout/session"
+	"github.com/stripe/stripe-go/v76/charge"
 	"github.com/stripe/stripe-go/v76/customer"
 	"github.com/stripe/stripe-go/v76/invoice"
 	"github.com/stripe/stripe-go/v76/price"
@@ -16,21 +22,105 @@ import (
 
 // StripeClient wraps all Stripe SDK operations
 type StripeClient struct {
-	apiKey string
+	apiKey      string
+	retryConfig *RetryConfig
+}
+
+// RetryConfig contains retry configuration
+type RetryConfig struct {
+	MaxAttempts   int
+	InitialDelay  time.Duration
+	MaxDelay      time.Duration
+	BackoffFactor float64
+}
+
+// DefaultRetryConfig returns the default retry configuration
+func DefaultRetryConfig() *RetryConfig {
+	return &RetryConfig{
+		MaxAttempts:   3,
+		InitialDelay:  500 * time.Millisecond,
+		MaxDelay:      5 * time.Second,
+		BackoffFactor: 2.0,
+	}
 }
 
 // NewStripeClient creates a new Stripe client
 func NewStripeClient(apiKey string) *StripeClient {
 	stripe.Key = apiKey
 	return &StripeClient{
-		apiKey: apiKey,
+		apiKey:      apiKey,
+		retryConfig: DefaultRetryConfig(),
+	}
+}
+
+// withRetry runs a Stripe call, retrying transient failures with exponential backoff.
+// Callers creating resources must set an idempotency key on their params so retries
+// reuse it and Stripe never creates a duplicate. The wait between attempts ends
+// early with ctx's error if ctx is done.
+func (c *StripeClient) withRetry(ctx context.Context, fn func() error) error {
+	cfg := c.retryConfig
+	if cfg == nil {
+		cfg = DefaultRetryConfig()
+	}
+
+	var lastErr error
+	delay := cfg.InitialDelay
+
+	for attempt := 1; attempt <= cfg.MaxAttempts; attempt++ {
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		lastErr = err
+
+		if !isTransientStripeError(err) {
+			return err
+		}
+
+		// Don't retry on last attempt
+		if attempt == cfg.MaxAttempts {
+			break
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("stopped retrying: %w (last error: %v)", ctx.Err(), lastErr)
+		case <-timer.C:
+		}
+
+		// Exponential backoff
+		delay = time.Duration(float64(delay) * cfg.BackoffFactor)
+		if delay > cfg.MaxDelay {
+			delay = cfg.MaxDelay
+		}
+	}
+
+	return fmt.Errorf("max retry attempts exceeded: %w", lastErr)
+}
+
+// isTransientStripeError checks if a Stripe error is a rate limit or server error
+func isTransientStripeError(err error) bool {
+	var stripeErr *stripe.Error
+	if !errors.As(err, &stripeErr) {
+		return false
 	}
+	return stripeErr.HTTPStatusCode == http.StatusTooManyRequests ||
+		stripeErr.HTTPStatusCode >= http.StatusInternalServerError ||
+		stripeErr.Code == stripe.ErrorCodeLockTimeout
+}
+
+// newIdempotencyKey generates a key shared by all retries of a single create call
+func newIdempotencyKey() *string {
+	return stripe.String(uuid.NewString())
 }
 
 // Product Operations
 
 // CreateProduct creates a Stripe product
-func (c *StripeClient) CreateProduct(name string, metadata map[string]string) (*stripe.Product, error) {
+func (c *StripeClient) CreateProduct(ctx context.Context, name string, metadata map[string]string) (*stripe.Product, error) {
 	params := &stripe.ProductParams{
 		Name: stripe.String(name),
 	}
@@ -38,8 +128,15 @@ func (c *StripeClient) CreateProduct(name string, metadata map[string]string) (*
 	if metadata != nil {
 		params.Metadata = metadata
 	}
+	params.IdempotencyKey = newIdempotencyKey()
 	
-	return product.New(params)
+	var result *stripe.Product
+	err := c.withRetry(ctx, func() error {
+		var err error
+		result, err = product.New(params)
+		return err
+	})
+	return result, err
 }
 
 // UpdateProduct updates a Stripe product
@@ -58,7 +155,7 @@ func (c *StripeClient) UpdateProduct(productID, name string, metadata map[string
 // Price Operations
 
 // CreatePrice creates a Stripe price
-func (c *StripeClient) CreatePrice(productID string, amountCents int64, currency, interval string) (*stripe.Price, error) {
+func (c *StripeClient) CreatePrice(ctx context.Context, productID string, amountCents int64, currency, interval string) (*stripe.Price, error) {
 	params := &stripe.PriceParams{
 		Product:    stripe.String(productID),
 		UnitAmount: stripe.Int64(amountCents),
@@ -67,14 +164,21 @@ func (c *StripeClient) CreatePrice(productID string, amountCents int64, currency
 			Interval: stripe.String(interval),
 		},
 	}
+	params.IdempotencyKey = newIdempotencyKey()
 	
-	return price.New(params)
+	var result *stripe.Price
+	err := c.withRetry(ctx, func() error {
+		var err error
+		result, err = price.New(params)
+		return err
+	})
+	return result, err
 }
 
 // Customer Operations
 
 // CreateCustomer creates a Stripe customer
-func (c *StripeClient) CreateCustomer(email, teamID string, metadata map[string]string) (*stripe.Customer, error) {
+func (c *StripeClient) CreateCustomer(ctx context.Context, email, teamID string, metadata map[string]string) (*stripe.Customer, error) {
 	params := &stripe.CustomerParams{
 		Email: stripe.String(email),
 		Metadata: map[string]string{
@@ -88,8 +192,15 @@ func (c *StripeClient) CreateCustomer(email, teamID string, metadata map[string]
 			params.Metadata[k] = v
 		}
 	}
+	params.IdempotencyKey = newIdempotencyKey()
 	
-	return customer.New(params)
+	var result *stripe.Customer
+	err := c.withRetry(ctx, func() error {
+		var err error
+		result, err = customer.New(params)
+		return err
+	})
+	return result, err
 }
 
 // GetCustomer retrieves a Stripe customer
@@ -109,7 +220,7 @@ func (c *StripeClient) UpdateCustomer(customerID, email string) (*stripe.Custome
 // Checkout Session Operations
 
 // CreateCheckoutSession creates a Stripe Checkout session
-func (c *StripeClient) CreateCheckoutSession(priceID, customerID, successURL, cancelURL string, metadata map[string]string, trialDays int32) (*stripe.CheckoutSession, error) {
+func (c *StripeClient) CreateCheckoutSession(ctx context.Context, priceID, customerID, successURL, cancelURL string, metadata map[string]string, trialDays int32) (*stripe.CheckoutSession, error) {
 	params := &stripe.CheckoutSessionParams{
 		Mode:       stripe.String(string(stripe.CheckoutSessionModeSubscription)),
 		SuccessURL: stripe.String(successURL),
@@ -139,24 +250,75 @@ func (c *StripeClient) CreateCheckoutSession(priceID, customerID, successURL, ca
 		}
 		params.SubscriptionData.TrialPeriodDays = stripe.Int64(int64(trialDays))
 	}
+	params.IdempotencyKey = newIdempotencyKey()
 	
-	return checkoutsession.New(params)
+	var result *stripe.CheckoutSession
+	err := c.withRetry(ctx, func() error {
+		var err error
+		result, err = checkoutsession.New(params)
+		return err
+	})
+	return result, err
 }
 
 // GetCheckoutSession retrieves a Stripe Checkout session
-func (c *StripeClient) GetCheckoutSession(sessionID string) (*stripe.CheckoutSession, error) {
+func (c *StripeClient) GetCheckoutSession(ctx context.Context, sessionID string) (*stripe.CheckoutSession, error) {
 	params := &stripe.CheckoutSessionParams{}
 	params.AddExpand("subscription")
 	params.AddExpand("customer")
 	
-	return checkoutsession.Get(sessionID, params)
+	var result *stripe.CheckoutSession
+	err := c.withRetry(ctx, func() error {
+		var err error
+		result, err = checkoutsession.Get(sessionID, params)
+		return err
+	})
+	return result, err
 }
 
 // Subscription Operations
 
 // GetSubscription retrieves a Stripe subscription
-func (c *StripeClient) GetSubscription(subscriptionID string) (*stripe.Subscription, error) {
-	return subscription.Get(subscriptionID, nil)
+func (c *StripeClient) GetSubscription(ctx context.Context, subscriptionID string) (*stripe.Subscription, error) {
+	var result *stripe.Subscription
+	err := c.withRetry(ctx, func() error {
+		var err error
+		result, err = subscription.Get(subscriptionID, nil)
+		return err
+	})
+	return result, err
+}
+
+// CreateTrialSubscription starts a subscription in trial without collecting
+// a payment method. If the customer hasn't added one by the end of the trial,
+// Stripe cancels the subscription instead of attempting a charge.
+func (c *StripeClient) CreateTrialSubscription(ctx context.Context, customerID, priceID string, trialDays int32, metadata map[string]string) (*stripe.Subscription, error) {
+	params := &stripe.SubscriptionParams{
+		Customer: stripe.String(customerID),
+		Items: []*stripe.SubscriptionItemsParams{
+			{Price: stripe.String(priceID)},
+		},
+		TrialPeriodDays: stripe.Int64(int64(trialDays)),
+		PaymentBehavior: stripe.String("default_incomplete"),
+		PaymentSettings: &stripe.SubscriptionPaymentSettingsParams{
+			SaveDefaultPaymentMethod: stripe.String("on_subscription"),
+		},
+		TrialSettings: &stripe.SubscriptionTrialSettingsParams{
+			EndBehavior: &stripe.SubscriptionTrialSettingsEndBehaviorParams{
+				MissingPaymentMethod: stripe.String("cancel"),
+			},
+		},
+		Metadata: metadata,
+	}
+	params.IdempotencyKey = newIdempotencyKey()
+	
+	var result *stripe.Subscription
+	err := c.withRetry(ctx, func() error {
+		var err error
+		result, err = subscription.New(params)
+		return err
+	})
+	return result, err
 }
 
 // CancelSubscription cancels a Stripe subscription
@@ -174,6 +336,22 @@ func (c *StripeClient) CancelSubscription(subscriptionID string, cancelAtPeriodE
 	return subscription.Cancel(subscriptionID, params)
 }
 
+// ScheduleCancellation sets a Stripe subscription to cancel at a specific time.
+// Setting the same cancel_at again is harmless, so the update is retried.
+func (c *StripeClient) ScheduleCancellation(ctx context.Context, subscriptionID string, cancelAt time.Time) (*stripe.Subscription, error) {
+	params := &stripe.SubscriptionParams{
+		CancelAt: stripe.Int64(cancelAt.Unix()),
+	}
+	
+	var result *stripe.Subscription
+	err := c.withRetry(ctx, func() error {
+		var err error
+		result, err = subscription.Update(subscriptionID, params)
+		return err
+	})
+	return result, err
+}
+
 // UpdateSubscription updates a Stripe subscription (e.g., change plan)
 func (c *StripeClient) UpdateSubscription(subscriptionID, newPriceID string, prorationBehavior string) (*stripe.Subscription, error) {
 	// Get current subscription to find the subscription item ID
@@ -230,13 +408,24 @@ func (c *StripeClient) GetUpcomingInvoice(customerID string) (*stripe.Invoice, e
 	return invoice.Upcoming(params)
 }
 
-// ListInvoices lists invoices for a customer
-func (c *StripeClient) ListInvoices(customerID string, limit int64) ([]*stripe.Invoice, error) {
+// ListInvoices lists invoices for a customer. A non-zero createdAfter or
+// createdBefore restricts results to invoices created in [createdAfter, createdBefore).
+func (c *StripeClient) ListInvoices(customerID string, limit int64, createdAfter, createdBefore time.Time) ([]*stripe.Invoice, error) {
 	params := &stripe.InvoiceListParams{
 		Customer: stripe.String(customerID),
 	}
 	params.Limit = stripe.Int64(limit)
 	
+	if !createdAfter.IsZero() || !createdBefore.IsZero() {
+		params.CreatedRange = &stripe.RangeQueryParams{}
+		if !createdAfter.IsZero() {
+			params.CreatedRange.GreaterThanOrEqual = createdAfter.Unix()
+		}
+		if !createdBefore.IsZero() {
+			params.CreatedRange.LesserThan = createdBefore.Unix()
+		}
+	}
+	
 	iter := invoice.List(params)
 	var invoices []*stripe.Invoice
 	
@@ -258,6 +447,17 @@ func (c *StripeClient) GetInvoice(invoiceID string) (*stripe.Invoice, error) {
 
 // Webhook Operations
 
+// GetCharge retrieves a Stripe charge
+func (c *StripeClient) GetCharge(ctx context.Context, chargeID string) (*stripe.Charge, error) {
+	var result *stripe.Charge
+	err := c.withRetry(ctx, func() error {
+		var err error
+		result, err = charge.Get(chargeID, nil)
+		return err
+	})
+	return result, err
+}
+
 // ConstructEvent constructs a Stripe event from webhook payload and signature
 func (c *StripeClient) ConstructEvent(payload []byte, signature, webhookSecret string) (stripe.Event, error) {
 	return webhook.ConstructEvent(payload, signature, webhookSecret)
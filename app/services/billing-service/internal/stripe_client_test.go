@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stripe/stripe-go/v76"
+)
+
+func newTestStripeClient() *StripeClient {
+	return &StripeClient{
+		retryConfig: &RetryConfig{
+			MaxAttempts:   3,
+			InitialDelay:  time.Millisecond,
+			MaxDelay:      5 * time.Millisecond,
+			BackoffFactor: 2.0,
+		},
+	}
+}
+
+// Test transient Stripe errors are retried until success
+func TestStripeClient_WithRetry_TransientErrorSucceeds(t *testing.T) {
+	client := newTestStripeClient()
+
+	attempts := 0
+	err := client.withRetry(context.Background(), func() error {
+		attempts++
+		if attempts < 3 {
+			return &stripe.Error{HTTPStatusCode: http.StatusServiceUnavailable}
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+}
+
+// Test retries stop after MaxAttempts
+func TestStripeClient_WithRetry_GivesUp(t *testing.T) {
+	client := newTestStripeClient()
+
+	attempts := 0
+	err := client.withRetry(context.Background(), func() error {
+		attempts++
+		return &stripe.Error{HTTPStatusCode: http.StatusTooManyRequests}
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 3, attempts)
+
+	var stripeErr *stripe.Error
+	assert.True(t, errors.As(err, &stripeErr))
+}
+
+// Test non-transient errors fail immediately
+func TestStripeClient_WithRetry_PermanentErrorNotRetried(t *testing.T) {
+	client := newTestStripeClient()
+
+	attempts := 0
+	err := client.withRetry(context.Background(), func() error {
+		attempts++
+		return &stripe.Error{HTTPStatusCode: http.StatusBadRequest, Code: stripe.ErrorCodeParameterInvalidEmpty}
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+}
+
+// Test a cancelled context stops the backoff instead of sleeping through it
+func TestStripeClient_WithRetry_StopsWhenContextDone(t *testing.T) {
+	client := &StripeClient{
+		retryConfig: &RetryConfig{
+			MaxAttempts:   3,
+			InitialDelay:  time.Hour,
+			MaxDelay:      time.Hour,
+			BackoffFactor: 2.0,
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	done := make(chan error, 1)
+	go func() {
+		done <- client.withRetry(ctx, func() error {
+			attempts++
+			return &stripe.Error{HTTPStatusCode: http.StatusServiceUnavailable}
+		})
+	}()
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, 1, attempts)
+	case <-time.After(time.Second):
+		t.Fatal("expected withRetry to return once the context was cancelled")
+	}
+}
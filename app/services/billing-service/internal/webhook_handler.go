@@ -3,6 +3,7 @@ package internal
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -10,27 +11,60 @@ import (
 
 	"github.com/haunted-saas/billing-service/internal/db"
 	"github.com/stripe/stripe-go/v76"
+	"github.com/stripe/stripe-go/v76/webhook"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 )
 
+// webhookStripeClient is the subset of StripeClient the webhook handler needs
+type webhookStripeClient interface {
+	ConstructEvent(payload []byte, signature, webhookSecret string) (stripe.Event, error)
+	GetCharge(ctx context.Context, chargeID string) (*stripe.Charge, error)
+	GetSubscription(ctx context.Context, subscriptionID string) (*stripe.Subscription, error)
+}
+
+// webhookStore is the subset of db.Store the webhook handler needs
+type webhookStore interface {
+	IsWebhookEventProcessed(ctx context.Context, stripeEventID string) (bool, error)
+	CreateWebhookEvent(ctx context.Context, event *db.WebhookEvent) error
+	MarkWebhookEventProcessed(ctx context.Context, stripeEventID string, processingError *string) error
+	GetPlanByStripePriceID(ctx context.Context, stripePriceID string) (*db.Plan, error)
+	CreateSubscription(ctx context.Context, subscription *db.Subscription) error
+	GetSubscriptionByTeamID(ctx context.Context, teamID string) (*db.Subscription, error)
+	GetSubscriptionByStripeID(ctx context.Context, stripeSubID string) (*db.Subscription, error)
+	GetSubscriptionByStripeCustomerID(ctx context.Context, stripeCustomerID string) (*db.Subscription, error)
+	UpdateSubscription(ctx context.Context, subscription *db.Subscription) error
+	GetTeamTrial(ctx context.Context, teamID string) (*db.TeamTrial, error)
+	CreateTeamTrial(ctx context.Context, trial *db.TeamTrial) error
+}
+
 // WebhookHandler handles Stripe webhook events
 type WebhookHandler struct {
-	stripeClient  *StripeClient
-	store         *db.Store
-	webhookSecret string
-	logger        *zap.Logger
+	stripeClient   webhookStripeClient
+	store          webhookStore
+	webhookSecrets []string
+	logger         *zap.Logger
+	notifier       TeamNotifier // Optional; nil disables real-time billing events
 }
 
-// NewWebhookHandler creates a new webhook handler
-func NewWebhookHandler(stripeClient *StripeClient, store *db.Store, webhookSecret string, logger *zap.Logger) *WebhookHandler {
+// NewWebhookHandler creates a new webhook handler. Every secret in
+// webhookSecrets is accepted, so multiple regional endpoints or a rotation
+// window can be served by the same handler.
+func NewWebhookHandler(stripeClient webhookStripeClient, store webhookStore, webhookSecrets []string, logger *zap.Logger) *WebhookHandler {
 	return &WebhookHandler{
-		stripeClient:  stripeClient,
-		store:         store,
-		webhookSecret: webhookSecret,
-		logger:        logger,
+		stripeClient:   stripeClient,
+		store:          store,
+		webhookSecrets: webhookSecrets,
+		logger:         logger,
 	}
 }
 
+// SetNotifier enables real-time events to teams, e.g. when a checkout
+// activates their subscription
+func (h *WebhookHandler) SetNotifier(notifier TeamNotifier) {
+	h.notifier = notifier
+}
+
 // HandleWebhook handles incoming Stripe webhook requests
 func (h *WebhookHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
@@ -52,7 +86,7 @@ func (h *WebhookHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 	}
 	
 	// Verify the webhook signature
-	event, err := h.stripeClient.ConstructEvent(payload, signature, h.webhookSecret)
+	event, err := h.constructEvent(payload, signature)
 	if err != nil {
 		h.logger.Error("webhook signature verification failed",
 			zap.Error(err),
@@ -88,6 +122,7 @@ func (h *WebhookHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 		EventType:     string(event.Type),
 		Processed:     false,
 		ReceivedAt:    time.Now(),
+		Payload:       payload,
 	}
 	
 	if err := h.store.CreateWebhookEvent(ctx, webhookEvent); err != nil {
@@ -120,6 +155,29 @@ func (h *WebhookHandler) HandleWebhook(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(map[string]string{"status": "received"})
 }
 
+// constructEvent verifies the payload against each configured secret until one matches
+func (h *WebhookHandler) constructEvent(payload []byte, signature string) (stripe.Event, error) {
+	if len(h.webhookSecrets) == 0 {
+		return stripe.Event{}, fmt.Errorf("no webhook secrets configured")
+	}
+
+	var lastErr error
+	for _, secret := range h.webhookSecrets {
+		event, err := h.stripeClient.ConstructEvent(payload, signature, secret)
+		if err == nil {
+			return event, nil
+		}
+		lastErr = err
+
+		// Only a signature mismatch means another secret might still verify
+		if !errors.Is(err, webhook.ErrNoValidSignature) {
+			break
+		}
+	}
+
+	return stripe.Event{}, lastErr
+}
+
 // processEvent processes a Stripe event
 func (h *WebhookHandler) processEvent(ctx context.Context, event stripe.Event) error {
 	switch event.Type {
@@ -144,6 +202,9 @@ func (h *WebhookHandler) processEvent(ctx context.Context, event stripe.Event) e
 	case "customer.subscription.trial_will_end":
 		return h.handleTrialWillEnd(ctx, event)
 	
+	case "charge.dispute.created":
+		return h.handleChargeDisputeCreated(ctx, event)
+	
 	default:
 		h.logger.Info("unhandled webhook event type",
 			zap.String("event_type", string(event.Type)))
@@ -170,7 +231,7 @@ func (h *WebhookHandler) handleCheckoutSessionCompleted(ctx context.Context, eve
 		zap.String("subscription_id", session.Subscription.ID))
 	
 	// Get the subscription details from Stripe
-	stripeSub, err := h.stripeClient.GetSubscription(session.Subscription.ID)
+	stripeSub, err := h.stripeClient.GetSubscription(ctx, session.Subscription.ID)
 	if err != nil {
 		return fmt.Errorf("failed to get subscription from Stripe: %w", err)
 	}
@@ -212,17 +273,84 @@ func (h *WebhookHandler) handleCheckoutSessionCompleted(ctx context.Context, eve
 		}
 	}
 	
+	if stripeSub.TrialEnd > 0 {
+		if err := h.recordTeamTrial(ctx, teamID, plan.ID, stripeSub); err != nil {
+			return err
+		}
+	}
+	
 	h.logger.Info("subscription provisioned",
 		zap.String("team_id", teamID),
 		zap.String("plan_id", plan.ID),
 		zap.String("status", subscription.Status))
 	
+	h.notifySubscriptionActivated(ctx, subscription, plan)
+	
 	// TODO: Call feature-flags-service or user-auth-service to provision access
 	// This would be a gRPC call to enable features for the team
 	
 	return nil
 }
 
+// recordTeamTrial records that a checkout started the team's trial, so
+// later checkouts and StartTrial don't grant another. A team that already
+// has a record keeps it.
+func (h *WebhookHandler) recordTeamTrial(ctx context.Context, teamID, planID string, stripeSub *stripe.Subscription) error {
+	_, err := h.store.GetTeamTrial(ctx, teamID)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("failed to check trial history: %w", err)
+	}
+	
+	trial := &db.TeamTrial{
+		TeamID:               teamID,
+		PlanID:               planID,
+		StripeSubscriptionID: &stripeSub.ID,
+		StartedAt:            time.Unix(stripeSub.TrialStart, 0),
+	}
+	if stripeSub.TrialStart == 0 {
+		trial.StartedAt = time.Now()
+	}
+	if err := h.store.CreateTeamTrial(ctx, trial); err != nil {
+		return fmt.Errorf("failed to record trial: %w", err)
+	}
+	return nil
+}
+
+// notifySubscriptionActivated tells the team's connected members that their
+// plan is live. Failures are logged; the subscription is already provisioned.
+func (h *WebhookHandler) notifySubscriptionActivated(ctx context.Context, subscription *db.Subscription, plan *db.Plan) {
+	if h.notifier == nil {
+		return
+	}
+	
+	payload := map[string]interface{}{
+		"team_id":            subscription.TeamID,
+		"subscription_id":    subscription.ID,
+		"status":             subscription.Status,
+		"current_period_end": subscription.CurrentPeriodEnd.Format(time.RFC3339),
+		"plan": map[string]interface{}{
+			"id":               plan.ID,
+			"name":             plan.Name,
+			"price_cents":      plan.PriceCents,
+			"currency":         plan.Currency,
+			"billing_interval": plan.BillingInterval,
+			"features":         plan.Features,
+		},
+	}
+	if subscription.TrialEnd != nil {
+		payload["trial_end"] = subscription.TrialEnd.Format(time.RFC3339)
+	}
+	
+	if err := h.notifier.NotifyTeam(ctx, subscription.TeamID, EventSubscriptionActivated, payload); err != nil {
+		h.logger.Warn("failed to send subscription activated notification",
+			zap.String("team_id", subscription.TeamID),
+			zap.Error(err))
+	}
+}
+
 // handleSubscriptionCreated handles customer.subscription.created events
 func (h *WebhookHandler) handleSubscriptionCreated(ctx context.Context, event stripe.Event) error {
 	var stripeSub stripe.Subscription
@@ -390,3 +518,85 @@ func (h *WebhookHandler) handleTrialWillEnd(ctx context.Context, event stripe.Ev
 	
 	return nil
 }
+
+// handleChargeDisputeCreated handles charge.dispute.created events
+func (h *WebhookHandler) handleChargeDisputeCreated(ctx context.Context, event stripe.Event) error {
+	var dispute stripe.Dispute
+	if err := json.Unmarshal(event.Data.Raw, &dispute); err != nil {
+		return fmt.Errorf("failed to unmarshal dispute: %w", err)
+	}
+	if dispute.Charge == nil {
+		return fmt.Errorf("dispute %s has no charge", dispute.ID)
+	}
+	
+	// The charge is usually delivered unexpanded, so fetch it for the customer
+	charge := dispute.Charge
+	if charge.Customer == nil {
+		fetched, err := h.stripeClient.GetCharge(ctx, charge.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get disputed charge: %w", err)
+		}
+		charge = fetched
+	}
+	if charge.Customer == nil {
+		h.logger.Warn("disputed charge has no customer",
+			zap.String("dispute_id", dispute.ID),
+			zap.String("charge_id", charge.ID))
+		return nil
+	}
+	
+	subscription, err := h.store.GetSubscriptionByStripeCustomerID(ctx, charge.Customer.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		h.logger.Warn("no subscription found for disputed charge",
+			zap.String("dispute_id", dispute.ID),
+			zap.String("customer_id", charge.Customer.ID))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get subscription for disputed charge: %w", err)
+	}
+	
+	disputedAt := time.Unix(dispute.Created, 0)
+	subscription.DisputeID = &dispute.ID
+	subscription.DisputedAt = &disputedAt
+	if err := h.store.UpdateSubscription(ctx, subscription); err != nil {
+		return fmt.Errorf("failed to update subscription: %w", err)
+	}
+	
+	h.logger.Warn("audit event",
+		zap.String("event_type", "billing.dispute.created"),
+		zap.String("team_id", subscription.TeamID),
+		zap.String("subscription_id", subscription.ID),
+		zap.String("dispute_id", dispute.ID),
+		zap.String("charge_id", charge.ID),
+		zap.Int64("amount", dispute.Amount),
+		zap.String("reason", string(dispute.Reason)))
+	
+	h.notifySubscriptionDisputed(ctx, subscription, &dispute)
+	
+	return nil
+}
+
+// notifySubscriptionDisputed tells the team's connected members that a
+// payment was disputed. Failures are logged; the dispute is already recorded.
+func (h *WebhookHandler) notifySubscriptionDisputed(ctx context.Context, subscription *db.Subscription, dispute *stripe.Dispute) {
+	if h.notifier == nil {
+		return
+	}
+	
+	payload := map[string]interface{}{
+		"team_id":         subscription.TeamID,
+		"subscription_id": subscription.ID,
+		"dispute_id":      dispute.ID,
+		"amount":          dispute.Amount,
+		"currency":        dispute.Currency,
+		"reason":          dispute.Reason,
+		"disputed_at":     subscription.DisputedAt.Format(time.RFC3339),
+	}
+	
+	if err := h.notifier.NotifyTeam(ctx, subscription.TeamID, EventSubscriptionDisputed, payload); err != nil {
+		h.logger.Warn("failed to send subscription disputed notification",
+			zap.String("team_id", subscription.TeamID),
+			zap.Error(err))
+	}
+}
@@ -0,0 +1,203 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/haunted-saas/billing-service/internal/db"
+	"github.com/stripe/stripe-go/v76"
+	"go.uber.org/zap"
+)
+
+// webhookRetryBatchSize bounds how many failed events one pass re-processes
+const webhookRetryBatchSize = 100
+
+// maxWebhookRetryBackoff caps the exponential delay between retries
+const maxWebhookRetryBackoff = time.Hour
+
+// webhookRetryClaim is how long claimed events are hidden from other
+// replicas' retriers while this one re-processes them
+const webhookRetryClaim = 10 * time.Minute
+
+// webhookRetryStore is the subset of db.Store the retrier needs
+type webhookRetryStore interface {
+	ClaimWebhookEventsToRetry(ctx context.Context, maxRetries int, now, claimUntil time.Time, limit int) ([]db.WebhookEvent, error)
+	RecordWebhookEventRetry(ctx context.Context, stripeEventID string, processingError *string, nextRetryAt *time.Time) error
+}
+
+// webhookEventProcessor applies a Stripe event, as WebhookHandler does for
+// live deliveries
+type webhookEventProcessor interface {
+	processEvent(ctx context.Context, event stripe.Event) error
+}
+
+// WebhookRetrier periodically re-processes webhook events that failed, so a
+// transient provisioning error doesn't leave a paid checkout unprovisioned.
+// Stripe is always acknowledged with 200, so it won't redeliver them itself.
+type WebhookRetrier struct {
+	processor  webhookEventProcessor
+	store      webhookRetryStore
+	interval   time.Duration
+	maxRetries int
+	backoff    time.Duration
+	logger     *zap.Logger
+
+	stopOnce sync.Once
+	stopChan chan struct{}
+	doneChan chan struct{}
+}
+
+// NewWebhookRetrier creates a new retrier. Each event is retried at most
+// maxRetries times, waiting backoff after the first failed retry and doubling
+// after each one. An interval of zero disables the background job.
+func NewWebhookRetrier(processor webhookEventProcessor, store webhookRetryStore, interval time.Duration, maxRetries int, backoff time.Duration, logger *zap.Logger) *WebhookRetrier {
+	return &WebhookRetrier{
+		processor:  processor,
+		store:      store,
+		interval:   interval,
+		maxRetries: maxRetries,
+		backoff:    backoff,
+		logger:     logger,
+		stopChan:   make(chan struct{}),
+		doneChan:   make(chan struct{}),
+	}
+}
+
+// Start begins the background retry loop
+func (r *WebhookRetrier) Start() {
+	if r.interval <= 0 || r.maxRetries <= 0 {
+		close(r.doneChan)
+		r.logger.Info("webhook retrier disabled")
+		return
+	}
+
+	go r.run()
+	r.logger.Info("webhook retrier started",
+		zap.Duration("interval", r.interval),
+		zap.Int("max_retries", r.maxRetries))
+}
+
+// Stop stops the background loop and waits for an in-progress pass to finish
+func (r *WebhookRetrier) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.stopChan)
+	})
+	<-r.doneChan
+}
+
+func (r *WebhookRetrier) run() {
+	defer close(r.doneChan)
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ctx, cancel := context.WithCancel(context.Background())
+			go func() {
+				select {
+				case <-r.stopChan:
+					cancel()
+				case <-ctx.Done():
+				}
+			}()
+			r.RetryFailed(ctx, time.Now())
+			cancel()
+
+		case <-r.stopChan:
+			return
+		}
+	}
+}
+
+// RetryFailed re-processes every failed event due by now and returns how
+// many succeeded. Events are claimed first, so replicas running at the same
+// time don't process the same event twice.
+func (r *WebhookRetrier) RetryFailed(ctx context.Context, now time.Time) int {
+	events, err := r.store.ClaimWebhookEventsToRetry(ctx, r.maxRetries, now, now.Add(webhookRetryClaim), webhookRetryBatchSize)
+	if err != nil {
+		r.logger.Error("failed to list webhook events to retry", zap.Error(err))
+		return 0
+	}
+
+	succeeded := 0
+	for i := range events {
+		if ctx.Err() != nil {
+			break
+		}
+		if r.retry(ctx, &events[i], now) {
+			succeeded++
+		}
+	}
+
+	if len(events) > 0 {
+		r.logger.Info("webhook retry pass completed",
+			zap.Int("retried", len(events)),
+			zap.Int("succeeded", succeeded))
+	}
+
+	return succeeded
+}
+
+// retry re-runs one stored event and records the outcome, scheduling the
+// next attempt on failure
+func (r *WebhookRetrier) retry(ctx context.Context, record *db.WebhookEvent, now time.Time) bool {
+	attempt := record.RetryCount + 1
+
+	var processingError *string
+	var nextRetryAt *time.Time
+	if err := r.reprocess(ctx, record); err != nil {
+		errMsg := err.Error()
+		processingError = &errMsg
+
+		if attempt < r.maxRetries {
+			next := now.Add(r.backoffFor(attempt))
+			nextRetryAt = &next
+		}
+
+		r.logger.Warn("webhook event retry failed",
+			zap.String("event_id", record.StripeEventID),
+			zap.String("event_type", record.EventType),
+			zap.Int("attempt", attempt),
+			zap.Bool("exhausted", nextRetryAt == nil),
+			zap.Error(err))
+	} else {
+		r.logger.Info("webhook event retry succeeded",
+			zap.String("event_id", record.StripeEventID),
+			zap.String("event_type", record.EventType),
+			zap.Int("attempt", attempt))
+	}
+
+	if err := r.store.RecordWebhookEventRetry(ctx, record.StripeEventID, processingError, nextRetryAt); err != nil {
+		r.logger.Error("failed to record webhook event retry",
+			zap.String("event_id", record.StripeEventID),
+			zap.Error(err))
+	}
+
+	return processingError == nil
+}
+
+func (r *WebhookRetrier) reprocess(ctx context.Context, record *db.WebhookEvent) error {
+	var event stripe.Event
+	if err := json.Unmarshal(record.Payload, &event); err != nil {
+		return fmt.Errorf("failed to decode stored webhook payload: %w", err)
+	}
+
+	return r.processor.processEvent(ctx, event)
+}
+
+// backoffFor returns the delay after the given failed attempt
+func (r *WebhookRetrier) backoffFor(attempt int) time.Duration {
+	delay := r.backoff
+	for i := 1; i < attempt && delay < maxWebhookRetryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxWebhookRetryBackoff {
+		delay = maxWebhookRetryBackoff
+	}
+	return delay
+}
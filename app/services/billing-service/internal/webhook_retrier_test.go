@@ -0,0 +1,108 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/haunted-saas/billing-service/internal/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stripe/stripe-go/v76"
+	"go.uber.org/zap"
+)
+
+// flakyProcessor fails the first failures calls and succeeds afterwards
+type flakyProcessor struct {
+	failures int
+	events   []stripe.Event
+}
+
+func (p *flakyProcessor) processEvent(ctx context.Context, event stripe.Event) error {
+	p.events = append(p.events, event)
+	if len(p.events) <= p.failures {
+		return errors.New("database unavailable")
+	}
+	return nil
+}
+
+func TestWebhookRetrier_RetriesUntilSuccessful(t *testing.T) {
+	base := time.Unix(1700000000, 0)
+	backoff := time.Minute
+	failedErr := "database unavailable"
+
+	record := db.WebhookEvent{
+		StripeEventID:   "evt_test_123",
+		EventType:       "checkout.session.completed",
+		Processed:       true,
+		ProcessingError: &failedErr,
+		Payload:         []byte(`{"id": "evt_test_123", "type": "checkout.session.completed", "data": {"object": {"id": "cs_test_123"}}}`),
+	}
+
+	mockStore := new(MockStore)
+	processor := &flakyProcessor{failures: 1}
+	retrier := NewWebhookRetrier(processor, mockStore, 0, 3, backoff, zap.NewNop())
+
+	// First retry fails again and is rescheduled after the backoff
+	mockStore.On("ClaimWebhookEventsToRetry", mock.Anything, 3, base, base.Add(webhookRetryClaim), webhookRetryBatchSize).
+		Return([]db.WebhookEvent{record}, nil).Once()
+	mockStore.On("RecordWebhookEventRetry", mock.Anything, "evt_test_123",
+		mock.MatchedBy(func(processingError *string) bool {
+			return processingError != nil && *processingError == "database unavailable"
+		}),
+		mock.MatchedBy(func(nextRetryAt *time.Time) bool {
+			return nextRetryAt != nil && nextRetryAt.Equal(base.Add(backoff))
+		})).Return(nil).Once()
+
+	assert.Equal(t, 0, retrier.RetryFailed(context.Background(), base))
+
+	// Second retry succeeds and clears the error
+	record.RetryCount = 1
+	later := base.Add(backoff)
+	mockStore.On("ClaimWebhookEventsToRetry", mock.Anything, 3, later, later.Add(webhookRetryClaim), webhookRetryBatchSize).
+		Return([]db.WebhookEvent{record}, nil).Once()
+	mockStore.On("RecordWebhookEventRetry", mock.Anything, "evt_test_123", (*string)(nil), (*time.Time)(nil)).
+		Return(nil).Once()
+
+	assert.Equal(t, 1, retrier.RetryFailed(context.Background(), later))
+
+	if assert.Len(t, processor.events, 2) {
+		assert.Equal(t, "evt_test_123", processor.events[1].ID)
+		assert.Equal(t, stripe.EventType("checkout.session.completed"), processor.events[1].Type)
+		assert.JSONEq(t, `{"id": "cs_test_123"}`, string(processor.events[1].Data.Raw))
+	}
+	mockStore.AssertExpectations(t)
+}
+
+func TestWebhookRetrier_StopsAfterMaxRetries(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	failedErr := "database unavailable"
+
+	mockStore := new(MockStore)
+	processor := &flakyProcessor{failures: 10}
+	retrier := NewWebhookRetrier(processor, mockStore, 0, 3, time.Minute, zap.NewNop())
+
+	mockStore.On("ClaimWebhookEventsToRetry", mock.Anything, 3, now, now.Add(webhookRetryClaim), webhookRetryBatchSize).
+		Return([]db.WebhookEvent{{
+			StripeEventID:   "evt_test_123",
+			EventType:       "checkout.session.completed",
+			ProcessingError: &failedErr,
+			RetryCount:      2,
+			Payload:         []byte(`{"id": "evt_test_123", "type": "checkout.session.completed"}`),
+		}}, nil)
+	mockStore.On("RecordWebhookEventRetry", mock.Anything, "evt_test_123", mock.AnythingOfType("*string"), (*time.Time)(nil)).
+		Return(nil)
+
+	assert.Equal(t, 0, retrier.RetryFailed(context.Background(), now))
+	mockStore.AssertExpectations(t)
+}
+
+func TestWebhookRetrier_BackoffDoublesUpToCap(t *testing.T) {
+	retrier := NewWebhookRetrier(nil, nil, 0, 10, time.Minute, zap.NewNop())
+
+	assert.Equal(t, time.Minute, retrier.backoffFor(1))
+	assert.Equal(t, 2*time.Minute, retrier.backoffFor(2))
+	assert.Equal(t, 4*time.Minute, retrier.backoffFor(3))
+	assert.Equal(t, maxWebhookRetryBackoff, retrier.backoffFor(10))
+}
@@ -0,0 +1,5 @@
+-- Track payment disputes (chargebacks) against a subscription
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS dispute_id VARCHAR(255);
+ALTER TABLE subscriptions ADD COLUMN IF NOT EXISTS disputed_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_disputed ON subscriptions(disputed_at) WHERE disputed_at IS NOT NULL;
@@ -0,0 +1,7 @@
+-- Track which teams have used their free trial (one per team)
+CREATE TABLE IF NOT EXISTS team_trials (
+    team_id UUID PRIMARY KEY,
+    plan_id UUID NOT NULL REFERENCES plans(id),
+    stripe_subscription_id VARCHAR(255),
+    started_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
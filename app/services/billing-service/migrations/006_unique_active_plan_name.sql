@@ -0,0 +1,2 @@
+-- Active plan names are unique (case-insensitive); archived plans free their name
+CREATE UNIQUE INDEX IF NOT EXISTS idx_plans_active_name ON plans (LOWER(name)) WHERE is_active = true;
@@ -0,0 +1,6 @@
+-- Keep the verified payload so failed events can be re-processed, and track retries
+ALTER TABLE webhook_events ADD COLUMN IF NOT EXISTS payload JSONB;
+ALTER TABLE webhook_events ADD COLUMN IF NOT EXISTS retry_count INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE webhook_events ADD COLUMN IF NOT EXISTS next_retry_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_webhook_events_retry ON webhook_events(next_retry_at) WHERE processing_error IS NOT NULL;
@@ -13,6 +13,8 @@ import (
 	"github.com/haunted-saas/feature-flags-service/internal"
 	"github.com/haunted-saas/feature-flags-service/internal/config"
 	pb "github.com/haunted-saas/feature-flags-service/proto/featureflags/v1"
+	"github.com/haunted-saas/pkg/deadline"
+	"github.com/haunted-saas/pkg/shutdown"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc"
@@ -29,7 +31,7 @@ func main() {
 	}
 
 	// Initialize logger
-	logger, err := initLogger(cfg.Logging.Level, cfg.Logging.Format)
+	logger, err := initLogger(cfg.Logging.Level, cfg.Logging.Format, cfg.Logging.Sampling.Zap())
 	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
@@ -41,6 +43,12 @@ func main() {
 		zap.String("app_name", cfg.Unleash.AppName),
 		zap.Duration("refresh_interval", cfg.Unleash.RefreshInterval))
 
+	// Parse degraded-mode feature defaults
+	featureDefaults, err := internal.ParseFeatureDefaults(cfg.Unleash.FeatureDefaults)
+	if err != nil {
+		logger.Fatal("Invalid FEATURE_DEFAULTS", zap.Error(err))
+	}
+
 	// Initialize Unleash client (HIGH PRIORITY - SDK initialization)
 	unleashConfig := &internal.UnleashConfig{
 		ServerURL:       cfg.Unleash.ServerURL,
@@ -50,6 +58,10 @@ func main() {
 		RefreshInterval: cfg.Unleash.RefreshInterval,
 		MetricsInterval: cfg.Unleash.MetricsInterval,
 		DisableMetrics:  cfg.Unleash.DisableMetrics,
+		AdminAPIToken:   cfg.Unleash.AdminAPIToken,
+		Project:         cfg.Unleash.Project,
+		Environment:     cfg.Unleash.Environment,
+		Defaults:        featureDefaults,
 	}
 
 	unleashClient, err := internal.NewUnleashClient(unleashConfig, logger)
@@ -71,11 +83,16 @@ func main() {
 
 	// Initialize gRPC server
 	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(loggingInterceptor(logger)),
+		grpc.ChainUnaryInterceptor(
+			loggingInterceptor(logger),
+			deadline.UnaryServerInterceptor(cfg.Server.DefaultDeadline),
+		),
 	)
 
 	// Register feature flags service
 	featureFlagsService := internal.NewFeatureFlagsServer(unleashClient, logger)
+	featureFlagsService.SetEvaluationTimeout(cfg.Server.EvaluationTimeout)
+	featureFlagsService.SetAdminToken(cfg.Server.AdminAPIToken)
 	pb.RegisterFeatureFlagsServiceServer(grpcServer, featureFlagsService)
 
 	// Register health check
@@ -108,14 +125,17 @@ func main() {
 	logger.Info("Shutting down server...")
 
 	// Graceful shutdown
-	grpcServer.GracefulStop()
+	if !shutdown.DrainGRPC(grpcServer, cfg.Server.DrainTimeout) {
+		logger.Warn("In-flight requests did not finish in time, forced gRPC server stop",
+			zap.Duration("timeout", cfg.Server.DrainTimeout))
+	}
 	logger.Info("✓ gRPC server stopped")
 
 	logger.Info("Shutdown complete")
 }
 
-// initLogger initializes the logger
-func initLogger(level, format string) (*zap.Logger, error) {
+// initLogger initializes the logger. A nil sampling config disables sampling.
+func initLogger(level, format string, sampling *zap.SamplingConfig) (*zap.Logger, error) {
 	var zapLevel zapcore.Level
 	switch level {
 	case "debug":
@@ -132,6 +152,7 @@ func initLogger(level, format string) (*zap.Logger, error) {
 
 	config := zap.NewProductionConfig()
 	config.Level = zap.NewAtomicLevelAt(zapLevel)
+	config.Sampling = sampling
 
 	if format == "console" {
 		config.Encoding = "console"
@@ -4,6 +4,7 @@ go 1.21
 
 require (
 	github.com/Unleash/unleash-client-go/v4 v4.0.1
+	github.com/haunted-saas/pkg v0.0.0
 	go.uber.org/zap v1.26.0
 	google.golang.org/grpc v1.60.1
 	google.golang.org/protobuf v1.32.0
@@ -11,9 +12,14 @@ require (
 
 require (
 	github.com/golang/protobuf v1.5.3 // indirect
+	github.com/jinzhu/inflection v1.0.0 // indirect
+	github.com/jinzhu/now v1.1.5 // indirect
 	go.uber.org/multierr v1.11.0 // indirect
 	golang.org/x/net v0.20.0 // indirect
 	golang.org/x/sys v0.16.0 // indirect
 	golang.org/x/text v0.14.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20231212172506-995d672761c0 // indirect
+	gorm.io/gorm v1.25.5 // indirect
 )
+
+replace github.com/haunted-saas/pkg => ../../pkg
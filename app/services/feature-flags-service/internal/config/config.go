@@ -5,6 +5,8 @@ import (
 	"os"
 	"strconv"
 	"time"
+
+	"github.com/haunted-saas/pkg/logsampling"
 )
 
 // Config holds the service configuration
@@ -16,8 +18,12 @@ type Config struct {
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
-	GRPCPort int
-	Host     string
+	GRPCPort          int
+	Host              string
+	EvaluationTimeout time.Duration // 0 disables the per-request guard
+	DefaultDeadline   time.Duration // Applied to requests that arrive without a deadline (0 disables)
+	DrainTimeout      time.Duration // Bounds the wait for in-flight RPCs on shutdown (0 waits indefinitely)
+	AdminAPIToken     string        // Required by admin RPCs as x-admin-token (empty disables them)
 }
 
 // UnleashConfig holds Unleash SDK configuration
@@ -29,20 +35,29 @@ type UnleashConfig struct {
 	RefreshInterval time.Duration
 	MetricsInterval time.Duration
 	DisableMetrics  bool
+	AdminAPIToken   string
+	Project         string
+	Environment     string
+	FeatureDefaults string
 }
 
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
-	Level  string
-	Format string
+	Level    string
+	Format   string
+	Sampling logsampling.Config
 }
 
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	cfg := &Config{
 		Server: ServerConfig{
-			GRPCPort: getEnvInt("GRPC_PORT", 50056),
-			Host:     getEnv("HOST", "0.0.0.0"),
+			GRPCPort:          getEnvInt("GRPC_PORT", 50056),
+			Host:              getEnv("HOST", "0.0.0.0"),
+			EvaluationTimeout: time.Duration(getEnvInt("EVALUATION_TIMEOUT_MS", 100)) * time.Millisecond,
+			DefaultDeadline:   time.Duration(getEnvInt("DEFAULT_REQUEST_DEADLINE_SECONDS", 30)) * time.Second,
+			DrainTimeout:      time.Duration(getEnvInt("GRPC_DRAIN_TIMEOUT_SECONDS", 15)) * time.Second,
+			AdminAPIToken:     getEnv("ADMIN_API_TOKEN", ""),
 		},
 		Unleash: UnleashConfig{
 			ServerURL:       getEnv("UNLEASH_SERVER_URL", ""),
@@ -52,10 +67,15 @@ func Load() (*Config, error) {
 			RefreshInterval: time.Duration(getEnvInt("UNLEASH_REFRESH_INTERVAL_SECONDS", 10)) * time.Second,
 			MetricsInterval: time.Duration(getEnvInt("UNLEASH_METRICS_INTERVAL_SECONDS", 60)) * time.Second,
 			DisableMetrics:  getEnvBool("UNLEASH_DISABLE_METRICS", false),
+			AdminAPIToken:   getEnv("UNLEASH_ADMIN_API_TOKEN", ""),
+			Project:         getEnv("UNLEASH_PROJECT", "default"),
+			Environment:     getEnv("UNLEASH_ENVIRONMENT", "development"),
+			FeatureDefaults: getEnv("FEATURE_DEFAULTS", ""),
 		},
 		Logging: LoggingConfig{
-			Level:  getEnv("LOG_LEVEL", "info"),
-			Format: getEnv("LOG_FORMAT", "json"),
+			Level:    getEnv("LOG_LEVEL", "info"),
+			Format:   getEnv("LOG_FORMAT", "json"),
+			Sampling: logsampling.FromEnv(),
 		},
 	}
 
@@ -84,11 +104,28 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("UNLEASH_REFRESH_INTERVAL_SECONDS must be at least 1")
 	}
 
+	// Validate evaluation timeout
+	if c.Server.EvaluationTimeout < 0 {
+		return fmt.Errorf("EVALUATION_TIMEOUT_MS cannot be negative")
+	}
+
 	// Validate metrics interval
 	if c.Unleash.MetricsInterval < 10*time.Second {
 		return fmt.Errorf("UNLEASH_METRICS_INTERVAL_SECONDS must be at least 10")
 	}
 
+	if err := c.Logging.Sampling.Validate(); err != nil {
+		return err
+	}
+
+	if c.Server.DefaultDeadline < 0 {
+		return fmt.Errorf("DEFAULT_REQUEST_DEADLINE_SECONDS cannot be negative")
+	}
+
+	if c.Server.DrainTimeout < 0 {
+		return fmt.Errorf("GRPC_DRAIN_TIMEOUT_SECONDS cannot be negative")
+	}
+
 	return nil
 }
 
@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"strings"
+)
+
+// FeatureDefault is the fallback evaluation used when Unleash is degraded
+type FeatureDefault struct {
+	Enabled        bool
+	RolloutPercent int // 0-100; only used when IsRollout is true
+	IsRollout      bool
+}
+
+// ParseFeatureDefaults parses "name=true,other=false,beta=30%" into feature defaults
+func ParseFeatureDefaults(raw string) (map[string]FeatureDefault, error) {
+	defaults := make(map[string]FeatureDefault)
+	if strings.TrimSpace(raw) == "" {
+		return defaults, nil
+	}
+
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf("invalid feature default %q: expected name=value", entry)
+		}
+		name := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+
+		if strings.HasSuffix(value, "%") {
+			percent, err := strconv.Atoi(strings.TrimSuffix(value, "%"))
+			if err != nil || percent < 0 || percent > 100 {
+				return nil, fmt.Errorf("invalid rollout percentage for %q: %s", name, value)
+			}
+			defaults[name] = FeatureDefault{RolloutPercent: percent, IsRollout: true}
+			continue
+		}
+
+		enabled, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default for %q: %s", name, value)
+		}
+		defaults[name] = FeatureDefault{Enabled: enabled}
+	}
+
+	return defaults, nil
+}
+
+// Evaluate returns whether the feature is on for the given stickiness ID (user or session).
+// Rollouts without a stickiness ID evaluate to false.
+func (d FeatureDefault) Evaluate(featureKey, stickinessID string) bool {
+	enabled, _ := d.evaluate(featureKey, stickinessID)
+	return enabled
+}
+
+// evaluate is Evaluate that also returns the rollout bucket the ID landed in,
+// or -1 when no bucket was computed
+func (d FeatureDefault) evaluate(featureKey, stickinessID string) (enabled bool, bucket int) {
+	if !d.IsRollout {
+		return d.Enabled, -1
+	}
+	if stickinessID == "" {
+		return false, -1
+	}
+	bucket = rolloutBucket(featureKey, stickinessID)
+	return bucket < d.RolloutPercent, bucket
+}
+
+// rolloutBucket maps a feature and ID to a stable bucket in [0, 100).
+// The feature key is part of the hash so users land in different buckets per feature.
+func rolloutBucket(featureKey, stickinessID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(featureKey + ":" + stickinessID))
+	return int(h.Sum32() % 100)
+}
@@ -0,0 +1,162 @@
+package internal
+
+import (
+	"fmt"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestParseFeatureDefaults(t *testing.T) {
+	defaults, err := ParseFeatureDefaults("new-dashboard=true, legacy-export=false,beta-search=30%")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if d := defaults["new-dashboard"]; !d.Enabled || d.IsRollout {
+		t.Errorf("unexpected default for new-dashboard: %+v", d)
+	}
+	if d := defaults["legacy-export"]; d.Enabled || d.IsRollout {
+		t.Errorf("unexpected default for legacy-export: %+v", d)
+	}
+	if d := defaults["beta-search"]; !d.IsRollout || d.RolloutPercent != 30 {
+		t.Errorf("unexpected default for beta-search: %+v", d)
+	}
+
+	for _, raw := range []string{"broken", "beta=130%", "beta=abc%", "beta=maybe"} {
+		if _, err := ParseFeatureDefaults(raw); err == nil {
+			t.Errorf("expected error for %q", raw)
+		}
+	}
+}
+
+func TestFeatureDefault_RolloutIsDeterministic(t *testing.T) {
+	rollout := FeatureDefault{RolloutPercent: 30, IsRollout: true}
+
+	for i := 0; i < 100; i++ {
+		userID := fmt.Sprintf("user-%d", i)
+		first := rollout.Evaluate("beta-search", userID)
+		for j := 0; j < 5; j++ {
+			if rollout.Evaluate("beta-search", userID) != first {
+				t.Fatalf("user %s flipped between evaluations", userID)
+			}
+		}
+	}
+
+	if rollout.Evaluate("beta-search", "") {
+		t.Error("expected rollout without a stickiness ID to be disabled")
+	}
+}
+
+func TestFeatureDefault_RolloutDistribution(t *testing.T) {
+	rollout := FeatureDefault{RolloutPercent: 30, IsRollout: true}
+
+	const users = 10000
+	enabled := 0
+	for i := 0; i < users; i++ {
+		if rollout.Evaluate("beta-search", fmt.Sprintf("user-%d", i)) {
+			enabled++
+		}
+	}
+
+	ratio := float64(enabled) / users
+	if ratio < 0.27 || ratio > 0.33 {
+		t.Errorf("expected ~30%% of users enabled, got %.1f%%", ratio*100)
+	}
+
+	// Edge percentages are absolute
+	none := FeatureDefault{RolloutPercent: 0, IsRollout: true}
+	all := FeatureDefault{RolloutPercent: 100, IsRollout: true}
+	if none.Evaluate("beta-search", "user-1") || !all.Evaluate("beta-search", "user-1") {
+		t.Error("expected 0% and 100% rollouts to be absolute")
+	}
+}
+
+func TestUnleashClient_UsesDegradedDefaults(t *testing.T) {
+	client, err := NewUnleashClient(&UnleashConfig{
+		Defaults: map[string]FeatureDefault{
+			"new-dashboard": {Enabled: true},
+			"beta-search":   {RolloutPercent: 100, IsRollout: true},
+		},
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !client.IsFeatureEnabled("new-dashboard", &FeatureContext{}) {
+		t.Error("expected new-dashboard default to be enabled")
+	}
+	if !client.IsFeatureEnabled("beta-search", &FeatureContext{UserID: "user-1"}) {
+		t.Error("expected beta-search rollout to include user-1")
+	}
+	if !client.IsEnabled("beta-search", map[string]interface{}{"userId": "user-1"}) {
+		t.Error("expected map context rollout to include user-1")
+	}
+	if client.IsFeatureEnabled("unknown-flag", &FeatureContext{UserID: "user-1"}) {
+		t.Error("expected features without a default to be disabled")
+	}
+
+	client.SetOverride("new-dashboard", false)
+	if client.IsFeatureEnabled("new-dashboard", &FeatureContext{}) {
+		t.Error("expected override to take precedence over default")
+	}
+}
+
+func TestUnleashClient_ExplainFeatureMatchesEvaluation(t *testing.T) {
+	client, err := NewUnleashClient(&UnleashConfig{
+		Defaults: map[string]FeatureDefault{
+			"beta-search": {RolloutPercent: 30, IsRollout: true},
+		},
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for i := 0; i < 50; i++ {
+		context := &FeatureContext{UserID: fmt.Sprintf("user-%d", i)}
+		explanation := client.ExplainFeature("beta-search", context)
+		if explanation.MatchedStrategy != StrategyGradualRollout {
+			t.Fatalf("unexpected strategy: %s", explanation.MatchedStrategy)
+		}
+		if explanation.Enabled != client.IsFeatureEnabled("beta-search", context) {
+			t.Fatalf("explanation disagrees with evaluation for %s", context.UserID)
+		}
+	}
+}
+
+func TestUnleashClient_ExplainFeatureMatchesEveryStrategy(t *testing.T) {
+	client, err := NewUnleashClient(&UnleashConfig{
+		Defaults: map[string]FeatureDefault{
+			"new-dashboard": {Enabled: true},
+			"beta-search":   {RolloutPercent: 100, IsRollout: true},
+			"dark-mode":     {Enabled: false},
+		},
+	}, zap.NewNop())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	client.SetOverride("dark-mode", true)
+
+	tests := []struct {
+		feature  string
+		context  *FeatureContext
+		strategy string
+		reason   string
+	}{
+		{"dark-mode", &FeatureContext{UserID: "user-1"}, StrategyOverride, "local override set to true"},
+		{"new-dashboard", &FeatureContext{UserID: "user-1"}, StrategyDefault, "degraded-mode default is true"},
+		{"beta-search", &FeatureContext{}, StrategyGradualRollout, "100% rollout requires a user or session ID"},
+		{"unknown", &FeatureContext{UserID: "user-1"}, StrategyNone, "no override or default configured"},
+	}
+
+	for _, tt := range tests {
+		explanation := client.ExplainFeature(tt.feature, tt.context)
+		if explanation.MatchedStrategy != tt.strategy || explanation.Reason != tt.reason {
+			t.Errorf("%s: got strategy %q reason %q, want %q %q",
+				tt.feature, explanation.MatchedStrategy, explanation.Reason, tt.strategy, tt.reason)
+		}
+		if explanation.Enabled != client.IsFeatureEnabled(tt.feature, tt.context) {
+			t.Errorf("%s: explanation disagrees with evaluation", tt.feature)
+		}
+	}
+}
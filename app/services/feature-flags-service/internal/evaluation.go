@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// defaultEvaluationTimeout bounds a single evaluation in case a strategy blocks
+const defaultEvaluationTimeout = 100 * time.Millisecond
+
+// contextError converts a done context into the matching gRPC status error.
+// Returns nil while the context is still active.
+func contextError(ctx context.Context) error {
+	switch err := ctx.Err(); {
+	case err == nil:
+		return nil
+	case errors.Is(err, context.DeadlineExceeded):
+		return status.Error(codes.DeadlineExceeded, "request deadline exceeded")
+	default:
+		return status.Error(codes.Canceled, "request canceled")
+	}
+}
+
+// evaluateWithTimeout runs fn unless ctx is already done, and gives up once ctx
+// or the evaluation timeout expires. A non-positive timeout only honours ctx.
+func evaluateWithTimeout[T any](ctx context.Context, timeout time.Duration, fn func() T) (T, error) {
+	var zero T
+	if err := contextError(ctx); err != nil {
+		return zero, err
+	}
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// Buffered so the goroutine can finish even if we stop waiting
+	result := make(chan T, 1)
+	go func() {
+		result <- fn()
+	}()
+
+	select {
+	case value := <-result:
+		return value, nil
+	case <-ctx.Done():
+		err := contextError(ctx)
+		if status.Code(err) == codes.DeadlineExceeded {
+			return zero, status.Error(codes.DeadlineExceeded, "feature evaluation timed out")
+		}
+		return zero, err
+	}
+}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestEvaluateWithTimeout_CanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	called := false
+	_, err := evaluateWithTimeout(ctx, time.Second, func() bool {
+		called = true
+		return true
+	})
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("expected Canceled, got %v", err)
+	}
+	if called {
+		t.Error("expected evaluation to be skipped for a canceled context")
+	}
+}
+
+func TestEvaluateWithTimeout_ExpiredDeadline(t *testing.T) {
+	ctx, cancel := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel()
+
+	_, err := evaluateWithTimeout(ctx, time.Second, func() bool { return true })
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestEvaluateWithTimeout_BlockingEvaluation(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	_, err := evaluateWithTimeout(context.Background(), 10*time.Millisecond, func() bool {
+		<-release
+		return true
+	})
+	if status.Code(err) != codes.DeadlineExceeded {
+		t.Errorf("expected DeadlineExceeded, got %v", err)
+	}
+}
+
+func TestEvaluateWithTimeout_ReturnsResult(t *testing.T) {
+	enabled, err := evaluateWithTimeout(context.Background(), 0, func() bool { return true })
+	if err != nil || !enabled {
+		t.Errorf("expected enabled result, got %v, %v", enabled, err)
+	}
+}
@@ -2,30 +2,66 @@ package internal
 
 import (
 	"context"
+	"encoding/json"
+	"sort"
+	"strings"
 	"time"
 
 	pb "github.com/haunted-saas/feature-flags-service/proto/featureflags/v1"
+	"github.com/haunted-saas/pkg/admintoken"
+	"github.com/haunted-saas/pkg/pagination"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
+// featureDefinitionSource provides full toggle definitions (implemented by UnleashClient)
+type featureDefinitionSource interface {
+	GetFeatureDefinition(featureKey string) (FeatureDefinition, bool)
+}
+
+// featureToggleSource lists every known toggle (implemented by UnleashClient)
+type featureToggleSource interface {
+	GetFeatureToggles() []Feature
+}
+
+// listFeaturesLimits leaves limit unset as "everything" so callers that
+// predate paging keep receiving the full list
+var listFeaturesLimits = pagination.Limits{Default: 0, Max: 500}
+
 // FeatureFlagsServer implements the gRPC service
 type FeatureFlagsServer struct {
 	pb.UnimplementedFeatureFlagsServiceServer
-	unleashClient *UnleashClient
-	logger        *zap.Logger
+	unleashClient     *UnleashClient
+	definitions       featureDefinitionSource
+	toggles           featureToggleSource
+	logger            *zap.Logger
+	evaluationTimeout time.Duration
+	adminToken        string
 }
 
 // NewFeatureFlagsServer creates a new feature flags server
 func NewFeatureFlagsServer(unleashClient *UnleashClient, logger *zap.Logger) *FeatureFlagsServer {
 	return &FeatureFlagsServer{
-		unleashClient: unleashClient,
-		logger:        logger,
+		unleashClient:     unleashClient,
+		definitions:       unleashClient,
+		toggles:           unleashClient,
+		logger:            logger,
+		evaluationTimeout: defaultEvaluationTimeout,
 	}
 }
 
+// SetEvaluationTimeout overrides the per-request evaluation timeout (0 disables the guard)
+func (s *FeatureFlagsServer) SetEvaluationTimeout(timeout time.Duration) {
+	s.evaluationTimeout = timeout
+}
+
+// SetAdminToken sets the token admin RPCs must present (empty disables them)
+func (s *FeatureFlagsServer) SetAdminToken(token string) {
+	s.adminToken = token
+}
+
 // IsFeatureEnabled checks if a feature is enabled for the given context
 // This is the core proxy function - extremely simple and fast
 func (s *FeatureFlagsServer) IsFeatureEnabled(ctx context.Context, req *pb.IsFeatureEnabledRequest) (*pb.IsFeatureEnabledResponse, error) {
@@ -58,7 +94,15 @@ func (s *FeatureFlagsServer) IsFeatureEnabled(ctx context.Context, req *pb.IsFea
 	}
 
 	// Call Unleash SDK (in-memory cache lookup - no network call!)
-	enabled := s.unleashClient.IsFeatureEnabled(req.FeatureName, featureContext)
+	enabled, err := evaluateWithTimeout(ctx, s.evaluationTimeout, func() bool {
+		return s.unleashClient.IsFeatureEnabled(req.FeatureName, featureContext)
+	})
+	if err != nil {
+		s.logger.Warn("feature evaluation aborted",
+			zap.String("feature_name", req.FeatureName),
+			zap.Error(err))
+		return nil, err
+	}
 
 	s.logger.Debug("feature flag evaluated",
 		zap.String("feature_name", req.FeatureName),
@@ -84,6 +128,9 @@ func (s *FeatureFlagsServer) GetFeatureVariant(ctx context.Context, req *pb.GetF
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, "invalid properties_json")
 	}
+	if req.DefaultPayloadJson != "" && !json.Valid([]byte(req.DefaultPayloadJson)) {
+		return nil, status.Error(codes.InvalidArgument, "invalid default_payload_json")
+	}
 
 	// Extract metadata
 	remoteAddr, userAgent, sessionID := s.extractMetadata(ctx)
@@ -99,7 +146,15 @@ func (s *FeatureFlagsServer) GetFeatureVariant(ctx context.Context, req *pb.GetF
 	}
 
 	// Get variant from Unleash SDK (in-memory cache)
-	variant := s.unleashClient.GetVariant(req.FeatureName, featureContext)
+	variant, err := evaluateWithTimeout(ctx, s.evaluationTimeout, func() Variant {
+		return s.unleashClient.GetVariant(req.FeatureName, featureContext)
+	})
+	if err != nil {
+		s.logger.Warn("feature variant evaluation aborted",
+			zap.String("feature_name", req.FeatureName),
+			zap.Error(err))
+		return nil, err
+	}
 
 	s.logger.Debug("feature variant evaluated",
 		zap.String("feature_name", req.FeatureName),
@@ -107,10 +162,9 @@ func (s *FeatureFlagsServer) GetFeatureVariant(ctx context.Context, req *pb.GetF
 		zap.String("variant_name", variant.Name),
 		zap.Bool("enabled", variant.Enabled))
 
-	// Convert variant payload to JSON
-	payloadJSON := "{}"
-	if variant.Payload.Value != "" {
-		payloadJSON = variant.Payload.Value
+	payloadJSON := variantPayloadJSON(variant)
+	if !variant.Enabled && req.DefaultPayloadJson != "" {
+		payloadJSON = req.DefaultPayloadJson
 	}
 
 	return &pb.GetFeatureVariantResponse{
@@ -120,14 +174,116 @@ func (s *FeatureFlagsServer) GetFeatureVariant(ctx context.Context, req *pb.GetF
 	}, nil
 }
 
-// ListFeatures lists all available features (for debugging/admin)
+// maxVariantBatchSize caps the number of features evaluated by GetFeatureVariants
+const maxVariantBatchSize = 100
+
+// GetFeatureVariants gets the variants for several feature flags in one call
+func (s *FeatureFlagsServer) GetFeatureVariants(ctx context.Context, req *pb.GetFeatureVariantsRequest) (*pb.GetFeatureVariantsResponse, error) {
+	// Validate request
+	if len(req.FeatureNames) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "feature_names is required")
+	}
+	if len(req.FeatureNames) > maxVariantBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "feature_names cannot exceed %d entries", maxVariantBatchSize)
+	}
+	for _, name := range req.FeatureNames {
+		if name == "" {
+			return nil, status.Error(codes.InvalidArgument, "feature_names cannot contain empty names")
+		}
+	}
+
+	// Parse properties JSON
+	properties, err := ParsePropertiesJSON(req.PropertiesJson)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid properties_json")
+	}
+
+	// Extract metadata
+	remoteAddr, userAgent, sessionID := s.extractMetadata(ctx)
+
+	// Build a single feature context shared by all evaluations
+	featureContext := &FeatureContext{
+		UserID:     req.UserId,
+		TeamID:     req.TeamId,
+		Properties: properties,
+		RemoteAddr: remoteAddr,
+		UserAgent:  userAgent,
+		SessionID:  sessionID,
+	}
+
+	// The timeout covers the whole batch
+	results, err := evaluateWithTimeout(ctx, s.evaluationTimeout, func() []*pb.FeatureVariantResult {
+		results := make([]*pb.FeatureVariantResult, len(req.FeatureNames))
+		for i, name := range req.FeatureNames {
+			variant := s.unleashClient.GetVariant(name, featureContext)
+			results[i] = &pb.FeatureVariantResult{
+				FeatureName: name,
+				Enabled:     variant.Enabled,
+				VariantName: variant.Name,
+				PayloadJson: variantPayloadJSON(variant),
+			}
+		}
+		return results
+	})
+	if err != nil {
+		s.logger.Warn("feature variants evaluation aborted",
+			zap.Int("count", len(req.FeatureNames)),
+			zap.Error(err))
+		return nil, err
+	}
+
+	s.logger.Debug("feature variants evaluated",
+		zap.Int("count", len(results)),
+		zap.String("user_id", req.UserId))
+
+	return &pb.GetFeatureVariantsResponse{
+		Variants: results,
+	}, nil
+}
+
+// variantPayloadJSON converts a variant payload to JSON, defaulting to an empty object
+func variantPayloadJSON(variant Variant) string {
+	if variant.Payload.Value != "" {
+		return variant.Payload.Value
+	}
+	return "{}"
+}
+
+// ListFeatures lists available features sorted by name, optionally filtered
+// by a name fragment and paged (for debugging/admin)
 func (s *FeatureFlagsServer) ListFeatures(ctx context.Context, req *pb.ListFeaturesRequest) (*pb.ListFeaturesResponse, error) {
-	// Get features from Unleash SDK
-	features := s.unleashClient.GetFeatureToggles()
+	page, err := pagination.Normalize(pagination.Request{
+		Limit:  int(req.Limit),
+		Offset: int(req.Offset),
+		Cursor: req.Cursor,
+	}, listFeaturesLimits)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	// The SDK has no server-side filtering, so filter and page its toggle list
+	nameFilter := strings.ToLower(req.NameContains)
+	var matches []Feature
+	for _, feature := range s.toggles.GetFeatureToggles() {
+		if strings.Contains(strings.ToLower(feature.Name), nameFilter) {
+			matches = append(matches, feature)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return matches[i].Name < matches[j].Name })
+
+	paged := matches
+	if page.Offset >= len(paged) {
+		paged = nil
+	} else {
+		paged = paged[page.Offset:]
+	}
+	if page.Limit > 0 && len(paged) > page.Limit {
+		paged = paged[:page.Limit]
+	}
 
 	// Convert to proto format
-	protoFeatures := make([]*pb.Feature, len(features))
-	for i, feature := range features {
+	protoFeatures := make([]*pb.Feature, len(paged))
+	for i, feature := range paged {
 		protoFeatures[i] = &pb.Feature{
 			Name:        feature.Name,
 			Description: feature.Description,
@@ -136,10 +292,173 @@ func (s *FeatureFlagsServer) ListFeatures(ctx context.Context, req *pb.ListFeatu
 		}
 	}
 
-	s.logger.Debug("features listed", zap.Int("count", len(features)))
+	s.logger.Debug("features listed",
+		zap.String("name_contains", req.NameContains),
+		zap.Int("matches", len(matches)),
+		zap.Int("count", len(paged)))
 
+	result := page.Result(int64(len(matches)), len(paged))
 	return &pb.ListFeaturesResponse{
-		Features: protoFeatures,
+		Features:   protoFeatures,
+		TotalCount: result.Total,
+		NextCursor: result.NextCursor,
+	}, nil
+}
+
+// ExplainFeature evaluates a feature and reports why it resolved the way it did.
+// Kept off the IsFeatureEnabled hot path - for admin debugging only, so the
+// caller must present the admin token.
+func (s *FeatureFlagsServer) ExplainFeature(ctx context.Context, req *pb.ExplainFeatureRequest) (*pb.ExplainFeatureResponse, error) {
+	if err := admintoken.Check(ctx, s.adminToken); err != nil {
+		return nil, err
+	}
+	if req.FeatureName == "" {
+		return nil, status.Error(codes.InvalidArgument, "feature_name is required")
+	}
+
+	properties, err := ParsePropertiesJSON(req.PropertiesJson)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid properties_json")
+	}
+
+	remoteAddr, userAgent, sessionID := s.extractMetadata(ctx)
+
+	featureContext := &FeatureContext{
+		UserID:     req.UserId,
+		TeamID:     req.TeamId,
+		Properties: properties,
+		RemoteAddr: remoteAddr,
+		UserAgent:  userAgent,
+		SessionID:  sessionID,
+	}
+
+	explanation, err := evaluateWithTimeout(ctx, s.evaluationTimeout, func() Explanation {
+		return s.unleashClient.ExplainFeature(req.FeatureName, featureContext)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	propertiesJSON, err := ToJSON(properties)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to encode properties")
+	}
+
+	s.logger.Info("feature explained",
+		zap.String("feature_name", req.FeatureName),
+		zap.String("user_id", req.UserId),
+		zap.String("matched_strategy", explanation.MatchedStrategy),
+		zap.Bool("enabled", explanation.Enabled))
+
+	return &pb.ExplainFeatureResponse{
+		Enabled:         explanation.Enabled,
+		MatchedStrategy: explanation.MatchedStrategy,
+		Reason:          explanation.Reason,
+		Context: &pb.EvaluationContext{
+			UserId:         featureContext.UserID,
+			TeamId:         featureContext.TeamID,
+			SessionId:      featureContext.SessionID,
+			RemoteAddr:     featureContext.RemoteAddr,
+			UserAgent:      featureContext.UserAgent,
+			PropertiesJson: propertiesJSON,
+			StickinessId:   explanation.StickinessID,
+		},
+	}, nil
+}
+
+// DescribeFeature returns the full toggle definition for a feature. Admin
+// only - the caller must present the admin token.
+func (s *FeatureFlagsServer) DescribeFeature(ctx context.Context, req *pb.DescribeFeatureRequest) (*pb.DescribeFeatureResponse, error) {
+	if err := admintoken.Check(ctx, s.adminToken); err != nil {
+		return nil, err
+	}
+	if req.FeatureName == "" {
+		return nil, status.Error(codes.InvalidArgument, "feature_name is required")
+	}
+
+	definition, ok := s.definitions.GetFeatureDefinition(req.FeatureName)
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "feature not found: %s", req.FeatureName)
+	}
+
+	strategies := make([]*pb.Strategy, len(definition.Strategies))
+	for i, strategy := range definition.Strategies {
+		strategies[i] = &pb.Strategy{
+			Name:       strategy.Name,
+			Parameters: strategy.Parameters,
+		}
+	}
+
+	variants := make([]*pb.VariantDefinition, len(definition.Variants))
+	for i, variant := range definition.Variants {
+		variants[i] = &pb.VariantDefinition{
+			Name:         variant.Name,
+			Weight:       int32(variant.Weight),
+			Stickiness:   variant.Stickiness,
+			PayloadType:  variant.Payload.Type,
+			PayloadValue: variant.Payload.Value,
+		}
+	}
+
+	s.logger.Debug("feature described",
+		zap.String("feature_name", req.FeatureName),
+		zap.Int("strategies", len(strategies)),
+		zap.Int("variants", len(variants)))
+
+	return &pb.DescribeFeatureResponse{
+		Feature: &pb.Feature{
+			Name:        definition.Name,
+			Description: definition.Description,
+			Enabled:     definition.Enabled,
+			CreatedAt:   definition.CreatedAt.Format(time.RFC3339),
+		},
+		Strategies: strategies,
+		Variants:   variants,
+	}, nil
+}
+
+// SetFeatureEnabled forces a feature on or off with a local override.
+// Admin only - the gateway enforces the admin role and sends the admin token.
+func (s *FeatureFlagsServer) SetFeatureEnabled(ctx context.Context, req *pb.SetFeatureEnabledRequest) (*pb.SetFeatureEnabledResponse, error) {
+	if err := admintoken.Check(ctx, s.adminToken); err != nil {
+		return nil, err
+	}
+
+	// Validate request
+	if req.FeatureName == "" {
+		return nil, status.Error(codes.InvalidArgument, "feature_name is required")
+	}
+	if req.RequestedByUserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "requested_by_user_id is required")
+	}
+
+	// Apply override immediately so subsequent evaluations see it
+	s.unleashClient.SetOverride(req.FeatureName, req.Enabled)
+
+	persisted := false
+	if req.Persist {
+		if err := s.unleashClient.PersistFeatureEnabled(ctx, req.FeatureName, req.Enabled); err != nil {
+			s.logger.Error("failed to persist feature override",
+				zap.String("feature_name", req.FeatureName),
+				zap.Bool("enabled", req.Enabled),
+				zap.Error(err))
+			return nil, status.Errorf(codes.Unavailable, "override applied locally but failed to persist to Unleash: %v", err)
+		}
+		persisted = true
+	}
+
+	// Audit log
+	s.logger.Info("audit event",
+		zap.String("event_type", "feature.override.set"),
+		zap.String("feature_name", req.FeatureName),
+		zap.Bool("enabled", req.Enabled),
+		zap.Bool("persisted", persisted),
+		zap.String("requested_by_user_id", req.RequestedByUserId))
+
+	return &pb.SetFeatureEnabledResponse{
+		FeatureName: req.FeatureName,
+		Enabled:     req.Enabled,
+		Persisted:   persisted,
 	}, nil
 }
 
@@ -168,8 +487,11 @@ func (s *FeatureFlagsServer) extractMetadata(ctx context.Context) (remoteAddr, u
 			remoteAddr = addrs[0]
 		}
 
-		// Extract user agent
-		if agents := md.Get("user-agent"); len(agents) > 0 {
+		// Extract user agent (the gateway forwards the browser's as x-user-agent
+		// because gRPC overwrites user-agent with its own)
+		if agents := md.Get("x-user-agent"); len(agents) > 0 {
+			userAgent = agents[0]
+		} else if agents := md.Get("user-agent"); len(agents) > 0 {
 			userAgent = agents[0]
 		}
 
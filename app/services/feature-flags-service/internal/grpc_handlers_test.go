@@ -0,0 +1,472 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/haunted-saas/feature-flags-service/proto/featureflags/v1"
+	"github.com/haunted-saas/pkg/admintoken"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newTestServer(t *testing.T) *FeatureFlagsServer {
+	t.Helper()
+
+	logger := zap.NewNop()
+	client, err := NewUnleashClient(&UnleashConfig{ServerURL: "http://unleash.test"}, logger)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	server := NewFeatureFlagsServer(client, logger)
+	server.SetAdminToken(testAdminToken)
+	return server
+}
+
+const testAdminToken = "secret"
+
+// adminContext carries the admin token admin RPCs require
+func adminContext() context.Context {
+	return metadata.NewIncomingContext(context.Background(), metadata.Pairs(admintoken.MetadataKey, testAdminToken))
+}
+
+func TestSetFeatureEnabled_OverrideAppliesImmediately(t *testing.T) {
+	server := newTestServer(t)
+	ctx := adminContext()
+
+	check := func() bool {
+		resp, err := server.IsFeatureEnabled(ctx, &pb.IsFeatureEnabledRequest{FeatureName: "new-dashboard", UserId: "user-1"})
+		if err != nil {
+			t.Fatalf("IsFeatureEnabled failed: %v", err)
+		}
+		return resp.Enabled
+	}
+
+	if check() {
+		t.Fatal("expected feature to start disabled")
+	}
+
+	resp, err := server.SetFeatureEnabled(ctx, &pb.SetFeatureEnabledRequest{
+		FeatureName:       "new-dashboard",
+		Enabled:           true,
+		RequestedByUserId: "admin-1",
+	})
+	if err != nil {
+		t.Fatalf("SetFeatureEnabled failed: %v", err)
+	}
+	if !resp.Enabled || resp.Persisted {
+		t.Errorf("unexpected response: %+v", resp)
+	}
+	if !check() {
+		t.Error("expected override to enable the feature")
+	}
+
+	if _, err := server.SetFeatureEnabled(ctx, &pb.SetFeatureEnabledRequest{
+		FeatureName:       "new-dashboard",
+		Enabled:           false,
+		RequestedByUserId: "admin-1",
+	}); err != nil {
+		t.Fatalf("SetFeatureEnabled failed: %v", err)
+	}
+	if check() {
+		t.Error("expected override to disable the feature")
+	}
+}
+
+func TestSetFeatureEnabled_Validation(t *testing.T) {
+	server := newTestServer(t)
+
+	tests := []struct {
+		name    string
+		request *pb.SetFeatureEnabledRequest
+	}{
+		{name: "missing feature name", request: &pb.SetFeatureEnabledRequest{RequestedByUserId: "admin-1"}},
+		{name: "missing requester", request: &pb.SetFeatureEnabledRequest{FeatureName: "new-dashboard"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := server.SetFeatureEnabled(adminContext(), tt.request)
+			if status.Code(err) != codes.InvalidArgument {
+				t.Errorf("expected InvalidArgument, got %v", err)
+			}
+		})
+	}
+}
+
+func TestSetFeatureEnabled_PersistWithoutAdminToken(t *testing.T) {
+	server := newTestServer(t)
+
+	_, err := server.SetFeatureEnabled(adminContext(), &pb.SetFeatureEnabledRequest{
+		FeatureName:       "new-dashboard",
+		Enabled:           true,
+		Persist:           true,
+		RequestedByUserId: "admin-1",
+	})
+	if status.Code(err) != codes.Unavailable {
+		t.Errorf("expected Unavailable, got %v", err)
+	}
+}
+
+func TestAdminRPCs_RequireAdminToken(t *testing.T) {
+	calls := map[string]func(server *FeatureFlagsServer, ctx context.Context) error{
+		"SetFeatureEnabled": func(server *FeatureFlagsServer, ctx context.Context) error {
+			_, err := server.SetFeatureEnabled(ctx, &pb.SetFeatureEnabledRequest{
+				FeatureName:       "new-dashboard",
+				Enabled:           true,
+				RequestedByUserId: "admin-1",
+			})
+			return err
+		},
+		"ExplainFeature": func(server *FeatureFlagsServer, ctx context.Context) error {
+			_, err := server.ExplainFeature(ctx, &pb.ExplainFeatureRequest{FeatureName: "new-dashboard", UserId: "user-1"})
+			return err
+		},
+		"DescribeFeature": func(server *FeatureFlagsServer, ctx context.Context) error {
+			_, err := server.DescribeFeature(ctx, &pb.DescribeFeatureRequest{FeatureName: "new-dashboard"})
+			return err
+		},
+	}
+
+	tests := []struct {
+		name       string
+		adminToken string
+		ctx        context.Context
+		want       codes.Code
+	}{
+		{name: "admin access not configured", adminToken: "", ctx: adminContext(), want: codes.PermissionDenied},
+		{name: "no admin token", adminToken: testAdminToken, ctx: context.Background(), want: codes.Unauthenticated},
+		{
+			name:       "wrong admin token",
+			adminToken: testAdminToken,
+			ctx:        metadata.NewIncomingContext(context.Background(), metadata.Pairs(admintoken.MetadataKey, "guess")),
+			want:       codes.Unauthenticated,
+		},
+	}
+
+	for rpc, call := range calls {
+		for _, tt := range tests {
+			t.Run(rpc+"/"+tt.name, func(t *testing.T) {
+				server := newTestServer(t)
+				server.SetAdminToken(tt.adminToken)
+
+				if err := call(server, tt.ctx); status.Code(err) != tt.want {
+					t.Errorf("expected %v, got %v", tt.want, err)
+				}
+			})
+		}
+	}
+
+	// A rejected SetFeatureEnabled must not apply the override
+	server := newTestServer(t)
+	server.SetFeatureEnabled(context.Background(), &pb.SetFeatureEnabledRequest{
+		FeatureName:       "new-dashboard",
+		Enabled:           true,
+		RequestedByUserId: "admin-1",
+	})
+	resp, err := server.IsFeatureEnabled(context.Background(), &pb.IsFeatureEnabledRequest{FeatureName: "new-dashboard", UserId: "user-1"})
+	if err != nil || resp.Enabled {
+		t.Errorf("expected the override to be rejected, got %+v, %v", resp, err)
+	}
+}
+
+func TestGetFeatureVariants_ReturnsPayloadsInOrder(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	server.unleashClient.SetVariantOverride("checkout-layout", Variant{
+		Name:    "two-column",
+		Enabled: true,
+		Payload: VariantPayload{Type: "json", Value: `{"columns":2}`},
+	})
+	server.unleashClient.SetVariantOverride("pricing-copy", Variant{
+		Name:    "urgent",
+		Enabled: true,
+		Payload: VariantPayload{Type: "string", Value: `"Only 3 left"`},
+	})
+
+	resp, err := server.GetFeatureVariants(ctx, &pb.GetFeatureVariantsRequest{
+		FeatureNames: []string{"pricing-copy", "checkout-layout", "unknown-flag"},
+		UserId:       "user-1",
+	})
+	if err != nil {
+		t.Fatalf("GetFeatureVariants failed: %v", err)
+	}
+	if len(resp.Variants) != 3 {
+		t.Fatalf("expected 3 variants, got %d", len(resp.Variants))
+	}
+
+	expected := []struct {
+		name, variant, payload string
+		enabled                bool
+	}{
+		{"pricing-copy", "urgent", `"Only 3 left"`, true},
+		{"checkout-layout", "two-column", `{"columns":2}`, true},
+		{"unknown-flag", "", "{}", false},
+	}
+	for i, want := range expected {
+		got := resp.Variants[i]
+		if got.FeatureName != want.name || got.VariantName != want.variant ||
+			got.PayloadJson != want.payload || got.Enabled != want.enabled {
+			t.Errorf("variant %d: got %+v, want %+v", i, got, want)
+		}
+	}
+}
+
+func TestGetFeatureVariant_DefaultPayloadWhenDisabled(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	server.unleashClient.SetVariantOverride("checkout-layout", Variant{
+		Name:    "disabled",
+		Enabled: false,
+		Payload: VariantPayload{Type: "json", Value: `{"columns":2}`},
+	})
+	server.unleashClient.SetVariantOverride("pricing-copy", Variant{
+		Name:    "urgent",
+		Enabled: true,
+		Payload: VariantPayload{Type: "json", Value: `{"copy":"Only 3 left"}`},
+	})
+
+	tests := []struct {
+		feature string
+		enabled bool
+		payload string
+	}{
+		{feature: "checkout-layout", enabled: false, payload: `{"columns":1}`},
+		{feature: "unknown-flag", enabled: false, payload: `{"columns":1}`},
+		{feature: "pricing-copy", enabled: true, payload: `{"copy":"Only 3 left"}`},
+	}
+	for _, tt := range tests {
+		resp, err := server.GetFeatureVariant(ctx, &pb.GetFeatureVariantRequest{
+			FeatureName:        tt.feature,
+			UserId:             "user-1",
+			DefaultPayloadJson: `{"columns":1}`,
+		})
+		if err != nil {
+			t.Fatalf("GetFeatureVariant(%s) failed: %v", tt.feature, err)
+		}
+		if resp.Enabled != tt.enabled || resp.PayloadJson != tt.payload {
+			t.Errorf("GetFeatureVariant(%s) = enabled %v payload %s, want enabled %v payload %s",
+				tt.feature, resp.Enabled, resp.PayloadJson, tt.enabled, tt.payload)
+		}
+	}
+
+	_, err := server.GetFeatureVariant(ctx, &pb.GetFeatureVariantRequest{
+		FeatureName:        "checkout-layout",
+		DefaultPayloadJson: "not-json",
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for invalid default payload, got %v", err)
+	}
+}
+
+func TestGetFeatureVariants_Validation(t *testing.T) {
+	server := newTestServer(t)
+	ctx := context.Background()
+
+	requests := []*pb.GetFeatureVariantsRequest{
+		{},
+		{FeatureNames: []string{"checkout-layout", ""}},
+		{FeatureNames: []string{"checkout-layout"}, PropertiesJson: "not-json"},
+	}
+	for _, req := range requests {
+		_, err := server.GetFeatureVariants(ctx, req)
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected InvalidArgument for %+v, got %v", req, err)
+		}
+	}
+}
+
+func TestIsFeatureEnabled_CanceledContext(t *testing.T) {
+	server := newTestServer(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := server.IsFeatureEnabled(ctx, &pb.IsFeatureEnabledRequest{FeatureName: "new-dashboard"})
+	if status.Code(err) != codes.Canceled {
+		t.Errorf("expected Canceled, got %v", err)
+	}
+}
+
+// fakeDefinitions serves fixed toggle definitions
+type fakeDefinitions map[string]FeatureDefinition
+
+func (f fakeDefinitions) GetFeatureDefinition(featureKey string) (FeatureDefinition, bool) {
+	definition, ok := f[featureKey]
+	return definition, ok
+}
+
+func TestDescribeFeature_ReturnsStrategiesAndVariants(t *testing.T) {
+	server := newTestServer(t)
+	server.definitions = fakeDefinitions{
+		"checkout-layout": {
+			Feature: Feature{
+				Name:        "checkout-layout",
+				Description: "New checkout layout",
+				Enabled:     true,
+				CreatedAt:   time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+			},
+			Strategies: []Strategy{
+				{Name: "userWithId", Parameters: map[string]string{"userIds": "user-1,user-2"}},
+				{Name: "flexibleRollout", Parameters: map[string]string{"rollout": "30", "stickiness": "userId"}},
+			},
+			Variants: []VariantDefinition{
+				{Name: "one-column", Weight: 500, Stickiness: "userId", Payload: VariantPayload{Type: "json", Value: `{"columns":1}`}},
+				{Name: "two-column", Weight: 500, Stickiness: "userId", Payload: VariantPayload{Type: "json", Value: `{"columns":2}`}},
+			},
+		},
+	}
+
+	resp, err := server.DescribeFeature(adminContext(), &pb.DescribeFeatureRequest{FeatureName: "checkout-layout"})
+	if err != nil {
+		t.Fatalf("DescribeFeature failed: %v", err)
+	}
+
+	if resp.Feature.Name != "checkout-layout" || !resp.Feature.Enabled || resp.Feature.CreatedAt != "2024-01-02T03:04:05Z" {
+		t.Errorf("unexpected feature: %+v", resp.Feature)
+	}
+	if len(resp.Strategies) != 2 {
+		t.Fatalf("expected 2 strategies, got %d", len(resp.Strategies))
+	}
+	if resp.Strategies[1].Name != "flexibleRollout" || resp.Strategies[1].Parameters["rollout"] != "30" {
+		t.Errorf("unexpected strategy: %+v", resp.Strategies[1])
+	}
+	if len(resp.Variants) != 2 {
+		t.Fatalf("expected 2 variants, got %d", len(resp.Variants))
+	}
+	if resp.Variants[1].Name != "two-column" || resp.Variants[1].Weight != 500 || resp.Variants[1].PayloadValue != `{"columns":2}` {
+		t.Errorf("unexpected variant: %+v", resp.Variants[1])
+	}
+}
+
+func TestDescribeFeature_NotFound(t *testing.T) {
+	server := newTestServer(t)
+	server.definitions = fakeDefinitions{}
+
+	_, err := server.DescribeFeature(adminContext(), &pb.DescribeFeatureRequest{FeatureName: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound, got %v", err)
+	}
+
+	_, err = server.DescribeFeature(adminContext(), &pb.DescribeFeatureRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument, got %v", err)
+	}
+}
+
+func TestExplainFeature_ReportsMatchedStrategy(t *testing.T) {
+	logger := zap.NewNop()
+	client, err := NewUnleashClient(&UnleashConfig{
+		ServerURL: "http://unleash.test",
+		Defaults: map[string]FeatureDefault{
+			"beta-search":   {RolloutPercent: 100, IsRollout: true},
+			"new-dashboard": {Enabled: true},
+		},
+	}, logger)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	server := NewFeatureFlagsServer(client, logger)
+	server.SetAdminToken(testAdminToken)
+	client.SetOverride("legacy-export", false)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+		"x-session-id", "sess-1",
+		admintoken.MetadataKey, testAdminToken,
+	))
+
+	tests := []struct {
+		feature  string
+		strategy string
+		enabled  bool
+	}{
+		{"beta-search", StrategyGradualRollout, true},
+		{"new-dashboard", StrategyDefault, true},
+		{"legacy-export", StrategyOverride, false},
+		{"unknown-flag", StrategyNone, false},
+	}
+	for _, tt := range tests {
+		resp, err := server.ExplainFeature(ctx, &pb.ExplainFeatureRequest{
+			FeatureName:    tt.feature,
+			UserId:         "user-1",
+			PropertiesJson: `{"plan":"pro"}`,
+		})
+		if err != nil {
+			t.Fatalf("ExplainFeature(%s) failed: %v", tt.feature, err)
+		}
+		if resp.MatchedStrategy != tt.strategy || resp.Enabled != tt.enabled {
+			t.Errorf("%s: got strategy %q enabled %t, want %q %t", tt.feature, resp.MatchedStrategy, resp.Enabled, tt.strategy, tt.enabled)
+		}
+		if resp.Reason == "" {
+			t.Errorf("%s: expected a reason", tt.feature)
+		}
+		if resp.Context.UserId != "user-1" || resp.Context.SessionId != "sess-1" || resp.Context.StickinessId != "user-1" {
+			t.Errorf("%s: unexpected context: %+v", tt.feature, resp.Context)
+		}
+		if resp.Context.PropertiesJson != `{"plan":"pro"}` {
+			t.Errorf("%s: unexpected properties: %s", tt.feature, resp.Context.PropertiesJson)
+		}
+	}
+}
+
+// fakeToggles serves a fixed toggle list
+type fakeToggles []Feature
+
+func (f fakeToggles) GetFeatureToggles() []Feature {
+	return f
+}
+
+func TestListFeatures_FiltersByNameFragment(t *testing.T) {
+	server := newTestServer(t)
+	server.toggles = fakeToggles{
+		{Name: "new-checkout", Enabled: true},
+		{Name: "dark-mode"},
+		{Name: "Checkout-Layout", Enabled: true},
+		{Name: "beta-search"},
+	}
+
+	resp, err := server.ListFeatures(context.Background(), &pb.ListFeaturesRequest{NameContains: "checkout"})
+	if err != nil {
+		t.Fatalf("ListFeatures failed: %v", err)
+	}
+
+	if resp.TotalCount != 2 || len(resp.Features) != 2 {
+		t.Fatalf("expected the 2 checkout features, got %d of %d", len(resp.Features), resp.TotalCount)
+	}
+	if resp.Features[0].Name != "Checkout-Layout" || resp.Features[1].Name != "new-checkout" {
+		t.Errorf("expected matches sorted by name, got %s, %s", resp.Features[0].Name, resp.Features[1].Name)
+	}
+	if resp.NextCursor != "" {
+		t.Errorf("expected no next page, got cursor %q", resp.NextCursor)
+	}
+}
+
+func TestListFeatures_Pages(t *testing.T) {
+	server := newTestServer(t)
+	server.toggles = fakeToggles{{Name: "a"}, {Name: "b"}, {Name: "c"}}
+
+	first, err := server.ListFeatures(context.Background(), &pb.ListFeaturesRequest{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListFeatures failed: %v", err)
+	}
+	if len(first.Features) != 2 || first.TotalCount != 3 || first.NextCursor == "" {
+		t.Fatalf("expected a first page of 2 with a cursor, got %d of %d, cursor %q", len(first.Features), first.TotalCount, first.NextCursor)
+	}
+
+	second, err := server.ListFeatures(context.Background(), &pb.ListFeaturesRequest{Limit: 2, Cursor: first.NextCursor})
+	if err != nil {
+		t.Fatalf("ListFeatures failed: %v", err)
+	}
+	if len(second.Features) != 1 || second.Features[0].Name != "c" || second.NextCursor != "" {
+		t.Errorf("expected the last page to hold only c, got %+v, cursor %q", second.Features, second.NextCursor)
+	}
+
+	_, err = server.ListFeatures(context.Background(), &pb.ListFeaturesRequest{Limit: -1})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for a negative limit, got %v", err)
+	}
+}
@@ -42,6 +42,10 @@ type UnleashConfig struct {
 	RefreshInterval time.Duration
 	MetricsInterval time.Duration
 	DisableMetrics  bool
+	AdminAPIToken   string // Optional: enables persisting overrides via the admin API
+	Project         string
+	Environment     string
+	Defaults        map[string]FeatureDefault // Fallback evaluation while Unleash is degraded
 }
 
 // ContextProperty represents a property in the feature flag context
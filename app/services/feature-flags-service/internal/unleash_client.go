@@ -1,6 +1,11 @@
 package internal
 
 import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
 	"time"
 
 	"go.uber.org/zap"
@@ -9,8 +14,14 @@ import (
 // UnleashClient wraps the Unleash SDK client - STUB IMPLEMENTATION
 // TODO: Implement full Unleash integration when SDK version is compatible
 type UnleashClient struct {
-	config *UnleashConfig
-	logger *zap.Logger
+	config     *UnleashConfig
+	logger     *zap.Logger
+	httpClient *http.Client
+
+	// Local overrides take precedence over Unleash evaluation
+	mu               sync.RWMutex
+	overrides        map[string]bool
+	variantOverrides map[string]Variant
 }
 
 // Feature represents a feature toggle
@@ -34,6 +45,27 @@ type VariantPayload struct {
 	Value string
 }
 
+// FeatureDefinition is the full toggle definition for a feature
+type FeatureDefinition struct {
+	Feature
+	Strategies []Strategy
+	Variants   []VariantDefinition
+}
+
+// Strategy represents an activation strategy on a feature toggle
+type Strategy struct {
+	Name       string
+	Parameters map[string]string
+}
+
+// VariantDefinition represents a configured variant on a feature toggle
+type VariantDefinition struct {
+	Name       string
+	Weight     int
+	Stickiness string
+	Payload    VariantPayload
+}
+
 // NewUnleashClient creates a new Unleash client - STUB
 func NewUnleashClient(config *UnleashConfig, logger *zap.Logger) (*UnleashClient, error) {
 	logger.Warn("Using stub Unleash client - feature flags will return default values",
@@ -41,29 +73,201 @@ func NewUnleashClient(config *UnleashConfig, logger *zap.Logger) (*UnleashClient
 		zap.String("app_name", config.AppName))
 	
 	return &UnleashClient{
-		config: config,
-		logger: logger,
+		config:           config,
+		logger:           logger,
+		httpClient:       &http.Client{Timeout: 10 * time.Second},
+		overrides:        make(map[string]bool),
+		variantOverrides: make(map[string]Variant),
 	}, nil
 }
 
-// IsFeatureEnabled checks if a feature is enabled - STUB returns false
+// SetOverride forces a feature on or off for all evaluations
+func (c *UnleashClient) SetOverride(featureKey string, enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.overrides[featureKey] = enabled
+}
+
+// ClearOverride removes a local override so Unleash evaluation applies again
+func (c *UnleashClient) ClearOverride(featureKey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.overrides, featureKey)
+}
+
+// SetVariantOverride forces the variant returned for a feature
+func (c *UnleashClient) SetVariantOverride(featureKey string, variant Variant) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.variantOverrides[featureKey] = variant
+}
+
+// getOverride returns the local override for a feature, if any
+func (c *UnleashClient) getOverride(featureKey string) (enabled bool, ok bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	enabled, ok = c.overrides[featureKey]
+	return enabled, ok
+}
+
+// PersistFeatureEnabled turns a feature on or off in Unleash via the admin API
+func (c *UnleashClient) PersistFeatureEnabled(ctx context.Context, featureKey string, enabled bool) error {
+	if c.config.AdminAPIToken == "" {
+		return fmt.Errorf("UNLEASH_ADMIN_API_TOKEN is not configured")
+	}
+
+	action := "off"
+	if enabled {
+		action = "on"
+	}
+
+	endpoint := fmt.Sprintf("%s/api/admin/projects/%s/features/%s/environments/%s/%s",
+		c.config.ServerURL,
+		url.PathEscape(c.config.Project),
+		url.PathEscape(featureKey),
+		url.PathEscape(c.config.Environment),
+		action)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return fmt.Errorf("failed to build admin API request: %w", err)
+	}
+	req.Header.Set("Authorization", c.config.AdminAPIToken)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("admin API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("admin API returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// evaluation records how a feature evaluation was decided. IsFeatureEnabled,
+// IsEnabled and ExplainFeature all go through evaluate, so an explanation
+// always matches the real result.
+type evaluation struct {
+	enabled  bool
+	strategy string
+	def      FeatureDefault // The matched default, when strategy is default or gradualRollout
+	bucket   int            // Rollout bucket, or -1 when none was computed
+}
+
+// evaluate decides a feature: a local override wins, then the configured
+// degraded-mode default. Features with neither are disabled.
+func (c *UnleashClient) evaluate(featureKey, stickinessID string) evaluation {
+	if enabled, ok := c.getOverride(featureKey); ok {
+		return evaluation{enabled: enabled, strategy: StrategyOverride, bucket: -1}
+	}
+
+	def, ok := c.config.Defaults[featureKey]
+	if !ok {
+		return evaluation{strategy: StrategyNone, bucket: -1}
+	}
+
+	strategy := StrategyDefault
+	if def.IsRollout {
+		strategy = StrategyGradualRollout
+	}
+	enabled, bucket := def.evaluate(featureKey, stickinessID)
+	return evaluation{enabled: enabled, strategy: strategy, def: def, bucket: bucket}
+}
+
+// reason describes the evaluation for ExplainFeature. Only built there, so
+// the IsFeatureEnabled path doesn't pay for formatting.
+func (e evaluation) reason() string {
+	switch e.strategy {
+	case StrategyOverride:
+		return fmt.Sprintf("local override set to %t", e.enabled)
+	case StrategyDefault:
+		return fmt.Sprintf("degraded-mode default is %t", e.def.Enabled)
+	case StrategyGradualRollout:
+		if e.bucket < 0 {
+			return fmt.Sprintf("%d%% rollout requires a user or session ID", e.def.RolloutPercent)
+		}
+		return fmt.Sprintf("bucket %d against %d%% rollout", e.bucket, e.def.RolloutPercent)
+	default:
+		return "no override or default configured"
+	}
+}
+
+// stickinessIDFor returns the ID used to bucket rollouts (user ID, then session ID)
+func stickinessIDFor(context *FeatureContext) string {
+	if context == nil {
+		return ""
+	}
+	if context.UserID != "" {
+		return context.UserID
+	}
+	return context.SessionID
+}
+
+// Strategy names reported by ExplainFeature
+const (
+	StrategyOverride       = "override"
+	StrategyDefault        = "default"
+	StrategyGradualRollout = "gradualRollout"
+	StrategyNone           = "none"
+)
+
+// Explanation describes how a feature evaluation was decided
+type Explanation struct {
+	Enabled         bool
+	MatchedStrategy string
+	Reason          string
+	StickinessID    string
+}
+
+// ExplainFeature evaluates a feature and reports which strategy decided the result.
+// Slower than IsFeatureEnabled - intended for debugging only.
+func (c *UnleashClient) ExplainFeature(featureKey string, context *FeatureContext) Explanation {
+	stickinessID := stickinessIDFor(context)
+	result := c.evaluate(featureKey, stickinessID)
+
+	return Explanation{
+		Enabled:         result.enabled,
+		MatchedStrategy: result.strategy,
+		Reason:          result.reason(),
+		StickinessID:    stickinessID,
+	}
+}
+
+// IsFeatureEnabled checks if a feature is enabled - STUB returns the configured default
 func (c *UnleashClient) IsFeatureEnabled(featureKey string, context *FeatureContext) bool {
+	enabled := c.evaluate(featureKey, stickinessIDFor(context)).enabled
 	c.logger.Debug("feature flag check (stub)",
 		zap.String("feature_key", featureKey),
-		zap.Bool("enabled", false))
-	return false
+		zap.Bool("enabled", enabled))
+	return enabled
 }
 
-// IsEnabled checks if a feature is enabled with map context - STUB returns false
+// IsEnabled checks if a feature is enabled with map context - STUB returns the configured default
 func (c *UnleashClient) IsEnabled(featureKey string, context map[string]interface{}) bool {
+	stickinessID, _ := context["userId"].(string)
+	if stickinessID == "" {
+		stickinessID, _ = context["sessionId"].(string)
+	}
+
+	enabled := c.evaluate(featureKey, stickinessID).enabled
 	c.logger.Debug("feature flag check (stub)",
 		zap.String("feature_key", featureKey),
-		zap.Bool("enabled", false))
-	return false
+		zap.Bool("enabled", enabled))
+	return enabled
 }
 
 // GetVariant gets a feature variant - STUB returns empty variant
 func (c *UnleashClient) GetVariant(featureKey string, context *FeatureContext) Variant {
+	c.mu.RLock()
+	variant, ok := c.variantOverrides[featureKey]
+	c.mu.RUnlock()
+	if ok {
+		return variant
+	}
+
 	c.logger.Debug("feature variant check (stub)",
 		zap.String("feature_key", featureKey))
 	return Variant{
@@ -82,6 +286,13 @@ func (c *UnleashClient) GetFeatureToggles() []Feature {
 	return []Feature{}
 }
 
+// GetFeatureDefinition returns the toggle definition for a feature - STUB knows no features
+func (c *UnleashClient) GetFeatureDefinition(featureKey string) (FeatureDefinition, bool) {
+	c.logger.Debug("get feature definition (stub)",
+		zap.String("feature_key", featureKey))
+	return FeatureDefinition{}, false
+}
+
 // IsReady checks if the client is ready - STUB returns true
 func (c *UnleashClient) IsReady() bool {
 	return true
@@ -13,6 +13,8 @@ import (
 	"github.com/haunted-saas/llm-gateway-service/internal"
 	"github.com/haunted-saas/llm-gateway-service/internal/config"
 	pb "github.com/haunted-saas/llm-gateway-service/proto/llm/v1"
+	"github.com/haunted-saas/pkg/deadline"
+	"github.com/haunted-saas/pkg/shutdown"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc"
@@ -29,7 +31,7 @@ func main() {
 	}
 
 	// Initialize logger
-	logger, err := initLogger(cfg.Logging.Level, cfg.Logging.Format)
+	logger, err := initLogger(cfg.Logging.Level, cfg.Logging.Format, cfg.Logging.Sampling.Zap())
 	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
@@ -46,6 +48,8 @@ func main() {
 		logger.Fatal("Failed to create prompt loader", zap.Error(err))
 	}
 	defer promptLoader.Close()
+	promptLoader.SetIgnorePatterns(cfg.Prompts.IgnorePatterns)
+	promptLoader.SetCacheMaxEntries(cfg.Prompts.CacheMaxEntries)
 
 	// Load all prompts
 	if err := promptLoader.LoadAllPrompts(); err != nil {
@@ -61,6 +65,9 @@ func main() {
 
 	// Initialize LLM providers
 	router := internal.NewLLMRouter(cfg.LLM.DefaultProvider, logger)
+	for prefix, providerName := range cfg.LLM.ModelFamilies {
+		router.RegisterModelFamily(prefix, providerName)
+	}
 
 	// Register OpenAI provider
 	openaiProvider, err := internal.NewOpenAIProvider(cfg.LLM.OpenAIAPIKey, cfg.LLM.TestMode, logger)
@@ -76,13 +83,78 @@ func main() {
 	// Initialize usage tracker
 	usageTracker := internal.NewUsageTracker(cfg.Analytics.UsageStoreMaxSize, logger)
 
+	// Forwarding usage to analytics-service is opt-in
+	if cfg.Analytics.ForwardUsage {
+		forwarder, err := internal.NewAnalyticsForwarder(
+			cfg.Analytics.ServiceAddr,
+			cfg.Analytics.UsageEventName,
+			time.Duration(cfg.Analytics.TimeoutSec)*time.Second,
+		)
+		if err != nil {
+			logger.Fatal("Failed to create analytics forwarder", zap.Error(err))
+		}
+		defer forwarder.Close()
+		usageTracker.SetForwarder(forwarder)
+		logger.Info("Forwarding usage to analytics-service",
+			zap.String("address", cfg.Analytics.ServiceAddr),
+			zap.String("event_name", cfg.Analytics.UsageEventName))
+	}
+
 	// Initialize gRPC server
 	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(loggingInterceptor(logger)),
+		grpc.ChainUnaryInterceptor(
+			loggingInterceptor(logger),
+			deadline.UnaryServerInterceptor(cfg.Server.DefaultDeadline),
+		),
 	)
 
 	// Register LLM gateway service
 	llmService := internal.NewLLMGatewayServer(promptLoader, router, usageTracker, logger)
+	llmService.SetMaxPromptBytes(cfg.LLM.MaxPromptBytes)
+	llmService.SetMaxVariablesBytes(cfg.LLM.MaxVariablesBytes)
+	llmService.SetTimeoutBounds(
+		time.Duration(cfg.LLM.MinTimeout)*time.Second,
+		time.Duration(cfg.LLM.DefaultTimeout)*time.Second,
+		time.Duration(cfg.LLM.MaxTimeout)*time.Second,
+	)
+	llmService.SetServiceDefaultModels(cfg.LLM.ServiceModels)
+	if len(cfg.LLM.TemperatureBounds) > 0 {
+		bounds := make(map[string]internal.TemperatureBounds, len(cfg.LLM.TemperatureBounds))
+		for prefix, r := range cfg.LLM.TemperatureBounds {
+			bounds[prefix] = internal.TemperatureBounds{Min: r.Min, Max: r.Max}
+		}
+		llmService.SetModelTemperatureBounds(internal.NewModelTemperatureBounds(bounds, cfg.LLM.TemperaturePolicy))
+	}
+
+	// Progress notifications are sent only for requests that opt in
+	if cfg.Notifications.ServiceAddr != "" {
+		notificationsClient, err := internal.NewNotificationsClient(
+			cfg.Notifications.ServiceAddr,
+			time.Duration(cfg.Notifications.TimeoutSec)*time.Second,
+		)
+		if err != nil {
+			logger.Fatal("Failed to create notifications client", zap.Error(err))
+		}
+		defer notificationsClient.Close()
+		llmService.SetProgressNotifier(notificationsClient)
+		logger.Info("Progress notifications enabled",
+			zap.String("address", cfg.Notifications.ServiceAddr))
+	}
+
+	// Prompt input/output auditing is opt-in and off unless a log path is set
+	if cfg.Audit.LogPath != "" {
+		auditSink, err := internal.NewFilePromptAuditSink(cfg.Audit.LogPath)
+		if err != nil {
+			logger.Fatal("Failed to open prompt audit log", zap.Error(err))
+		}
+		defer auditSink.Close()
+		llmService.SetPromptAuditor(internal.NewPromptAuditor(auditSink, cfg.Audit.AuditAll, cfg.Audit.RedactPII, cfg.Audit.MaxPending, logger))
+		logger.Info("Prompt auditing enabled",
+			zap.String("path", cfg.Audit.LogPath),
+			zap.Bool("audit_all", cfg.Audit.AuditAll),
+			zap.Bool("redact_pii", cfg.Audit.RedactPII))
+	}
+
 	pb.RegisterLLMGatewayServiceServer(grpcServer, llmService)
 
 	// Register health check
@@ -114,12 +186,15 @@ func main() {
 	logger.Info("Shutting down server...")
 
 	// Graceful shutdown
-	grpcServer.GracefulStop()
+	if !shutdown.DrainGRPC(grpcServer, cfg.Server.DrainTimeout) {
+		logger.Warn("In-flight requests did not finish in time, forced gRPC server stop",
+			zap.Duration("timeout", cfg.Server.DrainTimeout))
+	}
 	logger.Info("Server stopped")
 }
 
-// initLogger initializes the logger
-func initLogger(level, format string) (*zap.Logger, error) {
+// initLogger initializes the logger. A nil sampling config disables sampling.
+func initLogger(level, format string, sampling *zap.SamplingConfig) (*zap.Logger, error) {
 	var zapLevel zapcore.Level
 	switch level {
 	case "debug":
@@ -136,6 +211,7 @@ func initLogger(level, format string) (*zap.Logger, error) {
 
 	config := zap.NewProductionConfig()
 	config.Level = zap.NewAtomicLevelAt(zapLevel)
+	config.Sampling = sampling
 
 	if format == "console" {
 		config.Encoding = "console"
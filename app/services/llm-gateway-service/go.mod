@@ -4,6 +4,9 @@ go 1.21
 
 require (
 	github.com/fsnotify/fsnotify v1.7.0
+	github.com/haunted-saas/analytics-service v0.0.0
+	github.com/haunted-saas/notifications-service v0.0.0
+	github.com/haunted-saas/pkg v0.0.0
 	github.com/sashabaranov/go-openai v1.20.0
 	github.com/stretchr/testify v1.8.4
 	go.uber.org/zap v1.26.0
@@ -22,3 +25,9 @@ require (
 	golang.org/x/text v0.14.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240116215550-a9fa1716bcac // indirect
 )
+
+replace (
+	github.com/haunted-saas/analytics-service => ../analytics-service
+	github.com/haunted-saas/notifications-service => ../notifications-service
+	github.com/haunted-saas/pkg => ../../pkg
+)
@@ -0,0 +1,97 @@
+package internal
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	analyticsv1 "github.com/haunted-saas/analytics-service/proto/analytics/v1"
+	"github.com/haunted-saas/pkg/grpcclient"
+)
+
+// DefaultUsageEventName is the analytics event recorded for each LLM call
+const DefaultUsageEventName = "llm.call"
+
+// UsageForwarder sends usage events to an external analytics store
+type UsageForwarder interface {
+	ForwardUsage(ctx context.Context, event *UsageEvent) error
+}
+
+// AnalyticsForwarder records usage events in analytics-service as
+// TrackEvent calls so LLM usage shows up in product analytics
+type AnalyticsForwarder struct {
+	conn      *grpcclient.Conn
+	client    analyticsv1.AnalyticsServiceClient
+	eventName string
+}
+
+// NewAnalyticsForwarder connects to analytics-service
+func NewAnalyticsForwarder(address, eventName string, timeout time.Duration) (*AnalyticsForwarder, error) {
+	conn, err := grpcclient.Dial("analytics-service", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if eventName == "" {
+		eventName = DefaultUsageEventName
+	}
+
+	return &AnalyticsForwarder{
+		conn:      conn,
+		client:    analyticsv1.NewAnalyticsServiceClient(conn),
+		eventName: eventName,
+	}, nil
+}
+
+// ForwardUsage records a usage event in analytics-service
+func (f *AnalyticsForwarder) ForwardUsage(ctx context.Context, event *UsageEvent) error {
+	ctx, cancel := f.conn.Bound(ctx)
+	defer cancel()
+
+	_, err := f.client.TrackEvent(ctx, &analyticsv1.TrackEventRequest{
+		EventName:  f.eventName,
+		Properties: usageEventProperties(event),
+		Timestamp:  event.Timestamp.Unix(),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to track %s event: %w", f.eventName, err)
+	}
+	return nil
+}
+
+// Close closes the connection to analytics-service
+func (f *AnalyticsForwarder) Close() error {
+	return f.conn.Close()
+}
+
+// usageEventProperties converts a usage event to analytics event properties
+func usageEventProperties(event *UsageEvent) map[string]*analyticsv1.PropertyValue {
+	properties := map[string]*analyticsv1.PropertyValue{
+		"request_id":        stringProperty(event.RequestID),
+		"prompt_path":       stringProperty(event.PromptPath),
+		"calling_service":   stringProperty(event.CallingService),
+		"provider":          stringProperty(event.Provider),
+		"model":             stringProperty(event.Model),
+		"prompt_tokens":     numberProperty(float64(event.PromptTokens)),
+		"completion_tokens": numberProperty(float64(event.CompletionTokens)),
+		"total_tokens":      numberProperty(float64(event.TotalTokens)),
+		"response_time_ms":  numberProperty(float64(event.ResponseTimeMs)),
+		"success":           boolProperty(event.Success),
+	}
+	if event.ErrorMessage != "" {
+		properties["error_message"] = stringProperty(event.ErrorMessage)
+	}
+	return properties
+}
+
+func stringProperty(value string) *analyticsv1.PropertyValue {
+	return &analyticsv1.PropertyValue{Value: &analyticsv1.PropertyValue_StringValue{StringValue: value}}
+}
+
+func numberProperty(value float64) *analyticsv1.PropertyValue {
+	return &analyticsv1.PropertyValue{Value: &analyticsv1.PropertyValue_NumberValue{NumberValue: value}}
+}
+
+func boolProperty(value bool) *analyticsv1.PropertyValue {
+	return &analyticsv1.PropertyValue{Value: &analyticsv1.PropertyValue_BoolValue{BoolValue: value}}
+}
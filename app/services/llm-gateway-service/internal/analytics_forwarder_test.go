@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	analyticsv1 "github.com/haunted-saas/analytics-service/proto/analytics/v1"
+	pb "github.com/haunted-saas/llm-gateway-service/proto/llm/v1"
+	"github.com/haunted-saas/pkg/grpcclient"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// recordingAnalyticsClient hands tracked events to the test as they arrive
+type recordingAnalyticsClient struct {
+	analyticsv1.AnalyticsServiceClient
+	events chan *analyticsv1.TrackEventRequest
+}
+
+func (c *recordingAnalyticsClient) TrackEvent(ctx context.Context, in *analyticsv1.TrackEventRequest, opts ...grpc.CallOption) (*analyticsv1.TrackEventResponse, error) {
+	c.events <- in
+	return &analyticsv1.TrackEventResponse{}, nil
+}
+
+func TestLLMGatewayServer_CallPrompt_ForwardsUsageToAnalytics(t *testing.T) {
+	server := newAuditTestServer(t, greetingPrompt("greeting.txt", nil))
+
+	client := &recordingAnalyticsClient{events: make(chan *analyticsv1.TrackEventRequest, 1)}
+	server.usageTracker.SetForwarder(&AnalyticsForwarder{
+		conn:      &grpcclient.Conn{Timeout: time.Second},
+		client:    client,
+		eventName: DefaultUsageEventName,
+	})
+
+	_, err := server.CallPrompt(context.Background(), &pb.CallPromptRequest{
+		PromptPath:     "greeting.txt",
+		VariablesJson:  `{"name": "Ada"}`,
+		CallingService: "billing-service",
+		Model:          "gpt-4",
+	})
+	require.NoError(t, err)
+
+	select {
+	case event := <-client.events:
+		assert.Equal(t, "llm.call", event.EventName)
+		assert.Equal(t, "greeting.txt", event.Properties["prompt_path"].GetStringValue())
+		assert.Equal(t, "billing-service", event.Properties["calling_service"].GetStringValue())
+		assert.Equal(t, "gpt-4", event.Properties["model"].GetStringValue())
+		assert.True(t, event.Properties["success"].GetBoolValue())
+		assert.NotZero(t, event.Timestamp)
+	case <-time.After(time.Second):
+		t.Fatal("expected the call to be forwarded to analytics-service")
+	}
+}
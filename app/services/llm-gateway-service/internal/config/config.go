@@ -3,28 +3,39 @@ package config
 import (
 	"fmt"
 	"os"
+	"path"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/haunted-saas/pkg/logsampling"
 )
 
 // Config holds the service configuration
 type Config struct {
-	Server    ServerConfig
-	Prompts   PromptsConfig
-	LLM       LLMConfig
-	Analytics AnalyticsConfig
-	Logging   LoggingConfig
+	Server        ServerConfig
+	Prompts       PromptsConfig
+	LLM           LLMConfig
+	Analytics     AnalyticsConfig
+	Notifications NotificationsConfig
+	Audit         AuditConfig
+	Logging       LoggingConfig
 }
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
-	GRPCPort int
-	Host     string
+	GRPCPort        int
+	Host            string
+	DefaultDeadline time.Duration // Applied to requests that arrive without a deadline (0 disables)
+	DrainTimeout    time.Duration // Bounds the wait for in-flight RPCs on shutdown (0 waits indefinitely)
 }
 
 // PromptsConfig holds prompts configuration
 type PromptsConfig struct {
-	Directory  string
-	WatchMode  bool
+	Directory       string
+	WatchMode       bool
+	IgnorePatterns  []string // Globs for files and directories skipped by loading and watching
+	CacheMaxEntries int      // Prompts kept in memory before LRU eviction (0 means unbounded)
 }
 
 // LLMConfig holds LLM provider configuration
@@ -32,58 +43,121 @@ type LLMConfig struct {
 	OpenAIAPIKey       string
 	DefaultProvider    string
 	DefaultModel       string
+	MinTimeout         int
 	DefaultTimeout     int
 	MaxTimeout         int
 	TestMode           bool
 	MaxRetryAttempts   int
 	InitialRetryDelayMs int
 	MaxRetryDelayMs    int
+	ModelFamilies      map[string]string
+	MaxPromptBytes     int
+	MaxVariablesBytes  int
+	ServiceModels      map[string]string // calling service -> default model
+
+	// Per-model temperature ranges keyed by model name prefix, narrower than the global 0-2
+	TemperatureBounds map[string]TemperatureRange
+	TemperaturePolicy string // "reject" or "clamp" a temperature outside its model's range
+}
+
+// TemperatureRange is an inclusive temperature range
+type TemperatureRange struct {
+	Min float32
+	Max float32
 }
 
 // AnalyticsConfig holds analytics configuration
 type AnalyticsConfig struct {
 	ServiceAddr      string
 	UsageStoreMaxSize int
+	ForwardUsage     bool   // Send each usage event to analytics-service as a TrackEvent
+	UsageEventName   string // Analytics event name for forwarded usage
+	TimeoutSec       int    // Per-event timeout for forwarding
+}
+
+// NotificationsConfig holds the optional notifications-service connection
+type NotificationsConfig struct {
+	ServiceAddr string // Empty disables progress notifications
+	TimeoutSec  int    // Per-event timeout
+}
+
+// AuditConfig holds opt-in prompt input/output auditing configuration
+type AuditConfig struct {
+	LogPath    string // JSON lines file for audit records; empty disables auditing
+	AuditAll   bool   // Audit every prompt, not just those with "audit: true" frontmatter
+	RedactPII  bool   // Mask emails and phone numbers before recording
+	MaxPending int    // Records written at once; more are dropped
 }
 
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
-	Level  string
-	Format string
+	Level    string
+	Format   string
+	Sampling logsampling.Config
 }
 
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
 	cfg := &Config{
 		Server: ServerConfig{
-			GRPCPort: getEnvInt("GRPC_PORT", 50053),
-			Host:     getEnv("HOST", "0.0.0.0"),
+			GRPCPort:        getEnvInt("GRPC_PORT", 50053),
+			Host:            getEnv("HOST", "0.0.0.0"),
+			DefaultDeadline: time.Duration(getEnvInt("DEFAULT_REQUEST_DEADLINE_SECONDS", 150)) * time.Second,
+			DrainTimeout:    time.Duration(getEnvInt("GRPC_DRAIN_TIMEOUT_SECONDS", 30)) * time.Second,
 		},
 		Prompts: PromptsConfig{
-			Directory: getEnv("PROMPTS_DIR", "/app/prompts"),
-			WatchMode: getEnvBool("WATCH_PROMPTS", true),
+			Directory:       getEnv("PROMPTS_DIR", "/app/prompts"),
+			WatchMode:       getEnvBool("WATCH_PROMPTS", true),
+			IgnorePatterns:  getEnvList("PROMPTS_IGNORE", ".*,*~"),
+			CacheMaxEntries: getEnvInt("PROMPT_CACHE_MAX_ENTRIES", 0),
 		},
 		LLM: LLMConfig{
 			OpenAIAPIKey:       getEnv("OPENAI_API_KEY", ""),
 			DefaultProvider:    getEnv("DEFAULT_PROVIDER", "openai"),
 			DefaultModel:       getEnv("DEFAULT_MODEL", "gpt-4-turbo-preview"),
+			MinTimeout:         getEnvInt("MIN_TIMEOUT_SECONDS", 5),
 			DefaultTimeout:     getEnvInt("DEFAULT_TIMEOUT_SECONDS", 30),
 			MaxTimeout:         getEnvInt("MAX_TIMEOUT_SECONDS", 120),
 			TestMode:           getEnvBool("TEST_MODE", false),
 			MaxRetryAttempts:   getEnvInt("MAX_RETRY_ATTEMPTS", 3),
 			InitialRetryDelayMs: getEnvInt("INITIAL_RETRY_DELAY_MS", 1000),
 			MaxRetryDelayMs:    getEnvInt("MAX_RETRY_DELAY_MS", 10000),
+			ModelFamilies:      getEnvMap("MODEL_PROVIDER_MAP"),
+			MaxPromptBytes:     getEnvInt("MAX_PROMPT_BYTES", 102400),
+			MaxVariablesBytes:  getEnvInt("MAX_VARIABLES_BYTES", 65536),
+			ServiceModels:      getEnvMap("SERVICE_DEFAULT_MODELS"),
+			TemperaturePolicy:  getEnv("MODEL_TEMPERATURE_POLICY", "reject"),
 		},
 		Analytics: AnalyticsConfig{
 			ServiceAddr:      getEnv("ANALYTICS_SERVICE_ADDR", "analytics-service:50051"),
 			UsageStoreMaxSize: getEnvInt("USAGE_STORE_MAX_SIZE", 10000),
+			ForwardUsage:     getEnvBool("ANALYTICS_FORWARD_USAGE", false),
+			UsageEventName:   getEnv("ANALYTICS_USAGE_EVENT_NAME", "llm.call"),
+			TimeoutSec:       getEnvInt("ANALYTICS_TIMEOUT_SECONDS", 2),
+		},
+		Notifications: NotificationsConfig{
+			ServiceAddr: getEnv("NOTIFICATIONS_SERVICE_ADDR", ""),
+			TimeoutSec:  getEnvInt("NOTIFICATIONS_TIMEOUT_SECONDS", 2),
+		},
+		Audit: AuditConfig{
+			LogPath:    getEnv("PROMPT_AUDIT_LOG_PATH", ""),
+			AuditAll:   getEnvBool("PROMPT_AUDIT_ALL", false),
+			RedactPII:  getEnvBool("PROMPT_AUDIT_REDACT_PII", true),
+			MaxPending: getEnvInt("PROMPT_AUDIT_MAX_PENDING", 100),
 		},
 		Logging: LoggingConfig{
-			Level:  getEnv("LOG_LEVEL", "info"),
-			Format: getEnv("LOG_FORMAT", "json"),
+			Level:    getEnv("LOG_LEVEL", "info"),
+			Format:   getEnv("LOG_FORMAT", "json"),
+			Sampling: logsampling.FromEnv(),
 		},
 	}
 
+	bounds, err := parseTemperatureBounds(getEnvMap("MODEL_TEMPERATURE_BOUNDS"))
+	if err != nil {
+		return nil, err
+	}
+	cfg.LLM.TemperatureBounds = bounds
+
 	// Validate configuration
 	if err := cfg.Validate(); err != nil {
 		return nil, err
@@ -103,10 +177,74 @@ func (c *Config) Validate() error {
 	if c.Prompts.Directory == "" {
 		return fmt.Errorf("PROMPTS_DIR is required")
 	}
+	for _, pattern := range c.Prompts.IgnorePatterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid PROMPTS_IGNORE pattern %q: %w", pattern, err)
+		}
+	}
+	if c.Prompts.CacheMaxEntries < 0 {
+		return fmt.Errorf("PROMPT_CACHE_MAX_ENTRIES cannot be negative")
+	}
 
 	// Validate timeouts
-	if c.LLM.DefaultTimeout < 5 || c.LLM.DefaultTimeout > c.LLM.MaxTimeout {
-		return fmt.Errorf("invalid timeout configuration")
+	if c.LLM.MinTimeout < 1 {
+		return fmt.Errorf("MIN_TIMEOUT_SECONDS must be at least 1")
+	}
+	if c.LLM.DefaultTimeout < c.LLM.MinTimeout || c.LLM.DefaultTimeout > c.LLM.MaxTimeout {
+		return fmt.Errorf("invalid timeout configuration: need MIN_TIMEOUT_SECONDS <= DEFAULT_TIMEOUT_SECONDS <= MAX_TIMEOUT_SECONDS")
+	}
+
+	// Validate prompt size limit (0 disables it)
+	if c.LLM.MaxPromptBytes < 0 {
+		return fmt.Errorf("MAX_PROMPT_BYTES cannot be negative")
+	}
+	if c.LLM.MaxVariablesBytes < 0 {
+		return fmt.Errorf("MAX_VARIABLES_BYTES cannot be negative")
+	}
+
+	for prefix, bounds := range c.LLM.TemperatureBounds {
+		if bounds.Min < 0 || bounds.Max > 2.0 || bounds.Min > bounds.Max {
+			return fmt.Errorf("MODEL_TEMPERATURE_BOUNDS for %q must satisfy 0 <= min <= max <= 2", prefix)
+		}
+	}
+	if c.LLM.TemperaturePolicy != "reject" && c.LLM.TemperaturePolicy != "clamp" {
+		return fmt.Errorf("MODEL_TEMPERATURE_POLICY must be \"reject\" or \"clamp\"")
+	}
+
+	if c.Audit.AuditAll && c.Audit.LogPath == "" {
+		return fmt.Errorf("PROMPT_AUDIT_ALL requires PROMPT_AUDIT_LOG_PATH")
+	}
+	if c.Audit.LogPath != "" && c.Audit.MaxPending < 1 {
+		return fmt.Errorf("PROMPT_AUDIT_MAX_PENDING must be at least 1")
+	}
+
+	if c.Analytics.ForwardUsage {
+		if c.Analytics.ServiceAddr == "" {
+			return fmt.Errorf("ANALYTICS_SERVICE_ADDR is required when ANALYTICS_FORWARD_USAGE is enabled")
+		}
+		if c.Analytics.TimeoutSec < 1 {
+			return fmt.Errorf("ANALYTICS_TIMEOUT_SECONDS must be at least 1")
+		}
+	}
+
+	if c.Notifications.ServiceAddr != "" && c.Notifications.TimeoutSec < 1 {
+		return fmt.Errorf("NOTIFICATIONS_TIMEOUT_SECONDS must be at least 1")
+	}
+
+	if err := c.Logging.Sampling.Validate(); err != nil {
+		return err
+	}
+
+	if c.Server.DefaultDeadline < 0 {
+		return fmt.Errorf("DEFAULT_REQUEST_DEADLINE_SECONDS cannot be negative")
+	}
+	// A shorter default deadline would cut off long CallPrompt requests
+	if c.Server.DefaultDeadline > 0 && c.Server.DefaultDeadline < time.Duration(c.LLM.MaxTimeout)*time.Second {
+		return fmt.Errorf("DEFAULT_REQUEST_DEADLINE_SECONDS must be 0 or at least MAX_TIMEOUT_SECONDS")
+	}
+
+	if c.Server.DrainTimeout < 0 {
+		return fmt.Errorf("GRPC_DRAIN_TIMEOUT_SECONDS cannot be negative")
 	}
 
 	return nil
@@ -130,6 +268,64 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvMap parses "key=value,key=value" pairs (e.g. "mistral-=mistral,gemini-=google")
+func getEnvMap(key string) map[string]string {
+	result := make(map[string]string)
+	value := os.Getenv(key)
+	if value == "" {
+		return result
+	}
+
+	for _, pair := range strings.Split(value, ",") {
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		k := strings.TrimSpace(parts[0])
+		v := strings.TrimSpace(parts[1])
+		if k != "" && v != "" {
+			result[k] = v
+		}
+	}
+	return result
+}
+
+// parseTemperatureBounds parses "min:max" ranges from MODEL_TEMPERATURE_BOUNDS,
+// e.g. "claude-=0:1,gpt-4o=0:1.5"
+func parseTemperatureBounds(ranges map[string]string) (map[string]TemperatureRange, error) {
+	result := make(map[string]TemperatureRange, len(ranges))
+	for prefix, value := range ranges {
+		parts := strings.SplitN(value, ":", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid MODEL_TEMPERATURE_BOUNDS range %q for %q, expected min:max", value, prefix)
+		}
+		minTemp, errMin := strconv.ParseFloat(strings.TrimSpace(parts[0]), 32)
+		maxTemp, errMax := strconv.ParseFloat(strings.TrimSpace(parts[1]), 32)
+		if errMin != nil || errMax != nil {
+			return nil, fmt.Errorf("invalid MODEL_TEMPERATURE_BOUNDS range %q for %q, expected min:max", value, prefix)
+		}
+		result[prefix] = TemperatureRange{Min: float32(minTemp), Max: float32(maxTemp)}
+	}
+	return result, nil
+}
+
+// getEnvList parses a comma-separated list, dropping empty entries. Unlike
+// getEnv, a variable set to "" yields an empty list rather than the default.
+func getEnvList(key, defaultValue string) []string {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		value = defaultValue
+	}
+
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
 func getEnvBool(key string, defaultValue bool) bool {
 	if value := os.Getenv(key); value != "" {
 		if boolValue, err := strconv.ParseBool(value); err == nil {
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"strings"
 	"time"
 
@@ -21,10 +22,37 @@ type LLMGatewayServer struct {
 	router         *LLMRouter
 	usageTracker   *UsageTracker
 	logger         *zap.Logger
+	minTimeout     time.Duration
 	defaultTimeout time.Duration
 	maxTimeout     time.Duration
+	maxPromptBytes int
+	maxVarsBytes   int
+
+	// Default model per calling service, used when neither the request nor the prompt sets one
+	serviceDefaultModels map[string]string
+
+	// Records rendered prompts and responses when set (nil disables auditing)
+	auditor *PromptAuditor
+
+	// Sends start/complete events for requests that opt in (nil disables them)
+	progress ProgressNotifier
+
+	// Enforces the quota in prompts' metadata
+	quotas *PromptQuotaLimiter
+
+	// Narrows the global temperature range for specific models (nil disables)
+	temperatureBounds *ModelTemperatureBounds
 }
 
+// defaultMaxPromptBytes caps the size of a rendered prompt sent to a provider
+const defaultMaxPromptBytes = 100 * 1024
+
+// defaultMaxVariablesBytes caps the size of the variables JSON in a request
+const defaultMaxVariablesBytes = 64 * 1024
+
+// maxValidatePromptBytes caps the prompt content accepted by ValidatePrompt
+const maxValidatePromptBytes = 1024 * 1024
+
 // NewLLMGatewayServer creates a new LLM gateway server
 func NewLLMGatewayServer(
 	promptLoader *PromptLoader,
@@ -37,11 +65,54 @@ func NewLLMGatewayServer(
 		router:         router,
 		usageTracker:   usageTracker,
 		logger:         logger,
+		minTimeout:     5 * time.Second,
 		defaultTimeout: 30 * time.Second,
 		maxTimeout:     120 * time.Second,
+		maxPromptBytes: defaultMaxPromptBytes,
+		maxVarsBytes:   defaultMaxVariablesBytes,
+		quotas:         NewPromptQuotaLimiter(),
 	}
 }
 
+// SetMaxPromptBytes sets the maximum rendered prompt size (0 disables the limit)
+func (s *LLMGatewayServer) SetMaxPromptBytes(maxBytes int) {
+	s.maxPromptBytes = maxBytes
+}
+
+// SetMaxVariablesBytes sets the maximum variables JSON size (0 disables the limit)
+func (s *LLMGatewayServer) SetMaxVariablesBytes(maxBytes int) {
+	s.maxVarsBytes = maxBytes
+}
+
+// SetTimeoutBounds sets the allowed request timeout range and the timeout
+// used when a request doesn't set one
+func (s *LLMGatewayServer) SetTimeoutBounds(minTimeout, defaultTimeout, maxTimeout time.Duration) {
+	s.minTimeout = minTimeout
+	s.defaultTimeout = defaultTimeout
+	s.maxTimeout = maxTimeout
+}
+
+// SetServiceDefaultModels sets the default model for each calling service
+func (s *LLMGatewayServer) SetServiceDefaultModels(models map[string]string) {
+	s.serviceDefaultModels = models
+}
+
+// SetModelTemperatureBounds enables per-model temperature bounds, checked
+// after the global range once the model for a call is known
+func (s *LLMGatewayServer) SetModelTemperatureBounds(bounds *ModelTemperatureBounds) {
+	s.temperatureBounds = bounds
+}
+
+// SetPromptAuditor enables opt-in auditing of prompt inputs and outputs
+func (s *LLMGatewayServer) SetPromptAuditor(auditor *PromptAuditor) {
+	s.auditor = auditor
+}
+
+// SetProgressNotifier enables progress events for requests that set notify_progress
+func (s *LLMGatewayServer) SetProgressNotifier(notifier ProgressNotifier) {
+	s.progress = notifier
+}
+
 // CallPrompt executes a prompt with variables
 func (s *LLMGatewayServer) CallPrompt(ctx context.Context, req *pb.CallPromptRequest) (*pb.CallPromptResponse, error) {
 	startTime := time.Now()
@@ -63,6 +134,29 @@ func (s *LLMGatewayServer) CallPrompt(ctx context.Context, req *pb.CallPromptReq
 		return nil, status.Error(codes.InvalidArgument, "invalid prompt path")
 	}
 
+	if req.NotifyProgress && req.UserId == "" {
+		return nil, status.Error(codes.InvalidArgument, "user_id is required when notify_progress is set")
+	}
+
+	// Enforce variables size limit before the JSON is parsed
+	if s.maxVarsBytes > 0 && len(req.VariablesJson) > s.maxVarsBytes {
+		s.logger.Warn("variables exceed size limit",
+			zap.String("prompt_path", req.PromptPath),
+			zap.Int("size_bytes", len(req.VariablesJson)),
+			zap.Int("max_bytes", s.maxVarsBytes))
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("variables_json is %d bytes, exceeds limit of %d bytes", len(req.VariablesJson), s.maxVarsBytes))
+	}
+
+	// Determine timeout
+	timeout := s.defaultTimeout
+	if req.TimeoutSeconds > 0 {
+		timeout = time.Duration(req.TimeoutSeconds) * time.Second
+		if timeout < s.minTimeout || timeout > s.maxTimeout {
+			return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("timeout must be between %d and %d seconds",
+				int(s.minTimeout.Seconds()), int(s.maxTimeout.Seconds())))
+		}
+	}
+
 	// Load prompt from cache
 	prompt, err := s.promptLoader.GetPrompt(req.PromptPath)
 	if err != nil {
@@ -72,6 +166,14 @@ func (s *LLMGatewayServer) CallPrompt(ctx context.Context, req *pb.CallPromptReq
 		return nil, status.Error(codes.NotFound, fmt.Sprintf("prompt not found: %s", req.PromptPath))
 	}
 
+	// Enforce the prompt's allowed_services
+	if !prompt.AllowsService(req.CallingService) {
+		s.logger.Warn("calling service not allowed to use prompt",
+			zap.String("prompt_path", req.PromptPath),
+			zap.String("calling_service", req.CallingService))
+		return nil, status.Error(codes.PermissionDenied, fmt.Sprintf("service %q is not allowed to call prompt %s", req.CallingService, req.PromptPath))
+	}
+
 	// Substitute variables
 	renderedPrompt, err := s.substituteVariables(prompt, req.VariablesJson)
 	if err != nil {
@@ -81,21 +183,21 @@ func (s *LLMGatewayServer) CallPrompt(ctx context.Context, req *pb.CallPromptReq
 		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("variable substitution failed: %v", err))
 	}
 
+	// Enforce rendered prompt size limit
+	if s.maxPromptBytes > 0 && len(renderedPrompt) > s.maxPromptBytes {
+		s.logger.Warn("rendered prompt exceeds size limit",
+			zap.String("prompt_path", req.PromptPath),
+			zap.Int("size_bytes", len(renderedPrompt)),
+			zap.Int("max_bytes", s.maxPromptBytes))
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("rendered prompt is %d bytes, exceeds limit of %d bytes", len(renderedPrompt), s.maxPromptBytes))
+	}
+
 	// Validate and apply parameters
 	params, err := s.validateParameters(req.Parameters, prompt)
 	if err != nil {
 		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid parameters: %v", err))
 	}
 
-	// Determine timeout
-	timeout := s.defaultTimeout
-	if req.TimeoutSeconds > 0 {
-		if req.TimeoutSeconds < 5 || req.TimeoutSeconds > 120 {
-			return nil, status.Error(codes.InvalidArgument, "timeout must be between 5 and 120 seconds")
-		}
-		timeout = time.Duration(req.TimeoutSeconds) * time.Second
-	}
-
 	// Build LLM request
 	llmReq := &LLMRequest{
 		Prompt:     renderedPrompt,
@@ -118,9 +220,59 @@ func (s *LLMGatewayServer) CallPrompt(ctx context.Context, req *pb.CallPromptReq
 		}
 	}
 
+	// Fall back to the calling service's default model before the router's provider default
+	if llmReq.Model == "" {
+		if model, ok := s.serviceDefaultModels[req.CallingService]; ok {
+			llmReq.Model = model
+		}
+	}
+
+	// Enforce the model's temperature bounds, including a prompt's default temperature
+	model := s.router.ResolveModel(llmReq.Model)
+	requested := params.Temperature
+	clamped, err := s.temperatureBounds.Apply(model, params)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("invalid parameters: %v", err))
+	}
+	if clamped {
+		s.logger.Info("temperature clamped to model bounds",
+			zap.String("prompt_path", req.PromptPath),
+			zap.String("model", model),
+			zap.Float32("requested", requested),
+			zap.Float32("applied", params.Temperature))
+	}
+
+	// Enforce the prompt's quota last so only calls that reach the provider count
+	if allowed, retryAfter := s.quotas.Allow(prompt, req.CallingService); !allowed {
+		quota := prompt.Metadata.Quota
+		s.logger.Warn("prompt quota exceeded",
+			zap.String("prompt_path", req.PromptPath),
+			zap.String("calling_service", req.CallingService),
+			zap.Int("requests", quota.Requests),
+			zap.Duration("window", quota.Window()))
+		return nil, status.Error(codes.ResourceExhausted, fmt.Sprintf("quota of %d calls per %s exceeded for prompt %s, retry in %ds",
+			quota.Requests, quota.Window(), req.PromptPath, int(math.Ceil(retryAfter.Seconds()))))
+	}
+
+	// Let opted-in users show progress while the provider works
+	started := s.notifyProgressAsync(req, EventCallStarted, &CallProgress{
+		RequestID:     requestID,
+		CorrelationID: req.CorrelationId,
+		PromptPath:    req.PromptPath,
+	}, nil)
+
 	// Route to LLM provider
 	llmResp, err := s.router.Route(ctx, llmReq)
 	if err != nil {
+		failed := false
+		s.notifyProgressAsync(req, EventCallCompleted, &CallProgress{
+			RequestID:      requestID,
+			CorrelationID:  req.CorrelationId,
+			PromptPath:     req.PromptPath,
+			Success:        &failed,
+			ResponseTimeMs: time.Since(startTime).Milliseconds(),
+		}, started)
+
 		s.logger.Error("LLM call failed",
 			zap.String("prompt_path", req.PromptPath),
 			zap.String("request_id", requestID),
@@ -138,6 +290,14 @@ func (s *LLMGatewayServer) CallPrompt(ctx context.Context, req *pb.CallPromptReq
 			ErrorMessage:   err.Error(),
 		})
 
+		s.auditPrompt(prompt, req, &PromptAuditRecord{
+			RequestID:      requestID,
+			Model:          llmReq.Model,
+			RenderedPrompt: renderedPrompt,
+			Success:        false,
+			ErrorMessage:   err.Error(),
+		})
+
 		// Map error to gRPC code
 		if isRateLimitError(err) {
 			return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
@@ -150,6 +310,15 @@ func (s *LLMGatewayServer) CallPrompt(ctx context.Context, req *pb.CallPromptReq
 
 	responseTime := time.Since(startTime)
 
+	succeeded := true
+	s.notifyProgressAsync(req, EventCallCompleted, &CallProgress{
+		RequestID:      requestID,
+		CorrelationID:  req.CorrelationId,
+		PromptPath:     req.PromptPath,
+		Success:        &succeeded,
+		ResponseTimeMs: responseTime.Milliseconds(),
+	}, started)
+
 	// Track successful usage
 	s.trackUsageAsync(&UsageEvent{
 		RequestID:        requestID,
@@ -165,6 +334,14 @@ func (s *LLMGatewayServer) CallPrompt(ctx context.Context, req *pb.CallPromptReq
 		Success:          true,
 	})
 
+	s.auditPrompt(prompt, req, &PromptAuditRecord{
+		RequestID:      requestID,
+		Model:          llmResp.Model,
+		RenderedPrompt: renderedPrompt,
+		ResponseText:   llmResp.Text,
+		Success:        true,
+	})
+
 	s.logger.Info("CallPrompt completed",
 		zap.String("prompt_path", req.PromptPath),
 		zap.String("request_id", requestID),
@@ -205,6 +382,15 @@ func (s *LLMGatewayServer) substituteVariables(prompt *Prompt, variablesJSON str
 		}
 	}
 
+	// Check range targets are lists
+	for _, listVar := range prompt.ListVars {
+		if value, ok := variables[listVar]; ok {
+			if _, isList := value.([]interface{}); !isList {
+				return "", fmt.Errorf("variable %s must be an array", listVar)
+			}
+		}
+	}
+
 	// Execute template
 	var buf bytes.Buffer
 	if err := prompt.Template.Execute(&buf, variables); err != nil {
@@ -269,6 +455,46 @@ func (s *LLMGatewayServer) trackUsageAsync(event *UsageEvent) {
 	}()
 }
 
+// notifyProgressAsync sends a progress event to the request's user if the
+// request opted in. The event is sent after the after channel closes, so a
+// complete event never overtakes its start event. The returned channel closes
+// once the event has been sent; it is nil when no event is sent. Failures are
+// logged and never affect the call.
+func (s *LLMGatewayServer) notifyProgressAsync(req *pb.CallPromptRequest, eventType string, progress *CallProgress, after <-chan struct{}) <-chan struct{} {
+	if !req.NotifyProgress || s.progress == nil {
+		return nil
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if after != nil {
+			<-after
+		}
+
+		if err := s.progress.NotifyUser(context.Background(), req.UserId, eventType, progress); err != nil {
+			s.logger.Warn("failed to send progress notification",
+				zap.String("request_id", progress.RequestID),
+				zap.String("event_type", eventType),
+				zap.Error(err))
+		}
+	}()
+	return done
+}
+
+// auditPrompt records the execution if auditing is enabled for the prompt
+func (s *LLMGatewayServer) auditPrompt(prompt *Prompt, req *pb.CallPromptRequest, record *PromptAuditRecord) {
+	if s.auditor == nil || !s.auditor.ShouldAudit(prompt) {
+		return
+	}
+
+	record.CorrelationID = req.CorrelationId
+	record.PromptPath = req.PromptPath
+	record.CallingService = req.CallingService
+	record.Timestamp = time.Now()
+	s.auditor.RecordAsync(record)
+}
+
 // GetPromptMetadata returns metadata for a prompt
 func (s *LLMGatewayServer) GetPromptMetadata(ctx context.Context, req *pb.GetPromptMetadataRequest) (*pb.GetPromptMetadataResponse, error) {
 	if req.PromptPath == "" {
@@ -290,14 +516,23 @@ func (s *LLMGatewayServer) GetPromptMetadata(ctx context.Context, req *pb.GetPro
 
 // ListPrompts lists all available prompts
 func (s *LLMGatewayServer) ListPrompts(ctx context.Context, req *pb.ListPromptsRequest) (*pb.ListPromptsResponse, error) {
-	prompts := s.promptLoader.ListPrompts(req.DirectoryFilter)
+	prompts := s.promptLoader.ListPrompts(req.DirectoryFilter, req.TagsFilter, req.ModelFilter)
 
 	promptInfos := make([]*pb.PromptInfo, len(prompts))
 	for i, prompt := range prompts {
+		var tags []string
+		var defaultModel string
+		if prompt.Metadata != nil {
+			tags = prompt.Metadata.Tags
+			defaultModel = prompt.Metadata.DefaultModel
+		}
+
 		promptInfos[i] = &pb.PromptInfo{
 			Path:         prompt.Path,
 			SizeBytes:    prompt.FileSizeBytes,
 			LastModified: prompt.LastModified.Format(time.RFC3339),
+			Tags:         tags,
+			DefaultModel: defaultModel,
 		}
 	}
 
@@ -308,16 +543,76 @@ func (s *LLMGatewayServer) ListPrompts(ctx context.Context, req *pb.ListPromptsR
 
 // GetUsageStats returns usage statistics
 func (s *LLMGatewayServer) GetUsageStats(ctx context.Context, req *pb.GetUsageStatsRequest) (*pb.GetUsageStatsResponse, error) {
-	stats, err := s.usageTracker.GetStats(req.TimeRange, req.CallingService)
+	if _, err := ParseUsageBucket(req.Bucket); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	stats, err := s.usageTracker.GetStats(req.TimeRange, req.CallingService, req.Bucket)
 	if err != nil {
 		return nil, status.Error(codes.Internal, fmt.Sprintf("failed to get stats: %v", err))
 	}
 
+	buckets := make([]*pb.UsageBucket, len(stats.Buckets))
+	for i, bucket := range stats.Buckets {
+		buckets[i] = &pb.UsageBucket{
+			Start:    bucket.Start.Format(time.RFC3339),
+			Requests: bucket.Requests,
+			Tokens:   bucket.Tokens,
+		}
+	}
+
 	return &pb.GetUsageStatsResponse{
 		TotalRequests:     stats.TotalRequests,
 		TotalTokens:       stats.TotalTokens,
 		RequestsByService: stats.RequestsByService,
 		TokensByModel:     stats.TokensByModel,
+		Buckets:           buckets,
+		PromptCache:       promptCacheStatsToProto(s.promptLoader.CacheStats()),
+	}, nil
+}
+
+// promptCacheStatsToProto converts prompt cache counters for the stats response
+func promptCacheStatsToProto(stats PromptCacheStats) *pb.PromptCacheStats {
+	return &pb.PromptCacheStats{
+		Size:       int32(stats.Size),
+		MaxEntries: int32(stats.MaxEntries),
+		Evictions:  stats.Evictions,
+		Hits:       stats.Hits,
+		Misses:     stats.Misses,
+	}
+}
+
+// ValidatePrompt lints prompt content for CI without loading it into the cache
+func (s *LLMGatewayServer) ValidatePrompt(ctx context.Context, req *pb.ValidatePromptRequest) (*pb.ValidatePromptResponse, error) {
+	if req.Content == "" {
+		return nil, status.Error(codes.InvalidArgument, "content is required")
+	}
+	if len(req.Content) > maxValidatePromptBytes {
+		return nil, status.Error(codes.InvalidArgument,
+			fmt.Sprintf("content is too large: %d bytes exceeds limit of %d", len(req.Content), maxValidatePromptBytes))
+	}
+
+	result := s.promptLoader.ValidatePromptContent([]byte(req.Content))
+
+	diagnostics := make([]*pb.PromptDiagnostic, len(result.Diagnostics))
+	for i, d := range result.Diagnostics {
+		diagnostics[i] = &pb.PromptDiagnostic{
+			Category: d.Category,
+			Message:  d.Message,
+			Variable: d.Variable,
+		}
+	}
+
+	var requiredVars []string
+	if result.Metadata != nil {
+		requiredVars = result.Metadata.RequiredVars
+	}
+
+	return &pb.ValidatePromptResponse{
+		Valid:             result.Valid(),
+		Diagnostics:       diagnostics,
+		UsedVariables:     result.UsedVars,
+		RequiredVariables: requiredVars,
 	}, nil
 }
 
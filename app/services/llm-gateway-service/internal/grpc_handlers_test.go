@@ -2,8 +2,11 @@ package internal
 
 import (
 	"context"
+	"encoding/json"
+	"strings"
 	"testing"
 	"text/template"
+	"time"
 
 	pb "github.com/haunted-saas/llm-gateway-service/proto/llm/v1"
 	"github.com/stretchr/testify/assert"
@@ -22,6 +25,7 @@ func TestLLMGatewayServer_SubstituteVariables(t *testing.T) {
 		promptContent string
 		variablesJSON string
 		requiredVars  []string
+		listVars      []string
 		expectError   bool
 		expectedText  string
 	}{
@@ -63,6 +67,23 @@ func TestLLMGatewayServer_SubstituteVariables(t *testing.T) {
 			requiredVars:  []string{"name"},
 			expectError:   true,
 		},
+		{
+			name:          "list variable",
+			promptContent: "{{range .items}}- {{.title}}\n{{end}}",
+			variablesJSON: `{"items": [{"title": "First"}, {"title": "Second"}]}`,
+			requiredVars:  []string{"items"},
+			listVars:      []string{"items"},
+			expectError:   false,
+			expectedText:  "- First\n- Second\n",
+		},
+		{
+			name:          "list variable is not an array",
+			promptContent: "{{range .items}}- {{.title}}\n{{end}}",
+			variablesJSON: `{"items": "First"}`,
+			requiredVars:  []string{"items"},
+			listVars:      []string{"items"},
+			expectError:   true,
+		},
 		{
 			name:          "empty variables",
 			promptContent: "Static prompt",
@@ -79,6 +100,7 @@ func TestLLMGatewayServer_SubstituteVariables(t *testing.T) {
 			prompt := &Prompt{
 				Content:      tt.promptContent,
 				RequiredVars: tt.requiredVars,
+				ListVars:     tt.listVars,
 			}
 
 			// Parse template
@@ -246,3 +268,352 @@ func TestLLMGatewayServer_CallPrompt_Validation(t *testing.T) {
 		})
 	}
 }
+
+func TestLLMGatewayServer_CallPrompt_RenderedPromptTooLarge(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	cache := NewPromptCache()
+	cache.Set("large.txt", &Prompt{
+		Path:         "large.txt",
+		Content:      "Summarize: {{.text}}",
+		Template:     template.Must(template.New("large.txt").Parse("Summarize: {{.text}}")),
+		RequiredVars: []string{"text"},
+	})
+	promptLoader := &PromptLoader{
+		cache:  cache,
+		logger: logger,
+	}
+
+	provider := &stubProvider{name: "openai"}
+	router := NewLLMRouter("openai", logger)
+	router.RegisterProvider(provider)
+	usageTracker := NewUsageTracker(1000, logger)
+
+	server := NewLLMGatewayServer(promptLoader, router, usageTracker, logger)
+	server.SetMaxPromptBytes(64)
+
+	variables, _ := json.Marshal(map[string]string{"text": strings.Repeat("a", 100)})
+	_, err := server.CallPrompt(context.Background(), &pb.CallPromptRequest{
+		PromptPath:    "large.txt",
+		VariablesJson: string(variables),
+	})
+
+	st, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Empty(t, provider.called, "provider should not be called for an over-limit prompt")
+}
+
+func TestLLMGatewayServer_CallPrompt_VariablesTooLarge(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	cache := NewPromptCache()
+	cache.Set("large.txt", &Prompt{
+		Path:         "large.txt",
+		Content:      "Summarize: {{.text}}",
+		Template:     template.Must(template.New("large.txt").Parse("Summarize: {{.text}}")),
+		RequiredVars: []string{"text"},
+	})
+	promptLoader := &PromptLoader{
+		cache:  cache,
+		logger: logger,
+	}
+
+	provider := &stubProvider{name: "openai"}
+	router := NewLLMRouter("openai", logger)
+	router.RegisterProvider(provider)
+	usageTracker := NewUsageTracker(1000, logger)
+
+	server := NewLLMGatewayServer(promptLoader, router, usageTracker, logger)
+	server.SetMaxVariablesBytes(64)
+
+	// Deliberately invalid JSON: a parse error here would mean it was unmarshalled
+	_, err := server.CallPrompt(context.Background(), &pb.CallPromptRequest{
+		PromptPath:    "large.txt",
+		VariablesJson: `{"text": "` + strings.Repeat("a", 100),
+	})
+
+	st, ok := status.FromError(err)
+	assert.True(t, ok)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	assert.Contains(t, st.Message(), "exceeds limit of 64 bytes")
+	assert.NotContains(t, st.Message(), "invalid JSON")
+	assert.Empty(t, provider.called, "provider should not be called for over-limit variables")
+}
+
+func TestLLMGatewayServer_CallPrompt_ServiceDefaultModel(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	cache := NewPromptCache()
+	cache.Set("plain.txt", &Prompt{
+		Path:     "plain.txt",
+		Content:  "Say hello",
+		Template: template.Must(template.New("plain.txt").Parse("Say hello")),
+	})
+	cache.Set("pinned.txt", &Prompt{
+		Path:     "pinned.txt",
+		Content:  "Say hello",
+		Template: template.Must(template.New("pinned.txt").Parse("Say hello")),
+		Metadata: &PromptMetadata{DefaultModel: "gpt-4"},
+	})
+	promptLoader := &PromptLoader{
+		cache:  cache,
+		logger: logger,
+	}
+
+	provider := &stubProvider{name: "openai"}
+	router := NewLLMRouter("openai", logger)
+	router.RegisterProvider(provider)
+	usageTracker := NewUsageTracker(1000, logger)
+
+	server := NewLLMGatewayServer(promptLoader, router, usageTracker, logger)
+	server.SetServiceDefaultModels(map[string]string{"billing-service": "gpt-3.5-turbo"})
+
+	tests := []struct {
+		name          string
+		request       *pb.CallPromptRequest
+		expectedModel string
+	}{
+		{
+			name:          "service default used when nothing else is set",
+			request:       &pb.CallPromptRequest{PromptPath: "plain.txt", CallingService: "billing-service"},
+			expectedModel: "gpt-3.5-turbo",
+		},
+		{
+			name:          "prompt frontmatter wins over service default",
+			request:       &pb.CallPromptRequest{PromptPath: "pinned.txt", CallingService: "billing-service"},
+			expectedModel: "gpt-4",
+		},
+		{
+			name:          "request model wins over service default",
+			request:       &pb.CallPromptRequest{PromptPath: "plain.txt", CallingService: "billing-service", Model: "gpt-4o"},
+			expectedModel: "gpt-4o",
+		},
+		{
+			name:          "unconfigured service falls through to the router",
+			request:       &pb.CallPromptRequest{PromptPath: "plain.txt", CallingService: "other-service"},
+			expectedModel: router.defaultModels["openai"],
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider.called = nil
+
+			_, err := server.CallPrompt(context.Background(), tt.request)
+			require.NoError(t, err)
+			require.Len(t, provider.called, 1)
+			assert.Equal(t, tt.expectedModel, provider.called[0])
+		})
+	}
+}
+
+func TestLLMGatewayServer_CallPrompt_AllowedServices(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	cache := NewPromptCache()
+	cache.Set("restricted.txt", &Prompt{
+		Path:     "restricted.txt",
+		Content:  "Summarize the invoice",
+		Template: template.Must(template.New("restricted.txt").Parse("Summarize the invoice")),
+		Metadata: &PromptMetadata{AllowedServices: []string{"billing-service"}},
+	})
+	cache.Set("open.txt", &Prompt{
+		Path:     "open.txt",
+		Content:  "Say hello",
+		Template: template.Must(template.New("open.txt").Parse("Say hello")),
+	})
+	promptLoader := &PromptLoader{
+		cache:  cache,
+		logger: logger,
+	}
+
+	provider := &stubProvider{name: "openai"}
+	router := NewLLMRouter("openai", logger)
+	router.RegisterProvider(provider)
+
+	server := NewLLMGatewayServer(promptLoader, router, NewUsageTracker(1000, logger), logger)
+
+	tests := []struct {
+		name           string
+		promptPath     string
+		callingService string
+		expectedCode   codes.Code
+	}{
+		{name: "allowed service", promptPath: "restricted.txt", callingService: "billing-service", expectedCode: codes.OK},
+		{name: "disallowed service", promptPath: "restricted.txt", callingService: "analytics-service", expectedCode: codes.PermissionDenied},
+		{name: "missing calling service", promptPath: "restricted.txt", callingService: "", expectedCode: codes.PermissionDenied},
+		{name: "unrestricted prompt", promptPath: "open.txt", callingService: "analytics-service", expectedCode: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider.called = nil
+
+			_, err := server.CallPrompt(context.Background(), &pb.CallPromptRequest{
+				PromptPath:     tt.promptPath,
+				CallingService: tt.callingService,
+			})
+
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			if tt.expectedCode == codes.OK {
+				assert.Len(t, provider.called, 1)
+			} else {
+				assert.Empty(t, provider.called, "provider should not be called for a disallowed service")
+			}
+		})
+	}
+}
+
+func TestLLMGatewayServer_CallPrompt_ConfiguredTimeoutBounds(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	cache := NewPromptCache()
+	cache.Set("plain.txt", &Prompt{
+		Path:     "plain.txt",
+		Content:  "Say hello",
+		Template: template.Must(template.New("plain.txt").Parse("Say hello")),
+	})
+	promptLoader := &PromptLoader{
+		cache:  cache,
+		logger: logger,
+	}
+
+	provider := &stubProvider{name: "openai"}
+	router := NewLLMRouter("openai", logger)
+	router.RegisterProvider(provider)
+	usageTracker := NewUsageTracker(1000, logger)
+
+	server := NewLLMGatewayServer(promptLoader, router, usageTracker, logger)
+	server.SetTimeoutBounds(10*time.Second, 20*time.Second, 60*time.Second)
+
+	tests := []struct {
+		name           string
+		timeoutSeconds int32
+		expectedCode   codes.Code
+	}{
+		{name: "below configured min (within default bounds)", timeoutSeconds: 5, expectedCode: codes.InvalidArgument},
+		{name: "above configured max (within default bounds)", timeoutSeconds: 90, expectedCode: codes.InvalidArgument},
+		{name: "within configured bounds", timeoutSeconds: 45, expectedCode: codes.OK},
+		{name: "unset uses configured default", timeoutSeconds: 0, expectedCode: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider.called = nil
+
+			_, err := server.CallPrompt(context.Background(), &pb.CallPromptRequest{
+				PromptPath:     "plain.txt",
+				TimeoutSeconds: tt.timeoutSeconds,
+			})
+
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			if tt.expectedCode != codes.OK {
+				assert.Contains(t, err.Error(), "between 10 and 60 seconds")
+				assert.Empty(t, provider.called)
+			}
+		})
+	}
+}
+
+func TestLLMGatewayServer_CallPrompt_ModelTemperatureBounds(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	cache := NewPromptCache()
+	cache.Set("plain.txt", &Prompt{
+		Path:     "plain.txt",
+		Content:  "Say hello",
+		Template: template.Must(template.New("plain.txt").Parse("Say hello")),
+	})
+	cache.Set("warm.txt", &Prompt{
+		Path:     "warm.txt",
+		Content:  "Say hello",
+		Template: template.Must(template.New("warm.txt").Parse("Say hello")),
+		Metadata: &PromptMetadata{Temperature: func() *float32 { v := float32(1.8); return &v }()},
+	})
+	promptLoader := &PromptLoader{
+		cache:  cache,
+		logger: logger,
+	}
+
+	bounds := map[string]TemperatureBounds{
+		"claude-":  {Min: 0, Max: 1},
+		"claude-x": {Min: 0.5, Max: 0.7},
+	}
+
+	tests := []struct {
+		name                string
+		policy              string
+		request             *pb.CallPromptRequest
+		expectedCode        codes.Code
+		expectedTemperature float32
+	}{
+		{
+			name:                "within model bounds",
+			policy:              BoundsPolicyReject,
+			request:             &pb.CallPromptRequest{PromptPath: "plain.txt", Model: "claude-3", Parameters: &pb.LLMParameters{Temperature: 0.9}},
+			expectedTemperature: 0.9,
+		},
+		{
+			name:         "globally valid but above model max is rejected",
+			policy:       BoundsPolicyReject,
+			request:      &pb.CallPromptRequest{PromptPath: "plain.txt", Model: "claude-3", Parameters: &pb.LLMParameters{Temperature: 1.5}},
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:                "globally valid but above model max is clamped",
+			policy:              BoundsPolicyClamp,
+			request:             &pb.CallPromptRequest{PromptPath: "plain.txt", Model: "claude-3", Parameters: &pb.LLMParameters{Temperature: 1.5}},
+			expectedTemperature: 1,
+		},
+		{
+			name:                "longest prefix wins",
+			policy:              BoundsPolicyClamp,
+			request:             &pb.CallPromptRequest{PromptPath: "plain.txt", Model: "claude-x1", Parameters: &pb.LLMParameters{Temperature: 0.2}},
+			expectedTemperature: 0.5,
+		},
+		{
+			name:                "prompt default temperature is bounded too",
+			policy:              BoundsPolicyClamp,
+			request:             &pb.CallPromptRequest{PromptPath: "warm.txt", Model: "claude-3"},
+			expectedTemperature: 1,
+		},
+		{
+			name:                "models without bounds keep the global range",
+			policy:              BoundsPolicyReject,
+			request:             &pb.CallPromptRequest{PromptPath: "plain.txt", Model: "gpt-4", Parameters: &pb.LLMParameters{Temperature: 1.5}},
+			expectedTemperature: 1.5,
+		},
+		{
+			name:         "global range is still checked first",
+			policy:       BoundsPolicyClamp,
+			request:      &pb.CallPromptRequest{PromptPath: "plain.txt", Model: "claude-3", Parameters: &pb.LLMParameters{Temperature: 2.5}},
+			expectedCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &stubProvider{name: "openai"}
+			router := NewLLMRouter("openai", logger)
+			router.RegisterModelFamily("claude-", "openai")
+			router.RegisterProvider(provider)
+
+			server := NewLLMGatewayServer(promptLoader, router, NewUsageTracker(1000, logger), logger)
+			server.SetModelTemperatureBounds(NewModelTemperatureBounds(bounds, tt.policy))
+
+			_, err := server.CallPrompt(context.Background(), tt.request)
+			if tt.expectedCode != codes.OK {
+				st, ok := status.FromError(err)
+				require.True(t, ok)
+				assert.Equal(t, tt.expectedCode, st.Code())
+				assert.Empty(t, provider.called, "provider should not be called")
+				return
+			}
+
+			require.NoError(t, err)
+			require.NotNil(t, provider.lastParams)
+			assert.Equal(t, tt.expectedTemperature, provider.lastParams.Temperature)
+		})
+	}
+}
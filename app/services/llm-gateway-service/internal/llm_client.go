@@ -186,25 +186,38 @@ func (p *OpenAIProvider) ValidateModel(model string) error {
 	return nil
 }
 
+// DefaultModelFamilies maps model name prefixes to the provider serving them
+var DefaultModelFamilies = map[string]string{
+	"gpt-":    "openai",
+	"claude-": "anthropic",
+}
+
 // LLMRouter routes requests to the appropriate LLM provider
 type LLMRouter struct {
 	providers       map[string]LLMProvider
 	defaultProvider string
 	defaultModels   map[string]string
+	modelFamilies   map[string]string
 	retryConfig     *RetryConfig
 	logger          *zap.Logger
 }
 
 // NewLLMRouter creates a new LLM router
 func NewLLMRouter(defaultProvider string, logger *zap.Logger) *LLMRouter {
+	modelFamilies := make(map[string]string, len(DefaultModelFamilies))
+	for prefix, provider := range DefaultModelFamilies {
+		modelFamilies[prefix] = provider
+	}
+
 	return &LLMRouter{
 		providers:       make(map[string]LLMProvider),
 		defaultProvider: defaultProvider,
 		defaultModels: map[string]string{
 			"openai": "gpt-4-turbo-preview",
 		},
-		retryConfig: DefaultRetryConfig(),
-		logger:      logger,
+		modelFamilies: modelFamilies,
+		retryConfig:   DefaultRetryConfig(),
+		logger:        logger,
 	}
 }
 
@@ -214,17 +227,43 @@ func (r *LLMRouter) RegisterProvider(provider LLMProvider) {
 	r.logger.Info("LLM provider registered", zap.String("provider", provider.GetName()))
 }
 
+// RegisterModelFamily routes models starting with prefix to the named provider
+func (r *LLMRouter) RegisterModelFamily(prefix, providerName string) {
+	r.modelFamilies[prefix] = providerName
+	r.logger.Info("model family registered",
+		zap.String("prefix", prefix),
+		zap.String("provider", providerName))
+}
+
+// resolveProvider picks the provider for a model using the longest matching
+// family prefix, falling back to the default provider
+func (r *LLMRouter) resolveProvider(model string) string {
+	providerName := r.defaultProvider
+	matchedLen := 0
+	for prefix, name := range r.modelFamilies {
+		if len(prefix) > matchedLen && strings.HasPrefix(model, prefix) {
+			providerName = name
+			matchedLen = len(prefix)
+		}
+	}
+	return providerName
+}
+
+// ResolveModel returns the model a request for model is served by: model
+// itself, or the default provider's default model when it is empty
+func (r *LLMRouter) ResolveModel(model string) string {
+	if model != "" {
+		return model
+	}
+	return r.defaultModels[r.defaultProvider]
+}
+
 // Route routes a request to the appropriate provider
 func (r *LLMRouter) Route(ctx context.Context, req *LLMRequest) (*LLMResponse, error) {
 	// Select provider
 	providerName := r.defaultProvider
 	if req.Model != "" {
-		// Try to infer provider from model name
-		if strings.HasPrefix(req.Model, "gpt-") {
-			providerName = "openai"
-		} else if strings.HasPrefix(req.Model, "claude-") {
-			providerName = "anthropic"
-		}
+		providerName = r.resolveProvider(req.Model)
 	}
 
 	provider, ok := r.providers[providerName]
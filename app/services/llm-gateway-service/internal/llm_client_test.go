@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// stubProvider is a minimal LLMProvider that records the models it served
+type stubProvider struct {
+	name       string
+	called     []string
+	lastParams *LLMParameters
+}
+
+func (p *stubProvider) Call(ctx context.Context, req *LLMRequest) (*LLMResponse, error) {
+	p.called = append(p.called, req.Model)
+	p.lastParams = req.Parameters
+	return &LLMResponse{Text: p.name, Model: req.Model, TokenUsage: &TokenUsage{}}, nil
+}
+
+func (p *stubProvider) GetName() string {
+	return p.name
+}
+
+func (p *stubProvider) ValidateModel(model string) error {
+	return nil
+}
+
+func TestLLMRouter_Route_ModelFamilies(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	tests := []struct {
+		name             string
+		model            string
+		expectedProvider string
+	}{
+		{name: "built-in openai family", model: "gpt-4", expectedProvider: "openai"},
+		{name: "configured custom family", model: "mistral-large", expectedProvider: "mistral"},
+		{name: "longest prefix wins", model: "mistral-tiny-instruct", expectedProvider: "mistral-tiny"},
+		{name: "unknown model falls back to default", model: "llama-3-70b", expectedProvider: "openai"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			openai := &stubProvider{name: "openai"}
+			mistral := &stubProvider{name: "mistral"}
+			mistralTiny := &stubProvider{name: "mistral-tiny"}
+
+			router := NewLLMRouter("openai", logger)
+			router.RegisterProvider(openai)
+			router.RegisterProvider(mistral)
+			router.RegisterProvider(mistralTiny)
+			router.RegisterModelFamily("mistral-", "mistral")
+			router.RegisterModelFamily("mistral-tiny", "mistral-tiny")
+
+			resp, err := router.Route(context.Background(), &LLMRequest{Model: tt.model})
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedProvider, resp.Text)
+		})
+	}
+}
+
+func TestLLMRouter_Route_UnregisteredProvider(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+
+	router := NewLLMRouter("openai", logger)
+	router.RegisterProvider(&stubProvider{name: "openai"})
+	router.RegisterModelFamily("gemini-", "google")
+
+	_, err := router.Route(context.Background(), &LLMRequest{Model: "gemini-pro"})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "provider not found: google")
+}
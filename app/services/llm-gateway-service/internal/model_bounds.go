@@ -0,0 +1,71 @@
+package internal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// What happens to a temperature outside the bounds of the model it's sent to
+const (
+	BoundsPolicyReject = "reject" // Fail the call with InvalidArgument
+	BoundsPolicyClamp  = "clamp"  // Move the temperature to the nearest bound
+)
+
+// TemperatureBounds is the inclusive temperature range a model accepts
+type TemperatureBounds struct {
+	Min float32
+	Max float32
+}
+
+// ModelTemperatureBounds holds temperature bounds keyed by model name prefix
+// (e.g. "claude-" or "gpt-4o"). The longest matching prefix applies.
+type ModelTemperatureBounds struct {
+	bounds map[string]TemperatureBounds
+	policy string
+}
+
+// NewModelTemperatureBounds creates bounds enforced with the given policy
+func NewModelTemperatureBounds(bounds map[string]TemperatureBounds, policy string) *ModelTemperatureBounds {
+	return &ModelTemperatureBounds{bounds: bounds, policy: policy}
+}
+
+// lookup returns the bounds for a model using the longest matching prefix
+func (b *ModelTemperatureBounds) lookup(model string) (TemperatureBounds, string, bool) {
+	var matched TemperatureBounds
+	matchedPrefix := ""
+	found := false
+	for prefix, bounds := range b.bounds {
+		if strings.HasPrefix(model, prefix) && (!found || len(prefix) > len(matchedPrefix)) {
+			matched = bounds
+			matchedPrefix = prefix
+			found = true
+		}
+	}
+	return matched, matchedPrefix, found
+}
+
+// Apply enforces the model's bounds on params.Temperature, clamping it or
+// returning an error per the policy. A temperature of 0 means the provider
+// default and is left alone. clamped reports whether the value was changed.
+func (b *ModelTemperatureBounds) Apply(model string, params *LLMParameters) (clamped bool, err error) {
+	if b == nil || params == nil || params.Temperature == 0 {
+		return false, nil
+	}
+
+	bounds, prefix, ok := b.lookup(model)
+	if !ok || (params.Temperature >= bounds.Min && params.Temperature <= bounds.Max) {
+		return false, nil
+	}
+
+	if b.policy != BoundsPolicyClamp {
+		return false, fmt.Errorf("temperature %.2f is outside the %.2f-%.2f range for %s models",
+			params.Temperature, bounds.Min, bounds.Max, prefix)
+	}
+
+	if params.Temperature < bounds.Min {
+		params.Temperature = bounds.Min
+	} else {
+		params.Temperature = bounds.Max
+	}
+	return true, nil
+}
@@ -0,0 +1,77 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	notificationsv1 "github.com/haunted-saas/notifications-service/proto/notifications/v1"
+	"github.com/haunted-saas/pkg/grpcclient"
+)
+
+// Progress events sent to the user who started an LLM call
+const (
+	EventCallStarted   = "llm.call_started"
+	EventCallCompleted = "llm.call_completed"
+)
+
+// ProgressNotifier sends real-time events to a user's connected clients
+type ProgressNotifier interface {
+	NotifyUser(ctx context.Context, userID, eventType string, payload interface{}) error
+}
+
+// CallProgress is the payload of progress events. CorrelationID lets the UI
+// match events to the action that triggered the call.
+type CallProgress struct {
+	RequestID      string `json:"request_id"`
+	CorrelationID  string `json:"correlation_id,omitempty"`
+	PromptPath     string `json:"prompt_path"`
+	Success        *bool  `json:"success,omitempty"`
+	ResponseTimeMs int64  `json:"response_time_ms,omitempty"`
+}
+
+// NotificationsClient sends progress events through notifications-service
+type NotificationsClient struct {
+	conn   *grpcclient.Conn
+	client notificationsv1.NotificationsServiceClient
+}
+
+// NewNotificationsClient connects to notifications-service
+func NewNotificationsClient(address string, timeout time.Duration) (*NotificationsClient, error) {
+	conn, err := grpcclient.Dial("notifications-service", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &NotificationsClient{
+		conn:   conn,
+		client: notificationsv1.NewNotificationsServiceClient(conn),
+	}, nil
+}
+
+// NotifyUser sends an event to the user
+func (c *NotificationsClient) NotifyUser(ctx context.Context, userID, eventType string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification payload: %w", err)
+	}
+
+	ctx, cancel := c.conn.Bound(ctx)
+	defer cancel()
+
+	_, err = c.client.SendToUser(ctx, &notificationsv1.SendToUserRequest{
+		UserId:      userID,
+		EventType:   eventType,
+		PayloadJson: string(payloadJSON),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send %s to user %s: %w", eventType, userID, err)
+	}
+	return nil
+}
+
+// Close closes the connection to notifications-service
+func (c *NotificationsClient) Close() error {
+	return c.conn.Close()
+}
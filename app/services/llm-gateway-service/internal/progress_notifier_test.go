@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/haunted-saas/llm-gateway-service/proto/llm/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// progressEvent is one notification captured by recordingProgressNotifier
+type progressEvent struct {
+	userID    string
+	eventType string
+	progress  *CallProgress
+}
+
+// recordingProgressNotifier hands progress events to the test as they arrive
+type recordingProgressNotifier struct {
+	events chan progressEvent
+}
+
+func (n *recordingProgressNotifier) NotifyUser(ctx context.Context, userID, eventType string, payload interface{}) error {
+	n.events <- progressEvent{userID: userID, eventType: eventType, progress: payload.(*CallProgress)}
+	return nil
+}
+
+func (n *recordingProgressNotifier) next(t *testing.T) progressEvent {
+	t.Helper()
+	select {
+	case event := <-n.events:
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("expected a progress notification")
+		return progressEvent{}
+	}
+}
+
+func TestLLMGatewayServer_CallPrompt_NotifiesProgress(t *testing.T) {
+	server := newAuditTestServer(t, greetingPrompt("greeting.txt", nil))
+	notifier := &recordingProgressNotifier{events: make(chan progressEvent, 2)}
+	server.SetProgressNotifier(notifier)
+
+	resp, err := server.CallPrompt(context.Background(), &pb.CallPromptRequest{
+		PromptPath:     "greeting.txt",
+		VariablesJson:  `{"name": "Ada"}`,
+		CallingService: "ai-assistant",
+		CorrelationId:  "corr-123",
+		UserId:         "user-123",
+		NotifyProgress: true,
+	})
+	require.NoError(t, err)
+
+	started := notifier.next(t)
+	assert.Equal(t, "user-123", started.userID)
+	assert.Equal(t, EventCallStarted, started.eventType)
+	assert.Equal(t, resp.RequestId, started.progress.RequestID)
+	assert.Equal(t, "corr-123", started.progress.CorrelationID)
+	assert.Equal(t, "greeting.txt", started.progress.PromptPath)
+	assert.Nil(t, started.progress.Success)
+
+	completed := notifier.next(t)
+	assert.Equal(t, "user-123", completed.userID)
+	assert.Equal(t, EventCallCompleted, completed.eventType)
+	assert.Equal(t, resp.RequestId, completed.progress.RequestID)
+	assert.Equal(t, "corr-123", completed.progress.CorrelationID)
+	if assert.NotNil(t, completed.progress.Success) {
+		assert.True(t, *completed.progress.Success)
+	}
+}
+
+func TestLLMGatewayServer_CallPrompt_ProgressIsOptIn(t *testing.T) {
+	server := newAuditTestServer(t, greetingPrompt("greeting.txt", nil))
+	notifier := &recordingProgressNotifier{events: make(chan progressEvent, 2)}
+	server.SetProgressNotifier(notifier)
+
+	_, err := server.CallPrompt(context.Background(), &pb.CallPromptRequest{
+		PromptPath:    "greeting.txt",
+		VariablesJson: `{"name": "Ada"}`,
+		UserId:        "user-123",
+	})
+	require.NoError(t, err)
+
+	select {
+	case event := <-notifier.events:
+		t.Fatalf("expected no progress notification, got %s", event.eventType)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,147 @@
+package internal
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PromptAuditRecord captures the input and output of one prompt execution
+type PromptAuditRecord struct {
+	RequestID      string    `json:"request_id"`
+	CorrelationID  string    `json:"correlation_id,omitempty"`
+	PromptPath     string    `json:"prompt_path"`
+	CallingService string    `json:"calling_service"`
+	Model          string    `json:"model"`
+	RenderedPrompt string    `json:"rendered_prompt"`
+	ResponseText   string    `json:"response_text,omitempty"`
+	Success        bool      `json:"success"`
+	ErrorMessage   string    `json:"error_message,omitempty"`
+	Redacted       bool      `json:"redacted"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// PromptAuditSink stores prompt audit records
+type PromptAuditSink interface {
+	RecordPrompt(ctx context.Context, record *PromptAuditRecord) error
+}
+
+// FilePromptAuditSink appends audit records to a file as JSON lines
+type FilePromptAuditSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// NewFilePromptAuditSink opens (or creates) the audit log at path
+func NewFilePromptAuditSink(path string) (*FilePromptAuditSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open prompt audit log: %w", err)
+	}
+	return &FilePromptAuditSink{file: file}, nil
+}
+
+// RecordPrompt writes one record as a JSON line
+func (s *FilePromptAuditSink) RecordPrompt(ctx context.Context, record *PromptAuditRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal prompt audit record: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.file.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the audit log
+func (s *FilePromptAuditSink) Close() error {
+	return s.file.Close()
+}
+
+// PII patterns masked before a record reaches the sink
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	phonePattern = regexp.MustCompile(`\+?\d[\d\s().\-]{7,}\d`)
+)
+
+// redactPII masks email addresses and phone numbers
+func redactPII(text string) string {
+	text = emailPattern.ReplaceAllString(text, "[REDACTED_EMAIL]")
+	return phonePattern.ReplaceAllString(text, "[REDACTED_PHONE]")
+}
+
+// PromptAuditor decides which prompt executions are audited and forwards
+// them to the sink. It is separate from usage tracking, which never sees
+// prompt or response text.
+type PromptAuditor struct {
+	sink      PromptAuditSink
+	auditAll  bool
+	redactPII bool
+	logger    *zap.Logger
+
+	// One slot per record being written, so a slow sink can't pile up
+	// goroutines
+	pending chan struct{}
+}
+
+// NewPromptAuditor creates an auditor. With auditAll unset only prompts
+// whose frontmatter sets "audit: true" are recorded. At most maxPending
+// records are written at once; records beyond that are dropped.
+func NewPromptAuditor(sink PromptAuditSink, auditAll, redactPII bool, maxPending int, logger *zap.Logger) *PromptAuditor {
+	return &PromptAuditor{
+		sink:      sink,
+		auditAll:  auditAll,
+		redactPII: redactPII,
+		logger:    logger,
+		pending:   make(chan struct{}, maxPending),
+	}
+}
+
+// ShouldAudit reports whether executions of the prompt are recorded. A
+// prompt's "audit" frontmatter overrides the global setting either way.
+func (a *PromptAuditor) ShouldAudit(prompt *Prompt) bool {
+	if prompt.Metadata != nil && prompt.Metadata.Audit != nil {
+		return *prompt.Metadata.Audit
+	}
+	return a.auditAll
+}
+
+// RecordAsync redacts the record if configured and sends it to the sink
+// without blocking the caller. The record is dropped if maxPending records
+// are already being written.
+func (a *PromptAuditor) RecordAsync(record *PromptAuditRecord) {
+	select {
+	case a.pending <- struct{}{}:
+	default:
+		a.logger.Error("prompt audit backlog full, dropped record",
+			zap.String("request_id", record.RequestID),
+			zap.Int("max_pending", cap(a.pending)))
+		return
+	}
+
+	if a.redactPII {
+		record.RenderedPrompt = redactPII(record.RenderedPrompt)
+		record.ResponseText = redactPII(record.ResponseText)
+		record.Redacted = true
+	}
+
+	go func() {
+		defer func() { <-a.pending }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := a.sink.RecordPrompt(ctx, record); err != nil {
+			a.logger.Error("failed to record prompt audit",
+				zap.String("request_id", record.RequestID),
+				zap.Error(err))
+		}
+	}()
+}
@@ -0,0 +1,193 @@
+package internal
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/template"
+	"time"
+
+	pb "github.com/haunted-saas/llm-gateway-service/proto/llm/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+// recordingAuditSink hands records to the test as they arrive
+type recordingAuditSink struct {
+	records chan *PromptAuditRecord
+}
+
+func (s *recordingAuditSink) RecordPrompt(ctx context.Context, record *PromptAuditRecord) error {
+	s.records <- record
+	return nil
+}
+
+func newAuditTestServer(t *testing.T, prompts ...*Prompt) *LLMGatewayServer {
+	t.Helper()
+	logger, _ := zap.NewDevelopment()
+
+	cache := NewPromptCache()
+	for _, prompt := range prompts {
+		cache.Set(prompt.Path, prompt)
+	}
+	promptLoader := &PromptLoader{
+		cache:  cache,
+		logger: logger,
+	}
+
+	router := NewLLMRouter("openai", logger)
+	router.RegisterProvider(&stubProvider{name: "openai"})
+
+	return NewLLMGatewayServer(promptLoader, router, NewUsageTracker(1000, logger), logger)
+}
+
+func greetingPrompt(path string, metadata *PromptMetadata) *Prompt {
+	return &Prompt{
+		Path:         path,
+		Content:      "Greet {{.name}}",
+		Template:     template.Must(template.New(path).Parse("Greet {{.name}}")),
+		RequiredVars: []string{"name"},
+		Metadata:     metadata,
+	}
+}
+
+func TestLLMGatewayServer_CallPrompt_Audit(t *testing.T) {
+	audited := true
+	optedOut := false
+
+	tests := []struct {
+		name        string
+		prompt      *Prompt
+		withAuditor bool
+		auditAll    bool
+		expectAudit bool
+	}{
+		{name: "disabled by default", prompt: greetingPrompt("greet.txt", nil), expectAudit: false},
+		{name: "global audit records every prompt", prompt: greetingPrompt("greet.txt", nil), withAuditor: true, auditAll: true, expectAudit: true},
+		{name: "prompt opt-in without global audit", prompt: greetingPrompt("greet.txt", &PromptMetadata{Audit: &audited}), withAuditor: true, expectAudit: true},
+		{name: "prompt not opted in", prompt: greetingPrompt("greet.txt", nil), withAuditor: true, expectAudit: false},
+		{name: "prompt opt-out overrides global audit", prompt: greetingPrompt("greet.txt", &PromptMetadata{Audit: &optedOut}), withAuditor: true, auditAll: true, expectAudit: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newAuditTestServer(t, tt.prompt)
+			sink := &recordingAuditSink{records: make(chan *PromptAuditRecord, 1)}
+			if tt.withAuditor {
+				server.SetPromptAuditor(NewPromptAuditor(sink, tt.auditAll, false, 10, zap.NewNop()))
+			}
+
+			resp, err := server.CallPrompt(context.Background(), &pb.CallPromptRequest{
+				PromptPath:     "greet.txt",
+				VariablesJson:  `{"name":"Ada"}`,
+				CallingService: "test-service",
+				CorrelationId:  "corr-1",
+			})
+			require.NoError(t, err)
+
+			select {
+			case record := <-sink.records:
+				require.True(t, tt.expectAudit, "unexpected audit record")
+				assert.Equal(t, resp.RequestId, record.RequestID)
+				assert.Equal(t, "corr-1", record.CorrelationID)
+				assert.Equal(t, "greet.txt", record.PromptPath)
+				assert.Equal(t, "test-service", record.CallingService)
+				assert.Equal(t, "Greet Ada", record.RenderedPrompt)
+				assert.Equal(t, resp.ResponseText, record.ResponseText)
+				assert.True(t, record.Success)
+			case <-time.After(200 * time.Millisecond):
+				assert.False(t, tt.expectAudit, "expected an audit record")
+			}
+		})
+	}
+}
+
+func TestPromptAuditor_RedactsPII(t *testing.T) {
+	sink := &recordingAuditSink{records: make(chan *PromptAuditRecord, 1)}
+	auditor := NewPromptAuditor(sink, true, true, 10, zap.NewNop())
+
+	auditor.RecordAsync(&PromptAuditRecord{
+		RequestID:      "req-1",
+		RenderedPrompt: "Email ada@example.com or call +1 (555) 123-4567",
+		ResponseText:   "Contacted ada@example.com",
+	})
+
+	select {
+	case record := <-sink.records:
+		assert.Equal(t, "Email [REDACTED_EMAIL] or call [REDACTED_PHONE]", record.RenderedPrompt)
+		assert.Equal(t, "Contacted [REDACTED_EMAIL]", record.ResponseText)
+		assert.True(t, record.Redacted)
+	case <-time.After(time.Second):
+		t.Fatal("expected an audit record")
+	}
+}
+
+// blockingAuditSink holds every write until release is closed
+type blockingAuditSink struct {
+	release chan struct{}
+	records chan *PromptAuditRecord
+}
+
+func (s *blockingAuditSink) RecordPrompt(ctx context.Context, record *PromptAuditRecord) error {
+	<-s.release
+	s.records <- record
+	return nil
+}
+
+func TestPromptAuditor_DropsRecordsBeyondMaxPending(t *testing.T) {
+	sink := &blockingAuditSink{release: make(chan struct{}), records: make(chan *PromptAuditRecord, 2)}
+	auditor := NewPromptAuditor(sink, true, false, 1, zap.NewNop())
+
+	auditor.RecordAsync(&PromptAuditRecord{RequestID: "req-1"})
+	auditor.RecordAsync(&PromptAuditRecord{RequestID: "req-2"})
+	close(sink.release)
+
+	select {
+	case record := <-sink.records:
+		assert.Equal(t, "req-1", record.RequestID)
+	case <-time.After(time.Second):
+		t.Fatal("expected the first audit record")
+	}
+	select {
+	case record := <-sink.records:
+		t.Fatalf("expected the second record to be dropped, got %s", record.RequestID)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	// The slot is freed once the write finishes
+	require.Eventually(t, func() bool { return len(auditor.pending) == 0 }, time.Second, time.Millisecond)
+	auditor.RecordAsync(&PromptAuditRecord{RequestID: "req-3"})
+	select {
+	case record := <-sink.records:
+		assert.Equal(t, "req-3", record.RequestID)
+	case <-time.After(time.Second):
+		t.Fatal("expected a record once the backlog cleared")
+	}
+}
+
+func TestFilePromptAuditSink_WritesJSONLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := NewFilePromptAuditSink(path)
+	require.NoError(t, err)
+
+	require.NoError(t, sink.RecordPrompt(context.Background(), &PromptAuditRecord{RequestID: "req-1", RenderedPrompt: "one"}))
+	require.NoError(t, sink.RecordPrompt(context.Background(), &PromptAuditRecord{RequestID: "req-2", RenderedPrompt: "two"}))
+	require.NoError(t, sink.Close())
+
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+
+	var ids []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record PromptAuditRecord
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		ids = append(ids, record.RequestID)
+	}
+	assert.Equal(t, []string{"req-1", "req-2"}, ids)
+}
@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
 	"regexp"
 	"strings"
+	"sync"
 	"text/template"
 
 	"github.com/fsnotify/fsnotify"
@@ -21,6 +23,14 @@ type PromptLoader struct {
 	watcher    *fsnotify.Watcher
 	logger     *zap.Logger
 	watchMode  bool
+
+	// Globs matched against each path element and the whole relative path
+	ignorePatterns []string
+
+	// Every loadable prompt on disk keyed by relative path, so prompts
+	// evicted from a bounded cache can be reloaded on demand
+	filesMu sync.Mutex
+	files   map[string]string
 }
 
 // NewPromptLoader creates a new prompt loader
@@ -39,6 +49,51 @@ func NewPromptLoader(promptsDir string, watchMode bool, logger *zap.Logger) (*Pr
 	return loader, nil
 }
 
+// SetIgnorePatterns sets globs for files and directories that are neither
+// loaded nor watched, e.g. ".*" for .git and editor swap files. Set it before
+// LoadAllPrompts and WatchForChanges.
+func (l *PromptLoader) SetIgnorePatterns(patterns []string) {
+	l.ignorePatterns = patterns
+}
+
+// SetCacheMaxEntries bounds the prompt cache (0 means unbounded). Evicted
+// prompts are reloaded from disk the next time they are requested.
+func (l *PromptLoader) SetCacheMaxEntries(maxEntries int) {
+	l.cache.SetMaxEntries(maxEntries)
+}
+
+// CacheStats returns the prompt cache size and counters
+func (l *PromptLoader) CacheStats() PromptCacheStats {
+	return l.cache.Stats()
+}
+
+// isIgnored reports whether a path in the prompts directory matches an
+// ignore pattern. A pattern matching any directory on the path excludes
+// everything beneath it.
+func (l *PromptLoader) isIgnored(name string) bool {
+	if len(l.ignorePatterns) == 0 {
+		return false
+	}
+
+	relPath, err := filepath.Rel(l.promptsDir, name)
+	if err != nil || relPath == "." {
+		return false
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	for _, pattern := range l.ignorePatterns {
+		if matched, _ := path.Match(pattern, relPath); matched {
+			return true
+		}
+		for _, element := range strings.Split(relPath, "/") {
+			if matched, _ := path.Match(pattern, element); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // LoadAllPrompts loads all prompts from the prompts directory
 func (l *PromptLoader) LoadAllPrompts() error {
 	l.logger.Info("loading prompts", zap.String("directory", l.promptsDir))
@@ -52,6 +107,13 @@ func (l *PromptLoader) LoadAllPrompts() error {
 			return nil // Continue walking
 		}
 
+		if l.isIgnored(path) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
 		// Skip directories
 		if info.IsDir() {
 			return nil
@@ -93,7 +155,8 @@ func (l *PromptLoader) LoadAllPrompts() error {
 	l.logger.Info("prompts loaded",
 		zap.Int("loaded", loadedCount),
 		zap.Int("failed", failedCount),
-		zap.Int("total", l.cache.Count()))
+		zap.Int("cached", l.cache.Count()),
+		zap.Int64("evictions", l.cache.Stats().Evictions))
 
 	return nil
 }
@@ -104,25 +167,44 @@ func (l *PromptLoader) isValidPromptFile(path string) bool {
 	return ext == ".txt" || ext == ".md" || ext == ".prompt"
 }
 
-// loadPrompt loads a single prompt file
+// loadPrompt loads a single prompt file into the cache
 func (l *PromptLoader) loadPrompt(relPath, absPath string) error {
+	prompt, err := l.readPrompt(relPath, absPath)
+	if err != nil {
+		return err
+	}
+
+	// Store in cache
+	l.cache.Set(relPath, prompt)
+	l.trackFile(relPath, absPath)
+
+	l.logger.Debug("prompt loaded",
+		zap.String("path", relPath),
+		zap.Int("required_vars", len(prompt.RequiredVars)),
+		zap.Int64("size_bytes", prompt.FileSizeBytes))
+
+	return nil
+}
+
+// readPrompt reads and parses a single prompt file without caching it
+func (l *PromptLoader) readPrompt(relPath, absPath string) (*Prompt, error) {
 	// Read file content
 	content, err := os.ReadFile(absPath)
 	if err != nil {
-		return fmt.Errorf("failed to read file: %w", err)
+		return nil, fmt.Errorf("failed to read file: %w", err)
 	}
 
 	// Get file info
 	info, err := os.Stat(absPath)
 	if err != nil {
-		return fmt.Errorf("failed to stat file: %w", err)
+		return nil, fmt.Errorf("failed to stat file: %w", err)
 	}
 
 	// Parse frontmatter and content
 	metadata, promptContent := l.parseFrontmatter(content)
 
 	// Extract required variables from template
-	requiredVars := l.extractRequiredVariables(promptContent)
+	requiredVars, listVars := scanTemplateVariables(promptContent)
 
 	// Merge metadata required vars with extracted vars
 	if metadata != nil && len(metadata.RequiredVars) > 0 {
@@ -132,36 +214,80 @@ func (l *PromptLoader) loadPrompt(relPath, absPath string) error {
 	// Parse as Go template
 	tmpl, err := template.New(relPath).Parse(promptContent)
 	if err != nil {
-		return fmt.Errorf("failed to parse template: %w", err)
+		return nil, fmt.Errorf("failed to parse template: %w", err)
 	}
 
-	// Create prompt object
-	prompt := &Prompt{
+	return &Prompt{
 		Path:          relPath,
 		Content:       promptContent,
 		Template:      tmpl,
 		RequiredVars:  requiredVars,
+		ListVars:      listVars,
 		LastModified:  info.ModTime(),
 		FileSizeBytes: info.Size(),
 		Metadata:      metadata,
+	}, nil
+}
+
+// trackFile records a loadable prompt file
+func (l *PromptLoader) trackFile(relPath, absPath string) {
+	l.filesMu.Lock()
+	defer l.filesMu.Unlock()
+	if l.files == nil {
+		l.files = make(map[string]string)
 	}
+	l.files[relPath] = absPath
+}
 
-	// Store in cache
-	l.cache.Set(relPath, prompt)
+// untrackFile forgets a prompt file that was removed from disk
+func (l *PromptLoader) untrackFile(relPath string) {
+	l.filesMu.Lock()
+	defer l.filesMu.Unlock()
+	delete(l.files, relPath)
+}
 
-	l.logger.Debug("prompt loaded",
-		zap.String("path", relPath),
-		zap.Int("required_vars", len(requiredVars)),
-		zap.Int64("size_bytes", info.Size()))
+// trackedFiles returns a copy of the known prompt files
+func (l *PromptLoader) trackedFiles() map[string]string {
+	l.filesMu.Lock()
+	defer l.filesMu.Unlock()
+	files := make(map[string]string, len(l.files))
+	for relPath, absPath := range l.files {
+		files[relPath] = absPath
+	}
+	return files
+}
 
-	return nil
+// trackedFile returns the absolute path of a known prompt file
+func (l *PromptLoader) trackedFile(relPath string) (string, bool) {
+	l.filesMu.Lock()
+	defer l.filesMu.Unlock()
+	absPath, ok := l.files[relPath]
+	return absPath, ok
 }
 
 // parseFrontmatter parses YAML frontmatter from prompt content
 func (l *PromptLoader) parseFrontmatter(content []byte) (*PromptMetadata, string) {
+	frontmatterBytes, promptContent, ok := splitFrontmatter(content)
+	if !ok {
+		return nil, string(content)
+	}
+
+	// Parse YAML
+	var metadata PromptMetadata
+	if err := yaml.Unmarshal(frontmatterBytes, &metadata); err != nil {
+		l.logger.Warn("failed to parse frontmatter, ignoring", zap.Error(err))
+		return nil, string(content)
+	}
+
+	return &metadata, promptContent
+}
+
+// splitFrontmatter separates the YAML between leading --- markers from the
+// prompt body. ok is false when the content has no closed frontmatter block.
+func splitFrontmatter(content []byte) (frontmatter []byte, body string, ok bool) {
 	// Check if content starts with ---
 	if !bytes.HasPrefix(content, []byte("---\n")) && !bytes.HasPrefix(content, []byte("---\r\n")) {
-		return nil, string(content)
+		return nil, "", false
 	}
 
 	// Find the closing ---
@@ -177,51 +303,93 @@ func (l *PromptLoader) parseFrontmatter(content []byte) (*PromptMetadata, string
 
 	if endIdx == -1 {
 		// No closing ---, treat as regular content
-		return nil, string(content)
+		return nil, "", false
 	}
 
 	// Extract frontmatter
-	frontmatterBytes := bytes.Join(lines[1:endIdx], []byte("\n"))
-	
-	// Parse YAML
-	var metadata PromptMetadata
-	if err := yaml.Unmarshal(frontmatterBytes, &metadata); err != nil {
-		l.logger.Warn("failed to parse frontmatter, ignoring", zap.Error(err))
-		return nil, string(content)
-	}
+	frontmatter = bytes.Join(lines[1:endIdx], []byte("\n"))
 
 	// Extract content after frontmatter
 	contentBytes := bytes.Join(lines[endIdx+1:], []byte("\n"))
-	promptContent := strings.TrimSpace(string(contentBytes))
+	body = strings.TrimSpace(string(contentBytes))
 
-	return &metadata, promptContent
+	return frontmatter, body, true
 }
 
+// Template variable patterns
+var (
+	templateActionRe = regexp.MustCompile(`(?s)\{\{-?\s*(.*?)\s*-?\}\}`)
+	fieldRe          = regexp.MustCompile(`^\.?([a-zA-Z0-9_\.]+)$`)
+	rootFieldRe      = regexp.MustCompile(`^\$\.([a-zA-Z0-9_\.]+)$`)
+	rangeRe          = regexp.MustCompile(`^range\s+(?:\$\w+\s*(?:,\s*\$\w+\s*)?:=\s*)?(\$?)\.([a-zA-Z0-9_\.]+)$`)
+)
+
 // extractRequiredVariables extracts variable placeholders from template content
 func (l *PromptLoader) extractRequiredVariables(content string) []string {
-	// Match {{variable_name}} or {{object.property}}
-	re := regexp.MustCompile(`\{\{\.?([a-zA-Z0-9_\.]+)\}\}`)
-	matches := re.FindAllStringSubmatch(content, -1)
-
-	vars := make(map[string]bool)
-	for _, match := range matches {
-		if len(match) > 1 {
-			varName := match[1]
-			// Extract root variable name (before first dot)
-			if idx := strings.Index(varName, "."); idx != -1 {
-				varName = varName[:idx]
+	vars, _ := scanTemplateVariables(content)
+	return vars
+}
+
+// scanTemplateVariables walks template actions in order, tracking range blocks
+// so fields inside {{range}} are treated as element fields rather than
+// top-level variables
+func scanTemplateVariables(content string) (vars []string, listVars []string) {
+	varSet := make(map[string]bool)
+	listSet := make(map[string]bool)
+	var blocks []string
+	rangeDepth := 0
+
+	// rootName returns the variable name before the first dot
+	rootName := func(path string) string {
+		if idx := strings.Index(path, "."); idx != -1 {
+			return path[:idx]
+		}
+		return path
+	}
+
+	for _, match := range templateActionRe.FindAllStringSubmatch(content, -1) {
+		action := match[1]
+		keyword := strings.Fields(action + " ")[0]
+
+		switch keyword {
+		case "range":
+			if m := rangeRe.FindStringSubmatch(action); m != nil && (rangeDepth == 0 || m[1] == "$") {
+				varSet[rootName(m[2])] = true
+				if !strings.Contains(m[2], ".") {
+					listSet[m[2]] = true
+				}
+			}
+			blocks = append(blocks, keyword)
+			rangeDepth++
+		case "if", "with", "block", "define":
+			blocks = append(blocks, keyword)
+		case "end":
+			if len(blocks) > 0 {
+				if blocks[len(blocks)-1] == "range" {
+					rangeDepth--
+				}
+				blocks = blocks[:len(blocks)-1]
+			}
+		default:
+			if m := rootFieldRe.FindStringSubmatch(action); m != nil {
+				varSet[rootName(m[1])] = true
+			} else if m := fieldRe.FindStringSubmatch(action); m != nil && rangeDepth == 0 {
+				varSet[rootName(m[1])] = true
 			}
-			vars[varName] = true
 		}
 	}
 
-	// Convert to slice
-	result := make([]string, 0, len(vars))
-	for varName := range vars {
-		result = append(result, varName)
+	// Convert to slices
+	vars = make([]string, 0, len(varSet))
+	for varName := range varSet {
+		vars = append(vars, varName)
+	}
+	listVars = make([]string, 0, len(listSet))
+	for varName := range listSet {
+		listVars = append(listVars, varName)
 	}
 
-	return result
+	return vars, listVars
 }
 
 // mergeUniqueVars merges two variable lists, removing duplicates
@@ -241,34 +409,57 @@ func (l *PromptLoader) mergeUniqueVars(vars1, vars2 []string) []string {
 	return result
 }
 
-// GetPrompt retrieves a prompt from the cache
+// GetPrompt retrieves a prompt from the cache, reloading it from disk if it
+// was evicted
 func (l *PromptLoader) GetPrompt(path string) (*Prompt, error) {
-	prompt, ok := l.cache.Get(path)
+	if prompt, ok := l.cache.Get(path); ok {
+		return prompt, nil
+	}
+
+	absPath, ok := l.trackedFile(path)
 	if !ok {
 		return nil, fmt.Errorf("prompt not found: %s", path)
 	}
+
+	l.logger.Debug("prompt not cached, reloading", zap.String("path", path))
+	prompt, err := l.readPrompt(path, absPath)
+	if err != nil {
+		return nil, fmt.Errorf("prompt not found: %s: %w", path, err)
+	}
+	l.cache.Set(path, prompt)
 	return prompt, nil
 }
 
-// ListPrompts returns all loaded prompts
-func (l *PromptLoader) ListPrompts(directoryFilter string) []*Prompt {
+// ListPrompts returns loaded prompts, optionally filtered by directory prefix, tags
+// and default model. A prompt must carry every tag in tagsFilter to match.
+// Prompts evicted from the cache are read from disk without being cached
+// again, so a listing doesn't flush the working set.
+func (l *PromptLoader) ListPrompts(directoryFilter string, tagsFilter []string, modelFilter string) []*Prompt {
 	allPrompts := l.cache.GetAll()
-	
-	if directoryFilter == "" {
-		// Return all prompts
-		result := make([]*Prompt, 0, len(allPrompts))
-		for _, prompt := range allPrompts {
-			result = append(result, prompt)
+	for relPath, absPath := range l.trackedFiles() {
+		if _, ok := allPrompts[relPath]; ok {
+			continue
 		}
-		return result
+		prompt, err := l.readPrompt(relPath, absPath)
+		if err != nil {
+			l.logger.Warn("failed to read evicted prompt", zap.String("path", relPath), zap.Error(err))
+			continue
+		}
+		allPrompts[relPath] = prompt
 	}
 
-	// Filter by directory
-	result := make([]*Prompt, 0)
+	result := make([]*Prompt, 0, len(allPrompts))
 	for _, prompt := range allPrompts {
-		if strings.HasPrefix(prompt.Path, directoryFilter) {
-			result = append(result, prompt)
+		if directoryFilter != "" && !strings.HasPrefix(prompt.Path, directoryFilter) {
+			continue
+		}
+		if !prompt.HasTags(tagsFilter) {
+			continue
 		}
+		if modelFilter != "" && !prompt.UsesModel(modelFilter) {
+			continue
+		}
+		result = append(result, prompt)
 	}
 	return result
 }
@@ -306,6 +497,9 @@ func (l *PromptLoader) addWatchRecursive(dir string) error {
 			return err
 		}
 		if info.IsDir() {
+			if l.isIgnored(path) {
+				return filepath.SkipDir
+			}
 			if err := l.watcher.Add(path); err != nil {
 				l.logger.Warn("failed to watch directory", zap.String("path", path), zap.Error(err))
 			}
@@ -336,7 +530,7 @@ func (l *PromptLoader) watchLoop() {
 // handleFileEvent handles a file system event
 func (l *PromptLoader) handleFileEvent(event fsnotify.Event) {
 	// Check if it's a prompt file
-	if !l.isValidPromptFile(event.Name) {
+	if !l.isValidPromptFile(event.Name) || l.isIgnored(event.Name) {
 		return
 	}
 
@@ -366,10 +560,12 @@ func (l *PromptLoader) handleFileEvent(event fsnotify.Event) {
 	case event.Op&fsnotify.Remove == fsnotify.Remove:
 		l.logger.Info("prompt file removed, deleting from cache", zap.String("path", relPath))
 		l.cache.Delete(relPath)
+		l.untrackFile(relPath)
 
 	case event.Op&fsnotify.Rename == fsnotify.Rename:
 		l.logger.Info("prompt file renamed, deleting from cache", zap.String("path", relPath))
 		l.cache.Delete(relPath)
+		l.untrackFile(relPath)
 	}
 }
 
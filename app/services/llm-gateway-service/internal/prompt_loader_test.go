@@ -5,6 +5,7 @@ import (
 	"path/filepath"
 	"testing"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
@@ -116,6 +117,71 @@ func TestPromptLoader_ExtractRequiredVariables(t *testing.T) {
 	}
 }
 
+func TestScanTemplateVariables_Range(t *testing.T) {
+	tests := []struct {
+		name         string
+		content      string
+		expectedVars []string
+		expectedList []string
+	}{
+		{
+			name:         "range over list",
+			content:      "Items:\n{{range .items}}- {{.title}}: {{.price}}\n{{end}}",
+			expectedVars: []string{"items"},
+			expectedList: []string{"items"},
+		},
+		{
+			name:         "range with variables and trim markers",
+			content:      "{{- range $i, $item := .line_items -}}{{$i}} {{$item.name}}{{- end }} for {{.customer}}",
+			expectedVars: []string{"line_items", "customer"},
+			expectedList: []string{"line_items"},
+		},
+		{
+			name:         "root field inside range",
+			content:      "{{range .users}}{{.name}} joined {{$.team_name}}{{end}}",
+			expectedVars: []string{"users", "team_name"},
+			expectedList: []string{"users"},
+		},
+		{
+			name:         "nested range target is an element field",
+			content:      "{{range .orders}}{{range .lines}}{{.sku}}{{end}}{{end}}",
+			expectedVars: []string{"orders"},
+			expectedList: []string{"orders"},
+		},
+		{
+			name:         "range over nested path",
+			content:      "{{range .user.roles}}{{.}}{{end}}",
+			expectedVars: []string{"user"},
+			expectedList: []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars, listVars := scanTemplateVariables(tt.content)
+			assert.ElementsMatch(t, tt.expectedVars, vars)
+			assert.ElementsMatch(t, tt.expectedList, listVars)
+		})
+	}
+}
+
+func TestPromptLoader_LoadPrompt_ListVariable(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger, _ := zap.NewDevelopment()
+
+	content := "Summarize these tickets for {{.team_name}}:\n{{range .tickets}}- {{.subject}}\n{{end}}"
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "summary.md"), []byte(content), 0644))
+
+	loader, err := NewPromptLoader(tmpDir, false, logger)
+	require.NoError(t, err)
+	require.NoError(t, loader.LoadAllPrompts())
+
+	prompt, err := loader.GetPrompt("summary.md")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"team_name", "tickets"}, prompt.RequiredVars)
+	assert.Equal(t, []string{"tickets"}, prompt.ListVars)
+}
+
 func TestPromptLoader_ParseFrontmatter(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	loader := &PromptLoader{logger: logger}
@@ -212,11 +278,235 @@ func TestPromptLoader_ListPrompts(t *testing.T) {
 	require.NoError(t, err)
 
 	// Test list all
-	prompts := loader.ListPrompts("")
+	prompts := loader.ListPrompts("", nil, "")
 	assert.Equal(t, 3, len(prompts))
 
 	// Test list with filter
-	prompts = loader.ListPrompts("feature1")
+	prompts = loader.ListPrompts("feature1", nil, "")
 	assert.Equal(t, 1, len(prompts))
 	assert.Equal(t, "feature1/test.txt", prompts[0].Path)
 }
+
+func TestPromptLoader_ListPrompts_TagsFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger, _ := zap.NewDevelopment()
+
+	// Create test prompts in different directories sharing tags
+	testPrompts := map[string]string{
+		"onboarding/welcome.md": "---\ntags: [email, onboarding]\n---\nWelcome!",
+		"billing/receipt.md":    "---\ntags: [Email, billing]\n---\nThanks for paying.",
+		"chat/reply.txt":        "Plain prompt without tags",
+	}
+
+	for path, content := range testPrompts {
+		fullPath := filepath.Join(tmpDir, path)
+		dir := filepath.Dir(fullPath)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loader, err := NewPromptLoader(tmpDir, false, logger)
+	require.NoError(t, err)
+	err = loader.LoadAllPrompts()
+	require.NoError(t, err)
+
+	// Tag matches across directories, case-insensitively
+	prompts := loader.ListPrompts("", []string{"email"}, "")
+	assert.Equal(t, 2, len(prompts))
+
+	// Every tag must match
+	prompts = loader.ListPrompts("", []string{"email", "billing"}, "")
+	require.Equal(t, 1, len(prompts))
+	assert.Equal(t, "billing/receipt.md", prompts[0].Path)
+
+	// Directory and tag filters combine
+	prompts = loader.ListPrompts("onboarding", []string{"billing"}, "")
+	assert.Equal(t, 0, len(prompts))
+
+	// Unknown tag matches nothing
+	prompts = loader.ListPrompts("", []string{"missing"}, "")
+	assert.Equal(t, 0, len(prompts))
+}
+
+func TestPromptLoader_ListPrompts_ModelFilter(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger, _ := zap.NewDevelopment()
+
+	testPrompts := map[string]string{
+		"onboarding/welcome.md": "---\ndefault_model: gpt-3.5-turbo\ntags: [email]\n---\nWelcome!",
+		"billing/receipt.md":    "---\ndefault_model: GPT-3.5-Turbo\n---\nThanks for paying.",
+		"chat/reply.md":         "---\ndefault_model: gpt-4\ntags: [email]\n---\nHow can I help?",
+		"chat/plain.txt":        "Plain prompt without frontmatter",
+	}
+
+	for path, content := range testPrompts {
+		fullPath := filepath.Join(tmpDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	loader, err := NewPromptLoader(tmpDir, false, logger)
+	require.NoError(t, err)
+	err = loader.LoadAllPrompts()
+	require.NoError(t, err)
+
+	// Model matches across directories, case-insensitively
+	prompts := loader.ListPrompts("", nil, "gpt-3.5-turbo")
+	assert.Equal(t, 2, len(prompts))
+
+	// Model and tag filters combine
+	prompts = loader.ListPrompts("", []string{"email"}, "gpt-4")
+	require.Equal(t, 1, len(prompts))
+	assert.Equal(t, "chat/reply.md", prompts[0].Path)
+
+	// Prompts without a default model never match
+	prompts = loader.ListPrompts("chat", nil, "gpt-3.5-turbo")
+	assert.Equal(t, 0, len(prompts))
+}
+
+func TestPromptLoader_IgnorePatterns(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger, _ := zap.NewDevelopment()
+
+	files := map[string]string{
+		"greeting.txt":          "Hello {{.name}}!",
+		"team/welcome.md":       "Welcome to {{.team}}",
+		".git/info/exclude.txt": "# git internals",
+		"drafts/idea.txt":       "Not ready yet",
+		"team/.welcome.md.swp":  "swap file",
+		"team/notes.txt~":       "editor backup",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tmpDir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(fullPath), 0755))
+		require.NoError(t, os.WriteFile(fullPath, []byte(content), 0644))
+	}
+
+	loader, err := NewPromptLoader(tmpDir, true, logger)
+	require.NoError(t, err)
+	loader.SetIgnorePatterns([]string{".*", "*~", "drafts"})
+
+	require.NoError(t, loader.LoadAllPrompts())
+
+	var loaded []string
+	for _, prompt := range loader.ListPrompts("", nil, "") {
+		loaded = append(loaded, prompt.Path)
+	}
+	assert.ElementsMatch(t, []string{"greeting.txt", "team/welcome.md"}, loaded)
+
+	require.NoError(t, loader.WatchForChanges())
+	defer loader.Close()
+
+	var watched []string
+	for _, dir := range loader.watcher.WatchList() {
+		rel, err := filepath.Rel(tmpDir, dir)
+		require.NoError(t, err)
+		watched = append(watched, filepath.ToSlash(rel))
+	}
+	assert.ElementsMatch(t, []string{".", "team"}, watched)
+
+	// Events for ignored files are dropped even from watched directories
+	ignoredPath := filepath.Join(tmpDir, "team", ".draft.txt")
+	require.NoError(t, os.WriteFile(ignoredPath, []byte("Hidden {{.name}}"), 0644))
+	loader.handleFileEvent(fsnotify.Event{Name: ignoredPath, Op: fsnotify.Create})
+	_, err = loader.GetPrompt("team/.draft.txt")
+	assert.Error(t, err)
+}
+
+func TestPromptCache_EvictsLeastRecentlyUsedAtCapacity(t *testing.T) {
+	cache := NewPromptCache()
+	cache.SetMaxEntries(2)
+
+	cache.Set("a.txt", &Prompt{Path: "a.txt"})
+	cache.Set("b.txt", &Prompt{Path: "b.txt"})
+
+	// Touch a so b becomes the eviction candidate
+	_, ok := cache.Get("a.txt")
+	require.True(t, ok)
+
+	cache.Set("c.txt", &Prompt{Path: "c.txt"})
+
+	_, ok = cache.Get("b.txt")
+	assert.False(t, ok, "least recently used prompt should be evicted")
+	_, ok = cache.Get("a.txt")
+	assert.True(t, ok)
+	_, ok = cache.Get("c.txt")
+	assert.True(t, ok)
+
+	stats := cache.Stats()
+	assert.Equal(t, 2, stats.Size)
+	assert.Equal(t, 2, stats.MaxEntries)
+	assert.Equal(t, int64(1), stats.Evictions)
+	assert.Equal(t, int64(3), stats.Hits)
+	assert.Equal(t, int64(1), stats.Misses)
+}
+
+func TestPromptLoader_ReloadsEvictedPromptOnMiss(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger, _ := zap.NewDevelopment()
+
+	for _, name := range []string{"one.txt", "two.txt", "three.txt"} {
+		require.NoError(t, os.WriteFile(filepath.Join(tmpDir, name), []byte("Hello {{.name}}"), 0644))
+	}
+
+	loader, err := NewPromptLoader(tmpDir, false, logger)
+	require.NoError(t, err)
+	loader.SetCacheMaxEntries(1)
+	require.NoError(t, loader.LoadAllPrompts())
+
+	assert.Equal(t, 1, loader.cache.Count())
+	assert.Equal(t, int64(2), loader.CacheStats().Evictions)
+
+	// Every prompt is still served, reloading from disk on a miss
+	for _, name := range []string{"one.txt", "two.txt", "three.txt"} {
+		prompt, err := loader.GetPrompt(name)
+		require.NoError(t, err, name)
+		assert.Equal(t, name, prompt.Path)
+		assert.Contains(t, prompt.RequiredVars, "name")
+	}
+	assert.Equal(t, 1, loader.cache.Count())
+
+	// Listing includes evicted prompts without growing the cache
+	assert.Len(t, loader.ListPrompts("", nil, ""), 3)
+	assert.Equal(t, 1, loader.cache.Count())
+}
+
+func TestPromptLoader_RemovedPromptIsNotReloadedAfterEviction(t *testing.T) {
+	tmpDir := t.TempDir()
+	logger, _ := zap.NewDevelopment()
+
+	removed := filepath.Join(tmpDir, "removed.txt")
+	require.NoError(t, os.WriteFile(removed, []byte("Gone"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(tmpDir, "kept.txt"), []byte("Kept"), 0644))
+
+	loader, err := NewPromptLoader(tmpDir, true, logger)
+	require.NoError(t, err)
+	loader.SetCacheMaxEntries(1)
+	require.NoError(t, loader.LoadAllPrompts())
+
+	require.NoError(t, os.Remove(removed))
+	loader.handleFileEvent(fsnotify.Event{Name: removed, Op: fsnotify.Remove})
+
+	_, err = loader.GetPrompt("removed.txt")
+	assert.Error(t, err)
+	assert.Len(t, loader.ListPrompts("", nil, ""), 1)
+
+	// A prompt created after startup is reloadable once evicted
+	created := filepath.Join(tmpDir, "created.txt")
+	require.NoError(t, os.WriteFile(created, []byte("New"), 0644))
+	loader.handleFileEvent(fsnotify.Event{Name: created, Op: fsnotify.Create})
+	_, err = loader.GetPrompt("kept.txt")
+	require.NoError(t, err)
+
+	prompt, err := loader.GetPrompt("created.txt")
+	require.NoError(t, err)
+	assert.Equal(t, "New", prompt.Content)
+}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"sync"
+	"time"
+)
+
+// quotaWindow counts one key's calls in the current window
+type quotaWindow struct {
+	start  time.Time
+	length time.Duration
+	count  int
+}
+
+func (w *quotaWindow) end() time.Time {
+	return w.start.Add(w.length)
+}
+
+// PromptQuotaLimiter enforces the quota in a prompt's metadata with a fixed
+// window per prompt path, or per prompt path and calling service when the
+// quota is per_service
+type PromptQuotaLimiter struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	windows   map[string]*quotaWindow
+	lastSweep time.Time
+}
+
+// quotaSweepInterval is how often expired windows are dropped
+const quotaSweepInterval = time.Minute
+
+// NewPromptQuotaLimiter creates an empty limiter
+func NewPromptQuotaLimiter() *PromptQuotaLimiter {
+	return &PromptQuotaLimiter{
+		now:     time.Now,
+		windows: make(map[string]*quotaWindow),
+	}
+}
+
+// Allow counts a call to prompt by callingService and reports whether the
+// prompt's quota allows it. When it doesn't, retryAfter is the time until
+// the window resets. Prompts without a quota are always allowed.
+func (l *PromptQuotaLimiter) Allow(prompt *Prompt, callingService string) (allowed bool, retryAfter time.Duration) {
+	if prompt.Metadata == nil || prompt.Metadata.Quota == nil || prompt.Metadata.Quota.Requests <= 0 {
+		return true, 0
+	}
+	quota := prompt.Metadata.Quota
+
+	key := prompt.Path
+	if quota.PerService {
+		key += "\x00" + callingService
+	}
+
+	now := l.now()
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	current, ok := l.windows[key]
+	if !ok || !now.Before(current.end()) {
+		current = &quotaWindow{start: now, length: quota.Window()}
+		l.windows[key] = current
+	}
+
+	if current.count >= quota.Requests {
+		return false, current.end().Sub(now)
+	}
+	current.count++
+	return true, 0
+}
+
+// sweep drops expired windows so prompts that are no longer called don't
+// accumulate
+func (l *PromptQuotaLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < quotaSweepInterval {
+		return
+	}
+	for key, w := range l.windows {
+		if !now.Before(w.end()) {
+			delete(l.windows, key)
+		}
+	}
+	l.lastSweep = now
+}
@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "github.com/haunted-saas/llm-gateway-service/proto/llm/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLLMGatewayServer_CallPrompt_PromptQuota(t *testing.T) {
+	server := newAuditTestServer(t,
+		greetingPrompt("expensive.txt", &PromptMetadata{Quota: &PromptQuota{Requests: 2, WindowSeconds: 60}}),
+		greetingPrompt("cheap.txt", nil),
+	)
+	now := time.Unix(1700000000, 0)
+	server.quotas.now = func() time.Time { return now }
+
+	call := func(path string) error {
+		_, err := server.CallPrompt(context.Background(), &pb.CallPromptRequest{
+			PromptPath:     path,
+			VariablesJson:  `{"name": "Ada"}`,
+			CallingService: "ai-assistant",
+		})
+		return err
+	}
+
+	require.NoError(t, call("expensive.txt"))
+	require.NoError(t, call("expensive.txt"))
+
+	err := call("expensive.txt")
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "retry in 60s")
+
+	// Prompts without a quota are unaffected
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, call("cheap.txt"))
+	}
+
+	// The quota resets with the window
+	now = now.Add(time.Minute)
+	assert.NoError(t, call("expensive.txt"))
+}
+
+func TestPromptQuotaLimiter_PerService(t *testing.T) {
+	limiter := NewPromptQuotaLimiter()
+	shared := greetingPrompt("shared.txt", &PromptMetadata{Quota: &PromptQuota{Requests: 1}})
+	perService := greetingPrompt("per-service.txt", &PromptMetadata{Quota: &PromptQuota{Requests: 1, PerService: true}})
+
+	allowed, _ := limiter.Allow(shared, "billing-service")
+	assert.True(t, allowed)
+	allowed, retryAfter := limiter.Allow(shared, "ai-assistant")
+	assert.False(t, allowed, "expected a shared quota to count every caller")
+	assert.Equal(t, defaultQuotaWindow, retryAfter.Round(time.Second))
+
+	allowed, _ = limiter.Allow(perService, "billing-service")
+	assert.True(t, allowed)
+	allowed, _ = limiter.Allow(perService, "ai-assistant")
+	assert.True(t, allowed, "expected each service to have its own quota")
+	allowed, _ = limiter.Allow(perService, "billing-service")
+	assert.False(t, allowed)
+}
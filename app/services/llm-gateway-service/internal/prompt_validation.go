@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Prompt validation diagnostic categories
+const (
+	DiagnosticFrontmatterError       = "frontmatter_error"
+	DiagnosticTemplateError          = "template_error"
+	DiagnosticUndeclaredVariable     = "undeclared_variable"
+	DiagnosticUnusedRequiredVariable = "unused_required_variable"
+)
+
+// PromptDiagnostic describes a single problem found while validating a prompt
+type PromptDiagnostic struct {
+	Category string
+	Message  string
+	Variable string
+}
+
+// PromptValidation is the result of linting prompt content
+type PromptValidation struct {
+	Metadata    *PromptMetadata
+	UsedVars    []string
+	Diagnostics []PromptDiagnostic
+}
+
+// Valid reports whether the prompt has no diagnostics
+func (v *PromptValidation) Valid() bool {
+	return len(v.Diagnostics) == 0
+}
+
+// ValidatePromptContent parses prompt frontmatter and template the same way
+// the loader does and reports problems, without adding anything to the cache
+func (l *PromptLoader) ValidatePromptContent(content []byte) *PromptValidation {
+	result := &PromptValidation{}
+
+	body := string(content)
+	frontmatterBroken := false
+	if frontmatter, promptContent, ok := splitFrontmatter(content); ok {
+		body = promptContent
+
+		var metadata PromptMetadata
+		if err := yaml.Unmarshal(frontmatter, &metadata); err != nil {
+			frontmatterBroken = true
+			result.Diagnostics = append(result.Diagnostics, PromptDiagnostic{
+				Category: DiagnosticFrontmatterError,
+				Message:  fmt.Sprintf("failed to parse frontmatter: %v", err),
+			})
+		} else {
+			result.Metadata = &metadata
+		}
+	}
+
+	if _, err := template.New("prompt").Parse(body); err != nil {
+		result.Diagnostics = append(result.Diagnostics, PromptDiagnostic{
+			Category: DiagnosticTemplateError,
+			Message:  fmt.Sprintf("failed to parse template: %v", err),
+		})
+	}
+
+	result.UsedVars = l.extractRequiredVariables(body)
+	sort.Strings(result.UsedVars)
+
+	// Variable checks need the declared list, which is unknown if the frontmatter is broken
+	if frontmatterBroken {
+		return result
+	}
+
+	declared := make(map[string]bool)
+	if result.Metadata != nil {
+		for _, v := range result.Metadata.RequiredVars {
+			declared[v] = true
+		}
+	}
+
+	used := make(map[string]bool, len(result.UsedVars))
+	for _, v := range result.UsedVars {
+		used[v] = true
+		if !declared[v] {
+			result.Diagnostics = append(result.Diagnostics, PromptDiagnostic{
+				Category: DiagnosticUndeclaredVariable,
+				Message:  fmt.Sprintf("variable %q is used but not declared in required_vars", v),
+				Variable: v,
+			})
+		}
+	}
+
+	if result.Metadata != nil {
+		for _, v := range result.Metadata.RequiredVars {
+			if !used[v] {
+				result.Diagnostics = append(result.Diagnostics, PromptDiagnostic{
+					Category: DiagnosticUnusedRequiredVariable,
+					Message:  fmt.Sprintf("required variable %q is declared but never used", v),
+					Variable: v,
+				})
+			}
+		}
+	}
+
+	return result
+}
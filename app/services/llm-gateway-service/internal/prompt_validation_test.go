@@ -0,0 +1,102 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestPromptLoader_ValidatePromptContent(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	loader := &PromptLoader{logger: logger, cache: NewPromptCache()}
+
+	tests := []struct {
+		name               string
+		content            string
+		expectedCategories []string
+		expectedVariables  []string
+	}{
+		{
+			name: "valid prompt",
+			content: `---
+required_vars: [name, age]
+---
+Hello {{.name}}, you are {{.age}} years old.`,
+		},
+		{
+			name: "frontmatter error",
+			content: `---
+required_vars: [name
+---
+Hello {{.name}}!`,
+			expectedCategories: []string{DiagnosticFrontmatterError},
+		},
+		{
+			name: "template error",
+			content: `---
+required_vars: [name]
+---
+Hello {{.name}!`,
+			expectedCategories: []string{DiagnosticTemplateError, DiagnosticUnusedRequiredVariable},
+			expectedVariables:  []string{"", "name"},
+		},
+		{
+			name: "undeclared variable",
+			content: `---
+required_vars: [name]
+---
+Hello {{.name}}, welcome to {{.team.name}}.`,
+			expectedCategories: []string{DiagnosticUndeclaredVariable},
+			expectedVariables:  []string{"team"},
+		},
+		{
+			name: "unused required variable",
+			content: `---
+required_vars: [name, plan]
+---
+Hello {{.name}}!`,
+			expectedCategories: []string{DiagnosticUnusedRequiredVariable},
+			expectedVariables:  []string{"plan"},
+		},
+		{
+			name:               "no frontmatter",
+			content:            "Hello {{.name}}!",
+			expectedCategories: []string{DiagnosticUndeclaredVariable},
+			expectedVariables:  []string{"name"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := loader.ValidatePromptContent([]byte(tt.content))
+
+			var categories, variables []string
+			for _, d := range result.Diagnostics {
+				categories = append(categories, d.Category)
+				variables = append(variables, d.Variable)
+				assert.NotEmpty(t, d.Message)
+			}
+
+			assert.Equal(t, tt.expectedCategories, categories)
+			if tt.expectedVariables != nil {
+				assert.Equal(t, tt.expectedVariables, variables)
+			}
+			assert.Equal(t, len(tt.expectedCategories) == 0, result.Valid())
+		})
+	}
+
+	assert.Equal(t, 0, loader.cache.Count(), "validation must not load prompts into the cache")
+}
+
+func TestPromptLoader_ValidatePromptContent_ReportsVariables(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	loader := &PromptLoader{logger: logger, cache: NewPromptCache()}
+
+	result := loader.ValidatePromptContent([]byte("---\nrequired_vars: [name, age]\n---\n{{.name}} {{.age}} {{.name}}"))
+
+	require.NotNil(t, result.Metadata)
+	assert.Equal(t, []string{"age", "name"}, result.UsedVars)
+	assert.Equal(t, []string{"name", "age"}, result.Metadata.RequiredVars)
+}
@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"container/list"
+	"strings"
 	"sync"
 	"text/template"
 	"time"
@@ -12,6 +14,7 @@ type Prompt struct {
 	Content       string
 	Template      *template.Template
 	RequiredVars  []string
+	ListVars      []string // Range targets, must be JSON arrays
 	LastModified  time.Time
 	FileSizeBytes int64
 	Metadata      *PromptMetadata
@@ -19,52 +22,185 @@ type Prompt struct {
 
 // PromptMetadata contains optional frontmatter metadata
 type PromptMetadata struct {
-	Description  string   `yaml:"description"`
-	RequiredVars []string `yaml:"required_vars"`
-	DefaultModel string   `yaml:"default_model"`
-	Temperature  *float32 `yaml:"temperature"`
-	MaxTokens    *int32   `yaml:"max_tokens"`
+	Description     string       `yaml:"description"`
+	RequiredVars    []string     `yaml:"required_vars"`
+	DefaultModel    string       `yaml:"default_model"`
+	Temperature     *float32     `yaml:"temperature"`
+	MaxTokens       *int32       `yaml:"max_tokens"`
+	Tags            []string     `yaml:"tags"`
+	Audit           *bool        `yaml:"audit"`            // Record inputs/outputs; unset follows PROMPT_AUDIT_ALL
+	AllowedServices []string     `yaml:"allowed_services"` // Calling services that may execute the prompt; empty allows all
+	Quota           *PromptQuota `yaml:"quota"`            // Caps how often the prompt may be called; unset is unlimited
 }
 
-// PromptCache is a thread-safe cache for loaded prompts
+// PromptQuota limits a prompt to Requests calls per window, shared by every
+// caller or counted separately for each calling service
+type PromptQuota struct {
+	Requests      int  `yaml:"requests"`       // 0 or less is unlimited
+	WindowSeconds int  `yaml:"window_seconds"` // Defaults to 60
+	PerService    bool `yaml:"per_service"`
+}
+
+// defaultQuotaWindow applies to quotas that don't set window_seconds
+const defaultQuotaWindow = time.Minute
+
+// Window returns the quota's window length
+func (q *PromptQuota) Window() time.Duration {
+	if q.WindowSeconds <= 0 {
+		return defaultQuotaWindow
+	}
+	return time.Duration(q.WindowSeconds) * time.Second
+}
+
+// HasTags reports whether the prompt carries every one of the given tags
+func (p *Prompt) HasTags(tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	if p.Metadata == nil {
+		return false
+	}
+
+	promptTags := make(map[string]bool, len(p.Metadata.Tags))
+	for _, tag := range p.Metadata.Tags {
+		promptTags[normalizeTag(tag)] = true
+	}
+
+	for _, tag := range tags {
+		if !promptTags[normalizeTag(tag)] {
+			return false
+		}
+	}
+	return true
+}
+
+// UsesModel reports whether the prompt's default_model is model, ignoring case
+func (p *Prompt) UsesModel(model string) bool {
+	if p.Metadata == nil {
+		return false
+	}
+	return strings.EqualFold(strings.TrimSpace(p.Metadata.DefaultModel), strings.TrimSpace(model))
+}
+
+// AllowsService reports whether the calling service may execute the prompt.
+// Prompts without allowed_services are open to every service.
+func (p *Prompt) AllowsService(service string) bool {
+	if p.Metadata == nil || len(p.Metadata.AllowedServices) == 0 {
+		return true
+	}
+	for _, allowed := range p.Metadata.AllowedServices {
+		if strings.TrimSpace(allowed) == service {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeTag makes tag matching case-insensitive
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// PromptCache is a thread-safe cache for loaded prompts. With a maximum
+// size set it evicts the least recently used prompt when full.
 type PromptCache struct {
-	mu      sync.RWMutex
-	prompts map[string]*Prompt
+	mu         sync.Mutex
+	prompts    map[string]*list.Element
+	order      *list.List // Front is most recently used
+	maxEntries int        // 0 means unbounded
+	evictions  int64
+	hits       int64
+	misses     int64
+}
+
+// PromptCacheStats is a snapshot of cache size and activity
+type PromptCacheStats struct {
+	Size       int
+	MaxEntries int
+	Evictions  int64
+	Hits       int64
+	Misses     int64
+}
+
+// cacheEntry is the value stored in each list element
+type cacheEntry struct {
+	path   string
+	prompt *Prompt
 }
 
-// NewPromptCache creates a new prompt cache
+// NewPromptCache creates a new, unbounded prompt cache
 func NewPromptCache() *PromptCache {
 	return &PromptCache{
-		prompts: make(map[string]*Prompt),
+		prompts: make(map[string]*list.Element),
+		order:   list.New(),
 	}
 }
 
-// Get retrieves a prompt from the cache
+// SetMaxEntries bounds the number of cached prompts (0 means unbounded).
+// Shrinking below the current size evicts immediately.
+func (c *PromptCache) SetMaxEntries(maxEntries int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.maxEntries = maxEntries
+	c.evictOverflow()
+}
+
+// Get retrieves a prompt from the cache and marks it as recently used
 func (c *PromptCache) Get(path string) (*Prompt, bool) {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
-	prompt, ok := c.prompts[path]
-	return prompt, ok
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	elem, ok := c.prompts[path]
+	if !ok {
+		c.misses++
+		return nil, false
+	}
+	c.hits++
+	c.order.MoveToFront(elem)
+	return elem.Value.(*cacheEntry).prompt, true
 }
 
-// Set stores a prompt in the cache
+// Set stores a prompt in the cache, evicting the least recently used
+// prompt if the cache is full
 func (c *PromptCache) Set(path string, prompt *Prompt) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	c.prompts[path] = prompt
+	if elem, ok := c.prompts[path]; ok {
+		elem.Value.(*cacheEntry).prompt = prompt
+		c.order.MoveToFront(elem)
+		return
+	}
+	c.prompts[path] = c.order.PushFront(&cacheEntry{path: path, prompt: prompt})
+	c.evictOverflow()
+}
+
+// evictOverflow drops least recently used prompts until the cache fits.
+// The caller must hold c.mu.
+func (c *PromptCache) evictOverflow() {
+	if c.maxEntries <= 0 {
+		return
+	}
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.prompts, oldest.Value.(*cacheEntry).path)
+		c.evictions++
+	}
 }
 
 // Delete removes a prompt from the cache
 func (c *PromptCache) Delete(path string) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
-	delete(c.prompts, path)
+	if elem, ok := c.prompts[path]; ok {
+		c.order.Remove(elem)
+		delete(c.prompts, path)
+	}
 }
 
-// List returns all prompt paths
+// List returns all cached prompt paths
 func (c *PromptCache) List() []string {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	paths := make([]string, 0, len(c.prompts))
 	for path := range c.prompts {
 		paths = append(paths, path)
@@ -72,24 +208,37 @@ func (c *PromptCache) List() []string {
 	return paths
 }
 
-// GetAll returns all prompts
+// GetAll returns all cached prompts
 func (c *PromptCache) GetAll() map[string]*Prompt {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	prompts := make(map[string]*Prompt, len(c.prompts))
-	for path, prompt := range c.prompts {
-		prompts[path] = prompt
+	for path, elem := range c.prompts {
+		prompts[path] = elem.Value.(*cacheEntry).prompt
 	}
 	return prompts
 }
 
 // Count returns the number of cached prompts
 func (c *PromptCache) Count() int {
-	c.mu.RLock()
-	defer c.mu.RUnlock()
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	return len(c.prompts)
 }
 
+// Stats returns the current cache size and counters
+func (c *PromptCache) Stats() PromptCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return PromptCacheStats{
+		Size:       len(c.prompts),
+		MaxEntries: c.maxEntries,
+		Evictions:  c.evictions,
+		Hits:       c.hits,
+		Misses:     c.misses,
+	}
+}
+
 // LLMRequest represents a request to an LLM provider
 type LLMRequest struct {
 	Prompt     string
@@ -146,6 +295,14 @@ type UsageStats struct {
 	RequestsByService  map[string]int64
 	TokensByModel      map[string]int64
 	AverageResponseMs  int64
+	Buckets            []UsageBucket // Only set when bucketing is requested
+}
+
+// UsageBucket aggregates usage within one time bucket
+type UsageBucket struct {
+	Start    time.Time
+	Requests int64
+	Tokens   int64
 }
 
 // RetryConfig contains retry configuration
@@ -2,6 +2,8 @@ package internal
 
 import (
 	"context"
+	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -10,9 +12,9 @@ import (
 
 // UsageTracker tracks LLM usage for analytics
 type UsageTracker struct {
-	store  *UsageStore
-	logger *zap.Logger
-	// analyticsClient would go here for real analytics service integration
+	store     *UsageStore
+	logger    *zap.Logger
+	forwarder UsageForwarder
 }
 
 // NewUsageTracker creates a new usage tracker
@@ -23,6 +25,12 @@ func NewUsageTracker(maxStoreSize int, logger *zap.Logger) *UsageTracker {
 	}
 }
 
+// SetForwarder enables forwarding every tracked usage event, e.g. to
+// analytics-service
+func (t *UsageTracker) SetForwarder(forwarder UsageForwarder) {
+	t.forwarder = forwarder
+}
+
 // TrackUsage tracks a usage event
 func (t *UsageTracker) TrackUsage(ctx context.Context, event *UsageEvent) error {
 	// Store locally
@@ -37,22 +45,43 @@ func (t *UsageTracker) TrackUsage(ctx context.Context, event *UsageEvent) error
 		zap.Int32("total_tokens", event.TotalTokens),
 		zap.Bool("success", event.Success))
 
-	// Send to analytics service asynchronously (fire and forget)
-	go func() {
-		// Note: In production, you'd initialize this client once and reuse it
-		// For now, this demonstrates the integration pattern
-		// The actual client should be passed to UsageTracker constructor
-		t.logger.Debug("usage event logged locally",
-			zap.String("calling_service", event.CallingService),
-			zap.String("model", event.Model),
-			zap.Int32("total_tokens", event.TotalTokens))
-	}()
+	// Forward to analytics asynchronously (fire and forget) so a slow or
+	// unavailable analytics-service never holds up tracking
+	if t.forwarder != nil {
+		go func() {
+			if err := t.forwarder.ForwardUsage(context.Background(), event); err != nil {
+				t.logger.Warn("failed to forward usage event",
+					zap.String("request_id", event.RequestID),
+					zap.Error(err))
+			}
+		}()
+	}
 
 	return nil
 }
 
-// GetStats returns usage statistics
-func (t *UsageTracker) GetStats(timeRange string, serviceFilter string) (*UsageStats, error) {
+// ParseUsageBucket converts a bucket name ("hour", "day") to its size.
+// An empty name disables bucketing and returns 0.
+func ParseUsageBucket(bucket string) (time.Duration, error) {
+	switch bucket {
+	case "":
+		return 0, nil
+	case "hour":
+		return time.Hour, nil
+	case "day":
+		return 24 * time.Hour, nil
+	default:
+		return 0, fmt.Errorf("invalid bucket %q: must be \"hour\" or \"day\"", bucket)
+	}
+}
+
+// GetStats returns usage statistics, with a time series when bucket is set
+func (t *UsageTracker) GetStats(timeRange string, serviceFilter string, bucket string) (*UsageStats, error) {
+	bucketSize, err := ParseUsageBucket(bucket)
+	if err != nil {
+		return nil, err
+	}
+
 	// Parse time range
 	var since time.Time
 	switch timeRange {
@@ -66,7 +95,7 @@ func (t *UsageTracker) GetStats(timeRange string, serviceFilter string) (*UsageS
 		since = time.Now().Add(-24 * time.Hour) // Default to day
 	}
 
-	return t.store.GetStats(since, serviceFilter), nil
+	return t.store.GetStats(since, serviceFilter, bucketSize), nil
 }
 
 // UsageStore stores usage events in memory
@@ -114,8 +143,9 @@ func (s *UsageStore) GetRecent(limit int) []UsageEvent {
 	return result
 }
 
-// GetStats returns aggregated statistics
-func (s *UsageStore) GetStats(since time.Time, serviceFilter string) *UsageStats {
+// GetStats returns aggregated statistics. A non-zero bucketSize also groups
+// events into UTC-aligned buckets of that size, oldest first.
+func (s *UsageStore) GetStats(since time.Time, serviceFilter string, bucketSize time.Duration) *UsageStats {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -126,6 +156,7 @@ func (s *UsageStore) GetStats(since time.Time, serviceFilter string) *UsageStats
 
 	var totalResponseTime int64
 	var responseCount int64
+	buckets := make(map[time.Time]*UsageBucket)
 
 	for _, event := range s.events {
 		// Filter by time
@@ -144,6 +175,17 @@ func (s *UsageStore) GetStats(since time.Time, serviceFilter string) *UsageStats
 		stats.RequestsByService[event.CallingService]++
 		stats.TokensByModel[event.Model] += int64(event.TotalTokens)
 
+		if bucketSize > 0 {
+			start := event.Timestamp.UTC().Truncate(bucketSize)
+			bucket, ok := buckets[start]
+			if !ok {
+				bucket = &UsageBucket{Start: start}
+				buckets[start] = bucket
+			}
+			bucket.Requests++
+			bucket.Tokens += int64(event.TotalTokens)
+		}
+
 		if event.Success {
 			totalResponseTime += event.ResponseTimeMs
 			responseCount++
@@ -155,6 +197,16 @@ func (s *UsageStore) GetStats(since time.Time, serviceFilter string) *UsageStats
 		stats.AverageResponseMs = totalResponseTime / responseCount
 	}
 
+	if bucketSize > 0 {
+		stats.Buckets = make([]UsageBucket, 0, len(buckets))
+		for _, bucket := range buckets {
+			stats.Buckets = append(stats.Buckets, *bucket)
+		}
+		sort.Slice(stats.Buckets, func(i, j int) bool {
+			return stats.Buckets[i].Start.Before(stats.Buckets[j].Start)
+		})
+	}
+
 	return stats
 }
 
@@ -0,0 +1,69 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsageStore_GetStats_Buckets(t *testing.T) {
+	base := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+
+	store := NewUsageStore(100)
+	store.Add(UsageEvent{CallingService: "billing", Model: "gpt-4", TotalTokens: 10, Timestamp: base.Add(5 * time.Minute)})
+	store.Add(UsageEvent{CallingService: "billing", Model: "gpt-4", TotalTokens: 20, Timestamp: base.Add(55 * time.Minute)})
+	store.Add(UsageEvent{CallingService: "support", Model: "gpt-4", TotalTokens: 5, Timestamp: base.Add(61 * time.Minute)})
+	store.Add(UsageEvent{CallingService: "billing", Model: "gpt-4", TotalTokens: 7, Timestamp: base.Add(16 * time.Hour)})
+
+	since := base.Add(-time.Hour)
+
+	t.Run("hourly", func(t *testing.T) {
+		stats := store.GetStats(since, "", time.Hour)
+
+		require.Len(t, stats.Buckets, 3)
+		assert.Equal(t, UsageBucket{Start: base, Requests: 2, Tokens: 30}, stats.Buckets[0])
+		assert.Equal(t, UsageBucket{Start: base.Add(time.Hour), Requests: 1, Tokens: 5}, stats.Buckets[1])
+		assert.Equal(t, UsageBucket{Start: base.Add(16 * time.Hour), Requests: 1, Tokens: 7}, stats.Buckets[2])
+		assert.Equal(t, int64(4), stats.TotalRequests)
+	})
+
+	t.Run("daily", func(t *testing.T) {
+		stats := store.GetStats(since, "", 24*time.Hour)
+
+		day1 := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+		require.Len(t, stats.Buckets, 2)
+		assert.Equal(t, UsageBucket{Start: day1, Requests: 3, Tokens: 35}, stats.Buckets[0])
+		assert.Equal(t, UsageBucket{Start: day1.Add(24 * time.Hour), Requests: 1, Tokens: 7}, stats.Buckets[1])
+	})
+
+	t.Run("service filter applies to buckets", func(t *testing.T) {
+		stats := store.GetStats(since, "support", time.Hour)
+
+		require.Len(t, stats.Buckets, 1)
+		assert.Equal(t, UsageBucket{Start: base.Add(time.Hour), Requests: 1, Tokens: 5}, stats.Buckets[0])
+	})
+
+	t.Run("no bucketing", func(t *testing.T) {
+		stats := store.GetStats(since, "", 0)
+		assert.Nil(t, stats.Buckets)
+	})
+}
+
+func TestParseUsageBucket(t *testing.T) {
+	size, err := ParseUsageBucket("hour")
+	require.NoError(t, err)
+	assert.Equal(t, time.Hour, size)
+
+	size, err = ParseUsageBucket("day")
+	require.NoError(t, err)
+	assert.Equal(t, 24*time.Hour, size)
+
+	size, err = ParseUsageBucket("")
+	require.NoError(t, err)
+	assert.Zero(t, size)
+
+	_, err = ParseUsageBucket("minute")
+	assert.Error(t, err)
+}
@@ -14,6 +14,8 @@ import (
 	"github.com/haunted-saas/notifications-service/internal"
 	"github.com/haunted-saas/notifications-service/internal/config"
 	pb "github.com/haunted-saas/notifications-service/proto/notifications/v1"
+	"github.com/haunted-saas/pkg/deadline"
+	"github.com/haunted-saas/pkg/shutdown"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"google.golang.org/grpc"
@@ -30,7 +32,7 @@ func main() {
 	}
 
 	// Initialize logger
-	logger, err := initLogger(cfg.Logging.Level, cfg.Logging.Format)
+	logger, err := initLogger(cfg.Logging.Level, cfg.Logging.Format, cfg.Logging.Sampling.Zap())
 	if err != nil {
 		log.Fatalf("Failed to initialize logger: %v", err)
 	}
@@ -51,13 +53,31 @@ func main() {
 		authMW,
 		cfg.SocketIO.MaxConnections,
 		cfg.SocketIO.AllowedOrigins,
+		cfg.SocketIO.AllowCredentials,
 		logger,
 	)
 	if err != nil {
 		logger.Fatal("Failed to create Socket.IO server", zap.Error(err))
 	}
+	socketServer.SetReadyPayloadFields(cfg.SocketIO.ReadyPayloadFields)
+	if cfg.SocketIO.OfflineQueueSize > 0 {
+		socketServer.SetOfflineQueue(
+			internal.NewOfflineQueue(cfg.SocketIO.OfflineQueueSize),
+			cfg.SocketIO.OfflineFlushRetries,
+			time.Duration(cfg.SocketIO.OfflineFlushBackoffMs)*time.Millisecond,
+			time.Duration(cfg.SocketIO.OfflineAckTimeoutMs)*time.Millisecond,
+		)
+	}
 	logger.Info("✓ Socket.IO server initialized")
 
+	// Start idle connection sweeper
+	if cfg.SocketIO.IdleTimeoutSec > 0 {
+		socketServer.StartIdleSweeper(
+			time.Duration(cfg.SocketIO.IdleTimeoutSec)*time.Second,
+			time.Duration(cfg.SocketIO.IdleSweepSec)*time.Second,
+		)
+	}
+
 	// Start Socket.IO server
 	go func() {
 		logger.Info("🚀 Socket.IO server starting",
@@ -73,11 +93,26 @@ func main() {
 
 	// Initialize gRPC server
 	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(loggingInterceptor(logger)),
+		grpc.ChainUnaryInterceptor(
+			loggingInterceptor(logger),
+			deadline.UnaryServerInterceptor(cfg.Server.DefaultDeadline),
+		),
 	)
 
 	// Register notifications service
 	notificationsService := internal.NewNotificationsServer(socketServer, logger)
+	if cfg.SocketIO.UserRateLimitPerMinute > 0 {
+		notificationsService.SetRateLimiter(internal.NewUserRateLimiter(cfg.SocketIO.UserRateLimitPerMinute, time.Minute))
+	}
+	var connectionHistory *internal.ConnectionHistory
+	if cfg.SocketIO.HistorySampleSec > 0 {
+		connectionHistory = internal.NewConnectionHistory(time.Duration(cfg.SocketIO.HistoryRetentionSec) * time.Second)
+		connectionHistory.StartSampling(
+			time.Duration(cfg.SocketIO.HistorySampleSec)*time.Second,
+			socketServer.GetConnectionManager().GetConnectionCount,
+		)
+		notificationsService.SetConnectionHistory(connectionHistory)
+	}
 	pb.RegisterNotificationsServiceServer(grpcServer, notificationsService)
 
 	// Register health check
@@ -109,15 +144,22 @@ func main() {
 	logger.Info("Shutting down servers...")
 
 	// Graceful shutdown
-	grpcServer.GracefulStop()
+	socketServer.StopIdleSweeper()
+	if connectionHistory != nil {
+		connectionHistory.StopSampling()
+	}
+	if !shutdown.DrainGRPC(grpcServer, cfg.Server.DrainTimeout) {
+		logger.Warn("In-flight requests did not finish in time, forced gRPC server stop",
+			zap.Duration("timeout", cfg.Server.DrainTimeout))
+	}
 	logger.Info("✓ gRPC server stopped")
 
 	// Note: Socket.IO server shutdown would require additional implementation
 	logger.Info("Shutdown complete")
 }
 
-// initLogger initializes the logger
-func initLogger(level, format string) (*zap.Logger, error) {
+// initLogger initializes the logger. A nil sampling config disables sampling.
+func initLogger(level, format string, sampling *zap.SamplingConfig) (*zap.Logger, error) {
 	var zapLevel zapcore.Level
 	switch level {
 	case "debug":
@@ -134,6 +176,7 @@ func initLogger(level, format string) (*zap.Logger, error) {
 
 	config := zap.NewProductionConfig()
 	config.Level = zap.NewAtomicLevelAt(zapLevel)
+	config.Sampling = sampling
 
 	if format == "console" {
 		config.Encoding = "console"
@@ -1,10 +1,14 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
+	"time"
+
+	"github.com/haunted-saas/pkg/logsampling"
 )
 
 // Config holds the service configuration
@@ -17,19 +21,41 @@ type Config struct {
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
-	GRPCPort     int
-	SocketIOPort int
-	Host         string
+	GRPCPort        int
+	SocketIOPort    int
+	Host            string
+	DefaultDeadline time.Duration // Applied to requests that arrive without a deadline (0 disables)
+	DrainTimeout    time.Duration // Bounds the wait for in-flight RPCs on shutdown (0 waits indefinitely)
 }
 
 // SocketIOConfig holds Socket.IO configuration
 type SocketIOConfig struct {
 	AllowedOrigins     []string
+	AllowCredentials   bool
 	MaxConnections     int
 	PingTimeoutSec     int
 	PingIntervalSec    int
 	EnableWebSocket    bool
 	EnablePolling      bool
+	IdleTimeoutSec     int
+	IdleSweepSec       int
+	ReadyPayloadFields map[string]interface{} // Extra fields added to the connection_ready payload
+
+	// Offline queue: messages for users with no connections are held and
+	// flushed on reconnect. OfflineQueueSize 0 disables queueing.
+	OfflineQueueSize      int
+	OfflineFlushRetries   int
+	OfflineFlushBackoffMs int
+	OfflineAckTimeoutMs   int
+
+	// Per-user SendToUser limit; priority messages are exempt. 0 disables it.
+	UserRateLimitPerMinute int
+
+	// Connection count history for GetConnectionStatsHistory. Samples are
+	// taken every HistorySampleSec and kept for HistoryRetentionSec.
+	// HistorySampleSec 0 disables sampling.
+	HistorySampleSec    int
+	HistoryRetentionSec int
 }
 
 // AuthConfig holds authentication configuration
@@ -39,32 +65,52 @@ type AuthConfig struct {
 
 // LoggingConfig holds logging configuration
 type LoggingConfig struct {
-	Level  string
-	Format string
+	Level    string
+	Format   string
+	Sampling logsampling.Config
 }
 
 // Load loads configuration from environment variables
 func Load() (*Config, error) {
+	readyFields, err := parseReadyPayloadFields(getEnv("CONNECTION_READY_FIELDS", ""))
+	if err != nil {
+		return nil, err
+	}
+
 	cfg := &Config{
 		Server: ServerConfig{
-			GRPCPort:     getEnvInt("GRPC_PORT", 50055),
-			SocketIOPort: getEnvInt("SOCKETIO_PORT", 3000),
-			Host:         getEnv("HOST", "0.0.0.0"),
+			GRPCPort:        getEnvInt("GRPC_PORT", 50055),
+			SocketIOPort:    getEnvInt("SOCKETIO_PORT", 3000),
+			Host:            getEnv("HOST", "0.0.0.0"),
+			DefaultDeadline: time.Duration(getEnvInt("DEFAULT_REQUEST_DEADLINE_SECONDS", 30)) * time.Second,
+			DrainTimeout:    time.Duration(getEnvInt("GRPC_DRAIN_TIMEOUT_SECONDS", 15)) * time.Second,
 		},
 		SocketIO: SocketIOConfig{
-			AllowedOrigins:  parseAllowedOrigins(getEnv("ALLOWED_ORIGINS", "http://localhost:3000")),
+			AllowedOrigins:   parseAllowedOrigins(getEnv("ALLOWED_ORIGINS", "http://localhost:3000")),
+			AllowCredentials: getEnvBool("ALLOW_CREDENTIALS", true),
 			MaxConnections:  getEnvInt("MAX_CONNECTIONS", 10000),
 			PingTimeoutSec:  getEnvInt("PING_TIMEOUT_SECONDS", 60),
 			PingIntervalSec: getEnvInt("PING_INTERVAL_SECONDS", 25),
 			EnableWebSocket: getEnvBool("ENABLE_WEBSOCKET", true),
 			EnablePolling:   getEnvBool("ENABLE_POLLING", true),
+			IdleTimeoutSec:  getEnvInt("IDLE_TIMEOUT_SECONDS", 0),
+			IdleSweepSec:    getEnvInt("IDLE_SWEEP_INTERVAL_SECONDS", 60),
+			ReadyPayloadFields: readyFields,
+			OfflineQueueSize:       getEnvInt("OFFLINE_QUEUE_SIZE", 0),
+			OfflineFlushRetries:    getEnvInt("OFFLINE_FLUSH_RETRIES", 3),
+			OfflineFlushBackoffMs:  getEnvInt("OFFLINE_FLUSH_BACKOFF_MS", 200),
+			OfflineAckTimeoutMs:    getEnvInt("OFFLINE_ACK_TIMEOUT_MS", 5000),
+			UserRateLimitPerMinute: getEnvInt("USER_RATE_LIMIT_PER_MINUTE", 0),
+			HistorySampleSec:       getEnvInt("CONNECTION_HISTORY_SAMPLE_SECONDS", 60),
+			HistoryRetentionSec:    getEnvInt("CONNECTION_HISTORY_RETENTION_SECONDS", 86400),
 		},
 		Authentication: AuthConfig{
 			JWTSecret: getEnv("JWT_SECRET", ""),
 		},
 		Logging: LoggingConfig{
-			Level:  getEnv("LOG_LEVEL", "info"),
-			Format: getEnv("LOG_FORMAT", "json"),
+			Level:    getEnv("LOG_LEVEL", "info"),
+			Format:   getEnv("LOG_FORMAT", "json"),
+			Sampling: logsampling.FromEnv(),
 		},
 	}
 
@@ -88,11 +134,63 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("at least one transport (WebSocket or Polling) must be enabled")
 	}
 
+	// A full wildcard origin is unsafe once credentials are accepted
+	if c.SocketIO.AllowCredentials {
+		for _, origin := range c.SocketIO.AllowedOrigins {
+			if origin == "*" {
+				return fmt.Errorf("ALLOWED_ORIGINS cannot contain \"*\" when ALLOW_CREDENTIALS is enabled")
+			}
+		}
+	}
+
+	// Validate idle sweeper (IDLE_TIMEOUT_SECONDS=0 disables it)
+	if c.SocketIO.IdleTimeoutSec > 0 && c.SocketIO.IdleSweepSec < 1 {
+		return fmt.Errorf("IDLE_SWEEP_INTERVAL_SECONDS must be at least 1")
+	}
+
 	// Validate max connections
 	if c.SocketIO.MaxConnections < 1 {
 		return fmt.Errorf("MAX_CONNECTIONS must be at least 1")
 	}
 
+	if err := c.Logging.Sampling.Validate(); err != nil {
+		return err
+	}
+
+	if c.Server.DefaultDeadline < 0 {
+		return fmt.Errorf("DEFAULT_REQUEST_DEADLINE_SECONDS cannot be negative")
+	}
+
+	if c.Server.DrainTimeout < 0 {
+		return fmt.Errorf("GRPC_DRAIN_TIMEOUT_SECONDS cannot be negative")
+	}
+
+	if c.SocketIO.OfflineQueueSize < 0 || c.SocketIO.OfflineFlushRetries < 0 || c.SocketIO.OfflineFlushBackoffMs < 0 {
+		return fmt.Errorf("OFFLINE_QUEUE_SIZE, OFFLINE_FLUSH_RETRIES and OFFLINE_FLUSH_BACKOFF_MS cannot be negative")
+	}
+
+	if c.SocketIO.OfflineQueueSize > 0 && c.SocketIO.OfflineAckTimeoutMs < 1 {
+		return fmt.Errorf("OFFLINE_ACK_TIMEOUT_MS must be at least 1 when OFFLINE_QUEUE_SIZE is set")
+	}
+
+	if c.SocketIO.UserRateLimitPerMinute < 0 {
+		return fmt.Errorf("USER_RATE_LIMIT_PER_MINUTE cannot be negative")
+	}
+
+	if c.SocketIO.HistorySampleSec < 0 {
+		return fmt.Errorf("CONNECTION_HISTORY_SAMPLE_SECONDS cannot be negative")
+	}
+	if c.SocketIO.HistorySampleSec > 0 && c.SocketIO.HistoryRetentionSec < c.SocketIO.HistorySampleSec {
+		return fmt.Errorf("CONNECTION_HISTORY_RETENTION_SECONDS must be at least CONNECTION_HISTORY_SAMPLE_SECONDS")
+	}
+
+	// The core connection_ready fields are always set by the server
+	for _, key := range []string{"user_id", "rooms", "socket_id"} {
+		if _, exists := c.SocketIO.ReadyPayloadFields[key]; exists {
+			return fmt.Errorf("CONNECTION_READY_FIELDS cannot override %q", key)
+		}
+	}
+
 	return nil
 }
 
@@ -137,3 +235,15 @@ func parseAllowedOrigins(originsStr string) []string {
 	}
 	return result
 }
+
+// parseReadyPayloadFields parses a JSON object of extra connection_ready fields
+func parseReadyPayloadFields(raw string) (map[string]interface{}, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+		return nil, fmt.Errorf("CONNECTION_READY_FIELDS must be a JSON object: %w", err)
+	}
+	return fields, nil
+}
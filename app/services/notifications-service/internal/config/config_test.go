@@ -0,0 +1,83 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestValidate_WildcardOriginWithCredentials(t *testing.T) {
+	cfg := &Config{
+		SocketIO: SocketIOConfig{
+			AllowedOrigins:   []string{"*"},
+			AllowCredentials: true,
+			MaxConnections:   100,
+			EnableWebSocket:  true,
+		},
+		Authentication: AuthConfig{JWTSecret: "secret"},
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected \"*\" with credentials to be rejected")
+	}
+
+	cfg.SocketIO.AllowCredentials = false
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected \"*\" without credentials to be valid, got %v", err)
+	}
+
+	cfg.SocketIO.AllowCredentials = true
+	cfg.SocketIO.AllowedOrigins = []string{"https://*.example.com"}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected wildcard subdomain with credentials to be valid, got %v", err)
+	}
+}
+
+func TestValidate_OfflineAckTimeout(t *testing.T) {
+	cfg := &Config{
+		SocketIO: SocketIOConfig{
+			AllowedOrigins:   []string{"https://app.example.com"},
+			MaxConnections:   100,
+			EnableWebSocket:  true,
+			OfflineQueueSize: 100,
+		},
+		Authentication: AuthConfig{JWTSecret: "secret"},
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected a zero ack timeout to be rejected with the offline queue enabled")
+	}
+
+	cfg.SocketIO.OfflineAckTimeoutMs = 5000
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected a positive ack timeout to be valid, got %v", err)
+	}
+
+	cfg.SocketIO.OfflineQueueSize = 0
+	cfg.SocketIO.OfflineAckTimeoutMs = 0
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("expected the ack timeout to be ignored with the queue disabled, got %v", err)
+	}
+}
+
+func TestParseReadyPayloadFields(t *testing.T) {
+	fields, err := parseReadyPayloadFields(`{"server_version":"1.4.0","reconnect_delay_ms":2000}`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if fields["server_version"] != "1.4.0" || fields["reconnect_delay_ms"] != float64(2000) {
+		t.Errorf("unexpected fields: %v", fields)
+	}
+
+	if _, err := parseReadyPayloadFields(`["not", "an", "object"]`); err == nil {
+		t.Error("expected a non-object to be rejected")
+	}
+
+	cfg := &Config{
+		SocketIO: SocketIOConfig{
+			MaxConnections:     100,
+			EnableWebSocket:    true,
+			ReadyPayloadFields: map[string]interface{}{"socket_id": "x"},
+		},
+		Authentication: AuthConfig{JWTSecret: "secret"},
+	}
+	if err := cfg.Validate(); err == nil {
+		t.Error("expected overriding a core field to be rejected")
+	}
+}
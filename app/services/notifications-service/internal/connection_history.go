@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"sync"
+	"time"
+)
+
+// ConnectionSample is the total connection count at a point in time
+type ConnectionSample struct {
+	Timestamp        time.Time
+	TotalConnections int
+}
+
+// ConnectionHistory keeps periodic connection count samples for a rolling
+// retention window, oldest first
+type ConnectionHistory struct {
+	mu        sync.Mutex
+	samples   []ConnectionSample
+	retention time.Duration
+	interval  time.Duration
+	stop      chan struct{}
+}
+
+// NewConnectionHistory creates a history that keeps samples for retention
+func NewConnectionHistory(retention time.Duration) *ConnectionHistory {
+	return &ConnectionHistory{
+		retention: retention,
+	}
+}
+
+// Record adds a sample and drops samples older than the retention window
+func (h *ConnectionHistory) Record(now time.Time, totalConnections int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.samples = append(h.samples, ConnectionSample{Timestamp: now, TotalConnections: totalConnections})
+
+	cutoff := now.Add(-h.retention)
+	expired := 0
+	for expired < len(h.samples) && h.samples[expired].Timestamp.Before(cutoff) {
+		expired++
+	}
+	if expired > 0 {
+		h.samples = append(h.samples[:0], h.samples[expired:]...)
+	}
+}
+
+// Since returns the samples taken at or after since, oldest first. A zero
+// since returns every retained sample.
+func (h *ConnectionHistory) Since(since time.Time) []ConnectionSample {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	result := make([]ConnectionSample, 0, len(h.samples))
+	for _, sample := range h.samples {
+		if !sample.Timestamp.Before(since) {
+			result = append(result, sample)
+		}
+	}
+	return result
+}
+
+// Interval returns how often samples are taken, or 0 if sampling hasn't started
+func (h *ConnectionHistory) Interval() time.Duration {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.interval
+}
+
+// StartSampling records count() every interval until StopSampling is called
+func (h *ConnectionHistory) StartSampling(interval time.Duration, count func() int) {
+	h.mu.Lock()
+	h.interval = interval
+	h.stop = make(chan struct{})
+	stop := h.stop
+	h.mu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case now := <-ticker.C:
+				h.Record(now, count())
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// StopSampling stops periodic sampling
+func (h *ConnectionHistory) StopSampling() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.stop != nil {
+		close(h.stop)
+		h.stop = nil
+	}
+}
@@ -0,0 +1,58 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestConnectionHistory_SamplesAccumulate(t *testing.T) {
+	connManager := NewConnectionManager()
+	connManager.AddConnection(&Connection{SocketID: "sock-1", UserID: "user-1", Transport: "websocket"})
+
+	history := NewConnectionHistory(time.Hour)
+	history.StartSampling(10*time.Millisecond, connManager.GetConnectionCount)
+	defer history.StopSampling()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for len(history.Since(time.Time{})) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected samples to accumulate, got %d", len(history.Since(time.Time{})))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	samples := history.Since(time.Time{})
+	for i, sample := range samples {
+		if sample.TotalConnections != 1 {
+			t.Errorf("sample %d total_connections = %d, want 1", i, sample.TotalConnections)
+		}
+		if i > 0 && sample.Timestamp.Before(samples[i-1].Timestamp) {
+			t.Errorf("samples not ordered oldest first at %d", i)
+		}
+	}
+	if history.Interval() != 10*time.Millisecond {
+		t.Errorf("interval = %v, want 10ms", history.Interval())
+	}
+}
+
+func TestConnectionHistory_Retention(t *testing.T) {
+	base := time.Date(2024, 3, 10, 9, 0, 0, 0, time.UTC)
+	history := NewConnectionHistory(10 * time.Minute)
+
+	history.Record(base, 1)
+	history.Record(base.Add(5*time.Minute), 2)
+	history.Record(base.Add(12*time.Minute), 3)
+
+	samples := history.Since(time.Time{})
+	if len(samples) != 2 {
+		t.Fatalf("expected the sample older than the retention window to be dropped, got %d samples", len(samples))
+	}
+	if samples[0].TotalConnections != 2 || samples[1].TotalConnections != 3 {
+		t.Errorf("unexpected samples: %+v", samples)
+	}
+
+	recent := history.Since(base.Add(10 * time.Minute))
+	if len(recent) != 1 || recent[0].TotalConnections != 3 {
+		t.Errorf("expected only the latest sample in the window, got %+v", recent)
+	}
+}
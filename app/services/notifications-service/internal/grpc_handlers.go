@@ -3,6 +3,7 @@ package internal
 import (
 	"context"
 	"encoding/json"
+	"time"
 
 	pb "github.com/haunted-saas/notifications-service/proto/notifications/v1"
 	"go.uber.org/zap"
@@ -14,6 +15,8 @@ import (
 type NotificationsServer struct {
 	pb.UnimplementedNotificationsServiceServer
 	socketServer *SocketIOServer
+	rateLimiter  *UserRateLimiter   // nil disables per-user rate limiting
+	history      *ConnectionHistory // nil disables GetConnectionStatsHistory
 	logger       *zap.Logger
 }
 
@@ -25,7 +28,19 @@ func NewNotificationsServer(socketServer *SocketIOServer, logger *zap.Logger) *N
 	}
 }
 
-// SendToUser sends a message to a specific user
+// SetRateLimiter limits how many messages SendToUser sends each user.
+// Priority messages are not counted or limited.
+func (s *NotificationsServer) SetRateLimiter(limiter *UserRateLimiter) {
+	s.rateLimiter = limiter
+}
+
+// SetConnectionHistory serves GetConnectionStatsHistory from history
+func (s *NotificationsServer) SetConnectionHistory(history *ConnectionHistory) {
+	s.history = history
+}
+
+// SendToUser sends a message to a specific user. Priority messages bypass
+// the per-user rate limit and are never dropped from a full offline queue.
 func (s *NotificationsServer) SendToUser(ctx context.Context, req *pb.SendToUserRequest) (*pb.SendToUserResponse, error) {
 	// Validate request
 	if req.UserId == "" {
@@ -35,6 +50,14 @@ func (s *NotificationsServer) SendToUser(ctx context.Context, req *pb.SendToUser
 		return nil, status.Error(codes.InvalidArgument, "event_type is required")
 	}
 
+	if !req.Priority && s.rateLimiter != nil && !s.rateLimiter.Allow(req.UserId) {
+		s.logger.Warn("user rate limit exceeded",
+			zap.String("user_id", req.UserId),
+			zap.String("event_type", req.EventType),
+			zap.String("correlation_id", req.CorrelationId))
+		return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded for user")
+	}
+
 	// Get user's room
 	userRoom := "user_" + req.UserId
 
@@ -50,6 +73,26 @@ func (s *NotificationsServer) SendToUser(ctx context.Context, req *pb.SendToUser
 	connections := s.socketServer.GetConnectionManager().GetUserConnections(req.UserId)
 	connectionCount := len(connections)
 
+	// Hold the message for the user's next connection if they're offline
+	if connectionCount == 0 && s.socketServer.QueueOfflineMessage(req.UserId, &QueuedMessage{
+		EventType:     req.EventType,
+		Payload:       payload,
+		CorrelationID: req.CorrelationId,
+		QueuedAt:      time.Now(),
+		Priority:      req.Priority,
+	}) {
+		s.logger.Info("message queued for offline user",
+			zap.String("user_id", req.UserId),
+			zap.String("event_type", req.EventType),
+			zap.Bool("priority", req.Priority),
+			zap.String("correlation_id", req.CorrelationId))
+
+		return &pb.SendToUserResponse{
+			Delivered: false,
+			Queued:    true,
+		}, nil
+	}
+
 	// Emit to user's room
 	s.socketServer.GetServer().BroadcastToRoom("/", userRoom, req.EventType, payload)
 
@@ -169,6 +212,35 @@ func (s *NotificationsServer) IsUserConnected(ctx context.Context, req *pb.IsUse
 	}, nil
 }
 
+// maxPresenceBatchSize caps the number of users checked by AreUsersConnected
+const maxPresenceBatchSize = 1000
+
+// AreUsersConnected checks presence for many users in one call
+func (s *NotificationsServer) AreUsersConnected(ctx context.Context, req *pb.AreUsersConnectedRequest) (*pb.AreUsersConnectedResponse, error) {
+	if len(req.UserIds) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "user_ids is required")
+	}
+	if len(req.UserIds) > maxPresenceBatchSize {
+		return nil, status.Errorf(codes.InvalidArgument, "user_ids cannot exceed %d entries", maxPresenceBatchSize)
+	}
+
+	counts := s.socketServer.GetConnectionManager().GetUserConnectionCounts(req.UserIds)
+
+	connectionsByUser := make(map[string]int32, len(counts))
+	connectedCount := int32(0)
+	for userID, count := range counts {
+		connectionsByUser[userID] = int32(count)
+		if count > 0 {
+			connectedCount++
+		}
+	}
+
+	return &pb.AreUsersConnectedResponse{
+		ConnectionsByUser: connectionsByUser,
+		ConnectedCount:    connectedCount,
+	}, nil
+}
+
 // GetConnectionStats returns connection statistics
 func (s *NotificationsServer) GetConnectionStats(ctx context.Context, req *pb.GetConnectionStatsRequest) (*pb.GetConnectionStatsResponse, error) {
 	connManager := s.socketServer.GetConnectionManager()
@@ -215,6 +287,35 @@ func (s *NotificationsServer) GetConnectionStats(ctx context.Context, req *pb.Ge
 	}, nil
 }
 
+// GetConnectionStatsHistory returns recent total connection samples, oldest first
+func (s *NotificationsServer) GetConnectionStatsHistory(ctx context.Context, req *pb.GetConnectionStatsHistoryRequest) (*pb.GetConnectionStatsHistoryResponse, error) {
+	if s.history == nil {
+		return nil, status.Error(codes.FailedPrecondition, "connection history is disabled")
+	}
+	if req.WindowSeconds < 0 {
+		return nil, status.Error(codes.InvalidArgument, "window_seconds cannot be negative")
+	}
+
+	var since time.Time
+	if req.WindowSeconds > 0 {
+		since = time.Now().Add(-time.Duration(req.WindowSeconds) * time.Second)
+	}
+
+	samples := s.history.Since(since)
+	samplesProto := make([]*pb.ConnectionStatsSample, len(samples))
+	for i, sample := range samples {
+		samplesProto[i] = &pb.ConnectionStatsSample{
+			Timestamp:        sample.Timestamp.Unix(),
+			TotalConnections: int32(sample.TotalConnections),
+		}
+	}
+
+	return &pb.GetConnectionStatsHistoryResponse{
+		Samples:               samplesProto,
+		SampleIntervalSeconds: int32(s.history.Interval().Seconds()),
+	}, nil
+}
+
 // DisconnectUser disconnects all connections for a user
 func (s *NotificationsServer) DisconnectUser(ctx context.Context, req *pb.DisconnectUserRequest) (*pb.DisconnectUserResponse, error) {
 	if req.UserId == "" {
@@ -0,0 +1,123 @@
+package internal
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	socketio "github.com/googollee/go-socket.io"
+	pb "github.com/haunted-saas/notifications-service/proto/notifications/v1"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNotificationsServer_AreUsersConnected(t *testing.T) {
+	connManager := NewConnectionManager()
+	connManager.AddConnection(&Connection{SocketID: "sock-1", UserID: "user-1", Transport: "websocket"})
+	connManager.AddConnection(&Connection{SocketID: "sock-2", UserID: "user-1", Transport: "polling"})
+	connManager.AddConnection(&Connection{SocketID: "sock-3", UserID: "user-2", Transport: "websocket"})
+
+	server := NewNotificationsServer(&SocketIOServer{connManager: connManager}, zap.NewNop())
+
+	resp, err := server.AreUsersConnected(context.Background(), &pb.AreUsersConnectedRequest{
+		UserIds: []string{"user-1", "user-2", "user-3"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := map[string]int32{"user-1": 2, "user-2": 1, "user-3": 0}
+	for userID, count := range expected {
+		if got, ok := resp.ConnectionsByUser[userID]; !ok || got != count {
+			t.Errorf("connections for %s = %d (present: %v), want %d", userID, got, ok, count)
+		}
+	}
+	if resp.ConnectedCount != 2 {
+		t.Errorf("connected_count = %d, want 2", resp.ConnectedCount)
+	}
+}
+
+func TestNotificationsServer_AreUsersConnected_Validation(t *testing.T) {
+	server := NewNotificationsServer(&SocketIOServer{connManager: NewConnectionManager()}, zap.NewNop())
+
+	_, err := server.AreUsersConnected(context.Background(), &pb.AreUsersConnectedRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for empty user_ids, got %v", err)
+	}
+
+	tooMany := make([]string, maxPresenceBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = "user"
+	}
+	_, err = server.AreUsersConnected(context.Background(), &pb.AreUsersConnectedRequest{UserIds: tooMany})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for oversized batch, got %v", err)
+	}
+}
+
+func TestNotificationsServer_SendToUser_PriorityBypassesRateLimit(t *testing.T) {
+	connManager := NewConnectionManager()
+	connManager.AddConnection(&Connection{SocketID: "sock-1", UserID: "user-1", Transport: "websocket"})
+
+	server := NewNotificationsServer(&SocketIOServer{
+		server:      socketio.NewServer(nil),
+		connManager: connManager,
+		logger:      zap.NewNop(),
+	}, zap.NewNop())
+	server.SetRateLimiter(NewUserRateLimiter(1, time.Minute))
+
+	send := func(priority bool) (*pb.SendToUserResponse, error) {
+		return server.SendToUser(context.Background(), &pb.SendToUserRequest{
+			UserId:      "user-1",
+			EventType:   "security_alert",
+			PayloadJson: `{"reason":"new_device"}`,
+			Priority:    priority,
+		})
+	}
+
+	if _, err := send(false); err != nil {
+		t.Fatalf("first message failed: %v", err)
+	}
+	if _, err := send(false); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("expected ResourceExhausted over the limit, got %v", err)
+	}
+
+	resp, err := send(true)
+	if err != nil {
+		t.Fatalf("priority message failed: %v", err)
+	}
+	if !resp.Delivered || resp.ConnectionCount != 1 {
+		t.Errorf("priority message not delivered: %+v", resp)
+	}
+}
+
+func TestNotificationsServer_GetConnectionStatsHistory(t *testing.T) {
+	server := NewNotificationsServer(&SocketIOServer{connManager: NewConnectionManager()}, zap.NewNop())
+
+	if _, err := server.GetConnectionStatsHistory(context.Background(), &pb.GetConnectionStatsHistoryRequest{}); status.Code(err) != codes.FailedPrecondition {
+		t.Fatalf("expected FailedPrecondition without a history, got %v", err)
+	}
+
+	now := time.Now()
+	history := NewConnectionHistory(time.Hour)
+	history.Record(now.Add(-30*time.Minute), 4)
+	history.Record(now.Add(-time.Minute), 7)
+	server.SetConnectionHistory(history)
+
+	resp, err := server.GetConnectionStatsHistory(context.Background(), &pb.GetConnectionStatsHistoryRequest{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Samples) != 2 || resp.Samples[0].TotalConnections != 4 || resp.Samples[1].TotalConnections != 7 {
+		t.Errorf("expected all retained samples oldest first, got %+v", resp.Samples)
+	}
+
+	resp, err = server.GetConnectionStatsHistory(context.Background(), &pb.GetConnectionStatsHistoryRequest{WindowSeconds: 300})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(resp.Samples) != 1 || resp.Samples[0].TotalConnections != 7 {
+		t.Errorf("expected only samples within the window, got %+v", resp.Samples)
+	}
+}
@@ -0,0 +1,95 @@
+package internal
+
+import (
+	"sync"
+	"time"
+)
+
+// QueuedMessage is a message held for a user who had no connections when it was sent
+type QueuedMessage struct {
+	EventType     string
+	Payload       interface{}
+	CorrelationID string
+	QueuedAt      time.Time
+	Priority      bool // Never dropped when the user's queue is full
+}
+
+// OfflineQueue holds undelivered messages per user, oldest first
+type OfflineQueue struct {
+	mu         sync.Mutex
+	messages   map[string][]*QueuedMessage // user_id -> messages
+	maxPerUser int
+}
+
+// NewOfflineQueue creates a queue holding at most maxPerUser messages for each user
+func NewOfflineQueue(maxPerUser int) *OfflineQueue {
+	return &OfflineQueue{
+		messages:   make(map[string][]*QueuedMessage),
+		maxPerUser: maxPerUser,
+	}
+}
+
+// Enqueue appends a message for the user, dropping the oldest non-priority
+// messages when the user's queue is full. It returns the number dropped.
+func (q *OfflineQueue) Enqueue(userID string, msg *QueuedMessage) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.messages[userID] = append(q.messages[userID], msg)
+	return q.trim(userID)
+}
+
+// Requeue puts messages back at the front of the user's queue, ahead of
+// anything queued since they were drained, and returns the number dropped
+func (q *OfflineQueue) Requeue(userID string, msgs []*QueuedMessage) int {
+	if len(msgs) == 0 {
+		return 0
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	q.messages[userID] = append(append([]*QueuedMessage{}, msgs...), q.messages[userID]...)
+	return q.trim(userID)
+}
+
+// Drain removes and returns all messages queued for the user
+func (q *OfflineQueue) Drain(userID string) []*QueuedMessage {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	msgs := q.messages[userID]
+	delete(q.messages, userID)
+	return msgs
+}
+
+// Len returns the number of messages queued for the user
+func (q *OfflineQueue) Len(userID string) int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.messages[userID])
+}
+
+// trim drops the oldest non-priority messages over the per-user limit.
+// Priority messages are always kept, so a queue may exceed the limit when
+// it holds more priority messages than the limit allows. Callers hold the lock.
+func (q *OfflineQueue) trim(userID string) int {
+	msgs := q.messages[userID]
+	excess := len(msgs) - q.maxPerUser
+	if excess <= 0 {
+		return 0
+	}
+
+	kept := make([]*QueuedMessage, 0, len(msgs))
+	dropped := 0
+	for _, msg := range msgs {
+		if dropped < excess && !msg.Priority {
+			dropped++
+			continue
+		}
+		kept = append(kept, msg)
+	}
+	q.messages[userID] = kept
+	return dropped
+}
@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"sync"
+	"time"
+)
+
+// UserRateLimiter caps the messages sent to each user per fixed window
+type UserRateLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	windows map[string]*rateWindow // user_id -> current window
+	now     func() time.Time
+}
+
+// rateWindow counts messages sent to one user in the current window
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// NewUserRateLimiter allows limit messages per user in each window
+func NewUserRateLimiter(limit int, window time.Duration) *UserRateLimiter {
+	return &UserRateLimiter{
+		limit:   limit,
+		window:  window,
+		windows: make(map[string]*rateWindow),
+		now:     time.Now,
+	}
+}
+
+// Allow records a message for the user and reports whether it is within the limit
+func (l *UserRateLimiter) Allow(userID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	w, ok := l.windows[userID]
+	if !ok || now.Sub(w.start) >= l.window {
+		l.prune(now)
+		w = &rateWindow{start: now}
+		l.windows[userID] = w
+	}
+
+	if w.count >= l.limit {
+		return false
+	}
+	w.count++
+	return true
+}
+
+// prune drops expired windows so idle users don't accumulate; callers hold the lock
+func (l *UserRateLimiter) prune(now time.Time) {
+	for userID, w := range l.windows {
+		if now.Sub(w.start) >= l.window {
+			delete(l.windows, userID)
+		}
+	}
+}
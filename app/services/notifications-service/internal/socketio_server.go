@@ -1,8 +1,11 @@
 package internal
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	socketio "github.com/googollee/go-socket.io"
@@ -13,6 +16,16 @@ import (
 	"go.uber.org/zap"
 )
 
+var (
+	// errConnectionClosed is returned when a queued message's connection went away mid-flush
+	errConnectionClosed = errors.New("connection closed")
+	// errAckTimeout is returned when the client does not acknowledge a queued message in time
+	errAckTimeout = errors.New("delivery not acknowledged")
+)
+
+// ReadyPayloadFunc returns extra connection_ready fields computed at connect time
+type ReadyPayloadFunc func(connection *Connection) map[string]interface{}
+
 // SocketIOServer manages the Socket.IO server
 type SocketIOServer struct {
 	server      *socketio.Server
@@ -21,6 +34,15 @@ type SocketIOServer struct {
 	authMW      *AuthMiddleware
 	logger      *zap.Logger
 	maxConns    int
+	stopSweep   chan struct{}
+
+	readyFields      map[string]interface{}
+	readyPayloadFunc ReadyPayloadFunc
+
+	offlineQueue *OfflineQueue
+	flushRetries int
+	flushBackoff time.Duration
+	ackTimeout   time.Duration
 }
 
 // NewSocketIOServer creates a new Socket.IO server
@@ -28,6 +50,7 @@ func NewSocketIOServer(
 	authMW *AuthMiddleware,
 	maxConns int,
 	allowedOrigins []string,
+	allowCredentials bool,
 	logger *zap.Logger,
 ) (*SocketIOServer, error) {
 	// Create Socket.IO server with WebSocket and polling transports
@@ -35,12 +58,12 @@ func NewSocketIOServer(
 		Transports: []transport.Transport{
 			&websocket.Transport{
 				CheckOrigin: func(r *http.Request) bool {
-					return checkOrigin(r, allowedOrigins)
+					return checkOrigin(r, allowedOrigins, allowCredentials)
 				},
 			},
 			&polling.Transport{
 				CheckOrigin: func(r *http.Request) bool {
-					return checkOrigin(r, allowedOrigins)
+					return checkOrigin(r, allowedOrigins, allowCredentials)
 				},
 			},
 		},
@@ -66,6 +89,44 @@ func NewSocketIOServer(
 	return s, nil
 }
 
+// SetReadyPayloadFields sets static fields added to every connection_ready
+// payload, e.g. the server version or reconnection hints
+func (s *SocketIOServer) SetReadyPayloadFields(fields map[string]interface{}) {
+	s.readyFields = fields
+}
+
+// SetReadyPayloadFunc sets a hook that adds per-connection fields to the
+// connection_ready payload, e.g. feature flags for the connecting user
+func (s *SocketIOServer) SetReadyPayloadFunc(fn ReadyPayloadFunc) {
+	s.readyPayloadFunc = fn
+}
+
+// SetOfflineQueue enables queueing messages for users with no connections.
+// Queued messages are flushed when the user reconnects. A message counts as
+// delivered once the client acknowledges it within ackTimeout; otherwise it
+// is retried up to retries times, starting at backoff and doubling.
+func (s *SocketIOServer) SetOfflineQueue(queue *OfflineQueue, retries int, backoff, ackTimeout time.Duration) {
+	s.offlineQueue = queue
+	s.flushRetries = retries
+	s.flushBackoff = backoff
+	s.ackTimeout = ackTimeout
+}
+
+// QueueOfflineMessage queues a message for an offline user. It returns
+// false when offline queueing is disabled.
+func (s *SocketIOServer) QueueOfflineMessage(userID string, msg *QueuedMessage) bool {
+	if s.offlineQueue == nil {
+		return false
+	}
+
+	if dropped := s.offlineQueue.Enqueue(userID, msg); dropped > 0 {
+		s.logger.Warn("offline queue full, dropped oldest messages",
+			zap.String("user_id", userID),
+			zap.Int("dropped", dropped))
+	}
+	return true
+}
+
 // registerHandlers registers Socket.IO event handlers
 func (s *SocketIOServer) registerHandlers() {
 	// Connection handler
@@ -82,6 +143,20 @@ func (s *SocketIOServer) registerHandlers() {
 	s.server.OnError("/", func(conn socketio.Conn, err error) {
 		s.handleError(conn, err)
 	})
+
+	// Heartbeat handler - clients with nothing else to send emit this to
+	// stay active; onEvent records the activity
+	s.onEvent("heartbeat", func(conn socketio.Conn) {})
+}
+
+// onEvent registers a handler for an inbound event. Every inbound event
+// counts as activity for the idle sweeper, so register client events here
+// rather than on the server directly.
+func (s *SocketIOServer) onEvent(event string, handler func(conn socketio.Conn)) {
+	s.server.OnEvent("/", event, func(conn socketio.Conn) {
+		s.connManager.Touch(conn.ID())
+		handler(conn)
+	})
 }
 
 // handleConnect handles new connections
@@ -144,15 +219,122 @@ func (s *SocketIOServer) handleConnect(conn socketio.Conn) error {
 		zap.String("team_room", teamRoom))
 
 	// Emit connection_ready event to client
-	conn.Emit("connection_ready", map[string]interface{}{
-		"user_id": claims.UserID,
-		"rooms":   []string{userRoom, teamRoom},
-		"socket_id": socketID,
-	})
+	conn.Emit("connection_ready", s.buildReadyPayload(connection, []string{userRoom, teamRoom}))
+
+	// Deliver anything sent while the user was offline
+	if s.offlineQueue != nil {
+		go s.flushOfflineQueue(connection)
+	}
 
 	return nil
 }
 
+// flushOfflineQueue delivers the user's queued messages in order. If a
+// message still fails after its retries, it and everything after it are
+// re-queued for the next connection.
+func (s *SocketIOServer) flushOfflineQueue(connection *Connection) {
+	msgs := s.offlineQueue.Drain(connection.UserID)
+	if len(msgs) == 0 {
+		return
+	}
+
+	for i, msg := range msgs {
+		if err := s.deliverWithRetry(connection, msg); err != nil {
+			dropped := s.offlineQueue.Requeue(connection.UserID, msgs[i:])
+			s.logger.Warn("offline queue flush failed, re-queued undelivered messages",
+				zap.String("socket_id", connection.SocketID),
+				zap.String("user_id", connection.UserID),
+				zap.Int("delivered", i),
+				zap.Int("requeued", len(msgs)-i-dropped),
+				zap.Int("dropped", dropped),
+				zap.Error(err))
+			return
+		}
+	}
+
+	s.logger.Info("offline queue flushed",
+		zap.String("socket_id", connection.SocketID),
+		zap.String("user_id", connection.UserID),
+		zap.Int("delivered", len(msgs)))
+}
+
+// deliverWithRetry delivers one queued message, backing off exponentially
+// between attempts. A closed connection is not retried.
+func (s *SocketIOServer) deliverWithRetry(connection *Connection, msg *QueuedMessage) error {
+	backoff := s.flushBackoff
+	var err error
+
+	for attempt := 0; attempt <= s.flushRetries; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+
+		if err = s.emitQueuedMessage(connection, msg); err == nil {
+			return nil
+		}
+		if errors.Is(err, errConnectionClosed) {
+			return err
+		}
+
+		s.logger.Warn("queued message delivery failed",
+			zap.String("socket_id", connection.SocketID),
+			zap.String("event_type", msg.EventType),
+			zap.Int("attempt", attempt+1),
+			zap.Error(err))
+	}
+
+	return err
+}
+
+// emitQueuedMessage emits a queued message with an ack callback and waits
+// for the client to acknowledge it. Emit itself can't fail, so the ack is
+// the only signal that the message arrived.
+func (s *SocketIOServer) emitQueuedMessage(connection *Connection, msg *QueuedMessage) error {
+	if _, exists := s.connManager.GetConnection(connection.SocketID); !exists {
+		return errConnectionClosed
+	}
+
+	acked := make(chan struct{}, 1)
+	connection.Conn.Emit(msg.EventType, msg.Payload, func() {
+		select {
+		case acked <- struct{}{}:
+		default:
+		}
+	})
+
+	timer := time.NewTimer(s.ackTimeout)
+	defer timer.Stop()
+
+	select {
+	case <-acked:
+		return nil
+	case <-timer.C:
+		return errAckTimeout
+	}
+}
+
+// buildReadyPayload merges the configured extra fields into the
+// connection_ready payload. The core fields are set last so extras
+// can't override them.
+func (s *SocketIOServer) buildReadyPayload(connection *Connection, rooms []string) map[string]interface{} {
+	payload := make(map[string]interface{}, len(s.readyFields)+3)
+	for k, v := range s.readyFields {
+		payload[k] = v
+	}
+	if s.readyPayloadFunc != nil {
+		for k, v := range s.readyPayloadFunc(connection) {
+			payload[k] = v
+		}
+	}
+
+	payload["user_id"] = connection.UserID
+	payload["rooms"] = rooms
+	payload["socket_id"] = connection.SocketID
+
+	return payload
+}
+
 // handleDisconnect handles disconnections
 func (s *SocketIOServer) handleDisconnect(conn socketio.Conn, reason string) {
 	socketID := conn.ID()
@@ -187,6 +369,70 @@ func (s *SocketIOServer) handleError(conn socketio.Conn, err error) {
 		zap.Error(err))
 }
 
+// StartIdleSweeper periodically disconnects connections idle longer than idleTimeout
+func (s *SocketIOServer) StartIdleSweeper(idleTimeout, interval time.Duration) {
+	s.stopSweep = make(chan struct{})
+
+	s.logger.Info("idle sweeper started",
+		zap.Duration("idle_timeout", idleTimeout),
+		zap.Duration("interval", interval))
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				s.sweepIdleConnections(time.Now(), idleTimeout)
+			case <-s.stopSweep:
+				return
+			}
+		}
+	}()
+}
+
+// StopIdleSweeper stops the idle sweeper
+func (s *SocketIOServer) StopIdleSweeper() {
+	if s.stopSweep != nil {
+		close(s.stopSweep)
+		s.stopSweep = nil
+	}
+}
+
+// sweepIdleConnections disconnects connections not seen within idleTimeout and returns how many were swept
+func (s *SocketIOServer) sweepIdleConnections(now time.Time, idleTimeout time.Duration) int {
+	cutoff := now.Add(-idleTimeout)
+	swept := 0
+
+	for _, connection := range s.connManager.GetIdleConnections(cutoff) {
+		// Clean up before closing so the disconnect handler sees nothing
+		// left to do; skip connections that were active since the snapshot
+		if !s.connManager.RemoveIdleConnection(connection.SocketID, cutoff) {
+			continue
+		}
+		s.roomManager.LeaveAllRooms(connection.SocketID)
+		swept++
+
+		s.logger.Info("disconnecting idle connection",
+			zap.String("socket_id", connection.SocketID),
+			zap.String("user_id", connection.UserID),
+			zap.Duration("idle_for", now.Sub(connection.LastSeen)))
+
+		if connection.Conn != nil {
+			connection.Conn.Close()
+		}
+	}
+
+	if swept > 0 {
+		s.logger.Info("idle sweep completed",
+			zap.Int("swept", swept),
+			zap.Int("remaining", s.connManager.GetConnectionCount()))
+	}
+
+	return swept
+}
+
 // GetServer returns the underlying Socket.IO server
 func (s *SocketIOServer) GetServer() *socketio.Server {
 	return s.server
@@ -202,18 +448,56 @@ func (s *SocketIOServer) GetRoomManager() *RoomManager {
 	return s.roomManager
 }
 
-// checkOrigin checks if the origin is allowed
-func checkOrigin(r *http.Request, allowedOrigins []string) bool {
+// checkOrigin checks if the origin is allowed. Entries may be exact origins,
+// wildcard subdomain patterns ("https://*.example.com" or "*.example.com"),
+// or "*" - which is ignored when credentials are allowed.
+func checkOrigin(r *http.Request, allowedOrigins []string, allowCredentials bool) bool {
 	origin := r.Header.Get("Origin")
 	if origin == "" {
 		return true // Allow requests without origin (same-origin)
 	}
 
 	for _, allowed := range allowedOrigins {
-		if origin == allowed || allowed == "*" {
+		if allowed == "*" {
+			if allowCredentials {
+				continue
+			}
+			return true
+		}
+		if matchOrigin(origin, allowed) {
 			return true
 		}
 	}
 
 	return false
 }
+
+// matchOrigin matches an origin against an exact or wildcard subdomain pattern
+func matchOrigin(origin, pattern string) bool {
+	if origin == pattern {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+
+	// Optional scheme on the pattern must match
+	hostPattern := pattern
+	if idx := strings.Index(pattern, "://"); idx != -1 {
+		if !strings.EqualFold(pattern[:idx], u.Scheme) {
+			return false
+		}
+		hostPattern = pattern[idx+3:]
+	}
+
+	if !strings.HasPrefix(hostPattern, "*.") {
+		return false
+	}
+
+	// "*.example.com" matches "app.example.com" but not "example.com"
+	suffix := strings.ToLower(hostPattern[1:])
+	host := strings.ToLower(u.Host)
+	return len(host) > len(suffix) && strings.HasSuffix(host, suffix)
+}
@@ -0,0 +1,357 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	socketio "github.com/googollee/go-socket.io"
+	"go.uber.org/zap"
+)
+
+// fakeConn records what handleConnect emits; unused methods panic via the nil embed
+type fakeConn struct {
+	socketio.Conn
+	id      string
+	url     url.URL
+	emitted map[string][]interface{}
+}
+
+func (c *fakeConn) ID() string                { return c.id }
+func (c *fakeConn) URL() url.URL              { return c.url }
+func (c *fakeConn) RemoteHeader() http.Header { return http.Header{} }
+func (c *fakeConn) Join(room string)          {}
+func (c *fakeConn) Emit(event string, v ...interface{}) {
+	c.emitted[event] = v
+}
+
+func TestCheckOrigin_WildcardSubdomains(t *testing.T) {
+	allowed := []string{"http://localhost:3000", "https://*.example.com", "*.internal.test"}
+
+	tests := []struct {
+		name     string
+		origin   string
+		expected bool
+	}{
+		{name: "no origin header", origin: "", expected: true},
+		{name: "exact match", origin: "http://localhost:3000", expected: true},
+		{name: "subdomain match", origin: "https://app.example.com", expected: true},
+		{name: "nested subdomain match", origin: "https://eu.app.example.com", expected: true},
+		{name: "bare domain does not match wildcard", origin: "https://example.com", expected: false},
+		{name: "scheme mismatch", origin: "http://app.example.com", expected: false},
+		{name: "lookalike domain", origin: "https://evilexample.com", expected: false},
+		{name: "suffix attack", origin: "https://app.example.com.evil.io", expected: false},
+		{name: "schemeless pattern matches any scheme", origin: "http://api.internal.test", expected: true},
+		{name: "unknown origin", origin: "https://attacker.io", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/socket.io/", nil)
+			if tt.origin != "" {
+				req.Header.Set("Origin", tt.origin)
+			}
+			if got := checkOrigin(req, allowed, true); got != tt.expected {
+				t.Errorf("checkOrigin(%q) = %v, want %v", tt.origin, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheckOrigin_FullWildcard(t *testing.T) {
+	req := httptest.NewRequest("GET", "/socket.io/", nil)
+	req.Header.Set("Origin", "https://anything.io")
+
+	// "*" is honored only when credentials are not allowed
+	if !checkOrigin(req, []string{"*"}, false) {
+		t.Error("expected \"*\" to allow any origin without credentials")
+	}
+	if checkOrigin(req, []string{"*"}, true) {
+		t.Error("expected \"*\" to be rejected when credentials are allowed")
+	}
+}
+
+func TestSweepIdleConnections(t *testing.T) {
+	server := &SocketIOServer{
+		connManager: NewConnectionManager(),
+		roomManager: NewRoomManager(nil),
+		logger:      zap.NewNop(),
+	}
+
+	now := time.Now()
+	server.connManager.AddConnection(&Connection{
+		SocketID: "idle-socket",
+		UserID:   "user-1",
+		LastSeen: now.Add(-10 * time.Minute),
+	})
+	server.connManager.AddConnection(&Connection{
+		SocketID: "active-socket",
+		UserID:   "user-2",
+		LastSeen: now.Add(-30 * time.Second),
+	})
+	server.roomManager.JoinRoom("idle-socket", "user_user-1", "user")
+	server.roomManager.JoinRoom("active-socket", "user_user-2", "user")
+
+	swept := server.sweepIdleConnections(now, 5*time.Minute)
+
+	if swept != 1 {
+		t.Fatalf("expected 1 connection swept, got %d", swept)
+	}
+	if _, exists := server.connManager.GetConnection("idle-socket"); exists {
+		t.Error("expected idle connection to be removed")
+	}
+	if _, exists := server.connManager.GetConnection("active-socket"); !exists {
+		t.Error("expected active connection to survive")
+	}
+	if members := server.roomManager.GetRoomMembers("user_user-1"); len(members) != 0 {
+		t.Errorf("expected idle connection to leave its rooms, got members %v", members)
+	}
+	if members := server.roomManager.GetRoomMembers("user_user-2"); len(members) != 1 {
+		t.Errorf("expected active connection to stay in its room, got members %v", members)
+	}
+}
+
+func TestConnectionManager_TouchKeepsConnectionActive(t *testing.T) {
+	manager := NewConnectionManager()
+	manager.AddConnection(&Connection{
+		SocketID: "socket-1",
+		LastSeen: time.Now().Add(-time.Hour),
+	})
+
+	cutoff := time.Now().Add(-time.Minute)
+	if idle := manager.GetIdleConnections(cutoff); len(idle) != 1 {
+		t.Fatalf("expected 1 idle connection before touch, got %d", len(idle))
+	}
+
+	manager.Touch("socket-1")
+
+	if idle := manager.GetIdleConnections(cutoff); len(idle) != 0 {
+		t.Errorf("expected no idle connections after touch, got %d", len(idle))
+	}
+}
+
+func TestConnectionManager_RemoveIdleConnectionSkipsTouched(t *testing.T) {
+	manager := NewConnectionManager()
+	manager.AddConnection(&Connection{
+		SocketID: "socket-1",
+		LastSeen: time.Now().Add(-time.Hour),
+	})
+
+	cutoff := time.Now().Add(-time.Minute)
+	if idle := manager.GetIdleConnections(cutoff); len(idle) != 1 {
+		t.Fatalf("expected 1 idle connection, got %d", len(idle))
+	}
+
+	// Activity between the snapshot and the removal keeps the connection
+	manager.Touch("socket-1")
+
+	if manager.RemoveIdleConnection("socket-1", cutoff) {
+		t.Error("expected a touched connection not to be removed")
+	}
+	if _, exists := manager.GetConnection("socket-1"); !exists {
+		t.Error("expected touched connection to survive")
+	}
+}
+
+func TestSweepIdleConnections_ConcurrentTouch(t *testing.T) {
+	server := &SocketIOServer{
+		connManager: NewConnectionManager(),
+		roomManager: NewRoomManager(nil),
+		logger:      zap.NewNop(),
+	}
+	server.connManager.AddConnection(&Connection{
+		SocketID: "socket-1",
+		UserID:   "user-1",
+		LastSeen: time.Now(),
+	})
+
+	// Run under -race: sweeping reads LastSeen while Touch writes it
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			server.connManager.Touch("socket-1")
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		server.sweepIdleConnections(time.Now(), time.Hour)
+	}
+	<-done
+
+	if _, exists := server.connManager.GetConnection("socket-1"); !exists {
+		t.Error("expected active connection to survive")
+	}
+}
+
+func TestHandleConnect_ReadyPayloadIncludesConfiguredFields(t *testing.T) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &JWTClaims{
+		UserID: "user-1",
+		TeamID: "team-1",
+	}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	server := &SocketIOServer{
+		connManager: NewConnectionManager(),
+		roomManager: NewRoomManager(nil),
+		authMW:      NewAuthMiddleware("secret", zap.NewNop()),
+		logger:      zap.NewNop(),
+		maxConns:    10,
+	}
+	server.SetReadyPayloadFields(map[string]interface{}{
+		"server_version":     "1.4.0",
+		"reconnect_delay_ms": float64(2000),
+		"user_id":            "spoofed", // Core fields can't be overridden
+	})
+	server.SetReadyPayloadFunc(func(connection *Connection) map[string]interface{} {
+		return map[string]interface{}{
+			"features": map[string]bool{"team_" + connection.TeamID + "_beta": true},
+		}
+	})
+
+	conn := &fakeConn{
+		id:      "socket-1",
+		url:     url.URL{Path: "/socket.io/", RawQuery: "token=" + token},
+		emitted: make(map[string][]interface{}),
+	}
+	if err := server.handleConnect(conn); err != nil {
+		t.Fatalf("handleConnect failed: %v", err)
+	}
+
+	args, ok := conn.emitted["connection_ready"]
+	if !ok || len(args) != 1 {
+		t.Fatalf("expected one connection_ready payload, got %v", args)
+	}
+	payload := args[0].(map[string]interface{})
+
+	if payload["server_version"] != "1.4.0" {
+		t.Errorf("expected server_version 1.4.0, got %v", payload["server_version"])
+	}
+	if payload["reconnect_delay_ms"] != float64(2000) {
+		t.Errorf("expected reconnect_delay_ms 2000, got %v", payload["reconnect_delay_ms"])
+	}
+	if features, _ := payload["features"].(map[string]bool); !features["team_team-1_beta"] {
+		t.Errorf("expected per-connection features, got %v", payload["features"])
+	}
+	if payload["user_id"] != "user-1" {
+		t.Errorf("expected user_id user-1, got %v", payload["user_id"])
+	}
+	if payload["socket_id"] != "socket-1" {
+		t.Errorf("expected socket_id socket-1, got %v", payload["socket_id"])
+	}
+	if rooms, _ := payload["rooms"].([]string); len(rooms) != 2 || rooms[0] != "user_user-1" {
+		t.Errorf("expected user and team rooms, got %v", payload["rooms"])
+	}
+}
+
+// ackConn records the payloads emitted to it, in order, and acks each
+// one unless its attempt number is in dropAcks
+type ackConn struct {
+	socketio.Conn
+	payloads []interface{}
+	attempts int
+	dropAcks map[int]bool
+}
+
+func (c *ackConn) Emit(event string, v ...interface{}) {
+	c.attempts++
+	c.payloads = append(c.payloads, v[0])
+	if ack, ok := v[len(v)-1].(func()); ok && !c.dropAcks[c.attempts] {
+		ack()
+	}
+}
+
+func newOfflineQueueTestServer(retries int, dropAcks map[int]bool) (*SocketIOServer, *Connection, *ackConn) {
+	server := &SocketIOServer{
+		connManager: NewConnectionManager(),
+		roomManager: NewRoomManager(nil),
+		logger:      zap.NewNop(),
+	}
+	server.SetOfflineQueue(NewOfflineQueue(10), retries, time.Millisecond, 10*time.Millisecond)
+
+	conn := &ackConn{dropAcks: dropAcks}
+	connection := &Connection{SocketID: "socket-1", UserID: "user-1", Conn: conn}
+	server.connManager.AddConnection(connection)
+	return server, connection, conn
+}
+
+func TestFlushOfflineQueue_RetriesUnacknowledgedMessage(t *testing.T) {
+	// The client misses the first ack, so "first" is sent twice
+	server, connection, conn := newOfflineQueueTestServer(3, map[int]bool{1: true})
+	server.QueueOfflineMessage("user-1", &QueuedMessage{EventType: "notification", Payload: "first"})
+	server.QueueOfflineMessage("user-1", &QueuedMessage{EventType: "notification", Payload: "second"})
+
+	server.flushOfflineQueue(connection)
+
+	if conn.attempts != 3 {
+		t.Errorf("expected 3 delivery attempts, got %d", conn.attempts)
+	}
+	if len(conn.payloads) != 3 || conn.payloads[0] != "first" || conn.payloads[1] != "first" || conn.payloads[2] != "second" {
+		t.Errorf("expected first retried before second, got %v", conn.payloads)
+	}
+	if queued := server.offlineQueue.Len("user-1"); queued != 0 {
+		t.Errorf("expected empty queue after flush, got %d", queued)
+	}
+}
+
+func TestFlushOfflineQueue_RequeuesAfterRetriesExhausted(t *testing.T) {
+	// "first" is acked; every attempt at "second" goes unacknowledged
+	server, connection, conn := newOfflineQueueTestServer(2, map[int]bool{2: true, 3: true, 4: true})
+	server.QueueOfflineMessage("user-1", &QueuedMessage{EventType: "notification", Payload: "first"})
+	server.QueueOfflineMessage("user-1", &QueuedMessage{EventType: "notification", Payload: "second"})
+
+	server.flushOfflineQueue(connection)
+
+	// One attempt for "first", then the initial try plus 2 retries for "second"
+	if conn.attempts != 4 {
+		t.Errorf("expected 4 delivery attempts, got %d", conn.attempts)
+	}
+	remaining := server.offlineQueue.Drain("user-1")
+	if len(remaining) != 1 || remaining[0].Payload != "second" {
+		t.Errorf("expected only the unacknowledged message re-queued, got %v", remaining)
+	}
+}
+
+func TestFlushOfflineQueue_ClosedConnectionIsNotRetried(t *testing.T) {
+	server, connection, conn := newOfflineQueueTestServer(3, nil)
+	server.QueueOfflineMessage("user-1", &QueuedMessage{EventType: "notification", Payload: "first"})
+	server.QueueOfflineMessage("user-1", &QueuedMessage{EventType: "notification", Payload: "second"})
+
+	// The user disconnects before the flush reaches the messages
+	server.connManager.RemoveConnection(connection.SocketID)
+
+	server.flushOfflineQueue(connection)
+
+	if conn.attempts != 0 {
+		t.Errorf("expected nothing emitted to a closed connection, got %v", conn.payloads)
+	}
+	remaining := server.offlineQueue.Drain("user-1")
+	if len(remaining) != 2 || remaining[0].Payload != "first" || remaining[1].Payload != "second" {
+		t.Errorf("expected both messages kept for the next connection, got %v", remaining)
+	}
+}
+
+func TestOfflineQueue_PriorityMessagesAreNeverTrimmed(t *testing.T) {
+	queue := NewOfflineQueue(2)
+	queue.Enqueue("user-1", &QueuedMessage{EventType: "security_alert", Priority: true})
+	queue.Enqueue("user-1", &QueuedMessage{EventType: "notification"})
+	queue.Enqueue("user-1", &QueuedMessage{EventType: "notification"})
+
+	// The oldest non-priority message is dropped, not the older priority one
+	msgs := queue.Drain("user-1")
+	if len(msgs) != 2 || !msgs[0].Priority || msgs[1].Priority {
+		t.Fatalf("unexpected queue after trim: %+v", msgs)
+	}
+
+	// Priority messages are kept even past the limit
+	for i := 0; i < 3; i++ {
+		queue.Enqueue("user-1", &QueuedMessage{EventType: "security_alert", Priority: true})
+	}
+	if got := queue.Len("user-1"); got != 3 {
+		t.Errorf("queue length = %d, want 3 priority messages", got)
+	}
+}
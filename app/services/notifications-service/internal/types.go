@@ -56,10 +56,29 @@ func (m *ConnectionManager) RemoveConnection(socketID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
+	if conn, exists := m.connections[socketID]; exists {
+		m.removeLocked(conn)
+	}
+}
+
+// RemoveIdleConnection removes a connection only if it still hasn't been
+// seen since the cutoff, so activity that lands after a sweep picked the
+// connection keeps it alive. It reports whether the connection was removed.
+func (m *ConnectionManager) RemoveIdleConnection(socketID string, cutoff time.Time) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
 	conn, exists := m.connections[socketID]
-	if !exists {
-		return
+	if !exists || !conn.LastSeen.Before(cutoff) {
+		return false
 	}
+	m.removeLocked(conn)
+	return true
+}
+
+// removeLocked removes a connection from every index; m.mu must be held
+func (m *ConnectionManager) removeLocked(conn *Connection) {
+	socketID := conn.SocketID
 
 	// Remove from user connections
 	m.userConns[conn.UserID] = removeFromSlice(m.userConns[conn.UserID], socketID)
@@ -108,6 +127,45 @@ func (m *ConnectionManager) IsUserConnected(userID string) bool {
 	return len(m.userConns[userID]) > 0
 }
 
+// GetUserConnectionCounts returns the connection count for each user, 0 if disconnected
+func (m *ConnectionManager) GetUserConnectionCounts(userIDs []string) map[string]int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	counts := make(map[string]int, len(userIDs))
+	for _, userID := range userIDs {
+		counts[userID] = len(m.userConns[userID])
+	}
+	return counts
+}
+
+// Touch records activity on a connection
+func (m *ConnectionManager) Touch(socketID string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if conn, exists := m.connections[socketID]; exists {
+		conn.LastSeen = time.Now()
+	}
+}
+
+// GetIdleConnections returns connections not seen since the cutoff. The
+// results are copies taken under the lock, since Touch keeps updating
+// LastSeen on the live connections.
+func (m *ConnectionManager) GetIdleConnections(cutoff time.Time) []*Connection {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	idle := make([]*Connection, 0)
+	for _, conn := range m.connections {
+		if conn.LastSeen.Before(cutoff) {
+			snapshot := *conn
+			idle = append(idle, &snapshot)
+		}
+	}
+	return idle
+}
+
 // GetConnectionCount returns total connection count
 func (m *ConnectionManager) GetConnectionCount() int {
 	m.mu.RLock()
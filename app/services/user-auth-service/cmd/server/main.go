@@ -5,19 +5,26 @@ import (
 	"fmt"
 	"log"
 	"net"
+	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
 	"time"
 
+	"github.com/haunted-saas/pkg/deadline"
+	"github.com/haunted-saas/pkg/shutdown"
 	"github.com/haunted-saas/user-auth-service/internal/auth"
 	"github.com/haunted-saas/user-auth-service/internal/config"
 	"github.com/haunted-saas/user-auth-service/internal/database"
 	"github.com/haunted-saas/user-auth-service/internal/handler"
 	"github.com/haunted-saas/user-auth-service/internal/logging"
+	"github.com/haunted-saas/user-auth-service/internal/metrics"
+	"github.com/haunted-saas/user-auth-service/internal/notifications"
 	"github.com/haunted-saas/user-auth-service/internal/repository"
 	"github.com/haunted-saas/user-auth-service/internal/service"
 	pb "github.com/haunted-saas/user-auth-service/proto/userauth/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
@@ -58,11 +65,7 @@ func main() {
 	}
 
 	// Initialize Redis
-	redisClient := redis.NewClient(&redis.Options{
-		Addr:     fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port),
-		Password: cfg.Redis.Password,
-		DB:       cfg.Redis.DB,
-	})
+	redisClient := redis.NewClient(database.RedisOptions(cfg))
 
 	// Test Redis connection
 	ctx := context.Background()
@@ -77,10 +80,13 @@ func main() {
 		cfg.JWT.PrivateKeyPath,
 		cfg.JWT.PublicKeyPath,
 		cfg.JWT.Expiration,
+		cfg.JWT.Issuer,
+		cfg.JWT.Audience,
 	)
 	if err != nil {
 		logger.Fatal("Failed to initialize token manager", zap.Error(err))
 	}
+	tokenManager.SetLeeway(cfg.JWT.Leeway)
 
 	logger.Info("✓ Token manager initialized")
 
@@ -92,19 +98,47 @@ func main() {
 	rateLimiterRepo := repository.NewRateLimiterRepository(redisClient)
 	permCacheRepo := repository.NewPermissionCacheRepository(redisClient)
 	resetRepo := repository.NewPasswordResetRepository(redisClient)
+	loginHistoryRepo := repository.NewLoginHistoryRepository(redisClient)
+	auditRepo := repository.NewAuditRepository(db)
 
 	// Initialize services
+	auditService := service.NewAuditService(auditRepo, userRepo, logger)
+	logger.SetAuditSink(auditService)
+
 	authService := service.NewAuthService(
 		userRepo,
 		roleRepo,
 		sessionRepo,
 		rateLimiterRepo,
 		resetRepo,
+		permCacheRepo,
+		loginHistoryRepo,
 		tokenManager,
+		metrics.NewAuthMetrics(prometheus.DefaultRegisterer),
 		cfg,
 		logger,
 	)
 
+	// Lockout alerts and the deactivation cascade are optional; auth works
+	// without notifications-service
+	if cfg.Security.NotifyOnLockout || cfg.Security.NotifyOnDeactivation {
+		notificationsClient, err := notifications.NewClient(
+			cfg.Notifications.Address,
+			time.Duration(cfg.Notifications.TimeoutSec)*time.Second,
+		)
+		if err != nil {
+			logger.Warn("Notifications disabled", zap.Error(err))
+		} else {
+			defer notificationsClient.Close()
+			if cfg.Security.NotifyOnLockout {
+				authService.SetSecurityNotifier(notificationsClient)
+			}
+			if cfg.Security.NotifyOnDeactivation {
+				authService.SetDeactivationNotifier(notificationsClient)
+			}
+		}
+	}
+
 	rbacService := service.NewRBACService(
 		userRepo,
 		roleRepo,
@@ -114,13 +148,17 @@ func main() {
 		cfg,
 		logger,
 	)
+	authService.SetPermissionResolver(rbacService)
 
 	// Initialize handler
-	authHandler := handler.NewAuthHandler(authService, rbacService)
+	authHandler := handler.NewAuthHandler(authService, rbacService, auditService)
 
 	// Create gRPC server
 	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(loggingInterceptor(logger)),
+		grpc.ChainUnaryInterceptor(
+			loggingInterceptor(logger),
+			deadline.UnaryServerInterceptor(cfg.Server.DefaultDeadline),
+		),
 	)
 
 	// Register services
@@ -150,13 +188,39 @@ func main() {
 		}
 	}()
 
+	// Serve Prometheus metrics
+	var metricsServer *http.Server
+	if cfg.Server.MetricsPort > 0 {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", promhttp.Handler())
+		metricsServer = &http.Server{
+			Addr:    fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.MetricsPort),
+			Handler: mux,
+		}
+
+		go func() {
+			logger.Info("📈 Metrics server started", zap.String("address", metricsServer.Addr))
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				logger.Error("Metrics server failed", zap.Error(err))
+			}
+		}()
+	}
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	logger.Info("Shutting down server...")
-	grpcServer.GracefulStop()
+	if metricsServer != nil {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		metricsServer.Shutdown(shutdownCtx)
+		cancel()
+	}
+	if !shutdown.DrainGRPC(grpcServer, cfg.Server.DrainTimeout) {
+		logger.Warn("In-flight requests did not finish in time, forced gRPC server stop",
+			zap.Duration("timeout", cfg.Server.DrainTimeout))
+	}
 	logger.Info("Server stopped")
 }
 
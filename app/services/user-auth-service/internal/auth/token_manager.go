@@ -1,7 +1,9 @@
 package auth
 
 import (
+	"crypto/rand"
 	"crypto/rsa"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"time"
@@ -16,20 +18,25 @@ type TokenManager struct {
 	privateKey *rsa.PrivateKey
 	publicKey  *rsa.PublicKey
 	expiration time.Duration
+	issuer     string
+	audience   string        // Empty leaves aud unset and unchecked
+	leeway     time.Duration // Clock skew tolerated on exp, nbf and iat
 }
 
 // TokenClaims represents JWT claims
 type TokenClaims struct {
-	UserID      string   `json:"user_id"`
-	Email       string   `json:"email"`
-	SessionID   string   `json:"session_id"`
-	Roles       []string `json:"roles"`
-	Permissions []string `json:"permissions"`
+	UserID       string   `json:"user_id"`
+	Email        string   `json:"email"`
+	SessionID    string   `json:"session_id"`
+	Roles        []string `json:"roles"`
+	Permissions  []string `json:"permissions"`
+	TokenVersion int64    `json:"token_version,omitempty"` // The user's token version at issue; a lower one means roles changed since
 	jwt.RegisteredClaims
 }
 
-// NewTokenManager creates a new token manager
-func NewTokenManager(privateKeyPath, publicKeyPath string, expiration time.Duration) (*TokenManager, error) {
+// NewTokenManager creates a new token manager. Generated tokens carry issuer
+// and audience as iss and aud, and ValidateToken rejects tokens that don't.
+func NewTokenManager(privateKeyPath, publicKeyPath string, expiration time.Duration, issuer, audience string) (*TokenManager, error) {
 	// Load private key
 	privateKeyData, err := os.ReadFile(privateKeyPath)
 	if err != nil {
@@ -56,29 +63,42 @@ func NewTokenManager(privateKeyPath, publicKeyPath string, expiration time.Durat
 		privateKey: privateKey,
 		publicKey:  publicKey,
 		expiration: expiration,
+		issuer:     issuer,
+		audience:   audience,
 	}, nil
 }
 
-// GenerateToken generates a new JWT token
-func (tm *TokenManager) GenerateToken(user *domain.User, sessionID string) (string, error) {
+// SetLeeway sets how much clock skew ValidateToken tolerates, so tokens
+// within leeway of exp or nbf still validate when hosts' clocks drift
+func (tm *TokenManager) SetLeeway(leeway time.Duration) {
+	tm.leeway = leeway
+}
+
+// GenerateToken generates a new JWT token carrying the user's current token
+// version and the given permissions, which callers resolve with denies applied
+func (tm *TokenManager) GenerateToken(user *domain.User, permissions []string, sessionID string, tokenVersion int64) (string, error) {
 	now := time.Now()
 	expiresAt := now.Add(tm.expiration)
 	
 	claims := TokenClaims{
-		UserID:      user.ID,
-		Email:       user.Email,
-		SessionID:   sessionID,
-		Roles:       user.GetRoleNames(),
-		Permissions: user.GetPermissions(),
+		UserID:       user.ID,
+		Email:        user.Email,
+		SessionID:    sessionID,
+		Roles:        user.GetRoleNames(),
+		Permissions:  permissions,
+		TokenVersion: tokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(now),
 			NotBefore: jwt.NewNumericDate(now),
-			Issuer:    "user-auth-service",
+			Issuer:    tm.issuer,
 			Subject:   user.ID,
 			ID:        uuid.New().String(), // JTI for revocation
 		},
 	}
+	if tm.audience != "" {
+		claims.Audience = jwt.ClaimStrings{tm.audience}
+	}
 	
 	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
 	tokenString, err := token.SignedString(tm.privateKey)
@@ -89,15 +109,34 @@ func (tm *TokenManager) GenerateToken(user *domain.User, sessionID string) (stri
 	return tokenString, nil
 }
 
+// GenerateRefreshToken generates an opaque refresh token. It carries no
+// claims; what it refreshes is looked up in the session store.
+func (tm *TokenManager) GenerateRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
 // ValidateToken validates a JWT token and returns the claims
 func (tm *TokenManager) ValidateToken(tokenString string) (*TokenClaims, error) {
+	opts := []jwt.ParserOption{jwt.WithIssuer(tm.issuer)}
+	if tm.audience != "" {
+		opts = append(opts, jwt.WithAudience(tm.audience))
+	}
+	if tm.leeway > 0 {
+		opts = append(opts, jwt.WithLeeway(tm.leeway))
+	}
+	
 	token, err := jwt.ParseWithClaims(tokenString, &TokenClaims{}, func(token *jwt.Token) (interface{}, error) {
 		// Verify signing method
 		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
 		return tm.publicKey, nil
-	})
+	}, opts...)
 	
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/haunted-saas/user-auth-service/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeTestKeys writes a fresh RSA key pair and returns the PEM paths
+func writeTestKeys(t *testing.T) (string, string) {
+	t.Helper()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "jwt-private.pem")
+	publicPath := filepath.Join(dir, "jwt-public.pem")
+
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes})
+	require.NoError(t, os.WriteFile(privatePath, privatePEM, 0600))
+	require.NoError(t, os.WriteFile(publicPath, publicPEM, 0644))
+
+	return privatePath, publicPath
+}
+
+func TestTokenManager_IssuerAndAudience(t *testing.T) {
+	privatePath, publicPath := writeTestKeys(t)
+	user := &domain.User{ID: "user-123", Email: "test@example.com"}
+
+	issuing, err := NewTokenManager(privatePath, publicPath, time.Hour, "user-auth-service", "haunted-saas-api")
+	require.NoError(t, err)
+
+	token, err := issuing.GenerateToken(user, nil, "session-123", 0)
+	require.NoError(t, err)
+
+	t.Run("matching issuer and audience", func(t *testing.T) {
+		claims, err := issuing.ValidateToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, "user-auth-service", claims.Issuer)
+		assert.Equal(t, jwt.ClaimStrings{"haunted-saas-api"}, claims.Audience)
+	})
+
+	t.Run("mismatched audience", func(t *testing.T) {
+		validator, err := NewTokenManager(privatePath, publicPath, time.Hour, "user-auth-service", "other-api")
+		require.NoError(t, err)
+
+		_, err = validator.ValidateToken(token)
+		assert.ErrorIs(t, err, jwt.ErrTokenInvalidAudience)
+	})
+
+	t.Run("mismatched issuer", func(t *testing.T) {
+		validator, err := NewTokenManager(privatePath, publicPath, time.Hour, "other-issuer", "haunted-saas-api")
+		require.NoError(t, err)
+
+		_, err = validator.ValidateToken(token)
+		assert.ErrorIs(t, err, jwt.ErrTokenInvalidIssuer)
+	})
+
+	t.Run("missing audience", func(t *testing.T) {
+		noAudience, err := NewTokenManager(privatePath, publicPath, time.Hour, "user-auth-service", "")
+		require.NoError(t, err)
+		tokenWithoutAud, err := noAudience.GenerateToken(user, nil, "session-123", 0)
+		require.NoError(t, err)
+
+		_, err = issuing.ValidateToken(tokenWithoutAud)
+		assert.ErrorIs(t, err, jwt.ErrTokenRequiredClaimMissing)
+	})
+}
+
+func TestTokenManager_Leeway(t *testing.T) {
+	privatePath, publicPath := writeTestKeys(t)
+	user := &domain.User{ID: "user-123", Email: "test@example.com"}
+
+	// A negative expiration issues tokens that expired two seconds ago,
+	// as a validator with a slightly fast clock would see them
+	issuing, err := NewTokenManager(privatePath, publicPath, -2*time.Second, "user-auth-service", "")
+	require.NoError(t, err)
+	token, err := issuing.GenerateToken(user, nil, "session-123", 0)
+	require.NoError(t, err)
+
+	t.Run("expired without leeway", func(t *testing.T) {
+		validator, err := NewTokenManager(privatePath, publicPath, time.Hour, "user-auth-service", "")
+		require.NoError(t, err)
+
+		_, err = validator.ValidateToken(token)
+		assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+	})
+
+	t.Run("within leeway", func(t *testing.T) {
+		validator, err := NewTokenManager(privatePath, publicPath, time.Hour, "user-auth-service", "")
+		require.NoError(t, err)
+		validator.SetLeeway(30 * time.Second)
+
+		claims, err := validator.ValidateToken(token)
+		require.NoError(t, err)
+		assert.Equal(t, "user-123", claims.UserID)
+	})
+
+	t.Run("beyond leeway", func(t *testing.T) {
+		validator, err := NewTokenManager(privatePath, publicPath, time.Hour, "user-auth-service", "")
+		require.NoError(t, err)
+		validator.SetLeeway(time.Second)
+
+		_, err = validator.ValidateToken(token)
+		assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+	})
+}
+
+func TestTokenManager_GenerateRefreshToken(t *testing.T) {
+	tm := &TokenManager{}
+
+	first, err := tm.GenerateRefreshToken()
+	require.NoError(t, err)
+	second, err := tm.GenerateRefreshToken()
+	require.NoError(t, err)
+
+	assert.Len(t, first, 43) // 32 random bytes, base64url without padding
+	assert.NotEqual(t, first, second)
+}
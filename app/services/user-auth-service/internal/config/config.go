@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -11,17 +12,21 @@ import (
 
 // Config holds all configuration for the service
 type Config struct {
-	Server   ServerConfig
-	Database DatabaseConfig
-	Redis    RedisConfig
-	JWT      JWTConfig
-	Security SecurityConfig
+	Server        ServerConfig
+	Database      DatabaseConfig
+	Redis         RedisConfig
+	JWT           JWTConfig
+	Security      SecurityConfig
+	Notifications NotificationsConfig
 }
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
-	GRPCPort int
-	Host     string
+	GRPCPort        int
+	Host            string
+	DefaultDeadline time.Duration // Applied to requests that arrive without a deadline (0 disables)
+	MetricsPort     int           // Serves Prometheus metrics on /metrics (0 disables)
+	DrainTimeout    time.Duration // Bounds the wait for in-flight RPCs on shutdown (0 waits indefinitely)
 }
 
 // DatabaseConfig holds database configuration
@@ -34,10 +39,15 @@ type DatabaseConfig struct {
 
 // RedisConfig holds Redis configuration
 type RedisConfig struct {
-	Host     string
-	Port     int
-	Password string
-	DB       int
+	Host         string
+	Port         int
+	Password     string
+	DB           int
+	PoolSize     int // Maximum socket connections; 0 uses go-redis' default of 10 per CPU
+	DialTimeout  time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	MaxRetries   int // Retries before giving up on a command; -1 disables retries
 }
 
 // JWTConfig holds JWT configuration
@@ -45,16 +55,41 @@ type JWTConfig struct {
 	PrivateKeyPath string
 	PublicKeyPath  string
 	Expiration     time.Duration
+	Issuer         string        // Set as iss and required on validation
+	Audience       string        // Set as aud and required on validation (empty disables)
+	Leeway         time.Duration // Clock skew tolerated when validating exp and nbf
 }
 
 // SecurityConfig holds security-related configuration
 type SecurityConfig struct {
-	BcryptCost           int
-	MaxLoginAttempts     int
-	LockoutDuration      time.Duration
-	PermissionCacheTTL   time.Duration
-	SessionExpiration    time.Duration
-	PasswordResetTTL     time.Duration
+	BcryptCost                  int
+	MaxLoginAttempts            int
+	LockoutDuration             time.Duration
+	PermissionCacheTTL          time.Duration
+	PermissionCacheJitter       float64 // Fraction of PermissionCacheTTL to randomize by (0 disables)
+	SessionExpiration           time.Duration
+	PasswordResetTTL            time.Duration
+	WarmPermissionCache         bool          // Pre-populate the permission cache on login
+	KnownDeviceWindow           time.Duration // Logins from an IP seen within this window aren't flagged as a new device
+	DefaultRole                 string        // Role assigned to every newly registered user
+	NotifyOnLockout             bool          // Alert the account owner through notifications-service when it's locked
+	NotifyOnDeactivation        bool          // Notify deactivated users and drop their real-time connections
+	RoleChangePolicy            string        // RoleChangeLogout or RoleChangeReissue
+	NoRefreshTokenClients       []string      // Client types whose logins get no refresh token, e.g. browsers using the session cookie
+	KeepSessionOnPasswordChange bool          // ChangePassword ends every other session but keeps the caller's
+	RefreshTokenTTL             time.Duration // How long a refresh token can be redeemed for
+}
+
+// What happens to a user's sessions when their roles change
+const (
+	RoleChangeLogout  = "logout"  // End every session so the user signs in again with the new roles
+	RoleChangeReissue = "reissue" // Keep sessions; ValidateToken reissues stale tokens with the new roles
+)
+
+// NotificationsConfig holds the optional notifications-service connection
+type NotificationsConfig struct {
+	Address    string
+	TimeoutSec int
 }
 
 // Load loads configuration from environment variables
@@ -63,8 +98,11 @@ func Load() (*Config, error) {
 
 	config := &Config{
 		Server: ServerConfig{
-			GRPCPort: getEnvAsInt("GRPC_PORT", 50051),
-			Host:     getEnv("HOST", "0.0.0.0"),
+			GRPCPort:        getEnvAsInt("GRPC_PORT", 50051),
+			Host:            getEnv("HOST", "0.0.0.0"),
+			DefaultDeadline: time.Duration(getEnvAsInt("DEFAULT_REQUEST_DEADLINE_SECONDS", 30)) * time.Second,
+			DrainTimeout:    time.Duration(getEnvAsInt("GRPC_DRAIN_TIMEOUT_SECONDS", 15)) * time.Second,
+			MetricsPort:     getEnvAsInt("METRICS_PORT", 9090),
 		},
 		Database: DatabaseConfig{
 			URL:             getEnv("DATABASE_URL", ""),
@@ -73,23 +111,45 @@ func Load() (*Config, error) {
 			ConnMaxLifetime: time.Duration(getEnvAsInt("DB_CONN_MAX_LIFETIME_MINUTES", 5)) * time.Minute,
 		},
 		Redis: RedisConfig{
-			Host:     getEnv("REDIS_HOST", "localhost"),
-			Port:     getEnvAsInt("REDIS_PORT", 6379),
-			Password: getEnv("REDIS_PASSWORD", ""),
-			DB:       getEnvAsInt("REDIS_DB", 0),
+			Host:         getEnv("REDIS_HOST", "localhost"),
+			Port:         getEnvAsInt("REDIS_PORT", 6379),
+			Password:     getEnv("REDIS_PASSWORD", ""),
+			DB:           getEnvAsInt("REDIS_DB", 0),
+			PoolSize:     getEnvAsInt("REDIS_POOL_SIZE", 0),
+			DialTimeout:  time.Duration(getEnvAsInt("REDIS_DIAL_TIMEOUT_MS", 5000)) * time.Millisecond,
+			ReadTimeout:  time.Duration(getEnvAsInt("REDIS_READ_TIMEOUT_MS", 3000)) * time.Millisecond,
+			WriteTimeout: time.Duration(getEnvAsInt("REDIS_WRITE_TIMEOUT_MS", 3000)) * time.Millisecond,
+			MaxRetries:   getEnvAsInt("REDIS_MAX_RETRIES", 3),
 		},
 		JWT: JWTConfig{
 			PrivateKeyPath: getEnv("JWT_PRIVATE_KEY_PATH", "/app/keys/jwt-private.pem"),
 			PublicKeyPath:  getEnv("JWT_PUBLIC_KEY_PATH", "/app/keys/jwt-public.pem"),
 			Expiration:     time.Duration(getEnvAsInt("JWT_EXPIRATION_HOURS", 24)) * time.Hour,
+			Issuer:         getEnv("JWT_ISSUER", "user-auth-service"),
+			Audience:       getEnv("JWT_AUDIENCE", ""),
+			Leeway:         time.Duration(getEnvAsInt("JWT_LEEWAY_SECONDS", 0)) * time.Second,
 		},
 		Security: SecurityConfig{
-			BcryptCost:           getEnvAsInt("BCRYPT_COST", 12),
-			MaxLoginAttempts:     getEnvAsInt("MAX_LOGIN_ATTEMPTS", 5),
-			LockoutDuration:      time.Duration(getEnvAsInt("LOCKOUT_DURATION_MINUTES", 30)) * time.Minute,
-			PermissionCacheTTL:   time.Duration(getEnvAsInt("PERMISSION_CACHE_TTL_MINUTES", 5)) * time.Minute,
-			SessionExpiration:    time.Duration(getEnvAsInt("SESSION_EXPIRATION_HOURS", 24)) * time.Hour,
-			PasswordResetTTL:     time.Duration(getEnvAsInt("PASSWORD_RESET_TTL_MINUTES", 60)) * time.Minute,
+			BcryptCost:                  getEnvAsInt("BCRYPT_COST", 12),
+			MaxLoginAttempts:            getEnvAsInt("MAX_LOGIN_ATTEMPTS", 5),
+			LockoutDuration:             time.Duration(getEnvAsInt("LOCKOUT_DURATION_MINUTES", 30)) * time.Minute,
+			PermissionCacheTTL:          time.Duration(getEnvAsInt("PERMISSION_CACHE_TTL_MINUTES", 5)) * time.Minute,
+			PermissionCacheJitter:       getEnvAsFloat("PERMISSION_CACHE_TTL_JITTER", 0.1),
+			SessionExpiration:           time.Duration(getEnvAsInt("SESSION_EXPIRATION_HOURS", 24)) * time.Hour,
+			PasswordResetTTL:            time.Duration(getEnvAsInt("PASSWORD_RESET_TTL_MINUTES", 60)) * time.Minute,
+			WarmPermissionCache:         getEnvAsBool("WARM_PERMISSION_CACHE_ON_LOGIN", false),
+			KnownDeviceWindow:           time.Duration(getEnvAsInt("KNOWN_DEVICE_WINDOW_DAYS", 30)) * 24 * time.Hour,
+			DefaultRole:                 getEnv("DEFAULT_ROLE", "member"),
+			NotifyOnLockout:             getEnvAsBool("NOTIFY_ON_LOCKOUT", false),
+			NotifyOnDeactivation:        getEnvAsBool("NOTIFY_ON_DEACTIVATION", false),
+			RoleChangePolicy:            getEnv("ROLE_CHANGE_POLICY", RoleChangeLogout),
+			NoRefreshTokenClients:       getEnvAsList("NO_REFRESH_TOKEN_CLIENT_TYPES"),
+			KeepSessionOnPasswordChange: getEnvAsBool("KEEP_SESSION_ON_PASSWORD_CHANGE", true),
+			RefreshTokenTTL:             time.Duration(getEnvAsInt("REFRESH_TOKEN_TTL_DAYS", 30)) * 24 * time.Hour,
+		},
+		Notifications: NotificationsConfig{
+			Address:    getEnv("NOTIFICATIONS_SERVICE", ""),
+			TimeoutSec: getEnvAsInt("NOTIFICATIONS_TIMEOUT_SECONDS", 2),
 		},
 	}
 
@@ -98,6 +158,62 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("DATABASE_URL is required")
 	}
 
+	if config.Security.PermissionCacheJitter < 0 || config.Security.PermissionCacheJitter >= 1 {
+		return nil, fmt.Errorf("PERMISSION_CACHE_TTL_JITTER must be in the range [0, 1)")
+	}
+
+	if config.Security.KnownDeviceWindow <= 0 {
+		return nil, fmt.Errorf("KNOWN_DEVICE_WINDOW_DAYS must be at least 1")
+	}
+
+	if config.Security.RefreshTokenTTL <= 0 {
+		return nil, fmt.Errorf("REFRESH_TOKEN_TTL_DAYS must be at least 1")
+	}
+
+	if config.Security.RoleChangePolicy != RoleChangeLogout && config.Security.RoleChangePolicy != RoleChangeReissue {
+		return nil, fmt.Errorf("ROLE_CHANGE_POLICY must be %q or %q", RoleChangeLogout, RoleChangeReissue)
+	}
+
+	if config.JWT.Leeway < 0 {
+		return nil, fmt.Errorf("JWT_LEEWAY_SECONDS cannot be negative")
+	}
+
+	if config.Server.DefaultDeadline < 0 {
+		return nil, fmt.Errorf("DEFAULT_REQUEST_DEADLINE_SECONDS cannot be negative")
+	}
+
+	if config.Server.DrainTimeout < 0 {
+		return nil, fmt.Errorf("GRPC_DRAIN_TIMEOUT_SECONDS cannot be negative")
+	}
+
+	if config.Server.MetricsPort < 0 {
+		return nil, fmt.Errorf("METRICS_PORT cannot be negative")
+	}
+
+	if config.Redis.PoolSize < 0 {
+		return nil, fmt.Errorf("REDIS_POOL_SIZE cannot be negative")
+	}
+
+	if config.Redis.DialTimeout <= 0 || config.Redis.ReadTimeout <= 0 || config.Redis.WriteTimeout <= 0 {
+		return nil, fmt.Errorf("REDIS_DIAL_TIMEOUT_MS, REDIS_READ_TIMEOUT_MS and REDIS_WRITE_TIMEOUT_MS must be positive")
+	}
+
+	if config.Redis.MaxRetries < -1 {
+		return nil, fmt.Errorf("REDIS_MAX_RETRIES must be -1 (disabled) or greater")
+	}
+
+	if config.Security.NotifyOnLockout && config.Notifications.Address == "" {
+		return nil, fmt.Errorf("NOTIFICATIONS_SERVICE is required when NOTIFY_ON_LOCKOUT is enabled")
+	}
+
+	if config.Security.NotifyOnDeactivation && config.Notifications.Address == "" {
+		return nil, fmt.Errorf("NOTIFICATIONS_SERVICE is required when NOTIFY_ON_DEACTIVATION is enabled")
+	}
+
+	if config.Notifications.Address != "" && config.Notifications.TimeoutSec < 1 {
+		return nil, fmt.Errorf("NOTIFICATIONS_TIMEOUT_SECONDS must be at least 1")
+	}
+
 	return config, nil
 }
 
@@ -119,3 +235,38 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return value
 }
+
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := os.Getenv(key)
+	if valueStr == "" {
+		return defaultValue
+	}
+	value, err := strconv.ParseBool(valueStr)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// getEnvAsList splits a comma-separated variable, dropping empty entries
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
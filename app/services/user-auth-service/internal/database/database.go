@@ -69,5 +69,6 @@ func AutoMigrate(db *gorm.DB) error {
 		&domain.User{},
 		&domain.Role{},
 		&domain.Permission{},
+		&domain.AuditEvent{},
 	)
 }
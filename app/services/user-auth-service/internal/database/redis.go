@@ -0,0 +1,23 @@
+package database
+
+import (
+	"fmt"
+
+	"github.com/haunted-saas/user-auth-service/internal/config"
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisOptions builds the Redis client options, including pool size,
+// timeouts and retries, from configuration
+func RedisOptions(cfg *config.Config) *redis.Options {
+	return &redis.Options{
+		Addr:         fmt.Sprintf("%s:%d", cfg.Redis.Host, cfg.Redis.Port),
+		Password:     cfg.Redis.Password,
+		DB:           cfg.Redis.DB,
+		PoolSize:     cfg.Redis.PoolSize,
+		DialTimeout:  cfg.Redis.DialTimeout,
+		ReadTimeout:  cfg.Redis.ReadTimeout,
+		WriteTimeout: cfg.Redis.WriteTimeout,
+		MaxRetries:   cfg.Redis.MaxRetries,
+	}
+}
@@ -0,0 +1,36 @@
+package database
+
+import (
+	"testing"
+	"time"
+
+	"github.com/haunted-saas/user-auth-service/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisOptions(t *testing.T) {
+	cfg := &config.Config{
+		Redis: config.RedisConfig{
+			Host:         "redis.internal",
+			Port:         6380,
+			Password:     "secret",
+			DB:           2,
+			PoolSize:     50,
+			DialTimeout:  2 * time.Second,
+			ReadTimeout:  500 * time.Millisecond,
+			WriteTimeout: 750 * time.Millisecond,
+			MaxRetries:   5,
+		},
+	}
+
+	opts := RedisOptions(cfg)
+
+	assert.Equal(t, "redis.internal:6380", opts.Addr)
+	assert.Equal(t, "secret", opts.Password)
+	assert.Equal(t, 2, opts.DB)
+	assert.Equal(t, 50, opts.PoolSize)
+	assert.Equal(t, 2*time.Second, opts.DialTimeout)
+	assert.Equal(t, 500*time.Millisecond, opts.ReadTimeout)
+	assert.Equal(t, 750*time.Millisecond, opts.WriteTimeout)
+	assert.Equal(t, 5, opts.MaxRetries)
+}
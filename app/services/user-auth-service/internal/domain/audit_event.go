@@ -0,0 +1,25 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// AuditEvent is a persisted audit log entry
+type AuditEvent struct {
+	ID            int64           `gorm:"primaryKey;autoIncrement" json:"id"`
+	EventType     string          `gorm:"not null" json:"event_type"`
+	UserID        string          `json:"user_id,omitempty"`
+	Email         string          `json:"email,omitempty"`
+	IPAddress     string          `json:"ip_address,omitempty"`
+	Success       bool            `gorm:"not null;default:false" json:"success"`
+	ErrorReason   string          `json:"error_reason,omitempty"`
+	CorrelationID string          `json:"correlation_id,omitempty"`
+	Metadata      json.RawMessage `gorm:"type:jsonb" json:"metadata,omitempty"`
+	CreatedAt     time.Time       `gorm:"not null;index" json:"created_at"`
+}
+
+// TableName specifies the table name for GORM
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
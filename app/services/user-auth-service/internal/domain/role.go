@@ -13,6 +13,10 @@ type Role struct {
 	CreatedAt   time.Time    `gorm:"not null;default:now()" json:"created_at"`
 	UpdatedAt   time.Time    `gorm:"not null;default:now()" json:"updated_at"`
 	Permissions []Permission `gorm:"many2many:role_permissions;" json:"permissions,omitempty"`
+
+	// DeniedPermissions are withheld from anyone holding the role, even when
+	// another role grants them
+	DeniedPermissions []Permission `gorm:"many2many:role_denied_permissions;" json:"denied_permissions,omitempty"`
 }
 
 // TableName specifies the table name for GORM
@@ -15,3 +15,13 @@ type Session struct {
 	ExpiresAt    time.Time `json:"expires_at"`
 	LastActivity time.Time `json:"last_activity"`
 }
+
+// RefreshToken is the record behind an opaque refresh token. Only a hash of
+// the token itself is stored.
+type RefreshToken struct {
+	UserID     string    `json:"user_id"`
+	SessionID  string    `json:"session_id"` // Session the token renews
+	ClientType string    `json:"client_type"`
+	CreatedAt  time.Time `json:"created_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+}
@@ -2,6 +2,8 @@ package domain
 
 import (
 	"time"
+
+	"github.com/haunted-saas/pkg/permission"
 )
 
 // User represents a user account in the system
@@ -13,6 +15,8 @@ type User struct {
 	IsActive     bool       `gorm:"default:true" json:"is_active"`
 	IsLocked     bool       `gorm:"default:false" json:"is_locked"`
 	LockedUntil  *time.Time `gorm:"index" json:"locked_until,omitempty"`
+	LastLoginAt  *time.Time `json:"last_login_at,omitempty"`
+	LastLoginIP  string     `gorm:"type:varchar(45)" json:"last_login_ip,omitempty"`
 	CreatedAt    time.Time  `gorm:"not null;default:now()" json:"created_at"`
 	UpdatedAt    time.Time  `gorm:"not null;default:now()" json:"updated_at"`
 	Roles        []Role     `gorm:"many2many:user_roles;" json:"roles,omitempty"`
@@ -34,12 +38,19 @@ func (u *User) IsAccountLocked() bool {
 	return time.Now().Before(*u.LockedUntil)
 }
 
-// GetPermissions aggregates all permissions from all roles
+// GetPermissions aggregates all permissions granted by the user's roles,
+// leaving out any that a role denies. Deny wins, so a wildcard deny removes
+// every grant it covers. A wildcard grant only partly covered by a deny is
+// kept as-is; RBACService expands it against the known permissions.
 func (u *User) GetPermissions() []string {
+	denied := u.GetDeniedPermissions()
+	
 	permissionSet := make(map[string]bool)
 	for _, role := range u.Roles {
 		for _, perm := range role.Permissions {
-			permissionSet[perm.Name] = true
+			if !permission.AnyCovers(denied, perm.Name) {
+				permissionSet[perm.Name] = true
+			}
 		}
 	}
 	
@@ -50,6 +61,22 @@ func (u *User) GetPermissions() []string {
 	return permissions
 }
 
+// GetDeniedPermissions aggregates all permissions denied by the user's roles
+func (u *User) GetDeniedPermissions() []string {
+	deniedSet := make(map[string]bool)
+	for _, role := range u.Roles {
+		for _, perm := range role.DeniedPermissions {
+			deniedSet[perm.Name] = true
+		}
+	}
+	
+	denied := make([]string, 0, len(deniedSet))
+	for perm := range deniedSet {
+		denied = append(denied, perm)
+	}
+	return denied
+}
+
 // GetRoleNames returns a list of role names
 func (u *User) GetRoleNames() []string {
 	roleNames := make([]string, len(u.Roles))
@@ -0,0 +1,29 @@
+package handler
+
+import (
+	"github.com/haunted-saas/user-auth-service/internal/errors"
+	pb "github.com/haunted-saas/user-auth-service/proto/userauth/v1"
+)
+
+// ExportAuditLog streams audit events for a time range as JSON lines
+func (h *AuthHandler) ExportAuditLog(req *pb.ExportAuditLogRequest, stream pb.UserAuthService_ExportAuditLogServer) error {
+	if req.StartTime == nil || req.EndTime == nil {
+		return errors.MapToGRPCError(errors.New(errors.ErrCodeInvalidInput, "start_time and end_time are required"))
+	}
+
+	err := h.auditService.ExportAuditLog(
+		stream.Context(),
+		req.RequestingUserId,
+		req.StartTime.AsTime(),
+		req.EndTime.AsTime(),
+		req.Format,
+		func(data []byte) error {
+			return stream.Send(&pb.ExportAuditLogResponse{Data: data})
+		},
+	)
+	if _, ok := err.(*errors.ServiceError); ok {
+		return errors.MapToGRPCError(err)
+	}
+	// Send failures already carry a gRPC status
+	return err
+}
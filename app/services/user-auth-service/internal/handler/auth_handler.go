@@ -3,6 +3,7 @@ package handler
 import (
 	"context"
 
+	"github.com/haunted-saas/pkg/pagination"
 	"github.com/haunted-saas/user-auth-service/internal/errors"
 	"github.com/haunted-saas/user-auth-service/internal/service"
 	pb "github.com/haunted-saas/user-auth-service/proto/userauth/v1"
@@ -12,15 +13,17 @@ import (
 // AuthHandler handles authentication gRPC requests
 type AuthHandler struct {
 	pb.UnimplementedUserAuthServiceServer
-	authService *service.AuthService
-	rbacService *service.RBACService
+	authService  *service.AuthService
+	rbacService  *service.RBACService
+	auditService *service.AuditService
 }
 
 // NewAuthHandler creates a new auth handler
-func NewAuthHandler(authService *service.AuthService, rbacService *service.RBACService) *AuthHandler {
+func NewAuthHandler(authService *service.AuthService, rbacService *service.RBACService, auditService *service.AuditService) *AuthHandler {
 	return &AuthHandler{
-		authService: authService,
-		rbacService: rbacService,
+		authService:  authService,
+		rbacService:  rbacService,
+		auditService: auditService,
 	}
 }
 
@@ -38,16 +41,23 @@ func (h *AuthHandler) Register(ctx context.Context, req *pb.RegisterRequest) (*p
 
 // Login handles user login
 func (h *AuthHandler) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginResponse, error) {
-	user, token, expiresAt, err := h.authService.Login(ctx, req.Email, req.Password, req.IpAddress)
+	result, err := h.authService.Login(ctx, req.Email, req.Password, req.IpAddress, req.ClientType)
 	if err != nil {
 		return nil, errors.MapToGRPCError(err)
 	}
 	
-	return &pb.LoginResponse{
-		AccessToken: token,
-		User:        domainUserToProto(user),
-		ExpiresAt:   timestamppb.New(expiresAt),
-	}, nil
+	resp := &pb.LoginResponse{
+		AccessToken:  result.Token,
+		RefreshToken: result.RefreshToken,
+		User:         domainUserToProto(result.User),
+		ExpiresAt:    timestamppb.New(result.ExpiresAt),
+		NewDevice:    result.NewDevice,
+		LastLoginIp:  result.PreviousLoginIP,
+	}
+	if result.PreviousLoginAt != nil {
+		resp.LastLoginAt = timestamppb.New(*result.PreviousLoginAt)
+	}
+	return resp, nil
 }
 
 // Logout handles user logout
@@ -69,39 +79,59 @@ func (h *AuthHandler) Logout(ctx context.Context, req *pb.LogoutRequest) (*pb.Lo
 
 // ValidateToken validates a JWT token
 func (h *AuthHandler) ValidateToken(ctx context.Context, req *pb.ValidateTokenRequest) (*pb.ValidateTokenResponse, error) {
-	user, err := h.authService.ValidateToken(ctx, req.Token)
+	user, reissued, err := h.authService.ReissueToken(ctx, req.Token)
 	if err != nil {
 		return nil, errors.MapToGRPCError(err)
 	}
 	
-	permissions, err := h.rbacService.GetUserPermissions(ctx, user.ID)
-	if err != nil {
-		return nil, errors.MapToGRPCError(err)
+	// Permissions cost an extra lookup, so only resolve them when asked
+	var permissions []string
+	if req.IncludePermissions {
+		permissions, err = h.rbacService.GetEffectivePermissions(ctx, user.ID)
+		if err != nil {
+			return nil, errors.MapToGRPCError(err)
+		}
 	}
 	
 	return &pb.ValidateTokenResponse{
-		Valid:       true,
-		UserId:      user.ID,
-		TeamId:      "", // Add team_id when teams are implemented
-		Roles:       user.GetRoleNames(),
-		Permissions: permissions,
-		User:        domainUserToProto(user),
+		Valid:         true,
+		UserId:        user.ID,
+		TeamId:        "", // Add team_id when teams are implemented
+		Roles:         user.GetRoleNames(),
+		Permissions:   permissions,
+		User:          domainUserToProto(user),
+		ReissuedToken: reissued,
 	}, nil
 }
 
-// RefreshSession refreshes a session
+// RefreshSession refreshes a session. It predates RefreshToken and
+// redeems the refresh token the same way.
 func (h *AuthHandler) RefreshSession(ctx context.Context, req *pb.RefreshSessionRequest) (*pb.RefreshSessionResponse, error) {
-	// Validate the refresh token
-	_, err := h.authService.ValidateToken(ctx, req.RefreshToken)
+	result, err := h.authService.RefreshToken(ctx, req.RefreshToken)
 	if err != nil {
 		return nil, errors.MapToGRPCError(err)
 	}
 	
-	// For now, we'll just validate and return the same token
-	// In a full implementation, you'd generate a new access token
 	return &pb.RefreshSessionResponse{
-		AccessToken: req.RefreshToken,
-		ExpiresAt:   timestamppb.Now(),
+		AccessToken:  result.Token,
+		ExpiresAt:    timestamppb.New(result.ExpiresAt),
+		RefreshToken: result.RefreshToken,
+	}, nil
+}
+
+// RefreshToken redeems a refresh token for a new access token and a new
+// refresh token
+func (h *AuthHandler) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.RefreshTokenResponse, error) {
+	result, err := h.authService.RefreshToken(ctx, req.RefreshToken)
+	if err != nil {
+		return nil, errors.MapToGRPCError(err)
+	}
+	
+	return &pb.RefreshTokenResponse{
+		AccessToken:  result.Token,
+		RefreshToken: result.RefreshToken,
+		ExpiresAt:    timestamppb.New(result.ExpiresAt),
+		User:         domainUserToProto(result.User),
 	}, nil
 }
 
@@ -130,3 +160,81 @@ func (h *AuthHandler) ResetPassword(ctx context.Context, req *pb.ResetPasswordRe
 	
 	return &pb.ResetPasswordResponse{Success: true}, nil
 }
+
+// ChangePassword changes the signed-in user's password
+func (h *AuthHandler) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.ChangePasswordResponse, error) {
+	if err := h.authService.ChangePassword(ctx, req.SessionToken, req.CurrentPassword, req.NewPassword); err != nil {
+		return nil, errors.MapToGRPCError(err)
+	}
+	
+	return &pb.ChangePasswordResponse{Success: true}, nil
+}
+
+// DeactivateUser deactivates a user's account and ends their sessions
+func (h *AuthHandler) DeactivateUser(ctx context.Context, req *pb.DeactivateUserRequest) (*pb.DeactivateUserResponse, error) {
+	if err := h.authService.DeactivateUser(ctx, req.RequestingUserId, req.UserId, req.Reason); err != nil {
+		return nil, errors.MapToGRPCError(err)
+	}
+	
+	return &pb.DeactivateUserResponse{Success: true}, nil
+}
+
+// GetUser gets a user with their roles and permissions
+func (h *AuthHandler) GetUser(ctx context.Context, req *pb.GetUserRequest) (*pb.User, error) {
+	user, err := h.authService.GetUser(ctx, req.UserId)
+	if err != nil {
+		return nil, errors.MapToGRPCError(err)
+	}
+	
+	return domainUserToProto(user), nil
+}
+
+// GetUsers gets several users in one call. Unknown IDs are omitted.
+func (h *AuthHandler) GetUsers(ctx context.Context, req *pb.GetUsersRequest) (*pb.GetUsersResponse, error) {
+	users, err := h.authService.GetUsers(ctx, req.UserIds)
+	if err != nil {
+		return nil, errors.MapToGRPCError(err)
+	}
+	
+	pbUsers := make([]*pb.User, len(users))
+	for i := range users {
+		pbUsers[i] = domainUserToProto(&users[i])
+	}
+	
+	return &pb.GetUsersResponse{
+		Users: pbUsers,
+	}, nil
+}
+
+// ListUsers lists users a page at a time for admins
+func (h *AuthHandler) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	users, page, err := h.authService.ListUsers(ctx, req.RequestingUserId, pagination.Request{
+		Limit:  int(req.Limit),
+		Offset: int(req.Offset),
+		Cursor: req.Cursor,
+	})
+	if err != nil {
+		return nil, errors.MapToGRPCError(err)
+	}
+	
+	pbUsers := make([]*pb.User, len(users))
+	for i := range users {
+		pbUsers[i] = domainUserToProto(&users[i])
+	}
+	
+	return &pb.ListUsersResponse{
+		Users:      pbUsers,
+		TotalCount: page.Total,
+		NextCursor: page.NextCursor,
+	}, nil
+}
+
+// UpdateUser updates a user's profile and returns the refreshed user
+func (h *AuthHandler) UpdateUser(ctx context.Context, req *pb.UpdateUserRequest) (*pb.User, error) {
+	user, err := h.authService.UpdateProfile(ctx, req.UserId, req.Name)
+	if err != nil {
+		return nil, errors.MapToGRPCError(err)
+	}
+	
+	return domainUserToProto(user), nil
+}
@@ -0,0 +1,270 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/haunted-saas/user-auth-service/internal/auth"
+	"github.com/haunted-saas/user-auth-service/internal/config"
+	"github.com/haunted-saas/user-auth-service/internal/domain"
+	"github.com/haunted-saas/user-auth-service/internal/logging"
+	"github.com/haunted-saas/user-auth-service/internal/repository"
+	"github.com/haunted-saas/user-auth-service/internal/service"
+	pb "github.com/haunted-saas/user-auth-service/proto/userauth/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubUserRepository returns a single known user
+type stubUserRepository struct {
+	repository.UserRepository
+	user  *domain.User
+	roles map[string]domain.Role // Roles AssignRole can grant
+}
+
+func (r *stubUserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	if id != r.user.ID {
+		return nil, errors.New("user not found")
+	}
+	return r.user, nil
+}
+
+// AssignRole gives the user a role from roles
+func (r *stubUserRepository) AssignRole(ctx context.Context, userID, roleID string) error {
+	role, ok := r.roles[roleID]
+	if !ok {
+		return errors.New("role not found")
+	}
+	r.user.Roles = append(r.user.Roles, role)
+	return nil
+}
+
+// stubSessionRepository treats every session as active and no token as
+// revoked, and keeps the user's token version in memory
+type stubSessionRepository struct {
+	repository.SessionRepository
+	tokenVersion    int64
+	sessionsDeleted int
+}
+
+func (r *stubSessionRepository) Get(ctx context.Context, sessionID string) (*domain.Session, error) {
+	return &domain.Session{SessionID: sessionID}, nil
+}
+
+func (r *stubSessionRepository) IsRevoked(ctx context.Context, tokenJTI string) (bool, error) {
+	return false, nil
+}
+
+func (r *stubSessionRepository) ExtendExpiration(ctx context.Context, sessionID string, duration time.Duration) error {
+	return nil
+}
+
+func (r *stubSessionRepository) GetTokenVersion(ctx context.Context, userID string) (int64, error) {
+	return r.tokenVersion, nil
+}
+
+func (r *stubSessionRepository) IncrementTokenVersion(ctx context.Context, userID string) (int64, error) {
+	r.tokenVersion++
+	return r.tokenVersion, nil
+}
+
+func (r *stubSessionRepository) DeleteAllForUser(ctx context.Context, userID string) error {
+	r.sessionsDeleted++
+	return nil
+}
+
+// stubRoleRepository returns roles from a fixed set
+type stubRoleRepository struct {
+	repository.RoleRepository
+	roles map[string]domain.Role
+}
+
+func (r *stubRoleRepository) FindByID(ctx context.Context, id string) (*domain.Role, error) {
+	role, ok := r.roles[id]
+	if !ok {
+		return nil, errors.New("role not found")
+	}
+	return &role, nil
+}
+
+// stubPermissionRepository lists a fixed set of known permissions
+type stubPermissionRepository struct {
+	repository.PermissionRepository
+	permissions []domain.Permission
+}
+
+func (r *stubPermissionRepository) List(ctx context.Context) ([]domain.Permission, error) {
+	return r.permissions, nil
+}
+
+// stubPermissionCacheRepository always misses and counts lookups
+type stubPermissionCacheRepository struct {
+	repository.PermissionCacheRepository
+	lookups int
+}
+
+func (r *stubPermissionCacheRepository) GetUserPermissions(ctx context.Context, userID string) ([]string, error) {
+	r.lookups++
+	return nil, errors.New("cache miss")
+}
+
+func (r *stubPermissionCacheRepository) SetUserPermissions(ctx context.Context, userID string, permissions []string, ttl time.Duration) error {
+	return nil
+}
+
+func (r *stubPermissionCacheRepository) InvalidateUserPermissions(ctx context.Context, userID string) error {
+	return nil
+}
+
+// newTestTokenManager creates a token manager backed by a freshly generated key pair
+func newTestTokenManager(t *testing.T) *auth.TokenManager {
+	t.Helper()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "jwt-private.pem")
+	publicPath := filepath.Join(dir, "jwt-public.pem")
+
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes})
+	require.NoError(t, os.WriteFile(privatePath, privatePEM, 0600))
+	require.NoError(t, os.WriteFile(publicPath, publicPEM, 0644))
+
+	tokenManager, err := auth.NewTokenManager(privatePath, publicPath, time.Hour, "user-auth-service", "")
+	require.NoError(t, err)
+	return tokenManager
+}
+
+func TestAuthHandler_ValidateToken_IncludePermissions(t *testing.T) {
+	user := &domain.User{
+		ID:       "user-123",
+		Email:    "test@example.com",
+		IsActive: true,
+		Roles: []domain.Role{
+			{
+				Name: "support",
+				Permissions: []domain.Permission{
+					{Name: "users:*"},
+					{Name: "billing:read"},
+				},
+			},
+		},
+	}
+
+	logger, err := logging.NewLogger("error")
+	require.NoError(t, err)
+	cfg := &config.Config{}
+	tokenManager := newTestTokenManager(t)
+
+	userRepo := &stubUserRepository{user: user}
+	sessionRepo := &stubSessionRepository{}
+	cacheRepo := &stubPermissionCacheRepository{}
+	permRepo := &stubPermissionRepository{permissions: []domain.Permission{
+		{Name: "users:read"},
+		{Name: "users:write"},
+		{Name: "billing:read"},
+		{Name: "billing:write"},
+	}}
+
+	authService := service.NewAuthService(userRepo, nil, sessionRepo, nil, nil, cacheRepo, nil, tokenManager, nil, cfg, logger)
+	rbacService := service.NewRBACService(userRepo, nil, permRepo, cacheRepo, sessionRepo, cfg, logger)
+	handler := NewAuthHandler(authService, rbacService, nil)
+
+	token, err := tokenManager.GenerateToken(user, user.GetPermissions(), "session-123", 0)
+	require.NoError(t, err)
+
+	t.Run("permissions included when requested", func(t *testing.T) {
+		resp, err := handler.ValidateToken(context.Background(), &pb.ValidateTokenRequest{
+			Token:              token,
+			IncludePermissions: true,
+		})
+		require.NoError(t, err)
+		assert.True(t, resp.Valid)
+		assert.Equal(t, "user-123", resp.UserId)
+		assert.ElementsMatch(t, []string{"users:*", "users:read", "users:write", "billing:read"}, resp.Permissions)
+	})
+
+	t.Run("permissions omitted by default", func(t *testing.T) {
+		cacheRepo.lookups = 0
+
+		resp, err := handler.ValidateToken(context.Background(), &pb.ValidateTokenRequest{
+			Token: token,
+		})
+		require.NoError(t, err)
+		assert.True(t, resp.Valid)
+		assert.Empty(t, resp.Permissions)
+		assert.Zero(t, cacheRepo.lookups)
+	})
+}
+
+func TestAuthHandler_ValidateToken_ReissuesAfterRoleChange(t *testing.T) {
+	member := domain.Role{ID: "role-member", Name: "member", Permissions: []domain.Permission{{Name: "users:read"}}}
+	billing := domain.Role{ID: "role-billing", Name: "billing", Permissions: []domain.Permission{{Name: "billing:write"}}}
+	user := &domain.User{
+		ID:       "user-123",
+		Email:    "test@example.com",
+		IsActive: true,
+		Roles:    []domain.Role{member},
+	}
+
+	logger, err := logging.NewLogger("error")
+	require.NoError(t, err)
+	cfg := &config.Config{Security: config.SecurityConfig{RoleChangePolicy: config.RoleChangeReissue}}
+	tokenManager := newTestTokenManager(t)
+
+	roles := map[string]domain.Role{member.ID: member, billing.ID: billing}
+	userRepo := &stubUserRepository{user: user, roles: roles}
+	roleRepo := &stubRoleRepository{roles: roles}
+	sessionRepo := &stubSessionRepository{}
+	cacheRepo := &stubPermissionCacheRepository{}
+	permRepo := &stubPermissionRepository{}
+
+	authService := service.NewAuthService(userRepo, nil, sessionRepo, nil, nil, cacheRepo, nil, tokenManager, nil, cfg, logger)
+	rbacService := service.NewRBACService(userRepo, roleRepo, permRepo, cacheRepo, sessionRepo, cfg, logger)
+	handler := NewAuthHandler(authService, rbacService, nil)
+
+	token, err := tokenManager.GenerateToken(user, user.GetPermissions(), "session-123", 0)
+	require.NoError(t, err)
+
+	// A current token isn't reissued
+	resp, err := handler.ValidateToken(context.Background(), &pb.ValidateTokenRequest{Token: token})
+	require.NoError(t, err)
+	assert.Empty(t, resp.ReissuedToken)
+
+	require.NoError(t, rbacService.AssignRoleToUser(context.Background(), "user-123", "role-billing"))
+
+	// The session survives the role change and the token is swapped for one with the new role
+	resp, err = handler.ValidateToken(context.Background(), &pb.ValidateTokenRequest{
+		Token:              token,
+		IncludePermissions: true,
+	})
+	require.NoError(t, err)
+	assert.Zero(t, sessionRepo.sessionsDeleted)
+	assert.ElementsMatch(t, []string{"member", "billing"}, resp.Roles)
+	assert.ElementsMatch(t, []string{"users:read", "billing:write"}, resp.Permissions)
+	require.NotEmpty(t, resp.ReissuedToken)
+
+	claims, err := tokenManager.ValidateToken(resp.ReissuedToken)
+	require.NoError(t, err)
+	assert.Equal(t, "session-123", claims.SessionID)
+	assert.Equal(t, int64(1), claims.TokenVersion)
+	assert.ElementsMatch(t, []string{"member", "billing"}, claims.Roles)
+	assert.ElementsMatch(t, []string{"users:read", "billing:write"}, claims.Permissions)
+
+	// The reissued token is current
+	resp, err = handler.ValidateToken(context.Background(), &pb.ValidateTokenRequest{Token: resp.ReissuedToken})
+	require.NoError(t, err)
+	assert.Empty(t, resp.ReissuedToken)
+}
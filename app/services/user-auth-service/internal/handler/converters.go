@@ -56,6 +56,14 @@ func domainRoleToProto(role *domain.Role) *pb.Role {
 		}
 	}
 	
+	// Convert denied permissions
+	if len(role.DeniedPermissions) > 0 {
+		pbRole.DeniedPermissions = make([]*pb.Permission, len(role.DeniedPermissions))
+		for i, perm := range role.DeniedPermissions {
+			pbRole.DeniedPermissions[i] = domainPermissionToProto(&perm)
+		}
+	}
+	
 	return pbRole
 }
 
@@ -9,7 +9,7 @@ import (
 
 // CreateRole handles role creation
 func (h *AuthHandler) CreateRole(ctx context.Context, req *pb.CreateRoleRequest) (*pb.Role, error) {
-	role, err := h.rbacService.CreateRole(ctx, req.Name, req.Description, req.PermissionIds)
+	role, err := h.rbacService.CreateRole(ctx, req.ActorUserId, req.Name, req.Description, req.PermissionIds, req.DeniedPermissionIds)
 	if err != nil {
 		return nil, errors.MapToGRPCError(err)
 	}
@@ -19,7 +19,7 @@ func (h *AuthHandler) CreateRole(ctx context.Context, req *pb.CreateRoleRequest)
 
 // UpdateRole handles role updates
 func (h *AuthHandler) UpdateRole(ctx context.Context, req *pb.UpdateRoleRequest) (*pb.Role, error) {
-	role, err := h.rbacService.UpdateRole(ctx, req.RoleId, req.Name, req.Description, req.PermissionIds)
+	role, err := h.rbacService.UpdateRole(ctx, req.ActorUserId, req.RoleId, req.Name, req.Description, req.PermissionIds, req.DeniedPermissionIds)
 	if err != nil {
 		return nil, errors.MapToGRPCError(err)
 	}
@@ -29,7 +29,7 @@ func (h *AuthHandler) UpdateRole(ctx context.Context, req *pb.UpdateRoleRequest)
 
 // DeleteRole handles role deletion
 func (h *AuthHandler) DeleteRole(ctx context.Context, req *pb.DeleteRoleRequest) (*pb.DeleteRoleResponse, error) {
-	if err := h.rbacService.DeleteRole(ctx, req.RoleId); err != nil {
+	if err := h.rbacService.DeleteRole(ctx, req.ActorUserId, req.RoleId); err != nil {
 		return nil, errors.MapToGRPCError(err)
 	}
 	
@@ -72,6 +72,18 @@ func (h *AuthHandler) CheckPermission(ctx context.Context, req *pb.CheckPermissi
 	}, nil
 }
 
+// CheckPermissions checks several permissions for a user in one call
+func (h *AuthHandler) CheckPermissions(ctx context.Context, req *pb.CheckPermissionsRequest) (*pb.CheckPermissionsResponse, error) {
+	results, err := h.rbacService.CheckPermissions(ctx, req.UserId, req.Permissions)
+	if err != nil {
+		return nil, errors.MapToGRPCError(err)
+	}
+	
+	return &pb.CheckPermissionsResponse{
+		Results: results,
+	}, nil
+}
+
 // GetUserPermissions gets all permissions for a user
 func (h *AuthHandler) GetUserPermissions(ctx context.Context, req *pb.GetUserPermissionsRequest) (*pb.GetUserPermissionsResponse, error) {
 	permissions, err := h.rbacService.GetUserPermissions(ctx, req.UserId)
@@ -83,3 +95,37 @@ func (h *AuthHandler) GetUserPermissions(ctx context.Context, req *pb.GetUserPer
 		Permissions: permissions,
 	}, nil
 }
+
+// GetUserRoles gets the roles assigned to a user
+func (h *AuthHandler) GetUserRoles(ctx context.Context, req *pb.GetUserRolesRequest) (*pb.GetUserRolesResponse, error) {
+	roles, err := h.rbacService.GetUserRoles(ctx, req.RequestingUserId, req.UserId)
+	if err != nil {
+		return nil, errors.MapToGRPCError(err)
+	}
+	
+	pbRoles := make([]*pb.Role, len(roles))
+	for i := range roles {
+		pbRoles[i] = domainRoleToProto(&roles[i])
+	}
+	
+	return &pb.GetUserRolesResponse{
+		Roles: pbRoles,
+	}, nil
+}
+
+// ListPermissions lists the permission catalog for building roles
+func (h *AuthHandler) ListPermissions(ctx context.Context, req *pb.ListPermissionsRequest) (*pb.ListPermissionsResponse, error) {
+	permissions, err := h.rbacService.ListPermissions(ctx, req.RequestingUserId)
+	if err != nil {
+		return nil, errors.MapToGRPCError(err)
+	}
+	
+	pbPermissions := make([]*pb.Permission, len(permissions))
+	for i := range permissions {
+		pbPermissions[i] = domainPermissionToProto(&permissions[i])
+	}
+	
+	return &pb.ListPermissionsResponse{
+		Permissions: pbPermissions,
+	}, nil
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"context"
+	"testing"
+
+	"github.com/haunted-saas/user-auth-service/internal/config"
+	"github.com/haunted-saas/user-auth-service/internal/domain"
+	"github.com/haunted-saas/user-auth-service/internal/logging"
+	"github.com/haunted-saas/user-auth-service/internal/repository"
+	"github.com/haunted-saas/user-auth-service/internal/service"
+	pb "github.com/haunted-saas/user-auth-service/proto/userauth/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+// stubUserDirectory holds several users keyed by ID and reports unknown IDs
+// the way gorm does
+type stubUserDirectory struct {
+	repository.UserRepository
+	users map[string]*domain.User
+}
+
+func (r *stubUserDirectory) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	user, ok := r.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return user, nil
+}
+
+func (r *stubUserDirectory) GetUserRoles(ctx context.Context, userID string) ([]domain.Role, error) {
+	user, ok := r.users[userID]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return user.Roles, nil
+}
+
+func newUserDirectory(users ...*domain.User) *stubUserDirectory {
+	dir := &stubUserDirectory{users: make(map[string]*domain.User, len(users))}
+	for _, user := range users {
+		dir.users[user.ID] = user
+	}
+	return dir
+}
+
+func TestAuthHandler_GetUserRoles(t *testing.T) {
+	editor := domain.Role{
+		ID:          "role-editor",
+		Name:        "editor",
+		Description: "Edits content",
+		Permissions: []domain.Permission{{ID: "perm-1", Name: "content:write"}},
+	}
+	userRepo := newUserDirectory(
+		&domain.User{ID: "user-123", Roles: []domain.Role{editor}},
+		&domain.User{ID: "user-456", Roles: []domain.Role{{Name: "member"}}},
+		&domain.User{ID: "admin-1", Roles: []domain.Role{{Name: "admin"}}},
+	)
+
+	logger, err := logging.NewLogger("error")
+	require.NoError(t, err)
+	rbacService := service.NewRBACService(userRepo, nil, nil, nil, nil, &config.Config{}, logger)
+	handler := NewAuthHandler(nil, rbacService, nil)
+
+	tests := []struct {
+		name      string
+		requester string
+		userID    string
+		wantCode  codes.Code
+		wantRoles []string
+	}{
+		{name: "self access", requester: "user-123", userID: "user-123", wantCode: codes.OK, wantRoles: []string{"editor"}},
+		{name: "admin access", requester: "admin-1", userID: "user-123", wantCode: codes.OK, wantRoles: []string{"editor"}},
+		{name: "other non-admin denied", requester: "user-456", userID: "user-123", wantCode: codes.PermissionDenied},
+		{name: "unknown requester denied", requester: "ghost", userID: "user-123", wantCode: codes.PermissionDenied},
+		{name: "missing user_id", requester: "user-123", userID: "", wantCode: codes.InvalidArgument},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := handler.GetUserRoles(context.Background(), &pb.GetUserRolesRequest{
+				RequestingUserId: tt.requester,
+				UserId:           tt.userID,
+			})
+			require.Equal(t, tt.wantCode, status.Code(err))
+			if tt.wantCode != codes.OK {
+				return
+			}
+
+			names := make([]string, len(resp.Roles))
+			for i, role := range resp.Roles {
+				names[i] = role.Name
+			}
+			assert.Equal(t, tt.wantRoles, names)
+			assert.Equal(t, "Edits content", resp.Roles[0].Description)
+			require.Len(t, resp.Roles[0].Permissions, 1)
+			assert.Equal(t, "content:write", resp.Roles[0].Permissions[0].Name)
+		})
+	}
+}
+
+func TestAuthHandler_ListPermissions(t *testing.T) {
+	catalog := []domain.Permission{
+		{ID: "perm-2", Name: "users:write", Resource: "users", Action: "write", Description: "Modify users"},
+		{ID: "perm-3", Name: "billing:read", Resource: "billing", Action: "read", Description: "View billing"},
+		{ID: "perm-1", Name: "users:read", Resource: "users", Action: "read", Description: "View users"},
+	}
+
+	logger, err := logging.NewLogger("error")
+	require.NoError(t, err)
+	cfg := &config.Config{}
+
+	newHandler := func(user *domain.User) *AuthHandler {
+		userRepo := &stubUserRepository{user: user}
+		permRepo := &stubPermissionRepository{permissions: catalog}
+		rbacService := service.NewRBACService(userRepo, nil, permRepo, nil, nil, cfg, logger)
+		return NewAuthHandler(nil, rbacService, nil)
+	}
+
+	t.Run("admin receives the permission catalog", func(t *testing.T) {
+		handler := newHandler(&domain.User{ID: "admin-1", Roles: []domain.Role{{Name: "admin"}}})
+
+		resp, err := handler.ListPermissions(context.Background(), &pb.ListPermissionsRequest{
+			RequestingUserId: "admin-1",
+		})
+		require.NoError(t, err)
+
+		require.Len(t, resp.Permissions, 3)
+		names := make([]string, len(resp.Permissions))
+		for i, perm := range resp.Permissions {
+			names[i] = perm.Name
+		}
+		assert.Equal(t, []string{"billing:read", "users:read", "users:write"}, names)
+
+		assert.Equal(t, "perm-1", resp.Permissions[1].Id)
+		assert.Equal(t, "users", resp.Permissions[1].Resource)
+		assert.Equal(t, "read", resp.Permissions[1].Action)
+		assert.Equal(t, "View users", resp.Permissions[1].Description)
+	})
+
+	t.Run("non-admin denied", func(t *testing.T) {
+		handler := newHandler(&domain.User{ID: "user-123", Roles: []domain.Role{{Name: "member"}}})
+
+		_, err := handler.ListPermissions(context.Background(), &pb.ListPermissionsRequest{
+			RequestingUserId: "user-123",
+		})
+		assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	})
+}
+
+func TestAuthHandler_CheckPermissions(t *testing.T) {
+	userRepo := newUserDirectory(&domain.User{ID: "user-123", Roles: []domain.Role{{
+		Name: "billing-manager",
+		Permissions: []domain.Permission{
+			{Name: "users:read"},
+			{Name: "billing:*"},
+		},
+	}}})
+
+	logger, err := logging.NewLogger("error")
+	require.NoError(t, err)
+	rbacService := service.NewRBACService(userRepo, nil, &stubPermissionRepository{}, &stubPermissionCacheRepository{}, nil, &config.Config{}, logger)
+	handler := NewAuthHandler(nil, rbacService, nil)
+
+	tests := []struct {
+		name        string
+		userID      string
+		permissions []string
+		wantCode    codes.Code
+		wantResults map[string]bool
+	}{
+		{
+			name:        "several permissions in one call",
+			userID:      "user-123",
+			permissions: []string{"users:read", "users:write", "billing:refund"},
+			wantCode:    codes.OK,
+			wantResults: map[string]bool{"users:read": true, "users:write": false, "billing:refund": true},
+		},
+		{name: "missing user_id", userID: "", permissions: []string{"users:read"}, wantCode: codes.InvalidArgument},
+		{name: "no permissions", userID: "user-123", wantCode: codes.InvalidArgument},
+		{name: "unknown user", userID: "ghost", permissions: []string{"users:read"}, wantCode: codes.NotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := handler.CheckPermissions(context.Background(), &pb.CheckPermissionsRequest{
+				UserId:      tt.userID,
+				Permissions: tt.permissions,
+			})
+			require.Equal(t, tt.wantCode, status.Code(err))
+			if tt.wantCode != codes.OK {
+				return
+			}
+			assert.Equal(t, tt.wantResults, resp.Results)
+		})
+	}
+}
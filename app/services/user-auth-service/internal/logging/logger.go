@@ -11,6 +11,18 @@ import (
 // Logger wraps zap logger
 type Logger struct {
 	*zap.Logger
+	auditSink AuditSink
+}
+
+// AuditSink persists audit events in addition to logging them. ctx is the
+// request's context; sinks must not let its cancellation drop the event.
+type AuditSink interface {
+	RecordAuditEvent(ctx context.Context, event *AuditEvent)
+}
+
+// SetAuditSink sends every audit event to sink as well as the log
+func (l *Logger) SetAuditSink(sink AuditSink) {
+	l.auditSink = sink
 }
 
 // NewLogger creates a new logger
@@ -67,7 +79,7 @@ type AuditEvent struct {
 }
 
 // LogAuditEvent logs an audit event
-func (l *Logger) LogAuditEvent(event *AuditEvent) {
+func (l *Logger) LogAuditEvent(ctx context.Context, event *AuditEvent) {
 	fields := []zap.Field{
 		zap.String("event_type", event.EventType),
 		zap.String("user_id", event.UserID),
@@ -92,6 +104,10 @@ func (l *Logger) LogAuditEvent(event *AuditEvent) {
 	} else {
 		l.Warn("audit_event", fields...)
 	}
+	
+	if l.auditSink != nil {
+		l.auditSink.RecordAuditEvent(ctx, event)
+	}
 }
 
 // GetLogLevel returns the current log level from environment
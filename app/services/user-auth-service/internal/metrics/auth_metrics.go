@@ -0,0 +1,107 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Login failure reasons used as the "reason" label
+const (
+	LoginFailureInvalidPassword = "invalid_password"
+	LoginFailureLocked          = "locked"
+	LoginFailureNotFound        = "not_found"
+	LoginFailureDeactivated     = "deactivated"
+)
+
+// AuthMetrics counts authentication outcomes. A nil *AuthMetrics is valid
+// and records nothing.
+type AuthMetrics struct {
+	registrations  prometheus.Counter
+	loginSuccesses prometheus.Counter
+	loginFailures  *prometheus.CounterVec
+	passwordResets *prometheus.CounterVec
+	lockouts       prometheus.Counter
+}
+
+// NewAuthMetrics creates the auth counters and registers them with reg
+func NewAuthMetrics(reg prometheus.Registerer) *AuthMetrics {
+	m := &AuthMetrics{
+		registrations: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "user_auth",
+			Name:      "registrations_total",
+			Help:      "Users registered.",
+		}),
+		loginSuccesses: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "user_auth",
+			Name:      "login_successes_total",
+			Help:      "Successful logins.",
+		}),
+		loginFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "user_auth",
+			Name:      "login_failures_total",
+			Help:      "Failed logins by reason.",
+		}, []string{"reason"}),
+		passwordResets: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "user_auth",
+			Name:      "password_resets_total",
+			Help:      "Password resets by stage (requested, completed).",
+		}, []string{"stage"}),
+		lockouts: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: "user_auth",
+			Name:      "lockouts_total",
+			Help:      "Accounts locked after too many failed logins.",
+		}),
+	}
+
+	// Start each reason and stage at zero so rates can be graphed before the first event
+	for _, reason := range []string{LoginFailureInvalidPassword, LoginFailureLocked, LoginFailureNotFound, LoginFailureDeactivated} {
+		m.loginFailures.WithLabelValues(reason)
+	}
+	for _, stage := range []string{"requested", "completed"} {
+		m.passwordResets.WithLabelValues(stage)
+	}
+
+	reg.MustRegister(m.registrations, m.loginSuccesses, m.loginFailures, m.passwordResets, m.lockouts)
+	return m
+}
+
+// RegistrationSucceeded counts a new user
+func (m *AuthMetrics) RegistrationSucceeded() {
+	if m != nil {
+		m.registrations.Inc()
+	}
+}
+
+// LoginSucceeded counts a successful login
+func (m *AuthMetrics) LoginSucceeded() {
+	if m != nil {
+		m.loginSuccesses.Inc()
+	}
+}
+
+// LoginFailed counts a failed login with one of the LoginFailure reasons
+func (m *AuthMetrics) LoginFailed(reason string) {
+	if m != nil {
+		m.loginFailures.WithLabelValues(reason).Inc()
+	}
+}
+
+// PasswordResetRequested counts a reset token issued
+func (m *AuthMetrics) PasswordResetRequested() {
+	if m != nil {
+		m.passwordResets.WithLabelValues("requested").Inc()
+	}
+}
+
+// PasswordResetCompleted counts a password changed with a reset token
+func (m *AuthMetrics) PasswordResetCompleted() {
+	if m != nil {
+		m.passwordResets.WithLabelValues("completed").Inc()
+	}
+}
+
+// AccountLocked counts an account locked by the failed-login limit
+func (m *AuthMetrics) AccountLocked() {
+	if m != nil {
+		m.lockouts.Inc()
+	}
+}
@@ -0,0 +1,75 @@
+package notifications
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	notificationsv1 "github.com/haunted-saas/notifications-service/proto/notifications/v1"
+	"github.com/haunted-saas/pkg/grpcclient"
+)
+
+// Client sends security events to users and manages their real-time
+// connections through notifications-service
+type Client struct {
+	conn   *grpcclient.Conn
+	client notificationsv1.NotificationsServiceClient
+}
+
+// NewClient connects to notifications-service
+func NewClient(address string, timeout time.Duration) (*Client, error) {
+	conn, err := grpcclient.Dial("notifications-service", address, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		conn:   conn,
+		client: notificationsv1.NewNotificationsServiceClient(conn),
+	}, nil
+}
+
+// NotifyUser sends an event to the user. Security events are sent as
+// priority so they are neither rate limited nor dropped from the offline
+// queue while the user is disconnected.
+func (c *Client) NotifyUser(ctx context.Context, userID, eventType string, payload interface{}) error {
+	payloadJSON, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal notification payload: %w", err)
+	}
+
+	ctx, cancel := c.conn.Bound(ctx)
+	defer cancel()
+
+	_, err = c.client.SendToUser(ctx, &notificationsv1.SendToUserRequest{
+		UserId:      userID,
+		EventType:   eventType,
+		PayloadJson: string(payloadJSON),
+		Priority:    true,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to send %s to user %s: %w", eventType, userID, err)
+	}
+	return nil
+}
+
+// DisconnectUser closes all of the user's real-time connections
+func (c *Client) DisconnectUser(ctx context.Context, userID, reason string) error {
+	ctx, cancel := c.conn.Bound(ctx)
+	defer cancel()
+
+	_, err := c.client.DisconnectUser(ctx, &notificationsv1.DisconnectUserRequest{
+		UserId: userID,
+		Reason: reason,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to disconnect user %s: %w", userID, err)
+	}
+	return nil
+}
+
+// Close closes the connection to notifications-service
+func (c *Client) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,45 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/haunted-saas/user-auth-service/internal/domain"
+	"gorm.io/gorm"
+)
+
+// AuditRepository defines the interface for audit event storage
+type AuditRepository interface {
+	Create(ctx context.Context, event *domain.AuditEvent) error
+	FindInRange(ctx context.Context, from, to time.Time, afterID int64, limit int) ([]domain.AuditEvent, error)
+}
+
+// auditRepository implements AuditRepository
+type auditRepository struct {
+	db *gorm.DB
+}
+
+// NewAuditRepository creates a new audit repository
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+// Create persists an audit event
+func (r *auditRepository) Create(ctx context.Context, event *domain.AuditEvent) error {
+	return r.db.WithContext(ctx).Create(event).Error
+}
+
+// FindInRange returns up to limit events created in [from, to) with an ID
+// greater than afterID, in ID order. Callers page with the last ID seen so
+// large ranges are never loaded at once.
+func (r *auditRepository) FindInRange(ctx context.Context, from, to time.Time, afterID int64, limit int) ([]domain.AuditEvent, error) {
+	var events []domain.AuditEvent
+	err := r.db.WithContext(ctx).
+		Where("created_at >= ? AND created_at < ?", from, to).
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&events).Error
+
+	return events, err
+}
@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// LoginHistoryRepository defines the interface for tracking the IPs a user signs in from
+type LoginHistoryRepository interface {
+	RecordLoginIP(ctx context.Context, userID, ipAddress string, at time.Time, retention time.Duration) error
+	LastSeenIP(ctx context.Context, userID, ipAddress string) (time.Time, bool, error)
+}
+
+// loginHistoryRepository implements LoginHistoryRepository
+type loginHistoryRepository struct {
+	client *redis.Client
+}
+
+// NewLoginHistoryRepository creates a new login history repository
+func NewLoginHistoryRepository(client *redis.Client) LoginHistoryRepository {
+	return &loginHistoryRepository{client: client}
+}
+
+// RecordLoginIP records a successful login from an IP, scored by time.
+// Entries older than retention are pruned so the set stays bounded.
+func (r *loginHistoryRepository) RecordLoginIP(ctx context.Context, userID, ipAddress string, at time.Time, retention time.Duration) error {
+	key := fmt.Sprintf("login:ips:%s", userID)
+	cutoff := at.Add(-retention).Unix()
+
+	pipe := r.client.TxPipeline()
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(at.Unix()), Member: ipAddress})
+	pipe.ZRemRangeByScore(ctx, key, "-inf", "("+strconv.FormatInt(cutoff, 10))
+	pipe.Expire(ctx, key, retention)
+	_, err := pipe.Exec(ctx)
+	return err
+}
+
+// LastSeenIP returns when the user last logged in from an IP
+func (r *loginHistoryRepository) LastSeenIP(ctx context.Context, userID, ipAddress string) (time.Time, bool, error) {
+	key := fmt.Sprintf("login:ips:%s", userID)
+	score, err := r.client.ZScore(ctx, key, ipAddress).Result()
+	if err == redis.Nil {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+
+	return time.Unix(int64(score), 0), true, nil
+}
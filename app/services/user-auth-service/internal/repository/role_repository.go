@@ -18,6 +18,7 @@ type RoleRepository interface {
 	AssignPermission(ctx context.Context, roleID, permissionID string) error
 	RevokePermission(ctx context.Context, roleID, permissionID string) error
 	SetPermissions(ctx context.Context, roleID string, permissionIDs []string) error
+	SetDeniedPermissions(ctx context.Context, roleID string, permissionIDs []string) error
 }
 
 // roleRepository implements RoleRepository
@@ -35,11 +36,12 @@ func (r *roleRepository) Create(ctx context.Context, role *domain.Role) error {
 	return r.db.WithContext(ctx).Create(role).Error
 }
 
-// FindByID finds a role by ID with permissions preloaded
+// FindByID finds a role by ID with granted and denied permissions preloaded
 func (r *roleRepository) FindByID(ctx context.Context, id string) (*domain.Role, error) {
 	var role domain.Role
 	err := r.db.WithContext(ctx).
 		Preload("Permissions").
+		Preload("DeniedPermissions").
 		Where("id = ?", id).
 		First(&role).Error
 	
@@ -49,11 +51,12 @@ func (r *roleRepository) FindByID(ctx context.Context, id string) (*domain.Role,
 	return &role, nil
 }
 
-// FindByName finds a role by name with permissions preloaded
+// FindByName finds a role by name with granted and denied permissions preloaded
 func (r *roleRepository) FindByName(ctx context.Context, name string) (*domain.Role, error) {
 	var role domain.Role
 	err := r.db.WithContext(ctx).
 		Preload("Permissions").
+		Preload("DeniedPermissions").
 		Where("name = ?", name).
 		First(&role).Error
 	
@@ -121,3 +124,23 @@ func (r *roleRepository) SetPermissions(ctx context.Context, roleID string, perm
 		return nil
 	})
 }
+
+// SetDeniedPermissions sets all denied permissions for a role (replaces existing)
+func (r *roleRepository) SetDeniedPermissions(ctx context.Context, roleID string, permissionIDs []string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM role_denied_permissions WHERE role_id = ?", roleID).Error; err != nil {
+			return err
+		}
+		
+		for _, permID := range permissionIDs {
+			if err := tx.Exec(
+				"INSERT INTO role_denied_permissions (role_id, permission_id) VALUES (?, ?)",
+				roleID, permID,
+			).Error; err != nil {
+				return err
+			}
+		}
+		
+		return nil
+	})
+}
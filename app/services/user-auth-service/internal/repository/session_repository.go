@@ -2,6 +2,8 @@ package repository
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"time"
@@ -16,9 +18,15 @@ type SessionRepository interface {
 	Get(ctx context.Context, sessionID string) (*domain.Session, error)
 	Delete(ctx context.Context, sessionID string) error
 	DeleteAllForUser(ctx context.Context, userID string) error
+	DeleteAllForUserExcept(ctx context.Context, userID, keepSessionID string) error
 	ExtendExpiration(ctx context.Context, sessionID string, duration time.Duration) error
 	IsRevoked(ctx context.Context, tokenJTI string) (bool, error)
 	RevokeToken(ctx context.Context, tokenJTI string, expiresAt time.Time) error
+	GetTokenVersion(ctx context.Context, userID string) (int64, error)
+	IncrementTokenVersion(ctx context.Context, userID string) (int64, error)
+	CreateRefreshToken(ctx context.Context, token string, refresh *domain.RefreshToken) error
+	ConsumeRefreshToken(ctx context.Context, token string) (*domain.RefreshToken, error)
+	DeleteRefreshToken(ctx context.Context, userID, sessionID string) error
 }
 
 // sessionRepository implements SessionRepository
@@ -70,6 +78,16 @@ func (r *sessionRepository) Delete(ctx context.Context, sessionID string) error
 
 // DeleteAllForUser deletes all sessions for a user
 func (r *sessionRepository) DeleteAllForUser(ctx context.Context, userID string) error {
+	return r.DeleteAllForUserExcept(ctx, userID, "")
+}
+
+// DeleteAllForUserExcept deletes all sessions for a user other than
+// keepSessionID (empty keeps none), along with their refresh tokens
+func (r *sessionRepository) DeleteAllForUserExcept(ctx context.Context, userID, keepSessionID string) error {
+	if err := r.deleteRefreshTokensExcept(ctx, userID, keepSessionID); err != nil {
+		return err
+	}
+	
 	// Scan for all session keys
 	pattern := "session:*"
 	iter := r.client.Scan(ctx, 0, pattern, 0).Iterator()
@@ -86,7 +104,7 @@ func (r *sessionRepository) DeleteAllForUser(ctx context.Context, userID string)
 			continue
 		}
 		
-		if session.UserID == userID {
+		if session.UserID == userID && session.SessionID != keepSessionID {
 			r.client.Del(ctx, key)
 		}
 	}
@@ -129,3 +147,121 @@ func (r *sessionRepository) RevokeToken(ctx context.Context, tokenJTI string, ex
 	}
 	return r.client.Set(ctx, key, "1", ttl).Err()
 }
+
+// GetTokenVersion returns the user's current token version (0 until it is
+// first incremented)
+func (r *sessionRepository) GetTokenVersion(ctx context.Context, userID string) (int64, error) {
+	key := fmt.Sprintf("token_version:%s", userID)
+	version, err := r.client.Get(ctx, key).Int64()
+	if err == redis.Nil {
+		return 0, nil
+	}
+	return version, err
+}
+
+// IncrementTokenVersion marks every token issued to the user so far as stale
+func (r *sessionRepository) IncrementTokenVersion(ctx context.Context, userID string) (int64, error) {
+	key := fmt.Sprintf("token_version:%s", userID)
+	return r.client.Incr(ctx, key).Result()
+}
+
+// refreshTokenKey is the Redis key of a refresh token, which is stored hashed
+func refreshTokenKey(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return fmt.Sprintf("refresh_token:%s", hex.EncodeToString(hash[:]))
+}
+
+// userRefreshTokensKey indexes a user's refresh token keys by session ID, so
+// ending a session can delete its refresh token
+func userRefreshTokensKey(userID string) string {
+	return fmt.Sprintf("refresh_tokens:%s", userID)
+}
+
+// CreateRefreshToken stores a refresh token until refresh.ExpiresAt
+func (r *sessionRepository) CreateRefreshToken(ctx context.Context, token string, refresh *domain.RefreshToken) error {
+	data, err := json.Marshal(refresh)
+	if err != nil {
+		return err
+	}
+	
+	key := refreshTokenKey(token)
+	indexKey := userRefreshTokensKey(refresh.UserID)
+	ttl := time.Until(refresh.ExpiresAt)
+	
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, key, data, ttl)
+	pipe.HSet(ctx, indexKey, refresh.SessionID, key)
+	// The index outlives each token it points to; stale entries are harmless
+	pipe.Expire(ctx, indexKey, ttl)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// ConsumeRefreshToken looks up and deletes a refresh token in one step, so a
+// token can be redeemed only once. It returns ErrNotFound for unknown,
+// expired or already redeemed tokens.
+func (r *sessionRepository) ConsumeRefreshToken(ctx context.Context, token string) (*domain.RefreshToken, error) {
+	key := refreshTokenKey(token)
+	data, err := r.client.GetDel(ctx, key).Result()
+	if err == redis.Nil {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	
+	var refresh domain.RefreshToken
+	if err := json.Unmarshal([]byte(data), &refresh); err != nil {
+		return nil, err
+	}
+	
+	// Drop the index entry unless a newer token already replaced it
+	indexKey := userRefreshTokensKey(refresh.UserID)
+	if current, err := r.client.HGet(ctx, indexKey, refresh.SessionID).Result(); err == nil && current == key {
+		r.client.HDel(ctx, indexKey, refresh.SessionID)
+	}
+	
+	return &refresh, nil
+}
+
+// DeleteRefreshToken deletes the refresh token of a session, if it has one
+func (r *sessionRepository) DeleteRefreshToken(ctx context.Context, userID, sessionID string) error {
+	indexKey := userRefreshTokensKey(userID)
+	key, err := r.client.HGet(ctx, indexKey, sessionID).Result()
+	if err == redis.Nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	
+	pipe := r.client.TxPipeline()
+	pipe.Del(ctx, key)
+	pipe.HDel(ctx, indexKey, sessionID)
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// deleteRefreshTokensExcept deletes every refresh token of a user other than
+// the one for keepSessionID (empty keeps none)
+func (r *sessionRepository) deleteRefreshTokensExcept(ctx context.Context, userID, keepSessionID string) error {
+	indexKey := userRefreshTokensKey(userID)
+	tokens, err := r.client.HGetAll(ctx, indexKey).Result()
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return nil
+	}
+	
+	pipe := r.client.TxPipeline()
+	for sessionID, key := range tokens {
+		if sessionID == keepSessionID {
+			continue
+		}
+		pipe.Del(ctx, key)
+		pipe.HDel(ctx, indexKey, sessionID)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
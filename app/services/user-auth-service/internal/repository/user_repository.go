@@ -2,7 +2,9 @@ package repository
 
 import (
 	"context"
+	"time"
 
+	"github.com/haunted-saas/pkg/pagination"
 	"github.com/haunted-saas/user-auth-service/internal/domain"
 	"gorm.io/gorm"
 )
@@ -10,9 +12,13 @@ import (
 // UserRepository defines the interface for user data access
 type UserRepository interface {
 	Create(ctx context.Context, user *domain.User) error
+	CreateWithRole(ctx context.Context, user *domain.User, roleID string) error
 	FindByEmail(ctx context.Context, email string) (*domain.User, error)
 	FindByID(ctx context.Context, id string) (*domain.User, error)
+	FindByIDs(ctx context.Context, ids []string) ([]domain.User, error)
+	List(ctx context.Context, page pagination.Page) ([]domain.User, int64, error)
 	Update(ctx context.Context, user *domain.User) error
+	UpdateLastLogin(ctx context.Context, userID string, at time.Time, ipAddress string) error
 	GetUserRoles(ctx context.Context, userID string) ([]domain.Role, error)
 	AssignRole(ctx context.Context, userID, roleID string) error
 	RevokeRole(ctx context.Context, userID, roleID string) error
@@ -33,11 +39,27 @@ func (r *userRepository) Create(ctx context.Context, user *domain.User) error {
 	return r.db.WithContext(ctx).Create(user).Error
 }
 
+// CreateWithRole creates a user and assigns them a role in one transaction,
+// so a failed assignment doesn't leave a user without any role behind
+func (r *userRepository) CreateWithRole(ctx context.Context, user *domain.User, roleID string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+
+		return tx.Exec(
+			"INSERT INTO user_roles (user_id, role_id) VALUES (?, ?) ON CONFLICT DO NOTHING",
+			user.ID, roleID,
+		).Error
+	})
+}
+
 // FindByEmail finds a user by email with roles and permissions preloaded
 func (r *userRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
 	var user domain.User
 	err := r.db.WithContext(ctx).
 		Preload("Roles.Permissions").
+		Preload("Roles.DeniedPermissions").
 		Where("email = ?", email).
 		First(&user).Error
 	
@@ -52,6 +74,7 @@ func (r *userRepository) FindByID(ctx context.Context, id string) (*domain.User,
 	var user domain.User
 	err := r.db.WithContext(ctx).
 		Preload("Roles.Permissions").
+		Preload("Roles.DeniedPermissions").
 		Where("id = ?", id).
 		First(&user).Error
 	
@@ -61,11 +84,54 @@ func (r *userRepository) FindByID(ctx context.Context, id string) (*domain.User,
 	return &user, nil
 }
 
+// FindByIDs finds the users with the given IDs in a single query, with roles
+// and permissions preloaded. Unknown IDs are skipped.
+func (r *userRepository) FindByIDs(ctx context.Context, ids []string) ([]domain.User, error) {
+	var users []domain.User
+	err := r.db.WithContext(ctx).
+		Preload("Roles.Permissions").
+		Preload("Roles.DeniedPermissions").
+		Where("id IN ?", ids).
+		Find(&users).Error
+	
+	return users, err
+}
+
+// List returns a page of users, oldest first, with roles and permissions
+// preloaded, and the total number of users
+func (r *userRepository) List(ctx context.Context, page pagination.Page) ([]domain.User, int64, error) {
+	var total int64
+	if err := r.db.WithContext(ctx).Model(&domain.User{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	
+	var users []domain.User
+	err := page.Apply(r.db.WithContext(ctx)).
+		Preload("Roles.Permissions").
+		Preload("Roles.DeniedPermissions").
+		Order("created_at, id").
+		Find(&users).Error
+	
+	return users, total, err
+}
+
 // Update updates a user
 func (r *userRepository) Update(ctx context.Context, user *domain.User) error {
 	return r.db.WithContext(ctx).Save(user).Error
 }
 
+// UpdateLastLogin records the time and IP of a successful login
+// without touching other columns or associations
+func (r *userRepository) UpdateLastLogin(ctx context.Context, userID string, at time.Time, ipAddress string) error {
+	return r.db.WithContext(ctx).
+		Model(&domain.User{}).
+		Where("id = ?", userID).
+		UpdateColumns(map[string]interface{}{
+			"last_login_at": at,
+			"last_login_ip": ipAddress,
+		}).Error
+}
+
 // GetUserRoles gets all roles for a user
 func (r *userRepository) GetUserRoles(ctx context.Context, userID string) ([]domain.Role, error) {
 	var roles []domain.Role
@@ -73,6 +139,7 @@ func (r *userRepository) GetUserRoles(ctx context.Context, userID string) ([]dom
 		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
 		Where("user_roles.user_id = ?", userID).
 		Preload("Permissions").
+		Preload("DeniedPermissions").
 		Find(&roles).Error
 	
 	return roles, err
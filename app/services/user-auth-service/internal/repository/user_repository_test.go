@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/haunted-saas/user-auth-service/internal/domain"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// recordingConn is a database/sql connection that records transaction
+// boundaries and fails any statement touching failOn
+type recordingConn struct {
+	failOn string
+	events []string
+}
+
+func (c *recordingConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("prepare not supported")
+}
+
+func (c *recordingConn) Close() error { return nil }
+
+func (c *recordingConn) Begin() (driver.Tx, error) {
+	c.events = append(c.events, "begin")
+	return c, nil
+}
+
+func (c *recordingConn) Commit() error {
+	c.events = append(c.events, "commit")
+	return nil
+}
+
+func (c *recordingConn) Rollback() error {
+	c.events = append(c.events, "rollback")
+	return nil
+}
+
+func (c *recordingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if c.failOn != "" && strings.Contains(query, c.failOn) {
+		return nil, errors.New("insert failed")
+	}
+	return driver.RowsAffected(1), nil
+}
+
+// QueryContext serves INSERT ... RETURNING with one generated id
+func (c *recordingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if c.failOn != "" && strings.Contains(query, c.failOn) {
+		return nil, errors.New("insert failed")
+	}
+	return &idRows{id: "user-1"}, nil
+}
+
+type idRows struct {
+	id   string
+	done bool
+}
+
+func (r *idRows) Columns() []string { return []string{"id"} }
+func (r *idRows) Close() error      { return nil }
+
+func (r *idRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	for i := range dest {
+		dest[i] = r.id
+	}
+	return nil
+}
+
+type recordingConnector struct{ conn *recordingConn }
+
+func (c recordingConnector) Connect(context.Context) (driver.Conn, error) { return c.conn, nil }
+func (c recordingConnector) Driver() driver.Driver                        { return nil }
+
+func newRecordingDB(t *testing.T, failOn string) (*gorm.DB, *recordingConn) {
+	conn := &recordingConn{failOn: failOn}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sql.OpenDB(recordingConnector{conn})}), &gorm.Config{
+		DisableAutomaticPing: true,
+		Logger:               logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	return db, conn
+}
+
+func TestUserRepository_CreateWithRole_RollsBackWhenRoleAssignmentFails(t *testing.T) {
+	db, conn := newRecordingDB(t, "user_roles")
+	repo := NewUserRepository(db)
+
+	err := repo.CreateWithRole(context.Background(), &domain.User{Email: "test@example.com"}, "role-1")
+	if err == nil {
+		t.Fatal("expected the role assignment error")
+	}
+
+	if got := strings.Join(conn.events, ","); got != "begin,rollback" {
+		t.Errorf("transaction events = %q, want begin,rollback", got)
+	}
+}
+
+func TestUserRepository_CreateWithRole_Commits(t *testing.T) {
+	db, conn := newRecordingDB(t, "")
+	repo := NewUserRepository(db)
+
+	user := &domain.User{Email: "test@example.com"}
+	if err := repo.CreateWithRole(context.Background(), user, "role-1"); err != nil {
+		t.Fatalf("CreateWithRole: %v", err)
+	}
+
+	if got := strings.Join(conn.events, ","); got != "begin,commit" {
+		t.Errorf("transaction events = %q, want begin,commit", got)
+	}
+}
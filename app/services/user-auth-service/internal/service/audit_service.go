@@ -0,0 +1,148 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/haunted-saas/user-auth-service/internal/domain"
+	"github.com/haunted-saas/user-auth-service/internal/errors"
+	"github.com/haunted-saas/user-auth-service/internal/logging"
+	"github.com/haunted-saas/user-auth-service/internal/repository"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Audit log export formats
+const (
+	AuditExportFormatJSONLines = "jsonl"
+)
+
+// defaultAuditExportPageSize bounds how many events are held in memory per streamed chunk
+const defaultAuditExportPageSize = 500
+
+// auditWriteTimeout bounds how long persisting one audit event can hold up the request
+const auditWriteTimeout = 5 * time.Second
+
+// AuditService persists audit events and exports them for SIEM ingestion
+type AuditService struct {
+	auditRepo repository.AuditRepository
+	userRepo  repository.UserRepository
+	logger    *logging.Logger
+	pageSize  int
+}
+
+// NewAuditService creates a new audit service
+func NewAuditService(
+	auditRepo repository.AuditRepository,
+	userRepo repository.UserRepository,
+	logger *logging.Logger,
+) *AuditService {
+	return &AuditService{
+		auditRepo: auditRepo,
+		userRepo:  userRepo,
+		logger:    logger,
+		pageSize:  defaultAuditExportPageSize,
+	}
+}
+
+// RecordAuditEvent implements logging.AuditSink. Failures are logged so a
+// storage outage never fails the request that produced the event. The write
+// keeps the request's values but not its cancellation, so an event is still
+// recorded when the client hangs up, and is bounded by auditWriteTimeout.
+func (s *AuditService) RecordAuditEvent(ctx context.Context, event *logging.AuditEvent) {
+	record := &domain.AuditEvent{
+		EventType:     event.EventType,
+		UserID:        event.UserID,
+		Email:         event.Email,
+		IPAddress:     event.IPAddress,
+		Success:       event.Success,
+		ErrorReason:   event.ErrorReason,
+		CorrelationID: event.CorrelationID,
+		CreatedAt:     time.Now().UTC(),
+	}
+	if len(event.Metadata) > 0 {
+		if metadata, err := json.Marshal(event.Metadata); err == nil {
+			record.Metadata = metadata
+		}
+	}
+
+	writeCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), auditWriteTimeout)
+	defer cancel()
+
+	if err := s.auditRepo.Create(writeCtx, record); err != nil {
+		s.logger.Error("failed to persist audit event",
+			zap.Error(err),
+			zap.String("event_type", event.EventType))
+	}
+}
+
+// ExportAuditLog streams audit events created in [from, to) to send, one
+// page of newline-delimited JSON per call. Only admins may export.
+func (s *AuditService) ExportAuditLog(ctx context.Context, requestingUserID string, from, to time.Time, format string, send func([]byte) error) error {
+	if format == "" {
+		format = AuditExportFormatJSONLines
+	}
+	if format != AuditExportFormatJSONLines {
+		return errors.New(errors.ErrCodeInvalidInput, "unsupported export format: "+format)
+	}
+	if from.IsZero() || to.IsZero() || !from.Before(to) {
+		return errors.New(errors.ErrCodeInvalidInput, "start_time must be before end_time")
+	}
+
+	requester, err := s.userRepo.FindByID(ctx, requestingUserID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return errors.New(errors.ErrCodePermissionDenied, "only admins may export the audit log")
+		}
+		return errors.Wrap(errors.ErrCodeInternal, "failed to find requesting user", err)
+	}
+	if !isAdmin(requester) {
+		return errors.New(errors.ErrCodePermissionDenied, "only admins may export the audit log")
+	}
+
+	exported := 0
+	var afterID int64
+	for {
+		events, err := s.auditRepo.FindInRange(ctx, from, to, afterID, s.pageSize)
+		if err != nil {
+			return errors.Wrap(errors.ErrCodeInternal, "failed to read audit events", err)
+		}
+		if len(events) == 0 {
+			break
+		}
+
+		var chunk []byte
+		for i := range events {
+			line, err := json.Marshal(&events[i])
+			if err != nil {
+				return errors.Wrap(errors.ErrCodeInternal, "failed to encode audit event", err)
+			}
+			chunk = append(chunk, line...)
+			chunk = append(chunk, '\n')
+		}
+		if err := send(chunk); err != nil {
+			return err
+		}
+
+		exported += len(events)
+		if len(events) < s.pageSize {
+			break
+		}
+		afterID = events[len(events)-1].ID
+	}
+
+	s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
+		EventType: "audit.exported",
+		UserID:    requestingUserID,
+		Success:   true,
+		Metadata: map[string]interface{}{
+			"start_time": from,
+			"end_time":   to,
+			"format":     format,
+			"exported":   exported,
+		},
+	})
+
+	return nil
+}
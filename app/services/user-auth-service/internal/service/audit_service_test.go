@@ -0,0 +1,171 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/haunted-saas/user-auth-service/internal/domain"
+	"github.com/haunted-saas/user-auth-service/internal/errors"
+	"github.com/haunted-saas/user-auth-service/internal/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAuditRepository keeps events in memory and pages like the SQL query
+type fakeAuditRepository struct {
+	events    []domain.AuditEvent
+	queries   int
+	createCtx context.Context // Context of the last Create
+}
+
+func (r *fakeAuditRepository) Create(ctx context.Context, event *domain.AuditEvent) error {
+	r.createCtx = ctx
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	event.ID = int64(len(r.events) + 1)
+	r.events = append(r.events, *event)
+	return nil
+}
+
+func (r *fakeAuditRepository) FindInRange(ctx context.Context, from, to time.Time, afterID int64, limit int) ([]domain.AuditEvent, error) {
+	r.queries++
+	var page []domain.AuditEvent
+	for _, event := range r.events {
+		if event.ID <= afterID || event.CreatedAt.Before(from) || !event.CreatedAt.Before(to) {
+			continue
+		}
+		page = append(page, event)
+		if len(page) == limit {
+			break
+		}
+	}
+	return page, nil
+}
+
+func newTestAuditService(repo *fakeAuditRepository, userRepo *MockUserRepository) *AuditService {
+	logger, _ := logging.NewLogger("error")
+	service := NewAuditService(repo, userRepo, logger)
+	service.pageSize = 2
+	return service
+}
+
+func TestAuditService_ExportAuditLog_DrainsRange(t *testing.T) {
+	base := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	repo := &fakeAuditRepository{}
+	for i := 0; i < 7; i++ {
+		repo.Create(context.Background(), &domain.AuditEvent{
+			EventType: "user.login.success",
+			UserID:    fmt.Sprintf("user-%d", i),
+			Success:   true,
+			CreatedAt: base.Add(time.Duration(i) * time.Hour),
+		})
+	}
+
+	userRepo := new(MockUserRepository)
+	userRepo.On("FindByID", mock.Anything, "admin-1").Return(&domain.User{
+		ID:    "admin-1",
+		Roles: []domain.Role{{Name: "admin"}},
+	}, nil)
+
+	service := newTestAuditService(repo, userRepo)
+
+	// Hours 1 through 5 are in range
+	var chunks [][]byte
+	err := service.ExportAuditLog(context.Background(), "admin-1", base.Add(time.Hour), base.Add(6*time.Hour), "", func(data []byte) error {
+		chunks = append(chunks, data)
+		return nil
+	})
+	require.NoError(t, err)
+
+	var userIDs []string
+	for _, chunk := range chunks {
+		scanner := bufio.NewScanner(bytes.NewReader(chunk))
+		for scanner.Scan() {
+			var event domain.AuditEvent
+			require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+			userIDs = append(userIDs, event.UserID)
+		}
+	}
+
+	assert.Equal(t, []string{"user-1", "user-2", "user-3", "user-4", "user-5"}, userIDs)
+	assert.Len(t, chunks, 3, "expected one chunk per page of 2")
+	assert.Equal(t, 3, repo.queries)
+}
+
+func TestAuditService_ExportAuditLog_RequiresAdmin(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	userRepo.On("FindByID", mock.Anything, "user-1").Return(&domain.User{
+		ID:    "user-1",
+		Roles: []domain.Role{{Name: "member"}},
+	}, nil)
+
+	service := newTestAuditService(&fakeAuditRepository{}, userRepo)
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	err := service.ExportAuditLog(context.Background(), "user-1", from, from.Add(time.Hour), "", func([]byte) error {
+		t.Fatal("expected nothing to be sent")
+		return nil
+	})
+
+	serviceErr, ok := err.(*errors.ServiceError)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrCodePermissionDenied, serviceErr.Code)
+}
+
+func TestAuditService_ExportAuditLog_RejectsInvalidInput(t *testing.T) {
+	service := newTestAuditService(&fakeAuditRepository{}, new(MockUserRepository))
+	from := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	send := func([]byte) error { return nil }
+
+	err := service.ExportAuditLog(context.Background(), "admin-1", from, from.Add(time.Hour), "csv", send)
+	serviceErr, ok := err.(*errors.ServiceError)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInvalidInput, serviceErr.Code)
+
+	err = service.ExportAuditLog(context.Background(), "admin-1", from, from, "", send)
+	serviceErr, ok = err.(*errors.ServiceError)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInvalidInput, serviceErr.Code)
+}
+
+func TestAuditService_RecordAuditEvent(t *testing.T) {
+	repo := &fakeAuditRepository{}
+	service := newTestAuditService(repo, new(MockUserRepository))
+
+	service.RecordAuditEvent(context.Background(), &logging.AuditEvent{
+		EventType: "role.created",
+		UserID:    "admin-1",
+		Success:   true,
+		Metadata:  map[string]interface{}{"role_name": "editor"},
+	})
+
+	require.Len(t, repo.events, 1)
+	assert.Equal(t, "role.created", repo.events[0].EventType)
+	assert.JSONEq(t, `{"role_name":"editor"}`, string(repo.events[0].Metadata))
+	assert.False(t, repo.events[0].CreatedAt.IsZero())
+}
+
+type auditTestKey struct{}
+
+func TestAuditService_RecordAuditEvent_SurvivesCanceledRequest(t *testing.T) {
+	repo := &fakeAuditRepository{}
+	service := newTestAuditService(repo, new(MockUserRepository))
+
+	// The client hung up before the event was written
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), auditTestKey{}, "req-1"))
+	cancel()
+
+	service.RecordAuditEvent(ctx, &logging.AuditEvent{EventType: "user.logout", UserID: "user-1", Success: true})
+
+	require.Len(t, repo.events, 1)
+	assert.Equal(t, "req-1", repo.createCtx.Value(auditTestKey{}), "expected request values to carry over")
+	_, hasDeadline := repo.createCtx.Deadline()
+	assert.True(t, hasDeadline, "expected the write to be bounded")
+}
@@ -5,20 +5,52 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/haunted-saas/pkg/pagination"
 	"github.com/haunted-saas/user-auth-service/internal/auth"
 	"github.com/haunted-saas/user-auth-service/internal/config"
 	"github.com/haunted-saas/user-auth-service/internal/domain"
 	"github.com/haunted-saas/user-auth-service/internal/errors"
 	"github.com/haunted-saas/user-auth-service/internal/logging"
+	"github.com/haunted-saas/user-auth-service/internal/metrics"
 	"github.com/haunted-saas/user-auth-service/internal/repository"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+// Security events sent to account owners
+const (
+	EventAccountLocked      = "security.account_locked"
+	EventAccountDeactivated = "security.account_deactivated"
+)
+
+// maxGetUsers bounds how many users GetUsers returns per call
+const maxGetUsers = 100
+
+// listUsersLimits bounds the page size of ListUsers
+var listUsersLimits = pagination.DefaultLimits
+
+// SecurityNotifier alerts a user about activity on their account
+type SecurityNotifier interface {
+	NotifyUser(ctx context.Context, userID, eventType string, payload interface{}) error
+}
+
+// PermissionResolver resolves a user's permissions with denies applied
+type PermissionResolver interface {
+	GetUserPermissions(ctx context.Context, userID string) ([]string, error)
+}
+
+// DeactivationNotifier tells a deactivated user what happened and closes
+// their real-time connections
+type DeactivationNotifier interface {
+	SecurityNotifier
+	DisconnectUser(ctx context.Context, userID, reason string) error
+}
+
 // AuthService handles authentication operations
 type AuthService struct {
 	userRepo        repository.UserRepository
@@ -26,7 +58,13 @@ type AuthService struct {
 	sessionRepo     repository.SessionRepository
 	rateLimiterRepo repository.RateLimiterRepository
 	resetRepo       repository.PasswordResetRepository
+	permCacheRepo   repository.PermissionCacheRepository
+	loginHistory    repository.LoginHistoryRepository
 	tokenManager    *auth.TokenManager
+	metrics         *metrics.AuthMetrics
+	notifier        SecurityNotifier     // Optional; nil disables lockout alerts
+	deactivation    DeactivationNotifier // Optional; nil skips the deactivation cascade
+	permissions     PermissionResolver   // Optional; nil embeds the roles' grants minus exact denies
 	config          *config.Config
 	logger          *logging.Logger
 }
@@ -38,7 +76,10 @@ func NewAuthService(
 	sessionRepo repository.SessionRepository,
 	rateLimiterRepo repository.RateLimiterRepository,
 	resetRepo repository.PasswordResetRepository,
+	permCacheRepo repository.PermissionCacheRepository,
+	loginHistory repository.LoginHistoryRepository,
 	tokenManager *auth.TokenManager,
+	authMetrics *metrics.AuthMetrics,
 	config *config.Config,
 	logger *logging.Logger,
 ) *AuthService {
@@ -48,12 +89,41 @@ func NewAuthService(
 		sessionRepo:     sessionRepo,
 		rateLimiterRepo: rateLimiterRepo,
 		resetRepo:       resetRepo,
+		permCacheRepo:   permCacheRepo,
+		loginHistory:    loginHistory,
 		tokenManager:    tokenManager,
+		metrics:         authMetrics,
 		config:          config,
 		logger:          logger,
 	}
 }
 
+// SetSecurityNotifier enables alerting users when their account is locked
+// after too many failed login attempts
+func (s *AuthService) SetSecurityNotifier(notifier SecurityNotifier) {
+	s.notifier = notifier
+}
+
+// SetDeactivationNotifier enables notifying and disconnecting users from
+// real-time channels when their account is deactivated
+func (s *AuthService) SetDeactivationNotifier(notifier DeactivationNotifier) {
+	s.deactivation = notifier
+}
+
+// SetPermissionResolver makes tokens carry the user's effective permissions,
+// with wildcard grants narrowed by the user's denies
+func (s *AuthService) SetPermissionResolver(resolver PermissionResolver) {
+	s.permissions = resolver
+}
+
+// tokenPermissions returns the permissions to embed in a token for user
+func (s *AuthService) tokenPermissions(ctx context.Context, user *domain.User) ([]string, error) {
+	if s.permissions == nil {
+		return user.GetPermissions(), nil
+	}
+	return s.permissions.GetUserPermissions(ctx, user.ID)
+}
+
 // Register registers a new user
 func (s *AuthService) Register(ctx context.Context, email, password, name string) (*domain.User, error) {
 	// Validate input
@@ -75,6 +145,17 @@ func (s *AuthService) Register(ctx context.Context, email, password, name string
 		return nil, errors.New(errors.ErrCodeEmailAlreadyExists, "email already registered")
 	}
 	
+	// Resolve the default role up front so a misconfigured role name fails
+	// registration instead of creating a user without any role
+	defaultRole, err := s.roleRepo.FindByName(ctx, s.config.Security.DefaultRole)
+	if err != nil {
+		s.logger.Error("default role not found",
+			zap.Error(err),
+			zap.String("role_name", s.config.Security.DefaultRole))
+		return nil, errors.Wrap(errors.ErrCodeInternal,
+			fmt.Sprintf("default role %q not found", s.config.Security.DefaultRole), err)
+	}
+	
 	// Hash password
 	passwordHash, err := bcrypt.GenerateFromPassword([]byte(password), s.config.Security.BcryptCost)
 	if err != nil {
@@ -90,23 +171,13 @@ func (s *AuthService) Register(ctx context.Context, email, password, name string
 		IsLocked:     false,
 	}
 	
-	if err := s.userRepo.Create(ctx, user); err != nil {
-		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to create user", err)
-	}
-	
-	// Assign default "member" role
-	memberRole, err := s.roleRepo.FindByName(ctx, "member")
-	if err != nil {
-		s.logger.Error("failed to find member role", 
+	// Create the user with the default role; if the assignment fails the
+	// user is rolled back too
+	if err := s.userRepo.CreateWithRole(ctx, user, defaultRole.ID); err != nil {
+		s.logger.Error("failed to create user with default role",
 			zap.Error(err),
-			zap.String("user_id", user.ID))
-		// Continue even if role assignment fails
-	} else {
-		if err := s.userRepo.AssignRole(ctx, user.ID, memberRole.ID); err != nil {
-			s.logger.Error("failed to assign member role",
-				zap.Error(err),
-				zap.String("user_id", user.ID))
-		}
+			zap.String("role_name", defaultRole.Name))
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to create user", err)
 	}
 	
 	// Reload user with roles
@@ -116,7 +187,8 @@ func (s *AuthService) Register(ctx context.Context, email, password, name string
 	}
 	
 	// Log audit event
-	s.logger.LogAuditEvent(&logging.AuditEvent{
+	s.metrics.RegistrationSucceeded()
+	s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
 		EventType: "user.registered",
 		UserID:    user.ID,
 		Email:     user.Email,
@@ -126,8 +198,24 @@ func (s *AuthService) Register(ctx context.Context, email, password, name string
 	return user, nil
 }
 
-// Login authenticates a user and creates a session
-func (s *AuthService) Login(ctx context.Context, email, password, ipAddress string) (*domain.User, string, time.Time, error) {
+// LoginResult is the outcome of a successful login
+type LoginResult struct {
+	User         *domain.User
+	Token        string
+	RefreshToken string // Empty for client types configured without refresh tokens
+	ExpiresAt    time.Time
+
+	// Security context: the login before this one, and whether this one
+	// came from an IP not seen within the known-device window
+	PreviousLoginAt *time.Time
+	PreviousLoginIP string
+	NewDevice       bool
+}
+
+// Login authenticates a user and creates a session. clientType identifies
+// the kind of client signing in (e.g. "browser", "native") and decides
+// whether a refresh token is handed out.
+func (s *AuthService) Login(ctx context.Context, email, password, ipAddress, clientType string) (*LoginResult, error) {
 	// Check if account is locked
 	locked, duration, err := s.rateLimiterRepo.IsLocked(ctx, email)
 	if err != nil {
@@ -135,7 +223,8 @@ func (s *AuthService) Login(ctx context.Context, email, password, ipAddress stri
 	}
 	
 	if locked {
-		s.logger.LogAuditEvent(&logging.AuditEvent{
+		s.metrics.LoginFailed(metrics.LoginFailureLocked)
+		s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
 			EventType:   "user.login.failed",
 			Email:       email,
 			IPAddress:   ipAddress,
@@ -145,7 +234,7 @@ func (s *AuthService) Login(ctx context.Context, email, password, ipAddress stri
 				"locked_duration_remaining": duration.String(),
 			},
 		})
-		return nil, "", time.Time{}, errors.New(errors.ErrCodeAccountLocked, 
+		return nil, errors.New(errors.ErrCodeAccountLocked, 
 			fmt.Sprintf("account locked for %v", duration.Round(time.Second)))
 	}
 	
@@ -156,7 +245,8 @@ func (s *AuthService) Login(ctx context.Context, email, password, ipAddress stri
 			// Record failed attempt even for non-existent users (prevent enumeration)
 			s.rateLimiterRepo.RecordFailedAttempt(ctx, email)
 			
-			s.logger.LogAuditEvent(&logging.AuditEvent{
+			s.metrics.LoginFailed(metrics.LoginFailureNotFound)
+			s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
 				EventType:   "user.login.failed",
 				Email:       email,
 				IPAddress:   ipAddress,
@@ -164,14 +254,15 @@ func (s *AuthService) Login(ctx context.Context, email, password, ipAddress stri
 				ErrorReason: "invalid_credentials",
 			})
 			
-			return nil, "", time.Time{}, errors.New(errors.ErrCodeInvalidCredentials, "invalid email or password")
+			return nil, errors.New(errors.ErrCodeInvalidCredentials, "invalid email or password")
 		}
-		return nil, "", time.Time{}, errors.Wrap(errors.ErrCodeInternal, "failed to find user", err)
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to find user", err)
 	}
 	
 	// Check if account is locked in database
 	if user.IsAccountLocked() {
-		s.logger.LogAuditEvent(&logging.AuditEvent{
+		s.metrics.LoginFailed(metrics.LoginFailureLocked)
+		s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
 			EventType:   "user.login.failed",
 			UserID:      user.ID,
 			Email:       email,
@@ -179,7 +270,7 @@ func (s *AuthService) Login(ctx context.Context, email, password, ipAddress stri
 			Success:     false,
 			ErrorReason: "account_locked",
 		})
-		return nil, "", time.Time{}, errors.New(errors.ErrCodeAccountLocked, "account is locked")
+		return nil, errors.New(errors.ErrCodeAccountLocked, "account is locked")
 	}
 	
 	// Verify password
@@ -199,7 +290,8 @@ func (s *AuthService) Login(ctx context.Context, email, password, ipAddress stri
 			// Also set Redis lock
 			s.rateLimiterRepo.LockAccount(ctx, email, s.config.Security.LockoutDuration)
 			
-			s.logger.LogAuditEvent(&logging.AuditEvent{
+			s.metrics.AccountLocked()
+			s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
 				EventType:   "user.account.locked",
 				UserID:      user.ID,
 				Email:       email,
@@ -211,9 +303,12 @@ func (s *AuthService) Login(ctx context.Context, email, password, ipAddress stri
 					"locked_until": lockUntil,
 				},
 			})
+			
+			s.notifyAccountLocked(ctx, user, ipAddress, attempts, lockUntil)
 		}
 		
-		s.logger.LogAuditEvent(&logging.AuditEvent{
+		s.metrics.LoginFailed(metrics.LoginFailureInvalidPassword)
+		s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
 			EventType:   "user.login.failed",
 			UserID:      user.ID,
 			Email:       email,
@@ -225,7 +320,22 @@ func (s *AuthService) Login(ctx context.Context, email, password, ipAddress stri
 			},
 		})
 		
-		return nil, "", time.Time{}, errors.New(errors.ErrCodeInvalidCredentials, "invalid email or password")
+		return nil, errors.New(errors.ErrCodeInvalidCredentials, "invalid email or password")
+	}
+	
+	// Deactivated accounts can't sign in. Checked after the password so the
+	// account state isn't revealed to someone guessing it.
+	if !user.IsActive {
+		s.metrics.LoginFailed(metrics.LoginFailureDeactivated)
+		s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
+			EventType:   "user.login.failed",
+			UserID:      user.ID,
+			Email:       email,
+			IPAddress:   ipAddress,
+			Success:     false,
+			ErrorReason: "account_deactivated",
+		})
+		return nil, errors.New(errors.ErrCodePermissionDenied, "account is deactivated")
 	}
 	
 	// Reset failed attempts on successful login
@@ -235,9 +345,17 @@ func (s *AuthService) Login(ctx context.Context, email, password, ipAddress stri
 	sessionID := uuid.New().String()
 	
 	// Generate JWT
-	token, err := s.tokenManager.GenerateToken(user, sessionID)
+	tokenVersion, err := s.currentTokenVersion(ctx, user.ID)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to get token version", err)
+	}
+	permissions, err := s.tokenPermissions(ctx, user)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to resolve permissions", err)
+	}
+	token, err := s.tokenManager.GenerateToken(user, permissions, sessionID, tokenVersion)
 	if err != nil {
-		return nil, "", time.Time{}, errors.Wrap(errors.ErrCodeInternal, "failed to generate token", err)
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to generate token", err)
 	}
 	
 	// Extract JTI from token
@@ -256,30 +374,316 @@ func (s *AuthService) Login(ctx context.Context, email, password, ipAddress stri
 	}
 	
 	if err := s.sessionRepo.Create(ctx, session); err != nil {
-		return nil, "", time.Time{}, errors.Wrap(errors.ErrCodeInternal, "failed to create session", err)
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to create session", err)
 	}
 	
+	// Warm permission cache from the roles already loaded with the user. Users
+	// with denied permissions are left to RBACService, which may need to
+	// expand wildcard grants the denies narrow.
+	if s.config.Security.WarmPermissionCache && s.permCacheRepo != nil && len(user.GetDeniedPermissions()) == 0 {
+		if err := s.permCacheRepo.SetUserPermissions(ctx, user.ID, user.GetPermissions(), permissionCacheTTL(s.config.Security)); err != nil {
+			s.logger.Warn("failed to warm permission cache",
+				zap.Error(err),
+				zap.String("user_id", user.ID))
+		}
+	}
+	
+	result := &LoginResult{
+		User:            user,
+		Token:           token,
+		ExpiresAt:       expiresAt,
+		PreviousLoginAt: user.LastLoginAt,
+		PreviousLoginIP: user.LastLoginIP,
+		NewDevice:       s.isNewDevice(ctx, user, ipAddress),
+	}
+	if s.issuesRefreshToken(clientType) {
+		refreshToken, err := s.issueRefreshToken(ctx, user.ID, sessionID, clientType)
+		if err != nil {
+			return nil, err
+		}
+		result.RefreshToken = refreshToken
+	}
+	s.recordLogin(ctx, user, ipAddress)
+	
 	// Log audit event
-	s.logger.LogAuditEvent(&logging.AuditEvent{
+	s.metrics.LoginSucceeded()
+	s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
 		EventType: "user.login.success",
 		UserID:    user.ID,
 		Email:     user.Email,
 		IPAddress: ipAddress,
 		Success:   true,
 		Metadata: map[string]interface{}{
-			"session_id": sessionID,
+			"session_id":  sessionID,
+			"new_device":  result.NewDevice,
+			"client_type": clientType,
+		},
+	})
+	
+	return result, nil
+}
+
+// issuesRefreshToken reports whether logins from clientType get a refresh
+// token. Clients such as browsers holding the session cookie can be
+// configured to go without one. The client type is self-declared, so this
+// saves honest clients a token rather than keeping one from a caller.
+func (s *AuthService) issuesRefreshToken(clientType string) bool {
+	for _, omitted := range s.config.Security.NoRefreshTokenClients {
+		if strings.EqualFold(omitted, clientType) {
+			return false
+		}
+	}
+	return true
+}
+
+// issueRefreshToken generates and stores a refresh token for a session
+func (s *AuthService) issueRefreshToken(ctx context.Context, userID, sessionID, clientType string) (string, error) {
+	refreshToken, err := s.tokenManager.GenerateRefreshToken()
+	if err != nil {
+		return "", errors.Wrap(errors.ErrCodeInternal, "failed to generate refresh token", err)
+	}
+	
+	now := time.Now()
+	refresh := &domain.RefreshToken{
+		UserID:     userID,
+		SessionID:  sessionID,
+		ClientType: clientType,
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(s.config.Security.RefreshTokenTTL),
+	}
+	if err := s.sessionRepo.CreateRefreshToken(ctx, refreshToken, refresh); err != nil {
+		return "", errors.Wrap(errors.ErrCodeInternal, "failed to store refresh token", err)
+	}
+	
+	return refreshToken, nil
+}
+
+// RefreshResult is the outcome of redeeming a refresh token
+type RefreshResult struct {
+	User         *domain.User
+	Token        string
+	RefreshToken string    // Replaces the redeemed refresh token
+	ExpiresAt    time.Time // When Token expires
+}
+
+// RefreshToken redeems a refresh token for a new access token and a new
+// refresh token. The redeemed token is revoked, so replaying it fails.
+func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*RefreshResult, error) {
+	if refreshToken == "" {
+		return nil, errors.New(errors.ErrCodeInvalidInput, "refresh token is required")
+	}
+	
+	refresh, err := s.sessionRepo.ConsumeRefreshToken(ctx, refreshToken)
+	if err == repository.ErrNotFound {
+		return nil, errors.New(errors.ErrCodeInvalidToken, "invalid or expired refresh token")
+	}
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to redeem refresh token", err)
+	}
+	
+	user, err := s.userRepo.FindByID(ctx, refresh.UserID)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrCodeInvalidToken, "invalid or expired refresh token", err)
+	}
+	if !user.IsActive {
+		return nil, errors.New(errors.ErrCodePermissionDenied, "account is deactivated")
+	}
+	
+	tokenVersion, err := s.currentTokenVersion(ctx, user.ID)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to get token version", err)
+	}
+	permissions, err := s.tokenPermissions(ctx, user)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to resolve permissions", err)
+	}
+	token, err := s.tokenManager.GenerateToken(user, permissions, refresh.SessionID, tokenVersion)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to generate token", err)
+	}
+	claims, _ := s.tokenManager.ExtractClaims(token)
+	
+	// Extend the session, recreating it if it expired while the refresh
+	// token was still valid
+	now := time.Now()
+	session, err := s.sessionRepo.Get(ctx, refresh.SessionID)
+	if err != nil {
+		session = &domain.Session{
+			SessionID: refresh.SessionID,
+			UserID:    user.ID,
+			CreatedAt: now,
+		}
+	}
+	session.TokenJTI = claims.ID
+	session.ExpiresAt = now.Add(s.config.Security.SessionExpiration)
+	session.LastActivity = now
+	if err := s.sessionRepo.Create(ctx, session); err != nil {
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to create session", err)
+	}
+	
+	newRefreshToken, err := s.issueRefreshToken(ctx, user.ID, refresh.SessionID, refresh.ClientType)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Log audit event
+	s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
+		EventType: "user.token.refreshed",
+		UserID:    user.ID,
+		Email:     user.Email,
+		Success:   true,
+		Metadata: map[string]interface{}{
+			"session_id":  refresh.SessionID,
+			"client_type": refresh.ClientType,
 		},
 	})
 	
-	return user, token, expiresAt, nil
+	return &RefreshResult{
+		User:         user,
+		Token:        token,
+		RefreshToken: newRefreshToken,
+		ExpiresAt:    claims.ExpiresAt.Time,
+	}, nil
+}
+
+// isNewDevice reports whether a login comes from an IP the user hasn't
+// signed in from within the known-device window. A user's first login
+// is never flagged since there is nothing to compare against.
+func (s *AuthService) isNewDevice(ctx context.Context, user *domain.User, ipAddress string) bool {
+	if ipAddress == "" || user.LastLoginAt == nil || ipAddress == user.LastLoginIP {
+		return false
+	}
+	if s.loginHistory == nil {
+		return true
+	}
+
+	lastSeen, seen, err := s.loginHistory.LastSeenIP(ctx, user.ID, ipAddress)
+	if err != nil {
+		// Fall back to comparing against the last login IP only
+		s.logger.Warn("failed to check login history",
+			zap.Error(err),
+			zap.String("user_id", user.ID))
+		return true
+	}
+	return !seen || time.Since(lastSeen) > s.config.Security.KnownDeviceWindow
+}
+
+// recordLogin updates the user's last login and the IP history. Failures are
+// logged rather than failing a login that already succeeded.
+func (s *AuthService) recordLogin(ctx context.Context, user *domain.User, ipAddress string) {
+	now := time.Now()
+
+	if err := s.userRepo.UpdateLastLogin(ctx, user.ID, now, ipAddress); err != nil {
+		s.logger.Warn("failed to update last login",
+			zap.Error(err),
+			zap.String("user_id", user.ID))
+	} else {
+		user.LastLoginAt = &now
+		user.LastLoginIP = ipAddress
+	}
+
+	if s.loginHistory != nil && ipAddress != "" {
+		if err := s.loginHistory.RecordLoginIP(ctx, user.ID, ipAddress, now, s.config.Security.KnownDeviceWindow); err != nil {
+			s.logger.Warn("failed to record login IP",
+				zap.Error(err),
+				zap.String("user_id", user.ID))
+		}
+	}
+}
+
+// notifyAccountLocked tells the account owner about the failed attempts that
+// locked them out. Failures are logged; the lock is already in place.
+func (s *AuthService) notifyAccountLocked(ctx context.Context, user *domain.User, ipAddress string, attempts int, lockUntil time.Time) {
+	if s.notifier == nil {
+		return
+	}
+
+	payload := map[string]interface{}{
+		"reason":       "max_login_attempts_exceeded",
+		"ip_address":   ipAddress,
+		"attempts":     attempts,
+		"locked_until": lockUntil.Format(time.RFC3339),
+	}
+
+	if err := s.notifier.NotifyUser(ctx, user.ID, EventAccountLocked, payload); err != nil {
+		s.logger.Warn("failed to send account locked notification",
+			zap.Error(err),
+			zap.String("user_id", user.ID))
+	}
 }
 
 // ValidateToken validates a JWT token
 func (s *AuthService) ValidateToken(ctx context.Context, tokenString string) (*domain.User, error) {
+	user, _, err := s.validateToken(ctx, tokenString)
+	return user, err
+}
+
+// ReissueToken validates a token like ValidateToken and, under the reissue
+// role change policy, returns a replacement when the user's roles changed
+// since the token was issued. The replacement belongs to the same session
+// and carries the user's current roles and permissions; reissued is empty
+// when the token is current.
+func (s *AuthService) ReissueToken(ctx context.Context, tokenString string) (user *domain.User, reissued string, err error) {
+	user, claims, err := s.validateToken(ctx, tokenString)
+	if err != nil {
+		return nil, "", err
+	}
+	if s.config.Security.RoleChangePolicy != config.RoleChangeReissue {
+		return user, "", nil
+	}
+	
+	// The presented token is still valid, so a lookup or signing failure
+	// only delays the reissue to a later call
+	version, err := s.sessionRepo.GetTokenVersion(ctx, user.ID)
+	if err != nil {
+		s.logger.Error("failed to get token version", zap.Error(err), zap.String("user_id", user.ID))
+		return user, "", nil
+	}
+	if claims.TokenVersion >= version {
+		return user, "", nil
+	}
+	
+	permissions, err := s.tokenPermissions(ctx, user)
+	if err != nil {
+		s.logger.Error("failed to resolve permissions for reissue", zap.Error(err), zap.String("user_id", user.ID))
+		return user, "", nil
+	}
+	reissued, err = s.tokenManager.GenerateToken(user, permissions, claims.SessionID, version)
+	if err != nil {
+		s.logger.Error("failed to reissue token", zap.Error(err), zap.String("user_id", user.ID))
+		return user, "", nil
+	}
+	
+	s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
+		EventType: "user.token.reissued",
+		UserID:    user.ID,
+		Email:     user.Email,
+		Success:   true,
+		Metadata: map[string]interface{}{
+			"session_id":    claims.SessionID,
+			"token_version": version,
+		},
+	})
+	
+	return user, reissued, nil
+}
+
+// currentTokenVersion returns the token version new tokens for the user
+// carry. Versions are only tracked under the reissue role change policy.
+func (s *AuthService) currentTokenVersion(ctx context.Context, userID string) (int64, error) {
+	if s.config.Security.RoleChangePolicy != config.RoleChangeReissue {
+		return 0, nil
+	}
+	return s.sessionRepo.GetTokenVersion(ctx, userID)
+}
+
+// validateToken checks the token's signature, revocation and session, and
+// returns its user and claims
+func (s *AuthService) validateToken(ctx context.Context, tokenString string) (*domain.User, *auth.TokenClaims, error) {
 	// Validate token signature and expiration
 	claims, err := s.tokenManager.ValidateToken(tokenString)
 	if err != nil {
-		return nil, errors.Wrap(errors.ErrCodeInvalidToken, "invalid token", err)
+		return nil, nil, errors.Wrap(errors.ErrCodeInvalidToken, "invalid token", err)
 	}
 	
 	// Check if token is revoked
@@ -289,13 +693,13 @@ func (s *AuthService) ValidateToken(ctx context.Context, tokenString string) (*d
 	}
 	
 	if revoked {
-		return nil, errors.New(errors.ErrCodeRevokedToken, "token has been revoked")
+		return nil, nil, errors.New(errors.ErrCodeRevokedToken, "token has been revoked")
 	}
 	
 	// Check if session exists
 	session, err := s.sessionRepo.Get(ctx, claims.SessionID)
 	if err != nil {
-		return nil, errors.New(errors.ErrCodeInvalidToken, "session not found")
+		return nil, nil, errors.New(errors.ErrCodeInvalidToken, "session not found")
 	}
 	
 	// Extend session expiration (sliding window)
@@ -306,10 +710,13 @@ func (s *AuthService) ValidateToken(ctx context.Context, tokenString string) (*d
 	// Get user
 	user, err := s.userRepo.FindByID(ctx, claims.UserID)
 	if err != nil {
-		return nil, errors.Wrap(errors.ErrCodeUserNotFound, "user not found", err)
+		return nil, nil, errors.Wrap(errors.ErrCodeUserNotFound, "user not found", err)
+	}
+	if !user.IsActive {
+		return nil, nil, errors.New(errors.ErrCodeInvalidToken, "account is deactivated")
 	}
 	
-	return user, nil
+	return user, claims, nil
 }
 
 // Logout logs out a user
@@ -330,8 +737,13 @@ func (s *AuthService) Logout(ctx context.Context, tokenString string) error {
 		s.logger.Error("failed to revoke token", zap.Error(err), zap.String("jti", claims.ID))
 	}
 	
+	// Delete the session's refresh token
+	if err := s.sessionRepo.DeleteRefreshToken(ctx, claims.UserID, claims.SessionID); err != nil {
+		s.logger.Error("failed to delete refresh token", zap.Error(err), zap.String("session_id", claims.SessionID))
+	}
+	
 	// Log audit event
-	s.logger.LogAuditEvent(&logging.AuditEvent{
+	s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
 		EventType: "user.logout",
 		UserID:    claims.UserID,
 		Email:     claims.Email,
@@ -352,7 +764,7 @@ func (s *AuthService) LogoutAllDevices(ctx context.Context, userID string) error
 	}
 	
 	// Log audit event
-	s.logger.LogAuditEvent(&logging.AuditEvent{
+	s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
 		EventType: "user.logout.all_devices",
 		UserID:    userID,
 		Success:   true,
@@ -361,6 +773,192 @@ func (s *AuthService) LogoutAllDevices(ctx context.Context, userID string) error
 	return nil
 }
 
+// DeactivateUser deactivates an account and ends all of its sessions. Only
+// admins may deactivate users. actorID identifies the admin for the audit trail.
+func (s *AuthService) DeactivateUser(ctx context.Context, actorID, userID, reason string) error {
+	if userID == "" {
+		return errors.New(errors.ErrCodeInvalidInput, "user_id is required")
+	}
+	if actorID == userID {
+		return errors.New(errors.ErrCodeInvalidInput, "cannot deactivate your own account")
+	}
+	
+	actor, err := s.userRepo.FindByID(ctx, actorID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return errors.New(errors.ErrCodePermissionDenied, "only admins may deactivate users")
+		}
+		return errors.Wrap(errors.ErrCodeInternal, "failed to find requesting user", err)
+	}
+	if !isAdmin(actor) {
+		return errors.New(errors.ErrCodePermissionDenied, "only admins may deactivate users")
+	}
+	
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return errors.New(errors.ErrCodeUserNotFound, "user not found")
+		}
+		return errors.Wrap(errors.ErrCodeInternal, "failed to find user", err)
+	}
+	
+	user.IsActive = false
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return errors.Wrap(errors.ErrCodeInternal, "failed to deactivate user", err)
+	}
+	
+	// Kill sessions so existing tokens stop validating
+	if err := s.sessionRepo.DeleteAllForUser(ctx, userID); err != nil {
+		return errors.Wrap(errors.ErrCodeInternal, "failed to delete sessions", err)
+	}
+	
+	s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
+		EventType: "user.deactivated",
+		UserID:    actorID,
+		Success:   true,
+		Metadata: map[string]interface{}{
+			"deactivated_user_id": userID,
+			"reason":              reason,
+		},
+	})
+	
+	s.notifyDeactivated(ctx, userID, reason)
+	
+	return nil
+}
+
+// GetUser gets a user by ID with roles and permissions loaded
+func (s *AuthService) GetUser(ctx context.Context, userID string) (*domain.User, error) {
+	if userID == "" {
+		return nil, errors.New(errors.ErrCodeInvalidInput, "user_id is required")
+	}
+	
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrCodeUserNotFound, "user not found")
+		}
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to find user", err)
+	}
+	
+	return user, nil
+}
+
+// GetUsers gets several users by ID in one query, with roles and permissions
+// loaded. Unknown IDs are omitted rather than failing the batch.
+func (s *AuthService) GetUsers(ctx context.Context, userIDs []string) ([]domain.User, error) {
+	seen := make(map[string]bool, len(userIDs))
+	unique := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		if id == "" {
+			return nil, errors.New(errors.ErrCodeInvalidInput, "user_ids cannot contain an empty ID")
+		}
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, errors.New(errors.ErrCodeInvalidInput, "user_ids is required")
+	}
+	if len(unique) > maxGetUsers {
+		return nil, errors.New(errors.ErrCodeInvalidInput, fmt.Sprintf("at most %d user_ids can be requested at once", maxGetUsers))
+	}
+	
+	users, err := s.userRepo.FindByIDs(ctx, unique)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to find users", err)
+	}
+	
+	return users, nil
+}
+
+// ListUsers returns a page of users, oldest first. Only admins may list them.
+func (s *AuthService) ListUsers(ctx context.Context, requestingUserID string, req pagination.Request) ([]domain.User, pagination.Response, error) {
+	page, err := pagination.Normalize(req, listUsersLimits)
+	if err != nil {
+		return nil, pagination.Response{}, errors.New(errors.ErrCodeInvalidInput, err.Error())
+	}
+	
+	requester, err := s.userRepo.FindByID(ctx, requestingUserID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, pagination.Response{}, errors.New(errors.ErrCodePermissionDenied, "not allowed to list users")
+		}
+		return nil, pagination.Response{}, errors.Wrap(errors.ErrCodeInternal, "failed to find requesting user", err)
+	}
+	if !isAdmin(requester) {
+		return nil, pagination.Response{}, errors.New(errors.ErrCodePermissionDenied, "not allowed to list users")
+	}
+	
+	users, total, err := s.userRepo.List(ctx, page)
+	if err != nil {
+		return nil, pagination.Response{}, errors.Wrap(errors.ErrCodeInternal, "failed to list users", err)
+	}
+	
+	return users, page.Result(total, len(users)), nil
+}
+
+// UpdateProfile changes a user's name and returns the refreshed user
+func (s *AuthService) UpdateProfile(ctx context.Context, userID, name string) (*domain.User, error) {
+	if userID == "" {
+		return nil, errors.New(errors.ErrCodeInvalidInput, "user_id is required")
+	}
+	if err := auth.ValidateName(name); err != nil {
+		return nil, errors.New(errors.ErrCodeInvalidInput, err.Error())
+	}
+	
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrCodeUserNotFound, "user not found")
+		}
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to find user", err)
+	}
+	
+	previousName := user.Name
+	user.Name = name
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to update user", err)
+	}
+	
+	s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
+		EventType: "user.profile.updated",
+		UserID:    userID,
+		Success:   true,
+		Metadata: map[string]interface{}{
+			"previous_name": previousName,
+			"name":          name,
+		},
+	})
+	
+	return s.GetUser(ctx, userID)
+}
+
+// notifyDeactivated tells the user their account was deactivated, then drops
+// their real-time connections. Failures are logged; the account is already
+// deactivated and its sessions are gone.
+func (s *AuthService) notifyDeactivated(ctx context.Context, userID, reason string) {
+	if s.deactivation == nil {
+		return
+	}
+
+	payload := map[string]interface{}{
+		"reason": reason,
+	}
+	if err := s.deactivation.NotifyUser(ctx, userID, EventAccountDeactivated, payload); err != nil {
+		s.logger.Warn("failed to send account deactivated notification",
+			zap.Error(err),
+			zap.String("user_id", userID))
+	}
+
+	if err := s.deactivation.DisconnectUser(ctx, userID, "account_deactivated"); err != nil {
+		s.logger.Warn("failed to disconnect deactivated user",
+			zap.Error(err),
+			zap.String("user_id", userID))
+	}
+}
+
 // RequestPasswordReset generates a password reset token
 func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) (string, error) {
 	// Find user
@@ -391,7 +989,8 @@ func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) (s
 	}
 	
 	// Log audit event
-	s.logger.LogAuditEvent(&logging.AuditEvent{
+	s.metrics.PasswordResetRequested()
+	s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
 		EventType: "user.password_reset.requested",
 		UserID:    user.ID,
 		Email:     user.Email,
@@ -402,6 +1001,74 @@ func (s *AuthService) RequestPasswordReset(ctx context.Context, email string) (s
 	return token, nil
 }
 
+// ChangePassword changes the password of the user signed in with
+// tokenString after checking their current password. Every other session
+// is ended; the caller's own session is kept too unless
+// KEEP_SESSION_ON_PASSWORD_CHANGE is disabled.
+func (s *AuthService) ChangePassword(ctx context.Context, tokenString, currentPassword, newPassword string) error {
+	user, claims, err := s.validateToken(ctx, tokenString)
+	if err != nil {
+		return err
+	}
+	
+	// Verify current password
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(currentPassword)); err != nil {
+		s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
+			EventType:   "user.password_changed",
+			UserID:      user.ID,
+			Email:       user.Email,
+			Success:     false,
+			ErrorReason: "invalid_password",
+			Metadata: map[string]interface{}{
+				"session_id": claims.SessionID,
+			},
+		})
+		return errors.New(errors.ErrCodeInvalidCredentials, "current password is incorrect")
+	}
+	
+	// Validate new password
+	if err := auth.ValidatePassword(newPassword); err != nil {
+		return errors.New(errors.ErrCodeWeakPassword, err.Error())
+	}
+	
+	// Hash new password
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(newPassword), s.config.Security.BcryptCost)
+	if err != nil {
+		return errors.Wrap(errors.ErrCodeInternal, "failed to hash password", err)
+	}
+	
+	// Update password
+	user.PasswordHash = string(passwordHash)
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return errors.Wrap(errors.ErrCodeInternal, "failed to update password", err)
+	}
+	
+	// Invalidate sessions
+	keepSession := s.config.Security.KeepSessionOnPasswordChange
+	if keepSession {
+		err = s.sessionRepo.DeleteAllForUserExcept(ctx, user.ID, claims.SessionID)
+	} else {
+		err = s.sessionRepo.DeleteAllForUser(ctx, user.ID)
+	}
+	if err != nil {
+		return errors.Wrap(errors.ErrCodeInternal, "failed to delete sessions", err)
+	}
+	
+	// Log audit event
+	s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
+		EventType: "user.password_changed",
+		UserID:    user.ID,
+		Email:     user.Email,
+		Success:   true,
+		Metadata: map[string]interface{}{
+			"session_id":           claims.SessionID,
+			"kept_current_session": keepSession,
+		},
+	})
+	
+	return nil
+}
+
 // ResetPassword resets a user's password
 func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword string) error {
 	// Validate new password
@@ -440,7 +1107,8 @@ func (s *AuthService) ResetPassword(ctx context.Context, token, newPassword stri
 	s.sessionRepo.DeleteAllForUser(ctx, user.ID)
 	
 	// Log audit event
-	s.logger.LogAuditEvent(&logging.AuditEvent{
+	s.metrics.PasswordResetCompleted()
+	s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
 		EventType: "user.password_reset.completed",
 		UserID:    user.ID,
 		Email:     user.Email,
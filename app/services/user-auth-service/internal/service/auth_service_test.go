@@ -2,17 +2,31 @@ package service
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/haunted-saas/pkg/pagination"
 	"github.com/haunted-saas/user-auth-service/internal/auth"
 	"github.com/haunted-saas/user-auth-service/internal/config"
 	"github.com/haunted-saas/user-auth-service/internal/domain"
 	"github.com/haunted-saas/user-auth-service/internal/errors"
 	"github.com/haunted-saas/user-auth-service/internal/logging"
+	"github.com/haunted-saas/user-auth-service/internal/metrics"
+	"github.com/haunted-saas/user-auth-service/internal/repository"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 	"gorm.io/gorm"
 )
 
@@ -26,6 +40,11 @@ func (m *MockUserRepository) Create(ctx context.Context, user *domain.User) erro
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) CreateWithRole(ctx context.Context, user *domain.User, roleID string) error {
+	args := m.Called(ctx, user, roleID)
+	return args.Error(0)
+}
+
 func (m *MockUserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
 	args := m.Called(ctx, email)
 	if args.Get(0) == nil {
@@ -42,11 +61,26 @@ func (m *MockUserRepository) FindByID(ctx context.Context, id string) (*domain.U
 	return args.Get(0).(*domain.User), args.Error(1)
 }
 
+func (m *MockUserRepository) FindByIDs(ctx context.Context, ids []string) ([]domain.User, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).([]domain.User), args.Error(1)
+}
+
+func (m *MockUserRepository) List(ctx context.Context, page pagination.Page) ([]domain.User, int64, error) {
+	args := m.Called(ctx, page)
+	return args.Get(0).([]domain.User), args.Get(1).(int64), args.Error(2)
+}
+
 func (m *MockUserRepository) Update(ctx context.Context, user *domain.User) error {
 	args := m.Called(ctx, user)
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) UpdateLastLogin(ctx context.Context, userID string, at time.Time, ipAddress string) error {
+	args := m.Called(ctx, userID, at, ipAddress)
+	return args.Error(0)
+}
+
 func (m *MockUserRepository) GetUserRoles(ctx context.Context, userID string) ([]domain.Role, error) {
 	args := m.Called(ctx, userID)
 	return args.Get(0).([]domain.Role), args.Error(1)
@@ -75,35 +109,54 @@ func (m *MockRoleRepository) FindByName(ctx context.Context, name string) (*doma
 }
 
 func (m *MockRoleRepository) Create(ctx context.Context, role *domain.Role) error {
-	return nil
+	args := m.Called(ctx, role)
+	return args.Error(0)
 }
 
 func (m *MockRoleRepository) FindByID(ctx context.Context, id string) (*domain.Role, error) {
-	return nil, nil
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.Role), args.Error(1)
 }
 
 func (m *MockRoleRepository) Update(ctx context.Context, role *domain.Role) error {
-	return nil
+	args := m.Called(ctx, role)
+	return args.Error(0)
 }
 
 func (m *MockRoleRepository) Delete(ctx context.Context, id string) error {
-	return nil
+	args := m.Called(ctx, id)
+	return args.Error(0)
 }
 
 func (m *MockRoleRepository) GetRolePermissions(ctx context.Context, roleID string) ([]domain.Permission, error) {
-	return nil, nil
+	args := m.Called(ctx, roleID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]domain.Permission), args.Error(1)
 }
 
 func (m *MockRoleRepository) AssignPermission(ctx context.Context, roleID, permissionID string) error {
-	return nil
+	args := m.Called(ctx, roleID, permissionID)
+	return args.Error(0)
 }
 
 func (m *MockRoleRepository) RevokePermission(ctx context.Context, roleID, permissionID string) error {
-	return nil
+	args := m.Called(ctx, roleID, permissionID)
+	return args.Error(0)
 }
 
 func (m *MockRoleRepository) SetPermissions(ctx context.Context, roleID string, permissionIDs []string) error {
-	return nil
+	args := m.Called(ctx, roleID, permissionIDs)
+	return args.Error(0)
+}
+
+func (m *MockRoleRepository) SetDeniedPermissions(ctx context.Context, roleID string, permissionIDs []string) error {
+	args := m.Called(ctx, roleID, permissionIDs)
+	return args.Error(0)
 }
 
 type MockSessionRepository struct {
@@ -133,6 +186,11 @@ func (m *MockSessionRepository) DeleteAllForUser(ctx context.Context, userID str
 	return args.Error(0)
 }
 
+func (m *MockSessionRepository) DeleteAllForUserExcept(ctx context.Context, userID, keepSessionID string) error {
+	args := m.Called(ctx, userID, keepSessionID)
+	return args.Error(0)
+}
+
 func (m *MockSessionRepository) ExtendExpiration(ctx context.Context, sessionID string, duration time.Duration) error {
 	args := m.Called(ctx, sessionID, duration)
 	return args.Error(0)
@@ -148,6 +206,34 @@ func (m *MockSessionRepository) RevokeToken(ctx context.Context, tokenJTI string
 	return args.Error(0)
 }
 
+func (m *MockSessionRepository) GetTokenVersion(ctx context.Context, userID string) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockSessionRepository) IncrementTokenVersion(ctx context.Context, userID string) (int64, error) {
+	args := m.Called(ctx, userID)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *MockSessionRepository) CreateRefreshToken(ctx context.Context, token string, refresh *domain.RefreshToken) error {
+	args := m.Called(ctx, token, refresh)
+	return args.Error(0)
+}
+
+func (m *MockSessionRepository) ConsumeRefreshToken(ctx context.Context, token string) (*domain.RefreshToken, error) {
+	args := m.Called(ctx, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*domain.RefreshToken), args.Error(1)
+}
+
+func (m *MockSessionRepository) DeleteRefreshToken(ctx context.Context, userID, sessionID string) error {
+	args := m.Called(ctx, userID, sessionID)
+	return args.Error(0)
+}
+
 type MockRateLimiterRepository struct {
 	mock.Mock
 }
@@ -194,7 +280,7 @@ func TestAuthService_Register(t *testing.T) {
 			userName: "Test User",
 			setupMocks: func(userRepo *MockUserRepository, roleRepo *MockRoleRepository) {
 				userRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(nil, gorm.ErrRecordNotFound)
-				userRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.User")).Return(nil)
+				userRepo.On("CreateWithRole", mock.Anything, mock.AnythingOfType("*domain.User"), "role-123").Return(nil)
 				userRepo.On("FindByID", mock.Anything, mock.Anything).Return(&domain.User{
 					ID:    "user-123",
 					Email: "test@example.com",
@@ -205,7 +291,6 @@ func TestAuthService_Register(t *testing.T) {
 					ID:   "role-123",
 					Name: "member",
 				}, nil)
-				userRepo.On("AssignRole", mock.Anything, mock.Anything, "role-123").Return(nil)
 			},
 			expectedError: nil,
 		},
@@ -257,7 +342,8 @@ func TestAuthService_Register(t *testing.T) {
 			logger, _ := logging.NewLogger("error")
 			cfg := &config.Config{
 				Security: config.SecurityConfig{
-					BcryptCost: 12,
+					BcryptCost:  12,
+					DefaultRole: "member",
 				},
 			}
 			
@@ -268,6 +354,9 @@ func TestAuthService_Register(t *testing.T) {
 				rateLimiterRepo,
 				nil,
 				nil,
+				nil,
+				nil,
+				nil,
 				cfg,
 				logger,
 			)
@@ -296,6 +385,93 @@ func TestAuthService_Register(t *testing.T) {
 	}
 }
 
+// Test Register surfaces a missing default role instead of creating a role-less user
+func TestAuthService_Register_MissingDefaultRole(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	roleRepo := new(MockRoleRepository)
+
+	userRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(nil, gorm.ErrRecordNotFound)
+	roleRepo.On("FindByName", mock.Anything, "customer").Return(nil, gorm.ErrRecordNotFound)
+
+	logger, _ := logging.NewLogger("error")
+	cfg := &config.Config{
+		Security: config.SecurityConfig{
+			BcryptCost:  bcrypt.MinCost,
+			DefaultRole: "customer",
+		},
+	}
+
+	service := NewAuthService(
+		userRepo,
+		roleRepo,
+		new(MockSessionRepository),
+		new(MockRateLimiterRepository),
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		cfg,
+		logger,
+	)
+
+	user, err := service.Register(context.Background(), "test@example.com", "ValidPass123!", "Test User")
+
+	assert.Nil(t, user)
+	serviceErr, ok := err.(*errors.ServiceError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInternal, serviceErr.Code)
+	assert.Contains(t, serviceErr.Message, `"customer"`)
+
+	userRepo.AssertNotCalled(t, "CreateWithRole", mock.Anything, mock.Anything, mock.Anything)
+	roleRepo.AssertExpectations(t)
+}
+
+func TestAuthService_Register_RoleAssignmentFails(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	roleRepo := new(MockRoleRepository)
+
+	userRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(nil, gorm.ErrRecordNotFound)
+	roleRepo.On("FindByName", mock.Anything, "customer").Return(&domain.Role{ID: "role-123", Name: "customer"}, nil)
+	userRepo.On("CreateWithRole", mock.Anything, mock.AnythingOfType("*domain.User"), "role-123").
+		Return(fmt.Errorf("insert user_roles: connection reset"))
+
+	logger, _ := logging.NewLogger("error")
+	cfg := &config.Config{
+		Security: config.SecurityConfig{
+			BcryptCost:  bcrypt.MinCost,
+			DefaultRole: "customer",
+		},
+	}
+
+	service := NewAuthService(
+		userRepo,
+		roleRepo,
+		new(MockSessionRepository),
+		new(MockRateLimiterRepository),
+		nil,
+		nil,
+		nil,
+		nil,
+		nil,
+		cfg,
+		logger,
+	)
+
+	user, err := service.Register(context.Background(), "test@example.com", "ValidPass123!", "Test User")
+
+	assert.Nil(t, user)
+	serviceErr, ok := err.(*errors.ServiceError)
+	assert.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInternal, serviceErr.Code)
+
+	// The user and role are written together, never one without the other
+	userRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+	userRepo.AssertNotCalled(t, "AssignRole", mock.Anything, mock.Anything, mock.Anything)
+	userRepo.AssertNotCalled(t, "FindByID", mock.Anything, mock.Anything)
+	userRepo.AssertExpectations(t)
+}
+
 // Test Login
 func TestAuthService_Login(t *testing.T) {
 	// Create a valid password hash
@@ -328,6 +504,8 @@ func TestAuthService_Login(t *testing.T) {
 				}, nil)
 				rateLimiter.On("ResetAttempts", mock.Anything, "test@example.com").Return(nil)
 				sessionRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Session")).Return(nil)
+				sessionRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*domain.RefreshToken")).Return(nil)
+				userRepo.On("UpdateLastLogin", mock.Anything, "user-123", mock.AnythingOfType("time.Time"), "192.168.1.1").Return(nil)
 			},
 			expectedError: nil,
 		},
@@ -350,6 +528,22 @@ func TestAuthService_Login(t *testing.T) {
 			},
 			expectedError: errors.New(errors.ErrCodeInvalidCredentials, ""),
 		},
+		{
+			name:      "deactivated account",
+			email:     "test@example.com",
+			password:  "ValidPass123!",
+			ipAddress: "192.168.1.1",
+			setupMocks: func(userRepo *MockUserRepository, rateLimiter *MockRateLimiterRepository, sessionRepo *MockSessionRepository) {
+				rateLimiter.On("IsLocked", mock.Anything, "test@example.com").Return(false, time.Duration(0), nil)
+				userRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(&domain.User{
+					ID:           "user-123",
+					Email:        "test@example.com",
+					PasswordHash: string(validPasswordHash),
+					IsActive:     false,
+				}, nil)
+			},
+			expectedError: errors.New(errors.ErrCodePermissionDenied, ""),
+		},
 		{
 			name:      "account locked",
 			email:     "locked@example.com",
@@ -371,7 +565,7 @@ func TestAuthService_Login(t *testing.T) {
 
 			tt.setupMocks(userRepo, rateLimiterRepo, sessionRepo)
 
-			// Create service (without token manager for this test)
+			// Create service
 			logger, _ := logging.NewLogger("error")
 			cfg := &config.Config{
 				Security: config.SecurityConfig{
@@ -382,12 +576,7 @@ func TestAuthService_Login(t *testing.T) {
 				},
 			}
 
-			// Create a mock token manager
-			tokenManager, _ := auth.NewTokenManager(
-				"../../keys/jwt-private.pem",
-				"../../keys/jwt-public.pem",
-				24*time.Hour,
-			)
+			tokenManager := newTestTokenManager(t)
 
 			service := NewAuthService(
 				userRepo,
@@ -395,19 +584,21 @@ func TestAuthService_Login(t *testing.T) {
 				sessionRepo,
 				rateLimiterRepo,
 				nil,
+				nil,
+				nil,
 				tokenManager,
+				nil,
 				cfg,
 				logger,
 			)
 
 			// Execute
-			user, token, expiresAt, err := service.Login(context.Background(), tt.email, tt.password, tt.ipAddress)
+			result, err := service.Login(context.Background(), tt.email, tt.password, tt.ipAddress, "")
 
 			// Assert
 			if tt.expectedError != nil {
 				assert.Error(t, err)
-				assert.Nil(t, user)
-				assert.Empty(t, token)
+				assert.Nil(t, result)
 				serviceErr, ok := err.(*errors.ServiceError)
 				if ok {
 					expectedErr := tt.expectedError.(*errors.ServiceError)
@@ -415,9 +606,9 @@ func TestAuthService_Login(t *testing.T) {
 				}
 			} else {
 				assert.NoError(t, err)
-				assert.NotNil(t, user)
-				assert.NotEmpty(t, token)
-				assert.False(t, expiresAt.IsZero())
+				assert.NotNil(t, result.User)
+				assert.NotEmpty(t, result.Token)
+				assert.False(t, result.ExpiresAt.IsZero())
 			}
 
 			userRepo.AssertExpectations(t)
@@ -426,3 +617,950 @@ func TestAuthService_Login(t *testing.T) {
 		})
 	}
 }
+
+// newTestTokenManager creates a token manager backed by a freshly generated key pair
+func newTestTokenManager(t *testing.T) *auth.TokenManager {
+	t.Helper()
+
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKeyBytes, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	privatePath := filepath.Join(dir, "jwt-private.pem")
+	publicPath := filepath.Join(dir, "jwt-public.pem")
+
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyBytes})
+	if err := os.WriteFile(privatePath, privatePEM, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(publicPath, publicPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tokenManager, err := auth.NewTokenManager(privatePath, publicPath, time.Hour, "user-auth-service", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tokenManager
+}
+
+// Test Login warms the permission cache
+func TestAuthService_Login_WarmsPermissionCache(t *testing.T) {
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("ValidPass123!"), bcrypt.MinCost)
+
+	userRepo := new(MockUserRepository)
+	rateLimiterRepo := new(MockRateLimiterRepository)
+	sessionRepo := new(MockSessionRepository)
+	cacheRepo := new(MockPermissionCacheRepository)
+
+	rateLimiterRepo.On("IsLocked", mock.Anything, "test@example.com").Return(false, time.Duration(0), nil)
+	userRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(&domain.User{
+		ID:           "user-123",
+		Email:        "test@example.com",
+		PasswordHash: string(passwordHash),
+		IsActive:     true,
+		Roles: []domain.Role{
+			{Name: "member", Permissions: []domain.Permission{{Name: "users:read"}}},
+		},
+	}, nil)
+	rateLimiterRepo.On("ResetAttempts", mock.Anything, "test@example.com").Return(nil)
+	sessionRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Session")).Return(nil)
+	sessionRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*domain.RefreshToken")).Return(nil)
+	userRepo.On("UpdateLastLogin", mock.Anything, "user-123", mock.AnythingOfType("time.Time"), "192.168.1.1").Return(nil)
+	cacheRepo.On("SetUserPermissions", mock.Anything, "user-123", []string{"users:read"}, 5*time.Minute).Return(nil)
+
+	logger, _ := logging.NewLogger("error")
+	cfg := &config.Config{
+		Security: config.SecurityConfig{
+			BcryptCost:          bcrypt.MinCost,
+			MaxLoginAttempts:    5,
+			PermissionCacheTTL:  5 * time.Minute,
+			SessionExpiration:   24 * time.Hour,
+			WarmPermissionCache: true,
+		},
+	}
+
+	service := NewAuthService(
+		userRepo,
+		nil,
+		sessionRepo,
+		rateLimiterRepo,
+		nil,
+		cacheRepo,
+		nil,
+		newTestTokenManager(t),
+		nil,
+		cfg,
+		logger,
+	)
+
+	result, err := service.Login(context.Background(), "test@example.com", "ValidPass123!", "192.168.1.1", "")
+
+	assert.NoError(t, err)
+	assert.NotNil(t, result.User)
+	assert.NotEmpty(t, result.Token)
+	cacheRepo.AssertExpectations(t)
+}
+
+// Test ValidateToken rejects tokens of deactivated users
+func TestAuthService_ValidateToken_RejectsDeactivatedUser(t *testing.T) {
+	user := &domain.User{ID: "user-123", Email: "test@example.com", IsActive: false}
+	tokenManager := newTestTokenManager(t)
+	token, err := tokenManager.GenerateToken(user, nil, "session-1", 0)
+	require.NoError(t, err)
+
+	userRepo := new(MockUserRepository)
+	sessionRepo := new(MockSessionRepository)
+	userRepo.On("FindByID", mock.Anything, "user-123").Return(user, nil)
+	sessionRepo.On("IsRevoked", mock.Anything, mock.Anything).Return(false, nil)
+	sessionRepo.On("Get", mock.Anything, "session-1").Return(&domain.Session{SessionID: "session-1", UserID: "user-123"}, nil)
+	sessionRepo.On("ExtendExpiration", mock.Anything, "session-1", mock.Anything).Return(nil)
+
+	logger, _ := logging.NewLogger("error")
+	service := NewAuthService(userRepo, nil, sessionRepo, nil, nil, nil, nil, tokenManager, nil, &config.Config{}, logger)
+
+	_, err = service.ValidateToken(context.Background(), token)
+	serviceErr, ok := err.(*errors.ServiceError)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInvalidToken, serviceErr.Code)
+}
+
+// Test Login omits the refresh token for configured client types
+func TestAuthService_Login_OmitsRefreshTokenForConfiguredClients(t *testing.T) {
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("ValidPass123!"), bcrypt.MinCost)
+
+	userRepo := new(MockUserRepository)
+	rateLimiterRepo := new(MockRateLimiterRepository)
+	sessionRepo := new(MockSessionRepository)
+
+	rateLimiterRepo.On("IsLocked", mock.Anything, "test@example.com").Return(false, time.Duration(0), nil)
+	userRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(&domain.User{
+		ID:           "user-123",
+		Email:        "test@example.com",
+		PasswordHash: string(passwordHash),
+		IsActive:     true,
+	}, nil)
+	rateLimiterRepo.On("ResetAttempts", mock.Anything, "test@example.com").Return(nil)
+	sessionRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Session")).Return(nil)
+	sessionRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*domain.RefreshToken")).Return(nil)
+	userRepo.On("UpdateLastLogin", mock.Anything, "user-123", mock.AnythingOfType("time.Time"), "192.168.1.1").Return(nil)
+
+	logger, _ := logging.NewLogger("error")
+	cfg := &config.Config{
+		Security: config.SecurityConfig{
+			BcryptCost:            bcrypt.MinCost,
+			MaxLoginAttempts:      5,
+			SessionExpiration:     24 * time.Hour,
+			NoRefreshTokenClients: []string{"browser"},
+		},
+	}
+
+	service := NewAuthService(
+		userRepo,
+		nil,
+		sessionRepo,
+		rateLimiterRepo,
+		nil,
+		nil,
+		nil,
+		newTestTokenManager(t),
+		nil,
+		cfg,
+		logger,
+	)
+
+	tests := []struct {
+		clientType       string
+		wantRefreshToken bool
+	}{
+		{clientType: "browser", wantRefreshToken: false},
+		{clientType: "Browser", wantRefreshToken: false},
+		{clientType: "native", wantRefreshToken: true},
+		{clientType: "", wantRefreshToken: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.clientType, func(t *testing.T) {
+			result, err := service.Login(context.Background(), "test@example.com", "ValidPass123!", "192.168.1.1", tt.clientType)
+			require.NoError(t, err)
+			assert.NotEmpty(t, result.Token)
+			if tt.wantRefreshToken {
+				assert.NotEmpty(t, result.RefreshToken)
+				assert.NotEqual(t, result.Token, result.RefreshToken)
+			} else {
+				assert.Empty(t, result.RefreshToken)
+			}
+		})
+	}
+}
+
+// Test RefreshToken issues a new access token and rotates the refresh token
+func TestAuthService_RefreshToken(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	sessionRepo := new(MockSessionRepository)
+
+	user := &domain.User{ID: "user-123", Email: "test@example.com", IsActive: true}
+	refresh := &domain.RefreshToken{UserID: "user-123", SessionID: "session-1", ClientType: "native"}
+
+	// The first redemption consumes the token; replaying it finds nothing
+	sessionRepo.On("ConsumeRefreshToken", mock.Anything, "refresh-1").Return(refresh, nil).Once()
+	sessionRepo.On("ConsumeRefreshToken", mock.Anything, "refresh-1").Return(nil, repository.ErrNotFound)
+	userRepo.On("FindByID", mock.Anything, "user-123").Return(user, nil)
+	sessionRepo.On("Get", mock.Anything, "session-1").Return(&domain.Session{
+		SessionID: "session-1",
+		UserID:    "user-123",
+		IPAddress: "192.168.1.1",
+	}, nil)
+	sessionRepo.On("Create", mock.Anything, mock.MatchedBy(func(session *domain.Session) bool {
+		return session.SessionID == "session-1" && session.IPAddress == "192.168.1.1"
+	})).Return(nil)
+	var stored *domain.RefreshToken
+	sessionRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*domain.RefreshToken")).
+		Run(func(args mock.Arguments) { stored = args.Get(2).(*domain.RefreshToken) }).
+		Return(nil)
+
+	logger, _ := logging.NewLogger("error")
+	cfg := &config.Config{
+		Security: config.SecurityConfig{
+			SessionExpiration: 24 * time.Hour,
+			RefreshTokenTTL:   30 * 24 * time.Hour,
+		},
+	}
+	tokenManager := newTestTokenManager(t)
+	service := NewAuthService(userRepo, nil, sessionRepo, nil, nil, nil, nil, tokenManager, nil, cfg, logger)
+
+	result, err := service.RefreshToken(context.Background(), "refresh-1")
+	require.NoError(t, err)
+	assert.NotEmpty(t, result.Token)
+	assert.NotEmpty(t, result.RefreshToken)
+	assert.NotEqual(t, "refresh-1", result.RefreshToken)
+	assert.WithinDuration(t, time.Now().Add(time.Hour), result.ExpiresAt, time.Minute)
+
+	claims, err := tokenManager.ValidateToken(result.Token)
+	require.NoError(t, err)
+	assert.Equal(t, "session-1", claims.SessionID)
+
+	require.NotNil(t, stored)
+	assert.Equal(t, "session-1", stored.SessionID)
+	assert.Equal(t, "native", stored.ClientType)
+	assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), stored.ExpiresAt, time.Minute)
+
+	_, err = service.RefreshToken(context.Background(), "refresh-1")
+	serviceErr, ok := err.(*errors.ServiceError)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInvalidToken, serviceErr.Code)
+}
+
+// Test RefreshToken refuses deactivated users
+func TestAuthService_RefreshToken_InactiveUser(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	sessionRepo := new(MockSessionRepository)
+
+	sessionRepo.On("ConsumeRefreshToken", mock.Anything, "refresh-1").Return(&domain.RefreshToken{UserID: "user-123", SessionID: "session-1"}, nil)
+	userRepo.On("FindByID", mock.Anything, "user-123").Return(&domain.User{ID: "user-123", IsActive: false}, nil)
+
+	logger, _ := logging.NewLogger("error")
+	service := NewAuthService(userRepo, nil, sessionRepo, nil, nil, nil, nil, newTestTokenManager(t), nil, &config.Config{}, logger)
+
+	_, err := service.RefreshToken(context.Background(), "refresh-1")
+	serviceErr, ok := err.(*errors.ServiceError)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrCodePermissionDenied, serviceErr.Code)
+	sessionRepo.AssertNotCalled(t, "CreateRefreshToken", mock.Anything, mock.Anything, mock.Anything)
+}
+
+// Test Logout deletes the session's refresh token
+func TestAuthService_Logout_DeletesRefreshToken(t *testing.T) {
+	sessionRepo := new(MockSessionRepository)
+	tokenManager := newTestTokenManager(t)
+
+	token, err := tokenManager.GenerateToken(&domain.User{ID: "user-123", Email: "test@example.com"}, nil, "session-1", 0)
+	require.NoError(t, err)
+
+	sessionRepo.On("Delete", mock.Anything, "session-1").Return(nil)
+	sessionRepo.On("RevokeToken", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("time.Time")).Return(nil)
+	sessionRepo.On("DeleteRefreshToken", mock.Anything, "user-123", "session-1").Return(nil)
+
+	logger, _ := logging.NewLogger("error")
+	service := NewAuthService(nil, nil, sessionRepo, nil, nil, nil, nil, tokenManager, nil, &config.Config{}, logger)
+
+	require.NoError(t, service.Logout(context.Background(), token))
+	sessionRepo.AssertExpectations(t)
+}
+
+// loginFailureCount reads the login failure counter for a reason from reg
+func loginFailureCount(t *testing.T, reg *prometheus.Registry, reason string) float64 {
+	t.Helper()
+
+	families, err := reg.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "user_auth_login_failures_total" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			for _, label := range metric.GetLabel() {
+				if label.GetName() == "reason" && label.GetValue() == reason {
+					return metric.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return 0
+}
+
+// Test a failed login increments the counter for its reason
+func TestAuthService_Login_FailureMetrics(t *testing.T) {
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("ValidPass123!"), bcrypt.MinCost)
+
+	tests := []struct {
+		name       string
+		email      string
+		password   string
+		setupMocks func(*MockUserRepository, *MockRateLimiterRepository)
+		reason     string
+	}{
+		{
+			name:     "invalid password",
+			email:    "test@example.com",
+			password: "WrongPassword123!",
+			setupMocks: func(userRepo *MockUserRepository, rateLimiter *MockRateLimiterRepository) {
+				rateLimiter.On("IsLocked", mock.Anything, "test@example.com").Return(false, time.Duration(0), nil)
+				userRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(&domain.User{
+					ID:           "user-123",
+					Email:        "test@example.com",
+					PasswordHash: string(passwordHash),
+					IsActive:     true,
+				}, nil)
+				rateLimiter.On("RecordFailedAttempt", mock.Anything, "test@example.com").Return(nil)
+				rateLimiter.On("GetFailedAttempts", mock.Anything, "test@example.com").Return(1, nil)
+			},
+			reason: metrics.LoginFailureInvalidPassword,
+		},
+		{
+			name:     "unknown user",
+			email:    "missing@example.com",
+			password: "ValidPass123!",
+			setupMocks: func(userRepo *MockUserRepository, rateLimiter *MockRateLimiterRepository) {
+				rateLimiter.On("IsLocked", mock.Anything, "missing@example.com").Return(false, time.Duration(0), nil)
+				userRepo.On("FindByEmail", mock.Anything, "missing@example.com").Return(nil, gorm.ErrRecordNotFound)
+				rateLimiter.On("RecordFailedAttempt", mock.Anything, "missing@example.com").Return(nil)
+			},
+			reason: metrics.LoginFailureNotFound,
+		},
+		{
+			name:     "locked account",
+			email:    "locked@example.com",
+			password: "ValidPass123!",
+			setupMocks: func(userRepo *MockUserRepository, rateLimiter *MockRateLimiterRepository) {
+				rateLimiter.On("IsLocked", mock.Anything, "locked@example.com").Return(true, 30*time.Minute, nil)
+			},
+			reason: metrics.LoginFailureLocked,
+		},
+	}
+
+	reasons := []string{metrics.LoginFailureInvalidPassword, metrics.LoginFailureNotFound, metrics.LoginFailureLocked}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := new(MockUserRepository)
+			rateLimiterRepo := new(MockRateLimiterRepository)
+			tt.setupMocks(userRepo, rateLimiterRepo)
+
+			reg := prometheus.NewRegistry()
+			logger, _ := logging.NewLogger("error")
+			cfg := &config.Config{
+				Security: config.SecurityConfig{
+					BcryptCost:       bcrypt.MinCost,
+					MaxLoginAttempts: 5,
+					LockoutDuration:  30 * time.Minute,
+				},
+			}
+
+			service := NewAuthService(
+				userRepo,
+				nil,
+				nil,
+				rateLimiterRepo,
+				nil,
+				nil,
+				nil,
+				nil,
+				metrics.NewAuthMetrics(reg),
+				cfg,
+				logger,
+			)
+
+			_, err := service.Login(context.Background(), tt.email, tt.password, "192.168.1.1", "")
+			assert.Error(t, err)
+
+			for _, reason := range reasons {
+				want := 0.0
+				if reason == tt.reason {
+					want = 1
+				}
+				assert.Equal(t, want, loginFailureCount(t, reg, reason), "login failures for %s", reason)
+			}
+			userRepo.AssertExpectations(t)
+			rateLimiterRepo.AssertExpectations(t)
+		})
+	}
+}
+
+type MockLoginHistoryRepository struct {
+	mock.Mock
+}
+
+func (m *MockLoginHistoryRepository) RecordLoginIP(ctx context.Context, userID, ipAddress string, at time.Time, retention time.Duration) error {
+	args := m.Called(ctx, userID, ipAddress, at, retention)
+	return args.Error(0)
+}
+
+func (m *MockLoginHistoryRepository) LastSeenIP(ctx context.Context, userID, ipAddress string) (time.Time, bool, error) {
+	args := m.Called(ctx, userID, ipAddress)
+	return args.Get(0).(time.Time), args.Bool(1), args.Error(2)
+}
+
+// newLoginTestService builds an AuthService whose user logs in successfully
+func newLoginTestService(t *testing.T, user *domain.User, userRepo *MockUserRepository, history *MockLoginHistoryRepository) *AuthService {
+	t.Helper()
+
+	rateLimiterRepo := new(MockRateLimiterRepository)
+	sessionRepo := new(MockSessionRepository)
+	rateLimiterRepo.On("IsLocked", mock.Anything, user.Email).Return(false, time.Duration(0), nil)
+	rateLimiterRepo.On("ResetAttempts", mock.Anything, user.Email).Return(nil)
+	sessionRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Session")).Return(nil)
+	sessionRepo.On("CreateRefreshToken", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*domain.RefreshToken")).Return(nil)
+	userRepo.On("FindByEmail", mock.Anything, user.Email).Return(user, nil)
+
+	logger, _ := logging.NewLogger("error")
+	cfg := &config.Config{
+		Security: config.SecurityConfig{
+			BcryptCost:        bcrypt.MinCost,
+			MaxLoginAttempts:  5,
+			SessionExpiration: 24 * time.Hour,
+			KnownDeviceWindow: 30 * 24 * time.Hour,
+		},
+	}
+
+	return NewAuthService(
+		userRepo,
+		nil,
+		sessionRepo,
+		rateLimiterRepo,
+		nil,
+		nil,
+		history,
+		newTestTokenManager(t),
+		nil,
+		cfg,
+		logger,
+	)
+}
+
+// stubPermissionResolver returns a fixed permission set
+type stubPermissionResolver struct {
+	permissions []string
+}
+
+func (r *stubPermissionResolver) GetUserPermissions(ctx context.Context, userID string) ([]string, error) {
+	return r.permissions, nil
+}
+
+// Test Login embeds the resolved permissions, so a deny narrows the token's
+// wildcard grants too
+func TestAuthService_Login_EmbedsResolvedPermissions(t *testing.T) {
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("ValidPass123!"), bcrypt.MinCost)
+	user := &domain.User{
+		ID:           "user-123",
+		Email:        "test@example.com",
+		PasswordHash: string(passwordHash),
+		IsActive:     true,
+		Roles: []domain.Role{
+			{Name: "admin", Permissions: []domain.Permission{{Name: "users:*"}}},
+			{Name: "restricted", DeniedPermissions: []domain.Permission{{Name: "users:delete"}}},
+		},
+	}
+
+	userRepo := new(MockUserRepository)
+	history := new(MockLoginHistoryRepository)
+	userRepo.On("UpdateLastLogin", mock.Anything, "user-123", mock.AnythingOfType("time.Time"), "203.0.113.7").Return(nil)
+	history.On("RecordLoginIP", mock.Anything, "user-123", "203.0.113.7", mock.AnythingOfType("time.Time"), 30*24*time.Hour).Return(nil)
+
+	service := newLoginTestService(t, user, userRepo, history)
+	service.SetPermissionResolver(&stubPermissionResolver{permissions: []string{"users:read", "users:write"}})
+
+	result, err := service.Login(context.Background(), "test@example.com", "ValidPass123!", "203.0.113.7", "")
+	require.NoError(t, err)
+
+	claims, err := service.tokenManager.ValidateToken(result.Token)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users:read", "users:write"}, claims.Permissions)
+}
+
+// Test Login flags logins from IPs not seen recently
+func TestAuthService_Login_NewDeviceDetection(t *testing.T) {
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("ValidPass123!"), bcrypt.MinCost)
+	lastLogin := time.Now().Add(-2 * time.Hour)
+
+	tests := []struct {
+		name        string
+		lastLoginAt *time.Time
+		lastLoginIP string
+		loginIP     string
+		setupMocks  func(*MockLoginHistoryRepository)
+		expectNew   bool
+	}{
+		{
+			name:        "first login is not flagged",
+			lastLoginAt: nil,
+			loginIP:     "203.0.113.7",
+			setupMocks:  func(history *MockLoginHistoryRepository) {},
+			expectNew:   false,
+		},
+		{
+			name:        "same IP as last login",
+			lastLoginAt: &lastLogin,
+			lastLoginIP: "203.0.113.7",
+			loginIP:     "203.0.113.7",
+			setupMocks:  func(history *MockLoginHistoryRepository) {},
+			expectNew:   false,
+		},
+		{
+			name:        "IP never seen before",
+			lastLoginAt: &lastLogin,
+			lastLoginIP: "203.0.113.7",
+			loginIP:     "198.51.100.20",
+			setupMocks: func(history *MockLoginHistoryRepository) {
+				history.On("LastSeenIP", mock.Anything, "user-123", "198.51.100.20").Return(time.Time{}, false, nil)
+			},
+			expectNew: true,
+		},
+		{
+			name:        "IP seen within the window",
+			lastLoginAt: &lastLogin,
+			lastLoginIP: "203.0.113.7",
+			loginIP:     "198.51.100.20",
+			setupMocks: func(history *MockLoginHistoryRepository) {
+				history.On("LastSeenIP", mock.Anything, "user-123", "198.51.100.20").Return(time.Now().Add(-72*time.Hour), true, nil)
+			},
+			expectNew: false,
+		},
+		{
+			name:        "IP last seen outside the window",
+			lastLoginAt: &lastLogin,
+			lastLoginIP: "203.0.113.7",
+			loginIP:     "198.51.100.20",
+			setupMocks: func(history *MockLoginHistoryRepository) {
+				history.On("LastSeenIP", mock.Anything, "user-123", "198.51.100.20").Return(time.Now().Add(-60*24*time.Hour), true, nil)
+			},
+			expectNew: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &domain.User{
+				ID:           "user-123",
+				Email:        "test@example.com",
+				PasswordHash: string(passwordHash),
+				IsActive:     true,
+				LastLoginAt:  tt.lastLoginAt,
+				LastLoginIP:  tt.lastLoginIP,
+			}
+
+			userRepo := new(MockUserRepository)
+			history := new(MockLoginHistoryRepository)
+			tt.setupMocks(history)
+			userRepo.On("UpdateLastLogin", mock.Anything, "user-123", mock.AnythingOfType("time.Time"), tt.loginIP).Return(nil)
+			history.On("RecordLoginIP", mock.Anything, "user-123", tt.loginIP, mock.AnythingOfType("time.Time"), 30*24*time.Hour).Return(nil)
+
+			service := newLoginTestService(t, user, userRepo, history)
+			result, err := service.Login(context.Background(), "test@example.com", "ValidPass123!", tt.loginIP, "")
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectNew, result.NewDevice)
+			history.AssertExpectations(t)
+		})
+	}
+}
+
+// Test Login records the last login and returns the previous one
+func TestAuthService_Login_UpdatesLastLogin(t *testing.T) {
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("ValidPass123!"), bcrypt.MinCost)
+	previousLogin := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	user := &domain.User{
+		ID:           "user-123",
+		Email:        "test@example.com",
+		PasswordHash: string(passwordHash),
+		IsActive:     true,
+		LastLoginAt:  &previousLogin,
+		LastLoginIP:  "203.0.113.7",
+	}
+
+	userRepo := new(MockUserRepository)
+	history := new(MockLoginHistoryRepository)
+	userRepo.On("UpdateLastLogin", mock.Anything, "user-123", mock.AnythingOfType("time.Time"), "203.0.113.7").Return(nil)
+	history.On("RecordLoginIP", mock.Anything, "user-123", "203.0.113.7", mock.AnythingOfType("time.Time"), 30*24*time.Hour).Return(nil)
+
+	service := newLoginTestService(t, user, userRepo, history)
+	before := time.Now()
+	result, err := service.Login(context.Background(), "test@example.com", "ValidPass123!", "203.0.113.7", "")
+
+	assert.NoError(t, err)
+	assert.False(t, result.NewDevice)
+	if assert.NotNil(t, result.PreviousLoginAt) {
+		assert.True(t, result.PreviousLoginAt.Equal(previousLogin))
+	}
+	assert.Equal(t, "203.0.113.7", result.PreviousLoginIP)
+	if assert.NotNil(t, result.User.LastLoginAt) {
+		assert.False(t, result.User.LastLoginAt.Before(before))
+	}
+	userRepo.AssertExpectations(t)
+	history.AssertExpectations(t)
+}
+
+type MockSecurityNotifier struct {
+	mock.Mock
+}
+
+func (m *MockSecurityNotifier) NotifyUser(ctx context.Context, userID, eventType string, payload interface{}) error {
+	args := m.Called(ctx, userID, eventType, payload)
+	return args.Error(0)
+}
+
+// Test locking an account alerts its owner without affecting the login result
+func TestAuthService_Login_LockoutNotifiesUser(t *testing.T) {
+	passwordHash, _ := bcrypt.GenerateFromPassword([]byte("ValidPass123!"), bcrypt.MinCost)
+
+	tests := []struct {
+		name      string
+		notifyErr error
+	}{
+		{name: "notification sent"},
+		{name: "notification failure is not fatal", notifyErr: fmt.Errorf("notifications-service unavailable")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := new(MockUserRepository)
+			rateLimiterRepo := new(MockRateLimiterRepository)
+			notifier := new(MockSecurityNotifier)
+
+			rateLimiterRepo.On("IsLocked", mock.Anything, "test@example.com").Return(false, time.Duration(0), nil)
+			userRepo.On("FindByEmail", mock.Anything, "test@example.com").Return(&domain.User{
+				ID:           "user-123",
+				Email:        "test@example.com",
+				PasswordHash: string(passwordHash),
+				IsActive:     true,
+			}, nil)
+			rateLimiterRepo.On("RecordFailedAttempt", mock.Anything, "test@example.com").Return(nil)
+			rateLimiterRepo.On("GetFailedAttempts", mock.Anything, "test@example.com").Return(5, nil)
+			userRepo.On("Update", mock.Anything, mock.MatchedBy(func(user *domain.User) bool {
+				return user.IsLocked && user.LockedUntil != nil
+			})).Return(nil)
+			rateLimiterRepo.On("LockAccount", mock.Anything, "test@example.com", 30*time.Minute).Return(nil)
+			notifier.On("NotifyUser", mock.Anything, "user-123", EventAccountLocked, mock.MatchedBy(func(payload map[string]interface{}) bool {
+				return payload["ip_address"] == "203.0.113.7" && payload["attempts"] == 5
+			})).Return(tt.notifyErr)
+
+			logger, _ := logging.NewLogger("error")
+			cfg := &config.Config{
+				Security: config.SecurityConfig{
+					BcryptCost:       bcrypt.MinCost,
+					MaxLoginAttempts: 5,
+					LockoutDuration:  30 * time.Minute,
+				},
+			}
+
+			service := NewAuthService(
+				userRepo,
+				nil,
+				nil,
+				rateLimiterRepo,
+				nil,
+				nil,
+				nil,
+				nil,
+				nil,
+				cfg,
+				logger,
+			)
+			service.SetSecurityNotifier(notifier)
+
+			result, err := service.Login(context.Background(), "test@example.com", "WrongPassword123!", "203.0.113.7", "")
+
+			assert.Nil(t, result)
+			serviceErr, ok := err.(*errors.ServiceError)
+			require.True(t, ok)
+			assert.Equal(t, errors.ErrCodeInvalidCredentials, serviceErr.Code)
+			userRepo.AssertExpectations(t)
+			rateLimiterRepo.AssertExpectations(t)
+			notifier.AssertExpectations(t)
+		})
+	}
+}
+
+type MockDeactivationNotifier struct {
+	MockSecurityNotifier
+}
+
+func (m *MockDeactivationNotifier) DisconnectUser(ctx context.Context, userID, reason string) error {
+	args := m.Called(ctx, userID, reason)
+	return args.Error(0)
+}
+
+// Test deactivating a user kills their sessions, notifies them and drops their real-time connections
+func TestAuthService_DeactivateUser(t *testing.T) {
+	tests := []struct {
+		name          string
+		disconnectErr error
+	}{
+		{name: "user disconnected"},
+		{name: "disconnect failure is not fatal", disconnectErr: fmt.Errorf("notifications-service unavailable")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := new(MockUserRepository)
+			sessionRepo := new(MockSessionRepository)
+			notifier := new(MockDeactivationNotifier)
+
+			userRepo.On("FindByID", mock.Anything, "admin-1").Return(&domain.User{
+				ID:    "admin-1",
+				Roles: []domain.Role{{Name: "admin"}},
+			}, nil)
+			userRepo.On("FindByID", mock.Anything, "user-123").Return(&domain.User{
+				ID:       "user-123",
+				Email:    "test@example.com",
+				IsActive: true,
+			}, nil)
+			userRepo.On("Update", mock.Anything, mock.MatchedBy(func(user *domain.User) bool {
+				return user.ID == "user-123" && !user.IsActive
+			})).Return(nil)
+			sessionRepo.On("DeleteAllForUser", mock.Anything, "user-123").Return(nil)
+			notifier.On("NotifyUser", mock.Anything, "user-123", EventAccountDeactivated, mock.MatchedBy(func(payload map[string]interface{}) bool {
+				return payload["reason"] == "terms violation"
+			})).Return(nil)
+			notifier.On("DisconnectUser", mock.Anything, "user-123", "account_deactivated").Return(tt.disconnectErr)
+
+			logger, _ := logging.NewLogger("error")
+			service := NewAuthService(userRepo, nil, sessionRepo, nil, nil, nil, nil, nil, nil, &config.Config{}, logger)
+			service.SetDeactivationNotifier(notifier)
+
+			err := service.DeactivateUser(context.Background(), "admin-1", "user-123", "terms violation")
+
+			assert.NoError(t, err)
+			userRepo.AssertExpectations(t)
+			sessionRepo.AssertExpectations(t)
+			notifier.AssertExpectations(t)
+		})
+	}
+}
+
+// Test only admins may deactivate users
+func TestAuthService_DeactivateUser_RequiresAdmin(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	notifier := new(MockDeactivationNotifier)
+
+	userRepo.On("FindByID", mock.Anything, "member-1").Return(&domain.User{
+		ID:    "member-1",
+		Roles: []domain.Role{{Name: "member"}},
+	}, nil)
+
+	logger, _ := logging.NewLogger("error")
+	service := NewAuthService(userRepo, nil, nil, nil, nil, nil, nil, nil, nil, &config.Config{}, logger)
+	service.SetDeactivationNotifier(notifier)
+
+	err := service.DeactivateUser(context.Background(), "member-1", "user-123", "")
+
+	serviceErr, ok := err.(*errors.ServiceError)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrCodePermissionDenied, serviceErr.Code)
+	notifier.AssertNotCalled(t, "DisconnectUser", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAuthService_GetUser(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	userRepo.On("FindByID", mock.Anything, "user-123").Return(&domain.User{
+		ID:    "user-123",
+		Email: "test@example.com",
+		Roles: []domain.Role{{Name: "member", Permissions: []domain.Permission{{Name: "users:read"}}}},
+	}, nil)
+	userRepo.On("FindByID", mock.Anything, "missing").Return(nil, gorm.ErrRecordNotFound)
+
+	logger, _ := logging.NewLogger("error")
+	service := NewAuthService(userRepo, nil, nil, nil, nil, nil, nil, nil, nil, &config.Config{}, logger)
+
+	user, err := service.GetUser(context.Background(), "user-123")
+	require.NoError(t, err)
+	assert.Equal(t, "test@example.com", user.Email)
+	assert.Equal(t, []string{"users:read"}, user.GetPermissions())
+
+	_, err = service.GetUser(context.Background(), "missing")
+	serviceErr, ok := err.(*errors.ServiceError)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrCodeUserNotFound, serviceErr.Code)
+	assert.Equal(t, codes.NotFound, status.Code(errors.MapToGRPCError(err)))
+}
+
+func TestAuthService_GetUsers(t *testing.T) {
+	userRepo := new(MockUserRepository)
+
+	// Duplicates collapse into a single lookup; unknown IDs are simply absent
+	userRepo.On("FindByIDs", mock.Anything, []string{"user-1", "user-2", "missing"}).Return([]domain.User{
+		{ID: "user-1"},
+		{ID: "user-2"},
+	}, nil).Once()
+
+	logger, _ := logging.NewLogger("error")
+	service := NewAuthService(userRepo, nil, nil, nil, nil, nil, nil, nil, nil, &config.Config{}, logger)
+
+	users, err := service.GetUsers(context.Background(), []string{"user-1", "user-2", "user-1", "missing"})
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+	userRepo.AssertExpectations(t)
+
+	tooMany := make([]string, maxGetUsers+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("user-%d", i)
+	}
+	for name, ids := range map[string][]string{"none": nil, "empty ID": {"user-1", ""}, "too many": tooMany} {
+		_, err := service.GetUsers(context.Background(), ids)
+		serviceErr, ok := err.(*errors.ServiceError)
+		require.True(t, ok, name)
+		assert.Equal(t, errors.ErrCodeInvalidInput, serviceErr.Code, name)
+	}
+}
+
+func TestAuthService_ListUsers(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	userRepo.On("FindByID", mock.Anything, "admin-1").Return(&domain.User{ID: "admin-1", Roles: []domain.Role{{Name: "admin"}}}, nil)
+	userRepo.On("FindByID", mock.Anything, "member-1").Return(&domain.User{ID: "member-1", Roles: []domain.Role{{Name: "member"}}}, nil)
+	userRepo.On("List", mock.Anything, pagination.Page{Limit: 2, Offset: 0}).Return([]domain.User{{ID: "user-1"}, {ID: "user-2"}}, int64(3), nil).Once()
+
+	logger, _ := logging.NewLogger("error")
+	service := NewAuthService(userRepo, nil, nil, nil, nil, nil, nil, nil, nil, &config.Config{}, logger)
+
+	users, page, err := service.ListUsers(context.Background(), "admin-1", pagination.Request{Limit: 2})
+	require.NoError(t, err)
+	assert.Len(t, users, 2)
+	assert.Equal(t, int64(3), page.Total)
+	assert.Equal(t, pagination.EncodeCursor(2), page.NextCursor)
+
+	_, _, err = service.ListUsers(context.Background(), "member-1", pagination.Request{})
+	serviceErr, ok := err.(*errors.ServiceError)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrCodePermissionDenied, serviceErr.Code)
+
+	_, _, err = service.ListUsers(context.Background(), "admin-1", pagination.Request{Limit: -1})
+	serviceErr, ok = err.(*errors.ServiceError)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInvalidInput, serviceErr.Code)
+	userRepo.AssertExpectations(t)
+}
+
+func TestAuthService_UpdateProfile(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	stored := &domain.User{ID: "user-123", Email: "test@example.com", Name: "Old Name"}
+	userRepo.On("FindByID", mock.Anything, "user-123").Return(stored, nil)
+	userRepo.On("Update", mock.Anything, mock.MatchedBy(func(u *domain.User) bool {
+		return u.ID == "user-123" && u.Name == "New Name"
+	})).Return(nil).Once()
+
+	logger, _ := logging.NewLogger("error")
+	service := NewAuthService(userRepo, nil, nil, nil, nil, nil, nil, nil, nil, &config.Config{}, logger)
+
+	user, err := service.UpdateProfile(context.Background(), "user-123", "New Name")
+	require.NoError(t, err)
+	assert.Equal(t, "New Name", user.Name)
+	assert.Equal(t, "test@example.com", user.Email)
+	userRepo.AssertExpectations(t)
+
+	// Invalid names are rejected before anything is written
+	_, err = service.UpdateProfile(context.Background(), "user-123", "A")
+	serviceErr, ok := err.(*errors.ServiceError)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInvalidInput, serviceErr.Code)
+	userRepo.AssertNumberOfCalls(t, "Update", 1)
+}
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	const currentPassword = "OldP@ssw0rd!"
+	const newPassword = "NewP@ssw0rd!"
+
+	tests := []struct {
+		name        string
+		keepSession bool
+		current     string
+		next        string
+		wantCode    errors.ErrorCode
+	}{
+		{name: "keeps current session", keepSession: true, current: currentPassword, next: newPassword},
+		{name: "ends every session", keepSession: false, current: currentPassword, next: newPassword},
+		{name: "wrong current password", keepSession: true, current: "Wr0ngP@ssword!", next: newPassword, wantCode: errors.ErrCodeInvalidCredentials},
+		{name: "weak new password", keepSession: true, current: currentPassword, next: "weak", wantCode: errors.ErrCodeWeakPassword},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := bcrypt.GenerateFromPassword([]byte(currentPassword), bcrypt.MinCost)
+			require.NoError(t, err)
+			user := &domain.User{ID: "user-123", Email: "test@example.com", PasswordHash: string(hash), IsActive: true}
+
+			tokenManager := newTestTokenManager(t)
+			token, err := tokenManager.GenerateToken(user, user.GetPermissions(), "session-1", 0)
+			require.NoError(t, err)
+
+			userRepo := new(MockUserRepository)
+			sessionRepo := new(MockSessionRepository)
+			userRepo.On("FindByID", mock.Anything, "user-123").Return(user, nil)
+			sessionRepo.On("IsRevoked", mock.Anything, mock.Anything).Return(false, nil)
+			sessionRepo.On("Get", mock.Anything, "session-1").Return(&domain.Session{SessionID: "session-1", UserID: "user-123"}, nil)
+			sessionRepo.On("ExtendExpiration", mock.Anything, "session-1", mock.Anything).Return(nil)
+			if tt.wantCode == "" {
+				userRepo.On("Update", mock.Anything, mock.MatchedBy(func(u *domain.User) bool {
+					return bcrypt.CompareHashAndPassword([]byte(u.PasswordHash), []byte(newPassword)) == nil
+				})).Return(nil).Once()
+				if tt.keepSession {
+					sessionRepo.On("DeleteAllForUserExcept", mock.Anything, "user-123", "session-1").Return(nil).Once()
+				} else {
+					sessionRepo.On("DeleteAllForUser", mock.Anything, "user-123").Return(nil).Once()
+				}
+			}
+
+			cfg := &config.Config{
+				Security: config.SecurityConfig{
+					BcryptCost:                  bcrypt.MinCost,
+					SessionExpiration:           time.Hour,
+					KeepSessionOnPasswordChange: tt.keepSession,
+				},
+			}
+			logger, _ := logging.NewLogger("error")
+			service := NewAuthService(userRepo, nil, sessionRepo, nil, nil, nil, nil, tokenManager, nil, cfg, logger)
+
+			err = service.ChangePassword(context.Background(), token, tt.current, tt.next)
+			if tt.wantCode != "" {
+				serviceErr, ok := err.(*errors.ServiceError)
+				require.True(t, ok)
+				assert.Equal(t, tt.wantCode, serviceErr.Code)
+				userRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+				sessionRepo.AssertNotCalled(t, "DeleteAllForUser", mock.Anything, mock.Anything)
+				sessionRepo.AssertNotCalled(t, "DeleteAllForUserExcept", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+
+			require.NoError(t, err)
+			userRepo.AssertExpectations(t)
+			sessionRepo.AssertExpectations(t)
+		})
+	}
+}
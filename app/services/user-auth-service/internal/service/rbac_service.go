@@ -2,7 +2,12 @@ package service
 
 import (
 	"context"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/haunted-saas/pkg/permission"
 	"github.com/haunted-saas/user-auth-service/internal/config"
 	"github.com/haunted-saas/user-auth-service/internal/domain"
 	"github.com/haunted-saas/user-auth-service/internal/errors"
@@ -44,14 +49,28 @@ func NewRBACService(
 	}
 }
 
-// CreateRole creates a new role
-func (s *RBACService) CreateRole(ctx context.Context, name, description string, permissionIDs []string) (*domain.Role, error) {
+// CreateRole creates a new role granting permissionIDs and denying deniedPermissionIDs.
+// actorID identifies the user making the change for the audit trail.
+func (s *RBACService) CreateRole(ctx context.Context, actorID, name, description string, permissionIDs, deniedPermissionIDs []string) (*domain.Role, error) {
 	// Check if role already exists
 	existingRole, err := s.roleRepo.FindByName(ctx, name)
 	if err == nil && existingRole != nil {
 		return nil, errors.New(errors.ErrCodeInvalidInput, "role already exists")
 	}
 	
+	// Reject unknown permissions before anything is written
+	permissionIDs, err = s.validatePermissionIDs(ctx, permissionIDs)
+	if err != nil {
+		return nil, err
+	}
+	deniedPermissionIDs, err = s.validatePermissionIDs(ctx, deniedPermissionIDs)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkGrantDenyOverlap(permissionIDs, deniedPermissionIDs); err != nil {
+		return nil, err
+	}
+	
 	// Create role
 	role := &domain.Role{
 		Name:        name,
@@ -69,6 +88,11 @@ func (s *RBACService) CreateRole(ctx context.Context, name, description string,
 			return nil, errors.Wrap(errors.ErrCodeInternal, "failed to assign permissions", err)
 		}
 	}
+	if len(deniedPermissionIDs) > 0 {
+		if err := s.roleRepo.SetDeniedPermissions(ctx, role.ID, deniedPermissionIDs); err != nil {
+			return nil, errors.Wrap(errors.ErrCodeInternal, "failed to assign denied permissions", err)
+		}
+	}
 	
 	// Reload role with permissions
 	role, err = s.roleRepo.FindByID(ctx, role.ID)
@@ -79,13 +103,28 @@ func (s *RBACService) CreateRole(ctx context.Context, name, description string,
 	s.logger.Info("role created",
 		zap.String("role_id", role.ID),
 		zap.String("role_name", role.Name),
-		zap.Int("permission_count", len(role.Permissions)))
+		zap.Int("permission_count", len(role.Permissions)),
+		zap.Int("denied_permission_count", len(role.DeniedPermissions)))
+	
+	// Log audit event
+	s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
+		EventType: "role.created",
+		UserID:    actorID,
+		Success:   true,
+		Metadata: map[string]interface{}{
+			"role_id":            role.ID,
+			"role_name":          role.Name,
+			"permissions":        permissionNames(role.Permissions),
+			"denied_permissions": permissionNames(role.DeniedPermissions),
+		},
+	})
 	
 	return role, nil
 }
 
-// UpdateRole updates a role
-func (s *RBACService) UpdateRole(ctx context.Context, roleID, name, description string, permissionIDs []string) (*domain.Role, error) {
+// UpdateRole updates a role. Nil permissionIDs or deniedPermissionIDs leave that set unchanged.
+// actorID identifies the user making the change for the audit trail.
+func (s *RBACService) UpdateRole(ctx context.Context, actorID, roleID, name, description string, permissionIDs, deniedPermissionIDs []string) (*domain.Role, error) {
 	// Get role
 	role, err := s.roleRepo.FindByID(ctx, roleID)
 	if err != nil {
@@ -100,6 +139,29 @@ func (s *RBACService) UpdateRole(ctx context.Context, roleID, name, description
 		return nil, errors.New(errors.ErrCodeSystemRoleProtected, "cannot modify system role")
 	}
 	
+	// Reject unknown denies, and any permission the role would both grant and deny
+	if deniedPermissionIDs != nil {
+		deniedPermissionIDs, err = s.validatePermissionIDs(ctx, deniedPermissionIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+	grantIDs, denyIDs := permissionIDs, deniedPermissionIDs
+	if grantIDs == nil {
+		grantIDs = permissionIDsOf(role.Permissions)
+	}
+	if denyIDs == nil {
+		denyIDs = permissionIDsOf(role.DeniedPermissions)
+	}
+	if err := checkGrantDenyOverlap(grantIDs, denyIDs); err != nil {
+		return nil, err
+	}
+	
+	// Snapshot state for the audit diff
+	previousName := role.Name
+	previousPermissions := role.Permissions
+	previousDenied := role.DeniedPermissions
+	
 	// Update fields
 	if name != "" {
 		role.Name = name
@@ -118,6 +180,11 @@ func (s *RBACService) UpdateRole(ctx context.Context, roleID, name, description
 			return nil, errors.Wrap(errors.ErrCodeInternal, "failed to update permissions", err)
 		}
 	}
+	if deniedPermissionIDs != nil {
+		if err := s.roleRepo.SetDeniedPermissions(ctx, role.ID, deniedPermissionIDs); err != nil {
+			return nil, errors.Wrap(errors.ErrCodeInternal, "failed to update denied permissions", err)
+		}
+	}
 	
 	// Reload role with permissions
 	role, err = s.roleRepo.FindByID(ctx, role.ID)
@@ -129,11 +196,29 @@ func (s *RBACService) UpdateRole(ctx context.Context, roleID, name, description
 		zap.String("role_id", role.ID),
 		zap.String("role_name", role.Name))
 	
+	// Log audit event
+	added, removed := diffPermissions(previousPermissions, role.Permissions)
+	deniesAdded, deniesRemoved := diffPermissions(previousDenied, role.DeniedPermissions)
+	s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
+		EventType: "role.updated",
+		UserID:    actorID,
+		Success:   true,
+		Metadata: map[string]interface{}{
+			"role_id":                    role.ID,
+			"role_name":                  role.Name,
+			"previous_role_name":         previousName,
+			"permissions_added":          added,
+			"permissions_removed":        removed,
+			"denied_permissions_added":   deniesAdded,
+			"denied_permissions_removed": deniesRemoved,
+		},
+	})
+	
 	return role, nil
 }
 
-// DeleteRole deletes a role
-func (s *RBACService) DeleteRole(ctx context.Context, roleID string) error {
+// DeleteRole deletes a role. actorID identifies the user making the change for the audit trail.
+func (s *RBACService) DeleteRole(ctx context.Context, actorID, roleID string) error {
 	// Get role
 	role, err := s.roleRepo.FindByID(ctx, roleID)
 	if err != nil {
@@ -157,6 +242,18 @@ func (s *RBACService) DeleteRole(ctx context.Context, roleID string) error {
 		zap.String("role_id", roleID),
 		zap.String("role_name", role.Name))
 	
+	// Log audit event
+	s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
+		EventType: "role.deleted",
+		UserID:    actorID,
+		Success:   true,
+		Metadata: map[string]interface{}{
+			"role_id":             roleID,
+			"role_name":           role.Name,
+			"permissions_removed": permissionNames(role.Permissions),
+		},
+	})
+	
 	return nil
 }
 
@@ -188,11 +285,11 @@ func (s *RBACService) AssignRoleToUser(ctx context.Context, userID, roleID strin
 	// Invalidate permission cache
 	s.permCacheRepo.InvalidateUserPermissions(ctx, userID)
 	
-	// Invalidate all user sessions (force re-login to get new permissions)
-	s.sessionRepo.DeleteAllForUser(ctx, userID)
+	// Apply the new permissions to the user's sessions
+	s.applyRoleChangeToSessions(ctx, userID)
 	
 	// Log audit event
-	s.logger.LogAuditEvent(&logging.AuditEvent{
+	s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
 		EventType: "user.role.assigned",
 		UserID:    userID,
 		Email:     user.Email,
@@ -234,11 +331,11 @@ func (s *RBACService) RevokeRoleFromUser(ctx context.Context, userID, roleID str
 	// Invalidate permission cache
 	s.permCacheRepo.InvalidateUserPermissions(ctx, userID)
 	
-	// Invalidate all user sessions
-	s.sessionRepo.DeleteAllForUser(ctx, userID)
+	// Apply the new permissions to the user's sessions
+	s.applyRoleChangeToSessions(ctx, userID)
 	
 	// Log audit event
-	s.logger.LogAuditEvent(&logging.AuditEvent{
+	s.logger.LogAuditEvent(ctx, &logging.AuditEvent{
 		EventType: "user.role.revoked",
 		UserID:    userID,
 		Email:     user.Email,
@@ -252,46 +349,67 @@ func (s *RBACService) RevokeRoleFromUser(ctx context.Context, userID, roleID str
 	return nil
 }
 
-// CheckPermission checks if a user has a specific permission
-func (s *RBACService) CheckPermission(ctx context.Context, userID, permission string) (bool, error) {
-	// Try cache first
-	cachedPerms, err := s.permCacheRepo.GetUserPermissions(ctx, userID)
-	if err == nil {
-		// Check if permission is in cache
-		for _, perm := range cachedPerms {
-			if perm == permission {
-				return true, nil
-			}
-		}
-		return false, nil
+// applyRoleChangeToSessions makes the user's sessions pick up a role change.
+// Under the logout policy every session ends and the user signs in again;
+// under reissue the token version is bumped so ValidateToken hands each
+// session a token with the new roles.
+func (s *RBACService) applyRoleChangeToSessions(ctx context.Context, userID string) {
+	if s.config.Security.RoleChangePolicy != config.RoleChangeReissue {
+		s.sessionRepo.DeleteAllForUser(ctx, userID)
+		return
 	}
 	
-	// Cache miss - get from database
-	user, err := s.userRepo.FindByID(ctx, userID)
+	if _, err := s.sessionRepo.IncrementTokenVersion(ctx, userID); err != nil {
+		// Fall back to ending the sessions so stale tokens don't outlive the change
+		s.logger.Error("failed to bump token version, ending sessions instead", zap.Error(err), zap.String("user_id", userID))
+		s.sessionRepo.DeleteAllForUser(ctx, userID)
+	}
+}
+
+// CheckPermission checks if a user has a specific permission, directly or
+// through a wildcard grant. A permission denied by any of the user's roles is
+// never allowed.
+func (s *RBACService) CheckPermission(ctx context.Context, userID, permission string) (bool, error) {
+	permissions, err := s.GetUserPermissions(ctx, userID)
 	if err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return false, errors.New(errors.ErrCodeUserNotFound, "user not found")
-		}
-		return false, errors.Wrap(errors.ErrCodeInternal, "failed to find user", err)
+		return false, err
 	}
 	
-	// Get all permissions from all roles
-	permissions := user.GetPermissions()
+	return permissionAllowed(permissions, permission), nil
+}
+
+// CheckPermissions checks several permissions against the user's permission
+// set, loading it once, and returns whether each one is allowed
+func (s *RBACService) CheckPermissions(ctx context.Context, userID string, permissions []string) (map[string]bool, error) {
+	if userID == "" {
+		return nil, errors.New(errors.ErrCodeInvalidInput, "user_id is required")
+	}
+	if len(permissions) == 0 {
+		return nil, errors.New(errors.ErrCodeInvalidInput, "at least one permission is required")
+	}
 	
-	// Cache permissions
-	s.permCacheRepo.SetUserPermissions(ctx, userID, permissions, s.config.Security.PermissionCacheTTL)
+	granted, err := s.GetUserPermissions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
 	
-	// Check if user has the permission
+	results := make(map[string]bool, len(permissions))
 	for _, perm := range permissions {
-		if perm == permission {
-			return true, nil
-		}
+		results[perm] = permissionAllowed(granted, perm)
 	}
 	
-	return false, nil
+	return results, nil
+}
+
+// permissionAllowed reports whether a permission set from GetUserPermissions
+// allows name, matching wildcard grants. Denies are already applied to the
+// set: denied permissions are dropped and wildcards covering one are narrowed
+// to the known permissions they still allow.
+func permissionAllowed(granted []string, name string) bool {
+	return permission.AnyCovers(granted, name)
 }
 
-// GetUserPermissions gets all permissions for a user
+// GetUserPermissions gets all permissions for a user, with their denies applied
 func (s *RBACService) GetUserPermissions(ctx context.Context, userID string) ([]string, error) {
 	// Try cache first
 	cachedPerms, err := s.permCacheRepo.GetUserPermissions(ctx, userID)
@@ -308,11 +426,296 @@ func (s *RBACService) GetUserPermissions(ctx context.Context, userID string) ([]
 		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to find user", err)
 	}
 	
-	// Get all permissions from all roles
-	permissions := user.GetPermissions()
+	// Get all permissions from all roles, minus denied ones
+	permissions, err := s.resolvePermissions(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	
+	// Cache the resolved permissions so hits need no deny evaluation
+	s.permCacheRepo.SetUserPermissions(ctx, userID, permissions, permissionCacheTTL(s.config.Security))
+	
+	return permissions, nil
+}
+
+// resolvePermissions returns the user's granted permissions with their
+// denies applied. Deny wins: a wildcard grant covering a denied permission is
+// replaced by the known permissions it covers, minus the denied ones.
+func (s *RBACService) resolvePermissions(ctx context.Context, user *domain.User) ([]string, error) {
+	granted := user.GetPermissions()
+	denied := user.GetDeniedPermissions()
+	
+	var narrowed []string
+	resolved := make([]string, 0, len(granted))
+	for _, perm := range granted {
+		if permission.IsWildcard(perm) && coversAnyPermission(perm, denied) {
+			narrowed = append(narrowed, perm)
+		} else {
+			resolved = append(resolved, perm)
+		}
+	}
+	if len(narrowed) == 0 {
+		return resolved, nil
+	}
+	
+	known, err := s.permRepo.List(ctx)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to list permissions", err)
+	}
+	
+	resolvedSet := make(map[string]bool, len(resolved))
+	for _, perm := range resolved {
+		resolvedSet[perm] = true
+	}
+	for _, name := range permissionNames(known) {
+		if resolvedSet[name] || permission.IsWildcard(name) || permission.AnyCovers(denied, name) {
+			continue
+		}
+		if permission.AnyCovers(narrowed, name) {
+			resolvedSet[name] = true
+			resolved = append(resolved, name)
+		}
+	}
+	sort.Strings(resolved)
+	
+	return resolved, nil
+}
+
+// GetEffectivePermissions gets the user's permissions with wildcard grants
+// such as "users:*" or "*" expanded to every matching known permission
+func (s *RBACService) GetEffectivePermissions(ctx context.Context, userID string) ([]string, error) {
+	granted, err := s.GetUserPermissions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	
+	hasWildcard := false
+	for _, perm := range granted {
+		if permission.IsWildcard(perm) {
+			hasWildcard = true
+			break
+		}
+	}
+	if !hasWildcard {
+		return granted, nil
+	}
+	
+	known, err := s.permRepo.List(ctx)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to list permissions", err)
+	}
+	
+	knownNames := permissionNames(known)
+	effectiveSet := make(map[string]bool, len(granted)+len(knownNames))
+	for _, perm := range granted {
+		effectiveSet[perm] = true
+		if !permission.IsWildcard(perm) {
+			continue
+		}
+		for _, name := range knownNames {
+			if permission.Covers(perm, name) {
+				effectiveSet[name] = true
+			}
+		}
+	}
+	
+	effective := make([]string, 0, len(effectiveSet))
+	for perm := range effectiveSet {
+		effective = append(effective, perm)
+	}
+	sort.Strings(effective)
+	
+	return effective, nil
+}
+
+// GetUserRoles gets the roles assigned to a user. Only the user themselves or an admin may view them.
+func (s *RBACService) GetUserRoles(ctx context.Context, requestingUserID, userID string) ([]domain.Role, error) {
+	if userID == "" {
+		return nil, errors.New(errors.ErrCodeInvalidInput, "user_id is required")
+	}
+	
+	// Non-self access requires the admin role
+	if requestingUserID != userID {
+		requester, err := s.userRepo.FindByID(ctx, requestingUserID)
+		if err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return nil, errors.New(errors.ErrCodePermissionDenied, "not allowed to view roles for this user")
+			}
+			return nil, errors.Wrap(errors.ErrCodeInternal, "failed to find requesting user", err)
+		}
+		
+		if !isAdmin(requester) {
+			return nil, errors.New(errors.ErrCodePermissionDenied, "not allowed to view roles for this user")
+		}
+	}
+	
+	roles, err := s.userRepo.GetUserRoles(ctx, userID)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to get user roles", err)
+	}
+	
+	return roles, nil
+}
+
+// ListPermissions returns every permission that can be granted to a role,
+// sorted by name. Only admins may list them.
+func (s *RBACService) ListPermissions(ctx context.Context, requestingUserID string) ([]domain.Permission, error) {
+	requester, err := s.userRepo.FindByID(ctx, requestingUserID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, errors.New(errors.ErrCodePermissionDenied, "not allowed to list permissions")
+		}
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to find requesting user", err)
+	}
+	
+	if !isAdmin(requester) {
+		return nil, errors.New(errors.ErrCodePermissionDenied, "not allowed to list permissions")
+	}
 	
-	// Cache permissions
-	s.permCacheRepo.SetUserPermissions(ctx, userID, permissions, s.config.Security.PermissionCacheTTL)
+	permissions, err := s.permRepo.List(ctx)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to list permissions", err)
+	}
+	
+	sort.Slice(permissions, func(i, j int) bool {
+		return permissions[i].Name < permissions[j].Name
+	})
 	
 	return permissions, nil
 }
+
+// isAdmin reports whether the user has the admin role
+func isAdmin(user *domain.User) bool {
+	for _, roleName := range user.GetRoleNames() {
+		if roleName == "admin" {
+			return true
+		}
+	}
+	return false
+}
+
+// validatePermissionIDs deduplicates permissionIDs, keeping the first
+// occurrence of each, and checks that every ID exists
+func (s *RBACService) validatePermissionIDs(ctx context.Context, permissionIDs []string) ([]string, error) {
+	if len(permissionIDs) == 0 {
+		return permissionIDs, nil
+	}
+	
+	seen := make(map[string]bool, len(permissionIDs))
+	unique := make([]string, 0, len(permissionIDs))
+	for _, id := range permissionIDs {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	
+	found, err := s.permRepo.FindByIDs(ctx, unique)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrCodeInternal, "failed to look up permissions", err)
+	}
+	
+	known := make(map[string]bool, len(found))
+	for _, perm := range found {
+		known[perm.ID] = true
+	}
+	
+	var unknown []string
+	for _, id := range unique {
+		if !known[id] {
+			unknown = append(unknown, id)
+		}
+	}
+	if len(unknown) > 0 {
+		return nil, errors.New(errors.ErrCodeInvalidInput, "unknown permission IDs: "+strings.Join(unknown, ", "))
+	}
+	
+	return unique, nil
+}
+
+// checkGrantDenyOverlap rejects a role that would both grant and deny the same permission
+func checkGrantDenyOverlap(permissionIDs, deniedPermissionIDs []string) error {
+	granted := make(map[string]bool, len(permissionIDs))
+	for _, id := range permissionIDs {
+		granted[id] = true
+	}
+	
+	var overlap []string
+	for _, id := range deniedPermissionIDs {
+		if granted[id] {
+			overlap = append(overlap, id)
+		}
+	}
+	if len(overlap) > 0 {
+		return errors.New(errors.ErrCodeInvalidInput, "permissions cannot be both granted and denied: "+strings.Join(overlap, ", "))
+	}
+	return nil
+}
+
+// coversAnyPermission reports whether pattern covers any of names
+func coversAnyPermission(pattern string, names []string) bool {
+	for _, name := range names {
+		if permission.Covers(pattern, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// permissionIDsOf returns the IDs of the given permissions
+func permissionIDsOf(permissions []domain.Permission) []string {
+	ids := make([]string, 0, len(permissions))
+	for _, perm := range permissions {
+		ids = append(ids, perm.ID)
+	}
+	return ids
+}
+
+// permissionNames returns the names of the given permissions
+func permissionNames(permissions []domain.Permission) []string {
+	names := make([]string, 0, len(permissions))
+	for _, perm := range permissions {
+		names = append(names, perm.Name)
+	}
+	return names
+}
+
+// diffPermissions returns the permission names added and removed between two permission sets
+func diffPermissions(before, after []domain.Permission) (added, removed []string) {
+	beforeSet := make(map[string]bool, len(before))
+	for _, perm := range before {
+		beforeSet[perm.Name] = true
+	}
+	afterSet := make(map[string]bool, len(after))
+	for _, perm := range after {
+		afterSet[perm.Name] = true
+	}
+	
+	added = []string{}
+	for _, perm := range after {
+		if !beforeSet[perm.Name] {
+			added = append(added, perm.Name)
+		}
+	}
+	removed = []string{}
+	for _, perm := range before {
+		if !afterSet[perm.Name] {
+			removed = append(removed, perm.Name)
+		}
+	}
+	
+	return added, removed
+}
+
+// permissionCacheTTL returns the permission cache TTL randomized by the configured
+// jitter fraction, so entries written together don't all expire together
+func permissionCacheTTL(security config.SecurityConfig) time.Duration {
+	ttl := security.PermissionCacheTTL
+	if security.PermissionCacheJitter <= 0 {
+		return ttl
+	}
+
+	// Spread uniformly over [ttl*(1-jitter), ttl*(1+jitter)]
+	offset := (rand.Float64()*2 - 1) * security.PermissionCacheJitter
+	return ttl + time.Duration(float64(ttl)*offset)
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/haunted-saas/user-auth-service/internal/config"
 	"github.com/haunted-saas/user-auth-service/internal/domain"
@@ -11,6 +12,9 @@ import (
 	"github.com/haunted-saas/user-auth-service/internal/repository"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
 	"gorm.io/gorm"
 )
 
@@ -56,7 +60,7 @@ func (m *MockPermissionCacheRepository) GetUserPermissions(ctx context.Context,
 	return args.Get(0).([]string), args.Error(1)
 }
 
-func (m *MockPermissionCacheRepository) SetUserPermissions(ctx context.Context, userID string, permissions []string, ttl interface{}) error {
+func (m *MockPermissionCacheRepository) SetUserPermissions(ctx context.Context, userID string, permissions []string, ttl time.Duration) error {
 	args := m.Called(ctx, userID, permissions, ttl)
 	return args.Error(0)
 }
@@ -110,6 +114,26 @@ func TestRBACService_CheckPermission(t *testing.T) {
 			expectedResult: true,
 			expectedError:  nil,
 		},
+		{
+			name:       "permission granted by a wildcard",
+			userID:     "user-123",
+			permission: "users:delete",
+			setupMocks: func(userRepo *MockUserRepository, cacheRepo *MockPermissionCacheRepository) {
+				cacheRepo.On("GetUserPermissions", mock.Anything, "user-123").Return([]string{"users:*"}, nil)
+			},
+			expectedResult: true,
+			expectedError:  nil,
+		},
+		{
+			name:       "permission granted by the global wildcard",
+			userID:     "user-123",
+			permission: "billing:refund",
+			setupMocks: func(userRepo *MockUserRepository, cacheRepo *MockPermissionCacheRepository) {
+				cacheRepo.On("GetUserPermissions", mock.Anything, "user-123").Return([]string{"*"}, nil)
+			},
+			expectedResult: true,
+			expectedError:  nil,
+		},
 		{
 			name:       "permission not found",
 			userID:     "user-123",
@@ -178,6 +202,186 @@ func TestRBACService_CheckPermission(t *testing.T) {
 	}
 }
 
+// Test CheckPermissions
+func TestRBACService_CheckPermissions(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	cacheRepo := new(MockPermissionCacheRepository)
+
+	// A single cache miss loads the permission set once for every check
+	cacheRepo.On("GetUserPermissions", mock.Anything, "user-123").Return(nil, repository.ErrNotFound).Once()
+	userRepo.On("FindByID", mock.Anything, "user-123").Return(&domain.User{
+		ID:    "user-123",
+		Email: "test@example.com",
+		Roles: []domain.Role{
+			{
+				Name: "member",
+				Permissions: []domain.Permission{
+					{Name: "users:read"},
+					{Name: "users:write"},
+				},
+			},
+		},
+	}, nil).Once()
+	cacheRepo.On("SetUserPermissions", mock.Anything, "user-123", mock.Anything, mock.Anything).Return(nil).Once()
+
+	logger, _ := logging.NewLogger("error")
+	service := NewRBACService(userRepo, nil, nil, cacheRepo, new(MockSessionRepository), &config.Config{}, logger)
+
+	results, err := service.CheckPermissions(context.Background(), "user-123", []string{"users:read", "users:write", "users:delete", "roles:write"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{
+		"users:read":   true,
+		"users:write":  true,
+		"users:delete": false,
+		"roles:write":  false,
+	}, results)
+	userRepo.AssertExpectations(t)
+	cacheRepo.AssertExpectations(t)
+}
+
+func TestRBACService_CheckPermissions_InvalidInput(t *testing.T) {
+	logger, _ := logging.NewLogger("error")
+	service := NewRBACService(new(MockUserRepository), nil, nil, new(MockPermissionCacheRepository), new(MockSessionRepository), &config.Config{}, logger)
+
+	_, err := service.CheckPermissions(context.Background(), "", []string{"users:read"})
+	serviceErr, ok := err.(*errors.ServiceError)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInvalidInput, serviceErr.Code)
+
+	_, err = service.CheckPermissions(context.Background(), "user-123", nil)
+	serviceErr, ok = err.(*errors.ServiceError)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInvalidInput, serviceErr.Code)
+}
+
+// Test that a deny wins over a matching grant, including wildcard grants
+func TestRBACService_DeniedPermissions(t *testing.T) {
+	known := []domain.Permission{
+		{Name: "users:read"},
+		{Name: "users:write"},
+		{Name: "users:delete"},
+		{Name: "users:*"},
+		{Name: "billing:read"},
+	}
+
+	tests := []struct {
+		name     string
+		granted  []string
+		denied   []string
+		expected []string
+	}{
+		{
+			name:     "exact deny",
+			granted:  []string{"users:read", "users:delete"},
+			denied:   []string{"users:delete"},
+			expected: []string{"users:read"},
+		},
+		{
+			name:     "deny narrows a resource wildcard",
+			granted:  []string{"users:*"},
+			denied:   []string{"users:delete"},
+			expected: []string{"users:read", "users:write"},
+		},
+		{
+			name:     "deny narrows the global wildcard",
+			granted:  []string{"*"},
+			denied:   []string{"users:delete"},
+			expected: []string{"billing:read", "users:read", "users:write"},
+		},
+		{
+			name:     "wildcard deny",
+			granted:  []string{"users:read", "users:*", "billing:read"},
+			denied:   []string{"users:*"},
+			expected: []string{"billing:read"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			toPermissions := func(names []string) []domain.Permission {
+				perms := make([]domain.Permission, len(names))
+				for i, name := range names {
+					perms[i] = domain.Permission{Name: name}
+				}
+				return perms
+			}
+
+			// The grant and the deny come from different roles
+			userRepo := new(MockUserRepository)
+			userRepo.On("FindByID", mock.Anything, "user-123").Return(&domain.User{
+				ID: "user-123",
+				Roles: []domain.Role{
+					{Name: "admin", Permissions: toPermissions(tt.granted)},
+					{Name: "restricted", DeniedPermissions: toPermissions(tt.denied)},
+				},
+			}, nil)
+			permRepo := new(MockPermissionRepository)
+			permRepo.On("List", mock.Anything).Return(known, nil).Maybe()
+
+			// Only the resolved permissions are cached, so cache hits stay correct
+			var cached []string
+			cacheRepo := new(MockPermissionCacheRepository)
+			cacheRepo.On("GetUserPermissions", mock.Anything, "user-123").Return(nil, repository.ErrNotFound).Once()
+			cacheRepo.On("SetUserPermissions", mock.Anything, "user-123", mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) { cached = args.Get(2).([]string) }).
+				Return(nil).Once()
+
+			logger, _ := logging.NewLogger("error")
+			service := NewRBACService(userRepo, nil, permRepo, cacheRepo, new(MockSessionRepository), &config.Config{}, logger)
+
+			permissions, err := service.GetUserPermissions(context.Background(), "user-123")
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.expected, permissions)
+			assert.ElementsMatch(t, tt.expected, cached)
+
+			// Later checks are served from the cache
+			cacheRepo.On("GetUserPermissions", mock.Anything, "user-123").Return(cached, nil)
+
+			for _, perm := range tt.denied {
+				allowed, err := service.CheckPermission(context.Background(), "user-123", perm)
+				require.NoError(t, err)
+				assert.False(t, allowed, "denied permission %s was allowed", perm)
+			}
+			allowed, err := service.CheckPermission(context.Background(), "user-123", "users:delete")
+			require.NoError(t, err)
+			assert.False(t, allowed)
+
+			checks, err := service.CheckPermissions(context.Background(), "user-123", append([]string{"users:delete"}, tt.expected...))
+			require.NoError(t, err)
+			assert.False(t, checks["users:delete"])
+			for _, perm := range tt.expected {
+				assert.True(t, checks[perm], "granted permission %s was refused", perm)
+			}
+
+			effective, err := service.GetEffectivePermissions(context.Background(), "user-123")
+			require.NoError(t, err)
+			assert.ElementsMatch(t, tt.expected, effective)
+
+			userRepo.AssertNumberOfCalls(t, "FindByID", 1)
+		})
+	}
+}
+
+// Test that a role can't both grant and deny a permission
+func TestRBACService_CreateRole_RejectsGrantDenyOverlap(t *testing.T) {
+	roleRepo := new(MockRoleRepository)
+	permRepo := new(MockPermissionRepository)
+	logger, _ := logging.NewLogger("error")
+	service := NewRBACService(nil, roleRepo, permRepo, nil, nil, &config.Config{}, logger)
+
+	readUsers := domain.Permission{ID: "perm-1", Name: "users:read"}
+	roleRepo.On("FindByName", mock.Anything, "support").Return(nil, gorm.ErrRecordNotFound)
+	permRepo.On("FindByIDs", mock.Anything, []string{"perm-1"}).Return([]domain.Permission{readUsers}, nil)
+
+	role, err := service.CreateRole(context.Background(), "admin-1", "support", "Support staff", []string{"perm-1"}, []string{"perm-1"})
+	assert.Nil(t, role)
+	serviceErr, ok := err.(*errors.ServiceError)
+	require.True(t, ok)
+	assert.Equal(t, errors.ErrCodeInvalidInput, serviceErr.Code)
+	roleRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
 // Test AssignRoleToUser
 func TestRBACService_AssignRoleToUser(t *testing.T) {
 	tests := []struct {
@@ -277,5 +481,347 @@ func TestRBACService_AssignRoleToUser(t *testing.T) {
 	}
 }
 
+// Test role changes keep sessions and bump the token version under the reissue policy
+func TestRBACService_RoleChange_ReissuePolicy(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	roleRepo := new(MockRoleRepository)
+	cacheRepo := new(MockPermissionCacheRepository)
+	sessionRepo := new(MockSessionRepository)
+
+	userRepo.On("FindByID", mock.Anything, "user-123").Return(&domain.User{
+		ID:    "user-123",
+		Email: "test@example.com",
+	}, nil)
+	roleRepo.On("FindByID", mock.Anything, "role-456").Return(&domain.Role{
+		ID:   "role-456",
+		Name: "admin",
+	}, nil)
+	userRepo.On("AssignRole", mock.Anything, "user-123", "role-456").Return(nil)
+	userRepo.On("RevokeRole", mock.Anything, "user-123", "role-456").Return(nil)
+	cacheRepo.On("InvalidateUserPermissions", mock.Anything, "user-123").Return(nil)
+	sessionRepo.On("IncrementTokenVersion", mock.Anything, "user-123").Return(int64(1), nil).Once()
+	sessionRepo.On("IncrementTokenVersion", mock.Anything, "user-123").Return(int64(2), nil).Once()
+
+	logger, _ := logging.NewLogger("error")
+	cfg := &config.Config{Security: config.SecurityConfig{RoleChangePolicy: config.RoleChangeReissue}}
+	service := NewRBACService(userRepo, roleRepo, nil, cacheRepo, sessionRepo, cfg, logger)
+
+	assert.NoError(t, service.AssignRoleToUser(context.Background(), "user-123", "role-456"))
+	assert.NoError(t, service.RevokeRoleFromUser(context.Background(), "user-123", "role-456"))
+
+	sessionRepo.AssertNotCalled(t, "DeleteAllForUser", mock.Anything, mock.Anything)
+	sessionRepo.AssertExpectations(t)
+	cacheRepo.AssertNumberOfCalls(t, "InvalidateUserPermissions", 2)
+}
+
+// newObservedLogger returns a logger whose audit events can be inspected
+func newObservedLogger() (*logging.Logger, *observer.ObservedLogs) {
+	core, logs := observer.New(zap.InfoLevel)
+	return &logging.Logger{Logger: zap.New(core)}, logs
+}
+
+// auditEvents returns the context of every audit event with the given type
+func auditEvents(logs *observer.ObservedLogs, eventType string) []map[string]interface{} {
+	var events []map[string]interface{}
+	for _, entry := range logs.FilterMessage("audit_event").All() {
+		fields := entry.ContextMap()
+		if fields["event_type"] == eventType {
+			events = append(events, fields)
+		}
+	}
+	return events
+}
+
+// Test role change audit trail
+func TestRBACService_RoleAuditEvents(t *testing.T) {
+	readUsers := domain.Permission{ID: "perm-1", Name: "users:read"}
+	writeUsers := domain.Permission{ID: "perm-2", Name: "users:write"}
+	readBilling := domain.Permission{ID: "perm-3", Name: "billing:read"}
+
+	t.Run("role.created", func(t *testing.T) {
+		roleRepo := new(MockRoleRepository)
+		permRepo := new(MockPermissionRepository)
+		logger, logs := newObservedLogger()
+		service := NewRBACService(nil, roleRepo, permRepo, nil, nil, &config.Config{}, logger)
+
+		roleRepo.On("FindByName", mock.Anything, "support").Return(nil, gorm.ErrRecordNotFound)
+		permRepo.On("FindByIDs", mock.Anything, []string{"perm-1"}).Return([]domain.Permission{readUsers}, nil)
+		roleRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Role")).
+			Run(func(args mock.Arguments) { args.Get(1).(*domain.Role).ID = "role-1" }).
+			Return(nil)
+		roleRepo.On("SetPermissions", mock.Anything, "role-1", []string{"perm-1"}).Return(nil)
+		roleRepo.On("FindByID", mock.Anything, "role-1").Return(&domain.Role{
+			ID:          "role-1",
+			Name:        "support",
+			Permissions: []domain.Permission{readUsers},
+		}, nil)
+
+		_, err := service.CreateRole(context.Background(), "admin-1", "support", "Support staff", []string{"perm-1"}, nil)
+		assert.NoError(t, err)
+
+		events := auditEvents(logs, "role.created")
+		if assert.Len(t, events, 1) {
+			assert.Equal(t, "admin-1", events[0]["user_id"])
+			assert.Equal(t, "role-1", events[0]["role_id"])
+			assert.Equal(t, []interface{}{"users:read"}, events[0]["permissions"])
+		}
+		roleRepo.AssertExpectations(t)
+	})
+
+	t.Run("role.updated", func(t *testing.T) {
+		roleRepo := new(MockRoleRepository)
+		logger, logs := newObservedLogger()
+		service := NewRBACService(nil, roleRepo, nil, nil, nil, &config.Config{}, logger)
+
+		roleRepo.On("FindByID", mock.Anything, "role-1").Return(&domain.Role{
+			ID:          "role-1",
+			Name:        "support",
+			Permissions: []domain.Permission{readUsers, writeUsers},
+		}, nil).Once()
+		roleRepo.On("Update", mock.Anything, mock.AnythingOfType("*domain.Role")).Return(nil)
+		roleRepo.On("SetPermissions", mock.Anything, "role-1", []string{"perm-1", "perm-3"}).Return(nil)
+		roleRepo.On("FindByID", mock.Anything, "role-1").Return(&domain.Role{
+			ID:          "role-1",
+			Name:        "support",
+			Permissions: []domain.Permission{readUsers, readBilling},
+		}, nil).Once()
+
+		_, err := service.UpdateRole(context.Background(), "admin-1", "role-1", "", "", []string{"perm-1", "perm-3"}, nil)
+		assert.NoError(t, err)
+
+		events := auditEvents(logs, "role.updated")
+		if assert.Len(t, events, 1) {
+			assert.Equal(t, "admin-1", events[0]["user_id"])
+			assert.Equal(t, []interface{}{"billing:read"}, events[0]["permissions_added"])
+			assert.Equal(t, []interface{}{"users:write"}, events[0]["permissions_removed"])
+		}
+		roleRepo.AssertExpectations(t)
+	})
+
+	t.Run("role.deleted", func(t *testing.T) {
+		roleRepo := new(MockRoleRepository)
+		logger, logs := newObservedLogger()
+		service := NewRBACService(nil, roleRepo, nil, nil, nil, &config.Config{}, logger)
+
+		roleRepo.On("FindByID", mock.Anything, "role-1").Return(&domain.Role{
+			ID:          "role-1",
+			Name:        "support",
+			Permissions: []domain.Permission{readUsers, writeUsers},
+		}, nil)
+		roleRepo.On("Delete", mock.Anything, "role-1").Return(nil)
+
+		err := service.DeleteRole(context.Background(), "admin-1", "role-1")
+		assert.NoError(t, err)
+
+		events := auditEvents(logs, "role.deleted")
+		if assert.Len(t, events, 1) {
+			assert.Equal(t, "admin-1", events[0]["user_id"])
+			assert.Equal(t, []interface{}{"users:read", "users:write"}, events[0]["permissions_removed"])
+		}
+		roleRepo.AssertExpectations(t)
+	})
+
+	t.Run("system role update emits no audit event", func(t *testing.T) {
+		roleRepo := new(MockRoleRepository)
+		logger, logs := newObservedLogger()
+		service := NewRBACService(nil, roleRepo, nil, nil, nil, &config.Config{}, logger)
+
+		roleRepo.On("FindByID", mock.Anything, "role-admin").Return(&domain.Role{
+			ID:       "role-admin",
+			Name:     "admin",
+			IsSystem: true,
+		}, nil)
+
+		_, err := service.UpdateRole(context.Background(), "admin-1", "role-admin", "root", "", nil, nil)
+		assert.Error(t, err)
+		assert.Empty(t, auditEvents(logs, "role.updated"))
+	})
+}
+
+// Test that CreateRole rejects unknown permission IDs and deduplicates the rest
+func TestRBACService_CreateRole_ValidatesPermissions(t *testing.T) {
+	readUsers := domain.Permission{ID: "perm-1", Name: "users:read"}
+
+	t.Run("unknown permission rejected", func(t *testing.T) {
+		roleRepo := new(MockRoleRepository)
+		permRepo := new(MockPermissionRepository)
+		logger, _ := logging.NewLogger("error")
+		service := NewRBACService(nil, roleRepo, permRepo, nil, nil, &config.Config{}, logger)
+
+		roleRepo.On("FindByName", mock.Anything, "support").Return(nil, gorm.ErrRecordNotFound)
+		permRepo.On("FindByIDs", mock.Anything, []string{"perm-1", "perm-typo"}).Return([]domain.Permission{readUsers}, nil)
+
+		role, err := service.CreateRole(context.Background(), "admin-1", "support", "Support staff", []string{"perm-1", "perm-typo"}, nil)
+		assert.Nil(t, role)
+		serviceErr, ok := err.(*errors.ServiceError)
+		require.True(t, ok)
+		assert.Equal(t, errors.ErrCodeInvalidInput, serviceErr.Code)
+		assert.Contains(t, serviceErr.Message, "perm-typo")
+		roleRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+		roleRepo.AssertNotCalled(t, "SetPermissions", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("duplicate permissions assigned once", func(t *testing.T) {
+		roleRepo := new(MockRoleRepository)
+		permRepo := new(MockPermissionRepository)
+		logger, _ := logging.NewLogger("error")
+		service := NewRBACService(nil, roleRepo, permRepo, nil, nil, &config.Config{}, logger)
+
+		roleRepo.On("FindByName", mock.Anything, "support").Return(nil, gorm.ErrRecordNotFound)
+		permRepo.On("FindByIDs", mock.Anything, []string{"perm-1"}).Return([]domain.Permission{readUsers}, nil)
+		roleRepo.On("Create", mock.Anything, mock.AnythingOfType("*domain.Role")).
+			Run(func(args mock.Arguments) { args.Get(1).(*domain.Role).ID = "role-1" }).
+			Return(nil)
+		roleRepo.On("SetPermissions", mock.Anything, "role-1", []string{"perm-1"}).Return(nil)
+		roleRepo.On("FindByID", mock.Anything, "role-1").Return(&domain.Role{
+			ID:          "role-1",
+			Name:        "support",
+			Permissions: []domain.Permission{readUsers},
+		}, nil)
+
+		_, err := service.CreateRole(context.Background(), "admin-1", "support", "Support staff", []string{"perm-1", "perm-1"}, nil)
+		assert.NoError(t, err)
+		roleRepo.AssertExpectations(t)
+		permRepo.AssertExpectations(t)
+	})
+}
+
+// Test GetUserRoles access control
+func TestRBACService_GetUserRoles(t *testing.T) {
+	memberRoles := []domain.Role{
+		{
+			ID:          "role-member",
+			Name:        "member",
+			Description: "Standard team member",
+			Permissions: []domain.Permission{{ID: "perm-1", Name: "users:read"}},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		requestingUserID string
+		userID           string
+		setupMocks       func(*MockUserRepository)
+		expectedError    error
+	}{
+		{
+			name:             "self access",
+			requestingUserID: "user-123",
+			userID:           "user-123",
+			setupMocks: func(userRepo *MockUserRepository) {
+				userRepo.On("GetUserRoles", mock.Anything, "user-123").Return(memberRoles, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:             "admin access",
+			requestingUserID: "admin-1",
+			userID:           "user-123",
+			setupMocks: func(userRepo *MockUserRepository) {
+				userRepo.On("FindByID", mock.Anything, "admin-1").Return(&domain.User{
+					ID:    "admin-1",
+					Roles: []domain.Role{{Name: "admin"}},
+				}, nil)
+				userRepo.On("GetUserRoles", mock.Anything, "user-123").Return(memberRoles, nil)
+			},
+			expectedError: nil,
+		},
+		{
+			name:             "other non-admin user denied",
+			requestingUserID: "user-456",
+			userID:           "user-123",
+			setupMocks: func(userRepo *MockUserRepository) {
+				userRepo.On("FindByID", mock.Anything, "user-456").Return(&domain.User{
+					ID:    "user-456",
+					Roles: []domain.Role{{Name: "member"}},
+				}, nil)
+			},
+			expectedError: errors.New(errors.ErrCodePermissionDenied, ""),
+		},
+		{
+			name:             "missing user_id",
+			requestingUserID: "user-123",
+			userID:           "",
+			setupMocks:       func(userRepo *MockUserRepository) {},
+			expectedError:    errors.New(errors.ErrCodeInvalidInput, ""),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			userRepo := new(MockUserRepository)
+			tt.setupMocks(userRepo)
+
+			logger, _ := logging.NewLogger("error")
+			service := NewRBACService(userRepo, nil, nil, nil, nil, &config.Config{}, logger)
+
+			roles, err := service.GetUserRoles(context.Background(), tt.requestingUserID, tt.userID)
+
+			if tt.expectedError != nil {
+				assert.Error(t, err)
+				serviceErr, ok := err.(*errors.ServiceError)
+				if assert.True(t, ok) {
+					assert.Equal(t, tt.expectedError.(*errors.ServiceError).Code, serviceErr.Code)
+				}
+				assert.Nil(t, roles)
+			} else {
+				assert.NoError(t, err)
+				assert.Len(t, roles, 1)
+				assert.Equal(t, "member", roles[0].Name)
+				assert.Len(t, roles[0].Permissions, 1)
+			}
+
+			userRepo.AssertExpectations(t)
+		})
+	}
+}
+
 // Define ErrNotFound for tests
 var ErrNotFound = repository.ErrNotFound
+
+// Test permission cache writes use a jittered TTL
+func TestRBACService_PermissionCacheTTLJitter(t *testing.T) {
+	userRepo := new(MockUserRepository)
+	cacheRepo := new(MockPermissionCacheRepository)
+
+	baseTTL := 10 * time.Minute
+	jitter := 0.2
+	var ttls []time.Duration
+
+	for _, userID := range []string{"user-1", "user-2"} {
+		cacheRepo.On("GetUserPermissions", mock.Anything, userID).Return(nil, errors.New(errors.ErrCodeInternal, "not cached"))
+		userRepo.On("FindByID", mock.Anything, userID).Return(&domain.User{
+			ID:    userID,
+			Roles: []domain.Role{{Name: "member", Permissions: []domain.Permission{{Name: "users:read"}}}},
+		}, nil)
+		cacheRepo.On("SetUserPermissions", mock.Anything, userID, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				ttls = append(ttls, args.Get(3).(time.Duration))
+			}).
+			Return(nil)
+	}
+
+	logger, _ := logging.NewLogger("error")
+	cfg := &config.Config{
+		Security: config.SecurityConfig{
+			PermissionCacheTTL:    baseTTL,
+			PermissionCacheJitter: jitter,
+		},
+	}
+	service := NewRBACService(userRepo, nil, nil, cacheRepo, nil, cfg, logger)
+
+	_, err := service.GetUserPermissions(context.Background(), "user-1")
+	assert.NoError(t, err)
+	_, err = service.CheckPermission(context.Background(), "user-2", "users:read")
+	assert.NoError(t, err)
+
+	minTTL := time.Duration(float64(baseTTL) * (1 - jitter))
+	maxTTL := time.Duration(float64(baseTTL) * (1 + jitter))
+	assert.Len(t, ttls, 2)
+	for _, ttl := range ttls {
+		assert.GreaterOrEqual(t, ttl, minTTL)
+		assert.LessOrEqual(t, ttl, maxTTL)
+	}
+
+	cacheRepo.AssertExpectations(t)
+}
@@ -0,0 +1,16 @@
+-- Persist audit events so they can be exported for SIEM ingestion
+CREATE TABLE IF NOT EXISTS audit_events (
+    id BIGSERIAL PRIMARY KEY,
+    event_type VARCHAR(100) NOT NULL,
+    user_id TEXT,
+    email VARCHAR(255),
+    ip_address VARCHAR(45),
+    success BOOLEAN NOT NULL DEFAULT false,
+    error_reason VARCHAR(255),
+    correlation_id VARCHAR(255),
+    metadata JSONB,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Exports scan by time range
+CREATE INDEX IF NOT EXISTS idx_audit_events_created_at ON audit_events(created_at);
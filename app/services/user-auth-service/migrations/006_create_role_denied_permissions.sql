@@ -0,0 +1,10 @@
+-- Permissions a role explicitly denies. A deny wins over any grant, including
+-- wildcard grants, from the same or another role.
+CREATE TABLE IF NOT EXISTS role_denied_permissions (
+    role_id UUID REFERENCES roles(id) ON DELETE CASCADE,
+    permission_id UUID REFERENCES permissions(id) ON DELETE CASCADE,
+    PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_role_denied_permissions_role ON role_denied_permissions(role_id);
+CREATE INDEX IF NOT EXISTS idx_role_denied_permissions_permission ON role_denied_permissions(permission_id);